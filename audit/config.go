@@ -0,0 +1,29 @@
+package audit
+
+import (
+	"github.com/pkg/errors"
+)
+
+// DefaultPath the default destination of the audit logs
+const DefaultPath = "stdout"
+
+// Configuration the audit logger configuration
+type Configuration struct {
+	Enabled bool
+	// a file path, stdout or stderr
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+}
+
+// UnmarshalYAML parses the audit logger configuration from YAML.
+func (c *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration Configuration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the audit configuration")
+	}
+	if raw.Path != "" && !raw.Enabled {
+		return errors.New("The audit path is set but the audit logger is not enabled")
+	}
+	*c = Configuration(raw)
+	return nil
+}
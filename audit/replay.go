@@ -0,0 +1,174 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// replayLag the maximum delay between the execution of an healthcheck and
+// the audit log of its result. The file is read until the logs are older
+// than the end of the window plus this lag.
+const replayLag = time.Minute
+
+// entry an audit log, the timestamps are in seconds
+type entry struct {
+	Timestamp            float64           `json:"ts"`
+	Name                 string            `json:"name"`
+	Target               string            `json:"target"`
+	Success              bool              `json:"success"`
+	Message              string            `json:"message"`
+	Duration             float64           `json:"duration"`
+	Summary              interface{}       `json:"summary"`
+	Source               string            `json:"source"`
+	Labels               map[string]string `json:"labels"`
+	Metadata             map[string]string `json:"metadata"`
+	HealthcheckTimestamp float64           `json:"healthcheck-timestamp"`
+}
+
+// toTime converts a timestamp in seconds
+func toTime(timestamp float64) time.Time {
+	seconds, fraction := math.Modf(timestamp)
+	return time.Unix(int64(seconds), int64(fraction*float64(time.Second)))
+}
+
+// result returns the result of an audit log
+func (e *entry) result() *healthcheck.Result {
+	timestamp := toTime(e.HealthcheckTimestamp)
+	return &healthcheck.Result{
+		Name:                 e.Name,
+		Target:               e.Target,
+		Summary:              e.Summary,
+		Labels:               e.Labels,
+		Success:              e.Success,
+		HealthcheckTimestamp: timestamp.Unix(),
+		Message:              e.Message,
+		Duration:             e.Duration,
+		Source:               e.Source,
+		Metadata:             e.Metadata,
+		Timestamp:            timestamp,
+	}
+}
+
+// parseEntry parses an audit log line, ok is false for the other lines
+func parseEntry(line []byte) (*entry, bool) {
+	var e entry
+	if err := json.Unmarshal(line, &e); err != nil || e.Name == "" || e.Timestamp == 0 {
+		return nil, false
+	}
+	return &e, true
+}
+
+// logTime returns the time of the first audit log starting at offset, ok is
+// false if there is none
+func logTime(file *os.File, offset int64) (time.Time, bool, error) {
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return time.Time{}, false, errors.Wrap(err, "Fail to seek in the audit logs")
+	}
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if e, ok := parseEntry(line); ok {
+			return toTime(e.Timestamp), true, nil
+		}
+		if err == io.EOF {
+			return time.Time{}, false, nil
+		}
+		if err != nil {
+			return time.Time{}, false, errors.Wrap(err, "Fail to read the audit logs")
+		}
+	}
+}
+
+// lineStart returns the offset of the first line starting at or after
+// offset
+func lineStart(file *os.File, offset int64) (int64, error) {
+	if offset == 0 {
+		return 0, nil
+	}
+	if _, err := file.Seek(offset-1, io.SeekStart); err != nil {
+		return 0, errors.Wrap(err, "Fail to seek in the audit logs")
+	}
+	line, err := bufio.NewReader(file).ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return 0, errors.Wrap(err, "Fail to read the audit logs")
+	}
+	return offset - 1 + int64(len(line)), nil
+}
+
+// seek returns the offset of the first audit log written at or after from.
+// The logs are appended, so they are sorted by time and the offset is found
+// by a binary search.
+func seek(file *os.File, from time.Time) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, errors.Wrap(err, "Fail to read the audit logs")
+	}
+	low, high := int64(0), info.Size()
+	for low < high {
+		middle := low + (high-low)/2
+		start, err := lineStart(file, middle)
+		if err != nil {
+			return 0, err
+		}
+		t, ok, err := logTime(file, start)
+		if err != nil {
+			return 0, err
+		}
+		if !ok || !t.Before(from) {
+			high = middle
+		} else {
+			low = middle + 1
+		}
+	}
+	return lineStart(file, low)
+}
+
+// Replay reads the results of an audit log file executed between from and
+// to, and calls push for each of them in order. It returns the number of
+// results pushed, and stops on the first push error.
+func Replay(path string, from time.Time, to time.Time, push func(*healthcheck.Result) error) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Fail to open the audit logs %s", path)
+	}
+	defer file.Close()
+	offset, err := seek(file, from)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return 0, errors.Wrap(err, "Fail to seek in the audit logs")
+	}
+	reader := bufio.NewReader(file)
+	end := to.Add(replayLag)
+	count := 0
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if e, ok := parseEntry(line); ok {
+			if toTime(e.Timestamp).After(end) {
+				return count, nil
+			}
+			result := e.result()
+			if !result.Timestamp.Before(from) && !result.Timestamp.After(to) {
+				if err := push(result); err != nil {
+					return count, err
+				}
+				count++
+			}
+		}
+		if readErr == io.EOF {
+			return count, nil
+		}
+		if readErr != nil {
+			return count, errors.Wrap(readErr, "Fail to read the audit logs")
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+func TestReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var lines []string
+	for i := 0; i < 100; i++ {
+		executed := start.Add(time.Duration(i) * time.Minute)
+		lines = append(lines, fmt.Sprintf(`{"level":"info","ts":%d.5,"logger":"audit","msg":"healthcheck result","name":"check-%d","success":true,"status":"success","message":"success","duration":0.1,"summary":"on 127.0.0.1","source":"configuration","labels":{"env":"prod"},"metadata":null,"healthcheck-timestamp":%d}`, executed.Unix(), i, executed.Unix()))
+	}
+	// the lines which are not audit logs are ignored
+	lines = append(lines[:50], append([]string{"not a log"}, lines[50:]...)...)
+	err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+	if err != nil {
+		t.Fatalf("Fail to write the audit logs\n%v", err)
+	}
+	var names []string
+	count, err := Replay(path, start.Add(10*time.Minute), start.Add(60*time.Minute), func(result *healthcheck.Result) error {
+		if result.Labels["env"] != "prod" || !result.Success || result.Duration != 0.1 {
+			t.Fatalf("Invalid result %v", result)
+		}
+		names = append(names, result.Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Fail to replay the audit logs\n%v", err)
+	}
+	if count != 51 || len(names) != 51 || names[0] != "check-10" || names[50] != "check-60" {
+		t.Fatalf("Invalid replayed results %d: %v", count, names)
+	}
+	count, err = Replay(path, start.Add(10*time.Minute), start.Add(60*time.Minute), func(result *healthcheck.Result) error {
+		return fmt.Errorf("push error")
+	})
+	if err == nil || count != 0 {
+		t.Fatalf("The replay should stop on the push errors")
+	}
+	count, err = Replay(path, start.Add(200*time.Minute), start.Add(300*time.Minute), func(result *healthcheck.Result) error {
+		return nil
+	})
+	if err != nil || count != 0 {
+		t.Fatalf("No result should be replayed: %d %v", count, err)
+	}
+}
+
+func TestReplayAuditLogger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	logger, err := New(&Configuration{Enabled: true, Path: path})
+	if err != nil {
+		t.Fatalf("Fail to create the audit logger\n%v", err)
+	}
+	executed := time.Now().Add(-time.Minute)
+	logger.Log(&healthcheck.Result{
+		Name:      "foo",
+		Success:   false,
+		Message:   "connection refused",
+		Labels:    map[string]string{"env": "prod"},
+		Metadata:  map[string]string{"reason": "timeout"},
+		Timestamp: executed,
+	})
+	err = logger.Close()
+	if err != nil {
+		t.Fatalf("Fail to close the audit logger\n%v", err)
+	}
+	var replayed *healthcheck.Result
+	count, err := Replay(path, executed.Add(-time.Second), time.Now(), func(result *healthcheck.Result) error {
+		replayed = result
+		return nil
+	})
+	if err != nil || count != 1 {
+		t.Fatalf("Fail to replay the audit logs %d\n%v", count, err)
+	}
+	if replayed.Name != "foo" || replayed.Success || replayed.Message != "connection refused" || replayed.Metadata["reason"] != "timeout" || replayed.HealthcheckTimestamp != executed.Unix() {
+		t.Fatalf("Invalid replayed result %v", replayed)
+	}
+}
@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// Logger writes every healthcheck result to a dedicated sink
+type Logger struct {
+	logger *zap.Logger
+	// closes the audit file
+	closeSink func()
+	// the results logged during Close are dropped
+	lock   sync.RWMutex
+	closed bool
+}
+
+// New creates a new audit logger
+func New(config *Configuration) (*Logger, error) {
+	path := config.Path
+	if path == "" {
+		path = DefaultPath
+	}
+	// the file is opened directly instead of using the zap configuration,
+	// which never closes it
+	sink, closeSink, err := zap.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to create the audit logger on %s", path)
+	}
+	// every result should be logged: no sampling, no caller and no
+	// stacktrace
+	core := zapcore.NewCore(
+		zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+		sink,
+		zap.InfoLevel)
+	return &Logger{
+		logger:    zap.New(core).Named("audit"),
+		closeSink: closeSink,
+	}, nil
+}
+
+// Log logs a result
+func (l *Logger) Log(result *healthcheck.Result) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	if l.closed {
+		return
+	}
+	status := "failure"
+	if result.Success {
+		status = "success"
+	}
+	l.logger.Info("healthcheck result",
+		zap.String("name", result.Name),
+		zap.String("target", result.Target),
+		zap.Bool("success", result.Success),
+		zap.String("status", status),
+		zap.String("message", result.Message),
+		zap.Float64("duration", result.Duration),
+		zap.Reflect("summary", result.Summary),
+		zap.String("source", result.Source),
+		zap.Reflect("labels", result.Labels),
+		zap.Reflect("metadata", result.Metadata),
+		zap.Time("healthcheck-timestamp", result.Time().UTC()),
+	)
+}
+
+// Close flushes the audit logs and closes the file
+func (l *Logger) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	// syncing stdout returns an error on some systems
+	_ = l.logger.Sync()
+	l.closeSink()
+	return nil
+}
@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+func TestLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	logger, err := New(&Configuration{Enabled: true, Path: path})
+	if err != nil {
+		t.Fatalf("Fail to create the audit logger\n%v", err)
+	}
+	for i := 0; i < 200; i++ {
+		logger.Log(&healthcheck.Result{
+			Name:      "foo",
+			Target:    "127.0.0.1",
+			Summary:   "on 127.0.0.1:9000",
+			Success:   false,
+			Message:   "connection refused",
+			Duration:  0.5,
+			Labels:    map[string]string{"env": "prod"},
+			Timestamp: time.Now(),
+		})
+	}
+	err = logger.Close()
+	if err != nil {
+		t.Fatalf("Fail to close the audit logger\n%v", err)
+	}
+	// the results logged once closed are dropped
+	logger.Log(&healthcheck.Result{Name: "bar", Timestamp: time.Now()})
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Fail to close the audit logger twice\n%v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Fail to read the audit logs\n%v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	// the logs should not be sampled
+	if len(lines) != 200 {
+		t.Fatalf("Was expecting 200 logs, got %d", len(lines))
+	}
+	var entry map[string]interface{}
+	err = json.Unmarshal([]byte(lines[0]), &entry)
+	if err != nil {
+		t.Fatalf("Invalid log %s\n%v", lines[0], err)
+	}
+	if entry["name"] != "foo" || entry["target"] != "127.0.0.1" || entry["status"] != "failure" || entry["summary"] != "on 127.0.0.1:9000" || entry["duration"] != 0.5 || entry["logger"] != "audit" {
+		t.Fatalf("Invalid log %v", entry)
+	}
+}
+
+func TestUnmarshalConfig(t *testing.T) {
+	var config Configuration
+	err := yaml.Unmarshal([]byte("path: /tmp/audit.log"), &config)
+	if err == nil {
+		t.Fatalf("The audit path should require the audit to be enabled")
+	}
+	err = yaml.Unmarshal([]byte("enabled: true\npath: /tmp/audit.log"), &config)
+	if err != nil || config.Path != "/tmp/audit.log" {
+		t.Fatalf("Invalid configuration %v: %v", config, err)
+	}
+}
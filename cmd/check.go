@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mcorbin/cabourotte/daemon"
+	"github.com/mcorbin/cabourotte/healthcheck"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+)
+
+// checkCommand the command validating the configuration and executing all
+// healthchecks once
+func checkCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "check",
+		Usage: "validates the configuration and executes all healthchecks once",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "config",
+				Usage:    "Path to the configuration file, or to a directory containing YAML configuration files",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     "profile",
+				Usage:    "Profile overlay to merge into the configuration, read from the profiles directory",
+				EnvVars:  []string{"CABOUROTTE_PROFILE"},
+				Required: false,
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "Output format, table or json",
+				Value: "table",
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "Maximum duration of the whole run",
+				Value: 30 * time.Second,
+			},
+			&cli.BoolFlag{
+				Name:     "debug",
+				Usage:    "Enable debug logging",
+				Required: false,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			format := c.String("format")
+			if format != "table" && format != "json" {
+				return fmt.Errorf("Invalid format %s, should be table or json", format)
+			}
+			config, err := daemon.LoadConfigurationProfile(c.String("config"), c.String("profile"))
+			if err != nil {
+				return err
+			}
+			logger := zap.NewNop()
+			if c.Bool("debug") {
+				logger, err = zap.NewDevelopment()
+				if err != nil {
+					return errors.Wrapf(err, "Fail to start the logger")
+				}
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), c.Duration("timeout"))
+			defer cancel()
+			results, err := daemon.RunOnce(ctx, logger, config)
+			if err != nil {
+				return err
+			}
+			healthcheck.SetTimestampFormat(config.TimestampFormat)
+			if format == "json" {
+				err = printJSON(os.Stdout, results)
+			} else {
+				err = printTable(os.Stdout, results)
+			}
+			if err != nil {
+				return err
+			}
+			for _, result := range results {
+				if !result.Success {
+					return cli.Exit("Some healthchecks failed", 1)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// printJSON prints the results in JSON
+func printJSON(w io.Writer, results []*healthcheck.Result) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
+}
+
+// printTable prints the results in a table
+func printTable(w io.Writer, results []*healthcheck.Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSTATUS\tDURATION\tMESSAGE")
+	for _, result := range results {
+		status := "success"
+		if !result.Success {
+			status = "failure"
+		}
+		duration := time.Duration(result.Duration * float64(time.Second)).Round(time.Millisecond)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", result.Name, status, duration, result.Message)
+	}
+	return tw.Flush()
+}
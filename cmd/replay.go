@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mcorbin/cabourotte/audit"
+	"github.com/mcorbin/cabourotte/daemon"
+	"github.com/mcorbin/cabourotte/exporter"
+	"github.com/mcorbin/cabourotte/healthcheck"
+	"github.com/mcorbin/cabourotte/memorystore"
+	"github.com/mcorbin/cabourotte/prometheus"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+)
+
+// parseReplayTime parses a time of the replay window, a RFC 3339 timestamp or
+// a duration before now
+func parseReplayTime(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid time %s, should be a RFC 3339 timestamp or a duration", value)
+	}
+	return t, nil
+}
+
+// replayCommand the command pushing the results of the audit logs to an
+// exporter, to backfill a new exporter
+func replayCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "replay",
+		Usage: "pushes the results of the audit logs executed during a time window to an exporter",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "config",
+				Usage:    "Path to the configuration file, or to a directory containing YAML configuration files",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     "profile",
+				Usage:    "Profile overlay to merge into the configuration, read from the profiles directory",
+				EnvVars:  []string{"CABOUROTTE_PROFILE"},
+				Required: false,
+			},
+			&cli.StringFlag{
+				Name:     "exporter",
+				Usage:    "Name of the exporter receiving the results",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "audit-file",
+				Usage: "Path to the audit logs, the audit path of the configuration by default",
+			},
+			&cli.StringFlag{
+				Name:     "from",
+				Usage:    "Start of the window, a RFC 3339 timestamp or a duration before now (2h)",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "to",
+				Usage: "End of the window, a RFC 3339 timestamp or a duration before now, now by default",
+			},
+			&cli.Float64Flag{
+				Name:  "rate",
+				Usage: "Maximum number of results pushed per second",
+				Value: 50,
+			},
+			&cli.BoolFlag{
+				Name:     "debug",
+				Usage:    "Enable debug logging",
+				Required: false,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			now := time.Now()
+			from, err := parseReplayTime(c.String("from"), now)
+			if err != nil {
+				return err
+			}
+			to := now
+			if c.String("to") != "" {
+				to, err = parseReplayTime(c.String("to"), now)
+				if err != nil {
+					return err
+				}
+			}
+			if !from.Before(to) {
+				return errors.New("The start of the window should be before its end")
+			}
+			rate := c.Float64("rate")
+			if rate <= 0 {
+				return errors.New("The rate should be positive")
+			}
+			config, err := daemon.LoadConfigurationProfile(c.String("config"), c.String("profile"))
+			if err != nil {
+				return err
+			}
+			path := c.String("audit-file")
+			if path == "" {
+				path = config.Audit.Path
+			}
+			if path == "" || path == "stdout" || path == "stderr" {
+				return errors.New("The audit logs should be written to a file, use the audit-file option")
+			}
+			logger := zap.NewNop()
+			if c.Bool("debug") {
+				logger, err = zap.NewDevelopment()
+				if err != nil {
+					return errors.Wrapf(err, "Fail to start the logger")
+				}
+			}
+			healthcheck.SetTimestampFormat(config.TimestampFormat)
+			prom, err := prometheus.New()
+			if err != nil {
+				return err
+			}
+			exporterComponent, err := exporter.New(logger, memorystore.NewMemoryStore(logger), make(chan *healthcheck.Result), prom, &config.Exporters)
+			if err != nil {
+				return errors.Wrapf(err, "Fail to create the exporters")
+			}
+			target, ok := exporterComponent.Exporters[c.String("exporter")]
+			if !ok {
+				return fmt.Errorf("The exporter %s does not exist", c.String("exporter"))
+			}
+			err = target.Start()
+			if err != nil {
+				return errors.Wrapf(err, "Fail to start the exporter %s", target.Name())
+			}
+			ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+			defer ticker.Stop()
+			count, err := audit.Replay(path, from, to, func(result *healthcheck.Result) error {
+				<-ticker.C
+				return target.Push(result)
+			})
+			stopErr := target.Stop()
+			fmt.Printf("%d results replayed to the exporter %s\n", count, target.Name())
+			if err != nil {
+				return errors.Wrapf(err, "The replay stopped")
+			}
+			return stopErr
+		},
+	}
+}
@@ -2,8 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"gopkg.in/yaml.v2"
-	"io/ioutil"
 	"log"
 	"os"
 	"os/signal"
@@ -27,9 +25,15 @@ func Main() {
 				Flags: []cli.Flag{
 					&cli.StringFlag{
 						Name:     "config",
-						Usage:    "Path to the configuration file",
+						Usage:    "Path to the configuration file, or to a directory containing YAML configuration files",
 						Required: true,
 					},
+					&cli.StringFlag{
+						Name:     "profile",
+						Usage:    "Profile overlay to merge into the configuration, read from the profiles directory",
+						EnvVars:  []string{"CABOUROTTE_PROFILE"},
+						Required: false,
+					},
 					&cli.BoolFlag{
 						Name:     "debug",
 						Usage:    "Enable debug logging",
@@ -37,13 +41,9 @@ func Main() {
 					},
 				},
 				Action: func(c *cli.Context) error {
-					file, err := ioutil.ReadFile(c.String("config"))
+					config, err := daemon.LoadConfigurationProfile(c.String("config"), c.String("profile"))
 					if err != nil {
-						return errors.Wrapf(err, "fail to read the configuration file")
-					}
-					var config daemon.Configuration
-					if err := yaml.Unmarshal(file, &config); err != nil {
-						return errors.Wrapf(err, "Fail to read the yaml config file")
+						return err
 					}
 					zapConfig := zap.NewProductionConfig()
 					if c.Bool("debug") {
@@ -55,7 +55,11 @@ func Main() {
 					}
 					// nolint
 					defer logger.Sync()
-					daemonComponent, err := daemon.New(logger, &config)
+					flush, err := flushSignal(config.FlushSignal)
+					if err != nil {
+						return err
+					}
+					daemonComponent, err := daemon.New(logger, config)
 					if err != nil {
 						return errors.Wrapf(err, "Fail to creae the daemon")
 					}
@@ -67,8 +71,16 @@ func Main() {
 						syscall.SIGINT,
 						syscall.SIGTERM,
 						syscall.SIGHUP)
+					if flush != nil {
+						signal.Notify(signals, flush)
+					}
 					go func() {
 						for sig := range signals {
+							if flush != nil && sig == flush {
+								logger.Info(fmt.Sprintf("Received signal %s, flush the exporters", sig))
+								daemonComponent.FlushExporters()
+								continue
+							}
 							switch sig {
 							case syscall.SIGINT, syscall.SIGTERM:
 								logger.Info(fmt.Sprintf("Received signal %s, shutdown", sig))
@@ -81,19 +93,29 @@ func Main() {
 								errChan <- nil
 							case syscall.SIGHUP:
 								logger.Info(fmt.Sprintf("Received signal %s, reload", sig))
-								newFile, err := ioutil.ReadFile(c.String("config"))
+								newConfig, err := daemon.LoadConfigurationProfile(c.String("config"), c.String("profile"))
+								var newFlush os.Signal
+								if err == nil {
+									newFlush, err = flushSignal(newConfig.FlushSignal)
+								}
 								if err != nil {
-									logger.Error(err.Error())
+									logger.Error(fmt.Sprintf("Invalid configuration, keeping the running one: %s", err.Error()))
+									daemonComponent.Prometheus.RecordReload(err)
 								} else {
-									var newConfig daemon.Configuration
-									if err := yaml.Unmarshal(newFile, &newConfig); err != nil {
-										logger.Error(err.Error())
-									} else {
-										err := daemonComponent.Reload(&newConfig)
-										if err != nil {
-											logger.Error(fmt.Sprintf("Fail to reload: %s", err.Error()))
-											errChan <- err
+									err := daemonComponent.Reload(newConfig)
+									if err != nil {
+										logger.Error(fmt.Sprintf("Fail to reload: %s", err.Error()))
+										errChan <- err
+									}
+									// the flush signal is registered again if it changed
+									if newFlush != flush {
+										if flush != nil {
+											signal.Reset(flush)
+										}
+										if newFlush != nil {
+											signal.Notify(signals, newFlush)
 										}
+										flush = newFlush
 									}
 								}
 							}
@@ -104,6 +126,8 @@ func Main() {
 					return exitErr
 				},
 			},
+			checkCommand(),
+			replayCommand(),
 		},
 	}
 	err := app.Run(os.Args)
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mcorbin/cabourotte/daemon"
+)
+
+// flushSignal returns the signal flushing the exporters buffers, nil if the
+// flush signal is disabled. The flush signal is disabled if it is not set and
+// the default one is not available on this platform.
+func flushSignal(name string) (os.Signal, error) {
+	if name == daemon.NoFlushSignal {
+		return nil, nil
+	}
+	if name == "" {
+		return flushSignals[daemon.DefaultFlushSignal], nil
+	}
+	sig, ok := flushSignals[name]
+	if !ok {
+		return nil, fmt.Errorf("The flush signal %s is not available on this platform, use %s to disable it", name, daemon.NoFlushSignal)
+	}
+	return sig, nil
+}
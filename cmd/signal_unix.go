@@ -0,0 +1,14 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+)
+
+// flushSignals the signals which can flush the exporters buffers
+var flushSignals = map[string]os.Signal{
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package cmd
+
+import (
+	"os"
+)
+
+// flushSignals the signals which can flush the exporters buffers, SIGUSR1 and
+// SIGUSR2 are not available on windows
+var flushSignals = map[string]os.Signal{}
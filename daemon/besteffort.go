@@ -0,0 +1,195 @@
+package daemon
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/mcorbin/cabourotte/exporter"
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// SkippedEntry an invalid healthcheck or exporter ignored by the best-effort
+// loading of the configuration
+type SkippedEntry struct {
+	// the file of the entry, empty when not known
+	File string
+	// the entry section, for example http-checks or exporters.mqtt
+	Section string
+	// the position of the entry in its section
+	Index int
+	// the entry name, empty if it's not set
+	Name  string
+	Error string
+}
+
+// validator validates an entry of a configuration section
+type validator func(content []byte) error
+
+// checkValidator returns a validator decoding and validating a healthcheck
+// configuration
+func checkValidator(newCheck func() interface{ Validate() error }) validator {
+	return func(content []byte) error {
+		check := newCheck()
+		if err := yaml.Unmarshal(content, check); err != nil {
+			return err
+		}
+		return check.Validate()
+	}
+}
+
+// exporterValidator returns a validator decoding an exporter configuration,
+// which is validated while decoded
+func exporterValidator(newExporter func() interface{}) validator {
+	return func(content []byte) error {
+		return yaml.Unmarshal(content, newExporter())
+	}
+}
+
+// validateTCPCheck validates a TCP healthcheck and the checks expanded from
+// its CIDR
+func validateTCPCheck(content []byte) error {
+	var check healthcheck.TCPHealthcheckConfiguration
+	if err := yaml.Unmarshal(content, &check); err != nil {
+		return err
+	}
+	checks, err := expandTCPChecks([]healthcheck.TCPHealthcheckConfiguration{check})
+	if err != nil {
+		return err
+	}
+	for i := range checks {
+		if err := checks[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSections the healthchecks sections loaded in best-effort mode
+var checkSections = map[string]validator{
+	"command-checks": checkValidator(func() interface{ Validate() error } {
+		return &healthcheck.CommandHealthcheckConfiguration{}
+	}),
+	"dns-checks": checkValidator(func() interface{ Validate() error } {
+		return &healthcheck.DNSHealthcheckConfiguration{}
+	}),
+	"tcp-checks": validateTCPCheck,
+	"http-checks": checkValidator(func() interface{ Validate() error } {
+		return &healthcheck.HTTPHealthcheckConfiguration{}
+	}),
+	"tls-checks": checkValidator(func() interface{ Validate() error } {
+		return &healthcheck.TLSHealthcheckConfiguration{}
+	}),
+	"websocket-checks": checkValidator(func() interface{ Validate() error } {
+		return &healthcheck.WebSocketHealthcheckConfiguration{}
+	}),
+	"ssh-checks": checkValidator(func() interface{ Validate() error } {
+		return &healthcheck.SSHHealthcheckConfiguration{}
+	}),
+}
+
+// exporterSections the exporters sections loaded in best-effort mode
+var exporterSections = map[string]validator{
+	"http":        exporterValidator(func() interface{} { return &exporter.HTTPConfiguration{} }),
+	"riemann":     exporterValidator(func() interface{} { return &exporter.RiemannConfiguration{} }),
+	"chat":        exporterValidator(func() interface{} { return &exporter.ChatConfiguration{} }),
+	"grpc":        exporterValidator(func() interface{} { return &exporter.GRPCConfiguration{} }),
+	"openmetrics": exporterValidator(func() interface{} { return &exporter.OpenMetricsConfiguration{} }),
+	"mqtt":        exporterValidator(func() interface{} { return &exporter.MQTTConfiguration{} }),
+	"recording":   exporterValidator(func() interface{} { return &exporter.RecordingConfiguration{} }),
+	"statsd":      exporterValidator(func() interface{} { return &exporter.StatsDConfiguration{} }),
+	"pubsub":      exporterValidator(func() interface{} { return &exporter.PubSubConfiguration{} }),
+	"kafka":       exporterValidator(func() interface{} { return &exporter.KafkaConfiguration{} }),
+	"cloudwatch":  exporterValidator(func() interface{} { return &exporter.CloudWatchConfiguration{} }),
+	"sql":         exporterValidator(func() interface{} { return &exporter.SQLConfiguration{} }),
+}
+
+// entryName returns the name of a configuration entry
+func entryName(entry interface{}) string {
+	name, _ := elementName(entry)
+	return name
+}
+
+// pruneSection removes the invalid entries of a section, and returns them
+func pruneSection(section map[interface{}]interface{}, key string, prefix string, validate validator) ([]SkippedEntry, error) {
+	entries, ok := section[key].([]interface{})
+	if !ok {
+		return nil, nil
+	}
+	var skipped []SkippedEntry
+	valid := make([]interface{}, 0, len(entries))
+	for i, entry := range entries {
+		content, err := yaml.Marshal(entry)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to read the %s%s entries", prefix, key)
+		}
+		if err := validate(content); err != nil {
+			skipped = append(skipped, SkippedEntry{
+				Section: prefix + key,
+				Index:   i,
+				Name:    entryName(entry),
+				Error:   err.Error(),
+			})
+			continue
+		}
+		valid = append(valid, entry)
+	}
+	section[key] = valid
+	return skipped, nil
+}
+
+// pruneInvalid removes the invalid healthchecks and exporters of a raw
+// configuration, and returns the skipped entries
+func pruneInvalid(document map[interface{}]interface{}) ([]SkippedEntry, error) {
+	var skipped []SkippedEntry
+	for key, validate := range checkSections {
+		entries, err := pruneSection(document, key, "", validate)
+		if err != nil {
+			return nil, err
+		}
+		skipped = append(skipped, entries...)
+	}
+	if exporters, ok := document["exporters"].(map[interface{}]interface{}); ok {
+		for key, validate := range exporterSections {
+			entries, err := pruneSection(exporters, key, "exporters.", validate)
+			if err != nil {
+				return nil, err
+			}
+			skipped = append(skipped, entries...)
+		}
+	}
+	sortSkipped(skipped)
+	return skipped, nil
+}
+
+// sortSkipped sorts the skipped entries by file, section and position
+func sortSkipped(skipped []SkippedEntry) {
+	sort.SliceStable(skipped, func(i, j int) bool {
+		a, b := skipped[i], skipped[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Section != b.Section {
+			return a.Section < b.Section
+		}
+		return a.Index < b.Index
+	})
+}
+
+// logSkipped logs the entries skipped by the best-effort loading, and
+// returns their number for each section
+func logSkipped(logger *zap.Logger, skipped []SkippedEntry) map[string]int {
+	sections := make(map[string]int)
+	for _, entry := range skipped {
+		sections[entry.Section]++
+		logger.Error(fmt.Sprintf("Invalid configuration entry %d of %s skipped: %s", entry.Index, entry.Section, entry.Error),
+			zap.String("file", entry.File),
+			zap.String("section", entry.Section),
+			zap.Int("index", entry.Index),
+			zap.String("name", entry.Name))
+	}
+	return sections
+}
@@ -0,0 +1,94 @@
+package daemon
+
+import (
+	"os"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+const bestEffortConfig = `
+http:
+  host: "127.0.0.1"
+  port: 2000
+dns-checks:
+  - name: valid-dns
+    description: bar
+    domain: mcorbin.fr
+    interval: 10s
+  - name: invalid-dns
+    description: bar
+    interval: 10s
+tcp-checks:
+  - name: invalid-tcp
+    target: 127.0.0.1
+    timeout: 2s
+    interval: 10s
+exporters:
+  http:
+    - name: invalid-exporter
+      host: 127.0.0.1
+  riemann:
+    - name: riemann
+      host: 127.0.0.1
+      port: 5555
+`
+
+func TestBestEffortConfiguration(t *testing.T) {
+	var config Configuration
+	err := yaml.Unmarshal([]byte(bestEffortConfig), &config)
+	if err == nil {
+		t.Fatalf("The configuration should be invalid in strict mode")
+	}
+	err = yaml.Unmarshal([]byte("best-effort: true\n"+bestEffortConfig), &config)
+	if err != nil {
+		t.Fatalf("Fail to load the configuration\n%v", err)
+	}
+	if len(config.DNSChecks) != 1 || config.DNSChecks[0].Base.Name != "valid-dns" {
+		t.Fatalf("Invalid DNS checks %v", config.DNSChecks)
+	}
+	if len(config.TCPChecks) != 0 || len(config.Exporters.HTTP) != 0 || len(config.Exporters.Riemann) != 1 {
+		t.Fatalf("Invalid configuration %v", config)
+	}
+	expected := []SkippedEntry{
+		{Section: "dns-checks", Index: 1, Name: "invalid-dns"},
+		{Section: "exporters.http", Index: 0, Name: "invalid-exporter"},
+		{Section: "tcp-checks", Index: 0, Name: "invalid-tcp"},
+	}
+	if len(config.Skipped) != len(expected) {
+		t.Fatalf("Invalid skipped entries %v", config.Skipped)
+	}
+	for i, entry := range expected {
+		skipped := config.Skipped[i]
+		if skipped.Section != entry.Section || skipped.Index != entry.Index || skipped.Name != entry.Name || skipped.Error == "" {
+			t.Fatalf("Invalid skipped entry %v", skipped)
+		}
+	}
+}
+
+func TestBestEffortDirectory(t *testing.T) {
+	directory := writeFiles(t, map[string]string{
+		"main.yaml": "best-effort: true\n" + bestEffortConfig,
+		"tcp.yaml": `
+tcp-checks:
+  - name: valid-tcp
+    target: 127.0.0.1
+    port: 22
+    timeout: 2s
+    interval: 10s
+`,
+	})
+	defer os.RemoveAll(directory)
+	config, err := LoadConfiguration(directory)
+	if err != nil {
+		t.Fatalf("Fail to load the configuration\n%v", err)
+	}
+	if !config.BestEffort || len(config.Skipped) != 3 || len(config.TCPChecks) != 1 {
+		t.Fatalf("Invalid configuration %v", config)
+	}
+	for _, entry := range config.Skipped {
+		if entry.File == "" {
+			t.Fatalf("The file of the skipped entry is missing %v", entry)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package daemon
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// Healthchecks validates and creates the healthchecks of a configuration
+func Healthchecks(logger *zap.Logger, config *Configuration) ([]healthcheck.Healthcheck, error) {
+	checks := []healthcheck.Healthcheck{}
+	for i := range config.CommandChecks {
+		if err := config.CommandChecks[i].Validate(); err != nil {
+			return nil, errors.Wrapf(err, "Invalid healthcheck %s", config.CommandChecks[i].Base.Name)
+		}
+		checks = append(checks, healthcheck.NewCommandHealthcheck(logger, &config.CommandChecks[i]))
+	}
+	for i := range config.DNSChecks {
+		if err := config.DNSChecks[i].Validate(); err != nil {
+			return nil, errors.Wrapf(err, "Invalid healthcheck %s", config.DNSChecks[i].Base.Name)
+		}
+		checks = append(checks, healthcheck.NewDNSHealthcheck(logger, &config.DNSChecks[i]))
+	}
+	for i := range config.TCPChecks {
+		if err := config.TCPChecks[i].Validate(); err != nil {
+			return nil, errors.Wrapf(err, "Invalid healthcheck %s", config.TCPChecks[i].Base.Name)
+		}
+		checks = append(checks, healthcheck.NewTCPHealthcheck(logger, &config.TCPChecks[i]))
+	}
+	for i := range config.HTTPChecks {
+		if err := config.HTTPChecks[i].Validate(); err != nil {
+			return nil, errors.Wrapf(err, "Invalid healthcheck %s", config.HTTPChecks[i].Base.Name)
+		}
+		checks = append(checks, healthcheck.NewHTTPHealthcheck(logger, &config.HTTPChecks[i]))
+	}
+	for i := range config.TLSChecks {
+		if err := config.TLSChecks[i].Validate(); err != nil {
+			return nil, errors.Wrapf(err, "Invalid healthcheck %s", config.TLSChecks[i].Base.Name)
+		}
+		checks = append(checks, healthcheck.NewTLSHealthcheck(logger, &config.TLSChecks[i]))
+	}
+	for i := range config.WebSocketChecks {
+		if err := config.WebSocketChecks[i].Validate(); err != nil {
+			return nil, errors.Wrapf(err, "Invalid healthcheck %s", config.WebSocketChecks[i].Base.Name)
+		}
+		checks = append(checks, healthcheck.NewWebSocketHealthcheck(logger, &config.WebSocketChecks[i]))
+	}
+	for i := range config.SSHChecks {
+		if err := config.SSHChecks[i].Validate(); err != nil {
+			return nil, errors.Wrapf(err, "Invalid healthcheck %s", config.SSHChecks[i].Base.Name)
+		}
+		checks = append(checks, healthcheck.NewSSHHealthcheck(logger, &config.SSHChecks[i]))
+	}
+	for _, check := range checks {
+		if err := check.Initialize(); err != nil {
+			return nil, errors.Wrapf(err, "Fail to initialize healthcheck %s", check.Base().Name)
+		}
+	}
+	return checks, nil
+}
+
+// RunOnce executes once all healthchecks of a configuration, concurrently.
+// The context bounds the whole run. The results are sorted by name.
+func RunOnce(ctx context.Context, logger *zap.Logger, config *Configuration) ([]*healthcheck.Result, error) {
+	logSkipped(logger, config.Skipped)
+	logDuplicates(logger, config)
+	checks, err := Healthchecks(logger, config)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*healthcheck.Result, len(checks))
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			check := checks[i]
+			checkCtx := healthcheck.WithMetadata(ctx)
+			start := time.Now()
+			err := check.Execute(checkCtx)
+			if errors.Is(err, healthcheck.ErrResultSkipped) {
+				// a skipped result is not a failure
+				err = nil
+				healthcheck.AddMetadata(checkCtx, "skipped", "true")
+			}
+			result := healthcheck.NewResult(check, time.Since(start).Seconds(), err)
+			result.Metadata = healthcheck.GetMetadata(checkCtx)
+			healthcheck.ApplyMessageTemplate(check, result)
+			results[i] = result
+		}(i)
+	}
+	wg.Wait()
+	for i, check := range checks {
+		for _, alias := range check.Base().Aliases {
+			results = append(results, results[i].WithAlias(alias))
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+	return results, nil
+}
@@ -0,0 +1,61 @@
+package daemon
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+func TestRunOnce(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to listen :\n%v", err)
+	}
+	defer l.Close()
+	port := uint(l.Addr().(*net.TCPAddr).Port)
+	config := &Configuration{
+		TCPChecks: []healthcheck.TCPHealthcheckConfiguration{
+			{
+				Base: healthcheck.Base{
+					Name:     "open",
+					Interval: healthcheck.Duration(10 * time.Second),
+				},
+				Target:  "127.0.0.1",
+				Port:    port,
+				Timeout: healthcheck.Duration(time.Second),
+			},
+			{
+				Base: healthcheck.Base{
+					Name:     "closed",
+					Interval: healthcheck.Duration(10 * time.Second),
+				},
+				Target:  "127.0.0.1",
+				Port:    1,
+				Timeout: healthcheck.Duration(time.Second),
+			},
+		},
+	}
+	results, err := RunOnce(context.Background(), zap.NewExample(), config)
+	if err != nil {
+		t.Fatalf("Fail to run the healthchecks\n%v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Invalid results %v", results)
+	}
+	if results[0].Name != "closed" || results[0].Success {
+		t.Fatalf("Invalid result %v", results[0])
+	}
+	if results[1].Name != "open" || !results[1].Success {
+		t.Fatalf("Invalid result %v", results[1])
+	}
+	config.TCPChecks[0].Timeout = 0
+	_, err = RunOnce(context.Background(), zap.NewExample(), config)
+	if err == nil {
+		t.Fatalf("The configuration should be validated")
+	}
+}
@@ -0,0 +1,71 @@
+package daemon
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// MaxCIDRHosts the maximum number of healthchecks created from a CIDR target
+const MaxCIDRHosts = 1024
+
+// cidrHosts returns the addresses of the hosts of a network. The network and
+// broadcast addresses of the IPv4 networks are excluded.
+func cidrHosts(network *net.IPNet) ([]net.IP, error) {
+	ones, bits := network.Mask.Size()
+	size := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+	first := new(big.Int).SetBytes(network.IP)
+	if bits == 32 && bits-ones >= 2 {
+		first.Add(first, big.NewInt(1))
+		size.Sub(size, big.NewInt(2))
+	}
+	if size.Cmp(big.NewInt(MaxCIDRHosts)) > 0 {
+		return nil, fmt.Errorf("The network %s contains %s hosts, the maximum is %d", network.String(), size.String(), MaxCIDRHosts)
+	}
+	hosts := make([]net.IP, 0, size.Int64())
+	for i := int64(0); i < size.Int64(); i++ {
+		value := new(big.Int).Add(first, big.NewInt(i)).FillBytes(make([]byte, len(network.IP)))
+		hosts = append(hosts, net.IP(value))
+	}
+	return hosts, nil
+}
+
+// expandTCPChecks replaces the TCP healthchecks having a CIDR target by an
+// healthcheck per host, named <name>-<ip> and labelled with the CIDR
+func expandTCPChecks(checks []healthcheck.TCPHealthcheckConfiguration) ([]healthcheck.TCPHealthcheckConfiguration, error) {
+	if len(checks) == 0 {
+		return checks, nil
+	}
+	result := make([]healthcheck.TCPHealthcheckConfiguration, 0, len(checks))
+	for _, check := range checks {
+		if !strings.Contains(check.Target, "/") {
+			result = append(result, check)
+			continue
+		}
+		ip, network, err := net.ParseCIDR(check.Target)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid CIDR target %s for the healthcheck %s", check.Target, check.Base.Name)
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			network.IP = network.IP.To4()
+		}
+		hosts, err := cidrHosts(network)
+		if err != nil {
+			return nil, fmt.Errorf("Fail to expand the CIDR target of the healthcheck %s: %s", check.Base.Name, err.Error())
+		}
+		for _, host := range hosts {
+			config := check.DeepCopy()
+			config.Base.Name = fmt.Sprintf("%s-%s", check.Base.Name, host.String())
+			config.Target = host.String()
+			if config.Base.Labels == nil {
+				config.Base.Labels = make(map[string]string)
+			}
+			config.Base.Labels["cidr"] = network.String()
+			result = append(result, *config)
+		}
+	}
+	return result, nil
+}
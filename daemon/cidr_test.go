@@ -0,0 +1,84 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+func TestExpandTCPChecks(t *testing.T) {
+	check := healthcheck.TCPHealthcheckConfiguration{
+		Base: healthcheck.Base{
+			Name:     "sweep",
+			Interval: healthcheck.Duration(10 * time.Second),
+			Labels:   map[string]string{"env": "prod"},
+		},
+		Port:    22,
+		Timeout: healthcheck.Duration(time.Second),
+	}
+	cases := []struct {
+		target string
+		names  []string
+	}{
+		{target: "10.0.1.0/30", names: []string{"sweep-10.0.1.1", "sweep-10.0.1.2"}},
+		{target: "10.0.1.5/32", names: []string{"sweep-10.0.1.5"}},
+		{target: "10.0.1.4/31", names: []string{"sweep-10.0.1.4", "sweep-10.0.1.5"}},
+		{target: "2001:db8::/127", names: []string{"sweep-2001:db8::", "sweep-2001:db8::1"}},
+		{target: "10.0.1.1", names: []string{"sweep"}},
+	}
+	for _, c := range cases {
+		check.Target = c.target
+		checks, err := expandTCPChecks([]healthcheck.TCPHealthcheckConfiguration{check})
+		if err != nil {
+			t.Fatalf("Fail to expand %s: %v", c.target, err)
+		}
+		if len(checks) != len(c.names) {
+			t.Fatalf("Invalid checks for %s: %v", c.target, checks)
+		}
+		for i := range checks {
+			if checks[i].Base.Name != c.names[i] {
+				t.Fatalf("Invalid name %s for %s", checks[i].Base.Name, c.target)
+			}
+			if checks[i].Base.Labels["env"] != "prod" {
+				t.Fatalf("Invalid labels %v", checks[i].Base.Labels)
+			}
+			if err := checks[i].Validate(); err != nil {
+				t.Fatalf("Invalid expanded check: %v", err)
+			}
+		}
+	}
+	if check.Base.Labels["cidr"] != "" {
+		t.Fatalf("The original labels should not be modified")
+	}
+	for _, target := range []string{"10.0.0.0/16", "10.0.0.0/40", "2001:db8::/64"} {
+		check.Target = target
+		_, err := expandTCPChecks([]healthcheck.TCPHealthcheckConfiguration{check})
+		if err == nil {
+			t.Fatalf("Was expecting an error for %s", target)
+		}
+	}
+}
+
+func TestUnmarshalCIDRConfiguration(t *testing.T) {
+	var config Configuration
+	err := yaml.Unmarshal([]byte(`
+tcp-checks:
+  - name: sweep
+    target: 10.0.1.0/29
+    port: 22
+    timeout: 1s
+    interval: 10s
+`), &config)
+	if err != nil {
+		t.Fatalf("Fail to unmarshal the configuration: %v", err)
+	}
+	if len(config.TCPChecks) != 6 {
+		t.Fatalf("Invalid checks %v", config.TCPChecks)
+	}
+	if config.TCPChecks[5].Target != "10.0.1.6" || config.TCPChecks[5].Base.Labels["cidr"] != "10.0.1.0/29" {
+		t.Fatalf("Invalid check %v", config.TCPChecks[5])
+	}
+}
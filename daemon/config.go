@@ -1,36 +1,131 @@
 package daemon
 
 import (
+	"fmt"
+
 	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
 
+	"github.com/mcorbin/cabourotte/audit"
 	"github.com/mcorbin/cabourotte/discovery"
+	"github.com/mcorbin/cabourotte/election"
 	"github.com/mcorbin/cabourotte/exporter"
 	"github.com/mcorbin/cabourotte/healthcheck"
 	"github.com/mcorbin/cabourotte/http"
+	"github.com/mcorbin/cabourotte/memorystore"
+	"github.com/mcorbin/cabourotte/profiling"
 )
 
 // Configuration the HTTP server configuration
 type Configuration struct {
-	ResultBuffer  uint `yaml:"result-buffer"`
-	HTTP          http.Configuration
-	CommandChecks []healthcheck.CommandHealthcheckConfiguration `yaml:"command-checks"`
-	DNSChecks     []healthcheck.DNSHealthcheckConfiguration     `yaml:"dns-checks"`
-	TCPChecks     []healthcheck.TCPHealthcheckConfiguration     `yaml:"tcp-checks"`
-	HTTPChecks    []healthcheck.HTTPHealthcheckConfiguration    `yaml:"http-checks"`
-	TLSChecks     []healthcheck.TLSHealthcheckConfiguration     `yaml:"tls-checks"`
-	Exporters     exporter.Configuration
-	Discovery     discovery.Configuration
+	ResultBuffer   uint `yaml:"result-buffer"`
+	HTTP           http.Configuration
+	CommandChecks  []healthcheck.CommandHealthcheckConfiguration `yaml:"command-checks"`
+	DNSChecks      []healthcheck.DNSHealthcheckConfiguration     `yaml:"dns-checks"`
+	TCPChecks      []healthcheck.TCPHealthcheckConfiguration     `yaml:"tcp-checks"`
+	HTTPChecks     []healthcheck.HTTPHealthcheckConfiguration    `yaml:"http-checks"`
+	TLSChecks      []healthcheck.TLSHealthcheckConfiguration     `yaml:"tls-checks"`
+	Heartbeat      *healthcheck.HeartbeatHealthcheckConfiguration
+	Exporters      exporter.Configuration
+	Discovery      discovery.Configuration
+	LeaderElection election.Configuration            `yaml:"leader-election"`
+	DNSCache       healthcheck.DNSCacheConfiguration `yaml:"dns-cache"`
+	// the results timestamps format in the API and the exporters
+	TimestampFormat healthcheck.TimestampFormat `yaml:"timestamp-format"`
+	// logs all results to a dedicated sink
+	Audit audit.Configuration
+	// the thresholds of the healthchecks groups
+	Groups []memorystore.GroupConfiguration
+	// the deduplication of the healthchecks errors logs
+	ErrorLog healthcheck.ErrorLogConfiguration `yaml:"error-log"`
+	// the signal flushing the exporters buffers, SIGUSR1 by default
+	FlushSignal string `yaml:"flush-signal"`
+	// executes the healthchecks on a bounded pool of workers
+	Scheduler healthcheck.SchedulerConfiguration
+	// the pprof endpoints, on a dedicated listener
+	Pprof profiling.Configuration
+	// the healthchecks of WebSocket endpoints
+	WebSocketChecks []healthcheck.WebSocketHealthcheckConfiguration `yaml:"websocket-checks"`
+	// adds a correlation ID to the results, in their metadata and in the
+	// exporters logs and requests
+	CorrelationIDs bool `yaml:"correlation-ids"`
+	// static IPs of hosts, used by the healthchecks instead of the DNS
+	// resolution
+	HostAliases healthcheck.HostAliases `yaml:"host-aliases"`
+	// pauses the healthchecks while all exporters are failing
+	Backpressure exporter.BackpressureConfiguration
+	// skips the invalid healthchecks and exporters instead of failing the
+	// whole configuration
+	BestEffort bool `yaml:"best-effort"`
+	// the entries skipped by the best-effort loading
+	Skipped []SkippedEntry `yaml:"-"`
+	// the healthchecks of SSH servers host keys
+	SSHChecks []healthcheck.SSHHealthcheckConfiguration `yaml:"ssh-checks"`
+	// the healthchecks identical except their names and labels are logged
+	// (warn), executed once (coalesce) or rejected (error)
+	DuplicateChecks string `yaml:"duplicate-checks"`
+	// the identical healthchecks detected when loading the configuration
+	Duplicates []DuplicateCheck `yaml:"-"`
 }
 
+// DefaultFlushSignal the default signal flushing the exporters buffers
+const DefaultFlushSignal = "SIGUSR1"
+
+// NoFlushSignal disables the signal flushing the exporters buffers
+const NoFlushSignal = "none"
+
 // DefaultBufferSize the default siez for the buffer containing healthchecks results
 const DefaultBufferSize = 5000
 
+// bestEffort returns true if the best-effort loading is enabled in a raw
+// configuration
+func bestEffort(unmarshal func(interface{}) error) bool {
+	var mode struct {
+		BestEffort bool `yaml:"best-effort"`
+	}
+	if err := unmarshal(&mode); err != nil {
+		return false
+	}
+	return mode.BestEffort
+}
+
+// unmarshalBestEffort decodes the configuration without its invalid
+// healthchecks and exporters, and returns the skipped entries
+func unmarshalBestEffort(unmarshal func(interface{}) error, raw interface{}) ([]SkippedEntry, error) {
+	document := make(map[interface{}]interface{})
+	if err := unmarshal(&document); err != nil {
+		return nil, errors.Wrap(err, "Unable to read Cabourotte configuration")
+	}
+	skipped, err := pruneInvalid(document)
+	if err != nil {
+		return nil, err
+	}
+	content, err := yaml.Marshal(document)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to read Cabourotte configuration")
+	}
+	if err := yaml.Unmarshal(content, raw); err != nil {
+		return nil, errors.Wrap(err, "Unable to read Cabourotte configuration")
+	}
+	return skipped, nil
+}
+
 // UnmarshalYAML Parse a configuration from YAML.
 func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	chanSize := uint(DefaultBufferSize)
 	type rawConfiguration Configuration
 	raw := rawConfiguration{}
-	if err := unmarshal(&raw); err != nil {
+	unmarshal, err := withDefaults(unmarshal)
+	if err != nil {
+		return err
+	}
+	if bestEffort(unmarshal) {
+		skipped, err := unmarshalBestEffort(unmarshal, &raw)
+		if err != nil {
+			return err
+		}
+		raw.Skipped = skipped
+	} else if err := unmarshal(&raw); err != nil {
 		return errors.Wrap(err, "Unable to read Cabourotte configuration")
 	}
 	for i := range raw.CommandChecks {
@@ -47,6 +142,11 @@ func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) er
 			return errors.Wrap(err, "Invalid healthcheck configuration")
 		}
 	}
+	tcpChecks, err := expandTCPChecks(raw.TCPChecks)
+	if err != nil {
+		return errors.Wrap(err, "Invalid healthcheck configuration")
+	}
+	raw.TCPChecks = tcpChecks
 	for i := range raw.TCPChecks {
 		check := raw.TCPChecks[i]
 		err := check.Validate()
@@ -68,6 +168,55 @@ func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) er
 			return errors.Wrap(err, "Invalid healthcheck configuration")
 		}
 	}
+	for i := range raw.WebSocketChecks {
+		check := raw.WebSocketChecks[i]
+		err := check.Validate()
+		if err != nil {
+			return errors.Wrap(err, "Invalid healthcheck configuration")
+		}
+	}
+	for i := range raw.SSHChecks {
+		check := raw.SSHChecks[i]
+		err := check.Validate()
+		if err != nil {
+			return errors.Wrap(err, "Invalid healthcheck configuration")
+		}
+	}
+	if err := raw.HostAliases.Validate(); err != nil {
+		return errors.Wrap(err, "Invalid host aliases")
+	}
+	if raw.Heartbeat != nil {
+		err := raw.Heartbeat.Validate()
+		if err != nil {
+			return errors.Wrap(err, "Invalid heartbeat configuration")
+		}
+	}
+	if raw.DNSCache.TTL < 0 {
+		return errors.New("The DNS cache TTL should be positive")
+	}
+	if err := raw.TimestampFormat.Validate(); err != nil {
+		return err
+	}
+	if err := raw.ErrorLog.Validate(); err != nil {
+		return err
+	}
+	switch raw.FlushSignal {
+	case "", "SIGUSR1", "SIGUSR2", NoFlushSignal:
+	default:
+		return fmt.Errorf("Invalid flush signal %s, should be SIGUSR1, SIGUSR2 or %s", raw.FlushSignal, NoFlushSignal)
+	}
+	switch raw.DuplicateChecks {
+	case "", DuplicateChecksWarn, DuplicateChecksCoalesce, DuplicateChecksError:
+	default:
+		return fmt.Errorf("Invalid duplicate-checks mode %s, should be %s, %s or %s", raw.DuplicateChecks, DuplicateChecksWarn, DuplicateChecksCoalesce, DuplicateChecksError)
+	}
+	groups := make(map[string]bool)
+	for _, group := range raw.Groups {
+		if groups[group.Name] {
+			return fmt.Errorf("The group %s is duplicated", group.Name)
+		}
+		groups[group.Name] = true
+	}
 	if raw.ResultBuffer == 0 {
 		raw.ResultBuffer = chanSize
 	}
@@ -503,6 +503,28 @@ http-checks:
       - 201
     labels:
       environment: prod
+`,
+		`
+http:
+  host: 127.0.0.1
+  port: 2000
+flush-signal: SIGKILL
+`,
+		`
+http:
+  host: 127.0.0.1
+  port: 2000
+host-aliases:
+  example.com:
+    - 10.0.0.300
+`,
+		`
+http:
+  host: 127.0.0.1
+  port: 2000
+backpressure:
+  enabled: true
+  duration: -10s
 `,
 	}
 	for _, c := range cases {
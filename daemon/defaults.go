@@ -0,0 +1,111 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultsSection the configuration section of the values applied to the
+// healthchecks which don't set them. Its keys apply to all healthchecks,
+// except the healthchecks sections keys (for example tcp-checks) which
+// apply to the healthchecks of one type and take precedence.
+const DefaultsSection = "defaults"
+
+// applyDefaults merges the defaults into the healthchecks of a raw
+// configuration. A key set by an healthcheck is kept even if its value is a
+// zero value, and the maps (for example labels) are merged key by key.
+func applyDefaults(document map[interface{}]interface{}) error {
+	raw, ok := document[DefaultsSection]
+	if !ok || raw == nil {
+		return nil
+	}
+	defaults, ok := raw.(map[interface{}]interface{})
+	if !ok {
+		return errors.New("The defaults section should be a map")
+	}
+	global := make(map[interface{}]interface{})
+	sections := make(map[string]interface{})
+	for k, v := range defaults {
+		key := fmt.Sprintf("%v", k)
+		if key == "name" {
+			return errors.New("The defaults section can't set the healthchecks names")
+		}
+		if _, ok := checkSections[key]; ok {
+			section, ok := v.(map[interface{}]interface{})
+			if !ok {
+				return fmt.Errorf("The defaults of the %s should be a map", key)
+			}
+			if _, ok := section["name"]; ok {
+				return fmt.Errorf("The defaults of the %s can't set the healthchecks names", key)
+			}
+			sections[key] = section
+			continue
+		}
+		global[k] = v
+	}
+	for key := range checkSections {
+		entries, ok := document[key].([]interface{})
+		if !ok {
+			continue
+		}
+		var sectionDefaults interface{} = global
+		if section, ok := sections[key]; ok {
+			sectionDefaults = mergeValues(global, section)
+		}
+		for i, entry := range entries {
+			// the invalid entries are reported when decoding the section
+			if _, ok := entry.(map[interface{}]interface{}); !ok {
+				continue
+			}
+			entries[i] = mergeValues(sectionDefaults, entry)
+		}
+	}
+	return nil
+}
+
+// withDefaults returns an unmarshal function decoding the configuration with
+// its defaults applied
+func withDefaults(unmarshal func(interface{}) error) (func(interface{}) error, error) {
+	document := make(map[interface{}]interface{})
+	if err := unmarshal(&document); err != nil {
+		return nil, errors.Wrap(err, "Unable to read Cabourotte configuration")
+	}
+	if _, ok := document[DefaultsSection]; !ok {
+		return unmarshal, nil
+	}
+	if err := applyDefaults(document); err != nil {
+		return nil, errors.Wrap(err, "Invalid defaults")
+	}
+	content, err := yaml.Marshal(document)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to read Cabourotte configuration")
+	}
+	return func(v interface{}) error {
+		return yaml.Unmarshal(content, v)
+	}, nil
+}
+
+// directoryDefaults returns the defaults section of the configuration files
+// of a directory, which can only be defined in one file
+func directoryDefaults(files []string) (interface{}, error) {
+	var defaults interface{}
+	definedIn := ""
+	for _, file := range files {
+		raw, err := readRaw(file)
+		if err != nil {
+			return nil, err
+		}
+		value, ok := raw[DefaultsSection]
+		if !ok {
+			continue
+		}
+		if definedIn != "" {
+			return nil, fmt.Errorf("The defaults section is defined in both %s and %s", definedIn, file)
+		}
+		defaults = value
+		definedIn = file
+	}
+	return defaults, nil
+}
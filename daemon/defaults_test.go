@@ -0,0 +1,125 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// defaultsConfiguration defaults for all healthchecks and for the TCP and
+// HTTP healthchecks
+const defaultsConfiguration = `
+defaults:
+  interval: 10s
+  timeout: 2s
+  labels:
+    env: prod
+  tcp-checks:
+    resolve-ptr: true
+    labels:
+      protocol: tcp
+  http-checks:
+    valid-status: [200]
+`
+
+// defaultsChecks healthchecks overriding some of the defaults
+const defaultsChecks = `
+tcp-checks:
+  - name: db
+    target: "127.0.0.1"
+    port: 5432
+  - name: cache
+    target: "127.0.0.1"
+    port: 6379
+    interval: 30s
+    resolve-ptr: false
+    labels:
+      env: staging
+      team: a
+http-checks:
+  - name: api
+    target: "127.0.0.1"
+    port: 8080
+    protocol: http
+`
+
+// verifyDefaults verifies the defaults were applied to the healthchecks
+func verifyDefaults(t *testing.T, config *Configuration) {
+	if len(config.TCPChecks) != 2 || len(config.HTTPChecks) != 1 {
+		t.Fatalf("Invalid healthchecks %v %v", config.TCPChecks, config.HTTPChecks)
+	}
+	db := config.TCPChecks[0]
+	if db.Base.Interval != healthcheck.Duration(10*time.Second) || db.Timeout != healthcheck.Duration(2*time.Second) || !db.ResolvePTR {
+		t.Fatalf("The defaults should be applied %+v", db)
+	}
+	if len(db.Base.Labels) != 2 || db.Base.Labels["env"] != "prod" || db.Base.Labels["protocol"] != "tcp" {
+		t.Fatalf("Invalid labels %v", db.Base.Labels)
+	}
+	// the explicit values, including the zero values, are kept
+	cache := config.TCPChecks[1]
+	if cache.Base.Interval != healthcheck.Duration(30*time.Second) || cache.ResolvePTR {
+		t.Fatalf("The healthcheck values should be kept %+v", cache)
+	}
+	if len(cache.Base.Labels) != 3 || cache.Base.Labels["env"] != "staging" || cache.Base.Labels["team"] != "a" || cache.Base.Labels["protocol"] != "tcp" {
+		t.Fatalf("Invalid labels %v", cache.Base.Labels)
+	}
+	api := config.HTTPChecks[0]
+	if len(api.ValidStatus) != 1 || api.ValidStatus[0] != 200 || api.Base.Labels["protocol"] != "" || api.Base.Labels["env"] != "prod" {
+		t.Fatalf("Invalid http healthcheck %+v", api)
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	directory := writeFiles(t, map[string]string{
+		"cabourotte.yaml": defaultsConfiguration + defaultsChecks,
+	})
+	defer os.RemoveAll(directory)
+	config, err := LoadConfiguration(filepath.Join(directory, "cabourotte.yaml"))
+	if err != nil {
+		t.Fatalf("Fail to load the configuration\n%v", err)
+	}
+	verifyDefaults(t, config)
+}
+
+func TestDefaultsDirectory(t *testing.T) {
+	directory := writeFiles(t, map[string]string{
+		"main.yaml":   defaultsConfiguration,
+		"checks.yaml": defaultsChecks,
+	})
+	defer os.RemoveAll(directory)
+	config, err := LoadConfiguration(directory)
+	if err != nil {
+		t.Fatalf("Fail to load the configuration\n%v", err)
+	}
+	verifyDefaults(t, config)
+	directory = writeFiles(t, map[string]string{
+		"main.yaml":   defaultsConfiguration,
+		"checks.yaml": defaultsConfiguration + defaultsChecks,
+	})
+	defer os.RemoveAll(directory)
+	_, err = LoadConfiguration(directory)
+	if err == nil || !strings.Contains(err.Error(), "The defaults section is defined in both") {
+		t.Fatalf("Was expecting an error for the duplicated defaults, got %v", err)
+	}
+}
+
+func TestDefaultsInvalid(t *testing.T) {
+	cases := []string{
+		// the merged healthchecks are validated
+		"defaults:\n  interval: 1s\n" + defaultsChecks,
+		"defaults:\n  name: foo\n" + defaultsChecks,
+		"defaults:\n  tcp-checks: [foo]\n" + defaultsChecks,
+		"defaults: foo\n" + defaultsChecks,
+	}
+	for _, c := range cases {
+		directory := writeFiles(t, map[string]string{"cabourotte.yaml": c})
+		defer os.RemoveAll(directory)
+		if _, err := LoadConfiguration(filepath.Join(directory, "cabourotte.yaml")); err == nil {
+			t.Fatalf("Was expecting an error for the configuration\n%s", c)
+		}
+	}
+}
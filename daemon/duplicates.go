@@ -0,0 +1,124 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// DuplicateChecksWarn logs the identical healthchecks, they are all executed
+const DuplicateChecksWarn = "warn"
+
+// DuplicateChecksCoalesce executes the identical healthchecks once, the
+// results are emitted under all the names
+const DuplicateChecksCoalesce = "coalesce"
+
+// DuplicateChecksError rejects the configurations with identical healthchecks
+const DuplicateChecksError = "error"
+
+// DuplicateCheck an healthcheck identical to another one of its section
+type DuplicateCheck struct {
+	Section string
+	// the first definition of the healthcheck
+	Name      string
+	Duplicate string
+}
+
+// checkSection a section of healthchecks, the checks are a pointer to the
+// slice of configurations
+type checkSection struct {
+	name   string
+	checks interface{}
+}
+
+// checkSections returns the healthchecks sections of the configuration
+func (c *Configuration) checkSections() []checkSection {
+	return []checkSection{
+		{name: "command-checks", checks: &c.CommandChecks},
+		{name: "dns-checks", checks: &c.DNSChecks},
+		{name: "tcp-checks", checks: &c.TCPChecks},
+		{name: "http-checks", checks: &c.HTTPChecks},
+		{name: "tls-checks", checks: &c.TLSChecks},
+		{name: "websocket-checks", checks: &c.WebSocketChecks},
+		{name: "ssh-checks", checks: &c.SSHChecks},
+	}
+}
+
+// identity returns the configuration of an healthcheck without its name,
+// description, labels and source, serialized
+func identity(check reflect.Value) (string, error) {
+	stripped := reflect.New(check.Type()).Elem()
+	stripped.Set(check)
+	base := stripped.FieldByName("Base").Addr().Interface().(*healthcheck.Base)
+	base.Name = ""
+	base.Description = ""
+	base.Labels = nil
+	base.Source = ""
+	content, err := json.Marshal(stripped.Addr().Interface())
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// coalesceChecks detects the healthchecks identical to another one of their
+// section, except their names and labels. Depending on the mode, the
+// duplicates are reported, removed and added as aliases of the first
+// definition, or rejected.
+func (c *Configuration) coalesceChecks() error {
+	c.Duplicates = nil
+	for _, s := range c.checkSections() {
+		section := s.name
+		list := reflect.ValueOf(s.checks).Elem()
+		first := make(map[string]int)
+		kept := reflect.MakeSlice(list.Type(), 0, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			check := list.Index(i)
+			key, err := identity(check)
+			if err != nil {
+				return errors.Wrapf(err, "Fail to compare the healthchecks of %s", section)
+			}
+			base := check.FieldByName("Base").Interface().(healthcheck.Base)
+			index, ok := first[key]
+			if !ok {
+				first[key] = kept.Len()
+				kept = reflect.Append(kept, check)
+				continue
+			}
+			original := kept.Index(index).FieldByName("Base").Addr().Interface().(*healthcheck.Base)
+			duplicate := DuplicateCheck{Section: section, Name: original.Name, Duplicate: base.Name}
+			if c.DuplicateChecks == DuplicateChecksError {
+				return fmt.Errorf("The healthchecks %s and %s of %s are identical", duplicate.Name, duplicate.Duplicate, section)
+			}
+			c.Duplicates = append(c.Duplicates, duplicate)
+			if c.DuplicateChecks != DuplicateChecksCoalesce {
+				kept = reflect.Append(kept, check)
+				continue
+			}
+			original.Aliases = append(original.Aliases, healthcheck.Alias{Name: base.Name, Labels: base.Labels})
+		}
+		if kept.Len() != list.Len() {
+			list.Set(kept)
+		}
+	}
+	return nil
+}
+
+// logDuplicates logs the identical healthchecks of the configuration
+func logDuplicates(logger *zap.Logger, config *Configuration) {
+	for _, duplicate := range config.Duplicates {
+		message := fmt.Sprintf("The healthcheck %s of %s is identical to %s", duplicate.Duplicate, duplicate.Section, duplicate.Name)
+		if config.DuplicateChecks == DuplicateChecksCoalesce {
+			message = fmt.Sprintf("%s, it is executed once and its results are emitted under both names", message)
+		}
+		logger.Warn(message,
+			zap.String("section", duplicate.Section),
+			zap.String("name", duplicate.Name),
+			zap.String("duplicate", duplicate.Duplicate))
+	}
+}
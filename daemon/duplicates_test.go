@@ -0,0 +1,129 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// duplicatesFiles returns configuration files defining the same healthcheck
+// twice, with the duplicate-checks mode
+func duplicatesFiles(mode string) map[string]string {
+	return map[string]string{
+		"main.yaml": "duplicate-checks: " + mode + "\n",
+		"team-a.yaml": `
+tcp-checks:
+  - name: db-a
+    description: team a
+    target: "127.0.0.1"
+    port: 1
+    interval: 10s
+    timeout: 1s
+    labels:
+      team: a
+`,
+		"team-b.yaml": `
+tcp-checks:
+  - name: db-b
+    description: team b
+    target: "127.0.0.1"
+    port: 1
+    interval: 10s
+    timeout: 1s
+    labels:
+      team: b
+  - name: db-b-slow
+    target: "127.0.0.1"
+    port: 1
+    interval: 10s
+    timeout: 2s
+`,
+	}
+}
+
+func TestDuplicateChecksWarn(t *testing.T) {
+	directory := writeFiles(t, duplicatesFiles("warn"))
+	defer os.RemoveAll(directory)
+	config, err := LoadConfiguration(directory)
+	if err != nil {
+		t.Fatalf("Fail to load the configuration\n%v", err)
+	}
+	if len(config.TCPChecks) != 3 {
+		t.Fatalf("All the healthchecks should be kept: %v", config.TCPChecks)
+	}
+	if len(config.Duplicates) != 1 || config.Duplicates[0].Name != "db-a" || config.Duplicates[0].Duplicate != "db-b" {
+		t.Fatalf("Invalid duplicates %v", config.Duplicates)
+	}
+}
+
+func TestDuplicateChecksCoalesce(t *testing.T) {
+	directory := writeFiles(t, duplicatesFiles("coalesce"))
+	defer os.RemoveAll(directory)
+	config, err := LoadConfiguration(directory)
+	if err != nil {
+		t.Fatalf("Fail to load the configuration\n%v", err)
+	}
+	if len(config.TCPChecks) != 2 || config.TCPChecks[0].Base.Name != "db-a" {
+		t.Fatalf("The identical healthchecks should be coalesced: %v", config.TCPChecks)
+	}
+	aliases := config.TCPChecks[0].Base.Aliases
+	if len(aliases) != 1 || aliases[0].Name != "db-b" || aliases[0].Labels["team"] != "b" {
+		t.Fatalf("Invalid aliases %v", aliases)
+	}
+	results, err := RunOnce(context.Background(), zap.NewExample(), config)
+	if err != nil {
+		t.Fatalf("Fail to run the healthchecks\n%v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("The results should be emitted under all names: %v", results)
+	}
+	if results[1].Name != "db-b" || results[1].Labels["team"] != "b" || results[1].Message != results[0].Message {
+		t.Fatalf("Invalid result for the alias %v", results[1])
+	}
+}
+
+func TestDuplicateChecksError(t *testing.T) {
+	directory := writeFiles(t, duplicatesFiles("error"))
+	defer os.RemoveAll(directory)
+	_, err := LoadConfiguration(directory)
+	if err == nil {
+		t.Fatalf("Was expecting an error for the identical healthchecks")
+	}
+	directory = writeFiles(t, duplicatesFiles("merge"))
+	defer os.RemoveAll(directory)
+	_, err = LoadConfiguration(directory)
+	if err == nil {
+		t.Fatalf("Was expecting an error for the invalid mode")
+	}
+}
+
+func TestDuplicateChecksSeverity(t *testing.T) {
+	files := duplicatesFiles("coalesce")
+	files["team-b.yaml"] = `
+tcp-checks:
+  - name: db-b
+    target: "127.0.0.1"
+    port: 1
+    interval: 10s
+    timeout: 1s
+    severity: warning
+  - name: db-b-runbook
+    target: "127.0.0.1"
+    port: 1
+    interval: 10s
+    timeout: 1s
+    runbook-url: https://wiki/db
+`
+	directory := writeFiles(t, files)
+	defer os.RemoveAll(directory)
+	config, err := LoadConfiguration(directory)
+	if err != nil {
+		t.Fatalf("Fail to load the configuration\n%v", err)
+	}
+	// the alerting options are part of the healthcheck identity
+	if len(config.TCPChecks) != 3 || len(config.Duplicates) != 0 {
+		t.Fatalf("The healthchecks with different severities or runbooks should be kept: %v", config.Duplicates)
+	}
+}
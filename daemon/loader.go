@@ -0,0 +1,345 @@
+package daemon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// LoadConfiguration reads the configuration from a YAML file, or from all
+// YAML files of a directory. The identical healthchecks are detected once
+// all files are merged.
+func LoadConfiguration(path string) (*Configuration, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to read the configuration %s", path)
+	}
+	var config *Configuration
+	if !info.IsDir() {
+		config, err = loadFile(path, nil)
+	} else {
+		config, err = loadDirectory(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := config.coalesceChecks(); err != nil {
+		return nil, errors.Wrapf(err, "Invalid configuration %s", path)
+	}
+	return config, nil
+}
+
+// loadFile reads a configuration file. The defaults are applied if the file
+// has no defaults section.
+func loadFile(path string, defaults interface{}) (*Configuration, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to read the configuration file %s", path)
+	}
+	if defaults != nil {
+		raw, err := readRaw(path)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := raw[DefaultsSection]; !ok {
+			raw[DefaultsSection] = defaults
+			file, err = yaml.Marshal(raw)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Fail to apply the defaults to the configuration file %s", path)
+			}
+		}
+	}
+	var config Configuration
+	if err := yaml.Unmarshal(file, &config); err != nil {
+		return nil, errors.Wrapf(err, "Fail to read the yaml config file %s", path)
+	}
+	for i := range config.Skipped {
+		config.Skipped[i].File = path
+	}
+	return &config, nil
+}
+
+// configFiles returns the YAML files of a directory, sorted by name
+func configFiles(directory string) ([]string, error) {
+	entries, err := ioutil.ReadDir(directory)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to read the configuration directory %s", directory)
+	}
+	files := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		if strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml") {
+			files = append(files, filepath.Join(directory, name))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// merger merges configurations from several files, keeping track of
+// where each element was defined
+type merger struct {
+	config    Configuration
+	checks    map[string]string
+	exporters map[string]string
+	sections  map[string]string
+}
+
+// addName registers a name defined in a file, returning an error if it
+// already exists
+func addName(names map[string]string, kind string, name string, file string) error {
+	if existing, ok := names[name]; ok {
+		return fmt.Errorf("The %s %s is defined in both %s and %s", kind, name, existing, file)
+	}
+	names[name] = file
+	return nil
+}
+
+// mergeSection copies a configuration section if it is defined, returning an
+// error if it was already defined in another file
+func (m *merger) mergeSection(name string, file string, target interface{}, value interface{}) error {
+	v := reflect.ValueOf(value)
+	if v.IsZero() {
+		return nil
+	}
+	if existing, ok := m.sections[name]; ok {
+		return fmt.Errorf("The %s section is defined in both %s and %s", name, existing, file)
+	}
+	m.sections[name] = file
+	reflect.ValueOf(target).Elem().Set(v)
+	return nil
+}
+
+// merge merges a configuration read from a file
+func (m *merger) merge(file string, config *Configuration) error {
+	for _, check := range config.CommandChecks {
+		if err := addName(m.checks, "healthcheck", check.Base.Name, file); err != nil {
+			return err
+		}
+	}
+	for _, check := range config.DNSChecks {
+		if err := addName(m.checks, "healthcheck", check.Base.Name, file); err != nil {
+			return err
+		}
+	}
+	for _, check := range config.TCPChecks {
+		if err := addName(m.checks, "healthcheck", check.Base.Name, file); err != nil {
+			return err
+		}
+	}
+	for _, check := range config.HTTPChecks {
+		if err := addName(m.checks, "healthcheck", check.Base.Name, file); err != nil {
+			return err
+		}
+	}
+	for _, check := range config.TLSChecks {
+		if err := addName(m.checks, "healthcheck", check.Base.Name, file); err != nil {
+			return err
+		}
+	}
+	for _, check := range config.WebSocketChecks {
+		if err := addName(m.checks, "healthcheck", check.Base.Name, file); err != nil {
+			return err
+		}
+	}
+	for _, check := range config.SSHChecks {
+		if err := addName(m.checks, "healthcheck", check.Base.Name, file); err != nil {
+			return err
+		}
+	}
+	for _, exporter := range config.Exporters.HTTP {
+		if err := addName(m.exporters, "exporter", exporter.Name, file); err != nil {
+			return err
+		}
+	}
+	for _, exporter := range config.Exporters.Riemann {
+		if err := addName(m.exporters, "exporter", exporter.Name, file); err != nil {
+			return err
+		}
+	}
+	for _, exporter := range config.Exporters.Chat {
+		if err := addName(m.exporters, "exporter", exporter.Name, file); err != nil {
+			return err
+		}
+	}
+	for _, exporter := range config.Exporters.GRPC {
+		if err := addName(m.exporters, "exporter", exporter.Name, file); err != nil {
+			return err
+		}
+	}
+	for _, exporter := range config.Exporters.OpenMetrics {
+		if err := addName(m.exporters, "exporter", exporter.Name, file); err != nil {
+			return err
+		}
+	}
+	for _, exporter := range config.Exporters.MQTT {
+		if err := addName(m.exporters, "exporter", exporter.Name, file); err != nil {
+			return err
+		}
+	}
+	for _, exporter := range config.Exporters.Recording {
+		if err := addName(m.exporters, "exporter", exporter.Name, file); err != nil {
+			return err
+		}
+	}
+	for _, exporter := range config.Exporters.StatsD {
+		if err := addName(m.exporters, "exporter", exporter.Name, file); err != nil {
+			return err
+		}
+	}
+	for _, exporter := range config.Exporters.PubSub {
+		if err := addName(m.exporters, "exporter", exporter.Name, file); err != nil {
+			return err
+		}
+	}
+	for _, exporter := range config.Exporters.Kafka {
+		if err := addName(m.exporters, "exporter", exporter.Name, file); err != nil {
+			return err
+		}
+	}
+	for _, exporter := range config.Exporters.CloudWatch {
+		if err := addName(m.exporters, "exporter", exporter.Name, file); err != nil {
+			return err
+		}
+	}
+	for _, exporter := range config.Exporters.SQL {
+		if err := addName(m.exporters, "exporter", exporter.Name, file); err != nil {
+			return err
+		}
+	}
+	m.config.CommandChecks = append(m.config.CommandChecks, config.CommandChecks...)
+	m.config.DNSChecks = append(m.config.DNSChecks, config.DNSChecks...)
+	m.config.TCPChecks = append(m.config.TCPChecks, config.TCPChecks...)
+	m.config.HTTPChecks = append(m.config.HTTPChecks, config.HTTPChecks...)
+	m.config.TLSChecks = append(m.config.TLSChecks, config.TLSChecks...)
+	m.config.WebSocketChecks = append(m.config.WebSocketChecks, config.WebSocketChecks...)
+	m.config.SSHChecks = append(m.config.SSHChecks, config.SSHChecks...)
+	m.config.Exporters.HTTP = append(m.config.Exporters.HTTP, config.Exporters.HTTP...)
+	m.config.Exporters.Riemann = append(m.config.Exporters.Riemann, config.Exporters.Riemann...)
+	m.config.Exporters.Chat = append(m.config.Exporters.Chat, config.Exporters.Chat...)
+	m.config.Exporters.GRPC = append(m.config.Exporters.GRPC, config.Exporters.GRPC...)
+	m.config.Exporters.OpenMetrics = append(m.config.Exporters.OpenMetrics, config.Exporters.OpenMetrics...)
+	m.config.Exporters.MQTT = append(m.config.Exporters.MQTT, config.Exporters.MQTT...)
+	m.config.Exporters.Recording = append(m.config.Exporters.Recording, config.Exporters.Recording...)
+	m.config.Exporters.StatsD = append(m.config.Exporters.StatsD, config.Exporters.StatsD...)
+	m.config.Exporters.PubSub = append(m.config.Exporters.PubSub, config.Exporters.PubSub...)
+	m.config.Exporters.Kafka = append(m.config.Exporters.Kafka, config.Exporters.Kafka...)
+	m.config.Exporters.CloudWatch = append(m.config.Exporters.CloudWatch, config.Exporters.CloudWatch...)
+	m.config.Exporters.SQL = append(m.config.Exporters.SQL, config.Exporters.SQL...)
+	m.config.Exporters.Groups = append(m.config.Exporters.Groups, config.Exporters.Groups...)
+	m.config.Exporters.Aggregates = append(m.config.Exporters.Aggregates, config.Exporters.Aggregates...)
+	// the default buffer size is set by the configuration parser
+	if config.ResultBuffer != DefaultBufferSize {
+		if err := m.mergeSection("result-buffer", file, &m.config.ResultBuffer, config.ResultBuffer); err != nil {
+			return err
+		}
+	}
+	if err := m.mergeSection("http", file, &m.config.HTTP, config.HTTP); err != nil {
+		return err
+	}
+	if err := m.mergeSection("discovery", file, &m.config.Discovery, config.Discovery); err != nil {
+		return err
+	}
+	if err := m.mergeSection("leader-election", file, &m.config.LeaderElection, config.LeaderElection); err != nil {
+		return err
+	}
+	if err := m.mergeSection("exporters sampling", file, &m.config.Exporters.Sampling, config.Exporters.Sampling); err != nil {
+		return err
+	}
+	if err := m.mergeSection("exporters startup-check", file, &m.config.Exporters.StartupCheck, config.Exporters.StartupCheck); err != nil {
+		return err
+	}
+	if err := m.mergeSection("exporters enrichment", file, &m.config.Exporters.Enrichment, config.Exporters.Enrichment); err != nil {
+		return err
+	}
+	if err := m.mergeSection("dns-cache", file, &m.config.DNSCache, config.DNSCache); err != nil {
+		return err
+	}
+	if err := m.mergeSection("timestamp-format", file, &m.config.TimestampFormat, config.TimestampFormat); err != nil {
+		return err
+	}
+	if err := m.mergeSection("heartbeat", file, &m.config.Heartbeat, config.Heartbeat); err != nil {
+		return err
+	}
+	if err := m.mergeSection("audit", file, &m.config.Audit, config.Audit); err != nil {
+		return err
+	}
+	if err := m.mergeSection("groups", file, &m.config.Groups, config.Groups); err != nil {
+		return err
+	}
+	if err := m.mergeSection("error-log", file, &m.config.ErrorLog, config.ErrorLog); err != nil {
+		return err
+	}
+	if err := m.mergeSection("flush-signal", file, &m.config.FlushSignal, config.FlushSignal); err != nil {
+		return err
+	}
+	if err := m.mergeSection("scheduler", file, &m.config.Scheduler, config.Scheduler); err != nil {
+		return err
+	}
+	if err := m.mergeSection("pprof", file, &m.config.Pprof, config.Pprof); err != nil {
+		return err
+	}
+	if err := m.mergeSection("correlation-ids", file, &m.config.CorrelationIDs, config.CorrelationIDs); err != nil {
+		return err
+	}
+	if err := m.mergeSection("host-aliases", file, &m.config.HostAliases, config.HostAliases); err != nil {
+		return err
+	}
+	if err := m.mergeSection("backpressure", file, &m.config.Backpressure, config.Backpressure); err != nil {
+		return err
+	}
+	if err := m.mergeSection("duplicate-checks", file, &m.config.DuplicateChecks, config.DuplicateChecks); err != nil {
+		return err
+	}
+	// the best-effort loading applies to the file enabling it
+	m.config.BestEffort = m.config.BestEffort || config.BestEffort
+	m.config.Skipped = append(m.config.Skipped, config.Skipped...)
+	return nil
+}
+
+// loadDirectory reads and merges all configuration files of a directory.
+// Healthchecks and exporters names should be unique across files, and the
+// other sections can only be defined in one file.
+func loadDirectory(directory string) (*Configuration, error) {
+	files, err := configFiles(directory)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("No configuration file found in %s", directory)
+	}
+	// the defaults apply to the healthchecks of all files
+	defaults, err := directoryDefaults(files)
+	if err != nil {
+		return nil, err
+	}
+	m := merger{
+		config: Configuration{
+			ResultBuffer: DefaultBufferSize,
+		},
+		checks:    make(map[string]string),
+		exporters: make(map[string]string),
+		sections:  make(map[string]string),
+	}
+	for _, file := range files {
+		config, err := loadFile(file, defaults)
+		if err != nil {
+			return nil, err
+		}
+		err = m.merge(file, config)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to merge the configuration file %s", file)
+		}
+	}
+	return &m.config, nil
+}
@@ -0,0 +1,125 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	directory, err := ioutil.TempDir("", "cabourotte")
+	if err != nil {
+		t.Fatalf("Fail to create the temporary directory\n%v", err)
+	}
+	for name, content := range files {
+		err := ioutil.WriteFile(filepath.Join(directory, name), []byte(content), 0600)
+		if err != nil {
+			t.Fatalf("Fail to write the file %s\n%v", name, err)
+		}
+	}
+	return directory
+}
+
+func TestLoadDirectory(t *testing.T) {
+	directory := writeFiles(t, map[string]string{
+		"main.yaml": `
+http:
+  host: "127.0.0.1"
+  port: 2000
+`,
+		"dns.yaml": `
+dns-checks:
+  - name: foo
+    description: bar
+    domain: mcorbin.fr
+    interval: 10s
+`,
+		"tcp.yml": `
+result-buffer: 100
+tcp-checks:
+  - name: bar
+    description: bar
+    target: "127.0.0.1"
+    port: 8080
+    interval: 10s
+    timeout: 5s
+`,
+		"ignored.txt": "foo",
+	})
+	defer os.RemoveAll(directory)
+	config, err := LoadConfiguration(directory)
+	if err != nil {
+		t.Fatalf("Fail to load the configuration\n%v", err)
+	}
+	if config.HTTP.Port != 2000 {
+		t.Fatalf("Invalid HTTP configuration %v", config.HTTP)
+	}
+	if config.ResultBuffer != 100 {
+		t.Fatalf("Invalid result buffer %d", config.ResultBuffer)
+	}
+	if len(config.DNSChecks) != 1 || config.DNSChecks[0].Base.Name != "foo" {
+		t.Fatalf("Invalid DNS checks %v", config.DNSChecks)
+	}
+	if len(config.TCPChecks) != 1 || config.TCPChecks[0].Base.Name != "bar" {
+		t.Fatalf("Invalid TCP checks %v", config.TCPChecks)
+	}
+}
+
+func TestLoadDirectoryError(t *testing.T) {
+	cases := []struct {
+		files map[string]string
+		error string
+	}{
+		{
+			files: map[string]string{
+				"a.yaml": `
+dns-checks:
+  - name: foo
+    domain: mcorbin.fr
+    interval: 10s
+`,
+				"b.yaml": `
+tcp-checks:
+  - name: foo
+    target: "127.0.0.1"
+    port: 8080
+    interval: 10s
+    timeout: 5s
+`,
+			},
+			error: "healthcheck foo is defined in both",
+		},
+		{
+			files: map[string]string{
+				"a.yaml": `
+http:
+  host: "127.0.0.1"
+  port: 2000
+`,
+				"b.yaml": `
+http:
+  host: "127.0.0.1"
+  port: 2001
+`,
+			},
+			error: "The http section is defined in both",
+		},
+		{
+			files: map[string]string{},
+			error: "No configuration file found",
+		},
+	}
+	for _, c := range cases {
+		directory := writeFiles(t, c.files)
+		_, err := LoadConfiguration(directory)
+		os.RemoveAll(directory)
+		if err == nil {
+			t.Fatalf("Was expecting an error for:\n%v", c.files)
+		}
+		if !strings.Contains(err.Error(), c.error) {
+			t.Fatalf("Invalid error %s", err.Error())
+		}
+	}
+}
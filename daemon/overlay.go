@@ -0,0 +1,155 @@
+package daemon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// ProfileDirectory the directory containing the profiles overlays, next to
+// the configuration file or inside the configuration directory
+const ProfileDirectory = "profiles"
+
+// profilePath returns the path of the overlay of a profile
+func profilePath(path string, profile string, isDir bool) string {
+	directory := path
+	if !isDir {
+		directory = filepath.Dir(path)
+	}
+	return filepath.Join(directory, ProfileDirectory, fmt.Sprintf("%s.yaml", profile))
+}
+
+// readRaw reads a YAML file without decoding it into a configuration
+func readRaw(path string) (map[interface{}]interface{}, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to read the configuration file %s", path)
+	}
+	raw := make(map[interface{}]interface{})
+	if err := yaml.Unmarshal(file, &raw); err != nil {
+		return nil, errors.Wrapf(err, "Fail to read the yaml config file %s", path)
+	}
+	return raw, nil
+}
+
+// elementName returns the name of a list element, if the element is a map
+// containing a name
+func elementName(element interface{}) (string, bool) {
+	m, ok := element.(map[interface{}]interface{})
+	if !ok {
+		return "", false
+	}
+	name, ok := m["name"].(string)
+	return name, ok && name != ""
+}
+
+// mergeLists merges two lists. If all elements have a name (healthchecks,
+// exporters...), elements with the same name are deep merged and new
+// elements are appended. Otherwise, the overlay list replaces the base one.
+func mergeLists(base []interface{}, overlay []interface{}) []interface{} {
+	for _, element := range append(append([]interface{}{}, base...), overlay...) {
+		if _, ok := elementName(element); !ok {
+			return overlay
+		}
+	}
+	result := append([]interface{}{}, base...)
+	for _, element := range overlay {
+		name, _ := elementName(element)
+		merged := false
+		for i := range result {
+			if existing, _ := elementName(result[i]); existing == name {
+				result[i] = mergeValues(result[i], element)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			result = append(result, element)
+		}
+	}
+	return result
+}
+
+// mergeValues deep merges two YAML values, the overlay taking precedence.
+// Maps are merged key by key, lists are merged using mergeLists, and other
+// values are replaced.
+func mergeValues(base interface{}, overlay interface{}) interface{} {
+	switch o := overlay.(type) {
+	case map[interface{}]interface{}:
+		b, ok := base.(map[interface{}]interface{})
+		if !ok {
+			return overlay
+		}
+		result := make(map[interface{}]interface{}, len(b))
+		for k, v := range b {
+			result[k] = v
+		}
+		for k, v := range o {
+			if existing, ok := result[k]; ok {
+				result[k] = mergeValues(existing, v)
+			} else {
+				result[k] = v
+			}
+		}
+		return result
+	case []interface{}:
+		b, ok := base.([]interface{})
+		if !ok {
+			return overlay
+		}
+		return mergeLists(b, o)
+	}
+	return overlay
+}
+
+// LoadConfigurationProfile loads the configuration, and merges the overlay
+// of the profile into it. The result is validated like a single
+// configuration file. If the profile is empty, no overlay is applied.
+func LoadConfigurationProfile(path string, profile string) (*Configuration, error) {
+	// also verifies the base configuration is valid
+	config, err := LoadConfiguration(path)
+	if err != nil || profile == "" {
+		return config, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to read the configuration %s", path)
+	}
+	files := []string{path}
+	if info.IsDir() {
+		files, err = configFiles(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var base interface{} = make(map[interface{}]interface{})
+	for _, file := range files {
+		raw, err := readRaw(file)
+		if err != nil {
+			return nil, err
+		}
+		// the files were already verified, names and sections are not duplicated
+		base = mergeValues(base, raw)
+	}
+	overlayPath := profilePath(path, profile, info.IsDir())
+	overlay, err := readRaw(overlayPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to load the profile %s", profile)
+	}
+	merged, err := yaml.Marshal(mergeValues(base, overlay))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to merge the profile %s", profile)
+	}
+	var result Configuration
+	if err := yaml.Unmarshal(merged, &result); err != nil {
+		return nil, errors.Wrapf(err, "Invalid configuration for the profile %s", profile)
+	}
+	if err := result.coalesceChecks(); err != nil {
+		return nil, errors.Wrapf(err, "Invalid configuration for the profile %s", profile)
+	}
+	return &result, nil
+}
@@ -0,0 +1,96 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+func TestLoadConfigurationProfile(t *testing.T) {
+	directory := writeFiles(t, map[string]string{
+		"main.yaml": `
+http:
+  host: "127.0.0.1"
+  port: 2000
+tcp-checks:
+  - name: foo
+    description: bar
+    target: "127.0.0.1"
+    port: 8080
+    interval: 10s
+    timeout: 5s
+  - name: bar
+    description: bar
+    target: "127.0.0.1"
+    port: 8081
+    interval: 10s
+    timeout: 5s
+`,
+	})
+	defer os.RemoveAll(directory)
+	err := os.Mkdir(filepath.Join(directory, ProfileDirectory), 0700)
+	if err != nil {
+		t.Fatalf("Fail to create the profiles directory\n%v", err)
+	}
+	writeFile := func(name string, content string) {
+		err := ioutil.WriteFile(filepath.Join(directory, ProfileDirectory, name), []byte(content), 0600)
+		if err != nil {
+			t.Fatalf("Fail to write the file %s\n%v", name, err)
+		}
+	}
+	writeFile("prod.yaml", `
+http:
+  port: 3000
+tcp-checks:
+  - name: foo
+    target: "10.0.0.1"
+    interval: 20s
+  - name: baz
+    description: baz
+    target: "10.0.0.2"
+    port: 8082
+    interval: 10s
+    timeout: 5s
+`)
+	writeFile("invalid.yaml", `
+tcp-checks:
+  - name: foo
+    timeout: 50s
+`)
+	config, err := LoadConfigurationProfile(directory, "prod")
+	if err != nil {
+		t.Fatalf("Fail to load the configuration\n%v", err)
+	}
+	if config.HTTP.Port != 3000 || config.HTTP.Host != "127.0.0.1" {
+		t.Fatalf("Invalid HTTP configuration %v", config.HTTP)
+	}
+	if len(config.TCPChecks) != 3 {
+		t.Fatalf("Invalid TCP healthchecks %v", config.TCPChecks)
+	}
+	foo := config.TCPChecks[0]
+	if foo.Base.Name != "foo" || foo.Target != "10.0.0.1" || foo.Port != 8080 || foo.Base.Interval != healthcheck.Duration(20*time.Second) {
+		t.Fatalf("Invalid merged healthcheck %v", foo)
+	}
+	if config.TCPChecks[1].Target != "127.0.0.1" || config.TCPChecks[2].Base.Name != "baz" {
+		t.Fatalf("Invalid TCP healthchecks %v", config.TCPChecks)
+	}
+	config, err = LoadConfigurationProfile(directory, "")
+	if err != nil {
+		t.Fatalf("Fail to load the configuration\n%v", err)
+	}
+	if config.HTTP.Port != 2000 {
+		t.Fatalf("The profile should not be applied %v", config.HTTP)
+	}
+	_, err = LoadConfigurationProfile(directory, "invalid")
+	if err == nil {
+		t.Fatalf("The merged configuration should be validated")
+	}
+	_, err = LoadConfigurationProfile(directory, "missing")
+	if err == nil {
+		t.Fatalf("Was expecting an error for a missing profile")
+	}
+}
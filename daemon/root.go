@@ -3,15 +3,19 @@ package daemon
 import (
 	"reflect"
 	"sync"
+	"sync/atomic"
 
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 
+	"github.com/mcorbin/cabourotte/audit"
 	"github.com/mcorbin/cabourotte/discovery"
+	"github.com/mcorbin/cabourotte/election"
 	"github.com/mcorbin/cabourotte/exporter"
 	"github.com/mcorbin/cabourotte/healthcheck"
 	"github.com/mcorbin/cabourotte/http"
 	"github.com/mcorbin/cabourotte/memorystore"
+	"github.com/mcorbin/cabourotte/profiling"
 	"github.com/mcorbin/cabourotte/prometheus"
 )
 
@@ -26,8 +30,15 @@ type Component struct {
 	Exporter    *exporter.Component
 	Prometheus  *prometheus.Prometheus
 	Discovery   *discovery.Component
+	Election    *election.Component
 	lock        sync.RWMutex
 	ChanResult  chan *healthcheck.Result
+	audit       *audit.Logger
+	profiling   *profiling.Component
+	// nil if the backpressure is disabled
+	backpressure *exporter.Backpressure
+	// 1 once all the components are started
+	started int32
 }
 
 // New creates and start a new daemon component
@@ -37,17 +48,41 @@ func New(logger *zap.Logger, config *Configuration) (*Component, error) {
 	if err != nil {
 		return nil, err
 	}
+	prom.RecordSkippedEntries(logSkipped(logger, config.Skipped))
+	logDuplicates(logger, config)
 	chanResult := make(chan *healthcheck.Result, config.ResultBuffer)
 	checkComponent, err := healthcheck.New(logger, chanResult, prom)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Fail to create the healthcheck component")
 	}
+	checkComponent.SetDNSCache(config.DNSCache)
+	err = checkComponent.SetScheduler(config.Scheduler)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to configure the healthchecks scheduler")
+	}
+	checkComponent.SetCorrelationIDs(config.CorrelationIDs)
+	healthcheck.SetTimestampFormat(config.TimestampFormat)
+	healthcheck.SetErrorLog(config.ErrorLog)
+	healthcheck.SetHostAliases(config.HostAliases)
 	memstore := memorystore.NewMemoryStore(logger)
+	memstore.SetGroups(config.Groups)
+	err = prom.Register(memstore.GroupCollector())
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to register the groups metrics")
+	}
 	memstore.Start()
 	err = checkComponent.Start()
 	if err != nil {
 		return nil, errors.Wrapf(err, "Fail to start the healthcheck component")
 	}
+	var electionComponent *election.Component
+	if config.LeaderElection.Redis.Host != "" {
+		logger.Info("Enabling leader election")
+		electionComponent, err = election.New(logger, &config.LeaderElection, checkComponent, prom)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to create the leader election component")
+		}
+	}
 	http, err := http.New(logger, memstore, prom, &config.HTTP, checkComponent)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Fail to create the HTTP server")
@@ -60,10 +95,19 @@ func New(logger *zap.Logger, config *Configuration) (*Component, error) {
 	if err != nil {
 		return nil, errors.Wrapf(err, "Fail to create the exporter component")
 	}
+	auditLogger, err := newAudit(&config.Audit)
+	if err != nil {
+		return nil, err
+	}
+	exporterComponent.SetAudit(auditLogger)
 	err = exporterComponent.Start()
 	if err != nil {
 		return nil, errors.Wrapf(err, "Fail to start the exporter component")
 	}
+	backpressure, err := newBackpressure(logger, &config.Backpressure, exporterComponent, checkComponent, prom)
+	if err != nil {
+		return nil, err
+	}
 	discoveryComponent, err := discovery.New(logger, config.Discovery, prom, checkComponent)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Fail to create the service discovery component")
@@ -72,22 +116,62 @@ func New(logger *zap.Logger, config *Configuration) (*Component, error) {
 	if err != nil {
 		return nil, errors.Wrapf(err, "Fail to start the service discovery component")
 	}
-	component := Component{
-		MemoryStore: memstore,
-		ChanResult:  chanResult,
-		Config:      config,
-		Prometheus:  prom,
-		HTTP:        http,
-		Logger:      logger,
-		Exporter:    exporterComponent,
-		Discovery:   discoveryComponent,
-		Healthcheck: checkComponent,
+	profilingComponent, err := newProfiling(logger, &config.Pprof)
+	if err != nil {
+		return nil, err
+	}
+	component := &Component{
+		MemoryStore:  memstore,
+		ChanResult:   chanResult,
+		Config:       config,
+		Prometheus:   prom,
+		HTTP:         http,
+		Logger:       logger,
+		Exporter:     exporterComponent,
+		Discovery:    discoveryComponent,
+		Election:     electionComponent,
+		Healthcheck:  checkComponent,
+		audit:        auditLogger,
+		profiling:    profilingComponent,
+		backpressure: backpressure,
 	}
 	err = component.ReloadHealthchecks(config)
 	if err != nil {
 		return nil, err
 	}
-	return &component, nil
+	if electionComponent != nil {
+		err = electionComponent.Start()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to start the leader election component")
+		}
+	}
+	http.SetReadiness(component.ready)
+	http.SetRecorder(exporterComponent)
+	atomic.StoreInt32(&component.started, 1)
+	return component, nil
+}
+
+// ready verifies if Cabourotte is ready: all the components and exporters
+// should be started, and at least one healthcheck should have been executed.
+// A leader election follower, which does not execute the healthchecks, or a
+// configuration without healthchecks does not need a result.
+func (c *Component) ready() error {
+	if atomic.LoadInt32(&c.started) == 0 {
+		return errors.New("Cabourotte is starting")
+	}
+	if !c.Exporter.AllStarted() {
+		return errors.New("Some exporters are not started")
+	}
+	if c.Election != nil && !c.Election.IsLeader() {
+		return nil
+	}
+	if len(c.Healthcheck.ListChecks()) == 0 {
+		return nil
+	}
+	if len(c.MemoryStore.List()) == 0 {
+		return errors.New("No healthcheck executed yet")
+	}
+	return nil
 }
 
 // Stop stops the Cabourotte daemon
@@ -95,28 +179,120 @@ func (c *Component) Stop() error {
 	c.Logger.Info("Stopping the Cabourotte daemon")
 	c.lock.Lock()
 	defer c.lock.Unlock()
+	c.HTTP.SetShuttingDown()
+	if c.Election != nil {
+		err := c.Election.Stop()
+		if err != nil {
+			return errors.Wrapf(err, "Fail to stop the leader election component")
+		}
+	}
 	err := c.Discovery.Stop()
 	if err != nil {
 		return errors.Wrapf(err, "Fail to stop the service discovery component")
 	}
-	err = c.HTTP.Stop()
-	if err != nil {
-		return errors.Wrapf(err, "Fail to stop the HTTP server")
+	if c.backpressure != nil {
+		err = c.backpressure.Stop()
+		if err != nil {
+			return errors.Wrapf(err, "Fail to stop the backpressure component")
+		}
 	}
 	err = c.Healthcheck.Stop()
 	if err != nil {
 		return errors.Wrapf(err, "Fail to stop the healthcheck component")
 	}
+	// the healthchecks are stopped, the pending errors repetitions are logged
+	healthcheck.StopErrorLog()
 	close(c.ChanResult)
 	err = c.Exporter.Stop()
 	if err != nil {
 		return errors.Wrapf(err, "Fail to stop the exporter component")
 	}
+	if c.audit != nil {
+		err = c.audit.Close()
+		if err != nil {
+			return errors.Wrapf(err, "Fail to close the audit logger")
+		}
+	}
+	if c.profiling != nil {
+		err = c.profiling.Stop()
+		if err != nil {
+			return err
+		}
+	}
+	// the HTTP server is stopped last so the readiness endpoint reports
+	// the shutdown while the results are drained
+	err = c.HTTP.Stop()
+	if err != nil {
+		return errors.Wrapf(err, "Fail to stop the HTTP server")
+	}
 	return nil
 }
 
+// newAudit creates the audit logger, or returns nil if the audit is disabled
+func newAudit(config *audit.Configuration) (*audit.Logger, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+	auditLogger, err := audit.New(config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to create the audit logger")
+	}
+	return auditLogger, nil
+}
+
+// newProfiling creates and starts the pprof server, or returns nil if it is
+// disabled
+func newProfiling(logger *zap.Logger, config *profiling.Configuration) (*profiling.Component, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+	component := profiling.New(logger, config)
+	err := component.Start()
+	if err != nil {
+		return nil, err
+	}
+	return component, nil
+}
+
+// newBackpressure creates and starts the backpressure component, or returns
+// nil if it is disabled
+func newBackpressure(logger *zap.Logger, config *exporter.BackpressureConfiguration, exporterComponent *exporter.Component, checkComponent *healthcheck.Component, prom *prometheus.Prometheus) (*exporter.Backpressure, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+	component, err := exporter.NewBackpressure(logger, config, exporterComponent, checkComponent, prom)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to create the backpressure component")
+	}
+	err = component.Start()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to start the backpressure component")
+	}
+	return component, nil
+}
+
+// FlushExporters pushes the results buffered by the exporters, and logs the
+// number of results flushed
+func (c *Component) FlushExporters() {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	flushed := c.Exporter.Flush()
+	total := 0
+	for name, count := range flushed {
+		c.Logger.Info("Exporter flushed",
+			zap.String("exporter", name),
+			zap.Int("results", count))
+		total += count
+	}
+	c.Logger.Info("Exporters flushed", zap.Int("results", total))
+}
+
 // ReloadHealthchecks reloads the healthchecks from a configuration
 func (c *Component) ReloadHealthchecks(daemonConfig *Configuration) error {
+	err := c.reloadHeartbeat(daemonConfig)
+	if err != nil {
+		return err
+	}
 	return c.Healthcheck.ReloadForSource(
 		healthcheck.SourceConfig,
 		nil,
@@ -124,17 +300,68 @@ func (c *Component) ReloadHealthchecks(daemonConfig *Configuration) error {
 		daemonConfig.DNSChecks,
 		daemonConfig.TCPChecks,
 		daemonConfig.HTTPChecks,
-		daemonConfig.TLSChecks)
+		daemonConfig.TLSChecks,
+		daemonConfig.WebSocketChecks,
+		daemonConfig.SSHChecks)
+}
+
+// reloadHeartbeat adds, updates or removes the heartbeat healthcheck
+func (c *Component) reloadHeartbeat(daemonConfig *Configuration) error {
+	oldChecks := c.Healthcheck.SourceChecksNames(healthcheck.SourceHeartbeat)
+	newChecks := make(map[string]bool)
+	if daemonConfig.Heartbeat != nil {
+		config := daemonConfig.Heartbeat.DeepCopy()
+		config.Base.Source = healthcheck.SourceHeartbeat
+		newChecks[config.Base.Name] = true
+		err := c.Healthcheck.AddCheck(healthcheck.NewHeartbeatHealthcheck(c.Logger, config))
+		if err != nil {
+			return errors.Wrapf(err, "Fail to add the heartbeat healthcheck")
+		}
+	}
+	return c.Healthcheck.RemoveNonConfiguredHealthchecks(oldChecks, newChecks)
 }
 
 // Reload reloads the Cabourotte daemon. This function will remove or keep
 // existing healthchecks depending of the new configuration. New checks will be added.
 // The HTTP server will also be reloaded if its configuration has changed.
-func (c *Component) Reload(daemonConfig *Configuration) error {
+func (c *Component) Reload(daemonConfig *Configuration) (err error) {
 	c.Logger.Info("Reloading the Cabourotte daemon")
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	err := c.ReloadHealthchecks(daemonConfig)
+	defer func() {
+		c.Prometheus.RecordReload(err)
+	}()
+	if c.Config.DNSCache != daemonConfig.DNSCache {
+		c.Healthcheck.SetDNSCache(daemonConfig.DNSCache)
+	}
+	c.Healthcheck.SetCorrelationIDs(daemonConfig.CorrelationIDs)
+	healthcheck.SetTimestampFormat(daemonConfig.TimestampFormat)
+	healthcheck.SetHostAliases(daemonConfig.HostAliases)
+	if c.Config.ErrorLog != daemonConfig.ErrorLog {
+		healthcheck.SetErrorLog(daemonConfig.ErrorLog)
+	}
+	if c.Config.Scheduler != daemonConfig.Scheduler {
+		err := c.Healthcheck.SetScheduler(daemonConfig.Scheduler)
+		if err != nil {
+			return errors.Wrapf(err, "Fail to configure the healthchecks scheduler")
+		}
+	}
+	c.MemoryStore.SetGroups(daemonConfig.Groups)
+	if !reflect.DeepEqual(c.Config.Audit, daemonConfig.Audit) {
+		auditLogger, err := newAudit(&daemonConfig.Audit)
+		if err != nil {
+			return err
+		}
+		c.Exporter.SetAudit(auditLogger)
+		if c.audit != nil {
+			// nolint
+			c.audit.Close()
+		}
+		c.audit = auditLogger
+	}
+	c.Prometheus.RecordSkippedEntries(logSkipped(c.Logger, daemonConfig.Skipped))
+	logDuplicates(c.Logger, daemonConfig)
+	err = c.ReloadHealthchecks(daemonConfig)
 	if err != nil {
 		return errors.Wrapf(err, "Fail to reload healthchecks")
 	}
@@ -148,12 +375,42 @@ func (c *Component) Reload(daemonConfig *Configuration) error {
 		if err != nil {
 			return errors.Wrapf(err, "Fail to create the HTTP server")
 		}
+		http.SetReadiness(c.ready)
+		http.SetRecorder(c.Exporter)
 		err = http.Start()
 		if err != nil {
 			return errors.Wrapf(err, "Fail to start the HTTP server")
 		}
 		c.HTTP = http
 	}
+	if c.Config.Pprof != daemonConfig.Pprof {
+		if c.profiling != nil {
+			err := c.profiling.Stop()
+			if err != nil {
+				return err
+			}
+			c.profiling = nil
+		}
+		profilingComponent, err := newProfiling(c.Logger, &daemonConfig.Pprof)
+		if err != nil {
+			return err
+		}
+		c.profiling = profilingComponent
+	}
+	if c.Config.Backpressure != daemonConfig.Backpressure {
+		if c.backpressure != nil {
+			err := c.backpressure.Stop()
+			if err != nil {
+				return errors.Wrapf(err, "Fail to stop the backpressure component")
+			}
+			c.backpressure = nil
+		}
+		backpressure, err := newBackpressure(c.Logger, &daemonConfig.Backpressure, c.Exporter, c.Healthcheck, c.Prometheus)
+		if err != nil {
+			return err
+		}
+		c.backpressure = backpressure
+	}
 	c.Config = daemonConfig
 	c.Logger.Info("Reloaded")
 	return nil
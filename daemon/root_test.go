@@ -7,8 +7,12 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/mcorbin/cabourotte/election"
+	"github.com/mcorbin/cabourotte/exporter"
 	"github.com/mcorbin/cabourotte/healthcheck"
 	"github.com/mcorbin/cabourotte/http"
+	"github.com/mcorbin/cabourotte/memorystore"
+	"github.com/mcorbin/cabourotte/prometheus"
 )
 
 func TestNewStop(t *testing.T) {
@@ -21,12 +25,70 @@ func TestNewStop(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Fail to create the component\n%v", err)
 	}
+	// no healthcheck is configured, the daemon is ready once started
+	if err := component.ready(); err != nil {
+		t.Fatalf("The component should be ready\n%v", err)
+	}
 	err = component.Stop()
 	if err != nil {
 		t.Fatalf("Fail to start the component\n%v", err)
 	}
 }
 
+func TestReady(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	chanResult := make(chan *healthcheck.Result, 10)
+	store := memorystore.NewMemoryStore(logger)
+	checks, err := healthcheck.New(logger, chanResult, prom)
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	// the healthcheck is not executed
+	if err := checks.Suspend(); err != nil {
+		t.Fatalf("Fail to suspend the healthchecks\n%v", err)
+	}
+	err = checks.AddCheck(healthcheck.NewTCPHealthcheck(logger, &healthcheck.TCPHealthcheckConfiguration{
+		Base:    healthcheck.Base{Name: "foo", Interval: healthcheck.Duration(time.Second * 10)},
+		Target:  "127.0.0.1",
+		Port:    2003,
+		Timeout: healthcheck.Duration(time.Second),
+	}))
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	exporterComponent, err := exporter.New(logger, store, chanResult, prom, &exporter.Configuration{})
+	if err != nil {
+		t.Fatalf("Fail to create the exporter component\n%v", err)
+	}
+	component := &Component{
+		Healthcheck: checks,
+		Exporter:    exporterComponent,
+		MemoryStore: store,
+		started:     1,
+	}
+	if err := component.ready(); err == nil {
+		t.Fatalf("The component should wait for a result")
+	}
+	// a follower does not execute the healthchecks
+	electionComponent, err := election.New(logger, &election.Configuration{ID: "foo"}, checks, prom)
+	if err != nil {
+		t.Fatalf("Fail to create the leader election component\n%v", err)
+	}
+	component.Election = electionComponent
+	if err := component.ready(); err != nil {
+		t.Fatalf("A follower should be ready\n%v", err)
+	}
+	component.Election = nil
+	store.Add(&healthcheck.Result{Name: "foo", Success: true, Timestamp: time.Now(), HealthcheckTimestamp: time.Now().Unix()})
+	if err := component.ready(); err != nil {
+		t.Fatalf("The component should be ready\n%v", err)
+	}
+}
+
 func TestReload(t *testing.T) {
 	component, err := New(zap.NewExample(), &Configuration{
 		HTTP: http.Configuration{
@@ -201,3 +263,45 @@ func TestReload(t *testing.T) {
 		t.Fatalf("Fail to start the component\n%v", err)
 	}
 }
+
+func TestReloadHeartbeat(t *testing.T) {
+	component, err := New(zap.NewExample(), &Configuration{
+		HTTP: http.Configuration{
+			Host: "127.0.0.1",
+			Port: 2002,
+		},
+		Heartbeat: &healthcheck.HeartbeatHealthcheckConfiguration{
+			Base: healthcheck.Base{
+				Name:     "heartbeat",
+				Interval: healthcheck.Duration(time.Second * 10),
+				Labels:   map[string]string{"type": "heartbeat"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	check := component.Healthcheck.GetCheck("heartbeat")
+	if check == nil {
+		t.Fatalf("The heartbeat was not added")
+	}
+	if check.Base().Source != healthcheck.SourceHeartbeat {
+		t.Fatalf("Invalid heartbeat source %s", check.Base().Source)
+	}
+	err = component.Reload(&Configuration{
+		HTTP: http.Configuration{
+			Host: "127.0.0.1",
+			Port: 2002,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Fail to reload the component\n%v", err)
+	}
+	if component.Healthcheck.GetCheck("heartbeat") != nil {
+		t.Fatalf("The heartbeat was not removed")
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
@@ -1,10 +1,12 @@
 package discovery
 
 import (
+	"github.com/mcorbin/cabourotte/discovery/consul"
 	"github.com/mcorbin/cabourotte/discovery/http"
 )
 
 // Configuration the service discovery mechanisms configuration
 type Configuration struct {
-	HTTP http.Configuration
+	HTTP   http.Configuration
+	Consul consul.Configuration
 }
@@ -0,0 +1,91 @@
+package consul
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// Configuration the Consul discovery configuration. Healthchecks are created
+// from the templates for each instance of the service.
+type Configuration struct {
+	// the Consul API address, for example http://127.0.0.1:8500
+	Address    string
+	Service    string
+	Tag        string `json:"tag,omitempty" yaml:"tag,omitempty"`
+	Datacenter string `json:"datacenter,omitempty" yaml:"datacenter,omitempty"`
+	// only discover the instances passing their Consul healthchecks
+	Passing  bool
+	Interval healthcheck.Duration `json:"interval"`
+	// the Consul ACL token
+	Token    *healthcheck.Secret `json:"token,omitempty" yaml:"token,omitempty"`
+	Key      string              `json:"key,omitempty"`
+	Cert     string              `json:"cert,omitempty"`
+	Cacert   string              `json:"cacert,omitempty"`
+	Insecure bool
+	// the healthchecks templates, the target and the port are set for
+	// each discovered instance
+	TCPCheck  *healthcheck.TCPHealthcheckConfiguration  `json:"tcp-check,omitempty" yaml:"tcp-check,omitempty"`
+	HTTPCheck *healthcheck.HTTPHealthcheckConfiguration `json:"http-check,omitempty" yaml:"http-check,omitempty"`
+	// only probes a subset of the instances on each interval
+	Sampling *SamplingConfiguration `json:"sampling,omitempty" yaml:"sampling,omitempty"`
+}
+
+// placeholder is used to validate the templates
+var placeholder = instance{Address: "127.0.0.1", Port: 1}
+
+// UnmarshalYAML Parse a configuration from YAML.
+func (configuration *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration Configuration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read Consul discovery configuration")
+	}
+	if raw.Address == "" {
+		return errors.New("Invalid address for the Consul discovery configuration")
+	}
+	if raw.Service == "" {
+		return errors.New("Invalid service for the Consul discovery configuration")
+	}
+	if raw.Interval < healthcheck.Duration(10*time.Second) {
+		return errors.New("The Consul discovery interval should be greater or equal than 10 seconds")
+	}
+	if !((raw.Key != "" && raw.Cert != "") ||
+		(raw.Key == "" && raw.Cert == "")) {
+		return errors.New("Invalid certificates")
+	}
+	if raw.Token != nil {
+		if err := raw.Token.Validate(); err != nil {
+			return errors.Wrap(err, "Invalid Consul token")
+		}
+	}
+	if raw.TCPCheck == nil && raw.HTTPCheck == nil {
+		return errors.New("At least one healthcheck template should be configured for the Consul discovery")
+	}
+	if raw.TCPCheck != nil {
+		config := tcpCheck(raw.TCPCheck, placeholder)
+		if err := config.Validate(); err != nil {
+			return errors.Wrap(err, "Invalid Consul discovery TCP healthcheck template")
+		}
+	}
+	if raw.HTTPCheck != nil {
+		config := httpCheck(raw.HTTPCheck, placeholder)
+		if err := config.Validate(); err != nil {
+			return errors.Wrap(err, "Invalid Consul discovery HTTP healthcheck template")
+		}
+	}
+	if raw.Sampling != nil {
+		// the sampled healthchecks are replaced on each interval, they
+		// should be executed before
+		if raw.TCPCheck != nil && raw.TCPCheck.Base.Interval >= raw.Interval {
+			return errors.New("The Consul discovery TCP healthcheck template interval should be lower than the discovery interval when sampling is enabled")
+		}
+		if raw.HTTPCheck != nil && raw.HTTPCheck.Base.Interval >= raw.Interval {
+			return errors.New("The Consul discovery HTTP healthcheck template interval should be lower than the discovery interval when sampling is enabled")
+		}
+	}
+	*configuration = Configuration(raw)
+	return nil
+}
@@ -0,0 +1,286 @@
+package consul
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gopkg.in/tomb.v2"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+	"github.com/mcorbin/cabourotte/prometheus"
+	"github.com/mcorbin/cabourotte/tls"
+)
+
+// ConsulDiscovery the Consul discovery struct
+type ConsulDiscovery struct {
+	Logger           *zap.Logger
+	requestHistogram *prom.HistogramVec
+	Healthcheck      *healthcheck.Component
+	URL              string
+	Config           *Configuration
+	Client           *http.Client
+	token            string
+	t                tomb.Tomb
+	tick             *time.Ticker
+	sampler          *sampler
+}
+
+// instance a service instance discovered in Consul
+type instance struct {
+	Node    string
+	Address string
+	Port    uint
+	Weight  int
+}
+
+// serviceEntry an entry of the Consul /v1/health/service endpoint
+type serviceEntry struct {
+	Node struct {
+		Node    string
+		Address string
+	}
+	Service struct {
+		Address string
+		Port    uint
+		Weights struct {
+			Passing int
+		}
+	}
+}
+
+// New creates a new Consul Discovery
+func New(logger *zap.Logger, config *Configuration, checkComponent *healthcheck.Component, promComponent *prometheus.Prometheus) (*ConsulDiscovery, error) {
+	tlsConfig, err := tls.GetTLSConfig(config.Key, config.Cert, config.Cacert, config.Insecure)
+	if err != nil {
+		return nil, err
+	}
+	token := ""
+	if config.Token != nil {
+		token, err = config.Token.Resolve()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to load the Consul token")
+		}
+	}
+	query := url.Values{}
+	if config.Passing {
+		query.Set("passing", "true")
+	}
+	if config.Tag != "" {
+		query.Set("tag", config.Tag)
+	}
+	if config.Datacenter != "" {
+		query.Set("dc", config.Datacenter)
+	}
+	serviceURL := fmt.Sprintf(
+		"%s/v1/health/service/%s",
+		strings.TrimSuffix(config.Address, "/"),
+		url.PathEscape(config.Service))
+	if len(query) != 0 {
+		serviceURL = fmt.Sprintf("%s?%s", serviceURL, query.Encode())
+	}
+	buckets := []float64{
+		0.05, 0.1, 0.2, 0.4, 0.8, 1,
+		1.5, 2, 3, 5}
+	histo := prom.NewHistogramVec(prom.HistogramOpts{
+		Name:    "consul_discovery_duration_seconds",
+		Help:    "Time to execute the Consul request for healthchecks discovery.",
+		Buckets: buckets,
+	},
+		[]string{"status"},
+	)
+	err = promComponent.Register(histo)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the consul discovery request histogram")
+	}
+	var instancesSampler *sampler
+	if config.Sampling != nil {
+		instancesSampler = newSampler(config.Sampling)
+		for _, collector := range instancesSampler.collectors() {
+			err = promComponent.Register(collector)
+			if err != nil {
+				return nil, errors.Wrapf(err, "fail to register the consul discovery sampling metrics")
+			}
+		}
+	}
+	component := ConsulDiscovery{
+		sampler:          instancesSampler,
+		Healthcheck:      checkComponent,
+		requestHistogram: histo,
+		Logger:           logger,
+		Config:           config,
+		URL:              serviceURL,
+		token:            token,
+		Client: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+			},
+			Timeout: time.Second * 5,
+		},
+	}
+	return &component, nil
+}
+
+// checkName builds the name of an healthcheck created for an instance
+func checkName(name string, i instance) string {
+	return fmt.Sprintf("%s-%s-%d", name, i.Address, i.Port)
+}
+
+// instanceLabels returns the labels of an healthcheck created for an instance
+func instanceLabels(base healthcheck.Base, i instance) map[string]string {
+	labels := make(map[string]string, len(base.Labels)+1)
+	for k, v := range base.Labels {
+		labels[k] = v
+	}
+	if i.Node != "" {
+		labels["consul-node"] = i.Node
+	}
+	return labels
+}
+
+// tcpCheck creates a TCP healthcheck configuration for an instance
+func tcpCheck(template *healthcheck.TCPHealthcheckConfiguration, i instance) *healthcheck.TCPHealthcheckConfiguration {
+	config := template.DeepCopy()
+	config.Base.Name = checkName(template.Base.Name, i)
+	config.Base.Labels = instanceLabels(template.Base, i)
+	config.Target = i.Address
+	config.Port = i.Port
+	return config
+}
+
+// httpCheck creates an HTTP healthcheck configuration for an instance
+func httpCheck(template *healthcheck.HTTPHealthcheckConfiguration, i instance) *healthcheck.HTTPHealthcheckConfiguration {
+	config := template.DeepCopy()
+	config.Base.Name = checkName(template.Base.Name, i)
+	config.Base.Labels = instanceLabels(template.Base, i)
+	config.Target = i.Address
+	config.Port = i.Port
+	return config
+}
+
+// instances queries Consul for the service instances
+func (c *ConsulDiscovery) instances() ([]instance, error) {
+	req, err := http.NewRequest("GET", c.URL, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Consul discovery: fail to create request for %s", c.URL)
+	}
+	if c.token != "" {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Consul discovery: fail to send request to %s", c.URL)
+	}
+	defer resp.Body.Close()
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to read request body")
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("Consul discovery: request failed, status %d, body %s", resp.StatusCode, string(responseBody))
+	}
+	var entries []serviceEntry
+	if err := json.Unmarshal(responseBody, &entries); err != nil {
+		return nil, fmt.Errorf("Consul discovery: fail to convert the payload %s from json", string(responseBody))
+	}
+	result := make([]instance, 0, len(entries))
+	for _, entry := range entries {
+		address := entry.Service.Address
+		if address == "" {
+			address = entry.Node.Address
+		}
+		if address == "" || entry.Service.Port == 0 {
+			c.Logger.Debug(fmt.Sprintf("Consul discovery: ignoring the instance of node %s without address or port", entry.Node.Node))
+			continue
+		}
+		result = append(result, instance{
+			Node:    entry.Node.Node,
+			Address: address,
+			Port:    entry.Service.Port,
+			Weight:  entry.Service.Weights.Passing,
+		})
+	}
+	return result, nil
+}
+
+func (c *ConsulDiscovery) request() error {
+	instances, err := c.instances()
+	if err != nil {
+		return err
+	}
+	if c.sampler != nil {
+		total := len(instances)
+		instances = c.sampler.sample(instances)
+		probed := make([]string, 0, len(instances))
+		for _, i := range instances {
+			probed = append(probed, instanceKey(i))
+		}
+		c.Logger.Info(fmt.Sprintf("Consul discovery: probing %d of the %d instances of the service %s: %s", len(instances), total, c.Config.Service, strings.Join(probed, ", ")))
+	}
+	tcpChecks := []healthcheck.TCPHealthcheckConfiguration{}
+	httpChecks := []healthcheck.HTTPHealthcheckConfiguration{}
+	for _, i := range instances {
+		if c.Config.TCPCheck != nil {
+			tcpChecks = append(tcpChecks, *tcpCheck(c.Config.TCPCheck, i))
+		}
+		if c.Config.HTTPCheck != nil {
+			httpChecks = append(httpChecks, *httpCheck(c.Config.HTTPCheck, i))
+		}
+	}
+	return c.Healthcheck.ReloadForSource(
+		healthcheck.SourceConsulDiscovery,
+		nil,
+		nil,
+		nil,
+		tcpChecks,
+		httpChecks,
+		nil,
+		nil,
+		nil)
+}
+
+// Start starts the Consul discovery component
+func (c *ConsulDiscovery) Start() error {
+	c.tick = time.NewTicker(time.Duration(c.Config.Interval))
+	c.t.Go(func() error {
+		c.Logger.Info(fmt.Sprintf("Starting the Consul healthcheck discovery for service %s on %s", c.Config.Service, c.Config.Address))
+		for {
+			select {
+			case <-c.tick.C:
+				c.Logger.Debug(fmt.Sprintf("Consul discovery: polling %s", c.URL))
+				start := time.Now()
+				status := "success"
+				err := c.request()
+				duration := time.Since(start)
+				if err != nil {
+					status = "failure"
+					msg := fmt.Sprintf("Consul discovery error: %s", err.Error())
+					c.Logger.Error(msg)
+				}
+				c.requestHistogram.With(prom.Labels{"status": status}).Observe(duration.Seconds())
+			case <-c.t.Dying():
+				return nil
+			}
+		}
+	})
+	return nil
+}
+
+// Stop stops the Consul discovery component
+func (c *ConsulDiscovery) Stop() error {
+	c.Logger.Info("Stopping the consul discovery")
+	c.tick.Stop()
+	c.t.Kill(nil)
+	err := c.t.Wait()
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package consul
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+	"github.com/mcorbin/cabourotte/prometheus"
+)
+
+func TestUnmarshalYAML(t *testing.T) {
+	var config Configuration
+	err := yaml.Unmarshal([]byte(`
+address: http://127.0.0.1:8500
+service: web
+passing: true
+interval: 30s
+token:
+  env: CONSUL_TOKEN
+tcp-check:
+  name: web-tcp
+  interval: 10s
+  timeout: 2s
+`), &config)
+	if err != nil {
+		t.Fatalf("Fail to read the configuration: %v", err)
+	}
+	if config.Service != "web" || !config.Passing || config.TCPCheck.Base.Name != "web-tcp" {
+		t.Fatalf("Invalid configuration %+v", config)
+	}
+	invalid := []string{
+		"address: http://127.0.0.1:8500\nservice: web\ninterval: 30s\n",
+		"address: http://127.0.0.1:8500\ninterval: 30s\ntcp-check:\n  name: foo\n  interval: 10s\n  timeout: 2s\n",
+		"address: http://127.0.0.1:8500\nservice: web\ninterval: 1s\ntcp-check:\n  name: foo\n  interval: 10s\n  timeout: 2s\n",
+		"address: http://127.0.0.1:8500\nservice: web\ninterval: 30s\ntcp-check:\n  name: foo\n  interval: 10s\n",
+	}
+	for _, c := range invalid {
+		var config Configuration
+		if err := yaml.Unmarshal([]byte(c), &config); err == nil {
+			t.Fatalf("Was expecting an error for %s", c)
+		}
+	}
+}
+
+func TestRequest(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom)
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	responses := []string{
+		`[{"Node": {"Node": "n1", "Address": "10.0.0.1"}, "Service": {"Address": "", "Port": 8080}},
+		  {"Node": {"Node": "n2", "Address": "10.0.0.2"}, "Service": {"Address": "10.0.1.2", "Port": 8080}}]`,
+		`[{"Node": {"Node": "n2", "Address": "10.0.0.2"}, "Service": {"Address": "10.0.1.2", "Port": 8080}}]`,
+	}
+	count := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/health/service/web" || r.URL.Query().Get("passing") != "true" || r.Header.Get("X-Consul-Token") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, err := w.Write([]byte(responses[count]))
+		if err != nil {
+			t.Fatalf("Error writing body:\n%v", err)
+		}
+		count++
+	}))
+	defer ts.Close()
+	config := Configuration{
+		Address:  ts.URL,
+		Service:  "web",
+		Passing:  true,
+		Interval: healthcheck.Duration(time.Second * 10),
+		Token:    &healthcheck.Secret{Value: "secret"},
+		TCPCheck: &healthcheck.TCPHealthcheckConfiguration{
+			Base: healthcheck.Base{
+				Name:     "web",
+				Interval: healthcheck.Duration(time.Second * 10),
+				Labels:   map[string]string{"service": "web"},
+			},
+			Timeout: healthcheck.Duration(time.Second * 2),
+		},
+	}
+	discovery, err := New(logger, &config, checkComponent, prom)
+	if err != nil {
+		t.Fatalf("Fail to create the Consul discovery component :\n%v", err)
+	}
+	err = discovery.request()
+	if err != nil {
+		t.Fatalf("Consul discovery request failed\n%v", err)
+	}
+	checks := checkComponent.ListChecks()
+	if len(checks) != 2 {
+		t.Fatalf("Expected 2 configured healthchecks, got %d", len(checks))
+	}
+	names := []string{checks[0].Base().Name, checks[1].Base().Name}
+	sort.Strings(names)
+	if names[0] != "web-10.0.0.1-8080" || names[1] != "web-10.0.1.2-8080" {
+		t.Fatalf("Invalid healthchecks names %v", names)
+	}
+	for _, check := range checks {
+		base := check.Base()
+		if base.Source != healthcheck.SourceConsulDiscovery || base.Labels["service"] != "web" || base.Labels["consul-node"] == "" {
+			t.Fatalf("Invalid healthcheck %+v", base)
+		}
+	}
+	if config.TCPCheck.Base.Labels["consul-node"] != "" {
+		t.Fatalf("The template should not be modified")
+	}
+	err = discovery.request()
+	if err != nil {
+		t.Fatalf("Consul discovery request failed\n%v", err)
+	}
+	checks = checkComponent.ListChecks()
+	if len(checks) != 1 || checks[0].Base().Name != "web-10.0.1.2-8080" {
+		t.Fatalf("The removed instance should not be checked anymore")
+	}
+	config.Token = nil
+	discovery.token = ""
+	err = discovery.request()
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
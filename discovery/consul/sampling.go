@@ -0,0 +1,212 @@
+package consul
+
+import (
+	"math"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultSamplingCycle the default number of discovery intervals in which
+// every instance is probed at least once
+const DefaultSamplingCycle = 10
+
+// SamplingConfiguration only creates the healthchecks of a weighted random
+// subset of the instances on each discovery interval, the subset changing on
+// each interval. The instances are weighted by their Consul passing weight.
+type SamplingConfiguration struct {
+	// the number of instances probed on each interval. It is raised if
+	// needed to probe every instance during the cycle.
+	Size uint
+	// every instance is probed at least once every cycle intervals
+	Cycle uint
+}
+
+// UnmarshalYAML parses the sampling configuration from YAML.
+func (c *SamplingConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration SamplingConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the Consul discovery sampling configuration")
+	}
+	if raw.Size == 0 {
+		return errors.New("The Consul discovery sampling size should be greater than 0")
+	}
+	if raw.Cycle == 0 {
+		raw.Cycle = DefaultSamplingCycle
+	}
+	*c = SamplingConfiguration(raw)
+	return nil
+}
+
+// sampler selects the instances probed on each interval, and keeps track of
+// the last interval each instance was probed
+type sampler struct {
+	config *SamplingConfiguration
+	rand   *rand.Rand
+	// the current discovery interval
+	tick uint64
+	// the last interval each instance was probed
+	probed map[string]uint64
+	// the interval each instance was discovered
+	seen map[string]uint64
+
+	instancesGauge prom.Gauge
+	sampledGauge   prom.Gauge
+	coverageGauge  prom.Gauge
+	oldestGauge    prom.Gauge
+}
+
+// newSampler creates the sampler and its metrics
+func newSampler(config *SamplingConfiguration) *sampler {
+	return &sampler{
+		config: config,
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+		probed: make(map[string]uint64),
+		seen:   make(map[string]uint64),
+		instancesGauge: prom.NewGauge(prom.GaugeOpts{
+			Name: "consul_discovery_instances",
+			Help: "Number of instances discovered in Consul.",
+		}),
+		sampledGauge: prom.NewGauge(prom.GaugeOpts{
+			Name: "consul_discovery_sampled_instances",
+			Help: "Number of instances probed during the current discovery interval.",
+		}),
+		coverageGauge: prom.NewGauge(prom.GaugeOpts{
+			Name: "consul_discovery_sampling_coverage_ratio",
+			Help: "Ratio of the instances probed during the last sampling cycle.",
+		}),
+		oldestGauge: prom.NewGauge(prom.GaugeOpts{
+			Name: "consul_discovery_sampling_oldest_probe_intervals",
+			Help: "Number of discovery intervals since the least recently probed instance was probed.",
+		}),
+	}
+}
+
+// collectors returns the sampler metrics
+func (s *sampler) collectors() []prom.Collector {
+	return []prom.Collector{s.instancesGauge, s.sampledGauge, s.coverageGauge, s.oldestGauge}
+}
+
+// instanceKey identifies an instance
+func instanceKey(i instance) string {
+	return net.JoinHostPort(i.Address, strconv.FormatUint(uint64(i.Port), 10))
+}
+
+// size returns the number of instances probed on each interval
+func (s *sampler) size(total int) int {
+	cycle := s.config.Cycle
+	if cycle == 0 {
+		cycle = DefaultSamplingCycle
+	}
+	size := int(s.config.Size)
+	minimum := (total + int(cycle) - 1) / int(cycle)
+	if size < minimum {
+		size = minimum
+	}
+	return size
+}
+
+// sample selects the instances probed during the next interval. The instances
+// not probed since cycle intervals are always selected, the others are chosen
+// randomly according to their weight.
+func (s *sampler) sample(instances []instance) []instance {
+	s.tick++
+	cycle := uint64(s.config.Cycle)
+	if cycle == 0 {
+		cycle = DefaultSamplingCycle
+	}
+	size := s.size(len(instances))
+	probed := make(map[string]uint64, len(instances))
+	seen := make(map[string]uint64, len(instances))
+	// the last interval each instance was probed, the new ones are
+	// considered probed on the interval before their discovery
+	last := make(map[string]uint64, len(instances))
+	var due []instance
+	type candidate struct {
+		instance instance
+		key      float64
+	}
+	var candidates []candidate
+	for _, i := range instances {
+		k := instanceKey(i)
+		first, ok := s.seen[k]
+		if !ok {
+			first = s.tick
+		}
+		seen[k] = first
+		last[k] = first - 1
+		if tick, ok := s.probed[k]; ok {
+			probed[k] = tick
+			last[k] = tick
+		}
+		if s.tick-last[k] >= cycle {
+			due = append(due, i)
+			continue
+		}
+		weight := float64(i.Weight)
+		if weight <= 0 {
+			weight = 1
+		}
+		// weighted random sampling without replacement: the instances
+		// with the highest u^(1/weight) keys are selected
+		candidates = append(candidates, candidate{
+			instance: i,
+			key:      math.Pow(s.rand.Float64(), 1/weight),
+		})
+	}
+	// the removed instances are forgotten
+	s.probed = probed
+	s.seen = seen
+	// the least recently probed instances first
+	sort.SliceStable(due, func(a, b int) bool {
+		return last[instanceKey(due[a])] < last[instanceKey(due[b])]
+	})
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return candidates[a].key > candidates[b].key
+	})
+	result := due
+	for _, c := range candidates {
+		if len(result) >= size {
+			break
+		}
+		result = append(result, c.instance)
+	}
+	for _, i := range result {
+		k := instanceKey(i)
+		s.probed[k] = s.tick
+		last[k] = s.tick
+	}
+	s.observe(last, len(result), cycle)
+	return result
+}
+
+// observe updates the coverage metrics, last being the last interval each
+// instance was probed
+func (s *sampler) observe(last map[string]uint64, sampled int, cycle uint64) {
+	s.instancesGauge.Set(float64(len(last)))
+	s.sampledGauge.Set(float64(sampled))
+	covered := 0
+	for _, tick := range s.probed {
+		if s.tick-tick < cycle {
+			covered++
+		}
+	}
+	var oldest uint64
+	for _, tick := range last {
+		if s.tick-tick > oldest {
+			oldest = s.tick - tick
+		}
+	}
+	ratio := 1.0
+	if len(last) != 0 {
+		ratio = float64(covered) / float64(len(last))
+	}
+	s.coverageGauge.Set(ratio)
+	s.oldestGauge.Set(float64(oldest))
+}
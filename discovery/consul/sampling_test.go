@@ -0,0 +1,104 @@
+package consul
+
+import (
+	"testing"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"gopkg.in/yaml.v2"
+)
+
+// gaugeValue returns the value of a gauge
+func gaugeValue(t *testing.T, gauge prom.Gauge) float64 {
+	var metric dto.Metric
+	if err := gauge.Write(&metric); err != nil {
+		t.Fatalf("Fail to read the gauge\n%v", err)
+	}
+	return metric.GetGauge().GetValue()
+}
+
+func TestSample(t *testing.T) {
+	instances := make([]instance, 0, 20)
+	for i := 0; i < 20; i++ {
+		weight := 1
+		if i == 0 {
+			weight = 100
+		}
+		instances = append(instances, instance{Address: "10.0.0.1", Port: uint(8000 + i), Weight: weight})
+	}
+	s := newSampler(&SamplingConfiguration{Size: 2, Cycle: 5})
+	last := make(map[string]int)
+	for tick := 1; tick <= 50; tick++ {
+		sampled := s.sample(instances)
+		// the size is raised to probe the 20 instances in 5 intervals
+		if len(sampled) < 4 {
+			t.Fatalf("Invalid sample size %d", len(sampled))
+		}
+		for _, i := range sampled {
+			last[instanceKey(i)] = tick
+		}
+		for _, i := range instances {
+			if tick-last[instanceKey(i)] >= 5 {
+				t.Fatalf("The instance %s was not probed since interval %d, current interval %d", instanceKey(i), last[instanceKey(i)], tick)
+			}
+		}
+		if tick >= 5 && gaugeValue(t, s.coverageGauge) != 1 {
+			t.Fatalf("Invalid coverage ratio %f", gaugeValue(t, s.coverageGauge))
+		}
+		if gaugeValue(t, s.oldestGauge) >= 5 {
+			t.Fatalf("Invalid oldest probe %f", gaugeValue(t, s.oldestGauge))
+		}
+	}
+	if gaugeValue(t, s.instancesGauge) != 20 {
+		t.Fatalf("Invalid instances gauge %f", gaugeValue(t, s.instancesGauge))
+	}
+	// the instances are selected according to their weight
+	weighted := newSampler(&SamplingConfiguration{Size: 5, Cycle: 50})
+	heavy := 0
+	for tick := 0; tick < 40; tick++ {
+		for _, i := range weighted.sample(instances) {
+			if i.Weight == 100 {
+				heavy++
+			}
+		}
+	}
+	if heavy < 35 {
+		t.Fatalf("The heavy instance should be probed more often, probed %d times", heavy)
+	}
+	// the removed instances are forgotten
+	s.sample(instances[:2])
+	if len(s.probed) > 2 || gaugeValue(t, s.instancesGauge) != 2 {
+		t.Fatalf("The removed instances should be forgotten")
+	}
+}
+
+func TestUnmarshalSampling(t *testing.T) {
+	var config Configuration
+	err := yaml.Unmarshal([]byte(`
+address: http://127.0.0.1:8500
+service: web
+interval: 30s
+sampling:
+  size: 10
+tcp-check:
+  name: web-tcp
+  interval: 10s
+  timeout: 2s
+`), &config)
+	if err != nil {
+		t.Fatalf("Fail to read the configuration: %v", err)
+	}
+	if config.Sampling.Size != 10 || config.Sampling.Cycle != DefaultSamplingCycle {
+		t.Fatalf("Invalid sampling configuration %+v", config.Sampling)
+	}
+	invalid := []string{
+		"address: http://127.0.0.1:8500\nservice: web\ninterval: 30s\nsampling:\n  cycle: 2\ntcp-check:\n  name: foo\n  interval: 10s\n  timeout: 2s\n",
+		"address: http://127.0.0.1:8500\nservice: web\ninterval: 30s\nsampling:\n  size: 2\ntcp-check:\n  name: foo\n  interval: 30s\n  timeout: 2s\n",
+	}
+	for _, c := range invalid {
+		var config Configuration
+		if err := yaml.Unmarshal([]byte(c), &config); err == nil {
+			t.Fatalf("Was expecting an error for %s", c)
+		}
+	}
+}
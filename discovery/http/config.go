@@ -24,6 +24,10 @@ type ResultPayload struct {
 	TCPChecks     []healthcheck.TCPHealthcheckConfiguration     `json:"tcp-checks"`
 	HTTPChecks    []healthcheck.HTTPHealthcheckConfiguration    `json:"http-checks"`
 	TLSChecks     []healthcheck.TLSHealthcheckConfiguration     `json:"tls-checks"`
+	// the healthchecks of WebSocket endpoints
+	WebSocketChecks []healthcheck.WebSocketHealthcheckConfiguration `json:"websocket-checks"`
+	// the healthchecks of SSH servers host keys
+	SSHChecks []healthcheck.SSHHealthcheckConfiguration `json:"ssh-checks"`
 }
 
 // UnmarshalYAML Parse a configuration from YAML.
@@ -111,7 +111,9 @@ func (c *HTTPDiscovery) request() error {
 		payload.DNSChecks,
 		payload.TCPChecks,
 		payload.HTTPChecks,
-		payload.TLSChecks)
+		payload.TLSChecks,
+		payload.WebSocketChecks,
+		payload.SSHChecks)
 }
 
 // Start starts the HTTP discovery component
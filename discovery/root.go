@@ -4,6 +4,7 @@ import (
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 
+	"github.com/mcorbin/cabourotte/discovery/consul"
 	dhttp "github.com/mcorbin/cabourotte/discovery/http"
 	"github.com/mcorbin/cabourotte/healthcheck"
 	"github.com/mcorbin/cabourotte/prometheus"
@@ -11,9 +12,10 @@ import (
 
 // Component contains all service discovery instances
 type Component struct {
-	Logger        *zap.Logger
-	HTTPDiscovery *dhttp.HTTPDiscovery
-	Prometheus    *prometheus.Prometheus
+	Logger          *zap.Logger
+	HTTPDiscovery   *dhttp.HTTPDiscovery
+	ConsulDiscovery *consul.ConsulDiscovery
+	Prometheus      *prometheus.Prometheus
 }
 
 // New creates the main component from its configuration
@@ -29,6 +31,14 @@ func New(logger *zap.Logger, config Configuration, promComponent *prometheus.Pro
 		}
 		component.HTTPDiscovery = httpDiscovery
 	}
+	if config.Consul.Address != "" {
+		logger.Info("Enabling Consul discovery")
+		consulDiscovery, err := consul.New(logger, &config.Consul, healthcheck, promComponent)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to create the Consul discovery component")
+		}
+		component.ConsulDiscovery = consulDiscovery
+	}
 	return component, nil
 }
 
@@ -40,6 +50,12 @@ func (c *Component) Start() error {
 			return err
 		}
 	}
+	if c.ConsulDiscovery != nil {
+		err := c.ConsulDiscovery.Start()
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -51,5 +67,11 @@ func (c *Component) Stop() error {
 			return err
 		}
 	}
+	if c.ConsulDiscovery != nil {
+		err := c.ConsulDiscovery.Stop()
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -0,0 +1,62 @@
+package election
+
+import (
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// RedisConfiguration the configuration of the Redis lock backend
+type RedisConfiguration struct {
+	Host     string
+	Port     uint32
+	Password string
+}
+
+// Configuration the leader election configuration
+type Configuration struct {
+	ID            string
+	LockName      string               `yaml:"lock-name"`
+	LeaseDuration healthcheck.Duration `yaml:"lease-duration"`
+	RenewInterval healthcheck.Duration `yaml:"renew-interval"`
+	Redis         RedisConfiguration
+}
+
+// UnmarshalYAML parses the leader election configuration from YAML.
+func (c *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration Configuration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the leader election configuration")
+	}
+	if raw.Redis.Host == "" {
+		return errors.New("Invalid Redis host for the leader election configuration")
+	}
+	if raw.Redis.Port == 0 {
+		return errors.New("Invalid Redis port for the leader election configuration")
+	}
+	if raw.ID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return errors.Wrap(err, "Fail to get the hostname for the leader election ID")
+		}
+		raw.ID = hostname
+	}
+	if raw.LockName == "" {
+		raw.LockName = "cabourotte-leader"
+	}
+	if raw.LeaseDuration == 0 {
+		raw.LeaseDuration = healthcheck.Duration(15 * time.Second)
+	}
+	if raw.RenewInterval == 0 {
+		raw.RenewInterval = raw.LeaseDuration / 3
+	}
+	if raw.RenewInterval >= raw.LeaseDuration {
+		return errors.New("The leader election renew interval should be lower than the lease duration")
+	}
+	*c = Configuration(raw)
+	return nil
+}
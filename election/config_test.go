@@ -0,0 +1,62 @@
+package election
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+func TestUnmarshalConfig(t *testing.T) {
+	in := `
+id: foo
+redis:
+  host: "127.0.0.1"
+  port: 6379
+`
+	var result Configuration
+	if err := yaml.Unmarshal([]byte(in), &result); err != nil {
+		t.Fatalf("Unmarshal yaml error:\n%v", err)
+	}
+	want := Configuration{
+		ID:            "foo",
+		LockName:      "cabourotte-leader",
+		LeaseDuration: healthcheck.Duration(time.Second * 15),
+		RenewInterval: healthcheck.Duration(time.Second * 5),
+		Redis: RedisConfiguration{
+			Host: "127.0.0.1",
+			Port: 6379,
+		},
+	}
+	if result != want {
+		t.Fatalf("Invalid configuration: \n%v\n%v", result, want)
+	}
+}
+
+func TestUnmarshalConfigError(t *testing.T) {
+	cases := []string{
+		`
+redis:
+  port: 6379
+`,
+		`
+redis:
+  host: "127.0.0.1"
+`,
+		`
+lease-duration: 5s
+renew-interval: 10s
+redis:
+  host: "127.0.0.1"
+  port: 6379
+`,
+	}
+	for _, c := range cases {
+		var result Configuration
+		if err := yaml.Unmarshal([]byte(c), &result); err == nil {
+			t.Fatalf("Was expecting an error for:\n%s", c)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package election
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// acquireScript takes the lock if it is free, or renews it if it is already
+// owned by the caller.
+const acquireScript = `local v = redis.call('GET', KEYS[1])
+if v == false then
+  redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+  return 1
+end
+if v == ARGV[1] then
+  redis.call('PEXPIRE', KEYS[1], ARGV[2])
+  return 1
+end
+return 0`
+
+// releaseScript deletes the lock only if it is owned by the caller.
+const releaseScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0`
+
+// RedisLocker a Locker using a Redis key with an expiration as lease
+type RedisLocker struct {
+	Address  string
+	Password string
+	Key      string
+	ID       string
+	Lease    time.Duration
+}
+
+// NewRedisLocker creates a new Redis locker from the configuration
+func NewRedisLocker(config *Configuration) *RedisLocker {
+	return &RedisLocker{
+		Address:  net.JoinHostPort(config.Redis.Host, fmt.Sprintf("%d", config.Redis.Port)),
+		Password: config.Redis.Password,
+		Key:      config.LockName,
+		ID:       config.ID,
+		Lease:    time.Duration(config.LeaseDuration),
+	}
+}
+
+// Acquire acquires or renews the lease
+func (r *RedisLocker) Acquire(ctx context.Context) (bool, error) {
+	lease := strconv.FormatInt(r.Lease.Milliseconds(), 10)
+	reply, err := r.command(ctx, "EVAL", acquireScript, "1", r.Key, r.ID, lease)
+	if err != nil {
+		return false, errors.Wrapf(err, "Fail to acquire the Redis lock %s", r.Key)
+	}
+	return reply == "1", nil
+}
+
+// Release releases the lease if it is owned by this instance
+func (r *RedisLocker) Release(ctx context.Context) error {
+	_, err := r.command(ctx, "EVAL", releaseScript, "1", r.Key, r.ID)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to release the Redis lock %s", r.Key)
+	}
+	return nil
+}
+
+// command opens a connection to Redis, executes a command and returns its
+// reply. Lease operations are infrequent so connections are not reused.
+func (r *RedisLocker) command(ctx context.Context, args ...string) (string, error) {
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", r.Address)
+	if err != nil {
+		return "", errors.Wrapf(err, "Fail to connect to Redis on %s", r.Address)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		err = conn.SetDeadline(deadline)
+		if err != nil {
+			return "", errors.Wrapf(err, "Fail to set the Redis connection deadline")
+		}
+	}
+	reader := bufio.NewReader(conn)
+	if r.Password != "" {
+		_, err := roundTrip(conn, reader, "AUTH", r.Password)
+		if err != nil {
+			return "", errors.Wrapf(err, "Redis authentication failed")
+		}
+	}
+	return roundTrip(conn, reader, args...)
+}
+
+// roundTrip writes a command using the RESP protocol and reads the reply
+func roundTrip(conn net.Conn, reader *bufio.Reader, args ...string) (string, error) {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("*%d\r\n", len(args)))
+	for _, arg := range args {
+		builder.WriteString(fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg))
+	}
+	_, err := conn.Write([]byte(builder.String()))
+	if err != nil {
+		return "", errors.Wrapf(err, "Fail to send the Redis command")
+	}
+	return readReply(reader)
+}
+
+// readReply reads a simple string, integer, error or bulk string RESP reply
+func readReply(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", errors.Wrapf(err, "Fail to read the Redis reply")
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if len(line) == 0 {
+		return "", errors.New("Empty Redis reply")
+	}
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", fmt.Errorf("Redis error: %s", line[1:])
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", errors.Wrapf(err, "Invalid Redis bulk string size %s", line[1:])
+		}
+		if size < 0 {
+			return "", nil
+		}
+		buffer := make([]byte, size+2)
+		_, err = io.ReadFull(reader, buffer)
+		if err != nil {
+			return "", errors.Wrapf(err, "Fail to read the Redis bulk string")
+		}
+		return string(buffer[:size]), nil
+	}
+	return "", fmt.Errorf("Unsupported Redis reply %s", line)
+}
@@ -0,0 +1,155 @@
+package election
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gopkg.in/tomb.v2"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+	"github.com/mcorbin/cabourotte/prometheus"
+)
+
+// Locker the interface for the lock backends
+type Locker interface {
+	Acquire(ctx context.Context) (bool, error)
+	Release(ctx context.Context) error
+}
+
+// Component the leader election component. Only the leader executes the
+// healthchecks, followers keep them suspended until they acquire the lock.
+type Component struct {
+	Logger      *zap.Logger
+	Config      *Configuration
+	Locker      Locker
+	Healthcheck *healthcheck.Component
+	leaderGauge prom.Gauge
+	prometheus  *prometheus.Prometheus
+	// 1 if this instance is the leader, read concurrently with the
+	// election goroutine
+	leader    int32
+	lastRenew time.Time
+	tick      *time.Ticker
+	t         tomb.Tomb
+}
+
+// New creates a new leader election component. The healthchecks are suspended
+// until the lock is acquired.
+func New(logger *zap.Logger, config *Configuration, checkComponent *healthcheck.Component, promComponent *prometheus.Prometheus) (*Component, error) {
+	return newComponent(logger, config, NewRedisLocker(config), checkComponent, promComponent)
+}
+
+func newComponent(logger *zap.Logger, config *Configuration, locker Locker, checkComponent *healthcheck.Component, promComponent *prometheus.Prometheus) (*Component, error) {
+	gauge := prom.NewGauge(prom.GaugeOpts{
+		Name: "leader_election_leader",
+		Help: "1 if this instance is the leader, 0 if it is a follower.",
+	})
+	err := promComponent.Register(gauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the leader election Prometheus gauge")
+	}
+	err = checkComponent.Suspend()
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to suspend the healthchecks")
+	}
+	return &Component{
+		Logger:      logger,
+		Config:      config,
+		Locker:      locker,
+		Healthcheck: checkComponent,
+		leaderGauge: gauge,
+		prometheus:  promComponent,
+	}, nil
+}
+
+// IsLeader returns true if this instance is the leader
+func (c *Component) IsLeader() bool {
+	return atomic.LoadInt32(&c.leader) == 1
+}
+
+// setLeader updates the leader state
+func (c *Component) setLeader(leader bool) {
+	value := int32(0)
+	if leader {
+		value = 1
+	}
+	atomic.StoreInt32(&c.leader, value)
+}
+
+// campaign tries to acquire or renew the lock, and promotes or demotes
+// the instance depending of the result.
+func (c *Component) campaign() {
+	ctx, cancel := context.WithTimeout(c.t.Context(context.Background()), time.Duration(c.Config.RenewInterval))
+	defer cancel()
+	acquired, err := c.Locker.Acquire(ctx)
+	if err != nil {
+		c.Logger.Error(fmt.Sprintf("Leader election error: %s", err.Error()))
+		// the lease is still valid, stay leader until it expires
+		if c.IsLeader() && time.Since(c.lastRenew) < time.Duration(c.Config.LeaseDuration) {
+			return
+		}
+		acquired = false
+	}
+	if acquired {
+		c.lastRenew = time.Now()
+	}
+	if acquired && !c.IsLeader() {
+		c.Logger.Info(fmt.Sprintf("Instance %s is now the leader", c.Config.ID))
+		c.setLeader(true)
+		c.leaderGauge.Set(1)
+		c.Healthcheck.Resume()
+	} else if !acquired && c.IsLeader() {
+		c.Logger.Info(fmt.Sprintf("Instance %s is now a follower", c.Config.ID))
+		c.setLeader(false)
+		c.leaderGauge.Set(0)
+		err := c.Healthcheck.Suspend()
+		if err != nil {
+			c.Logger.Error(fmt.Sprintf("Fail to suspend healthchecks: %s", err.Error()))
+		}
+	}
+}
+
+// Start starts the leader election
+func (c *Component) Start() error {
+	c.Logger.Info(fmt.Sprintf("Starting the leader election for instance %s", c.Config.ID))
+	c.tick = time.NewTicker(time.Duration(c.Config.RenewInterval))
+	c.t.Go(func() error {
+		c.campaign()
+		for {
+			select {
+			case <-c.tick.C:
+				c.campaign()
+			case <-c.t.Dying():
+				return nil
+			}
+		}
+	})
+	return nil
+}
+
+// Stop stops the leader election, releasing the lock if needed
+func (c *Component) Stop() error {
+	c.Logger.Info("Stopping the leader election")
+	c.tick.Stop()
+	c.t.Kill(nil)
+	err := c.t.Wait()
+	if err != nil {
+		return err
+	}
+	c.prometheus.Unregister(c.leaderGauge)
+	if c.IsLeader() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.Config.RenewInterval))
+		defer cancel()
+		err := c.Locker.Release(ctx)
+		if err != nil {
+			return errors.Wrapf(err, "Fail to release the leader election lock")
+		}
+		c.setLeader(false)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package election
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+	"github.com/mcorbin/cabourotte/prometheus"
+)
+
+type fakeLocker struct {
+	acquired bool
+	err      error
+	released bool
+}
+
+func (l *fakeLocker) Acquire(ctx context.Context) (bool, error) {
+	return l.acquired, l.err
+}
+
+func (l *fakeLocker) Release(ctx context.Context) error {
+	l.released = true
+	return nil
+}
+
+func TestCampaign(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom)
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	locker := &fakeLocker{}
+	config := &Configuration{
+		ID:            "foo",
+		LeaseDuration: healthcheck.Duration(time.Second * 15),
+		RenewInterval: healthcheck.Duration(time.Second * 5),
+	}
+	component, err := newComponent(logger, config, locker, checkComponent, prom)
+	if err != nil {
+		t.Fatalf("Fail to create the leader election component\n%v", err)
+	}
+	if !checkComponent.IsSuspended() {
+		t.Fatalf("Healthchecks should be suspended until the lock is acquired")
+	}
+	component.campaign()
+	if component.IsLeader() || !checkComponent.IsSuspended() {
+		t.Fatalf("The instance should be a follower")
+	}
+	locker.acquired = true
+	component.campaign()
+	if !component.IsLeader() || checkComponent.IsSuspended() {
+		t.Fatalf("The instance should be the leader")
+	}
+	// a backend error keeps the leadership while the lease is valid
+	locker.err = errors.New("unavailable")
+	component.campaign()
+	if !component.IsLeader() {
+		t.Fatalf("The instance should still be the leader")
+	}
+	component.lastRenew = time.Now().Add(-time.Minute)
+	component.campaign()
+	if component.IsLeader() || !checkComponent.IsSuspended() {
+		t.Fatalf("The instance should be a follower after the lease expiration")
+	}
+	locker.err = nil
+	locker.acquired = false
+	component.campaign()
+	if component.IsLeader() {
+		t.Fatalf("The instance should be a follower")
+	}
+}
+
+func TestIsLeaderConcurrent(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom)
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	locker := &fakeLocker{acquired: true}
+	config := &Configuration{
+		ID:            "foo",
+		LeaseDuration: healthcheck.Duration(time.Second * 15),
+		RenewInterval: healthcheck.Duration(time.Millisecond * 10),
+	}
+	component, err := newComponent(logger, config, locker, checkComponent, prom)
+	if err != nil {
+		t.Fatalf("Fail to create the leader election component\n%v", err)
+	}
+	if err := component.Start(); err != nil {
+		t.Fatalf("Fail to start the leader election\n%v", err)
+	}
+	// the leader state is read while the election goroutine updates it
+	deadline := time.Now().Add(5 * time.Second)
+	for !component.IsLeader() {
+		if time.Now().After(deadline) {
+			t.Fatalf("The instance should be the leader")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := component.Stop(); err != nil {
+		t.Fatalf("Fail to stop the leader election\n%v", err)
+	}
+	if component.IsLeader() || !locker.released {
+		t.Fatalf("The lock should be released")
+	}
+}
+
+func TestRedisLocker(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to start the listener\n%v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			reader := bufio.NewReader(conn)
+			// read the array header and the 6 arguments of the EVAL command
+			for i := 0; i < 13; i++ {
+				_, err := reader.ReadString('\n')
+				if err != nil {
+					break
+				}
+			}
+			_, _ = conn.Write([]byte(":1\r\n"))
+			conn.Close()
+		}
+	}()
+	addr := listener.Addr().(*net.TCPAddr)
+	locker := NewRedisLocker(&Configuration{
+		ID:            "foo",
+		LockName:      "lock",
+		LeaseDuration: healthcheck.Duration(time.Second * 15),
+		Redis: RedisConfiguration{
+			Host: "127.0.0.1",
+			Port: uint32(addr.Port),
+		},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+	acquired, err := locker.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Fail to acquire the lock\n%v", err)
+	}
+	if !acquired {
+		t.Fatalf("The lock should be acquired")
+	}
+}
@@ -0,0 +1,207 @@
+package exporter
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// DefaultPercentiles the percentiles computed if none are configured
+var DefaultPercentiles = []float64{0.5, 0.9, 0.99}
+
+// AggregateConfiguration the configuration of an aggregation. The results are
+// aggregated per healthcheck over the window, and only the rollups are pushed
+// to the exporter.
+type AggregateConfiguration struct {
+	Name        string
+	Window      healthcheck.Duration
+	Percentiles []float64
+	Exporter    string
+}
+
+// UnmarshalYAML parses the configuration of an aggregation from YAML.
+func (c *AggregateConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration AggregateConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the aggregate configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid name for the aggregate configuration")
+	}
+	if raw.Exporter == "" {
+		return errors.New("Invalid exporter for the aggregate configuration")
+	}
+	if raw.Window < healthcheck.Duration(time.Second) {
+		return errors.New("The aggregate window should be greater than 1 second")
+	}
+	if len(raw.Percentiles) == 0 {
+		raw.Percentiles = DefaultPercentiles
+	}
+	for _, p := range raw.Percentiles {
+		if p <= 0 || p > 1 {
+			return fmt.Errorf("Invalid percentile %g for the aggregate %s", p, raw.Name)
+		}
+	}
+	*c = AggregateConfiguration(raw)
+	return nil
+}
+
+// validateAggregates verifies the aggregates configuration and returns the
+// names of the exporters receiving rollups
+func validateAggregates(aggregates []AggregateConfiguration, exporters map[string]Exporter, grouped map[string]bool) (map[string]bool, error) {
+	aggregated := make(map[string]bool)
+	names := make(map[string]bool)
+	for _, aggregate := range aggregates {
+		if _, ok := names[aggregate.Name]; ok {
+			return nil, fmt.Errorf("Aggregate %s is defined multiple times", aggregate.Name)
+		}
+		names[aggregate.Name] = true
+		if _, ok := exporters[aggregate.Exporter]; !ok {
+			return nil, fmt.Errorf("Unknown exporter %s in the aggregate %s", aggregate.Exporter, aggregate.Name)
+		}
+		if _, ok := grouped[aggregate.Exporter]; ok {
+			return nil, fmt.Errorf("The exporter %s can not belong to an exporter group and an aggregate", aggregate.Exporter)
+		}
+		if _, ok := aggregated[aggregate.Exporter]; ok {
+			return nil, fmt.Errorf("The exporter %s belongs to several aggregates", aggregate.Exporter)
+		}
+		aggregated[aggregate.Exporter] = true
+	}
+	return aggregated, nil
+}
+
+// samples the results of an healthcheck during the window
+type samples struct {
+	last      *healthcheck.Result
+	successes int
+	durations []float64
+}
+
+// aggregator aggregates the results of the healthchecks
+type aggregator struct {
+	config  *AggregateConfiguration
+	lock    sync.Mutex
+	samples map[string]*samples
+	// receives the flush requests, the number of pushed rollups is sent
+	// back on the channel
+	flushRequests chan chan int
+}
+
+func newAggregator(config *AggregateConfiguration) *aggregator {
+	return &aggregator{
+		config:        config,
+		samples:       make(map[string]*samples),
+		flushRequests: make(chan chan int),
+	}
+}
+
+// add adds a result to the current window
+func (a *aggregator) add(result *healthcheck.Result) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	s, ok := a.samples[result.Name]
+	if !ok {
+		s = &samples{}
+		a.samples[result.Name] = s
+	}
+	s.last = result
+	if result.Success {
+		s.successes++
+	}
+	s.durations = append(s.durations, result.Duration)
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// flush returns the rollups of the current window, sorted by healthcheck
+// name, and starts a new window
+func (a *aggregator) flush() []*healthcheck.Result {
+	a.lock.Lock()
+	current := a.samples
+	a.samples = make(map[string]*samples)
+	a.lock.Unlock()
+	now := time.Now()
+	rollups := make([]*healthcheck.Result, 0, len(current))
+	for name, s := range current {
+		count := len(s.durations)
+		sort.Float64s(s.durations)
+		total := float64(0)
+		for _, d := range s.durations {
+			total += d
+		}
+		ratio := float64(s.successes) / float64(count)
+		metadata := map[string]string{
+			"count":         strconv.Itoa(count),
+			"success-ratio": strconv.FormatFloat(ratio, 'f', 4, 64),
+		}
+		for _, p := range a.config.Percentiles {
+			key := fmt.Sprintf("duration-p%s", strconv.FormatFloat(p*100, 'f', -1, 64))
+			metadata[key] = strconv.FormatFloat(percentile(s.durations, p), 'f', -1, 64)
+		}
+		rollups = append(rollups, &healthcheck.Result{
+			Name:                 name,
+			Summary:              s.last.Summary,
+			Labels:               s.last.Labels,
+			Source:               s.last.Source,
+			Success:              s.successes == count,
+			HealthcheckTimestamp: now.Unix(),
+			Timestamp:            now,
+			Duration:             total / float64(count),
+			Message:              fmt.Sprintf("%d results over %s, success ratio %s", count, time.Duration(a.config.Window), metadata["success-ratio"]),
+			Metadata:             metadata,
+		})
+	}
+	sort.Slice(rollups, func(i, j int) bool {
+		return rollups[i].Name < rollups[j].Name
+	})
+	return rollups
+}
+
+// startAggregator periodically pushes the rollups of an aggregator to its exporter
+func (c *Component) startAggregator(a *aggregator) {
+	ticker := time.NewTicker(time.Duration(a.config.Window))
+	c.t.Go(func() error {
+		defer ticker.Stop()
+		exporter := c.Exporters[a.config.Exporter]
+		for {
+			select {
+			case <-ticker.C:
+				c.pushRollups(a, exporter)
+			case done := <-a.flushRequests:
+				done <- c.pushRollups(a, exporter)
+			case <-c.t.Dying():
+				return nil
+			}
+		}
+	})
+}
+
+// pushRollups pushes the rollups of the current window of an aggregator to its
+// exporter. Returns the number of rollups pushed.
+func (c *Component) pushRollups(a *aggregator, exporter Exporter) int {
+	pushed := 0
+	for _, rollup := range a.flush() {
+		if !exporter.IsStarted() {
+			c.reconnect(exporter)
+		}
+		if exporter.IsStarted() && c.push(exporter, rollup) {
+			pushed++
+		}
+	}
+	return pushed
+}
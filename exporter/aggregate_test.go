@@ -0,0 +1,80 @@
+package exporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+func TestAggregatorFlush(t *testing.T) {
+	a := newAggregator(&AggregateConfiguration{
+		Name:        "rollup",
+		Window:      healthcheck.Duration(time.Minute),
+		Percentiles: []float64{0.5, 0.9},
+		Exporter:    "http",
+	})
+	for i := 1; i <= 10; i++ {
+		a.add(&healthcheck.Result{
+			Name:     "foo",
+			Success:  i != 10,
+			Duration: float64(i),
+		})
+	}
+	a.add(&healthcheck.Result{Name: "bar", Success: true, Duration: 1})
+	rollups := a.flush()
+	if len(rollups) != 2 {
+		t.Fatalf("Invalid rollups %v", rollups)
+	}
+	bar := rollups[0]
+	foo := rollups[1]
+	if bar.Name != "bar" || !bar.Success || bar.Metadata["count"] != "1" {
+		t.Fatalf("Invalid rollup %v", bar)
+	}
+	if foo.Success {
+		t.Fatalf("The rollup should be a failure")
+	}
+	if foo.Duration != 5.5 {
+		t.Fatalf("Invalid mean duration %f", foo.Duration)
+	}
+	expected := map[string]string{
+		"count":         "10",
+		"success-ratio": "0.9000",
+		"duration-p50":  "5",
+		"duration-p90":  "9",
+	}
+	for k, v := range expected {
+		if foo.Metadata[k] != v {
+			t.Fatalf("Invalid metadata %s: %v", k, foo.Metadata)
+		}
+	}
+	if len(a.flush()) != 0 {
+		t.Fatalf("A new window should be started after a flush")
+	}
+}
+
+func TestValidateAggregates(t *testing.T) {
+	exporters := map[string]Exporter{
+		"a": &testExporter{name: "a"},
+		"b": &testExporter{name: "b"},
+	}
+	aggregated, err := validateAggregates([]AggregateConfiguration{{Name: "r", Exporter: "a"}}, exporters, map[string]bool{})
+	if err != nil {
+		t.Fatalf("Invalid aggregates:\n%v", err)
+	}
+	if !aggregated["a"] {
+		t.Fatalf("The exporter should be aggregated")
+	}
+	cases := [][]AggregateConfiguration{
+		{{Name: "r", Exporter: "c"}},
+		{{Name: "r", Exporter: "b"}},
+		{{Name: "r", Exporter: "a"}, {Name: "s", Exporter: "a"}},
+		{{Name: "r", Exporter: "a"}, {Name: "r", Exporter: "b"}},
+	}
+	for _, c := range cases {
+		_, err := validateAggregates(c, exporters, map[string]bool{"b": true})
+		if err == nil {
+			t.Fatalf("Was expecting an error for:\n%v", c)
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package exporter
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// awsMetadataURL the EC2 instance metadata service
+const awsMetadataURL = "http://169.254.169.254"
+
+// awsContainerCredentialsURL the ECS container credentials endpoint
+const awsContainerCredentialsURL = "http://169.254.170.2"
+
+// awsCredentialsMaxResponseSize the maximum size of the credentials responses
+const awsCredentialsMaxResponseSize = 64 * 1024
+
+// awsCredentials the credentials signing the AWS requests. The static
+// credentials have no expiration.
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expires         time.Time
+}
+
+// awsCredentialsSource returns the AWS credentials from the default chain:
+// the environment variables, the web identity token file, the shared
+// credentials file, the ECS container credentials and the EC2 instance
+// profile. The temporary credentials are cached until they expire.
+type awsCredentialsSource struct {
+	client      *http.Client
+	profile     string
+	region      string
+	lock        sync.Mutex
+	credentials *awsCredentials
+	// the endpoints, overridden by the tests
+	metadataURL  string
+	containerURL string
+	stsURL       string
+}
+
+// newAWSCredentialsSource creates a credentials source. The AWS_PROFILE
+// profile, or the default one, is used if the profile is empty.
+func newAWSCredentialsSource(client *http.Client, profile string, region string) *awsCredentialsSource {
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	if profile == "" {
+		profile = "default"
+	}
+	return &awsCredentialsSource{
+		client:       client,
+		profile:      profile,
+		region:       region,
+		metadataURL:  awsMetadataURL,
+		containerURL: awsContainerCredentialsURL,
+		stsURL:       fmt.Sprintf("https://sts.%s.amazonaws.com", region),
+	}
+}
+
+// fromEnvironment returns the credentials of the environment variables, or
+// nil
+func (s *awsCredentialsSource) fromEnvironment() *awsCredentials {
+	id := os.Getenv("AWS_ACCESS_KEY_ID")
+	secret := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if id == "" || secret == "" {
+		return nil
+	}
+	return &awsCredentials{
+		AccessKeyID:     id,
+		SecretAccessKey: secret,
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+// fromSharedFile returns the credentials of the profile in the shared
+// credentials file, or nil if there is no file or no credentials for the
+// profile
+func (s *awsCredentialsSource) fromSharedFile() (*awsCredentials, error) {
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "Fail to read the credentials file %s", path)
+	}
+	defer file.Close()
+	credentials := awsCredentials{}
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if section != s.profile {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+		switch strings.TrimSpace(parts[0]) {
+		case "aws_access_key_id":
+			credentials.AccessKeyID = value
+		case "aws_secret_access_key":
+			credentials.SecretAccessKey = value
+		case "aws_session_token":
+			credentials.SessionToken = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "Fail to read the credentials file %s", path)
+	}
+	if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+		return nil, nil
+	}
+	return &credentials, nil
+}
+
+// get sends a request to a credentials endpoint and returns the response
+// body
+func (s *awsCredentialsSource) get(req *http.Request) ([]byte, error) {
+	response, err := s.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Credentials request failed")
+	}
+	defer response.Body.Close()
+	body, err := healthcheck.ReadBody(response.Body, awsCredentialsMaxResponseSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "Fail to read the credentials response")
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("The credentials endpoint returned the status %d: %s", response.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+// parseAWSCredentials parses the JSON credentials returned by the ECS and EC2
+// endpoints
+func parseAWSCredentials(body []byte) (*awsCredentials, error) {
+	var response struct {
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string
+		Token           string
+		Expiration      time.Time
+	}
+	if err := json.Unmarshal(body, &response); err != nil || response.AccessKeyID == "" {
+		return nil, errors.New("Invalid credentials response")
+	}
+	return &awsCredentials{
+		AccessKeyID:     response.AccessKeyID,
+		SecretAccessKey: response.SecretAccessKey,
+		SessionToken:    response.Token,
+		Expires:         response.Expiration,
+	}, nil
+}
+
+// fromWebIdentity exchanges the web identity token file for temporary
+// credentials, the STS request is not signed
+func (s *awsCredentialsSource) fromWebIdentity(ctx context.Context, tokenFile string) (*awsCredentials, error) {
+	token, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to read the web identity token file %s", tokenFile)
+	}
+	session := os.Getenv("AWS_ROLE_SESSION_NAME")
+	if session == "" {
+		session = "cabourotte"
+	}
+	query := url.Values{}
+	query.Set("Action", "AssumeRoleWithWebIdentity")
+	query.Set("Version", "2011-06-15")
+	query.Set("RoleArn", os.Getenv("AWS_ROLE_ARN"))
+	query.Set("RoleSessionName", session)
+	query.Set("WebIdentityToken", strings.TrimSpace(string(token)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.stsURL+"/?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := s.get(req)
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		Credentials struct {
+			AccessKeyID     string `xml:"AccessKeyId"`
+			SecretAccessKey string
+			SessionToken    string
+			Expiration      time.Time
+		} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	}
+	if err := xml.Unmarshal(body, &response); err != nil || response.Credentials.AccessKeyID == "" {
+		return nil, errors.New("Invalid AssumeRoleWithWebIdentity response")
+	}
+	return &awsCredentials{
+		AccessKeyID:     response.Credentials.AccessKeyID,
+		SecretAccessKey: response.Credentials.SecretAccessKey,
+		SessionToken:    response.Credentials.SessionToken,
+		Expires:         response.Credentials.Expiration,
+	}, nil
+}
+
+// fromContainer returns the credentials of the ECS task role
+func (s *awsCredentialsSource) fromContainer(ctx context.Context, uri string) (*awsCredentials, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.containerURL+uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := s.get(req)
+	if err != nil {
+		return nil, err
+	}
+	return parseAWSCredentials(body)
+}
+
+// fromInstance returns the credentials of the EC2 instance profile, using
+// IMDSv2
+func (s *awsCredentialsSource) fromInstance(ctx context.Context) (*awsCredentials, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.metadataURL+"/latest/api/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	token, err := s.get(req)
+	if err != nil {
+		return nil, err
+	}
+	path := s.metadataURL + "/latest/meta-data/iam/security-credentials/"
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", string(token))
+	role, err := s.get(req)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(strings.SplitN(string(role), "\n", 2)[0])
+	if name == "" {
+		return nil, errors.New("No IAM role attached to the instance")
+	}
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, path+url.PathEscape(name), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", string(token))
+	body, err := s.get(req)
+	if err != nil {
+		return nil, err
+	}
+	return parseAWSCredentials(body)
+}
+
+// resolve returns the credentials of the first source of the chain
+func (s *awsCredentialsSource) resolve(ctx context.Context) (*awsCredentials, error) {
+	if credentials := s.fromEnvironment(); credentials != nil {
+		return credentials, nil
+	}
+	if tokenFile := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"); tokenFile != "" && os.Getenv("AWS_ROLE_ARN") != "" {
+		return s.fromWebIdentity(ctx, tokenFile)
+	}
+	credentials, err := s.fromSharedFile()
+	if err != nil || credentials != nil {
+		return credentials, err
+	}
+	if uri := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); uri != "" {
+		return s.fromContainer(ctx, uri)
+	}
+	return s.fromInstance(ctx)
+}
+
+// Credentials returns valid credentials, the temporary ones are renewed five
+// minutes before their expiration
+func (s *awsCredentialsSource) Credentials(ctx context.Context) (*awsCredentials, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.credentials != nil && (s.credentials.Expires.IsZero() || time.Now().Before(s.credentials.Expires.Add(-5*time.Minute))) {
+		return s.credentials, nil
+	}
+	credentials, err := s.resolve(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "Fail to get the AWS credentials")
+	}
+	s.credentials = credentials
+	return credentials, nil
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of the data
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// awsSign signs a request with the AWS Signature Version 4. The host, the
+// x-amz-* and the content-type headers are signed.
+func awsSign(req *http.Request, body []byte, credentials *awsCredentials, region string, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		name := strings.ToLower(k)
+		if strings.HasPrefix(name, "x-amz-") || name == "content-type" {
+			headers[name] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	// the query parameters are sorted, and encoded with %20 for the spaces
+	query := strings.Replace(req.URL.Query().Encode(), "+", "%20", -1)
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		query,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", date, region, service)
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		credentials.AccessKeyID, scope, signedHeaders, signature))
+}
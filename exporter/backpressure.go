@@ -0,0 +1,196 @@
+package exporter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gopkg.in/tomb.v2"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+	"github.com/mcorbin/cabourotte/prometheus"
+)
+
+// DefaultBackpressureDuration the default duration after which the
+// healthchecks are paused if all exporters are failing
+const DefaultBackpressureDuration = healthcheck.Duration(time.Minute)
+
+// DefaultBackpressureProbeInterval the default interval between two probes of
+// the exporters while the healthchecks are paused
+const DefaultBackpressureProbeInterval = healthcheck.Duration(time.Minute)
+
+// BackpressureConfiguration the configuration of the backpressure, which
+// pauses the healthchecks while all exporters are failing
+type BackpressureConfiguration struct {
+	Enabled bool
+	// the healthchecks are paused when all exporters are failing for this
+	// duration
+	Duration healthcheck.Duration
+	// the healthchecks paused are executed again after this interval, to
+	// verify if an exporter recovered
+	ProbeInterval healthcheck.Duration `yaml:"probe-interval"`
+}
+
+// UnmarshalYAML parses the backpressure configuration from YAML.
+func (c *BackpressureConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration BackpressureConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the backpressure configuration")
+	}
+	if raw.Duration < 0 {
+		return errors.New("The backpressure duration should be positive")
+	}
+	if raw.ProbeInterval < 0 {
+		return errors.New("The backpressure probe interval should be positive")
+	}
+	if raw.Duration == 0 {
+		raw.Duration = DefaultBackpressureDuration
+	}
+	if raw.ProbeInterval == 0 {
+		raw.ProbeInterval = DefaultBackpressureProbeInterval
+	}
+	*c = BackpressureConfiguration(raw)
+	return nil
+}
+
+// the states of the backpressure
+const (
+	backpressureRunning = iota
+	backpressurePaused
+	backpressureProbing
+)
+
+// Backpressure the backpressure component. It pauses the healthchecks when all
+// exporters have been failing for the configured duration, and resumes them
+// when an exporter recovers. While paused, the healthchecks are periodically
+// resumed to probe the exporters.
+type Backpressure struct {
+	Logger      *zap.Logger
+	Config      *BackpressureConfiguration
+	Exporter    *Component
+	Healthcheck *healthcheck.Component
+	gauge       prom.Gauge
+	prometheus  *prometheus.Prometheus
+	interval    time.Duration
+	state       int
+	pausedAt    time.Time
+	probeStart  time.Time
+	tick        *time.Ticker
+	t           tomb.Tomb
+}
+
+// NewBackpressure creates a new backpressure component
+func NewBackpressure(logger *zap.Logger, config *BackpressureConfiguration, exporterComponent *Component, checkComponent *healthcheck.Component, promComponent *prometheus.Prometheus) (*Backpressure, error) {
+	gauge := prom.NewGauge(prom.GaugeOpts{
+		Name: "healthchecks_backpressure",
+		Help: "1 if the healthchecks are paused because all exporters are failing.",
+	})
+	err := promComponent.Register(gauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the backpressure Prometheus gauge")
+	}
+	return &Backpressure{
+		Logger:      logger,
+		Config:      config,
+		Exporter:    exporterComponent,
+		Healthcheck: checkComponent,
+		gauge:       gauge,
+		prometheus:  promComponent,
+		interval:    time.Second,
+	}, nil
+}
+
+// pause pauses the healthchecks
+func (b *Backpressure) pause(now time.Time) {
+	err := b.Healthcheck.SetBackpressure(true)
+	if err != nil {
+		b.Logger.Error(fmt.Sprintf("Fail to pause the healthchecks: %s", err.Error()))
+		return
+	}
+	b.state = backpressurePaused
+	b.pausedAt = now
+	b.gauge.Set(1)
+}
+
+// resume resumes the healthchecks. probing is true if the healthchecks are
+// resumed to probe the exporters.
+func (b *Backpressure) resume(now time.Time, probing bool) {
+	err := b.Healthcheck.SetBackpressure(false)
+	if err != nil {
+		b.Logger.Error(fmt.Sprintf("Fail to resume the healthchecks: %s", err.Error()))
+		return
+	}
+	b.state = backpressureRunning
+	if probing {
+		b.state = backpressureProbing
+		b.probeStart = now
+	}
+	b.gauge.Set(0)
+}
+
+// evaluate pauses or resumes the healthchecks depending of the exporters health
+func (b *Backpressure) evaluate(now time.Time) {
+	since, last, failing := b.Exporter.AllFailing()
+	switch b.state {
+	case backpressureRunning:
+		if failing && now.Sub(since) >= time.Duration(b.Config.Duration) {
+			b.Logger.Warn(fmt.Sprintf("All exporters are failing since %s, pausing the healthchecks", now.Sub(since).Round(time.Second)))
+			b.pause(now)
+		}
+	case backpressurePaused:
+		if !failing {
+			b.Logger.Info("An exporter recovered, resuming the healthchecks")
+			b.resume(now, false)
+		} else if now.Sub(b.pausedAt) >= time.Duration(b.Config.ProbeInterval) {
+			b.Logger.Info("Resuming the healthchecks to probe the exporters")
+			b.resume(now, true)
+		}
+	case backpressureProbing:
+		if !failing {
+			b.Logger.Info("An exporter recovered, the healthchecks stay resumed")
+			b.state = backpressureRunning
+		} else if last.After(b.probeStart) {
+			b.Logger.Warn("All exporters are still failing, pausing the healthchecks")
+			b.pause(now)
+		}
+	}
+}
+
+// Start starts the backpressure
+func (b *Backpressure) Start() error {
+	b.Logger.Info(fmt.Sprintf("Starting the backpressure, the healthchecks are paused if all exporters are failing for %s", time.Duration(b.Config.Duration)))
+	b.tick = time.NewTicker(b.interval)
+	b.t.Go(func() error {
+		for {
+			select {
+			case now := <-b.tick.C:
+				b.evaluate(now)
+			case <-b.t.Dying():
+				return nil
+			}
+		}
+	})
+	return nil
+}
+
+// Stop stops the backpressure, the healthchecks paused are resumed
+func (b *Backpressure) Stop() error {
+	b.Logger.Info("Stopping the backpressure")
+	b.tick.Stop()
+	b.t.Kill(nil)
+	err := b.t.Wait()
+	if err != nil {
+		return err
+	}
+	b.prometheus.Unregister(b.gauge)
+	if b.state == backpressurePaused {
+		err := b.Healthcheck.SetBackpressure(false)
+		if err != nil {
+			return errors.Wrapf(err, "Fail to resume the healthchecks paused by the backpressure")
+		}
+	}
+	return nil
+}
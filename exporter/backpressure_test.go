@@ -0,0 +1,110 @@
+package exporter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+	"github.com/mcorbin/cabourotte/memorystore"
+	"github.com/mcorbin/cabourotte/prometheus"
+)
+
+// failAt records a failure of an exporter at the given date
+func failAt(h *health, name string, date time.Time) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if _, ok := h.failingSince[name]; !ok {
+		h.failingSince[name] = date
+	}
+	h.lastFailure[name] = date
+}
+
+func TestBackpressure(t *testing.T) {
+	chanResult := make(chan *healthcheck.Result, 10)
+	logger := zap.NewExample()
+	promComponent, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(
+		logger,
+		memorystore.NewMemoryStore(logger),
+		chanResult,
+		promComponent,
+		&Configuration{})
+	if err != nil {
+		t.Fatalf("Error creating the component :\n%v", err)
+	}
+	component.Exporters["foo"] = &testExporter{name: "foo", started: true}
+	component.Exporters["bar"] = &testExporter{name: "bar", started: true}
+	checkComponent, err := healthcheck.New(logger, chanResult, promComponent)
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	config := &BackpressureConfiguration{
+		Enabled:       true,
+		Duration:      healthcheck.Duration(time.Minute),
+		ProbeInterval: healthcheck.Duration(time.Minute),
+	}
+	backpressure, err := NewBackpressure(logger, config, component, checkComponent, promComponent)
+	if err != nil {
+		t.Fatalf("Fail to create the backpressure component\n%v", err)
+	}
+	start := time.Now()
+	failAt(component.health, "foo", start)
+	backpressure.evaluate(start.Add(2 * time.Minute))
+	if checkComponent.IsBackpressured() {
+		t.Fatalf("The healthchecks should not be paused, an exporter is healthy")
+	}
+	failAt(component.health, "bar", start)
+	backpressure.evaluate(start.Add(30 * time.Second))
+	if checkComponent.IsBackpressured() {
+		t.Fatalf("The healthchecks should not be paused yet")
+	}
+	backpressure.evaluate(start.Add(2 * time.Minute))
+	if !checkComponent.IsBackpressured() {
+		t.Fatalf("The healthchecks should be paused")
+	}
+	// probe the exporters
+	backpressure.evaluate(start.Add(4 * time.Minute))
+	if checkComponent.IsBackpressured() {
+		t.Fatalf("The healthchecks should be resumed to probe the exporters")
+	}
+	backpressure.evaluate(start.Add(4*time.Minute + time.Second))
+	if checkComponent.IsBackpressured() {
+		t.Fatalf("The healthchecks should not be paused before the exporters are probed")
+	}
+	failAt(component.health, "foo", start.Add(4*time.Minute+time.Second))
+	failAt(component.health, "bar", start.Add(4*time.Minute+time.Second))
+	backpressure.evaluate(start.Add(4*time.Minute + 2*time.Second))
+	if !checkComponent.IsBackpressured() {
+		t.Fatalf("The healthchecks should be paused again")
+	}
+	component.health.record("bar", nil)
+	backpressure.evaluate(start.Add(4*time.Minute + 3*time.Second))
+	if checkComponent.IsBackpressured() {
+		t.Fatalf("The healthchecks should be resumed, an exporter recovered")
+	}
+}
+
+func TestAllFailing(t *testing.T) {
+	component := &Component{
+		Exporters: map[string]Exporter{},
+		health:    newHealth(),
+	}
+	if _, _, failing := component.AllFailing(); failing {
+		t.Fatalf("No exporter is configured")
+	}
+	component.Exporters["foo"] = &testExporter{name: "foo"}
+	component.health.record("foo", errors.New("failure"))
+	if _, _, failing := component.AllFailing(); !failing {
+		t.Fatalf("The exporter is failing")
+	}
+	component.health.record("foo", nil)
+	if _, _, failing := component.AllFailing(); failing {
+		t.Fatalf("The exporter recovered")
+	}
+}
@@ -0,0 +1,272 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// DefaultChatTemplate the default template for chat notifications
+const DefaultChatTemplate = `[{{ .Name }}] {{ if .Success }}OK{{ else }}FAILURE{{ if .Severity }} ({{ .Severity }}){{ end }}{{ end }}: {{ .Summary }} - {{ .Message }}{{ if and (not .Success) .RunbookURL }} - runbook: {{ .RunbookURL }}{{ end }}`
+
+const (
+	stateSuccess = "success"
+	stateFailure = "failure"
+)
+
+// chatStateExpiration the healthchecks without result for this duration are
+// forgotten, for example because they were removed
+const chatStateExpiration = 24 * time.Hour
+
+// chatState the state of an healthcheck for the chat exporter
+type chatState struct {
+	// the last state notified, or ignored because not enabled
+	success  bool
+	notified time.Time
+	// the time of the last result
+	seen time.Time
+	// true if the current transition was suppressed by the cooldown
+	suppressed bool
+}
+
+// ChatConfiguration the configuration for the chat exporter, which sends
+// notifications to Slack or Microsoft Teams incoming webhooks.
+type ChatConfiguration struct {
+	Name     string
+	URL      string
+	Template string
+	Cooldown healthcheck.Duration
+	States   []string
+	// the delivery of the results, asynchronous by default
+	Delivery `json:",inline" yaml:",inline"`
+}
+
+// ChatExporter the chat exporter struct. Notifications are only sent when the
+// state of an healthcheck changes.
+type ChatExporter struct {
+	startedState
+	Logger   *zap.Logger
+	Config   *ChatConfiguration
+	Client   *http.Client
+	template *template.Template
+	counter  *prom.CounterVec
+	states   map[string]chatState
+	pruned   time.Time
+	lock     sync.Mutex
+}
+
+// UnmarshalYAML parses the configuration of the chat exporter from YAML.
+func (c *ChatConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration ChatConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read chat exporter configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid name for the chat exporter configuration")
+	}
+	webhook, err := url.Parse(raw.URL)
+	if err != nil || (webhook.Scheme != "http" && webhook.Scheme != "https") {
+		return errors.New("Invalid webhook URL for the chat exporter configuration")
+	}
+	if raw.Template == "" {
+		raw.Template = DefaultChatTemplate
+	}
+	_, err = template.New("chat").Parse(raw.Template)
+	if err != nil {
+		return errors.Wrap(err, "Invalid template for the chat exporter configuration")
+	}
+	if len(raw.States) == 0 {
+		raw.States = []string{stateFailure, stateSuccess}
+	}
+	for _, state := range raw.States {
+		if state != stateFailure && state != stateSuccess {
+			return fmt.Errorf("Invalid state %s for the chat exporter configuration", state)
+		}
+	}
+	*c = ChatConfiguration(raw)
+	return nil
+}
+
+// NewChatExporter creates a new chat exporter
+func NewChatExporter(logger *zap.Logger, config *ChatConfiguration, counter *prom.CounterVec) (*ChatExporter, error) {
+	templateString := config.Template
+	if templateString == "" {
+		templateString = DefaultChatTemplate
+	}
+	tmpl, err := template.New("chat").Parse(templateString)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid template for the chat exporter %s", config.Name)
+	}
+	return &ChatExporter{
+		Logger:   logger,
+		Config:   config,
+		template: tmpl,
+		counter:  counter,
+		states:   make(map[string]chatState),
+		Client: &http.Client{
+			Timeout: time.Second * 3,
+		},
+	}, nil
+}
+
+// IsStarted returns the exporter status
+func (c *ChatExporter) IsStarted() bool {
+	return c.started()
+}
+
+// Start starts the chat exporter
+func (c *ChatExporter) Start() error {
+	c.Logger.Info(fmt.Sprintf("Starting the chat exporter %s", c.Config.Name))
+	c.setStarted(true)
+	return nil
+}
+
+// Reconnect reconnects the chat exporter
+func (c *ChatExporter) Reconnect() error {
+	// nothing to do
+	c.setStarted(true)
+	return nil
+}
+
+// Stop stops the chat exporter
+func (c *ChatExporter) Stop() error {
+	c.Logger.Info(fmt.Sprintf("Stopping the chat exporter %s", c.Config.Name))
+	c.setStarted(false)
+	return nil
+}
+
+// Name returns the name of the exporter
+func (c *ChatExporter) Name() string {
+	return c.Config.Name
+}
+
+// probe verifies that the webhook is reachable
+func (c *ChatExporter) probe() error {
+	return probeURL(c.Client, c.Config.URL)
+}
+
+// GetConfig returns the config of the exporter
+func (c *ChatExporter) GetConfig() interface{} {
+	return c.Config
+}
+
+// shouldNotify returns true if a notification should be sent for this result,
+// and true if a transition is suppressed by the cooldown, reported only once
+// per transition. The state is only updated once the notification is sent
+// (see notified), a failed or suppressed notification is evaluated again on
+// the next result.
+func (c *ChatExporter) shouldNotify(result *healthcheck.Result) (bool, bool) {
+	// the failures during the startup grace period are not transitions
+	if result.Metadata["startup"] == "true" {
+		return false, false
+	}
+	now := time.Now()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.prune(now)
+	previous, known := c.states[result.Name]
+	// the first result is only a transition if it's a failure
+	if !known {
+		previous.success = true
+	}
+	previous.seen = now
+	c.states[result.Name] = previous
+	if previous.success == result.Success {
+		previous.suppressed = false
+		c.states[result.Name] = previous
+		return false, false
+	}
+	state := stateFailure
+	if result.Success {
+		state = stateSuccess
+	}
+	enabled := false
+	for _, s := range c.Config.States {
+		if s == state {
+			enabled = true
+		}
+	}
+	if !enabled {
+		previous.success = result.Success
+		previous.suppressed = false
+		c.states[result.Name] = previous
+		return false, false
+	}
+	if now.Sub(previous.notified) < time.Duration(c.Config.Cooldown) {
+		if previous.suppressed {
+			return false, false
+		}
+		previous.suppressed = true
+		c.states[result.Name] = previous
+		return false, true
+	}
+	return true, false
+}
+
+// notified records the state of an healthcheck once notified
+func (c *ChatExporter) notified(result *healthcheck.Result) {
+	now := time.Now()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.states[result.Name] = chatState{success: result.Success, notified: now, seen: now}
+}
+
+// prune removes the state of the healthchecks without recent results, at
+// most once per hour.
+// The function is *not* thread-safe.
+func (c *ChatExporter) prune(now time.Time) {
+	if now.Sub(c.pruned) < time.Hour {
+		return
+	}
+	c.pruned = now
+	for name, state := range c.states {
+		if now.Sub(state.seen) > chatStateExpiration {
+			delete(c.states, name)
+		}
+	}
+}
+
+// Push sends a notification to the webhook if the healthcheck state changed
+func (c *ChatExporter) Push(result *healthcheck.Result) error {
+	notify, suppressed := c.shouldNotify(result)
+	if suppressed {
+		c.counter.With(prom.Labels{"name": c.Config.Name, "status": "suppressed"}).Inc()
+	}
+	if !notify {
+		return nil
+	}
+	var text bytes.Buffer
+	err := c.template.Execute(&text, result)
+	if err != nil {
+		return errors.Wrapf(err, "Chat exporter: fail to build the message for %s", result.Name)
+	}
+	payload, err := json.Marshal(map[string]string{"text": text.String()})
+	if err != nil {
+		return errors.Wrapf(err, "Chat exporter: fail to convert the message to json")
+	}
+	resp, err := c.Client.Post(c.Config.URL, "application/json", bytes.NewBuffer(payload))
+	if err != nil {
+		c.counter.With(prom.Labels{"name": c.Config.Name, "status": "failure"}).Inc()
+		return errors.Wrapf(err, "Chat exporter: fail to send the notification")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		c.counter.With(prom.Labels{"name": c.Config.Name, "status": "failure"}).Inc()
+		return fmt.Errorf("Chat exporter: request failed, status %d", resp.StatusCode)
+	}
+	c.notified(result)
+	c.counter.With(prom.Labels{"name": c.Config.Name, "status": "sent"}).Inc()
+	return nil
+}
@@ -0,0 +1,198 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// chatServer returns a webhook server recording the messages. The decoding
+// errors are sent to the errors channel, the failing function makes the
+// server return errors.
+func chatServer(messages *[]string, errs chan error, failing func() bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		err := json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			errs <- err
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if failing != nil && failing() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		*messages = append(*messages, payload["text"])
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func TestChatExporter(t *testing.T) {
+	messages := []string{}
+	errs := make(chan error, 10)
+	ts := chatServer(&messages, errs, nil)
+	defer ts.Close()
+	counter := prom.NewCounterVec(prom.CounterOpts{
+		Name: "chat_exporter_notifications_total",
+	}, []string{"name", "status"})
+	exporter, err := NewChatExporter(
+		zap.NewExample(),
+		&ChatConfiguration{
+			Name:     "chat",
+			URL:      ts.URL,
+			Template: "{{ .Name }} {{ .Success }}",
+			Cooldown: healthcheck.Duration(time.Hour),
+			States:   []string{"failure", "success"},
+		},
+		counter)
+	if err != nil {
+		t.Fatalf("Error creating the chat exporter :\n%v", err)
+	}
+	err = exporter.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the chat exporter:\n%v", err)
+	}
+	results := []bool{true, true, false, false, true}
+	for _, success := range results {
+		err = exporter.Push(&healthcheck.Result{
+			Name:    "foo",
+			Success: success,
+		})
+		if err != nil {
+			t.Fatalf("Fail to push healthcheck result:\n%v", err)
+		}
+	}
+	// the first success is not a transition, the recovery is in the cooldown
+	if len(messages) != 1 {
+		t.Fatalf("Invalid messages %v", messages)
+	}
+	if messages[0] != "foo false" {
+		t.Fatalf("Invalid message %s", messages[0])
+	}
+	err = exporter.Push(&healthcheck.Result{
+		Name:    "bar",
+		Success: false,
+	})
+	if err != nil {
+		t.Fatalf("Fail to push healthcheck result:\n%v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("Invalid messages %v", messages)
+	}
+	// the failures during the startup grace period are ignored
+	err = exporter.Push(&healthcheck.Result{
+		Name:     "baz",
+		Success:  false,
+		Metadata: map[string]string{"startup": "true"},
+	})
+	if err != nil {
+		t.Fatalf("Fail to push healthcheck result:\n%v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("Invalid messages %v", messages)
+	}
+	if len(errs) != 0 {
+		t.Fatalf("Fail to decode the payload:\n%v", <-errs)
+	}
+}
+
+func TestChatExporterRetry(t *testing.T) {
+	messages := []string{}
+	errs := make(chan error, 10)
+	failing := true
+	ts := chatServer(&messages, errs, func() bool { return failing })
+	defer ts.Close()
+	counter := prom.NewCounterVec(prom.CounterOpts{
+		Name: "chat_exporter_notifications_total",
+	}, []string{"name", "status"})
+	exporter, err := NewChatExporter(
+		zap.NewExample(),
+		&ChatConfiguration{
+			Name:     "chat",
+			URL:      ts.URL,
+			Template: "{{ .Name }} {{ .Success }}",
+			Cooldown: healthcheck.Duration(200 * time.Millisecond),
+			States:   []string{"failure", "success"},
+		},
+		counter)
+	if err != nil {
+		t.Fatalf("Error creating the chat exporter :\n%v", err)
+	}
+	// the failed notification is sent again with the next result
+	if err := exporter.Push(&healthcheck.Result{Name: "foo"}); err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	failing = false
+	if err := exporter.Push(&healthcheck.Result{Name: "foo"}); err != nil {
+		t.Fatalf("Fail to push healthcheck result:\n%v", err)
+	}
+	// the recovery is suppressed by the cooldown, then sent
+	if err := exporter.Push(&healthcheck.Result{Name: "foo", Success: true}); err != nil {
+		t.Fatalf("Fail to push healthcheck result:\n%v", err)
+	}
+	if err := exporter.Push(&healthcheck.Result{Name: "foo", Success: true}); err != nil {
+		t.Fatalf("Fail to push healthcheck result:\n%v", err)
+	}
+	if len(messages) != 1 || messages[0] != "foo false" {
+		t.Fatalf("Invalid messages %v", messages)
+	}
+	var m dto.Metric
+	if err := counter.With(prom.Labels{"name": "chat", "status": "suppressed"}).Write(&m); err != nil {
+		t.Fatalf("Fail to read the counter\n%v", err)
+	}
+	if m.GetCounter().GetValue() != 1 {
+		t.Fatalf("Invalid suppressed counter %v", m.GetCounter().GetValue())
+	}
+	time.Sleep(300 * time.Millisecond)
+	if err := exporter.Push(&healthcheck.Result{Name: "foo", Success: true}); err != nil {
+		t.Fatalf("Fail to push healthcheck result:\n%v", err)
+	}
+	if len(messages) != 2 || messages[1] != "foo true" {
+		t.Fatalf("Invalid messages %v", messages)
+	}
+	if len(errs) != 0 {
+		t.Fatalf("Fail to decode the payload:\n%v", <-errs)
+	}
+}
+
+func TestChatDefaultTemplate(t *testing.T) {
+	var config ChatConfiguration
+	err := yaml.Unmarshal([]byte("name: chat\nurl: http://localhost\n"), &config)
+	if err != nil {
+		t.Fatalf("Fail to parse the configuration\n%v", err)
+	}
+	exporter, err := NewChatExporter(zap.NewExample(), &config, nil)
+	if err != nil {
+		t.Fatalf("Error creating the chat exporter :\n%v", err)
+	}
+	cases := map[string]*healthcheck.Result{
+		"[foo] FAILURE (warning): tcp - timeout - runbook: https://wiki.example.com/foo": {
+			Name: "foo", Summary: "tcp", Message: "timeout", Severity: healthcheck.SeverityWarning, RunbookURL: "https://wiki.example.com/foo",
+		},
+		"[foo] OK: tcp - success": {
+			Name: "foo", Summary: "tcp", Message: "success", Success: true, Severity: healthcheck.SeverityWarning, RunbookURL: "https://wiki.example.com/foo",
+		},
+		"[foo] FAILURE: tcp - timeout": {
+			Name: "foo", Summary: "tcp", Message: "timeout",
+		},
+	}
+	for expected, result := range cases {
+		var text bytes.Buffer
+		if err := exporter.template.Execute(&text, result); err != nil {
+			t.Fatalf("Fail to render the template\n%v", err)
+		}
+		if text.String() != expected {
+			t.Fatalf("Invalid message %s, expected %s", text.String(), expected)
+		}
+	}
+}
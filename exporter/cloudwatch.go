@@ -0,0 +1,418 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gopkg.in/tomb.v2"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// DefaultCloudWatchBatchSize the default number of metrics of a
+// PutMetricData request
+const DefaultCloudWatchBatchSize = 20
+
+// DefaultCloudWatchBatchDelay the default maximum duration a metric waits in
+// the batch
+const DefaultCloudWatchBatchDelay = healthcheck.Duration(10 * time.Second)
+
+// DefaultCloudWatchTimeout the default timeout of the PutMetricData requests
+const DefaultCloudWatchTimeout = 10 * time.Second
+
+// cloudWatchMaxBatchSize the maximum number of metrics of a PutMetricData
+// request
+const cloudWatchMaxBatchSize = 20
+
+// cloudWatchMaxDimensions the maximum number of dimensions of a metric
+const cloudWatchMaxDimensions = 30
+
+// cloudWatchMaxResponseSize the maximum size of the PutMetricData responses
+const cloudWatchMaxResponseSize = 1024 * 1024
+
+// CloudWatchConfiguration the AWS CloudWatch exporter configuration
+type CloudWatchConfiguration struct {
+	Name string
+	// the AWS_REGION or AWS_DEFAULT_REGION environment variables are used
+	// if empty
+	Region    string
+	Namespace string
+	// the labels used as dimensions, all the labels if empty. The
+	// healthcheck name is always a dimension.
+	Dimensions []string `json:"dimensions,omitempty" yaml:"dimensions,omitempty"`
+	// the profile of the shared credentials file, AWS_PROFILE or default
+	Profile string `json:"profile,omitempty" yaml:"profile,omitempty"`
+	// the CloudWatch API endpoint, the regional endpoint if empty
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	// the metrics are sent when the batch is full, or after the batch delay.
+	// Each result produces two metrics.
+	BatchSize  uint                 `json:"batch-size,omitempty" yaml:"batch-size,omitempty"`
+	BatchDelay healthcheck.Duration `json:"batch-delay,omitempty" yaml:"batch-delay,omitempty"`
+	Timeout    healthcheck.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// the delivery of the results, asynchronous by default
+	Delivery `json:",inline" yaml:",inline"`
+}
+
+// cloudWatchDimension a dimension of a metric
+type cloudWatchDimension struct {
+	Name  string
+	Value string
+}
+
+// cloudWatchMetric a metric of a PutMetricData request
+type cloudWatchMetric struct {
+	Name       string
+	Unit       string
+	Value      float64
+	Timestamp  time.Time
+	Dimensions []cloudWatchDimension
+}
+
+// CloudWatchExporter the AWS CloudWatch exporter struct
+type CloudWatchExporter struct {
+	startedState
+	Logger      *zap.Logger
+	Config      *CloudWatchConfiguration
+	client      *http.Client
+	credentials *awsCredentialsSource
+	counter     *prom.CounterVec
+	region      string
+	url         string
+	t           *tomb.Tomb
+	sendLock    sync.Mutex
+	lock        sync.Mutex
+	batch       []cloudWatchMetric
+	// the error of the last background flush, returned by the next push
+	lastErr error
+}
+
+// UnmarshalYAML parses the configuration of the CloudWatch exporter from YAML.
+func (c *CloudWatchConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration CloudWatchConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read CloudWatch exporter configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid name for the CloudWatch exporter configuration")
+	}
+	if raw.Namespace == "" || strings.HasPrefix(raw.Namespace, "AWS/") {
+		return errors.New("Invalid namespace for the CloudWatch exporter configuration, the AWS/ namespaces are reserved")
+	}
+	// the healthcheck name is also a dimension
+	if len(raw.Dimensions) >= cloudWatchMaxDimensions {
+		return fmt.Errorf("The CloudWatch exporter supports at most %d dimensions", cloudWatchMaxDimensions-1)
+	}
+	for _, dimension := range raw.Dimensions {
+		if dimension == "" || dimension == "name" {
+			return fmt.Errorf("Invalid dimension '%s' for the CloudWatch exporter", dimension)
+		}
+	}
+	if raw.Endpoint != "" {
+		endpoint, err := url.Parse(raw.Endpoint)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			return fmt.Errorf("Invalid endpoint %s for the CloudWatch exporter configuration", raw.Endpoint)
+		}
+	}
+	if raw.BatchSize == 0 {
+		raw.BatchSize = DefaultCloudWatchBatchSize
+	}
+	if raw.BatchSize > cloudWatchMaxBatchSize {
+		return fmt.Errorf("The CloudWatch exporter batch-size should be lower than %d", cloudWatchMaxBatchSize)
+	}
+	if raw.BatchDelay < 0 || raw.Timeout < 0 {
+		return errors.New("The CloudWatch exporter batch-delay and timeout should be positive")
+	}
+	if raw.BatchDelay == 0 {
+		raw.BatchDelay = DefaultCloudWatchBatchDelay
+	}
+	*c = CloudWatchConfiguration(raw)
+	return nil
+}
+
+// NewCloudWatchExporter creates a new CloudWatch exporter
+func NewCloudWatchExporter(logger *zap.Logger, config *CloudWatchConfiguration, counter *prom.CounterVec) (*CloudWatchExporter, error) {
+	region := config.Region
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		return nil, errors.New("The CloudWatch exporter region is missing")
+	}
+	timeout := time.Duration(config.Timeout)
+	if timeout == 0 {
+		timeout = DefaultCloudWatchTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://monitoring.%s.amazonaws.com", region)
+	}
+	return &CloudWatchExporter{
+		Logger:      logger,
+		Config:      config,
+		client:      client,
+		credentials: newAWSCredentialsSource(client, config.Profile, region),
+		counter:     counter,
+		region:      region,
+		url:         strings.TrimSuffix(endpoint, "/") + "/",
+	}, nil
+}
+
+// IsStarted returns the exporter status
+func (c *CloudWatchExporter) IsStarted() bool {
+	return c.started()
+}
+
+// Start starts sending the batches on the batch delay
+func (c *CloudWatchExporter) Start() error {
+	c.Logger.Info(fmt.Sprintf("Starting the CloudWatch exporter %s", c.Config.Name))
+	delay := c.Config.BatchDelay
+	if delay == 0 {
+		delay = DefaultCloudWatchBatchDelay
+	}
+	ticker := time.NewTicker(time.Duration(delay))
+	c.t = &tomb.Tomb{}
+	t := c.t
+	t.Go(func() error {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				_, err := c.flush()
+				if err != nil {
+					c.Logger.Error(err.Error(), zap.String("exporter", c.Config.Name))
+					c.lock.Lock()
+					c.lastErr = err
+					c.lock.Unlock()
+				}
+			case <-t.Dying():
+				return nil
+			}
+		}
+	})
+	c.setStarted(true)
+	return nil
+}
+
+// Reconnect restarts the exporter stopped after a failed PutMetricData
+func (c *CloudWatchExporter) Reconnect() error {
+	c.Logger.Info(fmt.Sprintf("CloudWatch exporter %s: reconnecting", c.Config.Name))
+	if c.t != nil {
+		// nolint
+		c.Stop()
+	}
+	if err := c.Start(); err != nil {
+		return errors.Wrapf(err, "Fail to restart the CloudWatch exporter")
+	}
+	c.Logger.Info(fmt.Sprintf("CloudWatch exporter %s: reconnected", c.Config.Name))
+	return nil
+}
+
+// probe verifies that the credentials can be retrieved and that the
+// CloudWatch endpoint is reachable
+func (c *CloudWatchExporter) probe() error {
+	if _, err := c.credentials.Credentials(context.Background()); err != nil {
+		return errors.Wrap(err, "CloudWatch exporter: fail to get the credentials")
+	}
+	return probeURL(c.client, c.url)
+}
+
+// Stop stops the CloudWatch exporter, the pending metrics are sent
+func (c *CloudWatchExporter) Stop() error {
+	c.Logger.Info(fmt.Sprintf("Stopping the CloudWatch exporter %s", c.Config.Name))
+	c.setStarted(false)
+	if c.t != nil {
+		c.t.Kill(nil)
+		// nolint
+		c.t.Wait()
+		c.t = nil
+	}
+	_, err := c.flush()
+	return err
+}
+
+// Name returns the name of the exporter
+func (c *CloudWatchExporter) Name() string {
+	return c.Config.Name
+}
+
+// GetConfig returns the config of the exporter
+func (c *CloudWatchExporter) GetConfig() interface{} {
+	return c.Config
+}
+
+// dimensions returns the dimensions of a result, sorted by name. The labels
+// with an empty value are ignored, CloudWatch rejects them.
+func (c *CloudWatchExporter) dimensions(result *healthcheck.Result) []cloudWatchDimension {
+	dimensions := []cloudWatchDimension{{Name: "name", Value: result.Name}}
+	if len(c.Config.Dimensions) == 0 {
+		for k, v := range result.Labels {
+			if k != "name" && v != "" {
+				dimensions = append(dimensions, cloudWatchDimension{Name: k, Value: v})
+			}
+		}
+	} else {
+		for _, k := range c.Config.Dimensions {
+			if v := result.Labels[k]; v != "" {
+				dimensions = append(dimensions, cloudWatchDimension{Name: k, Value: v})
+			}
+		}
+	}
+	sort.Slice(dimensions, func(i, j int) bool {
+		return dimensions[i].Name < dimensions[j].Name
+	})
+	if len(dimensions) > cloudWatchMaxDimensions {
+		dimensions = dimensions[:cloudWatchMaxDimensions]
+	}
+	return dimensions
+}
+
+// cloudWatchResult returns the success and duration metrics of a result
+func (c *CloudWatchExporter) cloudWatchResult(result *healthcheck.Result) []cloudWatchMetric {
+	dimensions := c.dimensions(result)
+	success := 0.0
+	if result.Success {
+		success = 1
+	}
+	timestamp := result.Time()
+	return []cloudWatchMetric{
+		{Name: "success", Unit: "None", Value: success, Timestamp: timestamp, Dimensions: dimensions},
+		{Name: "duration", Unit: "Seconds", Value: result.Duration, Timestamp: timestamp, Dimensions: dimensions},
+	}
+}
+
+// putMetricData builds the form of a PutMetricData request
+func (c *CloudWatchExporter) putMetricData(metrics []cloudWatchMetric) url.Values {
+	form := url.Values{}
+	form.Set("Action", "PutMetricData")
+	form.Set("Version", "2010-08-01")
+	form.Set("Namespace", c.Config.Namespace)
+	for i, metric := range metrics {
+		prefix := fmt.Sprintf("MetricData.member.%d.", i+1)
+		form.Set(prefix+"MetricName", metric.Name)
+		form.Set(prefix+"Unit", metric.Unit)
+		form.Set(prefix+"Value", strconv.FormatFloat(metric.Value, 'f', -1, 64))
+		form.Set(prefix+"Timestamp", metric.Timestamp.UTC().Format(time.RFC3339))
+		for j, dimension := range metric.Dimensions {
+			dimensionPrefix := fmt.Sprintf("%sDimensions.member.%d.", prefix, j+1)
+			form.Set(dimensionPrefix+"Name", dimension.Name)
+			form.Set(dimensionPrefix+"Value", dimension.Value)
+		}
+	}
+	return form
+}
+
+// send sends a batch of metrics to CloudWatch
+func (c *CloudWatchExporter) send(metrics []cloudWatchMetric) error {
+	ctx := context.Background()
+	credentials, err := c.credentials.Credentials(ctx)
+	if err != nil {
+		return errors.Wrap(err, "CloudWatch exporter: fail to get the credentials")
+	}
+	payload := []byte(c.putMetricData(metrics).Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewBuffer(payload))
+	if err != nil {
+		return errors.Wrap(err, "CloudWatch exporter: fail to create the PutMetricData request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	req.Header.Set("User-Agent", "Cabourotte")
+	awsSign(req, payload, credentials, c.region, "monitoring", time.Now())
+	response, err := c.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "CloudWatch exporter: PutMetricData request failed")
+	}
+	defer response.Body.Close()
+	body, err := healthcheck.ReadBody(response.Body, cloudWatchMaxResponseSize)
+	if err != nil {
+		return errors.Wrap(err, "CloudWatch exporter: fail to read the PutMetricData response")
+	}
+	if response.StatusCode != http.StatusOK {
+		var errorResponse struct {
+			Code    string `xml:"Error>Code"`
+			Message string `xml:"Error>Message"`
+		}
+		if xml.Unmarshal(body, &errorResponse) == nil && errorResponse.Code != "" {
+			return fmt.Errorf("CloudWatch exporter: PutMetricData returned the status %d: %s: %s", response.StatusCode, errorResponse.Code, errorResponse.Message)
+		}
+		return fmt.Errorf("CloudWatch exporter: PutMetricData returned the status %d: %s", response.StatusCode, string(body))
+	}
+	return nil
+}
+
+// flush sends the pending metrics, in batches of the batch size, and returns
+// the number of results sent. The metrics of a failed batch are dropped, the
+// other batches are still sent.
+func (c *CloudWatchExporter) flush() (int, error) {
+	c.sendLock.Lock()
+	defer c.sendLock.Unlock()
+	c.lock.Lock()
+	pending := c.batch
+	c.batch = nil
+	c.lock.Unlock()
+	batchSize := int(c.Config.BatchSize)
+	if batchSize == 0 {
+		batchSize = DefaultCloudWatchBatchSize
+	}
+	dropped := 0
+	var lastErr error
+	for start := 0; start < len(pending); start += batchSize {
+		end := start + batchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		batch := pending[start:end]
+		err := c.send(batch)
+		status := "sent"
+		if err != nil {
+			status = "failed"
+			dropped += len(batch)
+			lastErr = err
+		}
+		c.counter.With(prom.Labels{"name": c.Config.Name, "status": status}).Add(float64(len(batch)))
+	}
+	// each result produces two metrics
+	sent := (len(pending) - dropped) / 2
+	if lastErr != nil {
+		return sent, errors.Wrapf(lastErr, "%d of %d metrics dropped", dropped, len(pending))
+	}
+	return sent, nil
+}
+
+// Push adds the metrics of the result to the batch, which is sent once full.
+// The error of the last background flush is returned.
+func (c *CloudWatchExporter) Push(result *healthcheck.Result) error {
+	metrics := c.cloudWatchResult(result)
+	batchSize := c.Config.BatchSize
+	if batchSize == 0 {
+		batchSize = DefaultCloudWatchBatchSize
+	}
+	c.lock.Lock()
+	c.batch = append(c.batch, metrics...)
+	full := uint(len(c.batch)) >= batchSize
+	lastErr := c.lastErr
+	c.lastErr = nil
+	c.lock.Unlock()
+	if full {
+		if _, err := c.flush(); err != nil {
+			return err
+		}
+	}
+	return lastErr
+}
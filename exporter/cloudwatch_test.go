@@ -0,0 +1,234 @@
+package exporter
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+func TestAWSSign(t *testing.T) {
+	// the get-vanilla case of the AWS Signature Version 4 test suite
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	if err != nil {
+		t.Fatalf("Fail to create the request\n%v", err)
+	}
+	now, _ := time.Parse("20060102T150405Z", "20150830T123600Z")
+	credentials := &awsCredentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	awsSign(req, nil, credentials, "us-east-1", "service", now)
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if req.Header.Get("Authorization") != expected {
+		t.Fatalf("Invalid signature %s", req.Header.Get("Authorization"))
+	}
+}
+
+func TestCloudWatchExporter(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	var lock sync.Mutex
+	requests := 0
+	var metrics []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.FormValue("Action") != "PutMetricData" || r.FormValue("Namespace") != "Cabourotte" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		requests++
+		// the second request fails
+		if requests == 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`<ErrorResponse><Error><Code>InvalidParameterValue</Code><Message>invalid</Message></Error></ErrorResponse>`))
+			return
+		}
+		for i := 1; r.FormValue(metricField(i, "MetricName")) != ""; i++ {
+			metrics = append(metrics, r.FormValue(metricField(i, "MetricName"))+" "+
+				r.FormValue(metricField(i, "Value"))+" "+
+				r.FormValue(metricField(i, "Dimensions.member.1.Name"))+"="+
+				r.FormValue(metricField(i, "Dimensions.member.1.Value")))
+		}
+	}))
+	defer ts.Close()
+	config := CloudWatchConfiguration{
+		Name:       "cloudwatch",
+		Region:     "eu-west-3",
+		Namespace:  "Cabourotte",
+		Endpoint:   ts.URL,
+		BatchSize:  3,
+		BatchDelay: healthcheck.Duration(time.Hour),
+	}
+	counter := prom.NewCounterVec(prom.CounterOpts{Name: "test"}, []string{"name", "status"})
+	exporter, err := NewCloudWatchExporter(zap.NewExample(), &config, counter)
+	if err != nil {
+		t.Fatalf("Fail to create the exporter\n%v", err)
+	}
+	if err := exporter.Start(); err != nil {
+		t.Fatalf("Fail to start the exporter\n%v", err)
+	}
+	result := &healthcheck.Result{Name: "foo", Success: true, Duration: 0.5, Labels: map[string]string{"env": "prod"}, Timestamp: time.Now()}
+	if err := exporter.Push(result); err != nil {
+		t.Fatalf("Fail to push\n%v", err)
+	}
+	// the 4 metrics are sent in 2 batches, the second one fails
+	err = exporter.Push(result)
+	if err == nil || !strings.Contains(err.Error(), "1 of 4 metrics dropped") {
+		t.Fatalf("Was expecting a partial failure, got %v", err)
+	}
+	if err := exporter.Stop(); err != nil {
+		t.Fatalf("Fail to stop the exporter\n%v", err)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if requests != 2 || len(metrics) != 3 {
+		t.Fatalf("Invalid requests: %d requests, %v", requests, metrics)
+	}
+	if metrics[0] != "success 1 env=prod" || metrics[1] != "duration 0.5 env=prod" {
+		t.Fatalf("Invalid metrics %v", metrics)
+	}
+	var m dto.Metric
+	if err := counter.WithLabelValues("cloudwatch", "failed").Write(&m); err != nil {
+		t.Fatalf("Fail to read the counter\n%v", err)
+	}
+	if m.GetCounter().GetValue() != 1 {
+		t.Fatalf("Invalid counter %v", m.GetCounter().GetValue())
+	}
+}
+
+func TestCloudWatchExporterReconnect(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	var lock sync.Mutex
+	failing := true
+	sent := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		sent++
+	}))
+	defer ts.Close()
+	config := CloudWatchConfiguration{
+		Name:       "cloudwatch",
+		Region:     "eu-west-3",
+		Namespace:  "Cabourotte",
+		Endpoint:   ts.URL,
+		BatchSize:  2,
+		BatchDelay: healthcheck.Duration(time.Hour),
+	}
+	counter := prom.NewCounterVec(prom.CounterOpts{Name: "test"}, []string{"name", "status"})
+	exporter, err := NewCloudWatchExporter(zap.NewExample(), &config, counter)
+	if err != nil {
+		t.Fatalf("Fail to create the exporter\n%v", err)
+	}
+	if err := exporter.Start(); err != nil {
+		t.Fatalf("Fail to start the exporter\n%v", err)
+	}
+	result := &healthcheck.Result{Name: "foo", Success: true, Timestamp: time.Now()}
+	if err := exporter.Push(result); err == nil {
+		t.Fatalf("Was expecting a PutMetricData error")
+	}
+	// the exporters component stops the exporter after a failed push
+	// nolint
+	exporter.Stop()
+	lock.Lock()
+	failing = false
+	lock.Unlock()
+	if err := exporter.Reconnect(); err != nil {
+		t.Fatalf("Fail to reconnect the exporter\n%v", err)
+	}
+	if !exporter.IsStarted() {
+		t.Fatalf("The exporter should be started")
+	}
+	if err := exporter.Push(result); err != nil {
+		t.Fatalf("Fail to push\n%v", err)
+	}
+	if err := exporter.Stop(); err != nil {
+		t.Fatalf("Fail to stop the exporter\n%v", err)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if sent != 1 {
+		t.Fatalf("Invalid requests %d", sent)
+	}
+}
+
+// metricField returns the form field of a PutMetricData metric
+func metricField(i int, field string) string {
+	return fmt.Sprintf("MetricData.member.%d.%s", i, field)
+}
+
+func TestCloudWatchConfiguration(t *testing.T) {
+	invalid := []string{
+		"namespace: Cabourotte\n",
+		"name: cloudwatch\n",
+		"name: cloudwatch\nnamespace: AWS/EC2\n",
+		"name: cloudwatch\nnamespace: Cabourotte\nbatch-size: 21\n",
+		"name: cloudwatch\nnamespace: Cabourotte\ndimensions: [name]\n",
+		"name: cloudwatch\nnamespace: Cabourotte\nendpoint: foo\n",
+	}
+	for _, c := range invalid {
+		var config CloudWatchConfiguration
+		err := yaml.Unmarshal([]byte(c), &config)
+		if err == nil {
+			t.Fatalf("Was expected an error when decoding the configuration: \n%s", c)
+		}
+	}
+	var config CloudWatchConfiguration
+	err := yaml.Unmarshal([]byte("name: cloudwatch\nnamespace: Cabourotte\n"), &config)
+	if err != nil {
+		t.Fatalf("Fail to parse the configuration\n%v", err)
+	}
+	if config.BatchSize != DefaultCloudWatchBatchSize {
+		t.Fatalf("Invalid default batch size %d", config.BatchSize)
+	}
+}
+
+func TestCloudWatchExporterProbe(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	config := CloudWatchConfiguration{
+		Name:      "cloudwatch",
+		Region:    "eu-west-3",
+		Namespace: "Cabourotte",
+		Endpoint:  ts.URL,
+	}
+	counter := prom.NewCounterVec(prom.CounterOpts{Name: "test"}, []string{"name", "status"})
+	exporter, err := NewCloudWatchExporter(zap.NewExample(), &config, counter)
+	if err != nil {
+		t.Fatalf("Fail to create the exporter\n%v", err)
+	}
+	if err := exporter.probe(); err != nil {
+		t.Fatalf("Fail to probe the exporter\n%v", err)
+	}
+	ts.Close()
+	if err := exporter.probe(); err == nil {
+		t.Fatalf("Was expecting a probe error")
+	}
+}
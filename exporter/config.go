@@ -2,6 +2,29 @@ package exporter
 
 // Configuration the main configuration for the exporter component
 type Configuration struct {
-	HTTP    []HTTPConfiguration
-	Riemann []RiemannConfiguration
+	HTTP        []HTTPConfiguration
+	Riemann     []RiemannConfiguration
+	Chat        []ChatConfiguration
+	GRPC        []GRPCConfiguration        `yaml:"grpc"`
+	OpenMetrics []OpenMetricsConfiguration `yaml:"openmetrics"`
+	MQTT        []MQTTConfiguration        `yaml:"mqtt"`
+	Groups      []GroupConfiguration
+	Aggregates  []AggregateConfiguration
+	Sampling    SamplingConfiguration
+	// verifies the exporters destinations at startup
+	StartupCheck StartupCheck `yaml:"startup-check"`
+	// in-memory exporters recording the results, for integration tests
+	Recording []RecordingConfiguration
+	StatsD    []StatsDConfiguration `yaml:"statsd"`
+	// enriches the results with the data of an HTTP endpoint before
+	// exporting them
+	Enrichment EnrichmentConfiguration
+	// publishes the results to Google Cloud Pub/Sub topics
+	PubSub []PubSubConfiguration `yaml:"pubsub"`
+	// produces the results to Kafka topics, routed by rules
+	Kafka []KafkaConfiguration `yaml:"kafka"`
+	// puts the results as AWS CloudWatch metrics
+	CloudWatch []CloudWatchConfiguration `yaml:"cloudwatch"`
+	// inserts the results in a PostgreSQL table
+	SQL []SQLConfiguration `yaml:"sql"`
 }
@@ -1,6 +1,7 @@
 package exporter
 
 import (
+	"reflect"
 	"testing"
 
 	"gopkg.in/yaml.v2"
@@ -85,7 +86,7 @@ insecure: true
 		if err := yaml.Unmarshal([]byte(c.in), &result); err != nil {
 			t.Fatalf("Unmarshal yaml error:\n%v", err)
 		}
-		if result != c.want {
+		if !reflect.DeepEqual(result, c.want) {
 			t.Fatalf("Invalid configuration: \n%s\n%v", c.in, c.want)
 		}
 	}
@@ -127,6 +128,29 @@ port: 2003
 protocol: http
 key: /tmp/key
 cert: /tmp/cert
+`,
+		`
+name: foo
+host: "127.0.0.1"
+protocol: http
+endpoints:
+  - host: "127.0.0.2"
+    port: 2000
+`,
+		`
+name: foo
+protocol: http
+endpoints:
+  - host: "127.0.0.2"
+`,
+		`
+name: foo
+protocol: http
+endpoints:
+  - host: "127.0.0.2"
+    port: 2000
+  - host: "127.0.0.2"
+    port: 2000
 `,
 	}
 	for _, c := range cases {
@@ -0,0 +1,17 @@
+package exporter
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// correlationFields returns the log fields identifying a result, empty if
+// the result has no correlation ID
+func correlationFields(result *healthcheck.Result) []zap.Field {
+	id := result.CorrelationID()
+	if id == "" {
+		return nil
+	}
+	return []zap.Field{zap.String("correlation-id", id)}
+}
@@ -0,0 +1,98 @@
+package exporter
+
+import (
+	"fmt"
+	"sort"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// DefaultAsyncQueueSize the number of results queued for an asynchronous
+// exporter, the results fan-out blocks when the queue is full
+const DefaultAsyncQueueSize = 100
+
+const (
+	// DeliveryExported the result was pushed to all synchronous exporters
+	DeliveryExported = "exported"
+	// DeliveryFailed the push to a synchronous exporter failed
+	DeliveryFailed = "failed"
+	// DeliveryDispatched the result was only dispatched to asynchronous
+	// exporters
+	DeliveryDispatched = "dispatched"
+)
+
+// Delivery the delivery options of an exporter
+type Delivery struct {
+	// the results are pushed to the synchronous exporters first, and are
+	// considered exported once all of them succeed. The other exporters
+	// are pushed asynchronously.
+	Sync bool `json:"sync,omitempty" yaml:"sync,omitempty"`
+}
+
+// syncDelivery returns true if the exporter is synchronous
+func (d Delivery) syncDelivery() bool {
+	return d.Sync
+}
+
+// isSync returns true if the exporter configuration is synchronous
+func isSync(exporter Exporter) bool {
+	config, ok := exporter.GetConfig().(interface{ syncDelivery() bool })
+	return ok && config.syncDelivery()
+}
+
+// syncExporters returns the names of the synchronous exporters, in the order
+// results are pushed to them. The exporters of groups and aggregates keep
+// their own delivery.
+func syncExporters(exporters map[string]Exporter, grouped map[string]bool, aggregated map[string]bool) ([]string, error) {
+	names := []string{}
+	for name, exporter := range exporters {
+		if !isSync(exporter) {
+			continue
+		}
+		if grouped[name] || aggregated[name] {
+			return nil, fmt.Errorf("The exporter %s is synchronous and can't be used in a group or an aggregate", name)
+		}
+		if concurrent, ok := exporter.(concurrentExporter); ok && concurrent.maxConcurrentPushes() > 1 {
+			return nil, fmt.Errorf("The exporter %s is synchronous and can't push concurrently", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// pushSync pushes a result to the synchronous exporters, a stopped exporter
+// being reconnected first. Returns the aggregate delivery status of the
+// result.
+func (c *Component) pushSync(message *healthcheck.Result) string {
+	if len(c.syncOrder) == 0 {
+		c.deliveryCounter.With(prom.Labels{"status": DeliveryDispatched}).Inc()
+		return DeliveryDispatched
+	}
+	status := DeliveryExported
+	for _, name := range c.syncOrder {
+		exporter := c.Exporters[name]
+		if !exporter.IsStarted() {
+			c.reconnect(exporter)
+		}
+		if !exporter.IsStarted() || !c.push(exporter, message) {
+			status = DeliveryFailed
+			c.Logger.Error(fmt.Sprintf("The result of the healthcheck %s was not pushed to the synchronous exporter %s", message.Name, name),
+				correlationFields(message)...)
+		}
+	}
+	c.deliveryCounter.With(prom.Labels{"status": status}).Inc()
+	return status
+}
+
+// isSyncExporter returns true if the exporter is pushed synchronously
+func (c *Component) isSyncExporter(name string) bool {
+	for _, syncName := range c.syncOrder {
+		if syncName == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package exporter
+
+import (
+	"testing"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+	"github.com/mcorbin/cabourotte/memorystore"
+	"github.com/mcorbin/cabourotte/prometheus"
+)
+
+// deliveryCount returns the number of results delivered with a status
+func deliveryCount(t *testing.T, c *Component, status string) float64 {
+	var metric dto.Metric
+	if err := c.deliveryCounter.With(prom.Labels{"status": status}).Write(&metric); err != nil {
+		t.Fatalf("Fail to read the counter\n%v", err)
+	}
+	return metric.GetCounter().GetValue()
+}
+
+func TestSyncDelivery(t *testing.T) {
+	chanResult := make(chan *healthcheck.Result, 10)
+	logger := zap.NewExample()
+	promComponent, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(
+		logger,
+		memorystore.NewMemoryStore(logger),
+		chanResult,
+		promComponent,
+		&Configuration{
+			Recording: []RecordingConfiguration{
+				// every second push fails
+				{Name: "primary", Enabled: true, FailEvery: 2, Delivery: Delivery{Sync: true}},
+				{Name: "secondary", Enabled: true, Delivery: Delivery{Sync: true}},
+				{Name: "async", Enabled: true, FailEvery: 3},
+			},
+		})
+	if err != nil {
+		t.Fatalf("Error creating the component :\n%v", err)
+	}
+	if len(component.syncOrder) != 2 || component.syncOrder[0] != "primary" || len(component.pools) != 1 {
+		t.Fatalf("Invalid synchronous exporters %v", component.syncOrder)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Error starting the component :\n%v", err)
+	}
+	for i := 0; i < 6; i++ {
+		chanResult <- &healthcheck.Result{Name: "foo", Success: true, Sequence: uint64(i + 1)}
+	}
+	close(chanResult)
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Error stopping the component :\n%v", err)
+	}
+	// the failures of the primary exporter are reported, the other
+	// exporters still receive the results
+	if deliveryCount(t, component, DeliveryExported) != 3 || deliveryCount(t, component, DeliveryFailed) != 3 {
+		t.Fatalf("Invalid delivery counters")
+	}
+	for name, expected := range map[string]int{"primary": 3, "secondary": 6, "async": 4} {
+		recorded, err := component.Recorded(name)
+		if err != nil {
+			t.Fatalf("Fail to get the recorded results\n%v", err)
+		}
+		if len(recorded) != expected {
+			t.Fatalf("Invalid number of results for the exporter %s: %d", name, len(recorded))
+		}
+	}
+	// an asynchronous failure does not change the delivery status
+	if deliveryCount(t, component, DeliveryDispatched) != 0 {
+		t.Fatalf("The results should not be only dispatched")
+	}
+}
+
+func TestSyncExporters(t *testing.T) {
+	logger := zap.NewExample()
+	syncRecording := NewRecordingExporter(logger, &RecordingConfiguration{Name: "a", Delivery: Delivery{Sync: true}})
+	exporters := map[string]Exporter{
+		"a": syncRecording,
+		"b": &testExporter{name: "b"},
+	}
+	names, err := syncExporters(exporters, map[string]bool{}, map[string]bool{})
+	if err != nil {
+		t.Fatalf("Invalid synchronous exporters\n%v", err)
+	}
+	if len(names) != 1 || names[0] != "a" {
+		t.Fatalf("Invalid synchronous exporters %v", names)
+	}
+	_, err = syncExporters(exporters, map[string]bool{"a": true}, map[string]bool{})
+	if err == nil {
+		t.Fatalf("A synchronous exporter should not be allowed in a group")
+	}
+	concurrent, err := NewHTTPExporter(logger, &HTTPConfiguration{
+		Name:                "c",
+		Host:                "127.0.0.1",
+		Port:                8080,
+		Protocol:            healthcheck.HTTP,
+		MaxConcurrentPushes: 2,
+		Delivery:            Delivery{Sync: true},
+	})
+	if err != nil {
+		t.Fatalf("Fail to create the HTTP exporter\n%v", err)
+	}
+	_, err = syncExporters(map[string]Exporter{"c": concurrent}, map[string]bool{}, map[string]bool{})
+	if err == nil {
+		t.Fatalf("A synchronous exporter should not push concurrently")
+	}
+	var config RecordingConfiguration
+	err = yaml.Unmarshal([]byte("name: a\nenabled: true\nsync: true\n"), &config)
+	if err != nil {
+		t.Fatalf("Fail to parse the configuration\n%v", err)
+	}
+	if !config.Sync {
+		t.Fatalf("The exporter should be synchronous")
+	}
+}
@@ -0,0 +1,242 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// DefaultEnrichmentTimeout the default timeout of the enrichment requests
+const DefaultEnrichmentTimeout = healthcheck.Duration(time.Second)
+
+// DefaultEnrichmentTTL the default duration the enrichment data is cached
+const DefaultEnrichmentTTL = healthcheck.Duration(5 * time.Minute)
+
+// DefaultEnrichmentFailureTTL the default duration the enrichment failures
+// are cached, to not call the endpoint on each result while it is down
+const DefaultEnrichmentFailureTTL = healthcheck.Duration(30 * time.Second)
+
+// enrichmentMaxResponseSize the maximum size of the enrichment responses
+const enrichmentMaxResponseSize = 64 * 1024
+
+// EnrichmentConfiguration the configuration of the results enrichment. Before
+// being exported, the results are enriched with the key/values returned by an
+// HTTP endpoint for their healthcheck.
+type EnrichmentConfiguration struct {
+	// the endpoint called with a GET request, the healthcheck name is sent
+	// in the name query parameter. It should return a JSON object of
+	// strings. Disabled if empty.
+	URL     string
+	Headers map[string]string
+	Timeout healthcheck.Duration
+	// the duration the enrichment data of an healthcheck is cached
+	TTL        healthcheck.Duration
+	FailureTTL healthcheck.Duration `yaml:"failure-ttl"`
+	// the key/values are added to the labels (the default) or to the
+	// metadata. The existing keys are not overridden.
+	Target string
+}
+
+// UnmarshalYAML parses the enrichment configuration from YAML.
+func (c *EnrichmentConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration EnrichmentConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the enrichment configuration")
+	}
+	if raw.URL == "" {
+		return errors.New("Invalid URL for the enrichment configuration")
+	}
+	parsed, err := url.Parse(raw.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("Invalid URL %s for the enrichment configuration", raw.URL)
+	}
+	if raw.Timeout < 0 || raw.TTL < 0 || raw.FailureTTL < 0 {
+		return errors.New("The enrichment timeout and TTLs should be positive")
+	}
+	if raw.Timeout == 0 {
+		raw.Timeout = DefaultEnrichmentTimeout
+	}
+	if raw.TTL == 0 {
+		raw.TTL = DefaultEnrichmentTTL
+	}
+	if raw.FailureTTL == 0 {
+		raw.FailureTTL = DefaultEnrichmentFailureTTL
+	}
+	if raw.Target == "" {
+		raw.Target = "labels"
+	}
+	if raw.Target != "labels" && raw.Target != "metadata" {
+		return fmt.Errorf("Invalid enrichment target %s, labels or metadata are expected", raw.Target)
+	}
+	*c = EnrichmentConfiguration(raw)
+	return nil
+}
+
+// enrichmentEntry the cached enrichment data of an healthcheck
+type enrichmentEntry struct {
+	values  map[string]string
+	expires time.Time
+	// the last time the entry was used, the unused entries are pruned
+	used time.Time
+	// true while the entry is refreshed in the background
+	refreshing bool
+}
+
+// enricher enriches the results with the data returned by the enrichment
+// endpoint
+type enricher struct {
+	logger  *zap.Logger
+	config  *EnrichmentConfiguration
+	client  *http.Client
+	counter *prom.CounterVec
+	lock    sync.Mutex
+	cache   map[string]enrichmentEntry
+	// the background refreshes in progress
+	refreshes sync.WaitGroup
+}
+
+// newEnricher creates the enricher, or returns nil if the enrichment is
+// disabled
+func newEnricher(logger *zap.Logger, config *EnrichmentConfiguration, counter *prom.CounterVec) *enricher {
+	if config.URL == "" {
+		return nil
+	}
+	return &enricher{
+		logger:  logger,
+		config:  config,
+		client:  &http.Client{},
+		counter: counter,
+		cache:   make(map[string]enrichmentEntry),
+	}
+}
+
+// fetch calls the enrichment endpoint for an healthcheck
+func (e *enricher) fetch(name string) (map[string]string, error) {
+	timeout := time.Duration(e.config.Timeout)
+	if timeout == 0 {
+		timeout = time.Duration(DefaultEnrichmentTimeout)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.config.URL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Fail to create the enrichment request")
+	}
+	query := req.URL.Query()
+	query.Set("name", name)
+	req.URL.RawQuery = query.Encode()
+	req.Header.Set("User-Agent", "Cabourotte")
+	for k, v := range e.config.Headers {
+		req.Header.Set(k, v)
+	}
+	response, err := e.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Enrichment request failed")
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("The enrichment endpoint returned the status %d", response.StatusCode)
+	}
+	body, err := healthcheck.ReadBody(response.Body, enrichmentMaxResponseSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "Fail to read the enrichment response")
+	}
+	values := make(map[string]string)
+	err = json.Unmarshal(body, &values)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid enrichment response")
+	}
+	return values, nil
+}
+
+// values returns the cached enrichment data of an healthcheck. The endpoint
+// is called in the background when the entry is missing or expired, the
+// stale (or missing) data is returned until the refresh completes.
+func (e *enricher) values(name string) map[string]string {
+	now := time.Now()
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	entry, ok := e.cache[name]
+	entry.used = now
+	if (!ok || !now.Before(entry.expires)) && !entry.refreshing {
+		entry.refreshing = true
+		e.prune(now)
+		e.refreshes.Add(1)
+		go e.refresh(name)
+	}
+	e.cache[name] = entry
+	return entry.values
+}
+
+// refresh calls the enrichment endpoint and updates the cache. On failure,
+// the stale data is kept and the endpoint is called again after the failure
+// TTL.
+func (e *enricher) refresh(name string) {
+	defer e.refreshes.Done()
+	values, err := e.fetch(name)
+	ttl := time.Duration(e.config.TTL)
+	if err != nil {
+		e.logger.Error(fmt.Sprintf("Fail to enrich the results of the healthcheck %s: %s", name, err.Error()))
+		e.counter.WithLabelValues().Inc()
+		ttl = time.Duration(e.config.FailureTTL)
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	entry := e.cache[name]
+	if err == nil {
+		entry.values = values
+	}
+	entry.expires = time.Now().Add(ttl)
+	entry.refreshing = false
+	e.cache[name] = entry
+}
+
+// prune removes the entries of the healthcheck which produced no result
+// during two TTLs, for example because they were removed.
+// The function is *not* thread-safe.
+func (e *enricher) prune(now time.Time) {
+	unused := 2 * time.Duration(e.config.TTL)
+	for name, entry := range e.cache {
+		if !entry.refreshing && now.Sub(entry.used) > unused {
+			delete(e.cache, name)
+		}
+	}
+}
+
+// enrich merges the enrichment data into the result labels or metadata,
+// without overriding the existing keys
+func (e *enricher) enrich(result *healthcheck.Result) {
+	values := e.values(result.Name)
+	if len(values) == 0 {
+		return
+	}
+	// the labels and metadata maps can be shared with the healthcheck
+	// configuration, they are copied
+	target := result.Labels
+	if e.config.Target == "metadata" {
+		target = result.Metadata
+	}
+	merged := make(map[string]string, len(target)+len(values))
+	for k, v := range values {
+		merged[k] = v
+	}
+	for k, v := range target {
+		merged[k] = v
+	}
+	if e.config.Target == "metadata" {
+		result.Metadata = merged
+	} else {
+		result.Labels = merged
+	}
+}
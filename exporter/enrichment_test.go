@@ -0,0 +1,133 @@
+package exporter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+func TestEnrich(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Query().Get("name") != "foo" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		// nolint
+		json.NewEncoder(w).Encode(map[string]string{"owner": "team-a", "env": "overridden"})
+	}))
+	defer ts.Close()
+	counter := prom.NewCounterVec(prom.CounterOpts{Name: "enrichment_failures"}, []string{})
+	e := newEnricher(zap.NewExample(), &EnrichmentConfiguration{
+		URL:        ts.URL,
+		Headers:    map[string]string{"Authorization": "token"},
+		Timeout:    healthcheck.Duration(time.Second),
+		TTL:        healthcheck.Duration(time.Minute),
+		FailureTTL: healthcheck.Duration(time.Minute),
+		Target:     "labels",
+	}, counter)
+	labels := map[string]string{"env": "prod"}
+	// the first result is exported while the data is fetched
+	result := &healthcheck.Result{Name: "foo", Labels: labels}
+	e.enrich(result)
+	if len(result.Labels) != 1 {
+		t.Fatalf("The result should not be enriched yet %v", result.Labels)
+	}
+	e.refreshes.Wait()
+	for i := 0; i < 3; i++ {
+		result := &healthcheck.Result{Name: "foo", Labels: labels}
+		e.enrich(result)
+		if result.Labels["owner"] != "team-a" || result.Labels["env"] != "prod" {
+			t.Fatalf("Invalid enriched labels %v", result.Labels)
+		}
+	}
+	if len(labels) != 1 {
+		t.Fatalf("The healthcheck labels should not be modified %v", labels)
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("The enrichment data should be cached")
+	}
+	for i := 0; i < 3; i++ {
+		result := &healthcheck.Result{Name: "bar", Labels: labels}
+		e.enrich(result)
+		e.refreshes.Wait()
+		if len(result.Labels) != 1 {
+			t.Fatalf("The result should not be enriched %v", result.Labels)
+		}
+	}
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Fatalf("The enrichment failures should be cached")
+	}
+}
+
+func TestEnrichNotBlocking(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		// nolint
+		json.NewEncoder(w).Encode(map[string]string{"owner": "team-a"})
+	}))
+	defer ts.Close()
+	defer close(release)
+	counter := prom.NewCounterVec(prom.CounterOpts{Name: "enrichment_failures"}, []string{})
+	e := newEnricher(zap.NewExample(), &EnrichmentConfiguration{
+		URL:        ts.URL,
+		Timeout:    healthcheck.Duration(time.Minute),
+		TTL:        healthcheck.Duration(time.Minute),
+		FailureTTL: healthcheck.Duration(time.Minute),
+		Target:     "labels",
+	}, counter)
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		e.enrich(&healthcheck.Result{Name: "foo"})
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("The enrichment should not wait for the endpoint")
+	}
+	// the unused entries are pruned
+	e.lock.Lock()
+	e.cache["removed"] = enrichmentEntry{used: start.Add(-time.Hour), expires: start.Add(-time.Hour)}
+	e.prune(start)
+	_, removed := e.cache["removed"]
+	_, refreshing := e.cache["foo"]
+	e.lock.Unlock()
+	if removed || !refreshing {
+		t.Fatalf("Invalid cache after pruning")
+	}
+}
+
+func TestEnrichmentConfiguration(t *testing.T) {
+	var config EnrichmentConfiguration
+	err := yaml.Unmarshal([]byte("url: http://127.0.0.1:9000/cmdb\n"), &config)
+	if err != nil {
+		t.Fatalf("Fail to parse the configuration\n%v", err)
+	}
+	if config.Target != "labels" || config.TTL != DefaultEnrichmentTTL || config.Timeout != DefaultEnrichmentTimeout {
+		t.Fatalf("Invalid defaults %v", config)
+	}
+	invalid := []string{
+		"url: ftp://127.0.0.1/cmdb\n",
+		"url: http://127.0.0.1/cmdb\ntarget: annotations\n",
+		"url: http://127.0.0.1/cmdb\nttl: -1s\n",
+	}
+	for _, c := range invalid {
+		err := yaml.Unmarshal([]byte(c), &config)
+		if err == nil {
+			t.Fatalf("Was expecting an error when decoding the configuration: \n%s", c)
+		}
+	}
+}
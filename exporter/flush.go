@@ -0,0 +1,51 @@
+package exporter
+
+import (
+	"fmt"
+)
+
+// flusher is implemented by the exporters buffering the results in batches.
+// flush sends the pending results and returns their number.
+type flusher interface {
+	flush() (int, error)
+}
+
+// Flush immediately pushes the results buffered by the exporters: the rollups
+// of the current aggregation windows, the results queued for the exporters
+// pushing concurrently, and the batches of the batching exporters. Returns the
+// number of results flushed per exporter.
+func (c *Component) Flush() map[string]int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	flushed := make(map[string]int)
+	for _, aggregator := range c.aggregators {
+		// the rollups are pushed by the aggregator goroutine, so the
+		// exporter is not used concurrently
+		done := make(chan int, 1)
+		select {
+		case aggregator.flushRequests <- done:
+			flushed[aggregator.config.Exporter] += <-done
+		case <-c.t.Dying():
+		}
+	}
+	for name, pool := range c.pools {
+		drained := flushPool(pool)
+		// the drained results are counted when the batch is flushed
+		if _, ok := pool.exporter.(flusher); !ok {
+			flushed[name] += drained
+		}
+	}
+	for name, exporter := range c.Exporters {
+		f, ok := exporter.(flusher)
+		if !ok || !exporter.IsStarted() {
+			continue
+		}
+		count, err := f.flush()
+		if err != nil {
+			c.Logger.Error(fmt.Sprintf("Fail to flush the exporter %s: %s", name, err.Error()))
+			c.health.record(name, err)
+		}
+		flushed[name] += count
+	}
+	return flushed
+}
@@ -0,0 +1,161 @@
+package exporter
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+	"github.com/mcorbin/cabourotte/memorystore"
+	"github.com/mcorbin/cabourotte/prometheus"
+)
+
+// batchingExporter an exporter buffering the results in a batch
+type batchingExporter struct {
+	testExporter
+	batch int
+}
+
+func (e *batchingExporter) Push(result *healthcheck.Result) error {
+	e.batch++
+	return nil
+}
+
+func (e *batchingExporter) flush() (int, error) {
+	count := e.batch
+	e.pushed += count
+	e.batch = 0
+	return count, nil
+}
+
+func TestFlush(t *testing.T) {
+	chanResult := make(chan *healthcheck.Result, 10)
+	logger := zap.NewExample()
+	promComponent, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(
+		logger,
+		memorystore.NewMemoryStore(logger),
+		chanResult,
+		promComponent,
+		&Configuration{})
+	if err != nil {
+		t.Fatalf("Error creating the component :\n%v", err)
+	}
+	aggregated := &testExporter{name: "aggregated", started: true}
+	pooled := &testExporter{name: "pooled", started: true}
+	component.Exporters["aggregated"] = aggregated
+	component.Exporters["pooled"] = pooled
+	batching := &batchingExporter{testExporter: testExporter{name: "batching", started: true}, batch: 3}
+	component.Exporters["batching"] = batching
+	a := newAggregator(&AggregateConfiguration{
+		Name:     "rollup",
+		Window:   healthcheck.Duration(time.Hour),
+		Exporter: "aggregated",
+	})
+	component.aggregators = []*aggregator{a}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Error starting the component :\n%v", err)
+	}
+	pool := &pushPool{
+		exporter:      pooled,
+		workers:       1,
+		queue:         make(chan *healthcheck.Result, 3),
+		inFlight:      prom.NewGauge(prom.GaugeOpts{Name: "in_flight"}),
+		flushRequests: make(chan chan int),
+		stopped:       make(chan struct{}),
+	}
+	component.pools["pooled"] = pool
+	a.add(&healthcheck.Result{Name: "foo", Success: true, Duration: 1})
+	a.add(&healthcheck.Result{Name: "bar", Success: true, Duration: 1})
+	pool.queue <- &healthcheck.Result{Name: "foo", Success: true}
+	pool.queue <- &healthcheck.Result{Name: "bar", Success: true}
+	// the queue is drained by the pool worker, which may also push the
+	// results before receiving the flush request
+	component.startPool(pool)
+	flushed := component.Flush()
+	if flushed["aggregated"] != 2 || flushed["pooled"] > 2 || flushed["batching"] != 3 {
+		t.Fatalf("Invalid flushed results %v", flushed)
+	}
+	if aggregated.pushed != 2 || pooled.pushed != 2 || batching.pushed != 3 {
+		t.Fatalf("The results should be pushed")
+	}
+	flushed = component.Flush()
+	if flushed["aggregated"] != 0 || flushed["pooled"] != 0 || flushed["batching"] != 0 {
+		t.Fatalf("Nothing should be flushed %v", flushed)
+	}
+	delete(component.pools, "pooled")
+	stopPool(pool)
+	if flushPool(pool) != 0 {
+		t.Fatalf("Nothing should be flushed once the pool is stopped")
+	}
+	close(chanResult)
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Error stopping the component :\n%v", err)
+	}
+}
+
+// serialExporter an exporter failing if it is pushed concurrently
+type serialExporter struct {
+	testExporter
+	pushing    int32
+	concurrent int32
+}
+
+func (e *serialExporter) Push(result *healthcheck.Result) error {
+	if atomic.AddInt32(&e.pushing, 1) != 1 {
+		atomic.StoreInt32(&e.concurrent, 1)
+	}
+	time.Sleep(time.Millisecond)
+	atomic.AddInt32(&e.pushing, -1)
+	return nil
+}
+
+func TestFlushPoolSerialized(t *testing.T) {
+	logger := zap.NewExample()
+	promComponent, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(
+		logger,
+		memorystore.NewMemoryStore(logger),
+		make(chan *healthcheck.Result, 10),
+		promComponent,
+		&Configuration{})
+	if err != nil {
+		t.Fatalf("Error creating the component :\n%v", err)
+	}
+	exporter := &serialExporter{testExporter: testExporter{name: "serial", started: true}}
+	pool := &pushPool{
+		exporter:      exporter,
+		workers:       1,
+		queue:         make(chan *healthcheck.Result, 10),
+		inFlight:      prom.NewGauge(prom.GaugeOpts{Name: "in_flight"}),
+		flushRequests: make(chan chan int),
+		stopped:       make(chan struct{}),
+	}
+	component.startPool(pool)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			pool.queue <- &healthcheck.Result{Name: "foo", Success: true}
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		flushPool(pool)
+	}
+	<-done
+	stopPool(pool)
+	if atomic.LoadInt32(&exporter.concurrent) != 0 {
+		t.Fatalf("The exporter was pushed concurrently")
+	}
+}
@@ -0,0 +1,153 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// FormatJSON the results are encoded in JSON (the default)
+	FormatJSON = "json"
+	// FormatMsgpack the results are encoded in MessagePack, with the same
+	// fields as the JSON payloads
+	FormatMsgpack = "msgpack"
+)
+
+// validateFormat verifies the payload format of an exporter
+func validateFormat(format string) error {
+	if format != "" && format != FormatJSON && format != FormatMsgpack {
+		return fmt.Errorf("Invalid format %s, json or msgpack are expected", format)
+	}
+	return nil
+}
+
+// encodePayload encodes a payload in the format, and returns its content type
+func encodePayload(format string, payload interface{}) ([]byte, string, error) {
+	content, err := json.Marshal(payload)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "Fail to convert the payload to json")
+	}
+	if format != FormatMsgpack {
+		return content, "application/json", nil
+	}
+	// the payload is converted from its JSON representation, to keep the
+	// field names and the custom marshallers of the results
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, "", errors.Wrap(err, "Fail to convert the payload to msgpack")
+	}
+	var buffer bytes.Buffer
+	if err := writeMsgpack(&buffer, value); err != nil {
+		return nil, "", errors.Wrap(err, "Fail to convert the payload to msgpack")
+	}
+	return buffer.Bytes(), "application/msgpack", nil
+}
+
+// writeMsgpackHeader writes the header of a string, an array or a map of
+// length n. fix is the fixed format prefix for the small lengths, 0 if it
+// does not exist.
+func writeMsgpackHeader(buffer *bytes.Buffer, n int, fix byte, fixMax int, codes [3]byte) {
+	switch {
+	case fix != 0 && n <= fixMax:
+		buffer.WriteByte(fix | byte(n))
+	case codes[0] != 0 && n <= math.MaxUint8:
+		buffer.WriteByte(codes[0])
+		buffer.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buffer.WriteByte(codes[1])
+		// nolint
+		binary.Write(buffer, binary.BigEndian, uint16(n))
+	default:
+		buffer.WriteByte(codes[2])
+		// nolint
+		binary.Write(buffer, binary.BigEndian, uint32(n))
+	}
+}
+
+// writeMsgpackInt writes an integer in the smallest format
+func writeMsgpackInt(buffer *bytes.Buffer, i int64) {
+	switch {
+	case i >= -32 && i <= math.MaxInt8:
+		buffer.WriteByte(byte(int8(i)))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		buffer.WriteByte(0xd0)
+		buffer.WriteByte(byte(int8(i)))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		buffer.WriteByte(0xd1)
+		// nolint
+		binary.Write(buffer, binary.BigEndian, int16(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		buffer.WriteByte(0xd2)
+		// nolint
+		binary.Write(buffer, binary.BigEndian, int32(i))
+	default:
+		buffer.WriteByte(0xd3)
+		// nolint
+		binary.Write(buffer, binary.BigEndian, i)
+	}
+}
+
+// writeMsgpack encodes a value decoded from JSON. The map keys are sorted.
+func writeMsgpack(buffer *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buffer.WriteByte(0xc0)
+	case bool:
+		if v {
+			buffer.WriteByte(0xc3)
+		} else {
+			buffer.WriteByte(0xc2)
+		}
+	case json.Number:
+		if !strings.ContainsAny(v.String(), ".eE") {
+			if i, err := v.Int64(); err == nil {
+				writeMsgpackInt(buffer, i)
+				return nil
+			}
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return errors.Wrapf(err, "Invalid number %s", v.String())
+		}
+		buffer.WriteByte(0xcb)
+		// nolint
+		binary.Write(buffer, binary.BigEndian, math.Float64bits(f))
+	case string:
+		writeMsgpackHeader(buffer, len(v), 0xa0, 31, [3]byte{0xd9, 0xda, 0xdb})
+		buffer.WriteString(v)
+	case []interface{}:
+		writeMsgpackHeader(buffer, len(v), 0x90, 15, [3]byte{0, 0xdc, 0xdd})
+		for _, item := range v {
+			if err := writeMsgpack(buffer, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		writeMsgpackHeader(buffer, len(v), 0x80, 15, [3]byte{0, 0xde, 0xdf})
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := writeMsgpack(buffer, k); err != nil {
+				return err
+			}
+			if err := writeMsgpack(buffer, v[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("Unsupported type %T", value)
+	}
+	return nil
+}
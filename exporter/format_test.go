@@ -0,0 +1,219 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// readMsgpack decodes the msgpack values written by writeMsgpack
+func readMsgpack(reader *bytes.Reader) (interface{}, error) {
+	code, err := reader.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	readN := func(size int) (uint64, error) {
+		buf := make([]byte, 8)
+		if _, err := io.ReadFull(reader, buf[8-size:]); err != nil {
+			return 0, err
+		}
+		return binary.BigEndian.Uint64(buf), nil
+	}
+	readString := func(n uint64) (interface{}, error) {
+		buf := make([]byte, n)
+		_, err := io.ReadFull(reader, buf)
+		return string(buf), err
+	}
+	readArray := func(n uint64) (interface{}, error) {
+		result := []interface{}{}
+		for i := uint64(0); i < n; i++ {
+			v, err := readMsgpack(reader)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, v)
+		}
+		return result, nil
+	}
+	readMap := func(n uint64) (interface{}, error) {
+		result := map[string]interface{}{}
+		for i := uint64(0); i < n; i++ {
+			k, err := readMsgpack(reader)
+			if err != nil {
+				return nil, err
+			}
+			v, err := readMsgpack(reader)
+			if err != nil {
+				return nil, err
+			}
+			result[k.(string)] = v
+		}
+		return result, nil
+	}
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code&0xe0 == 0xa0:
+		return readString(uint64(code & 0x1f))
+	case code&0xf0 == 0x90:
+		return readArray(uint64(code & 0x0f))
+	case code&0xf0 == 0x80:
+		return readMap(uint64(code & 0x0f))
+	}
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (code - 0xd0)
+		n, err := readN(size)
+		shift := uint(64 - 8*size)
+		return int64(n<<shift) >> shift, err
+	case 0xcb:
+		n, err := readN(8)
+		return math.Float64frombits(n), err
+	case 0xd9, 0xda, 0xdb:
+		n, err := readN(1 << (code - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return readString(n)
+	case 0xdc, 0xdd:
+		n, err := readN(2 << (code - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return readArray(n)
+	case 0xde, 0xdf:
+		n, err := readN(2 << (code - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return readMap(n)
+	}
+	return nil, fmt.Errorf("unexpected code %x", code)
+}
+
+func TestEncodePayloadMsgpack(t *testing.T) {
+	long := strings.Repeat("a", 300)
+	many := make([]interface{}, 20)
+	for i := range many {
+		many[i] = int64(i)
+	}
+	payload := map[string]interface{}{
+		"nil":      nil,
+		"bool":     true,
+		"small":    5,
+		"negative": -20,
+		"int16":    1000,
+		"int32":    -100000,
+		"int64":    int64(1) << 40,
+		"float":    0.25,
+		"string":   "cabourotte",
+		"long":     long,
+		"array":    many,
+		"map":      map[string]interface{}{"k": "v"},
+	}
+	content, contentType, err := encodePayload(FormatMsgpack, payload)
+	if err != nil {
+		t.Fatalf("Fail to encode the payload\n%v", err)
+	}
+	if contentType != "application/msgpack" {
+		t.Fatalf("Invalid content type %s", contentType)
+	}
+	decoded, err := readMsgpack(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("Fail to decode the payload\n%v", err)
+	}
+	expected := map[string]interface{}{
+		"nil":      nil,
+		"bool":     true,
+		"small":    int64(5),
+		"negative": int64(-20),
+		"int16":    int64(1000),
+		"int32":    int64(-100000),
+		"int64":    int64(1) << 40,
+		"float":    0.25,
+		"string":   "cabourotte",
+		"long":     long,
+		"array":    many,
+		"map":      map[string]interface{}{"k": "v"},
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Fatalf("Invalid decoded payload %v", decoded)
+	}
+	content, contentType, err = encodePayload("", []int{1})
+	if err != nil || contentType != "application/json" || string(content) != "[1]" {
+		t.Fatalf("Invalid JSON payload %s %s %v", string(content), contentType, err)
+	}
+}
+
+func TestHTTPExporterMsgpack(t *testing.T) {
+	payloads := make(chan interface{}, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/msgpack" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		decoded, err := readMsgpack(bytes.NewReader(body))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		payloads <- decoded
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("Error getting HTTP server port :\n%v", err)
+	}
+	var config HTTPConfiguration
+	err = yaml.Unmarshal([]byte(fmt.Sprintf("name: foo\nhost: 127.0.0.1\nport: %d\nformat: msgpack\n", port)), &config)
+	if err != nil {
+		t.Fatalf("Fail to parse the configuration\n%v", err)
+	}
+	exporter, err := NewHTTPExporter(zap.NewExample(), &config)
+	if err != nil {
+		t.Fatalf("Error creating the http exporter :\n%v", err)
+	}
+	err = exporter.Push(&healthcheck.Result{
+		Name:                 "foo",
+		Success:              true,
+		HealthcheckTimestamp: 1600000000,
+		Timestamp:            time.Unix(1600000000, 0),
+		Labels:               map[string]string{"env": "prod"},
+	})
+	if err != nil {
+		t.Fatalf("Fail to push healthcheck result:\n%v", err)
+	}
+	results := (<-payloads).([]interface{})
+	result := results[0].(map[string]interface{})
+	if result["name"] != "foo" || result["success"] != true || result["labels"].(map[string]interface{})["env"] != "prod" {
+		t.Fatalf("Invalid result %v", result)
+	}
+	err = yaml.Unmarshal([]byte("name: foo\nhost: 127.0.0.1\nport: 8080\nformat: xml\n"), &config)
+	if err == nil {
+		t.Fatalf("The xml format should be invalid")
+	}
+}
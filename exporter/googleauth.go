@@ -0,0 +1,232 @@
+package exporter
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// googleTokenURL the default OAuth2 token endpoint of Google
+const googleTokenURL = "https://oauth2.googleapis.com/token"
+
+// googleMetadataTokenURL the token endpoint of the GCE metadata server
+const googleMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// googleTokenMaxResponseSize the maximum size of the token responses
+const googleTokenMaxResponseSize = 64 * 1024
+
+// googleCredentials a Google credentials file, for a service account or for
+// the user credentials created by gcloud
+type googleCredentials struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// googleToken the response of the token endpoints
+type googleToken struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// googleTokenSource returns OAuth2 access tokens, cached until they expire
+type googleTokenSource struct {
+	client      *http.Client
+	scope       string
+	credentials *googleCredentials
+	key         *rsa.PrivateKey
+	lock        sync.Mutex
+	token       string
+	expires     time.Time
+}
+
+// defaultCredentialsPath returns the path of the Application Default
+// Credentials file, empty if there is no file and the metadata server should
+// be used
+func defaultCredentialsPath() string {
+	if path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	path := filepath.Join(home, ".config", "gcloud", "application_default_credentials.json")
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// parsePrivateKey parses the PEM encoded RSA key of a service account
+func parsePrivateKey(content string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(content))
+	if block == nil {
+		return nil, errors.New("Invalid PEM private key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid private key")
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("The private key should be an RSA key")
+	}
+	return key, nil
+}
+
+// newGoogleTokenSource creates a token source from a credentials file. The
+// Application Default Credentials are used if the path is empty.
+func newGoogleTokenSource(client *http.Client, path string, scope string) (*googleTokenSource, error) {
+	source := &googleTokenSource{client: client, scope: scope}
+	if path == "" {
+		path = defaultCredentialsPath()
+	}
+	if path == "" {
+		return source, nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to read the credentials file %s", path)
+	}
+	var credentials googleCredentials
+	if err := json.Unmarshal(content, &credentials); err != nil {
+		return nil, errors.Wrapf(err, "Invalid credentials file %s", path)
+	}
+	if credentials.TokenURI == "" {
+		credentials.TokenURI = googleTokenURL
+	}
+	switch credentials.Type {
+	case "service_account":
+		if credentials.ClientEmail == "" {
+			return nil, fmt.Errorf("The client_email is missing in the credentials file %s", path)
+		}
+		key, err := parsePrivateKey(credentials.PrivateKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid credentials file %s", path)
+		}
+		source.key = key
+	case "authorized_user":
+		if credentials.RefreshToken == "" {
+			return nil, fmt.Errorf("The refresh_token is missing in the credentials file %s", path)
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported credentials type '%s' in the file %s", credentials.Type, path)
+	}
+	source.credentials = &credentials
+	return source, nil
+}
+
+// assertion returns the signed JWT exchanged for a service account token
+func (s *googleTokenSource) assertion(now time.Time) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   s.credentials.ClientEmail,
+		"scope": s.scope,
+		"aud":   s.credentials.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "Fail to create the token assertion")
+	}
+	content := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(content))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", errors.Wrap(err, "Fail to sign the token assertion")
+	}
+	return content + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// request returns the request fetching a new token
+func (s *googleTokenSource) request(ctx context.Context, now time.Time) (*http.Request, error) {
+	if s.credentials == nil {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, googleMetadataTokenURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Metadata-Flavor", "Google")
+		return req, nil
+	}
+	form := url.Values{}
+	if s.key != nil {
+		assertion, err := s.assertion(now)
+		if err != nil {
+			return nil, err
+		}
+		form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+		form.Set("assertion", assertion)
+	} else {
+		form.Set("grant_type", "refresh_token")
+		form.Set("client_id", s.credentials.ClientID)
+		form.Set("client_secret", s.credentials.ClientSecret)
+		form.Set("refresh_token", s.credentials.RefreshToken)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.credentials.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
+
+// Token returns a valid access token, renewed a minute before its
+// expiration
+func (s *googleTokenSource) Token(ctx context.Context) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := time.Now()
+	if s.token != "" && now.Before(s.expires) {
+		return s.token, nil
+	}
+	req, err := s.request(ctx, now)
+	if err != nil {
+		return "", errors.Wrap(err, "Fail to create the token request")
+	}
+	response, err := s.client.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "Token request failed")
+	}
+	defer response.Body.Close()
+	body, err := healthcheck.ReadBody(response.Body, googleTokenMaxResponseSize)
+	if err != nil {
+		return "", errors.Wrap(err, "Fail to read the token response")
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("The token endpoint returned the status %d: %s", response.StatusCode, string(body))
+	}
+	var token googleToken
+	if err := json.Unmarshal(body, &token); err != nil || token.AccessToken == "" {
+		return "", errors.New("Invalid token response")
+	}
+	s.token = token.AccessToken
+	s.expires = now.Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return s.token, nil
+}
@@ -0,0 +1,80 @@
+package exporter
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// GroupConfiguration the configuration of a group of exporters. Results are
+// only pushed to the primary exporter, and pushed to the fallback exporter
+// when the primary one is unhealthy.
+type GroupConfiguration struct {
+	Name     string
+	Primary  string
+	Fallback string
+}
+
+// validateGroups verifies the groups configuration and returns the names of
+// the exporters belonging to a group
+func validateGroups(groups []GroupConfiguration, exporters map[string]Exporter) (map[string]bool, error) {
+	grouped := make(map[string]bool)
+	names := make(map[string]bool)
+	for _, group := range groups {
+		if group.Name == "" {
+			return nil, errors.New("Invalid name for the exporter group configuration")
+		}
+		if _, ok := names[group.Name]; ok {
+			return nil, fmt.Errorf("Exporter group %s is defined multiple times", group.Name)
+		}
+		names[group.Name] = true
+		if group.Primary == group.Fallback {
+			return nil, fmt.Errorf("The primary and fallback exporters of the group %s should be different", group.Name)
+		}
+		for _, name := range []string{group.Primary, group.Fallback} {
+			if _, ok := exporters[name]; !ok {
+				return nil, fmt.Errorf("Unknown exporter %s in the exporter group %s", name, group.Name)
+			}
+			if _, ok := grouped[name]; ok {
+				return nil, fmt.Errorf("The exporter %s belongs to several exporter groups", name)
+			}
+			grouped[name] = true
+		}
+	}
+	return grouped, nil
+}
+
+// setActive updates the metrics of a group with its active exporter
+func (c *Component) setActive(group *GroupConfiguration, active string) {
+	for _, name := range []string{group.Primary, group.Fallback} {
+		value := float64(0)
+		if name == active {
+			value = 1
+		}
+		c.groupGauge.With(prom.Labels{"group": group.Name, "name": name}).Set(value)
+	}
+}
+
+// pushGroup pushes a result to the primary exporter of a group, or to the
+// fallback exporter if the primary one is unhealthy.
+func (c *Component) pushGroup(group *GroupConfiguration, message *healthcheck.Result) {
+	primary := c.Exporters[group.Primary]
+	if !primary.IsStarted() {
+		c.reconnect(primary)
+	}
+	if primary.IsStarted() && c.push(primary, message) {
+		c.setActive(group, group.Primary)
+		return
+	}
+	c.setActive(group, group.Fallback)
+	fallback := c.Exporters[group.Fallback]
+	if !fallback.IsStarted() {
+		c.reconnect(fallback)
+	}
+	if fallback.IsStarted() {
+		c.push(fallback, message)
+	}
+}
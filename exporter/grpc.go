@@ -0,0 +1,156 @@
+package exporter
+
+import (
+	cryptotls "crypto/tls"
+	"fmt"
+	"net"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/grpc"
+	"github.com/mcorbin/cabourotte/healthcheck"
+	"github.com/mcorbin/cabourotte/memorystore"
+	"github.com/mcorbin/cabourotte/tls"
+)
+
+// DefaultGRPCBufferSize the default number of results buffered per subscriber
+const DefaultGRPCBufferSize = 100
+
+// GRPCConfiguration the configuration of the gRPC exporter, which exposes the
+// results to subscribers.
+type GRPCConfiguration struct {
+	Name       string
+	Host       string
+	Port       uint32
+	Key        string `json:"key,omitempty"`
+	Cert       string `json:"cert,omitempty"`
+	Cacert     string `json:"cacert,omitempty"`
+	BufferSize int    `yaml:"buffer-size"`
+	// the delivery of the results, asynchronous by default
+	Delivery `json:",inline" yaml:",inline"`
+}
+
+// GRPCExporter the gRPC exporter struct
+type GRPCExporter struct {
+	startedState
+	Logger *zap.Logger
+	Config *GRPCConfiguration
+	Server *grpc.Server
+}
+
+// UnmarshalYAML parses the configuration of the gRPC exporter from YAML.
+func (c *GRPCConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration GRPCConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read gRPC exporter configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid name for the gRPC exporter configuration")
+	}
+	if net.ParseIP(raw.Host) == nil {
+		return errors.New("Invalid IP address for the gRPC exporter configuration")
+	}
+	if raw.Port == 0 {
+		return errors.New("Invalid port for the gRPC exporter configuration")
+	}
+	if !((raw.Key != "" && raw.Cert != "") ||
+		(raw.Key == "" && raw.Cert == "")) {
+		return errors.New("Invalid certificates")
+	}
+	if raw.Cacert != "" && raw.Cert == "" {
+		return errors.New("The cacert option requires a certificate")
+	}
+	if raw.BufferSize < 0 {
+		return errors.New("Invalid buffer size for the gRPC exporter configuration")
+	}
+	if raw.BufferSize == 0 {
+		raw.BufferSize = DefaultGRPCBufferSize
+	}
+	*c = GRPCConfiguration(raw)
+	return nil
+}
+
+// NewGRPCExporter creates a new gRPC exporter. The snapshots are built from the
+// memory store.
+func NewGRPCExporter(logger *zap.Logger, config *GRPCConfiguration, store *memorystore.MemoryStore, dropped *prom.CounterVec) (*GRPCExporter, error) {
+	var tlsConfig *cryptotls.Config
+	if config.Cert != "" {
+		c, err := tls.GetTLSConfig(config.Key, config.Cert, config.Cacert, false)
+		if err != nil {
+			return nil, err
+		}
+		// the ca certificate is used to verify the clients
+		if c.RootCAs != nil {
+			c.ClientCAs = c.RootCAs
+			c.ClientAuth = cryptotls.RequireAndVerifyClientCert
+		}
+		tlsConfig = c
+	}
+	bufferSize := config.BufferSize
+	if bufferSize == 0 {
+		bufferSize = DefaultGRPCBufferSize
+	}
+	address := net.JoinHostPort(config.Host, fmt.Sprintf("%d", config.Port))
+	server := grpc.New(
+		logger,
+		address,
+		tlsConfig,
+		bufferSize,
+		store.List,
+		dropped.With(prom.Labels{"name": config.Name}))
+	return &GRPCExporter{
+		Logger: logger,
+		Config: config,
+		Server: server,
+	}, nil
+}
+
+// IsStarted returns the exporter status
+func (c *GRPCExporter) IsStarted() bool {
+	return c.started()
+}
+
+// Start starts the gRPC exporter
+func (c *GRPCExporter) Start() error {
+	c.Logger.Info(fmt.Sprintf("Starting the gRPC exporter %s", c.Config.Name))
+	err := c.Server.Start()
+	if err != nil {
+		return err
+	}
+	c.setStarted(true)
+	return nil
+}
+
+// Reconnect restarts the gRPC server if needed
+func (c *GRPCExporter) Reconnect() error {
+	if c.started() {
+		return nil
+	}
+	return c.Start()
+}
+
+// Stop stops the gRPC exporter
+func (c *GRPCExporter) Stop() error {
+	c.Logger.Info(fmt.Sprintf("Stopping the gRPC exporter %s", c.Config.Name))
+	c.setStarted(false)
+	return c.Server.Stop()
+}
+
+// Name returns the name of the exporter
+func (c *GRPCExporter) Name() string {
+	return c.Config.Name
+}
+
+// GetConfig returns the config of the exporter
+func (c *GRPCExporter) GetConfig() interface{} {
+	return c.Config
+}
+
+// Push sends the result to the subscribers
+func (c *GRPCExporter) Push(result *healthcheck.Result) error {
+	c.Server.Broadcast(result)
+	return nil
+}
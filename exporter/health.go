@@ -0,0 +1,64 @@
+package exporter
+
+import (
+	"sync"
+	"time"
+)
+
+// health tracks the exporters failing to push or to reconnect
+type health struct {
+	lock sync.Mutex
+	// since when the exporters are failing, by exporter name
+	failingSince map[string]time.Time
+	// the last failure of the failing exporters, by exporter name
+	lastFailure map[string]time.Time
+}
+
+// newHealth creates a new health tracker
+func newHealth() *health {
+	return &health{
+		failingSince: make(map[string]time.Time),
+		lastFailure:  make(map[string]time.Time),
+	}
+}
+
+// record records the outcome of a push or of a reconnection of an exporter
+func (h *health) record(name string, err error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if err == nil {
+		delete(h.failingSince, name)
+		delete(h.lastFailure, name)
+		return
+	}
+	now := time.Now()
+	if _, ok := h.failingSince[name]; !ok {
+		h.failingSince[name] = now
+	}
+	h.lastFailure[name] = now
+}
+
+// AllFailing returns true if all exporters are failing, a successful push
+// marking an exporter as healthy again. since is the date since when all
+// exporters are failing, and last the oldest of their last failures.
+func (c *Component) AllFailing() (since time.Time, last time.Time, ok bool) {
+	if len(c.Exporters) == 0 {
+		return since, last, false
+	}
+	c.health.lock.Lock()
+	defer c.health.lock.Unlock()
+	for name := range c.Exporters {
+		failingSince, failing := c.health.failingSince[name]
+		if !failing {
+			return time.Time{}, time.Time{}, false
+		}
+		if failingSince.After(since) {
+			since = failingSince
+		}
+		lastFailure := c.health.lastFailure[name]
+		if last.IsZero() || lastFailure.Before(last) {
+			last = lastFailure
+		}
+	}
+	return since, last, true
+}
@@ -2,10 +2,10 @@ package exporter
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -15,6 +15,9 @@ import (
 	"github.com/mcorbin/cabourotte/tls"
 )
 
+// CorrelationIDHeader the header containing the correlation ID of the pushed result
+const CorrelationIDHeader = "X-Cabourotte-Correlation-ID"
+
 // HTTPConfiguration The configuration for the HTTP exporter.
 type HTTPConfiguration struct {
 	Name     string
@@ -26,8 +29,102 @@ type HTTPConfiguration struct {
 	Cert     string `json:"cert,omitempty"`
 	Cacert   string `json:"cacert,omitempty"`
 	Insecure bool
+	// optional proxy URL (http, https or socks5)
+	Proxy string
+	// the maximum size of the response body read, and the maximum duration
+	// of a request
+	MaxBodySize uint                 `json:"max-body-size,omitempty" yaml:"max-body-size,omitempty"`
+	Timeout     healthcheck.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// the maximum duration of the TLS handshake, the timeout is used if
+	// not set
+	HandshakeTimeout healthcheck.Duration `json:"handshake-timeout,omitempty" yaml:"handshake-timeout,omitempty"`
+	// the number of results pushed in parallel, 1 if not set
+	MaxConcurrentPushes uint `json:"max-concurrent-pushes,omitempty" yaml:"max-concurrent-pushes,omitempty"`
+	// the results are pushed to each endpoint, using the other options of
+	// the configuration
+	Endpoints []HTTPEndpoint `json:"endpoints,omitempty" yaml:"endpoints,omitempty"`
+	// the delivery of the results, asynchronous by default
+	Delivery `json:",inline" yaml:",inline"`
+	// the serialized result fields, all by default
+	Projection *Projection `json:"projection,omitempty" yaml:"projection,omitempty"`
+	// the encoding of the payloads, json (the default) or msgpack
+	Format string `json:"format,omitempty" yaml:"format,omitempty"`
+}
+
+// HTTPEndpoint an endpoint of an HTTP exporter
+type HTTPEndpoint struct {
+	Host string
+	Port uint32
+	// the exporter path is used if not set
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+}
+
+// UnmarshalYAML parses an HTTP exporter endpoint from YAML.
+func (e *HTTPEndpoint) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawEndpoint HTTPEndpoint
+	raw := rawEndpoint{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read HTTP exporter endpoint")
+	}
+	if raw.Host == "" {
+		return errors.New("Invalid host for the HTTP exporter endpoint")
+	}
+	if raw.Port == 0 {
+		return fmt.Errorf("Invalid port for the HTTP exporter endpoint %s", raw.Host)
+	}
+	*e = HTTPEndpoint(raw)
+	return nil
 }
 
+// endpointsConfigurations returns a configuration for each endpoint, named
+// <name>-<host>-<port>
+func (c *HTTPConfiguration) endpointsConfigurations() []HTTPConfiguration {
+	if len(c.Endpoints) == 0 {
+		return []HTTPConfiguration{*c}
+	}
+	result := make([]HTTPConfiguration, 0, len(c.Endpoints))
+	for _, endpoint := range c.Endpoints {
+		config := *c
+		config.Endpoints = nil
+		config.Name = fmt.Sprintf("%s-%s-%d", c.Name, endpoint.Host, endpoint.Port)
+		config.Host = endpoint.Host
+		config.Port = endpoint.Port
+		if endpoint.Path != "" {
+			config.Path = endpoint.Path
+		}
+		result = append(result, config)
+	}
+	return result
+}
+
+// validateEndpointsReferences verifies that the HTTP exporters with endpoints
+// are not referenced by their configured name in the exporter groups and
+// aggregates, these exporters are renamed for each endpoint.
+func validateEndpointsReferences(config *Configuration) error {
+	expanded := make(map[string]bool)
+	for _, httpConfig := range config.HTTP {
+		if len(httpConfig.Endpoints) != 0 {
+			expanded[httpConfig.Name] = true
+		}
+	}
+	for _, group := range config.Groups {
+		for _, name := range []string{group.Primary, group.Fallback} {
+			if expanded[name] {
+				return fmt.Errorf("The HTTP exporter %s has endpoints and can not be used in the exporter group %s, use the name of one of its endpoints (%s-<host>-<port>)", name, group.Name, name)
+			}
+		}
+	}
+	for _, aggregate := range config.Aggregates {
+		if expanded[aggregate.Exporter] {
+			return fmt.Errorf("The HTTP exporter %s has endpoints and can not be used in the aggregate %s, use the name of one of its endpoints (%s-<host>-<port>)", aggregate.Exporter, aggregate.Name, aggregate.Exporter)
+		}
+	}
+	return nil
+}
+
+// DefaultHTTPExporterTimeout the default timeout of the HTTP exporter requests
+const DefaultHTTPExporterTimeout = 3 * time.Second
+
 // HTTPExporter the http exporter struct
 type HTTPExporter struct {
 	Started bool
@@ -35,6 +132,9 @@ type HTTPExporter struct {
 	URL     string
 	Config  *HTTPConfiguration
 	Client  *http.Client
+	// protects Started, updated by the goroutines pushing the results and
+	// read by the readiness probe
+	startedLock sync.RWMutex
 }
 
 // UnmarshalYAML parses the configuration of the http component from YAML.
@@ -44,19 +144,52 @@ func (c *HTTPConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) err
 	if err := unmarshal(&raw); err != nil {
 		return errors.Wrap(err, "Unable to read HTTP exporter configuration")
 	}
-	if raw.Host == "" {
-		return errors.New("Invalid host for the HTTP exporter configuration")
-	}
 	if raw.Name == "" {
 		return errors.New("Invalid name for the HTTP exporter configuration")
 	}
-	if raw.Port == 0 {
-		return errors.New("Invalid port for the HTTP server")
+	if len(raw.Endpoints) != 0 {
+		if raw.Host != "" || raw.Port != 0 {
+			return errors.New("The host and the port of the HTTP exporter should be set in the endpoints")
+		}
+		endpoints := make(map[string]bool)
+		for _, endpoint := range raw.Endpoints {
+			key := net.JoinHostPort(endpoint.Host, fmt.Sprintf("%d", endpoint.Port))
+			if endpoints[key] {
+				return fmt.Errorf("The HTTP exporter endpoint %s is duplicated", key)
+			}
+			endpoints[key] = true
+		}
+	} else {
+		if raw.Host == "" {
+			return errors.New("Invalid host for the HTTP exporter configuration")
+		}
+		if raw.Port == 0 {
+			return errors.New("Invalid port for the HTTP server")
+		}
 	}
 	if !((raw.Key != "" && raw.Cert != "") ||
 		(raw.Key == "" && raw.Cert == "")) {
 		return errors.New("Invalid certificates")
 	}
+	if raw.Proxy != "" {
+		if _, err := healthcheck.ParseProxy(raw.Proxy); err != nil {
+			return errors.Wrap(err, "Invalid proxy for the HTTP exporter configuration")
+		}
+	}
+	if raw.Timeout < 0 {
+		return errors.New("The HTTP exporter timeout should be positive")
+	}
+	if raw.HandshakeTimeout < 0 || (raw.Timeout != 0 && raw.HandshakeTimeout > raw.Timeout) {
+		return errors.New("The HTTP exporter handshake-timeout should be positive and lower than the timeout")
+	}
+	if raw.Projection != nil {
+		if err := raw.Projection.Validate(); err != nil {
+			return errors.Wrap(err, "Invalid projection for the HTTP exporter")
+		}
+	}
+	if err := validateFormat(raw.Format); err != nil {
+		return errors.Wrap(err, "Invalid HTTP exporter configuration")
+	}
 	*c = HTTPConfiguration(raw)
 	return nil
 }
@@ -77,16 +210,27 @@ func NewHTTPExporter(logger *zap.Logger, config *HTTPConfiguration) (*HTTPExport
 		net.JoinHostPort(config.Host, fmt.Sprintf("%d", config.Port)),
 		config.Path)
 	transport := &http.Transport{
-		TLSClientConfig: tlsConfig,
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: time.Duration(config.HandshakeTimeout),
+	}
+	if config.Proxy != "" {
+		proxyURL, err := healthcheck.ParseProxy(config.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	timeout := time.Duration(config.Timeout)
+	if timeout == 0 {
+		timeout = DefaultHTTPExporterTimeout
 	}
-
 	exporter := HTTPExporter{
 		Logger: logger,
 		Config: config,
 		URL:    url,
 		Client: &http.Client{
 			Transport: transport,
-			Timeout:   time.Second * 3,
+			Timeout:   timeout,
 			CheckRedirect: func(req *http.Request, via []*http.Request) error {
 				return http.ErrUseLastResponse
 			},
@@ -97,28 +241,37 @@ func NewHTTPExporter(logger *zap.Logger, config *HTTPConfiguration) (*HTTPExport
 
 // IsStarted returns the exporter status
 func (c *HTTPExporter) IsStarted() bool {
+	c.startedLock.RLock()
+	defer c.startedLock.RUnlock()
 	return c.Started
 }
 
+// setStarted updates the exporter status
+func (c *HTTPExporter) setStarted(started bool) {
+	c.startedLock.Lock()
+	defer c.startedLock.Unlock()
+	c.Started = started
+}
+
 // Start starts the HTTP exporter component
 func (c *HTTPExporter) Start() error {
 	// nothing to do
 	c.Logger.Info(fmt.Sprintf("Starting the HTTP healthcheck exporter on %s:%d", c.Config.Host, c.Config.Port))
-	c.Started = true
+	c.setStarted(true)
 	return nil
 }
 
 // Reconnect reconnects the HTTP exporter component
 func (c *HTTPExporter) Reconnect() error {
 	// nothing to do
-	c.Started = true
+	c.setStarted(true)
 	return nil
 }
 
 // Stop stops the HTTP exporter component
 func (c *HTTPExporter) Stop() error {
 	c.Logger.Info(fmt.Sprintf("Stopping the http exporter %s", c.Config.Name))
-	c.Started = false
+	c.setStarted(false)
 	return nil
 }
 
@@ -132,23 +285,45 @@ func (c *HTTPExporter) GetConfig() interface{} {
 	return c.Config
 }
 
+// probe verifies that the destination is reachable
+func (c *HTTPExporter) probe() error {
+	return probeURL(c.Client, c.URL)
+}
+
+// maxConcurrentPushes returns the number of results pushed in parallel
+func (c *HTTPExporter) maxConcurrentPushes() uint {
+	return c.Config.MaxConcurrentPushes
+}
+
 // Push pushes events to the HTTP destination
 func (c *HTTPExporter) Push(result *healthcheck.Result) error {
-	var jsonBytes []byte
-	payload := []*healthcheck.Result{result}
-	jsonBytes, err := json.Marshal(payload)
+	projected, err := c.Config.Projection.project(result)
 	if err != nil {
-		return errors.Wrapf(err, "Fail to convert result to json:\n%v", result)
+		return err
 	}
-	req, err := http.NewRequest("POST", c.URL, bytes.NewBuffer(jsonBytes))
+	payload := []interface{}{projected}
+	content, contentType, err := encodePayload(c.Config.Format, payload)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to encode the result:\n%v", result)
+	}
+	req, err := http.NewRequest("POST", c.URL, bytes.NewBuffer(content))
 	if err != nil {
 		return errors.Wrapf(err, "HTTP exporter: fail to create request for %s", c.URL)
 	}
-	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Type", contentType)
+	if id := result.CorrelationID(); id != "" {
+		req.Header.Set(CorrelationIDHeader, id)
+	}
 	resp, err := c.Client.Do(req)
 	if err != nil {
 		return errors.Wrapf(err, "HTTP exporter: fail to send healthchecks to %s", c.URL)
 	}
+	defer resp.Body.Close()
+	// the response is read so the connection can be reused
+	_, err = healthcheck.ReadBody(resp.Body, c.Config.MaxBodySize)
+	if err != nil {
+		return errors.Wrapf(err, "HTTP exporter: fail to read the response from %s", c.URL)
+	}
 	if resp.StatusCode >= 400 {
 		return fmt.Errorf("HTTP exporter: request failed, status %d", resp.StatusCode)
 	}
@@ -1,6 +1,7 @@
 package exporter
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -11,6 +12,8 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/mcorbin/cabourotte/healthcheck"
+	"github.com/mcorbin/cabourotte/memorystore"
+	"github.com/mcorbin/cabourotte/prometheus"
 )
 
 func TestHTTPExporter(t *testing.T) {
@@ -56,3 +59,196 @@ func TestHTTPExporter(t *testing.T) {
 		t.Fatalf("The request counter is invalid")
 	}
 }
+
+func TestHTTPExporterCorrelationID(t *testing.T) {
+	header := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get(CorrelationIDHeader)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("Error getting HTTP server port :\n%v", err)
+	}
+	exporter, err := NewHTTPExporter(
+		zap.NewExample(),
+		&HTTPConfiguration{
+			Host:     "127.0.0.1",
+			Port:     uint32(port),
+			Protocol: healthcheck.HTTP,
+		})
+	if err != nil {
+		t.Fatalf("Error creating the http exporter :\n%v", err)
+	}
+	err = exporter.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the http exporter:\n%v", err)
+	}
+	err = exporter.Push(&healthcheck.Result{
+		Name:     "foo",
+		Success:  true,
+		Metadata: map[string]string{healthcheck.CorrelationIDMetadata: "abc"},
+	})
+	if err != nil {
+		t.Fatalf("Fail to push healthcheck result:\n%v", err)
+	}
+	if header != "abc" {
+		t.Fatalf("Invalid correlation ID header %s", header)
+	}
+	err = exporter.Push(&healthcheck.Result{Name: "foo", Success: true})
+	if err != nil {
+		t.Fatalf("Fail to push healthcheck result:\n%v", err)
+	}
+	if header != "" {
+		t.Fatalf("The correlation ID header should not be sent")
+	}
+	err = exporter.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the http exporter:\n%v", err)
+	}
+}
+
+func TestHTTPExporterLimits(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(500 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte(strings.Repeat("a", 100)))
+		if err != nil {
+			t.Fatalf("Error writing :\n%v", err)
+		}
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("Error getting HTTP server port :\n%v", err)
+	}
+	cases := []struct {
+		config   HTTPConfiguration
+		expected string
+	}{
+		{
+			config:   HTTPConfiguration{Path: "/", MaxBodySize: 10},
+			expected: "exceeds the limit of 10 bytes",
+		},
+		{
+			config:   HTTPConfiguration{Path: "/slow", Timeout: healthcheck.Duration(100 * time.Millisecond)},
+			expected: "Timeout",
+		},
+		{
+			config: HTTPConfiguration{Path: "/", MaxBodySize: 100},
+		},
+	}
+	for _, c := range cases {
+		config := c.config
+		config.Host = "127.0.0.1"
+		config.Port = uint32(port)
+		config.Protocol = healthcheck.HTTP
+		exporter, err := NewHTTPExporter(zap.NewExample(), &config)
+		if err != nil {
+			t.Fatalf("Error creating the http exporter :\n%v", err)
+		}
+		err = exporter.Push(&healthcheck.Result{Name: "foo"})
+		if c.expected == "" && err != nil {
+			t.Fatalf("Fail to push healthcheck result:\n%v", err)
+		}
+		if c.expected != "" && (err == nil || !strings.Contains(err.Error(), c.expected)) {
+			t.Fatalf("Was expecting an error containing %s: %v", c.expected, err)
+		}
+	}
+}
+
+func TestHTTPExporterEndpoints(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	endpoints := []HTTPEndpoint{}
+	for _, ts := range []*httptest.Server{ok, failing} {
+		port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+		if err != nil {
+			t.Fatalf("Error getting HTTP server port :\n%v", err)
+		}
+		endpoints = append(endpoints, HTTPEndpoint{Host: "127.0.0.1", Port: uint32(port)})
+	}
+	endpoints[1].Path = "/failing"
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(
+		logger,
+		memorystore.NewMemoryStore(logger),
+		make(chan *healthcheck.Result, 10),
+		prom,
+		&Configuration{
+			HTTP: []HTTPConfiguration{
+				{
+					Name:      "foo",
+					Path:      "/results",
+					Protocol:  healthcheck.HTTP,
+					Endpoints: endpoints,
+				},
+			}})
+	if err != nil {
+		t.Fatalf("Error creating the component :\n%v", err)
+	}
+	if len(component.Exporters) != 2 {
+		t.Fatalf("Was expecting an exporter per endpoint: %v", component.Exporters)
+	}
+	okExporter := component.Exporters[fmt.Sprintf("foo-127.0.0.1-%d", endpoints[0].Port)]
+	failingExporter := component.Exporters[fmt.Sprintf("foo-127.0.0.1-%d", endpoints[1].Port)]
+	if okExporter == nil || failingExporter == nil {
+		t.Fatalf("Invalid exporters names: %v", component.Exporters)
+	}
+	if okExporter.(*HTTPExporter).URL != fmt.Sprintf("http://127.0.0.1:%d/results", endpoints[0].Port) ||
+		failingExporter.(*HTTPExporter).URL != fmt.Sprintf("http://127.0.0.1:%d/failing", endpoints[1].Port) {
+		t.Fatalf("Invalid exporters URLs")
+	}
+	for _, exporter := range component.Exporters {
+		if err := exporter.Start(); err != nil {
+			t.Fatalf("Fail to start the exporter: %v", err)
+		}
+		component.push(exporter, &healthcheck.Result{Name: "foo"})
+	}
+	if !okExporter.IsStarted() || failingExporter.IsStarted() {
+		t.Fatalf("Only the failing endpoint should be stopped")
+	}
+}
+
+func TestHTTPExporterEndpointsReferences(t *testing.T) {
+	httpConfigs := []HTTPConfiguration{
+		{Name: "foo", Endpoints: []HTTPEndpoint{{Host: "127.0.0.1", Port: 2000}}},
+		{Name: "bar", Host: "127.0.0.1", Port: 2001},
+	}
+	valid := []Configuration{
+		{HTTP: httpConfigs, Groups: []GroupConfiguration{{Name: "g", Primary: "bar", Fallback: "foo-127.0.0.1-2000"}}},
+		{HTTP: httpConfigs, Aggregates: []AggregateConfiguration{{Name: "a", Exporter: "foo-127.0.0.1-2000"}}},
+	}
+	for _, c := range valid {
+		c := c
+		if err := validateEndpointsReferences(&c); err != nil {
+			t.Fatalf("Invalid configuration: %v", err)
+		}
+	}
+	invalid := []Configuration{
+		{HTTP: httpConfigs, Groups: []GroupConfiguration{{Name: "g", Primary: "bar", Fallback: "foo"}}},
+		{HTTP: httpConfigs, Groups: []GroupConfiguration{{Name: "g", Primary: "foo", Fallback: "bar"}}},
+		{HTTP: httpConfigs, Aggregates: []AggregateConfiguration{{Name: "a", Exporter: "foo"}}},
+	}
+	for _, c := range invalid {
+		c := c
+		if err := validateEndpointsReferences(&c); err == nil {
+			t.Fatalf("Was expecting an error for %v", c)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+	"github.com/mcorbin/cabourotte/kafka"
+	"github.com/mcorbin/cabourotte/tls"
+)
+
+// DefaultKafkaTimeout the default timeout of the Kafka exporter
+const DefaultKafkaTimeout = 5 * time.Second
+
+// KafkaRoute a routing rule of the Kafka exporter. All the conditions of the
+// rule should match the result.
+type KafkaRoute struct {
+	// the healthchecks names matching this regexp
+	Name *healthcheck.Regexp `json:"name,omitempty" yaml:"name,omitempty"`
+	// the labels of the result should have these values
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	// success or failure, any status if empty
+	Status string `json:"status,omitempty" yaml:"status,omitempty"`
+	Topic  string `json:"topic"`
+}
+
+// KafkaConfiguration the Kafka exporter configuration
+type KafkaConfiguration struct {
+	Name string
+	// the bootstrap brokers, as host:port
+	Brokers []string
+	// the topics the exporter produces to, they should exist in the cluster
+	Topics []string
+	// the topic of the results matching no route
+	DefaultTopic string `json:"default-topic" yaml:"default-topic"`
+	// the routing rules, the topic of the first matching rule is used
+	Routes   []KafkaRoute `json:"routes,omitempty" yaml:"routes,omitempty"`
+	ClientID string       `json:"client-id,omitempty" yaml:"client-id,omitempty"`
+	// waits for the leader acknowledgement only, instead of all the in-sync
+	// replicas
+	LeaderAck bool `json:"leader-ack" yaml:"leader-ack"`
+	// TLS is also enabled if a certificate is configured
+	TLS      bool   `json:"tls"`
+	Key      string `json:"key,omitempty"`
+	Cert     string `json:"cert,omitempty"`
+	Cacert   string `json:"cacert,omitempty"`
+	Insecure bool
+	Timeout  healthcheck.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// the maximum duration of the TLS handshake, the timeout is used if
+	// not set
+	HandshakeTimeout healthcheck.Duration `json:"handshake-timeout,omitempty" yaml:"handshake-timeout,omitempty"`
+	// the delivery of the results, asynchronous by default
+	Delivery `json:",inline" yaml:",inline"`
+	// the serialized result fields, all by default
+	Projection *Projection `json:"projection,omitempty" yaml:"projection,omitempty"`
+}
+
+// KafkaExporter the Kafka exporter struct
+type KafkaExporter struct {
+	startedState
+	Logger  *zap.Logger
+	Config  *KafkaConfiguration
+	Client  *kafka.Client
+	options kafka.Options
+	counter *prom.CounterVec
+}
+
+// topicNames returns the set of the configured topics
+func (c *KafkaConfiguration) topicNames() map[string]bool {
+	topics := make(map[string]bool, len(c.Topics))
+	for _, topic := range c.Topics {
+		topics[topic] = true
+	}
+	return topics
+}
+
+// UnmarshalYAML parses the configuration of the Kafka exporter from YAML.
+func (c *KafkaConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration KafkaConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read Kafka exporter configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid name for the Kafka exporter configuration")
+	}
+	if len(raw.Brokers) == 0 {
+		return errors.New("The Kafka exporter brokers are missing")
+	}
+	if len(raw.Topics) == 0 {
+		return errors.New("The Kafka exporter topics are missing")
+	}
+	config := KafkaConfiguration(raw)
+	topics := config.topicNames()
+	for _, topic := range raw.Topics {
+		if topic == "" || strings.ContainsAny(topic, "/ ") {
+			return fmt.Errorf("Invalid topic '%s' for the Kafka exporter", topic)
+		}
+	}
+	if len(topics) != len(raw.Topics) {
+		return errors.New("The Kafka exporter topics should be unique")
+	}
+	// the default topic gives a destination to all the results
+	if raw.DefaultTopic == "" {
+		return errors.New("The Kafka exporter default-topic is missing")
+	}
+	if !topics[raw.DefaultTopic] {
+		return fmt.Errorf("The Kafka exporter default-topic %s is not in the configured topics", raw.DefaultTopic)
+	}
+	for i, route := range raw.Routes {
+		if !topics[route.Topic] {
+			return fmt.Errorf("The topic '%s' of the Kafka exporter route %d is not in the configured topics", route.Topic, i)
+		}
+		if route.Name == nil && len(route.Labels) == 0 && route.Status == "" {
+			return fmt.Errorf("The Kafka exporter route %d has no condition, use the default-topic instead", i)
+		}
+		if route.Status != "" && route.Status != "success" && route.Status != "failure" {
+			return fmt.Errorf("Invalid status %s for the Kafka exporter route %d, success or failure are expected", route.Status, i)
+		}
+	}
+	if !((raw.Key != "" && raw.Cert != "") ||
+		(raw.Key == "" && raw.Cert == "")) {
+		return errors.New("Invalid certificates")
+	}
+	if raw.Timeout < 0 {
+		return errors.New("The Kafka exporter timeout should be positive")
+	}
+	if raw.HandshakeTimeout < 0 {
+		return errors.New("The Kafka exporter handshake-timeout should be positive")
+	}
+	if raw.Projection != nil {
+		if err := raw.Projection.Validate(); err != nil {
+			return errors.Wrap(err, "Invalid projection for the Kafka exporter")
+		}
+	}
+	*c = config
+	return nil
+}
+
+// NewKafkaExporter creates a new Kafka exporter
+func NewKafkaExporter(logger *zap.Logger, config *KafkaConfiguration, counter *prom.CounterVec) (*KafkaExporter, error) {
+	timeout := time.Duration(config.Timeout)
+	if timeout == 0 {
+		timeout = DefaultKafkaTimeout
+	}
+	clientID := config.ClientID
+	if clientID == "" {
+		clientID = fmt.Sprintf("cabourotte-%s", config.Name)
+	}
+	options := kafka.Options{
+		Brokers:          config.Brokers,
+		ClientID:         clientID,
+		Timeout:          timeout,
+		HandshakeTimeout: time.Duration(config.HandshakeTimeout),
+		Acks:             -1,
+	}
+	if config.LeaderAck {
+		options.Acks = 1
+	}
+	if config.TLS || config.Key != "" || config.Cacert != "" {
+		tlsConfig, err := tls.GetTLSConfig(config.Key, config.Cert, config.Cacert, config.Insecure)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to build the Kafka exporter tls configuration")
+		}
+		options.TLSConfig = tlsConfig
+	}
+	return &KafkaExporter{
+		Logger:  logger,
+		Config:  config,
+		options: options,
+		counter: counter,
+	}, nil
+}
+
+// IsStarted returns the exporter status
+func (c *KafkaExporter) IsStarted() bool {
+	return c.started() && c.Client != nil
+}
+
+// Start starts the Kafka exporter component. The configured topics should
+// exist in the cluster.
+func (c *KafkaExporter) Start() error {
+	c.Logger.Info(fmt.Sprintf("Starting the Kafka exporter %s", c.Config.Name))
+	client, err := kafka.Connect(c.options, c.Config.Topics)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to start the Kafka exporter %s", c.Config.Name)
+	}
+	c.Client = client
+	c.setStarted(true)
+	return nil
+}
+
+// Reconnect reconnects the Kafka exporter component
+func (c *KafkaExporter) Reconnect() error {
+	c.Logger.Info(fmt.Sprintf("Reconnecting the Kafka exporter %s", c.Config.Name))
+	if c.Client != nil {
+		// nolint
+		c.Client.Close()
+	}
+	return c.Start()
+}
+
+// Stop stops the Kafka exporter component
+func (c *KafkaExporter) Stop() error {
+	c.Logger.Info(fmt.Sprintf("Stopping the Kafka exporter %s", c.Config.Name))
+	c.setStarted(false)
+	if c.Client == nil {
+		return nil
+	}
+	err := c.Client.Close()
+	c.Client = nil
+	if err != nil {
+		return errors.Wrapf(err, "Fail to close the Kafka connections")
+	}
+	return nil
+}
+
+// Name returns the name of the exporter
+func (c *KafkaExporter) Name() string {
+	return c.Config.Name
+}
+
+// GetConfig returns the config of the exporter
+func (c *KafkaExporter) GetConfig() interface{} {
+	return c.Config
+}
+
+// match returns true if the result matches all the conditions of the route
+func (r *KafkaRoute) match(result *healthcheck.Result) bool {
+	if r.Name != nil {
+		reg := regexp.Regexp(*r.Name)
+		if !reg.MatchString(result.Name) {
+			return false
+		}
+	}
+	for k, v := range r.Labels {
+		if value, ok := result.Labels[k]; !ok || value != v {
+			return false
+		}
+	}
+	if r.Status == "success" && !result.Success {
+		return false
+	}
+	if r.Status == "failure" && result.Success {
+		return false
+	}
+	return true
+}
+
+// topic returns the topic of a result: the topic of the first matching
+// route, or the default topic
+func (c *KafkaExporter) topic(result *healthcheck.Result) string {
+	for i := range c.Config.Routes {
+		if c.Config.Routes[i].match(result) {
+			return c.Config.Routes[i].Topic
+		}
+	}
+	return c.Config.DefaultTopic
+}
+
+// Push produces the result to its topic. The healthcheck name is the record
+// key, to keep the results of an healthcheck ordered.
+func (c *KafkaExporter) Push(result *healthcheck.Result) error {
+	if c.Client == nil {
+		return errors.New("Kafka exporter: not connected")
+	}
+	topic := c.topic(result)
+	projected, err := c.Config.Projection.project(result)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(projected)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to convert result to json:\n%v", result)
+	}
+	err = c.Client.Produce(topic, []byte(result.Name), payload, nil)
+	if err != nil {
+		c.counter.WithLabelValues(c.Config.Name, topic, "failure").Inc()
+		return errors.Wrapf(err, "Kafka exporter: fail to produce to %s", topic)
+	}
+	c.counter.WithLabelValues(c.Config.Name, topic, "success").Inc()
+	return nil
+}
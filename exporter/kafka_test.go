@@ -0,0 +1,74 @@
+package exporter
+
+import (
+	"testing"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+func TestKafkaRouting(t *testing.T) {
+	var config KafkaConfiguration
+	err := yaml.Unmarshal([]byte(`
+name: kafka
+brokers: [127.0.0.1:9092]
+topics: [results, team-a, alerts]
+default-topic: results
+routes:
+  - status: failure
+    labels:
+      severity: critical
+    topic: alerts
+  - name: "^api-"
+    topic: team-a
+  - labels:
+      team: a
+    topic: team-a
+`), &config)
+	if err != nil {
+		t.Fatalf("Fail to parse the configuration\n%v", err)
+	}
+	counter := prom.NewCounterVec(prom.CounterOpts{Name: "test"}, []string{"name", "topic", "status"})
+	exporter, err := NewKafkaExporter(zap.NewExample(), &config, counter)
+	if err != nil {
+		t.Fatalf("Fail to create the exporter\n%v", err)
+	}
+	cases := []struct {
+		result healthcheck.Result
+		topic  string
+	}{
+		{result: healthcheck.Result{Name: "db", Success: true}, topic: "results"},
+		{result: healthcheck.Result{Name: "api-users", Success: true}, topic: "team-a"},
+		{result: healthcheck.Result{Name: "db", Labels: map[string]string{"team": "a"}}, topic: "team-a"},
+		{result: healthcheck.Result{Name: "api-users", Labels: map[string]string{"severity": "critical"}}, topic: "alerts"},
+		{result: healthcheck.Result{Name: "db", Success: true, Labels: map[string]string{"severity": "critical"}}, topic: "results"},
+	}
+	for i, c := range cases {
+		topic := exporter.topic(&c.result)
+		if topic != c.topic {
+			t.Fatalf("Invalid topic %s for the result %d, expected %s", topic, i, c.topic)
+		}
+	}
+}
+
+func TestKafkaConfiguration(t *testing.T) {
+	invalid := []string{
+		"name: kafka\nbrokers: [127.0.0.1:9092]\ntopics: [results]\n",
+		"name: kafka\nbrokers: [127.0.0.1:9092]\ntopics: [results]\ndefault-topic: other\n",
+		"name: kafka\ntopics: [results]\ndefault-topic: results\n",
+		"name: kafka\nbrokers: [127.0.0.1:9092]\ntopics: [results, results]\ndefault-topic: results\n",
+		"name: kafka\nbrokers: [127.0.0.1:9092]\ntopics: [results]\ndefault-topic: results\nroutes:\n  - status: failure\n    topic: alerts\n",
+		"name: kafka\nbrokers: [127.0.0.1:9092]\ntopics: [results]\ndefault-topic: results\nroutes:\n  - topic: results\n",
+		"name: kafka\nbrokers: [127.0.0.1:9092]\ntopics: [results]\ndefault-topic: results\nroutes:\n  - status: down\n    topic: results\n",
+	}
+	for _, c := range invalid {
+		var config KafkaConfiguration
+		err := yaml.Unmarshal([]byte(c), &config)
+		if err == nil {
+			t.Fatalf("Was expected an error when decoding the configuration: \n%s", c)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+	"github.com/mcorbin/cabourotte/mqtt"
+	"github.com/mcorbin/cabourotte/tls"
+)
+
+// DefaultMQTTTopic the default topic template of the MQTT exporter
+const DefaultMQTTTopic = "cabourotte/{{ .Name }}"
+
+// DefaultMQTTTimeout the default timeout of the MQTT exporter
+const DefaultMQTTTimeout = 5 * time.Second
+
+// MQTTConfiguration the MQTT exporter configuration
+type MQTTConfiguration struct {
+	Name string
+	Host string
+	Port uint32
+	// a template of the topic, executed with the result
+	Topic string
+	// the QoS of the published messages (0, 1 or 2)
+	QoS uint8 `json:"qos" yaml:"qos"`
+	// the messages are retained by the broker, to keep the last known state
+	Retain   bool
+	ClientID string `json:"client-id,omitempty" yaml:"client-id,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// TLS is also enabled if a certificate is configured
+	TLS      bool   `json:"tls"`
+	Key      string `json:"key,omitempty"`
+	Cert     string `json:"cert,omitempty"`
+	Cacert   string `json:"cacert,omitempty"`
+	Insecure bool
+	Timeout  healthcheck.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// the maximum duration of the TLS handshake, the timeout is used if
+	// not set
+	HandshakeTimeout healthcheck.Duration `json:"handshake-timeout,omitempty" yaml:"handshake-timeout,omitempty"`
+	// the delivery of the results, asynchronous by default
+	Delivery `json:",inline" yaml:",inline"`
+	// the serialized result fields, all by default
+	Projection *Projection `json:"projection,omitempty" yaml:"projection,omitempty"`
+}
+
+// MQTTExporter the MQTT exporter struct
+type MQTTExporter struct {
+	startedState
+	Logger   *zap.Logger
+	Config   *MQTTConfiguration
+	Client   *mqtt.Client
+	options  mqtt.Options
+	template *template.Template
+}
+
+// UnmarshalYAML parses the configuration of the MQTT exporter from YAML.
+func (c *MQTTConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration MQTTConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read MQTT exporter configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid name for the MQTT exporter configuration")
+	}
+	if raw.Host == "" {
+		return errors.New("Invalid host for the MQTT exporter configuration")
+	}
+	if raw.Port == 0 {
+		return errors.New("Invalid port for the MQTT exporter configuration")
+	}
+	if raw.QoS > 2 {
+		return errors.New("The MQTT exporter QoS should be 0, 1 or 2")
+	}
+	if raw.Topic == "" {
+		raw.Topic = DefaultMQTTTopic
+	}
+	if _, err := template.New("topic").Parse(raw.Topic); err != nil {
+		return errors.Wrap(err, "Invalid topic for the MQTT exporter configuration")
+	}
+	if raw.Password != "" && raw.Username == "" {
+		return errors.New("The MQTT exporter password requires a username")
+	}
+	if !((raw.Key != "" && raw.Cert != "") ||
+		(raw.Key == "" && raw.Cert == "")) {
+		return errors.New("Invalid certificates")
+	}
+	if raw.Timeout < 0 {
+		return errors.New("The MQTT exporter timeout should be positive")
+	}
+	if raw.HandshakeTimeout < 0 {
+		return errors.New("The MQTT exporter handshake-timeout should be positive")
+	}
+	if raw.Projection != nil {
+		if err := raw.Projection.Validate(); err != nil {
+			return errors.Wrap(err, "Invalid projection for the MQTT exporter")
+		}
+	}
+	*c = MQTTConfiguration(raw)
+	return nil
+}
+
+// NewMQTTExporter creates a new MQTT exporter
+func NewMQTTExporter(logger *zap.Logger, config *MQTTConfiguration) (*MQTTExporter, error) {
+	topic := config.Topic
+	if topic == "" {
+		topic = DefaultMQTTTopic
+	}
+	tmpl, err := template.New("topic").Option("missingkey=zero").Parse(topic)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid topic for the MQTT exporter")
+	}
+	timeout := time.Duration(config.Timeout)
+	if timeout == 0 {
+		timeout = DefaultMQTTTimeout
+	}
+	clientID := config.ClientID
+	if clientID == "" {
+		clientID = fmt.Sprintf("cabourotte-%s", config.Name)
+	}
+	options := mqtt.Options{
+		Address:          net.JoinHostPort(config.Host, fmt.Sprintf("%d", config.Port)),
+		ClientID:         clientID,
+		Username:         config.Username,
+		Password:         config.Password,
+		KeepAlive:        30 * time.Second,
+		Timeout:          timeout,
+		HandshakeTimeout: time.Duration(config.HandshakeTimeout),
+	}
+	if config.TLS || config.Key != "" || config.Cacert != "" {
+		tlsConfig, err := tls.GetTLSConfig(config.Key, config.Cert, config.Cacert, config.Insecure)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to build the MQTT exporter tls configuration")
+		}
+		options.TLSConfig = tlsConfig
+	}
+	return &MQTTExporter{
+		Logger:   logger,
+		Config:   config,
+		options:  options,
+		template: tmpl,
+	}, nil
+}
+
+// IsStarted returns the exporter status
+func (c *MQTTExporter) IsStarted() bool {
+	return c.started() && c.Client != nil && c.Client.Connected()
+}
+
+// Start starts the MQTT exporter component
+func (c *MQTTExporter) Start() error {
+	c.Logger.Info(fmt.Sprintf("Starting the MQTT exporter %s", c.Config.Name))
+	client, err := mqtt.Connect(c.options)
+	if err != nil {
+		return err
+	}
+	c.Client = client
+	c.setStarted(true)
+	return nil
+}
+
+// Reconnect reconnects the MQTT exporter component
+func (c *MQTTExporter) Reconnect() error {
+	c.Logger.Info(fmt.Sprintf("Reconnecting the MQTT exporter %s", c.Config.Name))
+	if c.Client != nil {
+		// nolint
+		c.Client.Disconnect()
+	}
+	return c.Start()
+}
+
+// Stop stops the MQTT exporter component
+func (c *MQTTExporter) Stop() error {
+	c.Logger.Info(fmt.Sprintf("Stopping the MQTT exporter %s", c.Config.Name))
+	c.setStarted(false)
+	if c.Client == nil {
+		return nil
+	}
+	err := c.Client.Disconnect()
+	c.Client = nil
+	if err != nil {
+		return errors.Wrapf(err, "Fail to disconnect from the MQTT broker")
+	}
+	return nil
+}
+
+// Name returns the name of the exporter
+func (c *MQTTExporter) Name() string {
+	return c.Config.Name
+}
+
+// GetConfig returns the config of the exporter
+func (c *MQTTExporter) GetConfig() interface{} {
+	return c.Config
+}
+
+// topic returns the topic of a result
+func (c *MQTTExporter) topic(result *healthcheck.Result) (string, error) {
+	var buffer bytes.Buffer
+	if err := c.template.Execute(&buffer, result); err != nil {
+		return "", errors.Wrap(err, "Fail to build the MQTT topic")
+	}
+	topic := buffer.String()
+	if topic == "" || strings.ContainsAny(topic, "+#") {
+		return "", fmt.Errorf("Invalid MQTT topic '%s' for the healthcheck %s", topic, result.Name)
+	}
+	return topic, nil
+}
+
+// Push publishes the result to the MQTT broker
+func (c *MQTTExporter) Push(result *healthcheck.Result) error {
+	if c.Client == nil {
+		return errors.New("MQTT exporter: not connected")
+	}
+	topic, err := c.topic(result)
+	if err != nil {
+		return err
+	}
+	projected, err := c.Config.Projection.project(result)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(projected)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to convert result to json:\n%v", result)
+	}
+	err = c.Client.Publish(topic, payload, c.Config.QoS, c.Config.Retain)
+	if err != nil {
+		return errors.Wrapf(err, "MQTT exporter: fail to publish on %s", topic)
+	}
+	return nil
+}
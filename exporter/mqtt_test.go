@@ -0,0 +1,55 @@
+package exporter
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+func TestMQTTExporterConfiguration(t *testing.T) {
+	var config MQTTConfiguration
+	err := yaml.Unmarshal([]byte(`
+name: edge
+host: broker.example.com
+port: 8883
+topic: 'sites/{{ index .Labels "site" }}/{{ .Name }}'
+qos: 1
+retain: true
+tls: true
+`), &config)
+	if err != nil {
+		t.Fatalf("Fail to unmarshal the configuration: %v", err)
+	}
+	exporter, err := NewMQTTExporter(zap.NewExample(), &config)
+	if err != nil {
+		t.Fatalf("Fail to create the exporter: %v", err)
+	}
+	if exporter.options.TLSConfig == nil || exporter.options.ClientID != "cabourotte-edge" {
+		t.Fatalf("Invalid options %v", exporter.options)
+	}
+	topic, err := exporter.topic(&healthcheck.Result{Name: "foo", Labels: map[string]string{"site": "paris"}})
+	if err != nil {
+		t.Fatalf("Fail to build the topic: %v", err)
+	}
+	if topic != "sites/paris/foo" {
+		t.Fatalf("Invalid topic %s", topic)
+	}
+	_, err = exporter.topic(&healthcheck.Result{Name: "foo+bar", Labels: map[string]string{"site": "paris"}})
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	invalid := []string{
+		"name: edge\nport: 1883\n",
+		"name: edge\nhost: localhost\nport: 1883\nqos: 3\n",
+		"name: edge\nhost: localhost\nport: 1883\ntopic: '{{ .Name'\n",
+		"name: edge\nhost: localhost\nport: 1883\npassword: foo\n",
+	}
+	for _, c := range invalid {
+		if err := yaml.Unmarshal([]byte(c), &MQTTConfiguration{}); err == nil {
+			t.Fatalf("Was expecting an error for %s", c)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package exporter
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/expfmt"
+	"go.uber.org/zap"
+	"gopkg.in/tomb.v2"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+	"github.com/mcorbin/cabourotte/prometheus"
+	"github.com/mcorbin/cabourotte/tls"
+)
+
+const (
+	// FormatOpenMetrics the OpenMetrics text format
+	FormatOpenMetrics = "openmetrics"
+	// FormatText the Prometheus text format
+	FormatText = "text"
+	// DefaultOpenMetricsInterval the default interval between two pushes
+	DefaultOpenMetricsInterval = healthcheck.Duration(15 * time.Second)
+)
+
+// OpenMetricsConfiguration the configuration for the OpenMetrics exporter,
+// which periodically pushes the Prometheus metrics to an endpoint.
+type OpenMetricsConfiguration struct {
+	Name     string
+	URL      string
+	Interval healthcheck.Duration
+	Format   string
+	Username string
+	Password string
+	Key      string `json:"key,omitempty"`
+	Cert     string `json:"cert,omitempty"`
+	Cacert   string `json:"cacert,omitempty"`
+	Insecure bool
+	// the delivery of the results, asynchronous by default
+	Delivery `json:",inline" yaml:",inline"`
+}
+
+// OpenMetricsExporter the OpenMetrics exporter struct
+type OpenMetricsExporter struct {
+	startedState
+	Logger     *zap.Logger
+	Config     *OpenMetricsConfiguration
+	Client     *http.Client
+	prometheus *prometheus.Prometheus
+	t          *tomb.Tomb
+}
+
+// UnmarshalYAML parses the configuration of the OpenMetrics exporter from YAML.
+func (c *OpenMetricsConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration OpenMetricsConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read OpenMetrics exporter configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid name for the OpenMetrics exporter configuration")
+	}
+	endpoint, err := url.Parse(raw.URL)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return errors.New("Invalid URL for the OpenMetrics exporter configuration")
+	}
+	if raw.Interval == 0 {
+		raw.Interval = DefaultOpenMetricsInterval
+	}
+	if raw.Interval < healthcheck.Duration(time.Second) {
+		return errors.New("The OpenMetrics exporter interval should be greater than 1 second")
+	}
+	if raw.Format == "" {
+		raw.Format = FormatOpenMetrics
+	}
+	if raw.Format != FormatOpenMetrics && raw.Format != FormatText {
+		return fmt.Errorf("Invalid format %s for the OpenMetrics exporter configuration", raw.Format)
+	}
+	if (raw.Username == "" && raw.Password != "") ||
+		(raw.Username != "" && raw.Password == "") {
+		return errors.New("Invalid Basic Auth configuration")
+	}
+	if !((raw.Key != "" && raw.Cert != "") ||
+		(raw.Key == "" && raw.Cert == "")) {
+		return errors.New("Invalid certificates")
+	}
+	*c = OpenMetricsConfiguration(raw)
+	return nil
+}
+
+// NewOpenMetricsExporter creates a new OpenMetrics exporter. The metrics are
+// gathered from the registry of the Prometheus component.
+func NewOpenMetricsExporter(logger *zap.Logger, config *OpenMetricsConfiguration, promComponent *prometheus.Prometheus) (*OpenMetricsExporter, error) {
+	tlsConfig, err := tls.GetTLSConfig(config.Key, config.Cert, config.Cacert, config.Insecure)
+	if err != nil {
+		return nil, err
+	}
+	return &OpenMetricsExporter{
+		Logger:     logger,
+		Config:     config,
+		prometheus: promComponent,
+		Client: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+			},
+			Timeout: time.Second * 5,
+		},
+	}, nil
+}
+
+// IsStarted returns the exporter status
+func (c *OpenMetricsExporter) IsStarted() bool {
+	return c.started()
+}
+
+// Start starts pushing the metrics on the configured interval
+func (c *OpenMetricsExporter) Start() error {
+	c.Logger.Info(fmt.Sprintf("Starting the OpenMetrics exporter %s", c.Config.Name))
+	interval := c.Config.Interval
+	if interval == 0 {
+		interval = DefaultOpenMetricsInterval
+	}
+	ticker := time.NewTicker(time.Duration(interval))
+	c.t = &tomb.Tomb{}
+	t := c.t
+	t.Go(func() error {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				err := c.send()
+				if err != nil {
+					c.Logger.Error(err.Error(), zap.String("exporter", c.Config.Name))
+				}
+			case <-t.Dying():
+				return nil
+			}
+		}
+	})
+	c.setStarted(true)
+	return nil
+}
+
+// Reconnect restarts the exporter if needed
+func (c *OpenMetricsExporter) Reconnect() error {
+	if c.started() {
+		return nil
+	}
+	return c.Start()
+}
+
+// Stop stops the OpenMetrics exporter
+func (c *OpenMetricsExporter) Stop() error {
+	c.Logger.Info(fmt.Sprintf("Stopping the OpenMetrics exporter %s", c.Config.Name))
+	c.setStarted(false)
+	if c.t == nil {
+		return nil
+	}
+	c.t.Kill(nil)
+	err := c.t.Wait()
+	c.t = nil
+	return err
+}
+
+// Name returns the name of the exporter
+func (c *OpenMetricsExporter) Name() string {
+	return c.Config.Name
+}
+
+// GetConfig returns the config of the exporter
+func (c *OpenMetricsExporter) GetConfig() interface{} {
+	return c.Config
+}
+
+// Push does nothing: the results are already in the Prometheus registry, and
+// are pushed on the configured interval.
+func (c *OpenMetricsExporter) Push(result *healthcheck.Result) error {
+	return nil
+}
+
+// render serializes the registry. Like the scrape endpoint, no timestamps
+// are added to the samples.
+func (c *OpenMetricsExporter) render() ([]byte, expfmt.Format, error) {
+	families, err := c.prometheus.Registry.Gather()
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "OpenMetrics exporter: fail to gather the metrics")
+	}
+	format := expfmt.FmtText
+	if c.Config.Format != FormatText {
+		format = expfmt.FmtOpenMetrics
+	}
+	var buffer bytes.Buffer
+	encoder := expfmt.NewEncoder(&buffer, format)
+	for _, family := range families {
+		err := encoder.Encode(family)
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "OpenMetrics exporter: fail to encode the metric %s", family.GetName())
+		}
+	}
+	if closer, ok := encoder.(expfmt.Closer); ok {
+		err := closer.Close()
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "OpenMetrics exporter: fail to encode the metrics")
+		}
+	}
+	return buffer.Bytes(), format, nil
+}
+
+// send pushes the metrics to the endpoint
+func (c *OpenMetricsExporter) send() error {
+	payload, format, err := c.render()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", c.Config.URL, bytes.NewBuffer(payload))
+	if err != nil {
+		return errors.Wrapf(err, "OpenMetrics exporter: fail to create request for %s", c.Config.URL)
+	}
+	req.Header.Set("Content-Type", string(format))
+	if c.Config.Username != "" {
+		req.SetBasicAuth(c.Config.Username, c.Config.Password)
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "OpenMetrics exporter: fail to send the metrics to %s", c.Config.URL)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("OpenMetrics exporter: request failed, status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package exporter
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+	"github.com/mcorbin/cabourotte/prometheus"
+)
+
+func TestOpenMetricsExporter(t *testing.T) {
+	payloads := make(chan string, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "foo" || password != "bar" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/openmetrics-text") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Fail to read the body: %v", err)
+		}
+		payloads <- string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	promComponent, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	counter := prom.NewCounterVec(prom.CounterOpts{
+		Name: "test_total",
+		Help: "test counter",
+	}, []string{"name"})
+	err = promComponent.Register(counter)
+	if err != nil {
+		t.Fatalf("Fail to register the counter:\n%v", err)
+	}
+	counter.With(prom.Labels{"name": "foo"}).Inc()
+	exporter, err := NewOpenMetricsExporter(
+		zap.NewExample(),
+		&OpenMetricsConfiguration{
+			Name:     "openmetrics",
+			URL:      ts.URL,
+			Interval: healthcheck.Duration(100 * time.Millisecond),
+			Format:   FormatOpenMetrics,
+			Username: "foo",
+			Password: "bar",
+		},
+		promComponent)
+	if err != nil {
+		t.Fatalf("Error creating the exporter :\n%v", err)
+	}
+	err = exporter.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the exporter:\n%v", err)
+	}
+	var payload string
+	select {
+	case payload = <-payloads:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("No metrics received")
+	}
+	err = exporter.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the exporter:\n%v", err)
+	}
+	if !strings.Contains(payload, `test_total{name="foo"} 1`) {
+		t.Fatalf("Invalid payload %s", payload)
+	}
+	if !strings.HasSuffix(payload, "# EOF\n") {
+		t.Fatalf("The payload should be terminated %s", payload)
+	}
+}
@@ -0,0 +1,158 @@
+package exporter
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// concurrentExporter is implemented by the exporters supporting concurrent
+// pushes
+type concurrentExporter interface {
+	maxConcurrentPushes() uint
+}
+
+// pushPool a worker pool pushing the results of an asynchronous exporter. The
+// queue is bounded: the results fan-out blocks when it is full.
+type pushPool struct {
+	exporter Exporter
+	workers  int
+	queue    chan *healthcheck.Result
+	inFlight prom.Gauge
+	// protects the exporter state (started, stopped)
+	lock sync.Mutex
+	wg   sync.WaitGroup
+	// receives the flush requests, the number of drained results is sent
+	// back on the channel. The queue is drained by a worker so the pushes
+	// stay serialized.
+	flushRequests chan chan int
+	// closed once the workers are stopped
+	stopped chan struct{}
+}
+
+// newPushPools creates the pools of the asynchronous exporters, skipping the
+// exporters pushed by the groups and the aggregates. The exporters configured
+// with concurrent pushes have a worker and a queue slot per concurrent push,
+// the others a single worker.
+func newPushPools(exporters map[string]Exporter, skipped map[string]bool, inFlight *prom.GaugeVec) map[string]*pushPool {
+	pools := make(map[string]*pushPool)
+	for name, exporter := range exporters {
+		if skipped[name] || isSync(exporter) {
+			continue
+		}
+		workers := 1
+		size := DefaultAsyncQueueSize
+		if concurrent, ok := exporter.(concurrentExporter); ok && concurrent.maxConcurrentPushes() > 1 {
+			workers = int(concurrent.maxConcurrentPushes())
+			size = workers
+		}
+		pools[name] = &pushPool{
+			exporter:      exporter,
+			workers:       workers,
+			queue:         make(chan *healthcheck.Result, size),
+			inFlight:      inFlight.With(prom.Labels{"name": name}),
+			flushRequests: make(chan chan int),
+			stopped:       make(chan struct{}),
+		}
+	}
+	return pools
+}
+
+// startPool starts the workers of a pool
+func (c *Component) startPool(pool *pushPool) {
+	workers := pool.workers
+	pool.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer pool.wg.Done()
+			for {
+				select {
+				case message, ok := <-pool.queue:
+					if !ok {
+						return
+					}
+					c.poolPush(pool, message)
+				case done := <-pool.flushRequests:
+					done <- c.drainPool(pool)
+				}
+			}
+		}()
+	}
+}
+
+// stopPool waits for the pool workers to push the queued results
+func stopPool(pool *pushPool) {
+	close(pool.queue)
+	pool.wg.Wait()
+	close(pool.stopped)
+}
+
+// flushPool asks a worker of the pool to push the queued results, and waits
+// for it. Returns the number of results drained.
+func flushPool(pool *pushPool) int {
+	done := make(chan int, 1)
+	select {
+	case pool.flushRequests <- done:
+		return <-done
+	case <-pool.stopped:
+		return 0
+	}
+}
+
+// drainPool pushes the results queued in a pool from a pool worker. Returns
+// the number of results drained.
+func (c *Component) drainPool(pool *pushPool) int {
+	drained := 0
+	for {
+		select {
+		case message, ok := <-pool.queue:
+			if !ok {
+				return drained
+			}
+			c.poolPush(pool, message)
+			drained++
+		default:
+			return drained
+		}
+	}
+}
+
+// poolPush pushes a result from a pool worker. The exporter is stopped if the
+// push fails, and reconnected by the next push.
+func (c *Component) poolPush(pool *pushPool, message *healthcheck.Result) {
+	exporter := pool.exporter
+	pool.lock.Lock()
+	if !exporter.IsStarted() {
+		c.reconnect(exporter)
+	}
+	started := exporter.IsStarted()
+	pool.lock.Unlock()
+	if !started {
+		return
+	}
+	pool.inFlight.Inc()
+	start := time.Now()
+	err := exporter.Push(message)
+	duration := time.Since(start)
+	pool.inFlight.Dec()
+	status := "success"
+	name := exporter.Name()
+	c.health.record(name, err)
+	if err != nil {
+		c.Logger.Error(fmt.Sprintf("Failed to push healthchecks result for exporter %s: %s", name, err.Error()),
+			correlationFields(message)...)
+		status = "failure"
+		pool.lock.Lock()
+		err := exporter.Stop()
+		pool.lock.Unlock()
+		if err != nil {
+			c.Logger.Error(fmt.Sprintf("Fail to close the exporter %s: %s", name, err.Error()),
+				correlationFields(message)...)
+		}
+	}
+	c.exporterHistogram.With(prom.Labels{"name": name, "status": status}).Observe(duration.Seconds())
+}
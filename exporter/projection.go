@@ -0,0 +1,118 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// Projection selects the result fields, labels and metadata serialized by an
+// exporter. For each of them, the keys to keep or the keys to remove can be
+// configured, everything is kept by default.
+type Projection struct {
+	Fields          []string `json:"fields,omitempty" yaml:"fields,omitempty"`
+	ExcludeFields   []string `json:"exclude-fields,omitempty" yaml:"exclude-fields,omitempty"`
+	Labels          []string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	ExcludeLabels   []string `json:"exclude-labels,omitempty" yaml:"exclude-labels,omitempty"`
+	Metadata        []string `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	ExcludeMetadata []string `json:"exclude-metadata,omitempty" yaml:"exclude-metadata,omitempty"`
+}
+
+// resultFields returns the names of the serialized result fields
+func resultFields() map[string]bool {
+	fields := make(map[string]bool)
+	resultType := reflect.TypeOf(healthcheck.Result{})
+	for i := 0; i < resultType.NumField(); i++ {
+		name := strings.Split(resultType.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}
+
+// Validate validates the projection, the fields should be result fields
+func (p *Projection) Validate() error {
+	if len(p.Fields) != 0 && len(p.ExcludeFields) != 0 {
+		return errors.New("The projection fields and exclude-fields options can't be used together")
+	}
+	if len(p.Labels) != 0 && len(p.ExcludeLabels) != 0 {
+		return errors.New("The projection labels and exclude-labels options can't be used together")
+	}
+	if len(p.Metadata) != 0 && len(p.ExcludeMetadata) != 0 {
+		return errors.New("The projection metadata and exclude-metadata options can't be used together")
+	}
+	known := resultFields()
+	for _, fields := range [][]string{p.Fields, p.ExcludeFields} {
+		for _, field := range fields {
+			if !known[field] {
+				return fmt.Errorf("Invalid projection field %s", field)
+			}
+		}
+	}
+	return nil
+}
+
+// keep returns true if a key is selected by the include or exclude lists
+func keep(key string, include []string, exclude []string) bool {
+	if len(include) != 0 {
+		for _, k := range include {
+			if k == key {
+				return true
+			}
+		}
+		return false
+	}
+	for _, k := range exclude {
+		if k == key {
+			return false
+		}
+	}
+	return true
+}
+
+// filterKeys returns a copy of m with the selected keys, nil if none is
+// selected
+func filterKeys(m map[string]string, include []string, exclude []string) map[string]string {
+	var result map[string]string
+	for k, v := range m {
+		if !keep(k, include, exclude) {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]string, len(m))
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// project returns the value serialized for a result. The result is not
+// modified, as it is shared between the exporters.
+func (p *Projection) project(result *healthcheck.Result) (interface{}, error) {
+	if p == nil {
+		return result, nil
+	}
+	projected := *result
+	projected.Labels = filterKeys(result.Labels, p.Labels, p.ExcludeLabels)
+	projected.Metadata = filterKeys(result.Metadata, p.Metadata, p.ExcludeMetadata)
+	content, err := json.Marshal(projected)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to convert result to json:\n%v", result)
+	}
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(content, &fields); err != nil {
+		return nil, errors.Wrapf(err, "Fail to project the result:\n%v", result)
+	}
+	for k := range fields {
+		if !keep(k, p.Fields, p.ExcludeFields) {
+			delete(fields, k)
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,75 @@
+package exporter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+func TestProjection(t *testing.T) {
+	result := &healthcheck.Result{
+		Name:     "foo",
+		Success:  true,
+		Message:  "ok",
+		Labels:   map[string]string{"env": "prod", "team": "infra"},
+		Metadata: map[string]string{"status": "200", "reason": "none"},
+	}
+	projection := &Projection{
+		Fields:        []string{"name", "labels", "metadata"},
+		ExcludeLabels: []string{"team"},
+		Metadata:      []string{"status"},
+	}
+	projected, err := projection.project(result)
+	if err != nil {
+		t.Fatalf("Fail to project the result\n%v", err)
+	}
+	content, err := json.Marshal(projected)
+	if err != nil {
+		t.Fatalf("Fail to convert the result to json\n%v", err)
+	}
+	expected := `{"labels":{"env":"prod"},"metadata":{"status":"200"},"name":"foo"}`
+	if string(content) != expected {
+		t.Fatalf("Invalid projected result %s", string(content))
+	}
+	// the shared result is not modified
+	if len(result.Labels) != 2 || len(result.Metadata) != 2 {
+		t.Fatalf("The result was modified %v", result)
+	}
+	var nilProjection *Projection
+	projected, err = nilProjection.project(result)
+	if err != nil || projected != result {
+		t.Fatalf("The result should not be projected")
+	}
+}
+
+func TestProjectionConfiguration(t *testing.T) {
+	var config HTTPConfiguration
+	err := yaml.Unmarshal([]byte("name: http\nhost: 127.0.0.1\nport: 8080\nprojection:\n  exclude-fields: [metadata]\n"), &config)
+	if err != nil {
+		t.Fatalf("Fail to parse the configuration\n%v", err)
+	}
+	if config.Projection == nil || config.Projection.ExcludeFields[0] != "metadata" {
+		t.Fatalf("Invalid projection %v", config.Projection)
+	}
+	invalid := []string{
+		"name: http\nhost: 127.0.0.1\nport: 8080\nprojection:\n  fields: [unknown]\n",
+		"name: http\nhost: 127.0.0.1\nport: 8080\nprojection:\n  labels: [env]\n  exclude-labels: [team]\n",
+		"name: mqtt\nhost: 127.0.0.1\nport: 1883\nprojection:\n  fields: [name]\n  exclude-fields: [labels]\n",
+	}
+	for i, c := range invalid {
+		var err error
+		if i == 2 {
+			var mqtt MQTTConfiguration
+			err = yaml.Unmarshal([]byte(c), &mqtt)
+		} else {
+			var http HTTPConfiguration
+			err = yaml.Unmarshal([]byte(c), &http)
+		}
+		if err == nil {
+			t.Fatalf("Was expected an error when decoding the configuration: \n%s", c)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gopkg.in/tomb.v2"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// DefaultPubSubEndpoint the Google Cloud Pub/Sub API endpoint
+const DefaultPubSubEndpoint = "https://pubsub.googleapis.com"
+
+// DefaultPubSubBatchSize the default number of messages of a publish request
+const DefaultPubSubBatchSize = 100
+
+// DefaultPubSubBatchDelay the default maximum duration a message waits in
+// the batch
+const DefaultPubSubBatchDelay = healthcheck.Duration(time.Second)
+
+// DefaultPubSubTimeout the default timeout of the publish requests
+const DefaultPubSubTimeout = 10 * time.Second
+
+// pubSubMaxBatchSize the maximum number of messages of a publish request
+const pubSubMaxBatchSize = 1000
+
+// pubSubScope the OAuth2 scope of the Pub/Sub API
+const pubSubScope = "https://www.googleapis.com/auth/pubsub"
+
+// pubSubMaxResponseSize the maximum size of the publish responses
+const pubSubMaxResponseSize = 1024 * 1024
+
+// PubSubConfiguration the Google Cloud Pub/Sub exporter configuration
+type PubSubConfiguration struct {
+	Name    string
+	Project string
+	Topic   string
+	// a service account or user credentials file, the Application Default
+	// Credentials are used if empty
+	CredentialsFile string `json:"credentials-file,omitempty" yaml:"credentials-file,omitempty"`
+	// the Pub/Sub API endpoint, DefaultPubSubEndpoint if empty
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	// the messages are published when the batch is full, or after the
+	// batch delay
+	BatchSize  uint                 `json:"batch-size,omitempty" yaml:"batch-size,omitempty"`
+	BatchDelay healthcheck.Duration `json:"batch-delay,omitempty" yaml:"batch-delay,omitempty"`
+	Timeout    healthcheck.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// the delivery of the results, asynchronous by default
+	Delivery `json:",inline" yaml:",inline"`
+}
+
+// pubSubMessage a message of a publish request
+type pubSubMessage struct {
+	Data        []byte            `json:"data"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	OrderingKey string            `json:"orderingKey,omitempty"`
+}
+
+// PubSubExporter the Google Cloud Pub/Sub exporter struct
+type PubSubExporter struct {
+	startedState
+	Logger  *zap.Logger
+	Config  *PubSubConfiguration
+	client  *http.Client
+	tokens  *googleTokenSource
+	counter *prom.CounterVec
+	url     string
+	t       *tomb.Tomb
+	// the batches are published one at a time, to keep the messages order
+	publishLock sync.Mutex
+	lock        sync.Mutex
+	batch       []pubSubMessage
+	// the error of the last background publish, returned by the next push
+	lastErr error
+}
+
+// UnmarshalYAML parses the configuration of the Pub/Sub exporter from YAML.
+func (c *PubSubConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration PubSubConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read Pub/Sub exporter configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid name for the Pub/Sub exporter configuration")
+	}
+	if raw.Project == "" || strings.Contains(raw.Project, "/") {
+		return errors.New("Invalid project for the Pub/Sub exporter configuration")
+	}
+	if raw.Topic == "" || strings.Contains(raw.Topic, "/") {
+		return errors.New("Invalid topic for the Pub/Sub exporter configuration")
+	}
+	if raw.Endpoint != "" {
+		endpoint, err := url.Parse(raw.Endpoint)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			return fmt.Errorf("Invalid endpoint %s for the Pub/Sub exporter configuration", raw.Endpoint)
+		}
+	}
+	if raw.BatchSize == 0 {
+		raw.BatchSize = DefaultPubSubBatchSize
+	}
+	if raw.BatchSize > pubSubMaxBatchSize {
+		return fmt.Errorf("The Pub/Sub exporter batch-size should be lower than %d", pubSubMaxBatchSize)
+	}
+	if raw.BatchDelay < 0 || raw.Timeout < 0 {
+		return errors.New("The Pub/Sub exporter batch-delay and timeout should be positive")
+	}
+	if raw.BatchDelay == 0 {
+		raw.BatchDelay = DefaultPubSubBatchDelay
+	}
+	*c = PubSubConfiguration(raw)
+	return nil
+}
+
+// NewPubSubExporter creates a new Pub/Sub exporter
+func NewPubSubExporter(logger *zap.Logger, config *PubSubConfiguration, counter *prom.CounterVec) (*PubSubExporter, error) {
+	timeout := time.Duration(config.Timeout)
+	if timeout == 0 {
+		timeout = DefaultPubSubTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+	tokens, err := newGoogleTokenSource(client, config.CredentialsFile, pubSubScope)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid credentials for the Pub/Sub exporter")
+	}
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultPubSubEndpoint
+	}
+	return &PubSubExporter{
+		Logger:  logger,
+		Config:  config,
+		client:  client,
+		tokens:  tokens,
+		counter: counter,
+		url: fmt.Sprintf("%s/v1/projects/%s/topics/%s:publish",
+			strings.TrimSuffix(endpoint, "/"),
+			url.PathEscape(config.Project),
+			url.PathEscape(config.Topic)),
+	}, nil
+}
+
+// IsStarted returns the exporter status
+func (c *PubSubExporter) IsStarted() bool {
+	return c.started()
+}
+
+// Start starts publishing the batches on the batch delay
+func (c *PubSubExporter) Start() error {
+	c.Logger.Info(fmt.Sprintf("Starting the Pub/Sub exporter %s", c.Config.Name))
+	delay := c.Config.BatchDelay
+	if delay == 0 {
+		delay = DefaultPubSubBatchDelay
+	}
+	ticker := time.NewTicker(time.Duration(delay))
+	c.t = &tomb.Tomb{}
+	t := c.t
+	t.Go(func() error {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				_, err := c.flush()
+				if err != nil {
+					c.Logger.Error(err.Error(), zap.String("exporter", c.Config.Name))
+					c.lock.Lock()
+					c.lastErr = err
+					c.lock.Unlock()
+				}
+			case <-t.Dying():
+				return nil
+			}
+		}
+	})
+	c.setStarted(true)
+	return nil
+}
+
+// Reconnect restarts the exporter stopped after a failed publish
+func (c *PubSubExporter) Reconnect() error {
+	c.Logger.Info(fmt.Sprintf("Pub/Sub exporter %s: reconnecting", c.Config.Name))
+	if c.t != nil {
+		// nolint
+		c.Stop()
+	}
+	if err := c.Start(); err != nil {
+		return errors.Wrapf(err, "Fail to restart the Pub/Sub exporter")
+	}
+	c.Logger.Info(fmt.Sprintf("Pub/Sub exporter %s: reconnected", c.Config.Name))
+	return nil
+}
+
+// probe verifies that an access token can be obtained and that the Pub/Sub
+// endpoint is reachable
+func (c *PubSubExporter) probe() error {
+	if _, err := c.tokens.Token(context.Background()); err != nil {
+		return errors.Wrap(err, "Pub/Sub exporter: fail to get an access token")
+	}
+	return probeURL(c.client, c.url)
+}
+
+// Stop stops the Pub/Sub exporter, the pending messages are published
+func (c *PubSubExporter) Stop() error {
+	c.Logger.Info(fmt.Sprintf("Stopping the Pub/Sub exporter %s", c.Config.Name))
+	c.setStarted(false)
+	if c.t != nil {
+		c.t.Kill(nil)
+		// nolint
+		c.t.Wait()
+		c.t = nil
+	}
+	_, err := c.flush()
+	return err
+}
+
+// Name returns the name of the exporter
+func (c *PubSubExporter) Name() string {
+	return c.Config.Name
+}
+
+// GetConfig returns the config of the exporter
+func (c *PubSubExporter) GetConfig() interface{} {
+	return c.Config
+}
+
+// pubSubResult returns the message of a result. The labels are the attributes,
+// the keys reserved by Google are ignored.
+func pubSubResult(result *healthcheck.Result) (pubSubMessage, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return pubSubMessage{}, errors.Wrapf(err, "Fail to convert result to json:\n%v", result)
+	}
+	var attributes map[string]string
+	for k, v := range result.Labels {
+		if strings.HasPrefix(k, "goog") {
+			continue
+		}
+		if attributes == nil {
+			attributes = make(map[string]string, len(result.Labels))
+		}
+		attributes[k] = v
+	}
+	return pubSubMessage{
+		Data:        data,
+		Attributes:  attributes,
+		OrderingKey: result.Name,
+	}, nil
+}
+
+// publish sends a batch of messages to the topic
+func (c *PubSubExporter) publish(messages []pubSubMessage) error {
+	payload, err := json.Marshal(map[string]interface{}{"messages": messages})
+	if err != nil {
+		return errors.Wrap(err, "Pub/Sub exporter: fail to create the publish request")
+	}
+	ctx := context.Background()
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Pub/Sub exporter: fail to get an access token")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewBuffer(payload))
+	if err != nil {
+		return errors.Wrap(err, "Pub/Sub exporter: fail to create the publish request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Cabourotte")
+	req.Header.Set("Authorization", "Bearer "+token)
+	response, err := c.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "Pub/Sub exporter: publish request failed")
+	}
+	defer response.Body.Close()
+	body, err := healthcheck.ReadBody(response.Body, pubSubMaxResponseSize)
+	if err != nil {
+		return errors.Wrap(err, "Pub/Sub exporter: fail to read the publish response")
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("Pub/Sub exporter: the publish request returned the status %d: %s", response.StatusCode, string(body))
+	}
+	return nil
+}
+
+// flush publishes the pending messages and returns their number. The messages
+// of a failed batch are dropped.
+func (c *PubSubExporter) flush() (int, error) {
+	c.publishLock.Lock()
+	defer c.publishLock.Unlock()
+	c.lock.Lock()
+	batch := c.batch
+	c.batch = nil
+	c.lock.Unlock()
+	if len(batch) == 0 {
+		return 0, nil
+	}
+	err := c.publish(batch)
+	status := "published"
+	if err != nil {
+		status = "failed"
+	}
+	c.counter.With(prom.Labels{"name": c.Config.Name, "status": status}).Add(float64(len(batch)))
+	if err != nil {
+		return 0, errors.Wrapf(err, "%d messages dropped", len(batch))
+	}
+	return len(batch), nil
+}
+
+// Push adds the result to the batch, which is published once full. The
+// error of the last background publish is returned.
+func (c *PubSubExporter) Push(result *healthcheck.Result) error {
+	msg, err := pubSubResult(result)
+	if err != nil {
+		return err
+	}
+	batchSize := c.Config.BatchSize
+	if batchSize == 0 {
+		batchSize = DefaultPubSubBatchSize
+	}
+	c.lock.Lock()
+	c.batch = append(c.batch, msg)
+	full := uint(len(c.batch)) >= batchSize
+	lastErr := c.lastErr
+	c.lastErr = nil
+	c.lock.Unlock()
+	if full {
+		if _, err := c.flush(); err != nil {
+			return err
+		}
+	}
+	return lastErr
+}
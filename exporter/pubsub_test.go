@@ -0,0 +1,270 @@
+package exporter
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// writeServiceAccount writes a service account credentials file using the
+// token endpoint of the test server
+func writeServiceAccount(t *testing.T, tokenURI string) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Fail to generate the key\n%v", err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	content, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "cabourotte@project.iam.gserviceaccount.com",
+		"private_key":  string(pemKey),
+		"token_uri":    tokenURI,
+	})
+	if err != nil {
+		t.Fatalf("Fail to create the credentials\n%v", err)
+	}
+	file, err := ioutil.TempFile("", "cabourotte-credentials")
+	if err != nil {
+		t.Fatalf("Fail to create the credentials file\n%v", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(content); err != nil {
+		t.Fatalf("Fail to write the credentials file\n%v", err)
+	}
+	return file.Name()
+}
+
+func TestPubSubExporter(t *testing.T) {
+	var lock sync.Mutex
+	tokens := 0
+	var batches [][]pubSubMessage
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || r.FormValue("assertion") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		lock.Lock()
+		tokens++
+		lock.Unlock()
+		w.Write([]byte(`{"access_token": "secret", "expires_in": 3600}`))
+	})
+	mux.HandleFunc("/v1/projects/my-project/topics/results:publish", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var request struct {
+			Messages []pubSubMessage
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		lock.Lock()
+		batches = append(batches, request.Messages)
+		lock.Unlock()
+		w.Write([]byte(`{"messageIds": ["1"]}`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	credentials := writeServiceAccount(t, ts.URL+"/token")
+	defer os.Remove(credentials)
+	counter := prom.NewCounterVec(prom.CounterOpts{Name: "pubsub_exporter_messages_total"}, []string{"name", "status"})
+	exporter, err := NewPubSubExporter(zap.NewExample(), &PubSubConfiguration{
+		Name:            "pubsub",
+		Project:         "my-project",
+		Topic:           "results",
+		CredentialsFile: credentials,
+		Endpoint:        ts.URL,
+		BatchSize:       2,
+		BatchDelay:      healthcheck.Duration(time.Hour),
+	}, counter)
+	if err != nil {
+		t.Fatalf("Fail to create the exporter\n%v", err)
+	}
+	err = exporter.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the exporter\n%v", err)
+	}
+	results := []*healthcheck.Result{
+		{Name: "foo", Success: true, Labels: map[string]string{"env": "prod", "goog-reserved": "a"}},
+		{Name: "bar", Success: false},
+		{Name: "baz", Success: true},
+	}
+	for _, result := range results {
+		if err := exporter.Push(result); err != nil {
+			t.Fatalf("Fail to push the result\n%v", err)
+		}
+	}
+	lock.Lock()
+	if len(batches) != 1 || len(batches[0]) != 2 {
+		t.Fatalf("The full batch should be published %v", batches)
+	}
+	lock.Unlock()
+	// the pending messages are published on stop
+	err = exporter.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the exporter\n%v", err)
+	}
+	if tokens != 1 || len(batches) != 2 || len(batches[1]) != 1 {
+		t.Fatalf("Invalid batches %v (%d tokens)", batches, tokens)
+	}
+	first := batches[0][0]
+	if first.OrderingKey != "foo" || len(first.Attributes) != 1 || first.Attributes["env"] != "prod" {
+		t.Fatalf("Invalid message %v", first)
+	}
+	var result healthcheck.Result
+	if err := json.Unmarshal(first.Data, &result); err != nil || result.Name != "foo" || !result.Success {
+		t.Fatalf("Invalid message data %s", string(first.Data))
+	}
+	var m dto.Metric
+	if err := counter.With(prom.Labels{"name": "pubsub", "status": "published"}).Write(&m); err != nil {
+		t.Fatalf("Fail to read the counter\n%v", err)
+	}
+	if m.GetCounter().GetValue() != 3 {
+		t.Fatalf("Invalid published messages count %f", m.GetCounter().GetValue())
+	}
+}
+
+func TestPubSubConfiguration(t *testing.T) {
+	var config PubSubConfiguration
+	err := yaml.Unmarshal([]byte("name: pubsub\nproject: my-project\ntopic: results\n"), &config)
+	if err != nil {
+		t.Fatalf("Fail to parse the configuration\n%v", err)
+	}
+	if config.BatchSize != DefaultPubSubBatchSize || config.BatchDelay != DefaultPubSubBatchDelay {
+		t.Fatalf("Invalid defaults %v", config)
+	}
+	invalid := []string{
+		"name: pubsub\ntopic: results\n",
+		"name: pubsub\nproject: my-project\ntopic: projects/my-project/topics/results\n",
+		"name: pubsub\nproject: my-project\ntopic: results\nbatch-size: 5000\n",
+		"name: pubsub\nproject: my-project\ntopic: results\nendpoint: pubsub.googleapis.com\n",
+	}
+	for _, c := range invalid {
+		err := yaml.Unmarshal([]byte(c), &config)
+		if err == nil {
+			t.Fatalf("Was expected an error when decoding the configuration: \n%s", c)
+		}
+	}
+}
+
+func TestPubSubExporterReconnect(t *testing.T) {
+	var lock sync.Mutex
+	failing := true
+	published := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"access_token": "secret", "expires_in": 3600}`))
+	})
+	mux.HandleFunc("/v1/projects/my-project/topics/results:publish", func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		published++
+		w.Write([]byte(`{"messageIds": ["1"]}`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	credentials := writeServiceAccount(t, ts.URL+"/token")
+	defer os.Remove(credentials)
+	counter := prom.NewCounterVec(prom.CounterOpts{Name: "pubsub_exporter_messages_total"}, []string{"name", "status"})
+	exporter, err := NewPubSubExporter(zap.NewExample(), &PubSubConfiguration{
+		Name:            "pubsub",
+		Project:         "my-project",
+		Topic:           "results",
+		CredentialsFile: credentials,
+		Endpoint:        ts.URL,
+		BatchSize:       1,
+		BatchDelay:      healthcheck.Duration(time.Hour),
+	}, counter)
+	if err != nil {
+		t.Fatalf("Fail to create the exporter\n%v", err)
+	}
+	err = exporter.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the exporter\n%v", err)
+	}
+	if err := exporter.Push(&healthcheck.Result{Name: "foo"}); err == nil {
+		t.Fatalf("Was expecting a publish error")
+	}
+	// the exporters component stops the exporter after a failed push
+	// nolint
+	exporter.Stop()
+	if exporter.IsStarted() {
+		t.Fatalf("The exporter should be stopped")
+	}
+	lock.Lock()
+	failing = false
+	lock.Unlock()
+	if err := exporter.Reconnect(); err != nil {
+		t.Fatalf("Fail to reconnect the exporter\n%v", err)
+	}
+	if !exporter.IsStarted() {
+		t.Fatalf("The exporter should be started")
+	}
+	if err := exporter.Push(&healthcheck.Result{Name: "foo"}); err != nil {
+		t.Fatalf("Fail to push the result\n%v", err)
+	}
+	if err := exporter.Stop(); err != nil {
+		t.Fatalf("Fail to stop the exporter\n%v", err)
+	}
+	if published != 1 {
+		t.Fatalf("Invalid published batches %d", published)
+	}
+}
+
+func TestPubSubExporterProbe(t *testing.T) {
+	validToken := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if !validToken {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "invalid_grant"}`))
+			return
+		}
+		w.Write([]byte(`{"access_token": "secret", "expires_in": 3600}`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	credentials := writeServiceAccount(t, ts.URL+"/token")
+	defer os.Remove(credentials)
+	counter := prom.NewCounterVec(prom.CounterOpts{Name: "pubsub_exporter_messages_total"}, []string{"name", "status"})
+	exporter, err := NewPubSubExporter(zap.NewExample(), &PubSubConfiguration{
+		Name:            "pubsub",
+		Project:         "my-project",
+		Topic:           "results",
+		CredentialsFile: credentials,
+		Endpoint:        ts.URL,
+	}, counter)
+	if err != nil {
+		t.Fatalf("Fail to create the exporter\n%v", err)
+	}
+	if err := exporter.probe(); err == nil {
+		t.Fatalf("Was expecting a probe error")
+	}
+	validToken = true
+	if err := exporter.probe(); err != nil {
+		t.Fatalf("Fail to probe the exporter\n%v", err)
+	}
+}
@@ -0,0 +1,187 @@
+package exporter
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// DefaultRecordingCapacity the default number of results kept by the
+// recording exporter
+const DefaultRecordingCapacity = 1000
+
+// RecordingConfiguration the configuration of the recording exporter, a test
+// double keeping the pushed results in memory
+type RecordingConfiguration struct {
+	Name string
+	// the exporter is a test double and should be explicitly enabled
+	Enabled bool
+	// the maximum number of results kept, the oldest ones are dropped
+	Capacity uint
+	// the pushes fail every N pushes, disabled if 0
+	FailEvery uint `json:"fail-every,omitempty" yaml:"fail-every,omitempty"`
+	// the pushes fail during this duration after the exporter creation
+	FailFor healthcheck.Duration `json:"fail-for,omitempty" yaml:"fail-for,omitempty"`
+	// the delivery of the results, asynchronous by default
+	Delivery `json:",inline" yaml:",inline"`
+}
+
+// RecordingExporter the recording exporter struct
+type RecordingExporter struct {
+	startedState
+	Logger  *zap.Logger
+	Config  *RecordingConfiguration
+	lock    sync.Mutex
+	results []*healthcheck.Result
+	pushes  uint
+	created time.Time
+}
+
+// UnmarshalYAML parses the configuration of the recording exporter from YAML.
+func (c *RecordingConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration RecordingConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read recording exporter configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid name for the recording exporter configuration")
+	}
+	if !raw.Enabled {
+		return fmt.Errorf("The recording exporter %s is a test double and should be explicitly enabled", raw.Name)
+	}
+	if raw.Capacity == 0 {
+		raw.Capacity = DefaultRecordingCapacity
+	}
+	if raw.FailFor < 0 {
+		return errors.New("The recording exporter fail-for should be positive")
+	}
+	*c = RecordingConfiguration(raw)
+	return nil
+}
+
+// NewRecordingExporter creates a new recording exporter
+func NewRecordingExporter(logger *zap.Logger, config *RecordingConfiguration) *RecordingExporter {
+	return &RecordingExporter{
+		Logger:  logger,
+		Config:  config,
+		created: time.Now(),
+	}
+}
+
+// IsStarted returns the exporter status
+func (c *RecordingExporter) IsStarted() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.started()
+}
+
+// Start starts the recording exporter component
+func (c *RecordingExporter) Start() error {
+	c.Logger.Info(fmt.Sprintf("Starting the recording exporter %s", c.Config.Name))
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.setStarted(true)
+	return nil
+}
+
+// Reconnect reconnects the recording exporter component
+func (c *RecordingExporter) Reconnect() error {
+	return c.Start()
+}
+
+// Stop stops the recording exporter component, the recorded results are kept
+func (c *RecordingExporter) Stop() error {
+	c.Logger.Info(fmt.Sprintf("Stopping the recording exporter %s", c.Config.Name))
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.setStarted(false)
+	return nil
+}
+
+// Name returns the name of the exporter
+func (c *RecordingExporter) Name() string {
+	return c.Config.Name
+}
+
+// GetConfig returns the config of the exporter
+func (c *RecordingExporter) GetConfig() interface{} {
+	return c.Config
+}
+
+// Push records the result, or returns an injected failure
+func (c *RecordingExporter) Push(result *healthcheck.Result) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.pushes++
+	if c.Config.FailEvery != 0 && c.pushes%c.Config.FailEvery == 0 {
+		return fmt.Errorf("Recording exporter: injected failure for the push %d", c.pushes)
+	}
+	if c.Config.FailFor != 0 && time.Since(c.created) < time.Duration(c.Config.FailFor) {
+		return errors.New("Recording exporter: injected failure")
+	}
+	capacity := int(c.Config.Capacity)
+	if capacity == 0 {
+		capacity = DefaultRecordingCapacity
+	}
+	if len(c.results) >= capacity {
+		c.results = c.results[len(c.results)-capacity+1:]
+	}
+	c.results = append(c.results, result)
+	return nil
+}
+
+// Results returns the recorded results, from the oldest to the newest
+func (c *RecordingExporter) Results() []*healthcheck.Result {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	results := make([]*healthcheck.Result, len(c.results))
+	copy(results, c.results)
+	return results
+}
+
+// Reset removes the recorded results
+func (c *RecordingExporter) Reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.results = nil
+}
+
+// recordingExporter returns a recording exporter by name
+func (c *Component) recordingExporter(name string) (*RecordingExporter, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	exporter, ok := c.Exporters[name]
+	if !ok {
+		return nil, fmt.Errorf("The exporter %s does not exist", name)
+	}
+	recording, ok := exporter.(*RecordingExporter)
+	if !ok {
+		return nil, fmt.Errorf("The exporter %s is not a recording exporter", name)
+	}
+	return recording, nil
+}
+
+// Recorded returns the results recorded by a recording exporter
+func (c *Component) Recorded(name string) ([]*healthcheck.Result, error) {
+	recording, err := c.recordingExporter(name)
+	if err != nil {
+		return nil, err
+	}
+	return recording.Results(), nil
+}
+
+// ResetRecorded removes the results recorded by a recording exporter
+func (c *Component) ResetRecorded(name string) error {
+	recording, err := c.recordingExporter(name)
+	if err != nil {
+		return err
+	}
+	recording.Reset()
+	return nil
+}
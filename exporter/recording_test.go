@@ -0,0 +1,88 @@
+package exporter
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+func TestRecordingExporter(t *testing.T) {
+	exporter := NewRecordingExporter(zap.NewExample(), &RecordingConfiguration{
+		Name:      "recording",
+		Enabled:   true,
+		Capacity:  3,
+		FailEvery: 4,
+	})
+	err := exporter.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the recording exporter\n%v", err)
+	}
+	failures := 0
+	for i := 0; i < 8; i++ {
+		err := exporter.Push(&healthcheck.Result{Name: "foo", Sequence: uint64(i + 1)})
+		if err != nil {
+			failures++
+		}
+	}
+	if failures != 2 {
+		t.Fatalf("Invalid number of injected failures %d", failures)
+	}
+	results := exporter.Results()
+	if len(results) != 3 {
+		t.Fatalf("Invalid number of recorded results %d", len(results))
+	}
+	if results[0].Sequence != 5 || results[1].Sequence != 6 || results[2].Sequence != 7 {
+		t.Fatalf("The oldest results should be dropped %v", results)
+	}
+	exporter.Reset()
+	if len(exporter.Results()) != 0 {
+		t.Fatalf("The recorded results should be removed")
+	}
+	err = exporter.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the recording exporter\n%v", err)
+	}
+}
+
+func TestRecordingExporterFailFor(t *testing.T) {
+	exporter := NewRecordingExporter(zap.NewExample(), &RecordingConfiguration{
+		Name:    "recording",
+		Enabled: true,
+		FailFor: healthcheck.Duration(200 * time.Millisecond),
+	})
+	if err := exporter.Push(&healthcheck.Result{Name: "foo"}); err == nil {
+		t.Fatalf("The push should fail")
+	}
+	time.Sleep(300 * time.Millisecond)
+	if err := exporter.Push(&healthcheck.Result{Name: "foo"}); err != nil {
+		t.Fatalf("The push should succeed\n%v", err)
+	}
+	if len(exporter.Results()) != 1 {
+		t.Fatalf("The result should be recorded")
+	}
+}
+
+func TestRecordingConfiguration(t *testing.T) {
+	var config RecordingConfiguration
+	err := yaml.Unmarshal([]byte("name: recording\nenabled: true\nfail-every: 3\n"), &config)
+	if err != nil {
+		t.Fatalf("Fail to parse the configuration\n%v", err)
+	}
+	if config.Capacity != DefaultRecordingCapacity || config.FailEvery != 3 {
+		t.Fatalf("Invalid configuration %v", config)
+	}
+	cases := []string{
+		"name: recording\n",
+		"enabled: true\n",
+		"name: recording\nenabled: true\nfail-for: -1s\n",
+	}
+	for _, c := range cases {
+		if err := yaml.Unmarshal([]byte(c), &RecordingConfiguration{}); err == nil {
+			t.Fatalf("The configuration should be invalid:\n%s", c)
+		}
+	}
+}
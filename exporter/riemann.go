@@ -3,6 +3,8 @@ package exporter
 import (
 	"fmt"
 	"net"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -23,6 +25,8 @@ type RiemannConfiguration struct {
 	Cert     string `json:"cert,omitempty"`
 	Cacert   string `json:"cacert,omitempty"`
 	Insecure bool
+	// the delivery of the results, asynchronous by default
+	Delivery `json:",inline" yaml:",inline"`
 }
 
 // RiemannExporter the Riemann exporter struct
@@ -31,6 +35,9 @@ type RiemannExporter struct {
 	Logger  *zap.Logger
 	Config  *RiemannConfiguration
 	Client  riemanngo.Client
+	// protects Started, updated by the goroutines pushing the results and
+	// read by the readiness probe
+	startedLock sync.RWMutex
 }
 
 // UnmarshalYAML parses the configuration of the Riemann component from YAML.
@@ -101,14 +108,14 @@ func (c *RiemannExporter) Start() error {
 	if err != nil {
 		return errors.Wrapf(err, "Fail to start the Riemann exporter")
 	}
-	c.Started = true
+	c.setStarted(true)
 	return nil
 }
 
 // Stop stops the Riemann exporter component
 func (c *RiemannExporter) Stop() error {
 	c.Logger.Info(fmt.Sprintf("Stopping the Riemann exporter %s", c.Config.Name))
-	c.Started = false
+	c.setStarted(false)
 	return c.Client.Close()
 }
 
@@ -125,7 +132,7 @@ func (c *RiemannExporter) Reconnect() error {
 		return errors.Wrapf(err, "Fail to restart the Riemann exporter")
 	}
 	c.Logger.Info("Riemann exporter: reconnected")
-	c.Started = true
+	c.setStarted(true)
 	return nil
 }
 
@@ -141,15 +148,21 @@ func (c *RiemannExporter) GetConfig() interface{} {
 
 // IsStarted returns the exporter status
 func (c *RiemannExporter) IsStarted() bool {
+	c.startedLock.RLock()
+	defer c.startedLock.RUnlock()
 	return c.Started
 }
 
+// setStarted updates the exporter status
+func (c *RiemannExporter) setStarted(started bool) {
+	c.startedLock.Lock()
+	defer c.startedLock.Unlock()
+	c.Started = started
+}
+
 // Push pushes events to the desination
 func (c *RiemannExporter) Push(result *healthcheck.Result) error {
-	state := "ok"
-	if !result.Success {
-		state = "critical"
-	}
+	state := result.State()
 	attributes := map[string]string{
 		"healthcheck": result.Name,
 		"source":      result.Source,
@@ -157,11 +170,21 @@ func (c *RiemannExporter) Push(result *healthcheck.Result) error {
 	for k, v := range result.Labels {
 		attributes[k] = v
 	}
+	for k, v := range result.Metadata {
+		attributes[k] = v
+	}
+	if result.RunbookURL != "" {
+		attributes["runbook-url"] = result.RunbookURL
+	}
+	if result.Sequence != 0 {
+		attributes["sequence"] = strconv.FormatUint(result.Sequence, 10)
+		attributes["epoch"] = result.Epoch
+	}
 	event := &riemanngo.Event{
 		Service:     "cabourotte-healthcheck",
 		Metric:      result.Duration,
 		Description: fmt.Sprintf("%s: %s", result.Summary, result.Message),
-		Time:        time.Unix(result.HealthcheckTimestamp, 0),
+		Time:        result.Time(),
 		State:       state,
 		Tags:        []string{"cabourotte"},
 		TTL:         time.Duration(c.Config.TTL),
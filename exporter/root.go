@@ -3,6 +3,7 @@ package exporter
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
@@ -10,6 +11,7 @@ import (
 	"go.uber.org/zap"
 	"gopkg.in/tomb.v2"
 
+	"github.com/mcorbin/cabourotte/audit"
 	"github.com/mcorbin/cabourotte/healthcheck"
 	"github.com/mcorbin/cabourotte/memorystore"
 	"github.com/mcorbin/cabourotte/prometheus"
@@ -26,6 +28,27 @@ type Exporter interface {
 	Push(*healthcheck.Result) error
 }
 
+// startedState the started state of an exporter. It is updated by the
+// goroutines pushing the results when the exporter is stopped or reconnected,
+// and read by the readiness probe.
+type startedState struct {
+	value int32
+}
+
+// setStarted updates the started state
+func (s *startedState) setStarted(started bool) {
+	var value int32
+	if started {
+		value = 1
+	}
+	atomic.StoreInt32(&s.value, value)
+}
+
+// started returns true if the exporter is started
+func (s *startedState) started() bool {
+	return atomic.LoadInt32(&s.value) == 1
+}
+
 // Component the exporter component
 type Component struct {
 	Logger            *zap.Logger
@@ -35,24 +58,55 @@ type Component struct {
 	MemoryStore       *memorystore.MemoryStore
 	exporterHistogram *prom.HistogramVec
 	chanResultGauge   *prom.GaugeVec
+	groupGauge        *prom.GaugeVec
+	chatCounter       *prom.CounterVec
+	grpcCounter       *prom.CounterVec
+	sqlCounter        *prom.CounterVec
+	cloudWatchCounter *prom.CounterVec
+	kafkaCounter      *prom.CounterVec
+	pubSubCounter     *prom.CounterVec
+	sampledCounter    *prom.CounterVec
+	inFlightGauge     *prom.GaugeVec
+	pools             map[string]*pushPool
+	grouped           map[string]bool
+	aggregated        map[string]bool
+	aggregators       []*aggregator
 	prometheus        *prometheus.Prometheus
 	gaugeTick         *time.Ticker
 	lock              sync.RWMutex
+	// the *audit.Logger receiving all results, before the sampling
+	audit atomic.Value
 
 	t  tomb.Tomb
 	wg sync.WaitGroup
+
+	health *health
+	// nil if the enrichment is disabled
+	enricher          *enricher
+	enrichmentCounter *prom.CounterVec
+	// the synchronous exporters, in push order
+	syncOrder       []string
+	deliveryCounter *prom.CounterVec
 }
 
 // New creates a new exporter component
 func New(logger *zap.Logger, store *memorystore.MemoryStore, chanResult chan *healthcheck.Result, promComponent *prometheus.Prometheus, config *Configuration) (*Component, error) {
+	if err := validateEndpointsReferences(config); err != nil {
+		return nil, err
+	}
 	exporters := make(map[string]Exporter)
 	for i := range config.HTTP {
-		httpConfig := config.HTTP[i]
-		exporter, err := NewHTTPExporter(logger, &httpConfig)
-		if err != nil {
-			return nil, errors.Wrapf(err, "fail to create the http exporter")
+		for _, httpConfig := range config.HTTP[i].endpointsConfigurations() {
+			httpConfig := httpConfig
+			if _, ok := exporters[httpConfig.Name]; ok {
+				return nil, fmt.Errorf("fail to create the http exporter: the exporter %s already exists", httpConfig.Name)
+			}
+			exporter, err := NewHTTPExporter(logger, &httpConfig)
+			if err != nil {
+				return nil, errors.Wrapf(err, "fail to create the http exporter")
+			}
+			exporters[httpConfig.Name] = exporter
 		}
-		exporters[httpConfig.Name] = exporter
 	}
 	for i := range config.Riemann {
 		riemannConfig := config.Riemann[i]
@@ -62,6 +116,102 @@ func New(logger *zap.Logger, store *memorystore.MemoryStore, chanResult chan *he
 		}
 		exporters[riemannConfig.Name] = exporter
 	}
+	chatCounter := prom.NewCounterVec(prom.CounterOpts{
+		Name: "chat_exporter_notifications_total",
+		Help: "Count the number of notifications sent or suppressed by the chat exporters.",
+	}, []string{"name", "status"})
+	for i := range config.Chat {
+		chatConfig := config.Chat[i]
+		exporter, err := NewChatExporter(logger, &chatConfig, chatCounter)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to create the chat exporter")
+		}
+		exporters[chatConfig.Name] = exporter
+	}
+	grpcCounter := prom.NewCounterVec(prom.CounterOpts{
+		Name: "grpc_exporter_dropped_results_total",
+		Help: "Count the number of results dropped for slow gRPC subscribers.",
+	}, []string{"name"})
+	for i := range config.GRPC {
+		grpcConfig := config.GRPC[i]
+		exporter, err := NewGRPCExporter(logger, &grpcConfig, store, grpcCounter)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to create the gRPC exporter")
+		}
+		exporters[grpcConfig.Name] = exporter
+	}
+	for i := range config.OpenMetrics {
+		openMetricsConfig := config.OpenMetrics[i]
+		exporter, err := NewOpenMetricsExporter(logger, &openMetricsConfig, promComponent)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to create the OpenMetrics exporter")
+		}
+		exporters[openMetricsConfig.Name] = exporter
+	}
+	for i := range config.MQTT {
+		mqttConfig := config.MQTT[i]
+		exporter, err := NewMQTTExporter(logger, &mqttConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to create the MQTT exporter")
+		}
+		exporters[mqttConfig.Name] = exporter
+	}
+	for i := range config.Recording {
+		recordingConfig := config.Recording[i]
+		exporters[recordingConfig.Name] = NewRecordingExporter(logger, &recordingConfig)
+	}
+	for i := range config.StatsD {
+		statsDConfig := config.StatsD[i]
+		exporters[statsDConfig.Name] = NewStatsDExporter(logger, &statsDConfig)
+	}
+	pubSubCounter := prom.NewCounterVec(prom.CounterOpts{
+		Name: "pubsub_exporter_messages_total",
+		Help: "Count the number of messages published or dropped by the Pub/Sub exporters.",
+	}, []string{"name", "status"})
+	for i := range config.PubSub {
+		pubSubConfig := config.PubSub[i]
+		exporter, err := NewPubSubExporter(logger, &pubSubConfig, pubSubCounter)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to create the Pub/Sub exporter")
+		}
+		exporters[pubSubConfig.Name] = exporter
+	}
+	kafkaCounter := prom.NewCounterVec(prom.CounterOpts{
+		Name: "kafka_exporter_records_total",
+		Help: "Count the number of records produced or failed by the Kafka exporters, by topic.",
+	}, []string{"name", "topic", "status"})
+	for i := range config.Kafka {
+		kafkaConfig := config.Kafka[i]
+		exporter, err := NewKafkaExporter(logger, &kafkaConfig, kafkaCounter)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to create the Kafka exporter")
+		}
+		exporters[kafkaConfig.Name] = exporter
+	}
+	cloudWatchCounter := prom.NewCounterVec(prom.CounterOpts{
+		Name: "cloudwatch_exporter_metrics_total",
+		Help: "Count the number of metrics sent or dropped by the CloudWatch exporters.",
+	}, []string{"name", "status"})
+	for i := range config.CloudWatch {
+		cloudWatchConfig := config.CloudWatch[i]
+		exporter, err := NewCloudWatchExporter(logger, &cloudWatchConfig, cloudWatchCounter)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to create the CloudWatch exporter")
+		}
+		exporters[cloudWatchConfig.Name] = exporter
+	}
+	sqlCounter := prom.NewCounterVec(prom.CounterOpts{
+		Name: "sql_exporter_results_total",
+		Help: "Count the number of results inserted or dropped by the SQL exporters.",
+	}, []string{"name", "status"})
+	for i := range config.SQL {
+		sqlConfig := config.SQL[i]
+		exporter, err := NewSQLExporter(logger, &sqlConfig, sqlCounter)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to create the SQL exporter")
+		}
+		exporters[sqlConfig.Name] = exporter
+	}
 	buckets := []float64{
 		0.05, 0.1, 0.2, 0.4, 0.8, 1,
 		1.5, 2, 3, 5}
@@ -75,17 +225,117 @@ func New(logger *zap.Logger, store *memorystore.MemoryStore, chanResult chan *he
 		Name: "result_chan_size",
 		Help: "Size of the result channel.",
 	}, []string{})
-	err := promComponent.Register(histo)
+	groupGauge := prom.NewGaugeVec(prom.GaugeOpts{
+		Name: "exporter_group_active",
+		Help: "1 if the exporter is the active exporter of the group, 0 otherwise.",
+	}, []string{"group", "name"})
+	sampledCounter := prom.NewCounterVec(prom.CounterOpts{
+		Name: "exporter_sampled_results_total",
+		Help: "Count the number of successful results not exported because the result channel was saturated.",
+	}, []string{})
+	inFlightGauge := prom.NewGaugeVec(prom.GaugeOpts{
+		Name: "exporter_inflight_pushes",
+		Help: "Number of results being pushed concurrently by an exporter.",
+	}, []string{"name"})
+	enrichmentCounter := prom.NewCounterVec(prom.CounterOpts{
+		Name: "exporter_enrichment_failures_total",
+		Help: "Count the number of failed enrichment requests, the results are exported without enrichment.",
+	}, []string{})
+	deliveryCounter := prom.NewCounterVec(prom.CounterOpts{
+		Name: "exporter_results_delivery_total",
+		Help: "Count the results by delivery status: exported to all synchronous exporters, failed, or only dispatched to asynchronous exporters.",
+	}, []string{"status"})
+	grouped, err := validateGroups(config.Groups, exporters)
+	if err != nil {
+		return nil, err
+	}
+	aggregated, err := validateAggregates(config.Aggregates, exporters, grouped)
+	if err != nil {
+		return nil, err
+	}
+	syncOrder, err := syncExporters(exporters, grouped, aggregated)
+	if err != nil {
+		return nil, err
+	}
+	skipped := make(map[string]bool, len(grouped)+len(aggregated))
+	for name := range grouped {
+		skipped[name] = true
+	}
+	for name := range aggregated {
+		skipped[name] = true
+	}
+	aggregators := make([]*aggregator, 0, len(config.Aggregates))
+	for i := range config.Aggregates {
+		aggregators = append(aggregators, newAggregator(&config.Aggregates[i]))
+	}
+	err = promComponent.Register(histo)
 	if err != nil {
 		return nil, errors.Wrapf(err, "fail to register the exporter Prometheus histogram")
 	}
+	err = promComponent.Register(groupGauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the exporter group Prometheus gauge")
+	}
+	err = promComponent.Register(chatCounter)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the chat exporter Prometheus counter")
+	}
+	err = promComponent.Register(grpcCounter)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the gRPC exporter Prometheus counter")
+	}
+	err = promComponent.Register(pubSubCounter)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the Pub/Sub exporter Prometheus counter")
+	}
+	err = promComponent.Register(kafkaCounter)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the Kafka exporter Prometheus counter")
+	}
+	err = promComponent.Register(cloudWatchCounter)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the CloudWatch exporter Prometheus counter")
+	}
+	err = promComponent.Register(sqlCounter)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the SQL exporter Prometheus counter")
+	}
+	err = promComponent.Register(sampledCounter)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the sampled results Prometheus counter")
+	}
 	err = promComponent.Register(gauge)
 	if err != nil {
 		return nil, errors.Wrapf(err, "fail to register the chan result Prometheus gauge")
 	}
+	err = promComponent.Register(inFlightGauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the exporter in-flight pushes Prometheus gauge")
+	}
+	err = promComponent.Register(enrichmentCounter)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the exporter enrichment failures Prometheus counter")
+	}
+	err = promComponent.Register(deliveryCounter)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the exporter delivery Prometheus counter")
+	}
 	return &Component{
 		exporterHistogram: histo,
 		chanResultGauge:   gauge,
+		groupGauge:        groupGauge,
+		chatCounter:       chatCounter,
+		grpcCounter:       grpcCounter,
+		sqlCounter:        sqlCounter,
+		cloudWatchCounter: cloudWatchCounter,
+		kafkaCounter:      kafkaCounter,
+		pubSubCounter:     pubSubCounter,
+		sampledCounter:    sampledCounter,
+		inFlightGauge:     inFlightGauge,
+		pools:             newPushPools(exporters, skipped, inFlightGauge),
+		grouped:           grouped,
+		aggregated:        aggregated,
+		aggregators:       aggregators,
 		MemoryStore:       store,
 		Logger:            logger,
 		Config:            config,
@@ -93,6 +343,11 @@ func New(logger *zap.Logger, store *memorystore.MemoryStore, chanResult chan *he
 		Exporters:         exporters,
 		prometheus:        promComponent,
 		gaugeTick:         time.NewTicker(time.Duration(time.Second * 10)),
+		health:            newHealth(),
+		enricher:          newEnricher(logger, &config.Enrichment, enrichmentCounter),
+		enrichmentCounter: enrichmentCounter,
+		syncOrder:         syncOrder,
+		deliveryCounter:   deliveryCounter,
 	}, nil
 }
 
@@ -102,10 +357,9 @@ func (c *Component) Start() error {
 	defer c.lock.Unlock()
 	c.Logger.Info("Starting the exporters")
 	for _, exporter := range c.Exporters {
-		err := exporter.Start()
+		err := c.startExporter(exporter)
 		if err != nil {
-			// do not return error on purpose, clients should be able to reconnect
-			c.Logger.Error(fmt.Sprintf("fail to create the exporter %s: %s", exporter.Name(), err.Error()))
+			return err
 		}
 	}
 	c.wg.Add(1)
@@ -120,53 +374,79 @@ func (c *Component) Start() error {
 			}
 		}
 	})
+	for _, aggregator := range c.aggregators {
+		c.startAggregator(aggregator)
+	}
+	for _, pool := range c.pools {
+		c.startPool(pool)
+	}
 	go func() {
 		defer c.wg.Done()
 		for message := range c.ChanResult {
+			// the audit logs the results as produced by the healthchecks,
+			// before any exporter processing
+			if auditLogger := c.auditLogger(); auditLogger != nil {
+				auditLogger.Log(message)
+			}
+			if c.enricher != nil {
+				c.enricher.enrich(message)
+			}
 			c.MemoryStore.Add(message)
 			if message.Success {
 				c.Logger.Info("Healthcheck successful",
-					zap.String("name", message.Name),
-					zap.Reflect("labels", message.Labels),
-					zap.Int64("healthcheck-timestamp", message.HealthcheckTimestamp),
+					append(correlationFields(message),
+						zap.String("name", message.Name),
+						zap.Reflect("labels", message.Labels),
+						zap.Int64("healthcheck-timestamp", message.HealthcheckTimestamp),
+					)...,
 				)
 			} else {
 				c.Logger.Error("healthcheck failed",
-					zap.String("name", message.Name),
-					zap.Reflect("labels", message.Labels),
-					zap.String("cause", message.Message),
-					zap.Int64("healthcheck-timestamp", message.HealthcheckTimestamp),
+					append(correlationFields(message),
+						zap.String("name", message.Name),
+						zap.Reflect("labels", message.Labels),
+						zap.String("cause", message.Message),
+						zap.Int64("healthcheck-timestamp", message.HealthcheckTimestamp),
+					)...,
 				)
 			}
+			// the rollups are computed from all results
+			for _, aggregator := range c.aggregators {
+				aggregator.add(message)
+			}
+			if c.sampledOut(message) {
+				continue
+			}
+			// the synchronous exporters are pushed first
+			c.pushSync(message)
 			for k := range c.Exporters {
 				exporter := c.Exporters[k]
+				if c.isSyncExporter(exporter.Name()) {
+					continue
+				}
+				if _, ok := c.grouped[exporter.Name()]; ok {
+					continue
+				}
+				if _, ok := c.aggregated[exporter.Name()]; ok {
+					continue
+				}
+				if pool, ok := c.pools[exporter.Name()]; ok {
+					pool.queue <- message
+					continue
+				}
 				if exporter.IsStarted() {
-					start := time.Now()
-					err := exporter.Push(message)
-					duration := time.Since(start)
-					status := "success"
-					name := exporter.Name()
-					if err != nil {
-						c.Logger.Error(fmt.Sprintf("Failed to push healthchecks result for exporter %s: %s", name, err.Error()))
-						status = "failure"
-						err := exporter.Stop()
-						if err != nil {
-							// do not return error
-							// on purpose
-							c.Logger.Error(fmt.Sprintf("Fail to close the exporter %s: %s", name, err.Error()))
-						}
-					}
-					c.exporterHistogram.With(prom.Labels{"name": name, "status": status}).Observe(duration.Seconds())
+					c.push(exporter, message)
 				}
 				if !exporter.IsStarted() {
-					err := exporter.Reconnect()
-					if err != nil {
-						// do not return error
-						// on purpose
-						c.Logger.Error(fmt.Sprintf("fail to reconnect the exporter %s: %s", exporter.Name(), err.Error()))
-					}
+					c.reconnect(exporter)
 				}
 			}
+			for i := range c.Config.Groups {
+				c.pushGroup(&c.Config.Groups[i], message)
+			}
+		}
+		for _, pool := range c.pools {
+			stopPool(pool)
 		}
 		c.Logger.Info("Exporter routine stopped")
 
@@ -175,6 +455,63 @@ func (c *Component) Start() error {
 	return nil
 }
 
+// SetAudit sets the audit logger, nil disables the audit logs
+func (c *Component) SetAudit(auditLogger *audit.Logger) {
+	c.audit.Store(auditLogger)
+}
+
+// auditLogger returns the audit logger
+func (c *Component) auditLogger() *audit.Logger {
+	auditLogger, _ := c.audit.Load().(*audit.Logger)
+	return auditLogger
+}
+
+// push pushes a result to an exporter. The exporter is stopped if the push fails.
+// Returns true if the result was successfully pushed.
+func (c *Component) push(exporter Exporter, message *healthcheck.Result) bool {
+	start := time.Now()
+	err := exporter.Push(message)
+	duration := time.Since(start)
+	status := "success"
+	name := exporter.Name()
+	c.health.record(name, err)
+	if err != nil {
+		c.Logger.Error(fmt.Sprintf("Failed to push healthchecks result for exporter %s: %s", name, err.Error()),
+			correlationFields(message)...)
+		status = "failure"
+		err := exporter.Stop()
+		if err != nil {
+			// do not return error
+			// on purpose
+			c.Logger.Error(fmt.Sprintf("Fail to close the exporter %s: %s", name, err.Error()),
+				correlationFields(message)...)
+		}
+	}
+	c.exporterHistogram.With(prom.Labels{"name": name, "status": status}).Observe(duration.Seconds())
+	return err == nil
+}
+
+// reconnect reconnects a stopped exporter
+func (c *Component) reconnect(exporter Exporter) {
+	err := exporter.Reconnect()
+	if err != nil {
+		// do not return error
+		// on purpose
+		c.Logger.Error(fmt.Sprintf("fail to reconnect the exporter %s: %s", exporter.Name(), err.Error()))
+		c.health.record(exporter.Name(), err)
+	}
+}
+
+// AllStarted returns true if all exporters are started
+func (c *Component) AllStarted() bool {
+	for k := range c.Exporters {
+		if !c.Exporters[k].IsStarted() {
+			return false
+		}
+	}
+	return true
+}
+
 // Stop the exporters
 func (c *Component) Stop() error {
 	c.Logger.Info("Stopping exporters")
@@ -188,6 +525,17 @@ func (c *Component) Stop() error {
 	}
 	c.prometheus.Unregister(c.chanResultGauge)
 	c.prometheus.Unregister(c.exporterHistogram)
+	c.prometheus.Unregister(c.groupGauge)
+	c.prometheus.Unregister(c.chatCounter)
+	c.prometheus.Unregister(c.grpcCounter)
+	c.prometheus.Unregister(c.sqlCounter)
+	c.prometheus.Unregister(c.cloudWatchCounter)
+	c.prometheus.Unregister(c.kafkaCounter)
+	c.prometheus.Unregister(c.pubSubCounter)
+	c.prometheus.Unregister(c.sampledCounter)
+	c.prometheus.Unregister(c.inFlightGauge)
+	c.prometheus.Unregister(c.enrichmentCounter)
+	c.prometheus.Unregister(c.deliveryCounter)
 	for k := range c.Exporters {
 		e := c.Exporters[k]
 		err := e.Stop()
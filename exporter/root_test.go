@@ -1,6 +1,7 @@
 package exporter
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -9,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	prom "github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 
 	"github.com/mcorbin/cabourotte/healthcheck"
@@ -83,3 +85,275 @@ func TestStartStop(t *testing.T) {
 		t.Fatalf("Error stopping the component :\n%v", err)
 	}
 }
+
+type testExporter struct {
+	name    string
+	started bool
+	fail    bool
+	pushed  int
+}
+
+func (e *testExporter) Start() error {
+	e.started = true
+	return nil
+}
+
+func (e *testExporter) Stop() error {
+	e.started = false
+	return nil
+}
+
+func (e *testExporter) Reconnect() error {
+	if e.fail {
+		return errors.New("unavailable")
+	}
+	e.started = true
+	return nil
+}
+
+func (e *testExporter) IsStarted() bool {
+	return e.started
+}
+
+func (e *testExporter) Name() string {
+	return e.name
+}
+
+func (e *testExporter) GetConfig() interface{} {
+	return nil
+}
+
+func (e *testExporter) Push(result *healthcheck.Result) error {
+	if e.fail {
+		return errors.New("unavailable")
+	}
+	e.pushed++
+	return nil
+}
+
+func TestPushGroup(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(
+		logger,
+		memorystore.NewMemoryStore(logger),
+		make(chan *healthcheck.Result, 10),
+		prom,
+		&Configuration{})
+	if err != nil {
+		t.Fatalf("Error creating the component :\n%v", err)
+	}
+	primary := &testExporter{name: "primary", started: true}
+	fallback := &testExporter{name: "fallback", started: true}
+	component.Exporters["primary"] = primary
+	component.Exporters["fallback"] = fallback
+	group := GroupConfiguration{Name: "group", Primary: "primary", Fallback: "fallback"}
+	result := &healthcheck.Result{Name: "foo", Success: true}
+	component.pushGroup(&group, result)
+	if primary.pushed != 1 || fallback.pushed != 0 {
+		t.Fatalf("The result should only be pushed to the primary exporter")
+	}
+	primary.fail = true
+	component.pushGroup(&group, result)
+	if primary.pushed != 1 || fallback.pushed != 1 {
+		t.Fatalf("The result should be pushed to the fallback exporter")
+	}
+	if primary.IsStarted() {
+		t.Fatalf("The primary exporter should be stopped")
+	}
+	primary.fail = false
+	component.pushGroup(&group, result)
+	if primary.pushed != 2 || fallback.pushed != 1 {
+		t.Fatalf("The result should be pushed to the primary exporter after its recovery")
+	}
+}
+
+func TestValidateGroups(t *testing.T) {
+	exporters := map[string]Exporter{
+		"a": &testExporter{name: "a"},
+		"b": &testExporter{name: "b"},
+		"c": &testExporter{name: "c"},
+	}
+	_, err := validateGroups([]GroupConfiguration{{Name: "g", Primary: "a", Fallback: "b"}}, exporters)
+	if err != nil {
+		t.Fatalf("Invalid groups:\n%v", err)
+	}
+	cases := [][]GroupConfiguration{
+		{{Name: "g", Primary: "a", Fallback: "a"}},
+		{{Name: "g", Primary: "a", Fallback: "d"}},
+		{{Name: "", Primary: "a", Fallback: "b"}},
+		{{Name: "g", Primary: "a", Fallback: "b"}, {Name: "h", Primary: "b", Fallback: "c"}},
+	}
+	for _, c := range cases {
+		_, err := validateGroups(c, exporters)
+		if err == nil {
+			t.Fatalf("Was expecting an error for:\n%v", c)
+		}
+	}
+}
+
+func TestConcurrentPushes(t *testing.T) {
+	mutex := &sync.Mutex{}
+	inFlight := 0
+	maxInFlight := 0
+	count := 0
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mutex.Unlock()
+		<-release
+		mutex.Lock()
+		inFlight--
+		count++
+		mutex.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("Error getting HTTP server port :\n%v", err)
+	}
+	chanResult := make(chan *healthcheck.Result, 10)
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(
+		logger,
+		memorystore.NewMemoryStore(logger),
+		chanResult,
+		prom,
+		&Configuration{
+			HTTP: []HTTPConfiguration{
+				{
+					Name:                "foo",
+					Port:                uint32(port),
+					Protocol:            healthcheck.HTTP,
+					MaxConcurrentPushes: 3,
+				},
+			}})
+	if err != nil {
+		t.Fatalf("Error creating the component :\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Error starting the component :\n%v", err)
+	}
+	for i := 0; i < 8; i++ {
+		chanResult <- &healthcheck.Result{
+			Name:                 "foo",
+			Success:              true,
+			HealthcheckTimestamp: time.Now().Unix(),
+		}
+	}
+	// the workers are all blocked by the server
+	for i := 0; i < 20; i++ {
+		mutex.Lock()
+		current := inFlight
+		mutex.Unlock()
+		if current == 3 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	mutex.Lock()
+	if inFlight != 3 {
+		t.Fatalf("Was expecting 3 concurrent pushes, got %d", inFlight)
+	}
+	mutex.Unlock()
+	close(release)
+	close(chanResult)
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+	if count != 8 || maxInFlight != 3 {
+		t.Fatalf("Invalid pushes count %d or concurrency %d", count, maxInFlight)
+	}
+}
+
+func TestStopUnregistersCounters(t *testing.T) {
+	logger := zap.NewExample()
+	promComponent, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	chanResult := make(chan *healthcheck.Result, 10)
+	component, err := New(logger, memorystore.NewMemoryStore(logger), chanResult, promComponent, &Configuration{})
+	if err != nil {
+		t.Fatalf("Error creating the component :\n%v", err)
+	}
+	if err := component.Start(); err != nil {
+		t.Fatalf("Error starting the component :\n%v", err)
+	}
+	close(chanResult)
+	if err := component.Stop(); err != nil {
+		t.Fatalf("Error stopping the component :\n%v", err)
+	}
+	counters := []struct {
+		name   string
+		help   string
+		labels []string
+	}{
+		{
+			name:   "pubsub_exporter_messages_total",
+			help:   "Count the number of messages published or dropped by the Pub/Sub exporters.",
+			labels: []string{"name", "status"},
+		},
+		{
+			name:   "kafka_exporter_records_total",
+			help:   "Count the number of records produced or failed by the Kafka exporters, by topic.",
+			labels: []string{"name", "topic", "status"},
+		},
+		{
+			name:   "cloudwatch_exporter_metrics_total",
+			help:   "Count the number of metrics sent or dropped by the CloudWatch exporters.",
+			labels: []string{"name", "status"},
+		},
+		{
+			name:   "sql_exporter_results_total",
+			help:   "Count the number of results inserted or dropped by the SQL exporters.",
+			labels: []string{"name", "status"},
+		},
+	}
+	for _, c := range counters {
+		counter := prom.NewCounterVec(prom.CounterOpts{Name: c.name, Help: c.help}, c.labels)
+		if err := promComponent.Register(counter); err != nil {
+			t.Fatalf("The counter %s should be unregistered: %v", c.name, err)
+		}
+	}
+}
+
+func TestNewAfterStop(t *testing.T) {
+	logger := zap.NewExample()
+	promComponent, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	chanResult := make(chan *healthcheck.Result, 10)
+	component, err := New(logger, memorystore.NewMemoryStore(logger), chanResult, promComponent, &Configuration{})
+	if err != nil {
+		t.Fatalf("Error creating the component :\n%v", err)
+	}
+	if err := component.Start(); err != nil {
+		t.Fatalf("Error starting the component :\n%v", err)
+	}
+	close(chanResult)
+	if err := component.Stop(); err != nil {
+		t.Fatalf("Error stopping the component :\n%v", err)
+	}
+	_, err = New(logger, memorystore.NewMemoryStore(logger), make(chan *healthcheck.Result, 10), promComponent, &Configuration{})
+	if err != nil {
+		t.Fatalf("Error creating the component again on the same registry :\n%v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package exporter
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// SamplingConfiguration the sampling policy of the exporters. When the
+// number of results waiting in the result channel is greater than the high
+// water mark, successful results are not pushed to the exporters. Failures
+// are always pushed.
+type SamplingConfiguration struct {
+	HighWaterMark int `yaml:"high-water-mark"`
+}
+
+// UnmarshalYAML parses the sampling configuration from YAML.
+func (c *SamplingConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration SamplingConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the sampling configuration")
+	}
+	if raw.HighWaterMark < 0 {
+		return errors.New("The sampling high water mark should be positive")
+	}
+	*c = SamplingConfiguration(raw)
+	return nil
+}
+
+// sampledOut returns true if the result should not be pushed to the exporters
+func (c *Component) sampledOut(message *healthcheck.Result) bool {
+	mark := c.Config.Sampling.HighWaterMark
+	if mark == 0 || !message.Success || len(c.ChanResult) <= mark {
+		return false
+	}
+	c.sampledCounter.WithLabelValues().Inc()
+	return true
+}
@@ -0,0 +1,47 @@
+package exporter
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+	"github.com/mcorbin/cabourotte/memorystore"
+	"github.com/mcorbin/cabourotte/prometheus"
+)
+
+func TestSampledOut(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	chanResult := make(chan *healthcheck.Result, 10)
+	component, err := New(
+		logger,
+		memorystore.NewMemoryStore(logger),
+		chanResult,
+		prom,
+		&Configuration{Sampling: SamplingConfiguration{HighWaterMark: 2}})
+	if err != nil {
+		t.Fatalf("Error creating the component :\n%v", err)
+	}
+	success := &healthcheck.Result{Name: "foo", Success: true}
+	failure := &healthcheck.Result{Name: "foo", Success: false}
+	if component.sampledOut(success) {
+		t.Fatalf("The result should not be sampled out below the high water mark")
+	}
+	for i := 0; i < 3; i++ {
+		chanResult <- success
+	}
+	if !component.sampledOut(success) {
+		t.Fatalf("Successful results should be sampled out above the high water mark")
+	}
+	if component.sampledOut(failure) {
+		t.Fatalf("Failures should never be sampled out")
+	}
+	component.Config.Sampling.HighWaterMark = 0
+	if component.sampledOut(success) {
+		t.Fatalf("The sampling should be disabled")
+	}
+}
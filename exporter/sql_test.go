@@ -0,0 +1,103 @@
+package exporter
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+func TestSQLConfiguration(t *testing.T) {
+	invalid := []string{
+		"driver: postgres\ndsn: postgres://user@localhost/db\n",
+		"name: sql\ndriver: sqlite\ndsn: results.db\n",
+		"name: sql\ndriver: mysql\ndsn: postgres://user@localhost/db\n",
+		"name: sql\ndriver: postgres\ndsn: localhost\n",
+		"name: sql\ndriver: postgres\ndsn: postgres://user@localhost/db\ntable: \"results; DROP TABLE foo\"\n",
+		"name: sql\ndriver: postgres\ndsn: postgres://user@localhost/db\nbatch-size: 1001\n",
+	}
+	for _, c := range invalid {
+		var config SQLConfiguration
+		err := yaml.Unmarshal([]byte(c), &config)
+		if err == nil {
+			t.Fatalf("Was expected an error when decoding the configuration: \n%s", c)
+		}
+	}
+	var config SQLConfiguration
+	err := yaml.Unmarshal([]byte("name: sql\ndriver: postgres\ndsn: postgres://user@localhost/db\ntable: monitoring.results\n"), &config)
+	if err != nil {
+		t.Fatalf("Fail to parse the configuration\n%v", err)
+	}
+	if config.Table != "monitoring.results" || config.BatchSize != DefaultSQLBatchSize || config.BatchDelay != DefaultSQLBatchDelay {
+		t.Fatalf("Invalid configuration %+v", config)
+	}
+	queries := createTableQueries(config.Table)
+	if !strings.HasPrefix(queries[0], "CREATE TABLE IF NOT EXISTS monitoring.results (") {
+		t.Fatalf("Invalid query %s", queries[0])
+	}
+	if queries[1] != "CREATE INDEX IF NOT EXISTS results_name_timestamp_idx ON monitoring.results (name, timestamp)" {
+		t.Fatalf("Invalid query %s", queries[1])
+	}
+}
+
+func TestSQLInsertQuery(t *testing.T) {
+	date := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	first, err := resultRow(&healthcheck.Result{Name: "foo", Success: true, Duration: 0.5, Source: "configuration", Timestamp: date, Labels: map[string]string{"env": "prod"}})
+	if err != nil {
+		t.Fatalf("Fail to convert the result\n%v", err)
+	}
+	second, err := resultRow(&healthcheck.Result{Name: "bar", Message: "timeout", Source: "api", Timestamp: date})
+	if err != nil {
+		t.Fatalf("Fail to convert the result\n%v", err)
+	}
+	query, args := insertQuery("results", [][]interface{}{first, second})
+	expected := "INSERT INTO results (name, success, message, duration, timestamp, source, labels, metadata) VALUES ($1, $2, $3, $4, $5, $6, $7, $8), ($9, $10, $11, $12, $13, $14, $15, $16)"
+	if query != expected {
+		t.Fatalf("Invalid query %s", query)
+	}
+	if len(args) != 16 || args[0] != "foo" || args[6] != `{"env":"prod"}` || args[7] != nil || args[8] != "bar" || args[10] != "timeout" || args[14] != nil {
+		t.Fatalf("Invalid arguments %v", args)
+	}
+	if args[4] != date {
+		t.Fatalf("Invalid timestamp %v", args[4])
+	}
+}
+
+func TestSQLExporterStartFailure(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to listen\n%v", err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	config := SQLConfiguration{
+		Name:    "sql",
+		Driver:  "postgres",
+		DSN:     "postgres://cabourotte@" + address + "/results",
+		Timeout: healthcheck.Duration(time.Second),
+	}
+	counter := prom.NewCounterVec(prom.CounterOpts{Name: "test"}, []string{"name", "status"})
+	exporter, err := NewSQLExporter(zap.NewExample(), &config, counter)
+	if err != nil {
+		t.Fatalf("Fail to create the exporter\n%v", err)
+	}
+	if err := exporter.Start(); err == nil {
+		t.Fatalf("Was expecting a connection error")
+	}
+	if exporter.IsStarted() {
+		t.Fatalf("The exporter should not be started")
+	}
+	// the results are kept until the exporter is reconnected
+	if err := exporter.Push(&healthcheck.Result{Name: "foo"}); err != nil {
+		t.Fatalf("Fail to push\n%v", err)
+	}
+	if err := exporter.Stop(); err == nil || !strings.Contains(err.Error(), "1 results dropped") {
+		t.Fatalf("Was expecting the pending results to be dropped, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package exporter
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// StartupCheck how the exporters destinations are verified at startup
+type StartupCheck string
+
+const (
+	// StartupCheckNone the destinations are not verified
+	StartupCheckNone StartupCheck = ""
+	// StartupCheckWarn an unreachable destination is logged
+	StartupCheckWarn StartupCheck = "warn"
+	// StartupCheckFail an unreachable destination fails the startup
+	StartupCheckFail StartupCheck = "fail"
+)
+
+// UnmarshalYAML parses the startup check mode from YAML
+func (s *StartupCheck) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw string
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the exporters startup check")
+	}
+	mode := StartupCheck(raw)
+	if mode != StartupCheckNone && mode != StartupCheckWarn && mode != StartupCheckFail {
+		return fmt.Errorf("Invalid exporters startup check %s, should be warn or fail", raw)
+	}
+	*s = mode
+	return nil
+}
+
+// prober is implemented by the exporters able to verify that their
+// destination is reachable. The exporters connecting to their destination
+// in Start (Kafka, SQL, MQTT, Riemann) are verified by their Start error.
+type prober interface {
+	probe() error
+}
+
+// probeURL sends a HEAD request to an URL. Any response means the
+// destination is reachable.
+func probeURL(client *http.Client, url string) error {
+	req, err := http.NewRequest("HEAD", url, nil)
+	if err != nil {
+		return errors.Wrapf(err, "fail to create the probe request for %s", url)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "%s is unreachable", url)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// startExporter starts an exporter and verifies its destination depending of
+// the startup check mode
+func (c *Component) startExporter(exporter Exporter) error {
+	err := exporter.Start()
+	if err == nil && c.Config.StartupCheck != StartupCheckNone {
+		if p, ok := exporter.(prober); ok {
+			err = p.probe()
+		}
+	}
+	if err == nil {
+		return nil
+	}
+	if c.Config.StartupCheck == StartupCheckFail {
+		return errors.Wrapf(err, "the exporter %s startup check failed", exporter.Name())
+	}
+	if c.Config.StartupCheck == StartupCheckWarn {
+		c.Logger.Error(fmt.Sprintf("the exporter %s destination is unreachable, results will not be exported until it recovers: %s", exporter.Name(), err.Error()))
+		return nil
+	}
+	// do not return error on purpose, clients should be able to reconnect
+	c.Logger.Error(fmt.Sprintf("fail to create the exporter %s: %s", exporter.Name(), err.Error()))
+	return nil
+}
@@ -0,0 +1,89 @@
+package exporter
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+	"github.com/mcorbin/cabourotte/memorystore"
+	"github.com/mcorbin/cabourotte/prometheus"
+)
+
+func TestStartupCheck(t *testing.T) {
+	method := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	defer ts.Close()
+	reachable, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("Error getting HTTP server port :\n%v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to listen: %v", err)
+	}
+	unreachable := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	cases := []struct {
+		mode    StartupCheck
+		port    uint32
+		failure bool
+	}{
+		{mode: StartupCheckFail, port: uint32(reachable)},
+		{mode: StartupCheckFail, port: uint32(unreachable), failure: true},
+		{mode: StartupCheckWarn, port: uint32(unreachable)},
+		{mode: StartupCheckNone, port: uint32(unreachable)},
+	}
+	for _, c := range cases {
+		logger := zap.NewExample()
+		prom, err := prometheus.New()
+		if err != nil {
+			t.Fatalf("Error creating prometheus component :\n%v", err)
+		}
+		chanResult := make(chan *healthcheck.Result, 10)
+		component, err := New(
+			logger,
+			memorystore.NewMemoryStore(logger),
+			chanResult,
+			prom,
+			&Configuration{
+				StartupCheck: c.mode,
+				HTTP: []HTTPConfiguration{
+					{
+						Name:     "foo",
+						Host:     "127.0.0.1",
+						Port:     c.port,
+						Protocol: healthcheck.HTTP,
+					},
+				}})
+		if err != nil {
+			t.Fatalf("Error creating the component :\n%v", err)
+		}
+		err = component.Start()
+		if c.failure != (err != nil) {
+			t.Fatalf("Invalid startup check result for %v: %v", c, err)
+		}
+		if err == nil {
+			close(chanResult)
+			if err := component.Stop(); err != nil {
+				t.Fatalf("Fail to stop the component: %v", err)
+			}
+		}
+	}
+	if method != "HEAD" {
+		t.Fatalf("The destination should be probed with a HEAD request")
+	}
+	var mode StartupCheck
+	if err := yaml.Unmarshal([]byte("maybe"), &mode); err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
@@ -0,0 +1,257 @@
+package exporter
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// DefaultStatsDPrefix the default prefix of the StatsD metrics
+const DefaultStatsDPrefix = "cabourotte"
+
+// DefaultStatsDTimeout the default timeout of the StatsD exporter
+const DefaultStatsDTimeout = 5 * time.Second
+
+// statsDInvalidChars the characters replaced in the StatsD metrics names and
+// tags
+var statsDInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_.\-/]`)
+
+// StatsDConfiguration the StatsD exporter configuration
+type StatsDConfiguration struct {
+	Name string
+	Host string
+	Port uint32
+	// udp (the default) or tcp
+	Protocol string
+	// the prefix of the metrics names
+	Prefix string
+	// sends the healthcheck name and labels as DogStatsD tags instead of
+	// adding the name to the metrics names
+	DogStatsD bool `json:"dogstatsd" yaml:"dogstatsd"`
+	// the sample rate of the counts and timings, between 0 and 1
+	SampleRate float64              `json:"sample-rate,omitempty" yaml:"sample-rate,omitempty"`
+	Timeout    healthcheck.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// the delivery of the results, asynchronous by default
+	Delivery `json:",inline" yaml:",inline"`
+}
+
+// StatsDExporter the StatsD exporter struct
+type StatsDExporter struct {
+	startedState
+	Logger  *zap.Logger
+	Config  *StatsDConfiguration
+	conn    net.Conn
+	address string
+	timeout time.Duration
+}
+
+// UnmarshalYAML parses the configuration of the StatsD exporter from YAML.
+func (c *StatsDConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration StatsDConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read StatsD exporter configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid name for the StatsD exporter configuration")
+	}
+	if raw.Host == "" {
+		return errors.New("Invalid host for the StatsD exporter configuration")
+	}
+	if raw.Port == 0 {
+		return errors.New("Invalid port for the StatsD exporter configuration")
+	}
+	if raw.Protocol == "" {
+		raw.Protocol = "udp"
+	}
+	if raw.Protocol != "udp" && raw.Protocol != "tcp" {
+		return fmt.Errorf("Invalid protocol %s for the StatsD exporter, udp or tcp are expected", raw.Protocol)
+	}
+	if raw.Prefix == "" {
+		raw.Prefix = DefaultStatsDPrefix
+	}
+	if raw.SampleRate == 0 {
+		raw.SampleRate = 1
+	}
+	if raw.SampleRate < 0 || raw.SampleRate > 1 {
+		return errors.New("The StatsD exporter sample rate should be between 0 and 1")
+	}
+	if raw.Timeout < 0 {
+		return errors.New("The StatsD exporter timeout should be positive")
+	}
+	*c = StatsDConfiguration(raw)
+	return nil
+}
+
+// NewStatsDExporter creates a new StatsD exporter
+func NewStatsDExporter(logger *zap.Logger, config *StatsDConfiguration) *StatsDExporter {
+	timeout := time.Duration(config.Timeout)
+	if timeout == 0 {
+		timeout = DefaultStatsDTimeout
+	}
+	return &StatsDExporter{
+		Logger:  logger,
+		Config:  config,
+		address: net.JoinHostPort(config.Host, fmt.Sprintf("%d", config.Port)),
+		timeout: timeout,
+	}
+}
+
+// protocol returns the protocol used to send the metrics
+func (c *StatsDExporter) protocol() string {
+	if c.Config.Protocol == "" {
+		return "udp"
+	}
+	return c.Config.Protocol
+}
+
+// IsStarted returns the exporter status
+func (c *StatsDExporter) IsStarted() bool {
+	return c.started()
+}
+
+// Start starts the StatsD exporter component
+func (c *StatsDExporter) Start() error {
+	c.Logger.Info(fmt.Sprintf("Starting the StatsD exporter %s on %s", c.Config.Name, c.address))
+	conn, err := net.DialTimeout(c.protocol(), c.address, c.timeout)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to connect to the StatsD server %s", c.address)
+	}
+	c.conn = conn
+	c.setStarted(true)
+	return nil
+}
+
+// Reconnect reconnects the StatsD exporter component
+func (c *StatsDExporter) Reconnect() error {
+	c.Logger.Info(fmt.Sprintf("Reconnecting the StatsD exporter %s", c.Config.Name))
+	if c.conn != nil {
+		// nolint
+		c.conn.Close()
+		c.conn = nil
+	}
+	return c.Start()
+}
+
+// Stop stops the StatsD exporter component
+func (c *StatsDExporter) Stop() error {
+	c.Logger.Info(fmt.Sprintf("Stopping the StatsD exporter %s", c.Config.Name))
+	c.setStarted(false)
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	if err != nil {
+		return errors.Wrapf(err, "Fail to close the StatsD connection")
+	}
+	return nil
+}
+
+// Name returns the name of the exporter
+func (c *StatsDExporter) Name() string {
+	return c.Config.Name
+}
+
+// GetConfig returns the config of the exporter
+func (c *StatsDExporter) GetConfig() interface{} {
+	return c.Config
+}
+
+// statsDName sanitizes a metric name or a tag
+func statsDName(name string) string {
+	return statsDInvalidChars.ReplaceAllString(name, "_")
+}
+
+// tags returns the DogStatsD tags of a result, sorted by key
+func (c *StatsDExporter) tags(result *healthcheck.Result) string {
+	if !c.Config.DogStatsD {
+		return ""
+	}
+	tags := []string{
+		fmt.Sprintf("name:%s", statsDName(result.Name)),
+	}
+	if result.Source != "" {
+		tags = append(tags, fmt.Sprintf("source:%s", statsDName(result.Source)))
+	}
+	keys := make([]string, 0, len(result.Labels))
+	for k := range result.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		tags = append(tags, fmt.Sprintf("%s:%s", statsDName(k), statsDName(result.Labels[k])))
+	}
+	return "|#" + strings.Join(tags, ",")
+}
+
+// metrics returns the StatsD lines of a result. The counts and timings are
+// sampled.
+func (c *StatsDExporter) metrics(result *healthcheck.Result) []string {
+	prefix := c.Config.Prefix
+	if prefix == "" {
+		prefix = DefaultStatsDPrefix
+	}
+	prefix = statsDName(prefix) + ".healthcheck."
+	if !c.Config.DogStatsD {
+		prefix = prefix + statsDName(result.Name) + "."
+	}
+	tags := c.tags(result)
+	success := "0"
+	status := "failure"
+	if result.Success {
+		success = "1"
+		status = "success"
+	}
+	lines := []string{
+		fmt.Sprintf("%ssuccess:%s|g%s", prefix, success, tags),
+	}
+	rate := c.Config.SampleRate
+	if rate == 0 {
+		rate = 1
+	}
+	if rate < 1 && rand.Float64() >= rate {
+		return lines
+	}
+	sampling := ""
+	if rate < 1 {
+		sampling = "|@" + strconv.FormatFloat(rate, 'f', -1, 64)
+	}
+	duration := strconv.FormatFloat(result.Duration*1000, 'f', -1, 64)
+	return append(lines,
+		fmt.Sprintf("%s%s:1|c%s%s", prefix, status, sampling, tags),
+		fmt.Sprintf("%sduration:%s|ms%s%s", prefix, duration, sampling, tags))
+}
+
+// Push sends the result metrics to the StatsD server. The UDP write errors
+// are only logged.
+func (c *StatsDExporter) Push(result *healthcheck.Result) error {
+	if c.conn == nil {
+		return errors.New("StatsD exporter: not connected")
+	}
+	payload := strings.Join(c.metrics(result), "\n")
+	if c.protocol() == "tcp" {
+		payload += "\n"
+	}
+	// nolint
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	_, err := c.conn.Write([]byte(payload))
+	if err != nil {
+		if c.protocol() == "udp" {
+			c.Logger.Error(fmt.Sprintf("StatsD exporter %s: fail to send the metrics: %s", c.Config.Name, err.Error()))
+			return nil
+		}
+		return errors.Wrapf(err, "StatsD exporter: fail to send the metrics")
+	}
+	return nil
+}
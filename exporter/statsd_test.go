@@ -0,0 +1,145 @@
+package exporter
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+func TestStatsDExporterUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to listen\n%v", err)
+	}
+	defer conn.Close()
+	exporter := NewStatsDExporter(zap.NewExample(), &StatsDConfiguration{
+		Name:       "statsd",
+		Host:       "127.0.0.1",
+		Port:       uint32(conn.LocalAddr().(*net.UDPAddr).Port),
+		Prefix:     "infra",
+		DogStatsD:  true,
+		SampleRate: 1,
+	})
+	err = exporter.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the StatsD exporter\n%v", err)
+	}
+	err = exporter.Push(&healthcheck.Result{
+		Name:     "foo",
+		Success:  true,
+		Duration: 0.25,
+		Source:   "configuration",
+		Labels:   map[string]string{"env": "prod"},
+	})
+	if err != nil {
+		t.Fatalf("Fail to push the result\n%v", err)
+	}
+	buffer := make([]byte, 1024)
+	// nolint
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	n, _, err := conn.ReadFrom(buffer)
+	if err != nil {
+		t.Fatalf("Fail to read the metrics\n%v", err)
+	}
+	expected := strings.Join([]string{
+		"infra.healthcheck.success:1|g|#name:foo,source:configuration,env:prod",
+		"infra.healthcheck.success:1|c|#name:foo,source:configuration,env:prod",
+		"infra.healthcheck.duration:250|ms|#name:foo,source:configuration,env:prod",
+	}, "\n")
+	if string(buffer[:n]) != expected {
+		t.Fatalf("Invalid metrics\n%s", string(buffer[:n]))
+	}
+	err = exporter.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the StatsD exporter\n%v", err)
+	}
+}
+
+func TestStatsDExporterTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to listen\n%v", err)
+	}
+	defer listener.Close()
+	lines := make(chan string, 10)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	exporter := NewStatsDExporter(zap.NewExample(), &StatsDConfiguration{
+		Name:       "statsd",
+		Host:       "127.0.0.1",
+		Port:       uint32(listener.Addr().(*net.TCPAddr).Port),
+		Protocol:   "tcp",
+		SampleRate: 0.5,
+	})
+	err = exporter.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the StatsD exporter\n%v", err)
+	}
+	defer exporter.Stop()
+	// the gauge is never sampled
+	for i := 0; i < 20; i++ {
+		err = exporter.Push(&healthcheck.Result{Name: "my check", Success: false, Duration: 1})
+		if err != nil {
+			t.Fatalf("Fail to push the result\n%v", err)
+		}
+		select {
+		case line := <-lines:
+			if line != "cabourotte.healthcheck.my_check.success:0|g" {
+				t.Fatalf("Invalid metric %s", line)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("The gauge was not received")
+		}
+		// the sampled metrics, if any
+		for {
+			select {
+			case line := <-lines:
+				if line != "cabourotte.healthcheck.my_check.failure:1|c|@0.5" &&
+					line != "cabourotte.healthcheck.my_check.duration:1000|ms|@0.5" {
+					t.Fatalf("Invalid sampled metric %s", line)
+				}
+				continue
+			case <-time.After(20 * time.Millisecond):
+			}
+			break
+		}
+	}
+}
+
+func TestStatsDConfiguration(t *testing.T) {
+	var config StatsDConfiguration
+	err := yaml.Unmarshal([]byte("name: statsd\nhost: 127.0.0.1\nport: 8125\n"), &config)
+	if err != nil {
+		t.Fatalf("Fail to parse the configuration\n%v", err)
+	}
+	if config.Protocol != "udp" || config.Prefix != DefaultStatsDPrefix || config.SampleRate != 1 {
+		t.Fatalf("Invalid defaults %v", config)
+	}
+	invalid := []string{
+		"name: statsd\nhost: 127.0.0.1\nport: 8125\nprotocol: http\n",
+		"name: statsd\nhost: 127.0.0.1\nport: 8125\nsample-rate: 2\n",
+		"name: statsd\nport: 8125\n",
+	}
+	for _, c := range invalid {
+		err := yaml.Unmarshal([]byte(c), &config)
+		if err == nil {
+			t.Fatalf("Was expected an error when decoding the configuration: \n%s", c)
+		}
+	}
+}
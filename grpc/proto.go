@@ -0,0 +1,108 @@
+package grpc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// maxMessageSize the maximum size of a message sent by clients
+const maxMessageSize = 4096
+
+// appendMap encodes a map as a list of entries, the keys are sorted.
+func appendMap(b []byte, num protowire.Number, m map[string]string) []byte {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var entry []byte
+		entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+		entry = protowire.AppendString(entry, k)
+		entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+		entry = protowire.AppendString(entry, m[k])
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+	return b
+}
+
+// encodeResult encodes a result using the Result message of cabourotte.proto
+func encodeResult(result *healthcheck.Result) []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, result.Name)
+	if result.Summary != nil {
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendString(b, fmt.Sprintf("%v", result.Summary))
+	}
+	b = protowire.AppendTag(b, 3, protowire.VarintType)
+	b = protowire.AppendVarint(b, protowire.EncodeBool(result.Success))
+	b = protowire.AppendTag(b, 4, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(result.HealthcheckTimestamp))
+	b = protowire.AppendTag(b, 5, protowire.BytesType)
+	b = protowire.AppendString(b, result.Message)
+	b = protowire.AppendTag(b, 6, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, math.Float64bits(result.Duration))
+	b = protowire.AppendTag(b, 7, protowire.BytesType)
+	b = protowire.AppendString(b, result.Source)
+	b = appendMap(b, 8, result.Labels)
+	b = appendMap(b, 9, result.Metadata)
+	b = protowire.AppendTag(b, 10, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(result.Time().UnixNano()))
+	if result.Sequence != 0 {
+		b = protowire.AppendTag(b, 11, protowire.VarintType)
+		b = protowire.AppendVarint(b, result.Sequence)
+		b = protowire.AppendTag(b, 12, protowire.BytesType)
+		b = protowire.AppendString(b, result.Epoch)
+	}
+	return b
+}
+
+// encodeSnapshot encodes results using the SnapshotResponse message of cabourotte.proto
+func encodeSnapshot(results []healthcheck.Result) []byte {
+	var b []byte
+	for i := range results {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, encodeResult(&results[i]))
+	}
+	return b
+}
+
+// writeMessage writes a length-prefixed gRPC message
+func writeMessage(w io.Writer, message []byte) error {
+	header := make([]byte, 5)
+	binary.BigEndian.PutUint32(header[1:], uint32(len(message)))
+	_, err := w.Write(append(header, message...))
+	return err
+}
+
+// readMessage reads a length-prefixed gRPC message
+func readMessage(r io.Reader) ([]byte, error) {
+	header := make([]byte, 5)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return nil, err
+	}
+	if header[0] != 0 {
+		return nil, errors.New("Compressed messages are not supported")
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > maxMessageSize {
+		return nil, fmt.Errorf("The message size %d is too large", size)
+	}
+	message := make([]byte, size)
+	_, err = io.ReadFull(r, message)
+	if err != nil {
+		return nil, err
+	}
+	return message, nil
+}
@@ -0,0 +1,229 @@
+package grpc
+
+import (
+	"context"
+	cryptotls "crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+const (
+	subscribePath = "/cabourotte.Results/Subscribe"
+	snapshotPath  = "/cabourotte.Results/Snapshot"
+
+	statusOK          = "0"
+	statusInvalid     = "3"
+	statusUnavailable = "14"
+	statusUnimplement = "12"
+)
+
+// subscriber a client of the Subscribe RPC
+type subscriber struct {
+	results chan *healthcheck.Result
+}
+
+// Server a gRPC server streaming the healthchecks results
+type Server struct {
+	Logger     *zap.Logger
+	Address    string
+	TLSConfig  *cryptotls.Config
+	BufferSize int
+
+	snapshot    func() []healthcheck.Result
+	dropped     prom.Counter
+	server      *http.Server
+	lock        sync.Mutex
+	subscribers map[*subscriber]bool
+	done        chan struct{}
+	wg          sync.WaitGroup
+}
+
+// New creates a new gRPC server. The snapshot function returns the current
+// state of the healthchecks, the dropped counter is incremented when a slow
+// subscriber loses a result.
+func New(logger *zap.Logger, address string, tlsConfig *cryptotls.Config, bufferSize int, snapshot func() []healthcheck.Result, dropped prom.Counter) *Server {
+	return &Server{
+		Logger:      logger,
+		Address:     address,
+		TLSConfig:   tlsConfig,
+		BufferSize:  bufferSize,
+		snapshot:    snapshot,
+		dropped:     dropped,
+		subscribers: make(map[*subscriber]bool),
+	}
+}
+
+// Start starts the gRPC server
+func (s *Server) Start() error {
+	s.Logger.Info(fmt.Sprintf("Starting the gRPC server on %s", s.Address))
+	listener, err := net.Listen("tcp", s.Address)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to listen on %s", s.Address)
+	}
+	s.done = make(chan struct{})
+	http2Server := &http2.Server{}
+	var handler http.Handler = http.HandlerFunc(s.handle)
+	if s.TLSConfig == nil {
+		handler = h2c.NewHandler(handler, http2Server)
+	}
+	s.server = &http.Server{
+		Handler:   handler,
+		TLSConfig: s.TLSConfig,
+	}
+	if s.TLSConfig != nil {
+		err = http2.ConfigureServer(s.server, http2Server)
+		if err != nil {
+			listener.Close()
+			return errors.Wrapf(err, "Fail to configure the gRPC server")
+		}
+		listener = cryptotls.NewListener(listener, s.server.TLSConfig)
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		err := s.server.Serve(listener)
+		if err != http.ErrServerClosed {
+			s.Logger.Error(fmt.Sprintf("gRPC server error: %s", err.Error()))
+		}
+	}()
+	return nil
+}
+
+// Stop stops the gRPC server
+func (s *Server) Stop() error {
+	s.Logger.Info("Stopping the gRPC server")
+	if s.server == nil {
+		return nil
+	}
+	close(s.done)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := s.server.Shutdown(ctx)
+	s.wg.Wait()
+	s.server = nil
+	if err != nil {
+		return errors.Wrapf(err, "Fail to stop the gRPC server")
+	}
+	return nil
+}
+
+// Broadcast sends a result to all subscribers. If the buffer of a
+// subscriber is full, its oldest result is dropped.
+func (s *Server) Broadcast(result *healthcheck.Result) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for sub := range s.subscribers {
+		select {
+		case sub.results <- result:
+			continue
+		default:
+		}
+		select {
+		case <-sub.results:
+			s.dropped.Inc()
+		default:
+		}
+		select {
+		case sub.results <- result:
+		default:
+			s.dropped.Inc()
+		}
+	}
+}
+
+func (s *Server) subscribe() *subscriber {
+	sub := &subscriber{
+		results: make(chan *healthcheck.Result, s.BufferSize),
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.subscribers[sub] = true
+	return sub
+}
+
+func (s *Server) unsubscribe(sub *subscriber) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.subscribers, sub)
+}
+
+// handle handles gRPC requests
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	if r.URL.Path != subscribePath && r.URL.Path != snapshotPath {
+		w.WriteHeader(http.StatusOK)
+		setStatus(w, statusUnimplement, fmt.Sprintf("unknown method %s", r.URL.Path))
+		return
+	}
+	_, err := readMessage(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusOK)
+		setStatus(w, statusInvalid, "invalid request message")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.URL.Path == snapshotPath {
+		err = writeMessage(w, encodeSnapshot(s.snapshot()))
+		if err != nil {
+			s.Logger.Error(fmt.Sprintf("gRPC server: fail to send the snapshot: %s", err.Error()))
+			return
+		}
+		setStatus(w, statusOK, "")
+		return
+	}
+	s.stream(w, r)
+}
+
+// stream sends the results to a subscriber until the client or the server
+// stops
+func (s *Server) stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		setStatus(w, statusUnavailable, "streaming is not supported")
+		return
+	}
+	flusher.Flush()
+	sub := s.subscribe()
+	defer s.unsubscribe(sub)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			setStatus(w, statusUnavailable, "server shutting down")
+			return
+		case result := <-sub.results:
+			err := writeMessage(w, encodeResult(result))
+			if err != nil {
+				s.Logger.Debug(fmt.Sprintf("gRPC server: fail to send a result: %s", err.Error()))
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// setStatus sets the gRPC status trailers
+func setStatus(w http.ResponseWriter, status string, message string) {
+	w.Header().Set("Grpc-Status", status)
+	if message != "" {
+		w.Header().Set("Grpc-Message", message)
+	}
+}
@@ -0,0 +1,145 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// decodeName returns the name field of an encoded result
+func decodeName(t *testing.T, b []byte) string {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatalf("Invalid message")
+		}
+		b = b[n:]
+		if num == 1 && typ == protowire.BytesType {
+			v, _ := protowire.ConsumeString(b)
+			return v
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			t.Fatalf("Invalid message")
+		}
+		b = b[n:]
+	}
+	return ""
+}
+
+func newClient() *http.Client {
+	return &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+				return net.Dial(network, addr)
+			},
+		},
+	}
+}
+
+func call(ctx context.Context, t *testing.T, address string, path string) *http.Response {
+	var body bytes.Buffer
+	err := writeMessage(&body, []byte{})
+	if err != nil {
+		t.Fatalf("Fail to write the request message: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+address+path, &body)
+	if err != nil {
+		t.Fatalf("Fail to create the request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	resp, err := newClient().Do(req)
+	if err != nil {
+		t.Fatalf("Fail to send the request: %v", err)
+	}
+	return resp
+}
+
+func TestServer(t *testing.T) {
+	dropped := prom.NewCounter(prom.CounterOpts{Name: "dropped"})
+	snapshot := func() []healthcheck.Result {
+		return []healthcheck.Result{{Name: "foo"}, {Name: "bar"}}
+	}
+	server := New(zap.NewExample(), "127.0.0.1:2010", nil, 2, snapshot, dropped)
+	err := server.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the server: %v", err)
+	}
+	resp := call(context.Background(), t, server.Address, snapshotPath)
+	message, err := readMessage(resp.Body)
+	if err != nil {
+		t.Fatalf("Fail to read the snapshot: %v", err)
+	}
+	// the trailers are available once the body is consumed
+	_, err = io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		t.Fatalf("Fail to read the response: %v", err)
+	}
+	resp.Body.Close()
+	if resp.Trailer.Get("Grpc-Status") != statusOK {
+		t.Fatalf("Invalid status %s", resp.Trailer.Get("Grpc-Status"))
+	}
+	count := 0
+	for len(message) > 0 {
+		_, _, n := protowire.ConsumeField(message)
+		message = message[n:]
+		count++
+	}
+	if count != 2 {
+		t.Fatalf("Invalid snapshot size %d", count)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := call(ctx, t, server.Address, subscribePath)
+	defer stream.Body.Close()
+	for i := 0; i < 50; i++ {
+		server.lock.Lock()
+		subscribers := len(server.subscribers)
+		server.lock.Unlock()
+		if subscribers == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	server.Broadcast(&healthcheck.Result{Name: "baz"})
+	message, err = readMessage(stream.Body)
+	if err != nil {
+		t.Fatalf("Fail to read the result: %v", err)
+	}
+	if decodeName(t, message) != "baz" {
+		t.Fatalf("Invalid result %s", decodeName(t, message))
+	}
+	cancel()
+	err = server.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the server: %v", err)
+	}
+}
+
+func TestBroadcastDropOldest(t *testing.T) {
+	dropped := prom.NewCounter(prom.CounterOpts{Name: "dropped"})
+	server := New(zap.NewExample(), "127.0.0.1:0", nil, 2, nil, dropped)
+	sub := server.subscribe()
+	for _, name := range []string{"a", "b", "c"} {
+		server.Broadcast(&healthcheck.Result{Name: name})
+	}
+	first := <-sub.results
+	second := <-sub.results
+	if first.Name != "b" || second.Name != "c" {
+		t.Fatalf("The oldest result should be dropped: %s %s", first.Name, second.Name)
+	}
+}
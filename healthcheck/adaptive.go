@@ -0,0 +1,101 @@
+package healthcheck
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// AdaptiveInterval executes the failing healthchecks more frequently, and
+// backs off exponentially while the healthcheck is successful
+type AdaptiveInterval struct {
+	// the interval after a failure, before the backoff. Defaults to the
+	// healthcheck interval.
+	BaseInterval Duration `json:"base-interval,omitempty" yaml:"base-interval,omitempty"`
+	// the interval while the healthcheck is failing
+	FailureInterval Duration `json:"failure-interval" yaml:"failure-interval"`
+	// the interval is doubled on each success up to this value
+	MaxInterval Duration `json:"max-interval" yaml:"max-interval"`
+}
+
+// Validate validates the adaptive interval of an healthcheck having the
+// given interval and timeout
+func (a *AdaptiveInterval) Validate(interval Duration, timeout Duration) error {
+	if a == nil {
+		return nil
+	}
+	base := a.base(interval)
+	if a.FailureInterval < Duration(2*time.Second) {
+		return errors.New("The healthcheck failure-interval should be greater than 2 second")
+	}
+	if a.FailureInterval < timeout {
+		return errors.New("The healthcheck failure-interval should be greater than the timeout")
+	}
+	if base < a.FailureInterval {
+		return errors.New("The healthcheck base-interval should be greater than the failure-interval")
+	}
+	if a.MaxInterval < base {
+		return errors.New("The healthcheck max-interval should be greater than the base-interval")
+	}
+	return nil
+}
+
+// base returns the base interval
+func (a *AdaptiveInterval) base(interval Duration) Duration {
+	if a.BaseInterval != 0 {
+		return a.BaseInterval
+	}
+	return interval
+}
+
+// next returns the interval following an execution, from the current one
+func (a *AdaptiveInterval) next(interval Duration, current time.Duration, success bool) time.Duration {
+	if !success {
+		return time.Duration(a.FailureInterval)
+	}
+	base := time.Duration(a.base(interval))
+	if current < base {
+		return base
+	}
+	next := current * 2
+	if next > time.Duration(a.MaxInterval) {
+		return time.Duration(a.MaxInterval)
+	}
+	return next
+}
+
+// initialInterval returns the interval of the first executions
+func initialInterval(base Base) time.Duration {
+	if base.AdaptiveInterval != nil {
+		return time.Duration(base.AdaptiveInterval.base(base.Interval))
+	}
+	return time.Duration(base.Interval)
+}
+
+// adapt updates the interval of the wrapper from the outcome of an execution
+func (w *Wrapper) adapt(success bool) {
+	base := w.healthcheck.Base()
+	if base.AdaptiveInterval == nil {
+		return
+	}
+	next := base.AdaptiveInterval.next(base.Interval, w.effective, success)
+	if next != w.effective {
+		w.healthcheck.LogDebug("interval changed to " + next.String())
+		w.effective = next
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdaptiveInterval) DeepCopyInto(out *AdaptiveInterval) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdaptiveInterval.
+func (in *AdaptiveInterval) DeepCopy() *AdaptiveInterval {
+	if in == nil {
+		return nil
+	}
+	out := new(AdaptiveInterval)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,62 @@
+package healthcheck
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveIntervalNext(t *testing.T) {
+	adaptive := &AdaptiveInterval{
+		FailureInterval: Duration(2 * time.Second),
+		MaxInterval:     Duration(30 * time.Second),
+	}
+	interval := Duration(5 * time.Second)
+	current := time.Duration(interval)
+	cases := []struct {
+		success  bool
+		expected time.Duration
+	}{
+		{success: true, expected: 10 * time.Second},
+		{success: true, expected: 20 * time.Second},
+		{success: true, expected: 30 * time.Second},
+		{success: true, expected: 30 * time.Second},
+		{success: false, expected: 2 * time.Second},
+		{success: false, expected: 2 * time.Second},
+		{success: true, expected: 5 * time.Second},
+		{success: true, expected: 10 * time.Second},
+	}
+	for i, c := range cases {
+		current = adaptive.next(interval, current, c.success)
+		if current != c.expected {
+			t.Fatalf("Invalid interval %s for the execution %d, expected %s", current, i, c.expected)
+		}
+	}
+}
+
+func TestAdaptiveIntervalValidate(t *testing.T) {
+	interval := Duration(10 * time.Second)
+	timeout := Duration(3 * time.Second)
+	invalid := []AdaptiveInterval{
+		{FailureInterval: Duration(time.Second), MaxInterval: Duration(time.Minute)},
+		{FailureInterval: Duration(2 * time.Second), MaxInterval: Duration(time.Minute)},
+		{FailureInterval: Duration(20 * time.Second), MaxInterval: Duration(time.Minute)},
+		{FailureInterval: Duration(5 * time.Second), MaxInterval: Duration(5 * time.Second)},
+		{BaseInterval: Duration(time.Minute), FailureInterval: Duration(5 * time.Second), MaxInterval: Duration(20 * time.Second)},
+	}
+	for i := range invalid {
+		if err := invalid[i].Validate(interval, timeout); err == nil {
+			t.Fatalf("The adaptive interval %d should be invalid", i)
+		}
+	}
+	valid := AdaptiveInterval{FailureInterval: Duration(5 * time.Second), MaxInterval: Duration(time.Minute)}
+	if err := valid.Validate(interval, timeout); err != nil {
+		t.Fatalf("The adaptive interval should be valid\n%v", err)
+	}
+	var disabled *AdaptiveInterval
+	if err := disabled.Validate(interval, timeout); err != nil {
+		t.Fatalf("A missing adaptive interval should be valid\n%v", err)
+	}
+	if initialInterval(Base{Interval: interval, AdaptiveInterval: &AdaptiveInterval{BaseInterval: Duration(20 * time.Second)}}) != 20*time.Second {
+		t.Fatalf("The initial interval should be the base interval")
+	}
+}
@@ -0,0 +1,193 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultAdaptiveTimeoutPercentile the default percentile of the recent
+	// latencies used to compute the timeout
+	DefaultAdaptiveTimeoutPercentile = 95
+	// DefaultAdaptiveTimeoutMultiplier the default multiplier applied to the
+	// percentile
+	DefaultAdaptiveTimeoutMultiplier = 3
+	// DefaultAdaptiveTimeoutWindow the default number of latencies kept
+	DefaultAdaptiveTimeoutWindow = 50
+	// DefaultAdaptiveTimeoutMinSamples the default number of latencies needed
+	// before the timeout is computed
+	DefaultAdaptiveTimeoutMinSamples = 10
+	// maxAdaptiveTimeoutWindow the maximum number of latencies kept
+	maxAdaptiveTimeoutWindow = 1000
+)
+
+// AdaptiveTimeout computes the timeout of the executions from the latencies
+// of the recent successful executions, as a multiple of a percentile bounded
+// by min-timeout and max-timeout. The timeout is max-timeout until enough
+// latencies are collected.
+type AdaptiveTimeout struct {
+	// the percentile of the latencies, between 0 and 100
+	Percentile float64 `json:"percentile,omitempty" yaml:"percentile,omitempty"`
+	// the multiplier applied to the percentile, at least 1
+	Multiplier float64  `json:"multiplier,omitempty" yaml:"multiplier,omitempty"`
+	MinTimeout Duration `json:"min-timeout" yaml:"min-timeout"`
+	MaxTimeout Duration `json:"max-timeout" yaml:"max-timeout"`
+	// the number of successful executions latencies kept
+	Window uint `json:"window,omitempty" yaml:"window,omitempty"`
+	// the number of latencies needed before the timeout is computed
+	MinSamples uint `json:"min-samples,omitempty" yaml:"min-samples,omitempty"`
+}
+
+// Validate validates the adaptive timeout of an healthcheck having the given
+// interval
+func (a *AdaptiveTimeout) Validate(interval Duration) error {
+	if a == nil {
+		return nil
+	}
+	if a.Percentile < 0 || a.Percentile > 100 {
+		return errors.New("The healthcheck adaptive timeout percentile should be between 0 and 100")
+	}
+	if a.Multiplier != 0 && a.Multiplier < 1 {
+		return errors.New("The healthcheck adaptive timeout multiplier should be greater than 1")
+	}
+	if a.MinTimeout <= 0 {
+		return errors.New("The healthcheck adaptive timeout min-timeout should be positive")
+	}
+	if a.MaxTimeout < a.MinTimeout {
+		return errors.New("The healthcheck adaptive timeout max-timeout should be greater than min-timeout")
+	}
+	if a.MaxTimeout > interval {
+		return errors.New("The healthcheck interval should be greater than the adaptive timeout max-timeout")
+	}
+	if a.Window > maxAdaptiveTimeoutWindow {
+		return fmt.Errorf("The healthcheck adaptive timeout window should be lower than %d", maxAdaptiveTimeoutWindow)
+	}
+	if a.MinSamples > a.window() {
+		return errors.New("The healthcheck adaptive timeout min-samples should be lower than the window")
+	}
+	return nil
+}
+
+// window returns the number of latencies kept
+func (a *AdaptiveTimeout) window() uint {
+	if a.Window == 0 {
+		return DefaultAdaptiveTimeoutWindow
+	}
+	return a.Window
+}
+
+// minSamples returns the number of latencies needed to compute the timeout
+func (a *AdaptiveTimeout) minSamples() int {
+	if a.MinSamples != 0 {
+		return int(a.MinSamples)
+	}
+	if a.window() < DefaultAdaptiveTimeoutMinSamples {
+		return int(a.window())
+	}
+	return DefaultAdaptiveTimeoutMinSamples
+}
+
+// compute returns the timeout from the latencies
+func (a *AdaptiveTimeout) compute(latencies []time.Duration) time.Duration {
+	if len(latencies) == 0 || len(latencies) < a.minSamples() {
+		return time.Duration(a.MaxTimeout)
+	}
+	percentile := a.Percentile
+	if percentile == 0 {
+		percentile = DefaultAdaptiveTimeoutPercentile
+	}
+	multiplier := a.Multiplier
+	if multiplier == 0 {
+		multiplier = DefaultAdaptiveTimeoutMultiplier
+	}
+	sorted := make([]time.Duration, len(latencies))
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	// nearest-rank percentile
+	rank := int(math.Ceil(percentile / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	timeout := time.Duration(float64(sorted[rank-1]) * multiplier)
+	if timeout < time.Duration(a.MinTimeout) {
+		return time.Duration(a.MinTimeout)
+	}
+	if timeout > time.Duration(a.MaxTimeout) {
+		return time.Duration(a.MaxTimeout)
+	}
+	return timeout
+}
+
+// latencyWindow the latencies of the recent successful executions of an
+// healthcheck
+type latencyWindow struct {
+	latencies []time.Duration
+	next      int
+}
+
+// add adds a latency, replacing the oldest one once the window is full
+func (l *latencyWindow) add(latency time.Duration, size uint) {
+	if len(l.latencies) < int(size) {
+		l.latencies = append(l.latencies, latency)
+		return
+	}
+	l.latencies[l.next] = latency
+	l.next = (l.next + 1) % len(l.latencies)
+}
+
+// timeoutKey the context key of the execution timeout
+type timeoutKey struct{}
+
+// withExecutionTimeout returns a context overriding the timeout of the
+// healthchecks executed with it
+func withExecutionTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, timeout)
+}
+
+// executionTimeout returns the timeout of an execution, the adaptive timeout
+// if set in the context or the static timeout
+func executionTimeout(ctx context.Context, timeout time.Duration) time.Duration {
+	if adaptive, ok := ctx.Value(timeoutKey{}).(time.Duration); ok {
+		return adaptive
+	}
+	return timeout
+}
+
+// adaptiveTimeout returns the current timeout of the wrapper, ok is false if
+// the timeout is static
+func (w *Wrapper) adaptiveTimeout() (time.Duration, bool) {
+	config := w.healthcheck.Base().AdaptiveTimeout
+	if config == nil {
+		return 0, false
+	}
+	return config.compute(w.latencies.latencies), true
+}
+
+// recordLatency keeps the latency of a successful execution
+func (w *Wrapper) recordLatency(success bool, latency time.Duration) {
+	config := w.healthcheck.Base().AdaptiveTimeout
+	if config == nil || !success {
+		return
+	}
+	w.latencies.add(latency, config.window())
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdaptiveTimeout) DeepCopyInto(out *AdaptiveTimeout) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdaptiveTimeout.
+func (in *AdaptiveTimeout) DeepCopy() *AdaptiveTimeout {
+	if in == nil {
+		return nil
+	}
+	out := new(AdaptiveTimeout)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,131 @@
+package healthcheck
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/prometheus"
+)
+
+func TestAdaptiveTimeoutCompute(t *testing.T) {
+	adaptive := &AdaptiveTimeout{
+		MinTimeout: Duration(100 * time.Millisecond),
+		MaxTimeout: Duration(5 * time.Second),
+		MinSamples: 4,
+	}
+	cases := []struct {
+		latencies []time.Duration
+		expected  time.Duration
+	}{
+		{latencies: nil, expected: 5 * time.Second},
+		{latencies: []time.Duration{time.Millisecond, time.Millisecond}, expected: 5 * time.Second},
+		{latencies: []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond, time.Millisecond}, expected: 100 * time.Millisecond},
+		{latencies: []time.Duration{200 * time.Millisecond, 100 * time.Millisecond, 300 * time.Millisecond, 400 * time.Millisecond}, expected: 1200 * time.Millisecond},
+		{latencies: []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 4 * time.Second}, expected: 5 * time.Second},
+	}
+	for i, c := range cases {
+		timeout := adaptive.compute(c.latencies)
+		if timeout != c.expected {
+			t.Fatalf("Invalid timeout %s for the case %d, expected %s", timeout, i, c.expected)
+		}
+	}
+	window := latencyWindow{}
+	for i := 1; i <= 5; i++ {
+		window.add(time.Duration(i)*time.Second, 3)
+	}
+	if len(window.latencies) != 3 || window.latencies[0] != 4*time.Second || window.latencies[1] != 5*time.Second || window.latencies[2] != 3*time.Second {
+		t.Fatalf("Invalid latencies window %v", window.latencies)
+	}
+}
+
+func TestAdaptiveTimeoutValidate(t *testing.T) {
+	interval := Duration(10 * time.Second)
+	invalid := []AdaptiveTimeout{
+		{MaxTimeout: Duration(time.Second)},
+		{MinTimeout: Duration(2 * time.Second), MaxTimeout: Duration(time.Second)},
+		{MinTimeout: Duration(time.Second), MaxTimeout: Duration(time.Minute)},
+		{MinTimeout: Duration(time.Second), MaxTimeout: Duration(5 * time.Second), Percentile: 150},
+		{MinTimeout: Duration(time.Second), MaxTimeout: Duration(5 * time.Second), Multiplier: 0.5},
+		{MinTimeout: Duration(time.Second), MaxTimeout: Duration(5 * time.Second), Window: 5, MinSamples: 10},
+	}
+	for i := range invalid {
+		if err := invalid[i].Validate(interval); err == nil {
+			t.Fatalf("The adaptive timeout %d should be invalid", i)
+		}
+	}
+	valid := AdaptiveTimeout{MinTimeout: Duration(time.Second), MaxTimeout: Duration(5 * time.Second)}
+	if err := valid.Validate(interval); err != nil {
+		t.Fatalf("The adaptive timeout should be valid\n%v", err)
+	}
+}
+
+type timeoutHealthcheck struct {
+	panicHealthcheck
+	timeouts []time.Duration
+}
+
+func (h *timeoutHealthcheck) Execute(ctx context.Context) error {
+	h.timeouts = append(h.timeouts, executionTimeout(ctx, time.Hour))
+	return nil
+}
+
+func TestAdaptiveTimeoutRun(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	results := make(chan *Result, 10)
+	component, err := New(zap.NewExample(), results, prom)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	check := &timeoutHealthcheck{
+		panicHealthcheck: panicHealthcheck{config: Base{
+			Name:     "foo",
+			Interval: Duration(10 * time.Second),
+			AdaptiveTimeout: &AdaptiveTimeout{
+				MinTimeout: Duration(time.Second),
+				MaxTimeout: Duration(5 * time.Second),
+				MinSamples: 2,
+			},
+		}},
+	}
+	w := NewWrapper(check)
+	for i := 0; i < 3; i++ {
+		component.run(context.Background(), w)
+		result := <-results
+		if result.Metadata["adaptive-timeout"] != check.timeouts[i].String() {
+			t.Fatalf("Invalid metadata %v", result.Metadata)
+		}
+	}
+	// the executions are fast, the timeout is the minimum once enough
+	// latencies are collected
+	if check.timeouts[0] != 5*time.Second || check.timeouts[1] != 5*time.Second || check.timeouts[2] != time.Second {
+		t.Fatalf("Invalid timeouts %v", check.timeouts)
+	}
+	// the latencies are kept when the wrapper is recreated
+	component.run(context.Background(), w.recreate())
+	<-results
+	if check.timeouts[3] != time.Second {
+		t.Fatalf("The latencies should be kept %v", check.timeouts)
+	}
+	families, err := prom.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Fail to gather the metrics\n%v", err)
+	}
+	found := false
+	for _, family := range families {
+		if family.GetName() == "healthcheck_adaptive_timeout_seconds" {
+			found = family.GetMetric()[0].GetGauge().GetValue() == 1
+		}
+	}
+	if !found {
+		t.Fatalf("The adaptive timeout gauge was not set")
+	}
+	if executionTimeout(context.Background(), time.Hour) != time.Hour {
+		t.Fatalf("The static timeout should be used by default")
+	}
+}
@@ -0,0 +1,49 @@
+package healthcheck
+
+// Alias another definition of an healthcheck coalesced with it. The results
+// of the healthcheck are also emitted under the alias name and labels.
+type Alias struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// DeepCopy returns a copy of the alias
+func (a Alias) DeepCopy() Alias {
+	out := Alias{Name: a.Name}
+	if a.Labels != nil {
+		out.Labels = make(map[string]string, len(a.Labels))
+		for k, v := range a.Labels {
+			out.Labels[k] = v
+		}
+	}
+	return out
+}
+
+// WithAlias returns a copy of the result under the alias name and labels
+func (r *Result) WithAlias(alias Alias) *Result {
+	out := *r
+	out.Name = alias.Name
+	out.Labels = alias.Labels
+	if r.Metadata != nil {
+		out.Metadata = make(map[string]string, len(r.Metadata))
+		for k, v := range r.Metadata {
+			out.Metadata[k] = v
+		}
+	}
+	return &out
+}
+
+// emitWithAliases emits the result, and a copy of it for each alias of the
+// healthcheck. The copies are done first, the exporters may update the
+// emitted results.
+func (c *Component) emitWithAliases(healthcheck Healthcheck, result *Result) {
+	aliases := healthcheck.Base().Aliases
+	copies := make([]*Result, len(aliases))
+	for i := range aliases {
+		copies[i] = result.WithAlias(aliases[i])
+	}
+	c.emit(result)
+	for _, aliased := range copies {
+		c.emit(aliased)
+	}
+}
@@ -0,0 +1,51 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// alpnProtocols the ALPN protocols offered when a protocol is expected
+var alpnProtocols = []string{"h2", "http/1.1"}
+
+// validateExpectedALPN verifies the expected ALPN protocol is one of the
+// offered protocols
+func validateExpectedALPN(config *HTTPHealthcheckConfiguration) error {
+	if config.ExpectedALPN == "" {
+		return nil
+	}
+	if config.Protocol != HTTPS {
+		return errors.New("The healthcheck expected-alpn option requires the https protocol")
+	}
+	for _, protocol := range alpnProtocols {
+		if config.ExpectedALPN == protocol {
+			return nil
+		}
+	}
+	return fmt.Errorf("Invalid expected-alpn %s, should be h2 or http/1.1", config.ExpectedALPN)
+}
+
+// checkALPN verifies the negotiated ALPN protocol, which is added to the
+// metadata. none is reported if the server did not negotiate a protocol.
+func checkALPN(ctx context.Context, expected string, state *tls.ConnectionState) error {
+	if expected == "" {
+		return nil
+	}
+	if state == nil {
+		AddMetadata(ctx, "reason", "alpn")
+		return errors.New("The ALPN protocol can't be verified, no TLS connection")
+	}
+	negotiated := state.NegotiatedProtocol
+	if negotiated == "" {
+		negotiated = "none"
+	}
+	AddMetadata(ctx, "alpn", negotiated)
+	if negotiated != expected {
+		AddMetadata(ctx, "reason", "alpn")
+		return fmt.Errorf("Negotiated ALPN protocol %s, expected %s", negotiated, expected)
+	}
+	return nil
+}
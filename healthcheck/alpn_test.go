@@ -0,0 +1,90 @@
+package healthcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestHTTPExecuteALPN(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	h2 := httptest.NewUnstartedServer(handler)
+	h2.EnableHTTP2 = true
+	h2.StartTLS()
+	defer h2.Close()
+	h1 := httptest.NewTLSServer(handler)
+	defer h1.Close()
+	cases := []struct {
+		server   *httptest.Server
+		expected string
+		err      string
+	}{
+		{server: h2, expected: "h2"},
+		{server: h1, expected: "http/1.1"},
+		{server: h1, expected: "h2", err: "Negotiated ALPN protocol http/1.1, expected h2"},
+		{server: h2, expected: "http/1.1", err: "Negotiated ALPN protocol h2, expected http/1.1"},
+	}
+	for _, c := range cases {
+		port, err := strconv.ParseUint(strings.Split(c.server.URL, ":")[2], 10, 16)
+		if err != nil {
+			t.Fatalf("error getting HTTP server port :\n%v", err)
+		}
+		h := NewHTTPHealthcheck(zap.NewExample(), &HTTPHealthcheckConfiguration{
+			Base:         Base{Name: "foo", OneOff: true},
+			ValidStatus:  []uint{200},
+			Port:         uint(port),
+			Target:       "127.0.0.1",
+			Protocol:     HTTPS,
+			Insecure:     true,
+			Path:         "/",
+			Timeout:      Duration(time.Second * 2),
+			ExpectedALPN: c.expected,
+		})
+		if err := h.Config.Validate(); err != nil {
+			t.Fatalf("Validation error\n%v", err)
+		}
+		if err := h.Initialize(); err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		ctx := WithMetadata(context.Background())
+		err = h.Execute(ctx)
+		metadata := GetMetadata(ctx)
+		if c.err == "" && err != nil {
+			t.Fatalf("healthcheck error :\n%v", err)
+		}
+		if c.err == "" && metadata["alpn"] != c.expected {
+			t.Fatalf("Invalid metadata %v", metadata)
+		}
+		if c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err) || metadata["reason"] != "alpn") {
+			t.Fatalf("Was expecting an error containing %s: %v %v", c.err, err, metadata)
+		}
+	}
+}
+
+func TestExpectedALPNValidate(t *testing.T) {
+	config := HTTPHealthcheckConfiguration{
+		Base:         Base{Name: "foo", OneOff: true},
+		ValidStatus:  []uint{200},
+		Target:       "127.0.0.1",
+		Port:         443,
+		Protocol:     HTTP,
+		Timeout:      Duration(time.Second),
+		ExpectedALPN: "h2",
+	}
+	if err := config.Validate(); err == nil {
+		t.Fatalf("The expected-alpn option should require https")
+	}
+	config.Protocol = HTTPS
+	config.ExpectedALPN = "h3"
+	if err := config.Validate(); err == nil {
+		t.Fatalf("The expected-alpn value should be invalid")
+	}
+}
@@ -0,0 +1,38 @@
+package healthcheck
+
+// SetBackpressure pauses the execution of all healthchecks when the exporters
+// are down, or starts them again. The backpressure is independent of Suspend:
+// the healthchecks are only executed if they are neither suspended nor paused
+// by the backpressure.
+func (c *Component) SetBackpressure(enabled bool) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.backpressure == enabled {
+		return nil
+	}
+	if enabled {
+		c.Logger.Warn("Pausing healthchecks execution, all exporters are down")
+		if !c.halted() {
+			err := c.stopAll()
+			if err != nil {
+				return err
+			}
+		}
+		c.backpressure = true
+		return nil
+	}
+	c.Logger.Info("Resuming healthchecks execution paused by the exporters backpressure")
+	c.backpressure = false
+	if !c.halted() {
+		c.startAll()
+	}
+	return nil
+}
+
+// IsBackpressured returns true if the healthchecks execution is paused by the
+// exporters backpressure
+func (c *Component) IsBackpressured() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.backpressure
+}
@@ -0,0 +1,79 @@
+package healthcheck
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/prometheus"
+)
+
+func TestBackpressureSuspend(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(logger, make(chan *Result, 10), prom)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	healthcheck := NewTCPHealthcheck(
+		logger,
+		&TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Second * 5),
+			},
+			Target:  "127.0.0.1",
+			Port:    9000,
+			Timeout: Duration(time.Second * 3),
+		},
+	)
+	err = component.AddCheck(healthcheck)
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	err = component.SetBackpressure(true)
+	if err != nil {
+		t.Fatalf("Fail to pause the healthchecks\n%v", err)
+	}
+	if !component.IsBackpressured() {
+		t.Fatalf("The component should be paused by the backpressure")
+	}
+	if component.Healthchecks["foo"].Tick != nil {
+		t.Fatalf("The healthcheck should be stopped")
+	}
+	// the leader election suspension is independent of the backpressure
+	err = component.Suspend()
+	if err != nil {
+		t.Fatalf("Fail to suspend the component\n%v", err)
+	}
+	err = component.SetBackpressure(false)
+	if err != nil {
+		t.Fatalf("Fail to resume the healthchecks\n%v", err)
+	}
+	if component.Healthchecks["foo"].Tick != nil {
+		t.Fatalf("The healthcheck should still be suspended")
+	}
+	err = component.SetBackpressure(true)
+	if err != nil {
+		t.Fatalf("Fail to pause the healthchecks\n%v", err)
+	}
+	component.Resume()
+	if component.Healthchecks["foo"].Tick != nil {
+		t.Fatalf("The healthcheck should still be paused by the backpressure")
+	}
+	err = component.SetBackpressure(false)
+	if err != nil {
+		t.Fatalf("Fail to resume the healthchecks\n%v", err)
+	}
+	if component.Healthchecks["foo"].Tick == nil {
+		t.Fatalf("The healthcheck should be started")
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package healthcheck
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// DefaultMaxBodySize the default maximum size in bytes of the HTTP bodies
+// read by the healthchecks and the exporters
+const DefaultMaxBodySize = 10 * 1024 * 1024
+
+// BodyTooLargeError is returned when a body exceeds its maximum size
+type BodyTooLargeError struct {
+	Limit uint
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("the body exceeds the limit of %d bytes", e.Limit)
+}
+
+// ReadBody reads a body, returning a BodyTooLargeError if it is larger than
+// the limit. DefaultMaxBodySize is used if the limit is 0.
+func ReadBody(body io.Reader, limit uint) ([]byte, error) {
+	if limit == 0 {
+		limit = DefaultMaxBodySize
+	}
+	content, err := ioutil.ReadAll(io.LimitReader(body, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if uint(len(content)) > limit {
+		return nil, &BodyTooLargeError{Limit: limit}
+	}
+	return content, nil
+}
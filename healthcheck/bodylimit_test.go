@@ -0,0 +1,74 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestReadBody(t *testing.T) {
+	content, err := ReadBody(strings.NewReader("hello"), 5)
+	if err != nil || string(content) != "hello" {
+		t.Fatalf("Invalid body %s: %v", string(content), err)
+	}
+	_, err = ReadBody(strings.NewReader("hello!"), 5)
+	var tooLarge *BodyTooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.Limit != 5 {
+		t.Fatalf("Was expecting a body too large error: %v", err)
+	}
+	content, err = ReadBody(strings.NewReader("hello"), 0)
+	if err != nil || string(content) != "hello" {
+		t.Fatalf("The default limit should be used: %v", err)
+	}
+}
+
+func TestHTTPExecuteMaxBodySize(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte(strings.Repeat("a", 1000)))
+		if err != nil {
+			t.Fatalf("Error writing :\n%v", err)
+		}
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	h := HTTPHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &HTTPHealthcheckConfiguration{
+			ValidStatus: []uint{200},
+			Port:        uint(port),
+			Target:      "127.0.0.1",
+			Protocol:    HTTP,
+			Path:        "/",
+			Timeout:     Duration(time.Second * 2),
+			MaxBodySize: 100,
+		},
+	}
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	ctx := WithMetadata(context.Background())
+	err = h.Execute(ctx)
+	if err == nil || !strings.Contains(err.Error(), "exceeds the limit of 100 bytes") {
+		t.Fatalf("Was expecting an error: %v", err)
+	}
+	if GetMetadata(ctx)["reason"] != "body-too-large" {
+		t.Fatalf("Invalid metadata %v", GetMetadata(ctx))
+	}
+	h.Config.MaxBodySize = 1000
+	err = h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os/exec"
+	"strconv"
 	"time"
 
 	"github.com/pkg/errors"
@@ -34,6 +35,15 @@ func (config *CommandHealthcheckConfiguration) Validate() error {
 	if config.Base.Name == "" {
 		return errors.New("The healthcheck name is missing")
 	}
+	if err := validateMessageTemplate(config.Base.MessageTemplate); err != nil {
+		return err
+	}
+	if err := validateAlerting(&config.Base); err != nil {
+		return err
+	}
+	if config.Base.StartupGracePeriod < 0 {
+		return errors.New("The healthcheck startup-grace-period should be positive")
+	}
 	if config.Command == "" {
 		return errors.New("The healthcheck command is missing")
 	}
@@ -47,6 +57,12 @@ func (config *CommandHealthcheckConfiguration) Validate() error {
 		if config.Base.Interval < config.Timeout {
 			return errors.New("The healthcheck interval should be greater than the timeout")
 		}
+		if err := config.Base.AdaptiveInterval.Validate(config.Base.Interval, config.Timeout); err != nil {
+			return err
+		}
+		if err := config.Base.AdaptiveTimeout.Validate(config.Base.Interval); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -66,6 +82,16 @@ func (h *CommandHealthcheck) Base() Base {
 	return h.Config.Base
 }
 
+// timeout returns the healthcheck timeout
+func (h *CommandHealthcheck) timeout() time.Duration {
+	return time.Duration(h.Config.Timeout)
+}
+
+// target returns the healthcheck target
+func (h *CommandHealthcheck) target() string {
+	return h.Config.Command
+}
+
 // SetSource set the healthcheck source
 func (h *CommandHealthcheck) SetSource(source string) {
 	h.Config.Base.Source = source
@@ -86,10 +112,8 @@ func (h *CommandHealthcheck) Summary() string {
 
 // LogError logs an error with context
 func (h *CommandHealthcheck) LogError(err error, message string) {
-	h.Logger.Error(err.Error(),
-		zap.String("extra", message),
-		zap.String("command", h.Config.Command),
-		zap.String("name", h.Config.Base.Name))
+	logError(h.Logger, h.Config.Base.Name, err, message,
+		zap.String("command", h.Config.Command))
 }
 
 // LogDebug logs a message with context
@@ -107,9 +131,9 @@ func (h *CommandHealthcheck) LogInfo(message string) {
 }
 
 // Execute executes an healthcheck on the given domain
-func (h *CommandHealthcheck) Execute() error {
+func (h *CommandHealthcheck) Execute(ctx context.Context) error {
 	h.LogDebug("start executing healthcheck")
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(h.Config.Timeout)*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, executionTimeout(ctx, time.Duration(h.Config.Timeout)*time.Second))
 	defer cancel()
 	var stdErr bytes.Buffer
 	cmd := exec.CommandContext(ctx, h.Config.Command, h.Config.Arguments...)
@@ -118,12 +142,14 @@ func (h *CommandHealthcheck) Execute() error {
 		var errorMsg string
 		exitErr, isExitError := err.(*exec.ExitError)
 		if isExitError {
+			AddMetadata(ctx, "exit-code", strconv.Itoa(exitErr.ExitCode()))
 			errorMsg = fmt.Sprintf("The command failed with code=%d, stderr=%s", exitErr.ExitCode(), stdErr.String())
 		} else {
 			errorMsg = fmt.Sprintf("The command failed, stderr=%s", stdErr.String())
 		}
 		return errors.Wrapf(err, errorMsg)
 	}
+	AddMetadata(ctx, "exit-code", "0")
 
 	return nil
 }
@@ -0,0 +1,83 @@
+package healthcheck
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CompressionAssertion requests a gzip encoded response and verifies the
+// response is compressed, for example to detect a CDN serving uncompressed
+// payloads. The compressed body is decompressed for the other assertions.
+type CompressionAssertion struct {
+	// the maximum ratio between the compressed and the uncompressed sizes,
+	// between 0 and 1, not verified if not set
+	MaxRatio float64 `json:"max-ratio,omitempty" yaml:"max-ratio,omitempty"`
+}
+
+// validate verifies the compression assertion options
+func (a *CompressionAssertion) validate(config *HTTPHealthcheckConfiguration) error {
+	if a.MaxRatio < 0 || a.MaxRatio > 1 {
+		return errors.New("The healthcheck compression max-ratio should be between 0 and 1")
+	}
+	if config.Method == http.MethodHead {
+		return errors.New("The healthcheck compression option can't be used with the HEAD method")
+	}
+	if config.Stream != nil || config.BodySHA256 != "" || config.GRPCWeb != nil {
+		return errors.New("The healthcheck compression option can't be used with the stream, body-sha256 and grpc-web options")
+	}
+	return nil
+}
+
+// apply requests a gzip encoded response. The header is set explicitly so the
+// transport does not decompress the body transparently.
+func (a *CompressionAssertion) apply(req *http.Request) {
+	req.Header.Set("Accept-Encoding", "gzip")
+}
+
+// check verifies the response is gzip encoded and returns the decompressed
+// body. The uncompressed body is limited to limit bytes.
+func (a *CompressionAssertion) check(ctx context.Context, response *http.Response, body []byte, limit uint) ([]byte, error) {
+	encoding := strings.ToLower(strings.TrimSpace(response.Header.Get("Content-Encoding")))
+	if encoding == "" {
+		encoding = "identity"
+	}
+	AddMetadata(ctx, "content-encoding", encoding)
+	if encoding != "gzip" {
+		AddMetadata(ctx, "reason", "not-compressed")
+		return nil, fmt.Errorf("The response is not gzip encoded: %s", encoding)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		AddMetadata(ctx, "reason", "compression-invalid")
+		return nil, errors.Wrap(err, "Invalid gzip response")
+	}
+	uncompressed, err := ReadBody(reader, limit)
+	if err != nil {
+		var tooLarge *BodyTooLargeError
+		if errors.As(err, &tooLarge) {
+			AddMetadata(ctx, "reason", "body-too-large")
+			return nil, errors.Wrap(err, "Fail to decompress the response")
+		}
+		AddMetadata(ctx, "reason", "compression-invalid")
+		return nil, errors.Wrap(err, "Invalid gzip response")
+	}
+	AddMetadata(ctx, "compressed-size", strconv.Itoa(len(body)))
+	AddMetadata(ctx, "uncompressed-size", strconv.Itoa(len(uncompressed)))
+	ratio := 0.0
+	if len(uncompressed) != 0 {
+		ratio = float64(len(body)) / float64(len(uncompressed))
+	}
+	AddMetadata(ctx, "compression-ratio", strconv.FormatFloat(ratio, 'f', 3, 64))
+	if a.MaxRatio != 0 && ratio > a.MaxRatio {
+		AddMetadata(ctx, "reason", "compression-ratio")
+		return nil, fmt.Errorf("The compression ratio %.3f is greater than %.3f", ratio, a.MaxRatio)
+	}
+	return uncompressed, nil
+}
@@ -0,0 +1,100 @@
+package healthcheck
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestHTTPExecuteCompression(t *testing.T) {
+	content := strings.Repeat("cabourotte ", 100)
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(content))
+	writer.Close()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gzip" && r.Header.Get("Accept-Encoding") == "gzip" {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.WriteHeader(http.StatusOK)
+			w.Write(compressed.Bytes())
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(content))
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("Error getting HTTP server port :\n%v", err)
+	}
+	match := Regexp(*regexp.MustCompile("^cabourotte"))
+	cases := []struct {
+		path        string
+		compression CompressionAssertion
+		success     bool
+		reason      string
+	}{
+		{path: "/gzip", compression: CompressionAssertion{}, success: true},
+		{path: "/gzip", compression: CompressionAssertion{MaxRatio: 0.5}, success: true},
+		{path: "/gzip", compression: CompressionAssertion{MaxRatio: 0.001}, success: false, reason: "compression-ratio"},
+		{path: "/identity", compression: CompressionAssertion{}, success: false, reason: "not-compressed"},
+	}
+	for i, c := range cases {
+		compression := c.compression
+		h := NewHTTPHealthcheck(zap.NewExample(), &HTTPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Second * 10),
+			},
+			ValidStatus: []uint{200},
+			Target:      "127.0.0.1",
+			Port:        uint(port),
+			Path:        c.path,
+			Protocol:    HTTP,
+			Timeout:     Duration(time.Second * 3),
+			BodyRegexp:  []Regexp{match},
+			Compression: &compression,
+		})
+		err := h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error\n%v", err)
+		}
+		ctx := WithMetadata(context.Background())
+		err = h.Execute(ctx)
+		metadata := GetMetadata(ctx)
+		if c.success && err != nil {
+			t.Fatalf("healthcheck %d error\n%v", i, err)
+		}
+		if !c.success && (err == nil || metadata["reason"] != c.reason) {
+			t.Fatalf("healthcheck %d should fail with the reason %s: %v", i, c.reason, metadata)
+		}
+		if c.success && (metadata["content-encoding"] != "gzip" ||
+			metadata["compressed-size"] != strconv.Itoa(compressed.Len()) ||
+			metadata["uncompressed-size"] != strconv.Itoa(len(content))) {
+			t.Fatalf("Invalid compression metadata for healthcheck %d: %v", i, metadata)
+		}
+	}
+}
+
+func TestCompressionValidate(t *testing.T) {
+	config := HTTPHealthcheckConfiguration{Method: http.MethodGet}
+	if err := (&CompressionAssertion{MaxRatio: 0.5}).validate(&config); err != nil {
+		t.Fatalf("The compression assertion should be valid\n%v", err)
+	}
+	if err := (&CompressionAssertion{MaxRatio: 2}).validate(&config); err == nil {
+		t.Fatalf("The compression max-ratio should be invalid")
+	}
+	config.Stream = &StreamAssertion{}
+	if err := (&CompressionAssertion{}).validate(&config); err == nil {
+		t.Fatalf("The compression and stream options should be incompatible")
+	}
+}
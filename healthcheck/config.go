@@ -13,6 +13,10 @@ const (
 	SourceKubernetesCRD string = "kubernetes-crd"
 	// SourceHTTPDiscovery the check was created from the http discovery mechanism
 	SourceHTTPDiscovery string = "http-discovery"
+	// SourceConsulDiscovery the check was created from the Consul discovery mechanism
+	SourceConsulDiscovery string = "consul-discovery"
+	// SourceHeartbeat the check is the Cabourotte heartbeat
+	SourceHeartbeat string = "heartbeat"
 )
 
 // Base shared fields between healthchecks
@@ -23,6 +27,32 @@ type Base struct {
 	OneOff      bool              `json:"one-off"`
 	Source      string            `json:"source"`
 	Labels      map[string]string `json:"labels,omitempty"`
+	// number of consecutive failures (or successes) needed to report a
+	// state change, 1 if not set
+	FailureThreshold uint `json:"failure-threshold,omitempty" yaml:"failure-threshold,omitempty"`
+	SuccessThreshold uint `json:"success-threshold,omitempty" yaml:"success-threshold,omitempty"`
+	// executions running for longer are abandoned, defaults to the
+	// timeout plus a grace period
+	MaxExecutionTime Duration `json:"max-execution-time,omitempty" yaml:"max-execution-time,omitempty"`
+	// a text/template replacing the result message
+	MessageTemplate string `json:"message-template,omitempty" yaml:"message-template,omitempty"`
+	// the results of the healthchecks of a group are rolled up
+	Group string `json:"group,omitempty" yaml:"group,omitempty"`
+	// the failures are tagged startup and do not change the state of the
+	// healthcheck during this period after its start
+	StartupGracePeriod Duration `json:"startup-grace-period,omitempty" yaml:"startup-grace-period,omitempty"`
+	// adjusts the interval depending on the last results, the interval is
+	// static if not set
+	AdaptiveInterval *AdaptiveInterval `json:"adaptive-interval,omitempty" yaml:"adaptive-interval,omitempty"`
+	// computes the timeout from the recent latencies, the timeout is
+	// static if not set
+	AdaptiveTimeout *AdaptiveTimeout `json:"adaptive-timeout,omitempty" yaml:"adaptive-timeout,omitempty"`
+	// the identical healthchecks coalesced with this one when loading the
+	// configuration
+	Aliases []Alias `json:"aliases,omitempty" yaml:"-"`
+	// added to the results for the notifications
+	RunbookURL string   `json:"runbook-url,omitempty" yaml:"runbook-url,omitempty"`
+	Severity   Severity `json:"severity,omitempty" yaml:"severity,omitempty"`
 }
 
 // SourceChecksNames returns all checks managed by the given source
@@ -49,6 +79,18 @@ func (in *Base) DeepCopyInto(out *Base) {
 			(*out)[key] = val
 		}
 	}
+	if in.AdaptiveInterval != nil {
+		out.AdaptiveInterval = in.AdaptiveInterval.DeepCopy()
+	}
+	if in.AdaptiveTimeout != nil {
+		out.AdaptiveTimeout = in.AdaptiveTimeout.DeepCopy()
+	}
+	if in.Aliases != nil {
+		out.Aliases = make([]Alias, len(in.Aliases))
+		for i := range in.Aliases {
+			out.Aliases[i] = in.Aliases[i].DeepCopy()
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Base.
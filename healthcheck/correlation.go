@@ -0,0 +1,53 @@
+package healthcheck
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sync/atomic"
+)
+
+// CorrelationIDMetadata the metadata key of the results correlation ID
+const CorrelationIDMetadata = "correlation-id"
+
+// newCorrelationID returns a random (version 4) UUID
+func newCorrelationID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// SetCorrelationIDs enables or disables the correlation IDs of the results.
+// The ID is added to the result metadata when the result is emitted, and
+// identifies the result in the exporters logs.
+func (c *Component) SetCorrelationIDs(enabled bool) {
+	value := int32(0)
+	if enabled {
+		value = 1
+	}
+	atomic.StoreInt32(&c.correlation, value)
+}
+
+// correlate adds a correlation ID to the result if it is enabled
+func (c *Component) correlate(result *Result) {
+	if atomic.LoadInt32(&c.correlation) == 0 {
+		return
+	}
+	id, err := newCorrelationID()
+	if err != nil {
+		c.Logger.Error(fmt.Sprintf("Fail to generate the correlation ID of the healthcheck %s: %s", result.Name, err.Error()))
+		return
+	}
+	if result.Metadata == nil {
+		result.Metadata = make(map[string]string)
+	}
+	result.Metadata[CorrelationIDMetadata] = id
+}
+
+// CorrelationID returns the correlation ID of a result, or an empty string
+func (r *Result) CorrelationID() string {
+	return r.Metadata[CorrelationIDMetadata]
+}
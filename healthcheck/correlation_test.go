@@ -0,0 +1,41 @@
+package healthcheck
+
+import (
+	"regexp"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/prometheus"
+)
+
+func TestCorrelationIDs(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(zap.NewExample(), make(chan *Result, 10), prom)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	component.emit(&Result{Name: "foo"})
+	result := <-component.ChanResult
+	if result.CorrelationID() != "" {
+		t.Fatalf("The correlation IDs should be disabled by default")
+	}
+	component.SetCorrelationIDs(true)
+	component.emit(&Result{Name: "foo"})
+	first := <-component.ChanResult
+	component.emit(&Result{Name: "foo", Metadata: map[string]string{"ip": "127.0.0.1"}})
+	second := <-component.ChanResult
+	uuid := regexp.MustCompile("^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$")
+	if !uuid.MatchString(first.CorrelationID()) || !uuid.MatchString(second.CorrelationID()) {
+		t.Fatalf("Invalid correlation IDs %s %s", first.CorrelationID(), second.CorrelationID())
+	}
+	if first.CorrelationID() == second.CorrelationID() {
+		t.Fatalf("The correlation IDs should be unique")
+	}
+	if second.Metadata["ip"] != "127.0.0.1" {
+		t.Fatalf("The existing metadata should be kept")
+	}
+}
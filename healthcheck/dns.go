@@ -1,8 +1,10 @@
 package healthcheck
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -10,11 +12,33 @@ import (
 	"net"
 )
 
+// DefaultDNSTimeout the timeout of the DNS healthchecks if not set, a hung
+// resolver should not block the healthcheck forever
+const DefaultDNSTimeout = 5 * time.Second
+
 // DNSHealthcheckConfiguration defines a DNS healthcheck configuration
 type DNSHealthcheckConfiguration struct {
 	Base        `json:",inline" yaml:",inline"`
 	ExpectedIPs []IP   `json:"expected-ips,omitempty" yaml:"expected-ips,omitempty"`
 	Domain      string `json:"domain"`
+	// the protocol used to reach the resolver, the system resolver is used if empty
+	Protocol DNSProtocol `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+	// the resolver address (host:port), or an URL for DNS-over-HTTPS
+	Resolver string   `json:"resolver,omitempty" yaml:"resolver,omitempty"`
+	Timeout  Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	Insecure bool     `json:"insecure,omitempty" yaml:"insecure,omitempty"`
+	Cacert   string   `json:"cacert,omitempty" yaml:"cacert,omitempty"`
+	// bypass the DNS answers cache
+	NoCache bool `json:"no-cache,omitempty" yaml:"no-cache,omitempty"`
+	// the queried records, A and AAAA if empty
+	RecordType DNSRecordType `json:"record-type,omitempty" yaml:"record-type,omitempty"`
+	// the address family used to reach the resolver, independently of the
+	// record type
+	TransportFamily DNSTransportFamily `json:"transport-family,omitempty" yaml:"transport-family,omitempty"`
+	// the resolution durations above which the result has a warning, or is
+	// failed. The cache is bypassed when they are set.
+	WarnResolveTime     Duration `json:"warn-resolve-time,omitempty" yaml:"warn-resolve-time,omitempty"`
+	CriticalResolveTime Duration `json:"critical-resolve-time,omitempty" yaml:"critical-resolve-time,omitempty"`
 }
 
 // DNSHealthcheck defines an HTTP healthcheck
@@ -23,7 +47,9 @@ type DNSHealthcheck struct {
 	Config *DNSHealthcheckConfiguration
 	URL    string
 
-	Tick *time.Ticker
+	Tick   *time.Ticker
+	lookup lookupFunc
+	cache  *dnsCache
 }
 
 // Validate validates the healthcheck configuration
@@ -31,22 +57,69 @@ func (config *DNSHealthcheckConfiguration) Validate() error {
 	if config.Base.Name == "" {
 		return errors.New("The healthcheck name is missing")
 	}
+	if err := validateMessageTemplate(config.Base.MessageTemplate); err != nil {
+		return err
+	}
+	if err := validateAlerting(&config.Base); err != nil {
+		return err
+	}
+	if config.Base.StartupGracePeriod < 0 {
+		return errors.New("The healthcheck startup-grace-period should be positive")
+	}
 	if config.Domain == "" {
 		return errors.New("The healthcheck domain is missing")
 	}
+	if err := validateResolver(config.Protocol, config.Resolver); err != nil {
+		return err
+	}
+	if err := config.RecordType.Validate(); err != nil {
+		return err
+	}
+	if err := validateTransportFamily(config.TransportFamily, config.Protocol, config.Resolver); err != nil {
+		return err
+	}
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = Duration(DefaultDNSTimeout)
+	}
+	if config.WarnResolveTime < 0 || config.WarnResolveTime >= timeout {
+		return errors.New("The healthcheck warn-resolve-time should be positive and lower than the timeout")
+	}
+	if config.CriticalResolveTime < 0 || config.CriticalResolveTime >= timeout {
+		return errors.New("The healthcheck critical-resolve-time should be positive and lower than the timeout")
+	}
+	if config.WarnResolveTime != 0 && config.CriticalResolveTime != 0 && config.WarnResolveTime >= config.CriticalResolveTime {
+		return errors.New("The healthcheck warn-resolve-time should be lower than critical-resolve-time")
+	}
 	if !config.Base.OneOff {
 		if config.Base.Interval < Duration(2*time.Second) {
 			return errors.New("The healthcheck interval should be greater than 2 second")
 		}
+		if err := config.Base.AdaptiveInterval.Validate(config.Base.Interval, config.Timeout); err != nil {
+			return err
+		}
+		if err := config.Base.AdaptiveTimeout.Validate(config.Base.Interval); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
 // Initialize the healthcheck.
 func (h *DNSHealthcheck) Initialize() error {
+	lookup, err := newLookup(h.Config)
+	if err != nil {
+		return err
+	}
+	h.lookup = lookup
 	return nil
 }
 
+// setDNSCache sets the cache shared by the DNS healthchecks
+func (h *DNSHealthcheck) setDNSCache(cache *dnsCache) {
+	h.cache = cache
+}
+
 // GetConfig get the config
 func (h *DNSHealthcheck) GetConfig() interface{} {
 	return h.Config
@@ -57,6 +130,19 @@ func (h *DNSHealthcheck) Base() Base {
 	return h.Config.Base
 }
 
+// timeout returns the healthcheck timeout, DefaultDNSTimeout if not set
+func (h *DNSHealthcheck) timeout() time.Duration {
+	if h.Config.Timeout == 0 {
+		return DefaultDNSTimeout
+	}
+	return time.Duration(h.Config.Timeout)
+}
+
+// target returns the healthcheck target
+func (h *DNSHealthcheck) target() string {
+	return h.Config.Domain
+}
+
 // SetSource set the healthcheck source
 func (h *DNSHealthcheck) SetSource(source string) {
 	h.Config.Base.Source = source
@@ -77,10 +163,8 @@ func (h *DNSHealthcheck) Summary() string {
 
 // LogError logs an error with context
 func (h *DNSHealthcheck) LogError(err error, message string) {
-	h.Logger.Error(err.Error(),
-		zap.String("extra", message),
-		zap.String("domain", h.Config.Domain),
-		zap.String("name", h.Config.Base.Name))
+	logError(h.Logger, h.Config.Base.Name, err, message,
+		zap.String("domain", h.Config.Domain))
 }
 
 // LogDebug logs a message with context
@@ -124,16 +208,75 @@ func verifyIPs(expectedIPs []IP, lookupIPs []net.IP) error {
 }
 
 // Execute executes an healthcheck on the given domain
-func (h *DNSHealthcheck) Execute() error {
+func (h *DNSHealthcheck) Execute(ctx context.Context) error {
 	h.LogDebug("start executing healthcheck")
-	ips, err := net.LookupIP(h.Config.Domain)
+	if h.lookup == nil {
+		err := h.Initialize()
+		if err != nil {
+			return err
+		}
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, executionTimeout(ctx, h.timeout()))
+	defer cancel()
+	ctx = timeoutCtx
+	var ips []net.IP
+	var err error
+	// a cached answer says nothing about the resolver latency
+	measured := h.Config.WarnResolveTime != 0 || h.Config.CriticalResolveTime != 0
+	start := time.Now()
+	if h.cache != nil && !h.Config.NoCache && !measured {
+		key := dnsCacheKey{
+			protocol:        h.Config.Protocol,
+			resolver:        h.Config.Resolver,
+			domain:          h.Config.Domain,
+			recordType:      h.Config.RecordType,
+			transportFamily: h.Config.TransportFamily,
+		}
+		ips, err = h.cache.lookup(ctx, key, h.lookup)
+	} else {
+		ips, err = h.lookup(ctx, h.Config.Domain)
+	}
+	resolveTime := time.Since(start)
+	if measured {
+		AddMetadata(ctx, "resolve-duration", fmt.Sprintf("%f", resolveTime.Seconds()))
+		AddMetadata(ctx, "resolver", h.resolver())
+	}
 	if err != nil {
 		return errors.Wrapf(err, "Fail to lookup IP for domain")
 	}
+	resolved := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		resolved = append(resolved, ip.String())
+	}
+	AddMetadata(ctx, "resolved-ips", strings.Join(resolved, ","))
 	err = verifyIPs(h.Config.ExpectedIPs, ips)
 	if err != nil {
 		return err
 	}
+	return h.checkResolveTime(ctx, resolveTime)
+}
+
+// resolver returns the resolver address used by the healthcheck, system for
+// the system resolver
+func (h *DNSHealthcheck) resolver() string {
+	if h.Config.Resolver == "" {
+		return "system"
+	}
+	return h.Config.Resolver
+}
+
+// checkResolveTime compares the resolution duration to the thresholds,
+// independently of the answer
+func (h *DNSHealthcheck) checkResolveTime(ctx context.Context, resolveTime time.Duration) error {
+	critical := time.Duration(h.Config.CriticalResolveTime)
+	warn := time.Duration(h.Config.WarnResolveTime)
+	if critical != 0 && resolveTime >= critical {
+		AddMetadata(ctx, "reason", "slow-resolve")
+		return fmt.Errorf("Domain %s resolved in %s, above the critical threshold %s", h.Config.Domain, resolveTime, critical)
+	}
+	if warn != 0 && resolveTime >= warn {
+		AddMetadata(ctx, "warning", fmt.Sprintf("Domain resolved in %s, above the warning threshold %s", resolveTime, warn))
+	}
 	return nil
 }
 
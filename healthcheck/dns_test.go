@@ -1,6 +1,7 @@
 package healthcheck
 
 import (
+	"context"
 	"net"
 	"testing"
 	"time"
@@ -19,7 +20,7 @@ func TestDNSExecuteSuccess(t *testing.T) {
 		},
 	}
 
-	err := h.Execute()
+	err := h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -33,7 +34,7 @@ func TestDNSExecuteFailure(t *testing.T) {
 		},
 	}
 
-	err := h.Execute()
+	err := h.Execute(context.Background())
 	if err == nil {
 		t.Fatalf("Was expecting an error: the domain does not exist")
 	}
@@ -112,3 +113,88 @@ func TestVerifyIPs(t *testing.T) {
 		t.Fatalf("Was expecting an error")
 	}
 }
+
+func TestDNSExecuteResolveTime(t *testing.T) {
+	cases := []struct {
+		warn     Duration
+		critical Duration
+		expected []IP
+		warning  bool
+		failure  bool
+		reason   string
+	}{
+		{warn: Duration(time.Second), critical: Duration(2 * time.Second)},
+		{warn: Duration(time.Millisecond), warning: true},
+		{warn: Duration(time.Millisecond), critical: Duration(2 * time.Millisecond), failure: true, reason: "slow-resolve"},
+		// the latency is verified independently of the answer
+		{critical: Duration(time.Second), expected: []IP{IP(net.ParseIP("10.0.0.2"))}, failure: true},
+	}
+	for _, c := range cases {
+		config := &DNSHealthcheckConfiguration{
+			Base:                Base{Name: "foo", Interval: Duration(10 * time.Second)},
+			Domain:              "mcorbin.fr",
+			Protocol:            DNSProtocolUDP,
+			Resolver:            "127.0.0.1:53",
+			Timeout:             Duration(5 * time.Second),
+			ExpectedIPs:         c.expected,
+			WarnResolveTime:     c.warn,
+			CriticalResolveTime: c.critical,
+		}
+		if err := config.Validate(); err != nil {
+			t.Fatalf("Invalid configuration: %v", err)
+		}
+		h := DNSHealthcheck{
+			Logger: zap.NewExample(),
+			Config: config,
+			lookup: func(ctx context.Context, domain string) ([]net.IP, error) {
+				time.Sleep(10 * time.Millisecond)
+				return []net.IP{net.ParseIP("10.0.0.1")}, nil
+			},
+		}
+		ctx := WithMetadata(context.Background())
+		err := h.Execute(ctx)
+		metadata := GetMetadata(ctx)
+		if metadata["resolve-duration"] == "" || metadata["resolver"] != "127.0.0.1:53" {
+			t.Fatalf("Invalid resolution metadata: %v", metadata)
+		}
+		if c.failure != (err != nil) || metadata["reason"] != c.reason {
+			t.Fatalf("Invalid result for %v: %v %v", c, err, metadata)
+		}
+		if c.warning != (metadata["warning"] != "") {
+			t.Fatalf("Invalid warning for %v: %v", c, metadata)
+		}
+	}
+	config := DNSHealthcheckConfiguration{
+		Base:                Base{Name: "foo", Interval: Duration(10 * time.Second)},
+		Domain:              "mcorbin.fr",
+		WarnResolveTime:     Duration(time.Second),
+		CriticalResolveTime: Duration(time.Millisecond),
+	}
+	if err := config.Validate(); err == nil {
+		t.Fatalf("The warn threshold should be lower than the critical one")
+	}
+}
+
+func TestDNSExecuteDefaultTimeout(t *testing.T) {
+	var deadline time.Time
+	h := DNSHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &DNSHealthcheckConfiguration{
+			Base:     Base{Name: "foo", Interval: Duration(10 * time.Second)},
+			Domain:   "mcorbin.fr",
+			Protocol: DNSProtocolDoH,
+			Resolver: "https://127.0.0.1/dns-query",
+		},
+		lookup: func(ctx context.Context, domain string) ([]net.IP, error) {
+			// a hung resolver is bounded by the default timeout
+			deadline, _ = ctx.Deadline()
+			return []net.IP{net.ParseIP("10.0.0.1")}, nil
+		},
+	}
+	if err := h.Execute(context.Background()); err != nil {
+		t.Fatalf("healthcheck error\n%v", err)
+	}
+	if deadline.IsZero() || deadline.After(time.Now().Add(DefaultDNSTimeout)) {
+		t.Fatalf("Invalid deadline %v", deadline)
+	}
+}
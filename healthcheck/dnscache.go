@@ -0,0 +1,85 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// DNSCacheConfiguration the configuration of the DNS answers cache shared by
+// the DNS healthchecks. The cache is disabled if the TTL is 0.
+type DNSCacheConfiguration struct {
+	TTL Duration `json:"ttl"`
+}
+
+// dnsCacheKey identifies a query
+type dnsCacheKey struct {
+	protocol        DNSProtocol
+	resolver        string
+	domain          string
+	recordType      DNSRecordType
+	transportFamily DNSTransportFamily
+}
+
+// dnsCacheEntry a cached answer
+type dnsCacheEntry struct {
+	ips     []net.IP
+	expires time.Time
+}
+
+// dnsCache caches the successful DNS answers for a short duration, so
+// identical queries done by several healthchecks reuse the same answer
+type dnsCache struct {
+	lock    sync.Mutex
+	ttl     time.Duration
+	entries map[dnsCacheKey]dnsCacheEntry
+	counter *prom.CounterVec
+}
+
+func newDNSCache(counter *prom.CounterVec) *dnsCache {
+	return &dnsCache{
+		entries: make(map[dnsCacheKey]dnsCacheEntry),
+		counter: counter,
+	}
+}
+
+// setTTL updates the TTL and flushes the cache
+func (c *dnsCache) setTTL(ttl time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.ttl = ttl
+	c.entries = make(map[dnsCacheKey]dnsCacheEntry)
+}
+
+// lookup returns the cached answer for the key, or executes the lookup
+// function and caches its answer
+func (c *dnsCache) lookup(ctx context.Context, key dnsCacheKey, lookup lookupFunc) ([]net.IP, error) {
+	c.lock.Lock()
+	ttl := c.ttl
+	if ttl == 0 {
+		c.lock.Unlock()
+		return lookup(ctx, key.domain)
+	}
+	now := time.Now()
+	entry, ok := c.entries[key]
+	if ok && now.Before(entry.expires) {
+		c.lock.Unlock()
+		c.counter.With(prom.Labels{"result": "hit"}).Inc()
+		return entry.ips, nil
+	}
+	// expired entries are removed on access
+	delete(c.entries, key)
+	c.lock.Unlock()
+	c.counter.With(prom.Labels{"result": "miss"}).Inc()
+	ips, err := lookup(ctx, key.domain)
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	c.entries[key] = dnsCacheEntry{ips: ips, expires: now.Add(ttl)}
+	c.lock.Unlock()
+	return ips, nil
+}
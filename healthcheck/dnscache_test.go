@@ -0,0 +1,101 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+func TestDNSCacheLookup(t *testing.T) {
+	counter := prom.NewCounterVec(prom.CounterOpts{Name: "dns_cache_requests_total"}, []string{"result"})
+	cache := newDNSCache(counter)
+	calls := 0
+	lookup := func(ctx context.Context, domain string) ([]net.IP, error) {
+		calls++
+		return []net.IP{net.ParseIP("10.0.0.1")}, nil
+	}
+	key := dnsCacheKey{domain: "mcorbin.fr"}
+	// the cache is disabled by default
+	for i := 0; i < 2; i++ {
+		_, err := cache.lookup(context.Background(), key, lookup)
+		if err != nil {
+			t.Fatalf("Lookup error: %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("The cache should be disabled (%d calls)", calls)
+	}
+	cache.setTTL(time.Hour)
+	for i := 0; i < 3; i++ {
+		ips, err := cache.lookup(context.Background(), key, lookup)
+		if err != nil {
+			t.Fatalf("Lookup error: %v", err)
+		}
+		if len(ips) != 1 || !ips[0].Equal(net.ParseIP("10.0.0.1")) {
+			t.Fatalf("Invalid answer %v", ips)
+		}
+	}
+	if calls != 3 {
+		t.Fatalf("The answer should be cached (%d calls)", calls)
+	}
+	_, err := cache.lookup(context.Background(), dnsCacheKey{domain: "other.fr"}, lookup)
+	if err != nil || calls != 4 {
+		t.Fatalf("Different domains should not share answers (%d calls)", calls)
+	}
+	failing := func(ctx context.Context, domain string) ([]net.IP, error) {
+		calls++
+		return nil, errors.New("failure")
+	}
+	for i := 0; i < 2; i++ {
+		_, err := cache.lookup(context.Background(), dnsCacheKey{domain: "failure.fr"}, failing)
+		if err == nil {
+			t.Fatalf("Was expecting an error")
+		}
+	}
+	if calls != 6 {
+		t.Fatalf("Errors should not be cached (%d calls)", calls)
+	}
+}
+
+func TestDNSExecuteNoCache(t *testing.T) {
+	counter := prom.NewCounterVec(prom.CounterOpts{Name: "dns_cache_requests_total"}, []string{"result"})
+	cache := newDNSCache(counter)
+	cache.setTTL(time.Hour)
+	calls := 0
+	h := DNSHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &DNSHealthcheckConfiguration{
+			Domain:  "mcorbin.fr",
+			NoCache: true,
+		},
+		lookup: func(ctx context.Context, domain string) ([]net.IP, error) {
+			calls++
+			return []net.IP{net.ParseIP("10.0.0.1")}, nil
+		},
+	}
+	h.setDNSCache(cache)
+	for i := 0; i < 2; i++ {
+		err := h.Execute(context.Background())
+		if err != nil {
+			t.Fatalf("healthcheck error :\n%v", err)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("The no-cache option should bypass the cache (%d calls)", calls)
+	}
+	h.Config.NoCache = false
+	for i := 0; i < 2; i++ {
+		err := h.Execute(context.Background())
+		if err != nil {
+			t.Fatalf("healthcheck error :\n%v", err)
+		}
+	}
+	if calls != 3 {
+		t.Fatalf("The cache should be used (%d calls)", calls)
+	}
+}
@@ -0,0 +1,95 @@
+package healthcheck
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DNSRecordType the type of the records queried by the DNS healthcheck
+type DNSRecordType string
+
+// DNSTransportFamily the address family used to reach the resolver
+type DNSTransportFamily string
+
+const (
+	// DNSRecordTypeA only the A records are queried
+	DNSRecordTypeA DNSRecordType = "A"
+	// DNSRecordTypeAAAA only the AAAA records are queried
+	DNSRecordTypeAAAA DNSRecordType = "AAAA"
+
+	// DNSTransportIPv4 the resolver is reached over IPv4
+	DNSTransportIPv4 DNSTransportFamily = "ipv4"
+	// DNSTransportIPv6 the resolver is reached over IPv6
+	DNSTransportIPv6 DNSTransportFamily = "ipv6"
+)
+
+// Validate validates the record type
+func (t DNSRecordType) Validate() error {
+	if t != "" && t != DNSRecordTypeA && t != DNSRecordTypeAAAA {
+		return fmt.Errorf("Invalid DNS record type %s, should be A or AAAA", t)
+	}
+	return nil
+}
+
+// lookupNetwork returns the network passed to LookupIP
+func (t DNSRecordType) lookupNetwork() string {
+	switch t {
+	case DNSRecordTypeA:
+		return "ip4"
+	case DNSRecordTypeAAAA:
+		return "ip6"
+	}
+	return "ip"
+}
+
+// queryTypes returns the queried DNS types
+func (t DNSRecordType) queryTypes() []dnsmessage.Type {
+	switch t {
+	case DNSRecordTypeA:
+		return []dnsmessage.Type{dnsmessage.TypeA}
+	case DNSRecordTypeAAAA:
+		return []dnsmessage.Type{dnsmessage.TypeAAAA}
+	}
+	return []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA}
+}
+
+// validateTransportFamily validates the transport family for the protocol
+// and the resolver
+func validateTransportFamily(family DNSTransportFamily, protocol DNSProtocol, resolver string) error {
+	if family == "" {
+		return nil
+	}
+	if family != DNSTransportIPv4 && family != DNSTransportIPv6 {
+		return fmt.Errorf("Invalid DNS transport family %s, should be ipv4 or ipv6", family)
+	}
+	if protocol == "" {
+		return errors.New("The DNS transport family can't be used with the system resolver")
+	}
+	host := resolver
+	if protocol == DNSProtocolDoH {
+		return nil
+	}
+	if h, _, err := net.SplitHostPort(resolver); err == nil {
+		host = h
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if (ip.To4() != nil) != (family == DNSTransportIPv4) {
+			return fmt.Errorf("The resolver %s can't be reached over %s", resolver, family)
+		}
+	}
+	return nil
+}
+
+// network returns the network used to dial the resolver, for example tcp6
+func (family DNSTransportFamily) network(network string) string {
+	switch family {
+	case DNSTransportIPv4:
+		return network + "4"
+	case DNSTransportIPv6:
+		return network + "6"
+	}
+	return network
+}
@@ -0,0 +1,57 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// DNSFailurePolicy defines how a resolution error of the healthcheck target
+// is reported
+type DNSFailurePolicy string
+
+const (
+	// DNSFailurePolicyFail resolution errors are healthcheck failures (the default)
+	DNSFailurePolicyFail DNSFailurePolicy = "fail"
+	// DNSFailurePolicySkip no result is produced on resolution errors
+	DNSFailurePolicySkip DNSFailurePolicy = "skip"
+	// DNSFailurePolicyWarn resolution errors produce a successful result with a warning
+	DNSFailurePolicyWarn DNSFailurePolicy = "warn"
+)
+
+// ErrResultSkipped is returned by healthchecks which should not produce a result
+var ErrResultSkipped = errors.New("healthcheck result skipped")
+
+// Validate validates the policy
+func (p DNSFailurePolicy) Validate() error {
+	if p != "" && p != DNSFailurePolicyFail && p != DNSFailurePolicySkip && p != DNSFailurePolicyWarn {
+		return fmt.Errorf("Invalid DNS failure policy %s", p)
+	}
+	return nil
+}
+
+// isDNSError returns true if the error is a resolution error
+func isDNSError(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr)
+}
+
+// applyDNSFailurePolicy handles an healthcheck error according to the policy.
+// The handled parameter is false if the error is not a resolution error, or
+// if the policy is to fail.
+func applyDNSFailurePolicy(ctx context.Context, policy DNSFailurePolicy, err error) (handled bool, result error) {
+	if err == nil || !isDNSError(err) {
+		return false, err
+	}
+	AddMetadata(ctx, "reason", "dns-resolution-failure")
+	switch policy {
+	case DNSFailurePolicySkip:
+		return true, errors.Wrap(ErrResultSkipped, err.Error())
+	case DNSFailurePolicyWarn:
+		AddMetadata(ctx, "warning", err.Error())
+		return true, nil
+	}
+	return false, err
+}
@@ -0,0 +1,227 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	cryptotls "crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/mcorbin/cabourotte/tls"
+)
+
+// DNSProtocol the protocol used to reach the DNS resolver
+type DNSProtocol string
+
+const (
+	// DNSProtocolUDP plain DNS over UDP
+	DNSProtocolUDP DNSProtocol = "udp"
+	// DNSProtocolTCP plain DNS over TCP
+	DNSProtocolTCP DNSProtocol = "tcp"
+	// DNSProtocolDoT DNS-over-TLS
+	DNSProtocolDoT DNSProtocol = "dot"
+	// DNSProtocolDoH DNS-over-HTTPS
+	DNSProtocolDoH DNSProtocol = "doh"
+)
+
+// maxDNSMessageSize the maximum size of a DNS-over-HTTPS response
+const maxDNSMessageSize = 65535
+
+// validateResolver validates the resolver for the protocol. If no protocol is
+// configured, the system resolver is used.
+func validateResolver(protocol DNSProtocol, resolver string) error {
+	switch protocol {
+	case "":
+		if resolver != "" {
+			return errors.New("The healthcheck protocol is required when a resolver is configured")
+		}
+	case DNSProtocolUDP, DNSProtocolTCP:
+		if resolver == "" {
+			return errors.New("The healthcheck resolver is missing")
+		}
+	case DNSProtocolDoT:
+		if _, _, err := net.SplitHostPort(resolver); err != nil {
+			return errors.New("The DNS-over-TLS resolver should be an address including a port")
+		}
+	case DNSProtocolDoH:
+		u, err := url.Parse(resolver)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return errors.New("The DNS-over-HTTPS resolver should be an https URL")
+		}
+	default:
+		return fmt.Errorf("Invalid DNS protocol %s", protocol)
+	}
+	return nil
+}
+
+// resolverAddress adds the default DNS port to the resolver address if needed
+func resolverAddress(resolver string) string {
+	if _, _, err := net.SplitHostPort(resolver); err != nil {
+		return net.JoinHostPort(resolver, "53")
+	}
+	return resolver
+}
+
+// lookupFunc resolves the IPs of a domain
+type lookupFunc func(ctx context.Context, domain string) ([]net.IP, error)
+
+// newLookup returns the lookup function for the healthcheck configuration
+func newLookup(config *DNSHealthcheckConfiguration) (lookupFunc, error) {
+	if config.Protocol == "" {
+		return func(ctx context.Context, domain string) ([]net.IP, error) {
+			if ips := lookupAliased(config.RecordType.lookupNetwork(), domain); len(ips) != 0 {
+				return ips, nil
+			}
+			return net.DefaultResolver.LookupIP(ctx, config.RecordType.lookupNetwork(), domain)
+		}, nil
+	}
+	tlsConfig, err := tls.GetTLSConfig("", "", config.Cacert, config.Insecure)
+	if err != nil {
+		return nil, err
+	}
+	if config.Protocol == DNSProtocolDoH {
+		client := &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+				DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+					dialer := net.Dialer{}
+					network = config.TransportFamily.network(network)
+					AddMetadata(ctx, "dns-transport", network)
+					return dialer.DialContext(ctx, network, addr)
+				},
+			},
+		}
+		return func(ctx context.Context, domain string) ([]net.IP, error) {
+			return lookupDoH(ctx, client, config.Resolver, domain, config.RecordType)
+		}, nil
+	}
+	address := resolverAddress(config.Resolver)
+	if config.Protocol == DNSProtocolDoT {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid resolver %s", address)
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = host
+		}
+	}
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			dialer := net.Dialer{}
+			network := config.TransportFamily.network("tcp")
+			if config.Protocol == DNSProtocolUDP {
+				network = config.TransportFamily.network("udp")
+			}
+			AddMetadata(ctx, "dns-transport", network)
+			if config.Protocol != DNSProtocolDoT {
+				return dialer.DialContext(ctx, network, address)
+			}
+			conn, err := dialer.DialContext(ctx, network, address)
+			if err != nil {
+				return nil, err
+			}
+			// the resolver uses the TCP framing on non packet connections,
+			// which is what DNS-over-TLS expects
+			tlsConn := cryptotls.Client(conn, tlsConfig)
+			err = tlsConn.HandshakeContext(ctx)
+			if err != nil {
+				conn.Close()
+				return nil, errors.Wrapf(err, "TLS handshake failed with the resolver %s", address)
+			}
+			return tlsConn, nil
+		},
+	}
+	return func(ctx context.Context, domain string) ([]net.IP, error) {
+		return resolver.LookupIP(ctx, config.RecordType.lookupNetwork(), domain)
+	}, nil
+}
+
+// lookupDoH resolves the A and AAAA records of a domain using DNS-over-HTTPS,
+// or only the records of the record type
+func lookupDoH(ctx context.Context, client *http.Client, resolver string, domain string, recordType DNSRecordType) ([]net.IP, error) {
+	name, err := dnsmessage.NewName(dnsName(domain))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid domain %s", domain)
+	}
+	result := []net.IP{}
+	for _, qtype := range recordType.queryTypes() {
+		ips, err := queryDoH(ctx, client, resolver, name, qtype)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ips...)
+	}
+	if len(result) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: domain, IsNotFound: true}
+	}
+	return result, nil
+}
+
+// dnsName returns the fully qualified version of a domain
+func dnsName(domain string) string {
+	if len(domain) > 0 && domain[len(domain)-1] == '.' {
+		return domain
+	}
+	return domain + "."
+}
+
+// queryDoH sends a DNS-over-HTTPS query (RFC 8484)
+func queryDoH(ctx context.Context, client *http.Client, resolver string, name dnsmessage.Name, qtype dnsmessage.Type) ([]net.IP, error) {
+	query := dnsmessage.Message{
+		Header: dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{
+			{Name: name, Type: qtype, Class: dnsmessage.ClassINET},
+		},
+	}
+	payload, err := query.Pack()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to build the DNS query")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, resolver, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to build the DNS-over-HTTPS request")
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "DNS-over-HTTPS request failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DNS-over-HTTPS request failed with status %d", resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, resp.Body, maxDNSMessageSize))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to read the DNS-over-HTTPS response")
+	}
+	var response dnsmessage.Message
+	err = response.Unpack(body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid DNS-over-HTTPS response")
+	}
+	if response.Header.RCode != dnsmessage.RCodeSuccess {
+		return nil, &net.DNSError{
+			Err:        fmt.Sprintf("DNS-over-HTTPS query failed: %s", response.Header.RCode.String()),
+			Name:       name.String(),
+			IsNotFound: response.Header.RCode == dnsmessage.RCodeNameError,
+		}
+	}
+	ips := []net.IP{}
+	for _, answer := range response.Answers {
+		switch body := answer.Body.(type) {
+		case *dnsmessage.AResource:
+			ips = append(ips, net.IP(body.A[:]))
+		case *dnsmessage.AAAAResource:
+			ips = append(ips, net.IP(body.AAAA[:]))
+		}
+	}
+	return ips, nil
+}
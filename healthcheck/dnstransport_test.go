@@ -0,0 +1,205 @@
+package healthcheck
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsAnswer answers A queries with 10.0.0.1
+func dnsAnswer(t *testing.T, query []byte) []byte {
+	var msg dnsmessage.Message
+	err := msg.Unpack(query)
+	if err != nil {
+		t.Errorf("Invalid DNS query: %v", err)
+		return nil
+	}
+	msg.Header.Response = true
+	for _, q := range msg.Questions {
+		if q.Type == dnsmessage.TypeA {
+			msg.Answers = append(msg.Answers, dnsmessage.Resource{
+				Header: dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: q.Class, TTL: 60},
+				Body:   &dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}},
+			})
+		}
+	}
+	response, err := msg.Pack()
+	if err != nil {
+		t.Errorf("Fail to pack the DNS response: %v", err)
+	}
+	return response
+}
+
+func TestValidateResolver(t *testing.T) {
+	cases := []struct {
+		protocol DNSProtocol
+		resolver string
+		valid    bool
+	}{
+		{protocol: "", resolver: "", valid: true},
+		{protocol: "", resolver: "1.1.1.1", valid: false},
+		{protocol: DNSProtocolUDP, resolver: "1.1.1.1", valid: true},
+		{protocol: DNSProtocolTCP, resolver: "", valid: false},
+		{protocol: DNSProtocolDoT, resolver: "1.1.1.1:853", valid: true},
+		{protocol: DNSProtocolDoT, resolver: "1.1.1.1", valid: false},
+		{protocol: DNSProtocolDoH, resolver: "https://1.1.1.1/dns-query", valid: true},
+		{protocol: DNSProtocolDoH, resolver: "http://1.1.1.1/dns-query", valid: false},
+		{protocol: "foo", resolver: "1.1.1.1", valid: false},
+	}
+	for _, c := range cases {
+		err := validateResolver(c.protocol, c.resolver)
+		if c.valid && err != nil {
+			t.Fatalf("The resolver %s %s should be valid: %v", c.protocol, c.resolver, err)
+		}
+		if !c.valid && err == nil {
+			t.Fatalf("The resolver %s %s should be invalid", c.protocol, c.resolver)
+		}
+	}
+}
+
+func TestDNSExecuteDoH(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/dns-message" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		query, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Fail to read the query: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(dnsAnswer(t, query))
+	}))
+	defer ts.Close()
+	h := NewDNSHealthcheck(zap.NewExample(), &DNSHealthcheckConfiguration{
+		Domain:      "cabourotte.test",
+		Protocol:    DNSProtocolDoH,
+		Resolver:    ts.URL + "/dns-query",
+		Insecure:    true,
+		Timeout:     Duration(time.Second * 2),
+		ExpectedIPs: []IP{IP(net.ParseIP("10.0.0.1"))},
+	})
+	err := h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	err = h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+}
+
+func TestDNSExecuteUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to listen :\n%v", err)
+	}
+	defer conn.Close()
+	go func() {
+		buffer := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			conn.WriteTo(dnsAnswer(t, buffer[:n]), addr)
+		}
+	}()
+	h := NewDNSHealthcheck(zap.NewExample(), &DNSHealthcheckConfiguration{
+		Domain:      "cabourotte.test.",
+		Protocol:    DNSProtocolUDP,
+		Resolver:    conn.LocalAddr().String(),
+		Timeout:     Duration(time.Second * 2),
+		ExpectedIPs: []IP{IP(net.ParseIP("10.0.0.1"))},
+	})
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	err = h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+}
+
+func TestDNSExecuteTransportFamily(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "[::1]:0")
+	if err != nil {
+		t.Fatalf("Fail to listen :\n%v", err)
+	}
+	defer conn.Close()
+	go func() {
+		buffer := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			conn.WriteTo(dnsAnswer(t, buffer[:n]), addr)
+		}
+	}()
+	config := &DNSHealthcheckConfiguration{
+		Base: Base{
+			Name:     "foo",
+			Interval: Duration(10 * time.Second),
+		},
+		Domain:          "cabourotte.test.",
+		Protocol:        DNSProtocolUDP,
+		Resolver:        conn.LocalAddr().String(),
+		Timeout:         Duration(time.Second * 2),
+		RecordType:      DNSRecordTypeA,
+		TransportFamily: DNSTransportIPv6,
+		ExpectedIPs:     []IP{IP(net.ParseIP("10.0.0.1"))},
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Invalid configuration: %v", err)
+	}
+	h := NewDNSHealthcheck(zap.NewExample(), config)
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	ctx := WithMetadata(context.Background())
+	err = h.Execute(ctx)
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	metadata := GetMetadata(ctx)
+	if metadata["dns-transport"] != "udp6" || metadata["resolved-ips"] != "10.0.0.1" {
+		t.Fatalf("Invalid metadata %v", metadata)
+	}
+}
+
+func TestValidateTransportFamily(t *testing.T) {
+	cases := []struct {
+		family   DNSTransportFamily
+		protocol DNSProtocol
+		resolver string
+		valid    bool
+	}{
+		{family: "", protocol: "", resolver: "", valid: true},
+		{family: DNSTransportIPv6, protocol: "", resolver: "", valid: false},
+		{family: DNSTransportIPv6, protocol: DNSProtocolUDP, resolver: "[2001:db8::1]:53", valid: true},
+		{family: DNSTransportIPv6, protocol: DNSProtocolUDP, resolver: "1.1.1.1", valid: false},
+		{family: DNSTransportIPv4, protocol: DNSProtocolTCP, resolver: "1.1.1.1", valid: true},
+		{family: DNSTransportIPv4, protocol: DNSProtocolDoT, resolver: "dns.example.com:853", valid: true},
+		{family: DNSTransportIPv4, protocol: DNSProtocolDoH, resolver: "https://[2001:db8::1]/dns-query", valid: true},
+		{family: "ipv5", protocol: DNSProtocolUDP, resolver: "1.1.1.1", valid: false},
+	}
+	for _, c := range cases {
+		err := validateTransportFamily(c.family, c.protocol, c.resolver)
+		if c.valid != (err == nil) {
+			t.Fatalf("Invalid validation for %v: %v", c, err)
+		}
+	}
+	if err := DNSRecordType("MX").Validate(); err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
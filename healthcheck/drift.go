@@ -0,0 +1,24 @@
+package healthcheck
+
+import (
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// observeSchedule updates the scheduling drift and missed ticks metrics of an
+// healthcheck when one of its executions starts. The first execution is not
+// observed, it is delayed on purpose.
+func (c *Component) observeSchedule(w *Wrapper, start time.Time) {
+	last := w.lastRun
+	w.lastRun = start
+	if last.IsZero() || w.effective <= 0 {
+		return
+	}
+	labels := prom.Labels{"name": w.healthcheck.Base().Name}
+	elapsed := start.Sub(last)
+	c.driftGauge.With(labels).Set((elapsed - w.effective).Seconds())
+	if missed := elapsed/w.effective - 1; missed > 0 {
+		c.missedCounter.With(labels).Add(float64(missed))
+	}
+}
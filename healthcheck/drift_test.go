@@ -0,0 +1,47 @@
+package healthcheck
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/prometheus"
+)
+
+func TestObserveSchedule(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(zap.NewExample(), make(chan *Result, 10), prom)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	wrapper := NewWrapper(&panicHealthcheck{config: Base{Name: "foo", Interval: Duration(10 * time.Second)}})
+	wrapper.effective = 10 * time.Second
+	start := time.Now()
+	component.observeSchedule(wrapper, start)
+	component.observeSchedule(wrapper, start.Add(12*time.Second))
+	component.observeSchedule(wrapper, start.Add(42*time.Second))
+	families, err := prom.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Fail to gather the metrics\n%v", err)
+	}
+	drift := 0.0
+	missed := 0.0
+	for _, family := range families {
+		switch family.GetName() {
+		case "healthcheck_scheduling_drift_seconds":
+			drift = family.GetMetric()[0].GetGauge().GetValue()
+		case "healthcheck_missed_ticks_total":
+			missed = family.GetMetric()[0].GetCounter().GetValue()
+		}
+	}
+	if drift != 20 {
+		t.Fatalf("Invalid scheduling drift %f", drift)
+	}
+	if missed != 2 {
+		t.Fatalf("Invalid number of missed ticks %f", missed)
+	}
+}
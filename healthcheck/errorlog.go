@@ -0,0 +1,175 @@
+package healthcheck
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// DefaultErrorLogWindow the default deduplication window of the healthchecks
+// errors logs
+const DefaultErrorLogWindow = time.Minute
+
+// ErrorLogConfiguration the configuration of the healthchecks errors logs
+type ErrorLogConfiguration struct {
+	// identical errors of an healthcheck are logged once per window, with
+	// the number of repetitions
+	Dedup  bool
+	Window Duration
+}
+
+// Validate validates the errors logs configuration
+func (c ErrorLogConfiguration) Validate() error {
+	if c.Window < 0 {
+		return errors.New("The error log window should be positive")
+	}
+	return nil
+}
+
+// dedupEntry an error logged during the current window
+type dedupEntry struct {
+	start      time.Time
+	suppressed uint
+	logger     *zap.Logger
+	message    string
+	fields     []zap.Field
+}
+
+// errorDedup collapses the identical errors logged during a window
+type errorDedup struct {
+	lock    sync.Mutex
+	window  time.Duration
+	entries map[string]*dedupEntry
+	// the repetitions of the expired entries are logged on a ticker, until
+	// the deduplication is stopped
+	stopChan chan struct{}
+	done     chan struct{}
+}
+
+var errorLog atomic.Value
+
+// errorLogLock serializes the configuration changes of the errors logs
+var errorLogLock sync.Mutex
+
+// SetErrorLog configures the deduplication of the healthchecks errors logs.
+// The repetitions of the previous configuration are logged.
+func SetErrorLog(config ErrorLogConfiguration) {
+	errorLogLock.Lock()
+	defer errorLogLock.Unlock()
+	var dedup *errorDedup
+	if config.Dedup {
+		window := time.Duration(config.Window)
+		if window == 0 {
+			window = DefaultErrorLogWindow
+		}
+		dedup = &errorDedup{
+			window:   window,
+			entries:  make(map[string]*dedupEntry),
+			stopChan: make(chan struct{}),
+			done:     make(chan struct{}),
+		}
+		go dedup.run()
+	}
+	previous, _ := errorLog.Load().(*errorDedup)
+	errorLog.Store(dedup)
+	if previous != nil {
+		previous.stop()
+	}
+}
+
+// StopErrorLog logs the pending repetitions and disables the deduplication
+// of the healthchecks errors logs
+func StopErrorLog() {
+	SetErrorLog(ErrorLogConfiguration{})
+}
+
+// logError logs an healthcheck error, deduplicated if configured
+func logError(logger *zap.Logger, name string, err error, message string, fields ...zap.Field) {
+	fields = append([]zap.Field{zap.String("extra", message)}, fields...)
+	fields = append(fields, zap.String("name", name))
+	dedup, _ := errorLog.Load().(*errorDedup)
+	if dedup == nil {
+		logger.Error(err.Error(), fields...)
+		return
+	}
+	dedup.log(name+"\x00"+message+"\x00"+err.Error(), logger, err.Error(), fields)
+}
+
+// log logs a message if it was not already logged during the window. The
+// number of suppressed messages is logged when the window expires.
+func (d *errorDedup) log(key string, logger *zap.Logger, message string, fields []zap.Field) {
+	now := time.Now()
+	d.lock.Lock()
+	entry, ok := d.entries[key]
+	if ok && now.Sub(entry.start) < d.window {
+		entry.suppressed++
+		d.lock.Unlock()
+		return
+	}
+	expired := []*dedupEntry{}
+	if ok && entry.suppressed != 0 {
+		expired = append(expired, entry)
+	}
+	d.entries[key] = &dedupEntry{
+		start:   now,
+		logger:  logger,
+		message: message,
+		fields:  fields,
+	}
+	d.lock.Unlock()
+	d.flush(expired)
+	logger.Error(message, fields...)
+}
+
+// run logs the repetitions of the expired entries on each window, and of all
+// the entries once stopped
+func (d *errorDedup) run() {
+	defer close(d.done)
+	ticker := time.NewTicker(d.window)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			d.flush(d.sweep(now, false))
+		case <-d.stopChan:
+			d.flush(d.sweep(time.Now(), true))
+			return
+		}
+	}
+}
+
+// stop stops the ticker and logs the pending repetitions
+func (d *errorDedup) stop() {
+	close(d.stopChan)
+	<-d.done
+}
+
+// sweep removes the expired entries, or all of them, and returns the ones
+// with repetitions
+func (d *errorDedup) sweep(now time.Time, all bool) []*dedupEntry {
+	expired := []*dedupEntry{}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for k, entry := range d.entries {
+		if all || now.Sub(entry.start) >= d.window {
+			delete(d.entries, k)
+			if entry.suppressed != 0 {
+				expired = append(expired, entry)
+			}
+		}
+	}
+	return expired
+}
+
+// flush logs the number of repetitions of the expired entries
+func (d *errorDedup) flush(entries []*dedupEntry) {
+	for _, entry := range entries {
+		fields := append(entry.fields[:len(entry.fields):len(entry.fields)],
+			zap.Uint("repeated", entry.suppressed),
+			zap.Duration("window", d.window))
+		entry.logger.Error(entry.message, fields...)
+	}
+}
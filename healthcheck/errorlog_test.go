@@ -0,0 +1,106 @@
+package healthcheck
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// syncBuffer a buffer written by the deduplication ticker
+type syncBuffer struct {
+	lock   sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buffer.String()
+}
+
+func (b *syncBuffer) Reset() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.buffer.Reset()
+}
+
+func TestLogErrorDedup(t *testing.T) {
+	var buffer syncBuffer
+	logger := zap.New(zapcore.NewCore(
+		zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+		zapcore.AddSync(&buffer),
+		zap.DebugLevel))
+	SetErrorLog(ErrorLogConfiguration{Dedup: true, Window: Duration(100 * time.Millisecond)})
+	defer SetErrorLog(ErrorLogConfiguration{})
+	for i := 0; i < 3; i++ {
+		logError(logger, "foo", errors.New("connection refused"), "failure")
+	}
+	logError(logger, "bar", errors.New("connection refused"), "failure")
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Invalid logs %v", lines)
+	}
+	time.Sleep(150 * time.Millisecond)
+	logError(logger, "foo", errors.New("connection refused"), "failure")
+	lines = strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Invalid logs %v", lines)
+	}
+	if !strings.Contains(lines[2], `"repeated":2`) || !strings.Contains(lines[2], `"name":"foo"`) {
+		t.Fatalf("Invalid repetitions log %s", lines[2])
+	}
+	if strings.Contains(lines[3], "repeated") {
+		t.Fatalf("Invalid log %s", lines[3])
+	}
+	SetErrorLog(ErrorLogConfiguration{})
+	buffer.Reset()
+	for i := 0; i < 3; i++ {
+		logError(logger, "foo", errors.New("connection refused"), "failure")
+	}
+	lines = strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Invalid logs %v", lines)
+	}
+}
+
+func TestLogErrorDedupFlush(t *testing.T) {
+	var buffer syncBuffer
+	logger := zap.New(zapcore.NewCore(
+		zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+		zapcore.AddSync(&buffer),
+		zap.DebugLevel))
+	SetErrorLog(ErrorLogConfiguration{Dedup: true, Window: Duration(100 * time.Millisecond)})
+	defer SetErrorLog(ErrorLogConfiguration{})
+	for i := 0; i < 3; i++ {
+		logError(logger, "foo", errors.New("connection refused"), "failure")
+	}
+	// the repetitions are logged on the ticker even if the errors stop
+	time.Sleep(250 * time.Millisecond)
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"repeated":2`) {
+		t.Fatalf("Invalid logs %v", lines)
+	}
+	SetErrorLog(ErrorLogConfiguration{Dedup: true, Window: Duration(time.Hour)})
+	buffer.Reset()
+	for i := 0; i < 3; i++ {
+		logError(logger, "foo", errors.New("connection refused"), "failure")
+	}
+	// the pending repetitions are logged when the deduplication stops
+	StopErrorLog()
+	lines = strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"repeated":2`) {
+		t.Fatalf("Invalid logs %v", lines)
+	}
+}
@@ -0,0 +1,38 @@
+package healthcheck
+
+import (
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// validateTCPFastOpen verifies that TCP Fast Open can be used: the data of the
+// first step is sent in the SYN, so the client should speak first
+func validateTCPFastOpen(config *TCPHealthcheckConfiguration) error {
+	if !config.TCPFastOpen {
+		return nil
+	}
+	if len(config.Steps) == 0 || config.Steps[0].Send == "" {
+		return errors.New("The TCP tcp-fast-open option requires a first step with a send payload")
+	}
+	if config.TLS != nil || config.ExpectBanner != nil || config.Proxy != "" {
+		return errors.New("The TCP tcp-fast-open option can't be used with tls, expect-banner or proxy")
+	}
+	if config.WarnConnectTime != 0 || config.CriticalConnectTime != 0 {
+		return errors.New("The TCP tcp-fast-open option can't be used with the connect time thresholds, the connection is established on the first write")
+	}
+	return nil
+}
+
+// chainControl returns a dialer control function calling both functions
+func chainControl(first, second func(network, address string, c syscall.RawConn) error) func(network, address string, c syscall.RawConn) error {
+	if first == nil {
+		return second
+	}
+	return func(network, address string, c syscall.RawConn) error {
+		if err := first(network, address, c); err != nil {
+			return err
+		}
+		return second(network, address, c)
+	}
+}
@@ -0,0 +1,33 @@
+//go:build linux
+
+package healthcheck
+
+import (
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// tcpFastOpenConnect the TCP_FASTOPEN_CONNECT socket option, available since
+// Linux 4.11
+const tcpFastOpenConnect = 30
+
+// fastOpenControl returns a dialer control function enabling TCP Fast Open
+// on the sockets: the connection is established on the first write, which is
+// sent in the SYN. fallback is called if the kernel doesn't support it, the
+// connection is then established normally.
+func fastOpenControl(fallback func(err error)) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		err := c.Control(func(fd uintptr) {
+			sockErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, tcpFastOpenConnect, 1)
+		})
+		if err != nil {
+			return err
+		}
+		if sockErr != nil {
+			fallback(errors.Wrap(sockErr, "Fail to enable TCP Fast Open"))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package healthcheck
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+)
+
+// fastOpenControl returns a dialer control function calling fallback, TCP
+// Fast Open is only supported on Linux
+func fastOpenControl(fallback func(err error)) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		fallback(fmt.Errorf("TCP Fast Open is not supported on %s", runtime.GOOS))
+		return nil
+	}
+}
@@ -0,0 +1,67 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+)
+
+func TestTCPExecuteFastOpen(t *testing.T) {
+	listener := scriptedListener(t, "", map[string]string{"PING": "PONG\r\n"})
+	defer listener.Close()
+	configYAML := `
+name: foo
+target: 127.0.0.1
+timeout: 2s
+interval: 10s
+tcp-fast-open: true
+steps:
+  - send: "PING\n"
+    expect: "^PONG"
+`
+	var config TCPHealthcheckConfiguration
+	err := yaml.UnmarshalStrict([]byte(configYAML), &config)
+	if err != nil {
+		t.Fatalf("Fail to parse the configuration: %v", err)
+	}
+	config.Port = uint(listener.Addr().(*net.TCPAddr).Port)
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Invalid configuration: %v", err)
+	}
+	h := NewTCPHealthcheck(zap.NewExample(), &config)
+	h.buildURL()
+	// the healthcheck is successful with or without the kernel support
+	for i := 0; i < 2; i++ {
+		ctx := WithMetadata(context.Background())
+		err = h.Execute(ctx)
+		if err != nil {
+			t.Fatalf("healthcheck error :\n%v", err)
+		}
+		fastOpen := GetMetadata(ctx)["fast-open"]
+		if fastOpen != "enabled" && fastOpen != "unsupported" {
+			t.Fatalf("Invalid metadata %v", GetMetadata(ctx))
+		}
+	}
+}
+
+func TestValidateTCPFastOpen(t *testing.T) {
+	invalid := []string{
+		"tcp-fast-open: true\n",
+		"tcp-fast-open: true\nsteps:\n  - expect: \"^220\"\n",
+		"tcp-fast-open: true\nsteps:\n  - send: \"PING\\n\"\nexpect-banner: \"^220\"\n",
+		"tcp-fast-open: true\nsteps:\n  - send: \"PING\\n\"\nwarn-connect-time: 1s\n",
+	}
+	for _, c := range invalid {
+		var config TCPHealthcheckConfiguration
+		err := yaml.UnmarshalStrict([]byte("name: foo\ntarget: 127.0.0.1\nport: 22\ntimeout: 2s\ninterval: 10s\n"+c), &config)
+		if err != nil {
+			t.Fatalf("Fail to parse the configuration: %v", err)
+		}
+		if err := config.Validate(); err == nil {
+			t.Fatalf("Was expecting an error for %s", c)
+		}
+	}
+}
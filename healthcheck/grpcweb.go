@@ -0,0 +1,190 @@
+package healthcheck
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// grpcHealthCheckPath the path of the Check method of the gRPC health service
+const grpcHealthCheckPath = "/grpc.health.v1.Health/Check"
+
+// grpcWebContentType the content type of the gRPC-Web binary wire format
+const grpcWebContentType = "application/grpc-web+proto"
+
+// the serving status values of the gRPC health service responses
+var grpcServingStatus = map[uint64]string{
+	0: "UNKNOWN",
+	1: "SERVING",
+	2: "NOT_SERVING",
+	3: "SERVICE_UNKNOWN",
+}
+
+// GRPCWebAssertion calls the gRPC health service using the gRPC-Web wire
+// format, for the endpoints only reachable through a gRPC-Web proxy. The
+// healthcheck path is the base path of the gRPC-Web service.
+type GRPCWebAssertion struct {
+	// the checked service, the overall server health if empty
+	Service string `json:"service,omitempty" yaml:"service,omitempty"`
+}
+
+// validate verifies the gRPC-Web assertion options, the request method and
+// body are set by the assertion
+func (a *GRPCWebAssertion) validate(config *HTTPHealthcheckConfiguration) error {
+	if config.Method != "" && config.Method != http.MethodPost {
+		return errors.New("The healthcheck grpc-web option requires the POST method")
+	}
+	if config.Body != "" || config.Stream != nil || config.Metric != nil || config.BodySHA256 != "" ||
+		config.CacheValidation != nil || len(config.BodyRegexp) != 0 {
+		return errors.New("The healthcheck grpc-web option can't be used with the body, stream, metric, body-sha256, cache-validation and body-regexp options")
+	}
+	return nil
+}
+
+// path returns the path of the Check method, below the base path
+func (a *GRPCWebAssertion) path(base string) string {
+	return strings.TrimSuffix(base, "/") + grpcHealthCheckPath
+}
+
+// request returns the framed HealthCheckRequest message
+func (a *GRPCWebAssertion) request() []byte {
+	var message []byte
+	if a.Service != "" {
+		message = protowire.AppendTag(message, 1, protowire.BytesType)
+		message = protowire.AppendString(message, a.Service)
+	}
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	return append(frame, message...)
+}
+
+// apply sets the gRPC-Web headers of the request
+func (a *GRPCWebAssertion) apply(req *http.Request) {
+	req.Header.Set("Content-Type", grpcWebContentType)
+	req.Header.Set("Accept", grpcWebContentType)
+	req.Header.Set("X-Grpc-Web", "1")
+}
+
+// grpcWebResponse the decoded frames of a gRPC-Web response
+type grpcWebResponse struct {
+	messages [][]byte
+	trailers http.Header
+}
+
+// readGRPCWebFrames decodes the frames of a gRPC-Web response body
+func readGRPCWebFrames(body []byte) (*grpcWebResponse, error) {
+	response := &grpcWebResponse{trailers: make(http.Header)}
+	for len(body) != 0 {
+		if len(body) < 5 {
+			return nil, errors.New("Truncated gRPC-Web frame header")
+		}
+		flag := body[0]
+		length := binary.BigEndian.Uint32(body[1:5])
+		body = body[5:]
+		if uint64(len(body)) < uint64(length) {
+			return nil, errors.New("Truncated gRPC-Web frame")
+		}
+		payload := body[:length]
+		body = body[length:]
+		if flag&0x80 != 0 {
+			reader := textproto.NewReader(bufio.NewReader(bytes.NewReader(append(payload, '\r', '\n'))))
+			trailers, err := reader.ReadMIMEHeader()
+			if err != nil {
+				return nil, errors.Wrap(err, "Invalid gRPC-Web trailers")
+			}
+			for k, v := range trailers {
+				response.trailers[k] = v
+			}
+			continue
+		}
+		if flag&0x01 != 0 {
+			return nil, errors.New("Compressed gRPC-Web messages are not supported")
+		}
+		response.messages = append(response.messages, payload)
+	}
+	return response, nil
+}
+
+// servingStatus decodes the status of a HealthCheckResponse message
+func servingStatus(message []byte) (uint64, error) {
+	var status uint64
+	for len(message) != 0 {
+		num, typ, n := protowire.ConsumeTag(message)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		message = message[n:]
+		if num == 1 && typ == protowire.VarintType {
+			value, n := protowire.ConsumeVarint(message)
+			if n < 0 {
+				return 0, protowire.ParseError(n)
+			}
+			status = value
+			message = message[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, message)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		message = message[n:]
+	}
+	return status, nil
+}
+
+// check verifies the gRPC status and the serving status of a gRPC-Web
+// response. The status is read from the trailers frame, or from the headers
+// for the trailers-only responses.
+func (a *GRPCWebAssertion) check(ctx context.Context, response *http.Response, body []byte) error {
+	frames, err := readGRPCWebFrames(body)
+	if err != nil {
+		AddMetadata(ctx, "reason", "grpc-web-invalid")
+		return err
+	}
+	status := frames.trailers.Get("Grpc-Status")
+	message := frames.trailers.Get("Grpc-Message")
+	if status == "" {
+		status = response.Header.Get("Grpc-Status")
+		message = response.Header.Get("Grpc-Message")
+	}
+	if status == "" {
+		AddMetadata(ctx, "reason", "grpc-web-invalid")
+		return errors.New("The gRPC-Web response has no grpc-status")
+	}
+	AddMetadata(ctx, "grpc-status", status)
+	if message != "" {
+		AddMetadata(ctx, "grpc-message", message)
+	}
+	if status != "0" {
+		AddMetadata(ctx, "reason", "grpc-status")
+		return fmt.Errorf("gRPC health check failed with the status %s: %s", status, message)
+	}
+	if len(frames.messages) != 1 {
+		AddMetadata(ctx, "reason", "grpc-web-invalid")
+		return fmt.Errorf("Expected one gRPC-Web message, got %d", len(frames.messages))
+	}
+	serving, err := servingStatus(frames.messages[0])
+	if err != nil {
+		AddMetadata(ctx, "reason", "grpc-web-invalid")
+		return errors.Wrap(err, "Invalid gRPC health check response")
+	}
+	name, ok := grpcServingStatus[serving]
+	if !ok {
+		name = strconv.FormatUint(serving, 10)
+	}
+	AddMetadata(ctx, "grpc-serving-status", name)
+	if name != "SERVING" {
+		AddMetadata(ctx, "reason", "grpc-not-serving")
+		return fmt.Errorf("The gRPC service is not serving: %s", name)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// grpcWebFrame builds a gRPC-Web frame
+func grpcWebFrame(flag byte, payload []byte) []byte {
+	frame := make([]byte, 5, 5+len(payload))
+	frame[0] = flag
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(payload)))
+	return append(frame, payload...)
+}
+
+func TestHTTPExecuteGRPCWeb(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/grpc.health.v1.Health/Check" || r.Header.Get("Content-Type") != grpcWebContentType {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil || len(body) < 5 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		service := ""
+		if len(body) > 5 {
+			_, _, n := protowire.ConsumeTag(body[5:])
+			value, _ := protowire.ConsumeString(body[5+n:])
+			service = value
+		}
+		w.Header().Set("Content-Type", grpcWebContentType)
+		if service == "missing" {
+			// trailers-only response
+			w.Header().Set("Grpc-Status", "5")
+			w.Header().Set("Grpc-Message", "unknown service")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		status := uint64(1)
+		if service == "down" {
+			status = 2
+		}
+		var message []byte
+		message = protowire.AppendTag(message, 1, protowire.VarintType)
+		message = protowire.AppendVarint(message, status)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(grpcWebFrame(0, message))
+		_, _ = w.Write(grpcWebFrame(0x80, []byte("grpc-status: 0\r\ngrpc-message: \r\n")))
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("Error getting HTTP server port :\n%v", err)
+	}
+	cases := []struct {
+		service string
+		success bool
+		status  string
+		serving string
+		reason  string
+	}{
+		{service: "", success: true, status: "0", serving: "SERVING"},
+		{service: "down", success: false, status: "0", serving: "NOT_SERVING", reason: "grpc-not-serving"},
+		{service: "missing", success: false, status: "5", reason: "grpc-status"},
+	}
+	for _, c := range cases {
+		config := &HTTPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Second * 10),
+			},
+			Target:   "127.0.0.1",
+			Port:     uint(port),
+			Protocol: HTTP,
+			Path:     "/api/",
+			Timeout:  Duration(time.Second * 3),
+			GRPCWeb:  &GRPCWebAssertion{Service: c.service},
+		}
+		if err := config.Validate(); err != nil {
+			t.Fatalf("Invalid configuration\n%v", err)
+		}
+		h := NewHTTPHealthcheck(zap.NewExample(), config)
+		if err := h.Initialize(); err != nil {
+			t.Fatalf("Initialization error\n%v", err)
+		}
+		ctx := WithMetadata(context.Background())
+		err := h.Execute(ctx)
+		metadata := GetMetadata(ctx)
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error for the service %q\n%v", c.service, err)
+		}
+		if !c.success && (err == nil || metadata["reason"] != c.reason) {
+			t.Fatalf("healthcheck should fail with the reason %s: %v", c.reason, metadata)
+		}
+		if metadata["grpc-status"] != c.status || metadata["grpc-serving-status"] != c.serving {
+			t.Fatalf("Invalid metadata for the service %q: %v", c.service, metadata)
+		}
+	}
+}
+
+func TestReadGRPCWebFrames(t *testing.T) {
+	_, err := readGRPCWebFrames([]byte{0, 0, 0, 0, 5, 1})
+	if err == nil {
+		t.Fatalf("The frame should be truncated")
+	}
+	_, err = readGRPCWebFrames(grpcWebFrame(1, []byte("compressed")))
+	if err == nil {
+		t.Fatalf("The compressed messages should not be supported")
+	}
+	config := HTTPHealthcheckConfiguration{Body: "foo", GRPCWeb: &GRPCWebAssertion{}}
+	if err := config.GRPCWeb.validate(&config); err == nil {
+		t.Fatalf("The body should not be allowed with grpc-web")
+	}
+}
@@ -0,0 +1,126 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// HeartbeatHealthcheckConfiguration defines the heartbeat configuration. The
+// heartbeat is a synthetic healthcheck which is always successful, and can be
+// used to detect that Cabourotte is not running anymore.
+type HeartbeatHealthcheckConfiguration struct {
+	Base `json:",inline" yaml:",inline"`
+}
+
+// HeartbeatHealthcheck defines the heartbeat healthcheck
+type HeartbeatHealthcheck struct {
+	Logger *zap.Logger
+	Config *HeartbeatHealthcheckConfiguration
+}
+
+// Validate validates the healthcheck configuration
+func (config *HeartbeatHealthcheckConfiguration) Validate() error {
+	if config.Base.Name == "" {
+		return errors.New("The heartbeat name is missing")
+	}
+	if err := validateMessageTemplate(config.Base.MessageTemplate); err != nil {
+		return err
+	}
+	if err := validateAlerting(&config.Base); err != nil {
+		return err
+	}
+	if config.Base.StartupGracePeriod < 0 {
+		return errors.New("The healthcheck startup-grace-period should be positive")
+	}
+	if config.Base.OneOff {
+		return errors.New("The heartbeat can't be a one-off healthcheck")
+	}
+	if config.Base.Interval < Duration(2*time.Second) {
+		return errors.New("The heartbeat interval should be greater than 2 second")
+	}
+	return nil
+}
+
+// Initialize the healthcheck.
+func (h *HeartbeatHealthcheck) Initialize() error {
+	return nil
+}
+
+// GetConfig get the config
+func (h *HeartbeatHealthcheck) GetConfig() interface{} {
+	return h.Config
+}
+
+// Base get the base configuration
+func (h *HeartbeatHealthcheck) Base() Base {
+	return h.Config.Base
+}
+
+// SetSource set the healthcheck source
+func (h *HeartbeatHealthcheck) SetSource(source string) {
+	h.Config.Base.Source = source
+}
+
+// Summary returns an healthcheck summary
+func (h *HeartbeatHealthcheck) Summary() string {
+	if h.Config.Base.Description != "" {
+		return h.Config.Base.Description
+	}
+	return "Cabourotte heartbeat"
+}
+
+// LogError logs an error with context
+func (h *HeartbeatHealthcheck) LogError(err error, message string) {
+	logError(h.Logger, h.Config.Base.Name, err, message)
+}
+
+// LogDebug logs a message with context
+func (h *HeartbeatHealthcheck) LogDebug(message string) {
+	h.Logger.Debug(message,
+		zap.String("name", h.Config.Base.Name))
+}
+
+// LogInfo logs a message with context
+func (h *HeartbeatHealthcheck) LogInfo(message string) {
+	h.Logger.Info(message,
+		zap.String("name", h.Config.Base.Name))
+}
+
+// Execute always succeeds
+func (h *HeartbeatHealthcheck) Execute(ctx context.Context) error {
+	h.LogDebug("emitting heartbeat")
+	return nil
+}
+
+// NewHeartbeatHealthcheck creates a heartbeat healthcheck from a logger and a configuration
+func NewHeartbeatHealthcheck(logger *zap.Logger, config *HeartbeatHealthcheckConfiguration) *HeartbeatHealthcheck {
+	return &HeartbeatHealthcheck{
+		Logger: logger,
+		Config: config,
+	}
+}
+
+// MarshalJSON marshal to json a heartbeat healthcheck
+func (h *HeartbeatHealthcheck) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.Config)
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HeartbeatHealthcheckConfiguration) DeepCopyInto(out *HeartbeatHealthcheckConfiguration) {
+	*out = *in
+	in.Base.DeepCopyInto(&out.Base)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HeartbeatHealthcheckConfiguration.
+func (in *HeartbeatHealthcheckConfiguration) DeepCopy() *HeartbeatHealthcheckConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(HeartbeatHealthcheckConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
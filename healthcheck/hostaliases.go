@@ -0,0 +1,113 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// HostAliases static IPs of hosts, used instead of the DNS resolution like
+// the /etc/hosts file. The hosts which are not in the map are resolved
+// normally.
+type HostAliases map[string][]string
+
+// hostAliases the current resolvedAliases
+var hostAliases atomic.Value
+
+// resolvedAliases the IPs of the aliased hosts, by lower case host name
+type resolvedAliases map[string][]net.IP
+
+// aliasHost normalizes a host name
+func aliasHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// Validate validates the host aliases
+func (a HostAliases) Validate() error {
+	for host, ips := range a {
+		if aliasHost(host) == "" {
+			return errors.New("Invalid empty host in the host aliases")
+		}
+		if len(ips) == 0 {
+			return fmt.Errorf("No IP configured for the host alias %s", host)
+		}
+		for _, ip := range ips {
+			if net.ParseIP(ip) == nil {
+				return fmt.Errorf("Invalid IP %s for the host alias %s", ip, host)
+			}
+		}
+	}
+	return nil
+}
+
+// SetHostAliases sets the host aliases used by the healthchecks. The aliases
+// should be valid.
+func SetHostAliases(aliases HostAliases) {
+	resolved := make(resolvedAliases, len(aliases))
+	for host, ips := range aliases {
+		for _, ip := range ips {
+			if parsed := net.ParseIP(ip); parsed != nil {
+				resolved[aliasHost(host)] = append(resolved[aliasHost(host)], parsed)
+			}
+		}
+	}
+	hostAliases.Store(resolved)
+}
+
+// aliasIPs returns the IPs of an aliased host, nil if the host has no alias
+func aliasIPs(host string) []net.IP {
+	aliases, ok := hostAliases.Load().(resolvedAliases)
+	if !ok {
+		return nil
+	}
+	return aliases[aliasHost(host)]
+}
+
+// dialFunc the signature of net.Dialer.DialContext
+type dialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// dialAliased dials the address, the IPs of the host are tried in order if
+// the host is aliased
+func dialAliased(ctx context.Context, dial dialFunc, network string, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return dial(ctx, network, address)
+	}
+	ips := aliasIPs(host)
+	if len(ips) == 0 {
+		return dial(ctx, network, address)
+	}
+	var firstErr error
+	for _, ip := range ips {
+		conn, err := dial(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+// lookupAliased returns the IPs of an aliased host matching the lookup
+// network (ip, ip4 or ip6), nil if no alias matches
+func lookupAliased(network string, host string) []net.IP {
+	ips := aliasIPs(host)
+	if len(ips) == 0 {
+		return nil
+	}
+	result := make([]net.IP, 0, len(ips))
+	for _, ip := range ips {
+		ipv4 := ip.To4() != nil
+		if (network == "ip4" && !ipv4) || (network == "ip6" && ipv4) {
+			continue
+		}
+		result = append(result, ip)
+	}
+	return result
+}
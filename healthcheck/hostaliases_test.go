@@ -0,0 +1,76 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestHostAliasesValidate(t *testing.T) {
+	valid := HostAliases{"example.com": {"127.0.0.1", "::1"}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("The host aliases should be valid\n%v", err)
+	}
+	cases := []HostAliases{
+		{"": {"127.0.0.1"}},
+		{"example.com": {}},
+		{"example.com": {"127.0.0.300"}},
+	}
+	for i := range cases {
+		if err := cases[i].Validate(); err == nil {
+			t.Fatalf("The host aliases %d should be invalid", i)
+		}
+	}
+}
+
+func TestDialAliased(t *testing.T) {
+	SetHostAliases(HostAliases{"Example.COM": {"10.0.0.1", "127.0.0.1"}})
+	defer SetHostAliases(nil)
+	var dialed []string
+	dial := func(ctx context.Context, network, address string) (net.Conn, error) {
+		dialed = append(dialed, address)
+		if address != "127.0.0.1:80" {
+			return nil, &net.OpError{Op: "dial", Net: network}
+		}
+		client, server := net.Pipe()
+		server.Close()
+		return client, nil
+	}
+	conn, err := dialAliased(context.Background(), dial, "tcp", "example.com.:80")
+	if err != nil {
+		t.Fatalf("Fail to dial the aliased host\n%v", err)
+	}
+	conn.Close()
+	if len(dialed) != 2 || dialed[0] != "10.0.0.1:80" {
+		t.Fatalf("The aliased IPs should be tried in order %v", dialed)
+	}
+	dialed = nil
+	_, err = dialAliased(context.Background(), dial, "tcp", "other.com:80")
+	if err == nil || len(dialed) != 1 || dialed[0] != "other.com:80" {
+		t.Fatalf("The hosts without alias should be dialed directly %v", dialed)
+	}
+	ips := lookupAliased("ip6", "example.com")
+	if len(ips) != 0 {
+		t.Fatalf("No IPv6 alias was expected %v", ips)
+	}
+	ips = lookupAliased("ip", "example.com")
+	if len(ips) != 2 || !ips[1].Equal(net.ParseIP("127.0.0.1")) {
+		t.Fatalf("Invalid aliased IPs %v", ips)
+	}
+}
+
+func TestLookupAliased(t *testing.T) {
+	SetHostAliases(HostAliases{"cabourotte.invalid": {"10.1.2.3"}})
+	defer SetHostAliases(nil)
+	lookup, err := newLookup(&DNSHealthcheckConfiguration{Domain: "cabourotte.invalid"})
+	if err != nil {
+		t.Fatalf("Fail to create the lookup\n%v", err)
+	}
+	ips, err := lookup(context.Background(), "cabourotte.invalid")
+	if err != nil {
+		t.Fatalf("Fail to resolve the aliased domain\n%v", err)
+	}
+	if err := verifyIPs([]IP{IP(net.ParseIP("10.1.2.3"))}, ips); err != nil {
+		t.Fatalf("Invalid aliased IPs\n%v", err)
+	}
+}
@@ -11,19 +11,21 @@ import (
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"regexp"
+	"strconv"
 	"time"
 
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
-
-	"gopkg.in/tomb.v2"
 )
 
 // HTTPHealthcheckConfiguration defines an HTTP healthcheck configuration
 type HTTPHealthcheckConfiguration struct {
 	Base        `json:",inline" yaml:",inline"`
 	ValidStatus []uint `json:"valid-status" yaml:"valid-status"`
+	// status codes ranges considered valid, for example "200-299,401"
+	ValidStatusRanges StatusRanges `json:"valid-status-ranges,omitempty" yaml:"valid-status-ranges,omitempty"`
 	// can be an IP or a domain
 	Target     string            `json:"target"`
 	Method     string            `json:"method"`
@@ -40,6 +42,62 @@ type HTTPHealthcheckConfiguration struct {
 	Key        string            `json:"key,omitempty"`
 	Cert       string            `json:"cert,omitempty"`
 	Cacert     string            `json:"cacert,omitempty"`
+	ResolvePTR bool              `json:"resolve-ptr,omitempty" yaml:"resolve-ptr,omitempty"`
+	// how resolution errors of the target are reported
+	DNSFailurePolicy DNSFailurePolicy `json:"dns-failure-policy,omitempty" yaml:"dns-failure-policy,omitempty"`
+	// optional timeouts for each phase of the request
+	PhaseTimeouts PhaseTimeouts `json:"phase-timeouts,omitempty" yaml:"phase-timeouts,omitempty"`
+	// optional proxy URL (http, https or socks5)
+	Proxy string `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	// add the response headers to the result metadata
+	IncludeResponseHeaders bool `json:"include-response-headers" yaml:"include-response-headers"`
+	// the headers to include, a default list is used if empty
+	ResponseHeaders []string `json:"response-headers,omitempty" yaml:"response-headers,omitempty"`
+	// verifies a metric in a Prometheus text format response
+	Metric *MetricAssertion `json:"metric,omitempty" yaml:"metric,omitempty"`
+	// credentials sent with the request
+	BasicAuth   *BasicAuth `json:"basic-auth,omitempty" yaml:"basic-auth,omitempty"`
+	BearerToken *Secret    `json:"bearer-token,omitempty" yaml:"bearer-token,omitempty"`
+	// the maximum size of the response body, DefaultMaxBodySize if not set
+	MaxBodySize uint `json:"max-body-size,omitempty" yaml:"max-body-size,omitempty"`
+	// the IP to connect to, the target is still used for the SNI, the Host
+	// header and the certificate verification
+	ConnectTo IP `json:"connect-to,omitempty" yaml:"connect-to,omitempty"`
+	// the network namespace the connections originate from, Linux only
+	NetworkNamespace string `json:"network-namespace,omitempty" yaml:"network-namespace,omitempty"`
+	// execute the healthcheck from each source IP, the healthcheck is
+	// successful if at least source-quorum sources are
+	SourceIPs    []IP `json:"source-ips,omitempty" yaml:"source-ips,omitempty"`
+	SourceQuorum uint `json:"source-quorum,omitempty" yaml:"source-quorum,omitempty"`
+	// the negotiated TLS parameters policy
+	MinTLSVersion    TLSVersion `json:"min-tls-version,omitempty" yaml:"min-tls-version,omitempty"`
+	ForbiddenCiphers []string   `json:"forbidden-ciphers,omitempty" yaml:"forbidden-ciphers,omitempty"`
+	// h2 and http/1.1 are offered, the negotiated protocol should be this one
+	ExpectedALPN string `json:"expected-alpn,omitempty" yaml:"expected-alpn,omitempty"`
+	// sends a conditional request and verifies the content did not change,
+	// the valid status codes are not used
+	CacheValidation *CacheValidation `json:"cache-validation,omitempty" yaml:"cache-validation,omitempty"`
+	// the expected hex encoded SHA-256 of the response body
+	BodySHA256 string `json:"body-sha256,omitempty" yaml:"body-sha256,omitempty"`
+	// verifies the OCSP status of the server certificate
+	OCSP *OCSPAssertion `json:"ocsp,omitempty" yaml:"ocsp,omitempty"`
+	// reads the response line by line, for the streamed responses which are
+	// never closed
+	Stream *StreamAssertion `json:"stream,omitempty" yaml:"stream,omitempty"`
+	// calls the gRPC health service using the gRPC-Web wire format
+	GRPCWeb *GRPCWebAssertion `json:"grpc-web,omitempty" yaml:"grpc-web,omitempty"`
+	// requests a gzip encoded response and verifies it is compressed
+	Compression *CompressionAssertion `json:"compression,omitempty" yaml:"compression,omitempty"`
+	// verifies the response contains the required security headers
+	SecurityHeaders *SecurityHeadersAssertion `json:"security-headers,omitempty" yaml:"security-headers,omitempty"`
+	// validates the response body against a JSON schema
+	JSONSchema *JSONSchemaAssertion `json:"json-schema,omitempty" yaml:"json-schema,omitempty"`
+	// the TLS handshake timeout, which is the tls phase timeout, and the
+	// handshake duration thresholds
+	TLSHandshakeTimeouts `json:",inline" yaml:",inline"`
+	// the requests executed in order before the healthcheck request, all
+	// bounded by the timeout
+	Steps []HTTPStep `json:"steps,omitempty" yaml:"steps,omitempty"`
 }
 
 // Validate validates the healthcheck configuration
@@ -47,7 +105,32 @@ func (config *HTTPHealthcheckConfiguration) Validate() error {
 	if config.Base.Name == "" {
 		return errors.New("The healthcheck name is missing")
 	}
-	if len(config.ValidStatus) == 0 {
+	if err := validateMessageTemplate(config.Base.MessageTemplate); err != nil {
+		return err
+	}
+	if err := validateAlerting(&config.Base); err != nil {
+		return err
+	}
+	if config.Base.StartupGracePeriod < 0 {
+		return errors.New("The healthcheck startup-grace-period should be positive")
+	}
+	if err := validateBodySHA256(config.BodySHA256); err != nil {
+		return err
+	}
+	if config.GRPCWeb != nil {
+		if err := config.GRPCWeb.validate(config); err != nil {
+			return err
+		}
+		config.Method = http.MethodPost
+		if len(config.ValidStatus) == 0 && len(config.ValidStatusRanges) == 0 {
+			config.ValidStatus = []uint{http.StatusOK}
+		}
+	}
+	if config.CacheValidation != nil {
+		if err := config.CacheValidation.Validate(); err != nil {
+			return err
+		}
+	} else if len(config.ValidStatus) == 0 && len(config.ValidStatusRanges) == 0 {
 		return errors.New("At least one valid status code should be provided")
 	}
 	if config.Target == "" {
@@ -59,6 +142,74 @@ func (config *HTTPHealthcheckConfiguration) Validate() error {
 	if config.Timeout == 0 {
 		return errors.New("The healthcheck timeout is missing")
 	}
+	if err := config.DNSFailurePolicy.Validate(); err != nil {
+		return err
+	}
+	if config.Proxy != "" {
+		if _, err := ParseProxy(config.Proxy); err != nil {
+			return err
+		}
+	}
+	if err := validateSourceIPs(config.SourceIPs, config.SourceQuorum, config.SourceIP, ""); err != nil {
+		return err
+	}
+	if err := validateNetworkNamespace(config.NetworkNamespace); err != nil {
+		return err
+	}
+	if err := validateForbiddenCiphers(config.ForbiddenCiphers); err != nil {
+		return err
+	}
+	if (config.MinTLSVersion != 0 || len(config.ForbiddenCiphers) != 0) && config.Protocol != HTTPS {
+		return errors.New("The healthcheck TLS policy requires the https protocol")
+	}
+	if err := validateExpectedALPN(config); err != nil {
+		return err
+	}
+	if config.OCSP != nil {
+		if config.Protocol != HTTPS {
+			return errors.New("The healthcheck OCSP assertion requires the https protocol")
+		}
+		if err := config.OCSP.Validate(); err != nil {
+			return err
+		}
+	}
+	if config.ConnectTo != nil {
+		if len(config.ConnectTo) != net.IPv4len && len(config.ConnectTo) != net.IPv6len {
+			return errors.New("The healthcheck connect-to value should be a valid IP")
+		}
+		if config.Proxy != "" {
+			return errors.New("The healthcheck connect-to and proxy options can't be used together")
+		}
+	}
+	if err := validateResponseHeaders(config.ResponseHeaders); err != nil {
+		return err
+	}
+	if config.Metric != nil {
+		if err := config.Metric.Validate(); err != nil {
+			return err
+		}
+	}
+	if config.Stream != nil && (config.BodySHA256 != "" || config.Metric != nil) {
+		return errors.New("The healthcheck stream option can't be used with the body-sha256 and metric options")
+	}
+	if config.Compression != nil {
+		if err := config.Compression.validate(config); err != nil {
+			return err
+		}
+	}
+	if config.SecurityHeaders != nil {
+		if err := config.SecurityHeaders.Validate(); err != nil {
+			return err
+		}
+	}
+	if config.JSONSchema != nil {
+		if err := config.JSONSchema.validate(config); err != nil {
+			return err
+		}
+	}
+	if err := validateAuth(config.BasicAuth, config.BearerToken, config.Headers); err != nil {
+		return err
+	}
 	if config.Method != "" {
 		if config.Method != "GET" && config.Method != "POST" && config.Method != "PUT" && config.Method != "HEAD" && config.Method != "DELETE" {
 			return errors.New(fmt.Sprintf("The healthcheck method is invalid: %s", config.Method))
@@ -73,11 +224,29 @@ func (config *HTTPHealthcheckConfiguration) Validate() error {
 		if config.Base.Interval < config.Timeout {
 			return errors.New("The healthcheck interval should be greater than the timeout")
 		}
+		if err := config.Base.AdaptiveInterval.Validate(config.Base.Interval, config.Timeout); err != nil {
+			return err
+		}
+		if err := config.Base.AdaptiveTimeout.Validate(config.Base.Interval); err != nil {
+			return err
+		}
 	}
 	if !((config.Key != "" && config.Cert != "") ||
 		(config.Key == "" && config.Cert == "")) {
 		return errors.New("Invalid certificates")
 	}
+	if config.PhaseTimeouts.max() > config.Timeout {
+		return errors.New("The healthcheck phase timeouts should be lower than the timeout")
+	}
+	if config.HandshakeTimeout != 0 && config.PhaseTimeouts.TLS != 0 {
+		return errors.New("The healthcheck handshake-timeout and the tls phase timeout can't be both set")
+	}
+	if err := config.TLSHandshakeTimeouts.Validate(config.Timeout); err != nil {
+		return err
+	}
+	if err := validateHTTPSteps(config); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -86,10 +255,13 @@ type HTTPHealthcheck struct {
 	Logger *zap.Logger
 	Config *HTTPHealthcheckConfiguration
 	URL    string
+	// the URL without the path, used by the steps
+	origin string
 
 	Tick      *time.Ticker
-	t         tomb.Tomb
 	transport *http.Transport
+	auth      *httpAuth
+	schema    *jsonSchema
 }
 
 // buildURL build the target URL for the HTTP healthcheck, depending of its
@@ -99,11 +271,15 @@ func (h *HTTPHealthcheck) buildURL() {
 	if h.Config.Protocol == HTTPS {
 		protocol = "https"
 	}
-	h.URL = fmt.Sprintf(
-		"%s://%s%s",
+	path := h.Config.Path
+	if h.Config.GRPCWeb != nil {
+		path = h.Config.GRPCWeb.path(path)
+	}
+	h.origin = fmt.Sprintf(
+		"%s://%s",
 		protocol,
-		net.JoinHostPort(h.Config.Target, fmt.Sprintf("%d", h.Config.Port)),
-		h.Config.Path)
+		net.JoinHostPort(h.Config.Target, fmt.Sprintf("%d", h.Config.Port)))
+	h.URL = h.origin + path
 }
 
 // Summary returns an healthcheck summary
@@ -156,10 +332,49 @@ func (h *HTTPHealthcheck) Initialize() error {
 
 	}
 	tlsConfig.InsecureSkipVerify = h.Config.Insecure
+	dial := dialer.DialContext
+	if h.Config.NetworkNamespace != "" {
+		dial = networkNamespaceDial(h.Config.NetworkNamespace, dial)
+	}
 	h.transport = &http.Transport{
-		DialContext:     dialer.DialContext,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialAliased(ctx, dial, network, addr)
+		},
 		TLSClientConfig: tlsConfig,
 	}
+	if h.Config.ExpectedALPN != "" {
+		tlsConfig.NextProtos = append([]string(nil), alpnProtocols...)
+		h.transport.ForceAttemptHTTP2 = true
+	}
+	if h.Config.ConnectTo != nil {
+		connectTo := net.IP(h.Config.ConnectTo).String()
+		h.transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			_, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			return dial(ctx, network, net.JoinHostPort(connectTo, port))
+		}
+	}
+	if h.Config.Proxy != "" {
+		proxyURL, err := ParseProxy(h.Config.Proxy)
+		if err != nil {
+			return err
+		}
+		h.transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	auth, err := newHTTPAuth(h.Config.BasicAuth, h.Config.BearerToken)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to load the credentials for healthcheck %s", h.Config.Base.Name)
+	}
+	h.auth = auth
+	if h.Config.JSONSchema != nil {
+		schema, err := h.Config.JSONSchema.compile()
+		if err != nil {
+			return errors.Wrapf(err, "Fail to load the JSON schema for healthcheck %s", h.Config.Base.Name)
+		}
+		h.schema = schema
+	}
 	return nil
 }
 
@@ -173,6 +388,35 @@ func (h *HTTPHealthcheck) Base() Base {
 	return h.Config.Base
 }
 
+// timeout returns the healthcheck timeout
+func (h *HTTPHealthcheck) timeout() time.Duration {
+	return time.Duration(h.Config.Timeout)
+}
+
+// sourceChecks returns an healthcheck for each source IP
+func (h *HTTPHealthcheck) sourceChecks() []Healthcheck {
+	checks := make([]Healthcheck, 0, len(h.Config.SourceIPs))
+	for _, ip := range h.Config.SourceIPs {
+		config := h.Config.DeepCopy()
+		config.Base = sourceBase(config.Base, ip)
+		config.SourceIP = ip
+		config.SourceIPs = nil
+		config.SourceQuorum = 0
+		checks = append(checks, NewHTTPHealthcheck(h.Logger, config))
+	}
+	return checks
+}
+
+// sourceQuorum returns the number of sources which should succeed
+func (h *HTTPHealthcheck) sourceQuorum() int {
+	return quorum(h.Config.SourceQuorum, len(h.Config.SourceIPs))
+}
+
+// target returns the healthcheck target
+func (h *HTTPHealthcheck) target() string {
+	return h.Config.Target
+}
+
 // SetSource set the healthcheck source
 func (h *HTTPHealthcheck) SetSource(source string) {
 	h.Config.Base.Source = source
@@ -186,16 +430,14 @@ func (h *HTTPHealthcheck) isSuccessful(response *http.Response) bool {
 			return true
 		}
 	}
-	return false
+	return h.Config.ValidStatusRanges.contains(uint(response.StatusCode))
 }
 
 // LogError logs an error with context
 func (h *HTTPHealthcheck) LogError(err error, message string) {
-	h.Logger.Error(err.Error(),
-		zap.String("extra", message),
+	logError(h.Logger, h.Config.Base.Name, err, message,
 		zap.String("target", h.Config.Target),
-		zap.Uint("port", h.Config.Port),
-		zap.String("name", h.Config.Base.Name))
+		zap.Uint("port", h.Config.Port))
 }
 
 // LogDebug logs a message with context
@@ -215,18 +457,17 @@ func (h *HTTPHealthcheck) LogInfo(message string) {
 }
 
 // Execute executes an healthcheck on the given target
-func (h *HTTPHealthcheck) Execute() error {
-	h.LogDebug("start executing healthcheck")
-	ctx := h.t.Context(context.TODO())
-	body := bytes.NewBuffer([]byte(h.Config.Body))
-	req, err := http.NewRequest(h.Config.Method, h.URL, body)
-	if err != nil {
-		return errors.Wrapf(err, "fail to initialize HTTP request")
-	}
-	req.Header.Set("User-Agent", "Cabourotte")
-	for k, v := range h.Config.Headers {
-		req.Header.Set(k, v)
+func (h *HTTPHealthcheck) Execute(ctx context.Context) error {
+	err := h.execute(ctx)
+	if h.auth != nil {
+		return h.auth.redact(err)
 	}
+	return err
+}
+
+// execute executes the HTTP request and verifies the response
+func (h *HTTPHealthcheck) execute(ctx context.Context) error {
+	h.LogDebug("start executing healthcheck")
 	redirect := http.ErrUseLastResponse
 	if h.Config.Redirect {
 		redirect = nil
@@ -237,30 +478,185 @@ func (h *HTTPHealthcheck) Execute() error {
 			return redirect
 		},
 	}
-	timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(h.Config.Timeout))
+	timeoutCtx, cancel := context.WithTimeout(ctx, executionTimeout(ctx, time.Duration(h.Config.Timeout)))
 	defer cancel()
-	req = req.WithContext(timeoutCtx)
+	requestURL := h.URL
+	requestBody := h.Config.Body
+	headers := h.Config.Headers
+	if len(h.Config.Steps) != 0 {
+		var err error
+		client, err = newCookieClient(client)
+		if err != nil {
+			return err
+		}
+		variables, err := h.runHTTPSteps(timeoutCtx, client)
+		if err != nil {
+			return err
+		}
+		path, err := renderStep(h.Config.Path, variables)
+		if err != nil {
+			return errors.Wrap(err, "fail to render the healthcheck path")
+		}
+		requestURL = h.origin + path
+		requestBody, err = renderStep(requestBody, variables)
+		if err != nil {
+			return errors.Wrap(err, "fail to render the healthcheck body")
+		}
+		headers, err = renderHeaders(headers, variables)
+		if err != nil {
+			return err
+		}
+	}
+	body := bytes.NewBuffer([]byte(requestBody))
+	if h.Config.GRPCWeb != nil {
+		body = bytes.NewBuffer(h.Config.GRPCWeb.request())
+	}
+	req, err := http.NewRequest(h.Config.Method, requestURL, body)
+	if err != nil {
+		return errors.Wrapf(err, "fail to initialize HTTP request")
+	}
+	req.Header.Set("User-Agent", "Cabourotte")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if h.auth != nil {
+		h.auth.apply(req)
+	}
+	if h.Config.CacheValidation != nil {
+		h.Config.CacheValidation.apply(req)
+	}
+	if h.Config.GRPCWeb != nil {
+		h.Config.GRPCWeb.apply(req)
+	}
+	if h.Config.Compression != nil {
+		h.Config.Compression.apply(req)
+	}
+	phaseTimeouts := h.Config.PhaseTimeouts
+	if h.Config.HandshakeTimeout != 0 {
+		phaseTimeouts.TLS = h.Config.HandshakeTimeout
+	}
+	tracer := newPhaseTracer(phaseTimeouts, cancel)
+	defer tracer.stop()
+	req = req.WithContext(httptrace.WithClientTrace(timeoutCtx, tracer.clientTrace()))
 	response, err := client.Do(req)
+	tracer.addMetadata(ctx)
+	if h.Config.ResolvePTR {
+		if remoteAddr := tracer.getRemoteAddr(); remoteAddr != nil {
+			addPTRMetadata(ctx, remoteAddr)
+		} else {
+			addPTRMetadataForIP(ctx, h.Config.Target)
+		}
+	}
+	if handled, policyErr := applyDNSFailurePolicy(ctx, h.Config.DNSFailurePolicy, err); handled {
+		return policyErr
+	}
 	if err != nil {
+		if phaseErr := tracer.exceededError(); phaseErr != nil {
+			return phaseErr
+		}
 		return errors.Wrapf(err, "HTTP request failed")
 	}
 	defer response.Body.Close()
-	responseBody, err := ioutil.ReadAll(response.Body)
+	addIPMetadata(ctx, tracer.getRemoteAddr())
+	AddMetadata(ctx, "status-code", strconv.Itoa(response.StatusCode))
+	if response.TLS != nil {
+		AddMetadata(ctx, "tls-version", tlsVersionName(response.TLS.Version))
+		AddMetadata(ctx, "tls-cipher-suite", tls.CipherSuiteName(response.TLS.CipherSuite))
+	}
+	// the handshake is only measured on new connections
+	if duration := tracer.duration(phaseTLS); duration != 0 {
+		if err := h.Config.TLSHandshakeTimeouts.check(ctx, duration); err != nil {
+			return err
+		}
+	}
+	if err := checkTLSPolicy(ctx, h.Config.MinTLSVersion, h.Config.ForbiddenCiphers, response.TLS); err != nil {
+		return err
+	}
+	if err := checkALPN(ctx, h.Config.ExpectedALPN, response.TLS); err != nil {
+		return err
+	}
+	if err := checkOCSP(timeoutCtx, h.Config.OCSP, response.TLS); err != nil {
+		return err
+	}
+	if h.Config.IncludeResponseHeaders {
+		addHeadersMetadata(ctx, h.Config.ResponseHeaders, response.Header)
+	}
+	var responseBody []byte
+	var bodyHash string
+	var size int
+	var stream *streamResult
+	if h.Config.Stream != nil {
+		stream, err = readStream(response.Body, h.Config.Stream, h.Config.MaxBodySize)
+		if stream != nil {
+			responseBody = stream.content
+			size = len(responseBody)
+		}
+	} else if h.Config.BodySHA256 != "" {
+		// the body is only kept if it's verified
+		keep := len(h.Config.BodyRegexp) != 0 || h.Config.Metric != nil || h.Config.JSONSchema != nil
+		responseBody, bodyHash, size, err = readHashedBody(response.Body, h.Config.MaxBodySize, keep)
+	} else {
+		responseBody, err = ReadBody(response.Body, h.Config.MaxBodySize)
+		size = len(responseBody)
+	}
 	if err != nil {
+		var tooLarge *BodyTooLargeError
+		if errors.As(err, &tooLarge) {
+			AddMetadata(ctx, "reason", "body-too-large")
+		}
 		return errors.Wrapf(err, "Fail to read request body")
 	}
+	AddMetadata(ctx, "response-size", strconv.Itoa(size))
 	responseBodyStr := string(responseBody)
-	if !h.isSuccessful(response) {
+	if h.Config.CacheValidation != nil {
+		notModified, err := h.Config.CacheValidation.check(ctx, response)
+		if err != nil || notModified {
+			return err
+		}
+	} else if !h.isSuccessful(response) {
 		errorMsg := fmt.Sprintf("HTTP request failed: %d %s", response.StatusCode, html.EscapeString(responseBodyStr))
 		err = errors.New(errorMsg)
 		return err
 	}
+	if h.Config.SecurityHeaders != nil {
+		if err := h.Config.SecurityHeaders.check(ctx, response.Header); err != nil {
+			return err
+		}
+	}
+	if h.Config.Compression != nil {
+		responseBody, err = h.Config.Compression.check(ctx, response, responseBody, h.Config.MaxBodySize)
+		if err != nil {
+			return err
+		}
+		responseBodyStr = string(responseBody)
+	}
+	if h.Config.BodySHA256 != "" {
+		if err := checkBodyHash(ctx, h.Config.BodySHA256, bodyHash); err != nil {
+			return err
+		}
+	}
+	if h.Config.Stream != nil {
+		if err := checkStream(ctx, h.Config.Stream, stream); err != nil {
+			return err
+		}
+	}
 	for _, regex := range h.Config.BodyRegexp {
 		r := regexp.Regexp(regex)
 		if !r.MatchString(responseBodyStr) {
 			return fmt.Errorf("healthcheck body does not match regex %s: %s", r.String(), responseBodyStr)
 		}
 	}
+	if h.schema != nil {
+		if err := checkJSONSchema(ctx, h.schema, responseBody); err != nil {
+			return err
+		}
+	}
+	if h.Config.Metric != nil {
+		return h.Config.Metric.check(ctx, responseBody)
+	}
+	if h.Config.GRPCWeb != nil {
+		return h.Config.GRPCWeb.check(ctx, response, responseBody)
+	}
 	return nil
 }
 
@@ -286,6 +682,11 @@ func (in *HTTPHealthcheckConfiguration) DeepCopyInto(out *HTTPHealthcheckConfigu
 		*out = make([]uint, len(*in))
 		copy(*out, *in)
 	}
+	if in.ValidStatusRanges != nil {
+		in, out := &in.ValidStatusRanges, &out.ValidStatusRanges
+		*out = make(StatusRanges, len(*in))
+		copy(*out, *in)
+	}
 	if in.Headers != nil {
 		in, out := &in.Headers, &out.Headers
 		*out = make(map[string]string, len(*in))
@@ -298,6 +699,24 @@ func (in *HTTPHealthcheckConfiguration) DeepCopyInto(out *HTTPHealthcheckConfigu
 		*out = make(IP, len(*in))
 		copy(*out, *in)
 	}
+	if in.SourceIPs != nil {
+		in, out := &in.SourceIPs, &out.SourceIPs
+		*out = make([]IP, len(*in))
+		for i := range *in {
+			(*out)[i] = make(IP, len((*in)[i]))
+			copy((*out)[i], (*in)[i])
+		}
+	}
+	if in.ForbiddenCiphers != nil {
+		in, out := &in.ForbiddenCiphers, &out.ForbiddenCiphers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ConnectTo != nil {
+		in, out := &in.ConnectTo, &out.ConnectTo
+		*out = make(IP, len(*in))
+		copy(*out, *in)
+	}
 	if in.BodyRegexp != nil {
 		in, out := &in.BodyRegexp, &out.BodyRegexp
 		*out = make([]Regexp, len(*in))
@@ -305,6 +724,68 @@ func (in *HTTPHealthcheckConfiguration) DeepCopyInto(out *HTTPHealthcheckConfigu
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ResponseHeaders != nil {
+		in, out := &in.ResponseHeaders, &out.ResponseHeaders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Metric != nil {
+		in, out := &in.Metric, &out.Metric
+		*out = new(MetricAssertion)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BasicAuth != nil {
+		in, out := &in.BasicAuth, &out.BasicAuth
+		*out = new(BasicAuth)
+		**out = **in
+	}
+	if in.BearerToken != nil {
+		in, out := &in.BearerToken, &out.BearerToken
+		*out = new(Secret)
+		**out = **in
+	}
+	if in.CacheValidation != nil {
+		in, out := &in.CacheValidation, &out.CacheValidation
+		*out = new(CacheValidation)
+		**out = **in
+	}
+	if in.OCSP != nil {
+		in, out := &in.OCSP, &out.OCSP
+		*out = new(OCSPAssertion)
+		**out = **in
+	}
+	if in.Stream != nil {
+		in, out := &in.Stream, &out.Stream
+		*out = new(StreamAssertion)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GRPCWeb != nil {
+		in, out := &in.GRPCWeb, &out.GRPCWeb
+		*out = new(GRPCWebAssertion)
+		**out = **in
+	}
+	if in.Compression != nil {
+		in, out := &in.Compression, &out.Compression
+		*out = new(CompressionAssertion)
+		**out = **in
+	}
+	if in.SecurityHeaders != nil {
+		in, out := &in.SecurityHeaders, &out.SecurityHeaders
+		*out = new(SecurityHeadersAssertion)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.JSONSchema != nil {
+		in, out := &in.JSONSchema, &out.JSONSchema
+		*out = new(JSONSchemaAssertion)
+		**out = **in
+	}
+	if in.Steps != nil {
+		in, out := &in.Steps, &out.Steps
+		*out = make([]HTTPStep, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPHealthcheckConfiguration.
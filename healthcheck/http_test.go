@@ -1,10 +1,14 @@
 package healthcheck
 
 import (
+	"context"
+	"encoding/pem"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -104,7 +108,7 @@ func TestHTTPExecuteGetSuccess(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Initialization error :\n%v", err)
 	}
-	err = h.Execute()
+	err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -154,7 +158,7 @@ func TestHTTPExecuteRegexpSuccess(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Initialization error :\n%v", err)
 	}
-	err = h.Execute()
+	err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -198,7 +202,7 @@ func TestHTTPExecuteRegexpFailure(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Initialization error :\n%v", err)
 	}
-	err = h.Execute()
+	err = h.Execute(context.Background())
 	if err == nil {
 		t.Fatalf("Was expecting an error")
 	}
@@ -240,7 +244,7 @@ func TestHTTPv6ExecuteSuccess(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Initialization error :\n%v", err)
 	}
-	err = h.Execute()
+	err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -279,7 +283,7 @@ func TestHTTPExecuteFailure(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Initialization error :\n%v", err)
 	}
-	err = h.Execute()
+	err = h.Execute(context.Background())
 	if err == nil {
 		t.Fatalf("Was expecting an error")
 	}
@@ -403,7 +407,7 @@ func TestHTTPExecuteSourceIP(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Initialization error :\n%v", err)
 	}
-	err = h.Execute()
+	err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -466,7 +470,7 @@ func TestHTTPExecutePostSuccess(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Initialization error :\n%v", err)
 	}
-	err = h.Execute()
+	err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -480,3 +484,121 @@ func TestHTTPExecutePostSuccess(t *testing.T) {
 		t.Fatal("Invalid body")
 	}
 }
+
+func TestHTTPExecutePhaseTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	h := HTTPHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &HTTPHealthcheckConfiguration{
+			ValidStatus: []uint{200},
+			Port:        uint(port),
+			Target:      "127.0.0.1",
+			Protocol:    HTTP,
+			Path:        "/",
+			Timeout:     Duration(time.Second * 2),
+			PhaseTimeouts: PhaseTimeouts{
+				Connect:   Duration(time.Second),
+				FirstByte: Duration(100 * time.Millisecond),
+			},
+		},
+	}
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	ctx := WithMetadata(context.Background())
+	err = h.Execute(ctx)
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	if !strings.Contains(err.Error(), "first-byte phase") {
+		t.Fatalf("Invalid error message %s", err.Error())
+	}
+	metadata := GetMetadata(ctx)
+	if metadata["timeout-phase"] != "first-byte" {
+		t.Fatalf("Invalid metadata %v", metadata)
+	}
+	if _, ok := metadata["connect-duration"]; !ok {
+		t.Fatalf("The connect duration is missing from the metadata %v", metadata)
+	}
+}
+
+func TestHTTPExecuteConnectTo(t *testing.T) {
+	host := ""
+	serverName := ""
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.Host
+		serverName = r.TLS.ServerName
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	cacert := filepath.Join(t.TempDir(), "ca.pem")
+	content := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err := os.WriteFile(cacert, content, 0600); err != nil {
+		t.Fatalf("Fail to write the certificate: %v", err)
+	}
+	config := &HTTPHealthcheckConfiguration{
+		Base: Base{
+			Name:     "foo",
+			Interval: Duration(10 * time.Second),
+		},
+		ValidStatus: []uint{200},
+		Port:        uint(port),
+		// the httptest certificate is valid for example.com
+		Target:    "example.com",
+		ConnectTo: IP(net.ParseIP("127.0.0.1")),
+		Protocol:  HTTPS,
+		Path:      "/",
+		Cacert:    cacert,
+		Timeout:   Duration(time.Second * 2),
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Invalid configuration: %v", err)
+	}
+	h := NewHTTPHealthcheck(zap.NewExample(), config)
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	err = h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	if host != "example.com:"+strconv.FormatUint(port, 10) || serverName != "example.com" {
+		t.Fatalf("Invalid host %s or server name %s", host, serverName)
+	}
+
+	config.Target = "cabourotte.invalid"
+	h = NewHTTPHealthcheck(zap.NewExample(), config)
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	err = h.Execute(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Fatalf("The certificate verification should fail: %v", err)
+	}
+
+	config.Proxy = "http://127.0.0.1:3128"
+	if err := config.Validate(); err == nil {
+		t.Fatalf("connect-to and proxy should not be allowed together")
+	}
+	config.Proxy = ""
+	config.ConnectTo = IP{1, 2}
+	if err := config.Validate(); err == nil {
+		t.Fatalf("connect-to should be a valid IP")
+	}
+}
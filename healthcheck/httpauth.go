@@ -0,0 +1,192 @@
+package healthcheck
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// redacted replaces the credentials in the API responses and in the
+// healthchecks messages
+const redacted = "<redacted>"
+
+// Secret a credential, set directly or read from a file or from an
+// environment variable
+type Secret struct {
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+	File  string `json:"file,omitempty" yaml:"file,omitempty"`
+	Env   string `json:"env,omitempty" yaml:"env,omitempty"`
+}
+
+// Validate validates the secret configuration
+func (s *Secret) Validate() error {
+	count := 0
+	for _, source := range []string{s.Value, s.File, s.Env} {
+		if source != "" {
+			count++
+		}
+	}
+	if count != 1 {
+		return errors.New("A secret should be defined using exactly one of value, file or env")
+	}
+	return nil
+}
+
+// Resolve returns the secret value
+func (s *Secret) Resolve() (string, error) {
+	if s.File != "" {
+		content, err := ioutil.ReadFile(s.File)
+		if err != nil {
+			return "", errors.Wrapf(err, "Fail to read the secret file %s", s.File)
+		}
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}
+	if s.Env != "" {
+		value, ok := os.LookupEnv(s.Env)
+		if !ok {
+			return "", fmt.Errorf("the environment variable %s is not set", s.Env)
+		}
+		return value, nil
+	}
+	return s.Value, nil
+}
+
+// MarshalJSON marshal to json a secret, without its value
+func (s Secret) MarshalJSON() ([]byte, error) {
+	type rawSecret Secret
+	raw := rawSecret(s)
+	if raw.Value != "" {
+		raw.Value = redacted
+	}
+	return json.Marshal(raw)
+}
+
+// BasicAuth the basic auth credentials of an healthcheck
+type BasicAuth struct {
+	Username string `json:"username"`
+	Password Secret `json:"password"`
+}
+
+// Validate validates the basic auth configuration
+func (b *BasicAuth) Validate() error {
+	if b.Username == "" {
+		return errors.New("The basic auth username is missing")
+	}
+	if err := b.Password.Validate(); err != nil {
+		return errors.Wrap(err, "Invalid basic auth password")
+	}
+	return nil
+}
+
+// validateAuth validates the authentication options of an HTTP healthcheck
+func validateAuth(basicAuth *BasicAuth, bearerToken *Secret, headers map[string]string) error {
+	if basicAuth != nil && bearerToken != nil {
+		return errors.New("The basic-auth and bearer-token options are mutually exclusive")
+	}
+	if basicAuth == nil && bearerToken == nil {
+		return nil
+	}
+	for k := range headers {
+		if http.CanonicalHeaderKey(k) == "Authorization" {
+			return errors.New("The Authorization header can't be used with the basic-auth or bearer-token options")
+		}
+	}
+	if basicAuth != nil {
+		if err := basicAuth.Validate(); err != nil {
+			return err
+		}
+	}
+	if bearerToken != nil {
+		if err := bearerToken.Validate(); err != nil {
+			return errors.Wrap(err, "Invalid bearer token")
+		}
+	}
+	return nil
+}
+
+// httpAuth the resolved credentials of an HTTP healthcheck
+type httpAuth struct {
+	username string
+	password string
+	token    string
+}
+
+// newHTTPAuth resolves the credentials of an HTTP healthcheck, returning nil
+// if no authentication is configured
+func newHTTPAuth(basicAuth *BasicAuth, bearerToken *Secret) (*httpAuth, error) {
+	if basicAuth != nil {
+		password, err := basicAuth.Password.Resolve()
+		if err != nil {
+			return nil, err
+		}
+		return &httpAuth{username: basicAuth.Username, password: password}, nil
+	}
+	if bearerToken != nil {
+		token, err := bearerToken.Resolve()
+		if err != nil {
+			return nil, err
+		}
+		return &httpAuth{token: token}, nil
+	}
+	return nil, nil
+}
+
+// apply sets the credentials on a request
+func (a *httpAuth) apply(req *http.Request) {
+	if a.token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", a.token))
+		return
+	}
+	req.SetBasicAuth(a.username, a.password)
+}
+
+// redactedError an error whose message does not contain the credentials. The
+// original error is kept for errors.Is, errors.As and errors.Cause.
+type redactedError struct {
+	message string
+	err     error
+}
+
+func (e *redactedError) Error() string {
+	return e.message
+}
+
+// Unwrap returns the original error
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// Cause returns the original error
+func (e *redactedError) Cause() error {
+	return e.err
+}
+
+// redact removes the credentials from an error, the target may for example
+// echo the request headers in its response
+func (a *httpAuth) redact(err error) error {
+	if err == nil {
+		return nil
+	}
+	message := err.Error()
+	secrets := []string{a.password, a.token}
+	if a.password != "" {
+		secrets = append(secrets, base64.StdEncoding.EncodeToString([]byte(a.username+":"+a.password)))
+	}
+	found := false
+	for _, secret := range secrets {
+		if secret != "" && strings.Contains(message, secret) {
+			message = strings.ReplaceAll(message, secret, redacted)
+			found = true
+		}
+	}
+	if !found {
+		return err
+	}
+	return &redactedError{message: message, err: err}
+}
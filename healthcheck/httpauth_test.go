@@ -0,0 +1,144 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+func TestValidateAuth(t *testing.T) {
+	token := &Secret{Value: "token"}
+	basic := &BasicAuth{Username: "user", Password: Secret{Env: "PASSWORD"}}
+	if err := validateAuth(basic, nil, nil); err != nil {
+		t.Fatalf("Valid basic auth rejected: %v", err)
+	}
+	if err := validateAuth(nil, token, map[string]string{"Foo": "Bar"}); err != nil {
+		t.Fatalf("Valid bearer token rejected: %v", err)
+	}
+	if err := validateAuth(basic, token, nil); err == nil {
+		t.Fatalf("basic-auth and bearer-token should be mutually exclusive")
+	}
+	if err := validateAuth(nil, token, map[string]string{"authorization": "foo"}); err == nil {
+		t.Fatalf("The Authorization header should be rejected")
+	}
+	if err := validateAuth(nil, &Secret{Value: "a", File: "/tmp/a"}, nil); err == nil {
+		t.Fatalf("A secret with several sources should be rejected")
+	}
+	if err := validateAuth(&BasicAuth{Password: Secret{Value: "a"}}, nil, nil); err == nil {
+		t.Fatalf("A basic auth without username should be rejected")
+	}
+}
+
+func TestSecretMarshalJSON(t *testing.T) {
+	config := HTTPHealthcheckConfiguration{
+		Protocol:  HTTP,
+		BasicAuth: &BasicAuth{Username: "user", Password: Secret{Value: "my-password"}},
+	}
+	result, err := json.Marshal(&config)
+	if err != nil {
+		t.Fatalf("Fail to marshal the configuration: %v", err)
+	}
+	if strings.Contains(string(result), "my-password") {
+		t.Fatalf("The password is present in the JSON configuration: %s", string(result))
+	}
+}
+
+func TestHTTPExecuteAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cabourotte")
+	if err != nil {
+		t.Fatalf("Fail to create the temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	err = ioutil.WriteFile(tokenFile, []byte("my-token\n"), 0600)
+	if err != nil {
+		t.Fatalf("Fail to write the token file: %v", err)
+	}
+	os.Setenv("CABOUROTTE_TEST_PASSWORD", "my-password")
+	defer os.Unsetenv("CABOUROTTE_TEST_PASSWORD")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		username, password, ok := r.BasicAuth()
+		if authorization == "Bearer my-token" || (ok && username == "user" && password == "my-password") {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		// echo the header to verify that it is redacted
+		w.WriteHeader(http.StatusUnauthorized)
+		_, err := w.Write([]byte(fmt.Sprintf("invalid credentials %s", authorization)))
+		if err != nil {
+			t.Fatalf("Error writing :\n%v", err)
+		}
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	cases := []struct {
+		basicAuth   *BasicAuth
+		bearerToken *Secret
+		success     bool
+		secret      string
+	}{
+		{bearerToken: &Secret{File: tokenFile}, success: true},
+		{basicAuth: &BasicAuth{Username: "user", Password: Secret{Env: "CABOUROTTE_TEST_PASSWORD"}}, success: true},
+		{bearerToken: &Secret{Value: "invalid-token"}, secret: "invalid-token"},
+		{basicAuth: &BasicAuth{Username: "user", Password: Secret{Value: "invalid"}}, secret: "dXNlcjppbnZhbGlk"},
+	}
+	for _, c := range cases {
+		h := HTTPHealthcheck{
+			Logger: zap.NewExample(),
+			Config: &HTTPHealthcheckConfiguration{
+				ValidStatus: []uint{200},
+				Port:        uint(port),
+				Target:      "127.0.0.1",
+				Protocol:    HTTP,
+				Path:        "/",
+				Timeout:     Duration(time.Second * 2),
+				BasicAuth:   c.basicAuth,
+				BearerToken: c.bearerToken,
+			},
+		}
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		err = h.Execute(context.Background())
+		if c.success && err != nil {
+			t.Fatalf("healthcheck error :\n%v", err)
+		}
+		if !c.success {
+			if err == nil {
+				t.Fatalf("Was expecting an error")
+			}
+			if strings.Contains(err.Error(), c.secret) || !strings.Contains(err.Error(), redacted) {
+				t.Fatalf("The credentials are not redacted: %s", err.Error())
+			}
+		}
+	}
+}
+
+func TestRedactKeepsError(t *testing.T) {
+	auth := &httpAuth{token: "secret-token"}
+	sentinel := errors.New("connection refused")
+	err := auth.redact(errors.Wrap(sentinel, "request with secret-token failed"))
+	if strings.Contains(err.Error(), "secret-token") || !strings.Contains(err.Error(), redacted) {
+		t.Fatalf("The credentials are not redacted: %s", err.Error())
+	}
+	if !errors.Is(err, sentinel) || errors.Cause(err) != sentinel {
+		t.Fatalf("The original error should be kept: %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CacheValidation sends a conditional request with the expected ETag and/or
+// Last-Modified values. The healthcheck is successful if the response is a
+// 304, or a 200 with the expected ETag (the expected Last-Modified if no ETag
+// is configured).
+type CacheValidation struct {
+	ETag         string `json:"etag,omitempty" yaml:"etag,omitempty"`
+	LastModified string `json:"last-modified,omitempty" yaml:"last-modified,omitempty"`
+}
+
+// Validate validates the cache validation
+func (c *CacheValidation) Validate() error {
+	if c.ETag == "" && c.LastModified == "" {
+		return errors.New("The cache validation requires an etag or a last-modified value")
+	}
+	if c.LastModified != "" {
+		if _, err := http.ParseTime(c.LastModified); err != nil {
+			return errors.Wrapf(err, "Invalid cache validation last-modified value %s", c.LastModified)
+		}
+	}
+	return nil
+}
+
+// etag returns the expected ETag, quoted if needed
+func (c *CacheValidation) etag() string {
+	if strings.HasPrefix(c.ETag, "\"") || strings.HasPrefix(c.ETag, "W/\"") {
+		return c.ETag
+	}
+	return fmt.Sprintf("\"%s\"", c.ETag)
+}
+
+// apply adds the conditional headers to the request
+func (c *CacheValidation) apply(req *http.Request) {
+	if c.ETag != "" {
+		req.Header.Set("If-None-Match", c.etag())
+	}
+	if c.LastModified != "" {
+		req.Header.Set("If-Modified-Since", c.LastModified)
+	}
+}
+
+// sameETag compares two ETags, ignoring the weak indicator
+func sameETag(a string, b string) bool {
+	return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+}
+
+// check verifies the response of the conditional request. The observed ETag
+// is added to the result metadata. Returns true if the response is a 304.
+func (c *CacheValidation) check(ctx context.Context, response *http.Response) (bool, error) {
+	observed := response.Header.Get("ETag")
+	if observed != "" {
+		AddMetadata(ctx, "etag", observed)
+	}
+	switch response.StatusCode {
+	case http.StatusNotModified:
+		return true, nil
+	case http.StatusOK:
+		if c.ETag != "" {
+			if !sameETag(observed, c.etag()) {
+				AddMetadata(ctx, "reason", "cache-validation")
+				return false, fmt.Errorf("Unexpected ETag %s, expected %s", observed, c.etag())
+			}
+			return false, nil
+		}
+		lastModified := response.Header.Get("Last-Modified")
+		if lastModified != c.LastModified {
+			AddMetadata(ctx, "reason", "cache-validation")
+			return false, fmt.Errorf("The content was modified since %s (last modified %s)", c.LastModified, lastModified)
+		}
+		return false, nil
+	}
+	AddMetadata(ctx, "reason", "cache-validation")
+	return false, fmt.Errorf("Unexpected status %d for the conditional request, expected 304 or 200", response.StatusCode)
+}
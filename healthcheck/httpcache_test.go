@@ -0,0 +1,85 @@
+package healthcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestHTTPExecuteCacheValidation(t *testing.T) {
+	etag := "\"v1\""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if r.Header.Get("If-None-Match") == "\"stale\"" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("Error getting HTTP server port :\n%v", err)
+	}
+	cases := []struct {
+		validation CacheValidation
+		success    bool
+		reason     string
+	}{
+		{validation: CacheValidation{ETag: "v1"}, success: true},
+		{validation: CacheValidation{ETag: "W/\"v1\""}, success: true},
+		{validation: CacheValidation{ETag: "v2"}, success: false, reason: "cache-validation"},
+		{validation: CacheValidation{ETag: "stale"}, success: false, reason: "cache-validation"},
+		{validation: CacheValidation{LastModified: "Wed, 21 Oct 2015 07:28:00 GMT"}, success: false, reason: "cache-validation"},
+	}
+	for i, c := range cases {
+		validation := c.validation
+		h := NewHTTPHealthcheck(zap.NewExample(), &HTTPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Second * 10),
+				OneOff:   false,
+			},
+			Target:          "127.0.0.1",
+			Port:            uint(port),
+			Protocol:        HTTP,
+			Timeout:         Duration(time.Second * 3),
+			CacheValidation: &validation,
+		})
+		err := h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		ctx := WithMetadata(context.Background())
+		err = h.Execute(ctx)
+		if c.success != (err == nil) {
+			t.Fatalf("Invalid result for case %d: %v", i, err)
+		}
+		metadata := GetMetadata(ctx)
+		if metadata["reason"] != c.reason || metadata["etag"] != etag {
+			t.Fatalf("Invalid metadata for case %d: %v", i, metadata)
+		}
+	}
+}
+
+func TestCacheValidationValidate(t *testing.T) {
+	if (&CacheValidation{}).Validate() == nil {
+		t.Fatalf("An etag or a last-modified value should be required")
+	}
+	if (&CacheValidation{LastModified: "yesterday"}).Validate() == nil {
+		t.Fatalf("The last-modified value should be an HTTP date")
+	}
+	if err := (&CacheValidation{LastModified: "Wed, 21 Oct 2015 07:28:00 GMT"}).Validate(); err != nil {
+		t.Fatalf("Invalid last-modified value:\n%v", err)
+	}
+}
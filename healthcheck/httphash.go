@@ -0,0 +1,63 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// validateBodySHA256 verifies that the expected body hash is an hex encoded
+// SHA-256
+func validateBodySHA256(expected string) error {
+	if expected == "" {
+		return nil
+	}
+	decoded, err := hex.DecodeString(expected)
+	if err != nil || len(decoded) != sha256.Size {
+		return fmt.Errorf("Invalid body-sha256 %s, an hex encoded SHA-256 is expected", expected)
+	}
+	return nil
+}
+
+// readHashedBody streams a body in a SHA-256 hash, without buffering it if
+// keep is false. Returns the body if kept, its hex encoded hash and its size.
+// A BodyTooLargeError is returned if the body is larger than the limit,
+// DefaultMaxBodySize is used if the limit is 0.
+func readHashedBody(body io.Reader, limit uint, keep bool) ([]byte, string, int, error) {
+	if limit == 0 {
+		limit = DefaultMaxBodySize
+	}
+	hash := sha256.New()
+	reader := io.TeeReader(io.LimitReader(body, int64(limit)+1), hash)
+	var content []byte
+	var size int64
+	var err error
+	if keep {
+		content, err = ioutil.ReadAll(reader)
+		size = int64(len(content))
+	} else {
+		size, err = io.Copy(ioutil.Discard, reader)
+	}
+	if err != nil {
+		return nil, "", 0, err
+	}
+	if uint64(size) > uint64(limit) {
+		return nil, "", 0, &BodyTooLargeError{Limit: limit}
+	}
+	return content, hex.EncodeToString(hash.Sum(nil)), int(size), nil
+}
+
+// checkBodyHash compares the body hash to the expected one, the actual hash
+// is added to the metadata
+func checkBodyHash(ctx context.Context, expected string, actual string) error {
+	AddMetadata(ctx, "body-sha256", actual)
+	if !strings.EqualFold(expected, actual) {
+		AddMetadata(ctx, "reason", "body-hash-mismatch")
+		return fmt.Errorf("The body SHA-256 %s does not match the expected hash %s", actual, expected)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestHTTPExecuteBodySHA256(t *testing.T) {
+	content := "the served configuration"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(content))
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("Error getting HTTP server port :\n%v", err)
+	}
+	sum := sha256.Sum256([]byte(content))
+	expected := hex.EncodeToString(sum[:])
+	other := sha256.Sum256([]byte("another content"))
+	regex := Regexp(*regexp.MustCompile("configuration"))
+	cases := []struct {
+		hash        string
+		regexp      []Regexp
+		maxBodySize uint
+		success     bool
+		reason      string
+	}{
+		{hash: expected, success: true},
+		{hash: strings.ToUpper(expected), regexp: []Regexp{regex}, success: true},
+		{hash: hex.EncodeToString(other[:]), success: false, reason: "body-hash-mismatch"},
+		{hash: expected, maxBodySize: 4, success: false, reason: "body-too-large"},
+	}
+	for i, c := range cases {
+		h := NewHTTPHealthcheck(zap.NewExample(), &HTTPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Second * 10),
+			},
+			ValidStatus: []uint{200},
+			Target:      "127.0.0.1",
+			Port:        uint(port),
+			Protocol:    HTTP,
+			Timeout:     Duration(time.Second * 3),
+			BodySHA256:  c.hash,
+			BodyRegexp:  c.regexp,
+			MaxBodySize: c.maxBodySize,
+		})
+		err := h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error\n%v", err)
+		}
+		ctx := WithMetadata(context.Background())
+		err = h.Execute(ctx)
+		metadata := GetMetadata(ctx)
+		if c.success && err != nil {
+			t.Fatalf("healthcheck %d error\n%v", i, err)
+		}
+		if !c.success && (err == nil || metadata["reason"] != c.reason) {
+			t.Fatalf("healthcheck %d should fail with the reason %s: %v", i, c.reason, metadata)
+		}
+		if c.reason != "body-too-large" && metadata["body-sha256"] != expected {
+			t.Fatalf("Invalid body hash metadata %v", metadata)
+		}
+		if c.reason != "body-too-large" && metadata["response-size"] != strconv.Itoa(len(content)) {
+			t.Fatalf("Invalid response size metadata %v", metadata)
+		}
+	}
+}
+
+func TestValidateBodySHA256(t *testing.T) {
+	if err := validateBodySHA256(""); err != nil {
+		t.Fatalf("An empty hash should be valid\n%v", err)
+	}
+	sum := sha256.Sum256([]byte("foo"))
+	if err := validateBodySHA256(hex.EncodeToString(sum[:])); err != nil {
+		t.Fatalf("The hash should be valid\n%v", err)
+	}
+	for _, hash := range []string{"abc", "zz" + hex.EncodeToString(sum[1:])} {
+		if err := validateBodySHA256(hash); err == nil {
+			t.Fatalf("The hash %s should be invalid", hash)
+		}
+	}
+}
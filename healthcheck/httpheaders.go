@@ -0,0 +1,77 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxHeaderValueSize the maximum size of a captured header value
+	maxHeaderValueSize = 256
+	// maxHeadersSize the maximum total size of the captured headers
+	maxHeadersSize = 2048
+)
+
+// defaultResponseHeaders the headers captured when no allow-list is provided
+var defaultResponseHeaders = []string{
+	"Age",
+	"Cache-Control",
+	"Content-Type",
+	"Location",
+	"Retry-After",
+	"Server",
+	"Via",
+	"X-Cache",
+}
+
+// sensitiveHeaders the headers which can never be captured
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Set-Cookie":          true,
+	"Www-Authenticate":    true,
+}
+
+// validateResponseHeaders verifies that no sensitive header is in the allow-list
+func validateResponseHeaders(headers []string) error {
+	for _, header := range headers {
+		if sensitiveHeaders[http.CanonicalHeaderKey(header)] {
+			return fmt.Errorf("The header %s can not be included in the healthcheck results", header)
+		}
+	}
+	return nil
+}
+
+// addHeadersMetadata adds the allowed response headers to the result metadata.
+// Values are truncated, and headers are dropped once the total size is reached.
+func addHeadersMetadata(ctx context.Context, allowList []string, headers http.Header) {
+	if len(allowList) == 0 {
+		allowList = defaultResponseHeaders
+	}
+	names := make([]string, 0, len(allowList))
+	for _, name := range allowList {
+		names = append(names, http.CanonicalHeaderKey(name))
+	}
+	sort.Strings(names)
+	size := 0
+	for _, name := range names {
+		values, ok := headers[name]
+		if !ok || sensitiveHeaders[name] {
+			continue
+		}
+		value := strings.Join(values, ", ")
+		if len(value) > maxHeaderValueSize {
+			value = value[:maxHeaderValueSize]
+		}
+		size += len(name) + len(value)
+		if size > maxHeadersSize {
+			return
+		}
+		AddMetadata(ctx, "header-"+strings.ToLower(name), value)
+	}
+}
@@ -0,0 +1,43 @@
+package healthcheck
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAddHeadersMetadata(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Server", "nginx")
+	headers.Set("Retry-After", "120")
+	headers.Set("Set-Cookie", "secret")
+	headers.Set("X-Long", strings.Repeat("a", 1000))
+	ctx := WithMetadata(context.Background())
+	addHeadersMetadata(ctx, nil, headers)
+	metadata := GetMetadata(ctx)
+	if metadata["header-server"] != "nginx" || metadata["header-retry-after"] != "120" {
+		t.Fatalf("Invalid metadata %v", metadata)
+	}
+	if _, ok := metadata["header-x-long"]; ok {
+		t.Fatalf("The header should not be captured by default %v", metadata)
+	}
+	ctx = WithMetadata(context.Background())
+	addHeadersMetadata(ctx, []string{"x-long", "set-cookie"}, headers)
+	metadata = GetMetadata(ctx)
+	if len(metadata["header-x-long"]) != maxHeaderValueSize {
+		t.Fatalf("The header should be truncated %v", metadata)
+	}
+	if _, ok := metadata["header-set-cookie"]; ok {
+		t.Fatalf("Sensitive headers should never be captured %v", metadata)
+	}
+}
+
+func TestValidateResponseHeaders(t *testing.T) {
+	if err := validateResponseHeaders([]string{"server", "x-cache"}); err != nil {
+		t.Fatalf("The headers should be valid: %v", err)
+	}
+	if err := validateResponseHeaders([]string{"server", "authorization"}); err == nil {
+		t.Fatalf("The authorization header should be rejected")
+	}
+}
@@ -0,0 +1,115 @@
+package healthcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// StatusRange a range of HTTP status codes, bounds included
+type StatusRange struct {
+	Min uint
+	Max uint
+}
+
+// StatusRanges a list of HTTP status codes ranges, for example
+// "200-299,401,403"
+type StatusRanges []StatusRange
+
+// contains returns true if the status code is in one of the ranges
+func (r StatusRanges) contains(status uint) bool {
+	for _, statusRange := range r {
+		if status >= statusRange.Min && status <= statusRange.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// parseStatus parses a status code
+func parseStatus(s string) (uint, error) {
+	status, err := strconv.ParseUint(strings.TrimSpace(s), 10, 16)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Invalid status code %s", s)
+	}
+	if status < 100 || status > 599 {
+		return 0, fmt.Errorf("Invalid status code %d, should be between 100 and 599", status)
+	}
+	return uint(status), nil
+}
+
+// parseStatusRanges parses a comma separated list of status codes ranges
+func parseStatusRanges(s string) (StatusRanges, error) {
+	result := StatusRanges{}
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			return nil, fmt.Errorf("Invalid status codes ranges %s", s)
+		}
+		bounds := strings.SplitN(part, "-", 2)
+		min, err := parseStatus(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		max := min
+		if len(bounds) == 2 {
+			max, err = parseStatus(bounds[1])
+			if err != nil {
+				return nil, err
+			}
+		}
+		if min > max {
+			return nil, fmt.Errorf("Invalid status codes range %s", part)
+		}
+		result = append(result, StatusRange{Min: min, Max: max})
+	}
+	return result, nil
+}
+
+// String returns the ranges using the configuration format
+func (r StatusRanges) String() string {
+	parts := make([]string, 0, len(r))
+	for _, statusRange := range r {
+		if statusRange.Min == statusRange.Max {
+			parts = append(parts, strconv.FormatUint(uint64(statusRange.Min), 10))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", statusRange.Min, statusRange.Max))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// UnmarshalYAML read status codes ranges from yaml
+func (r *StatusRanges) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw string
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the status codes ranges")
+	}
+	ranges, err := parseStatusRanges(raw)
+	if err != nil {
+		return err
+	}
+	*r = ranges
+	return nil
+}
+
+// UnmarshalJSON read status codes ranges from json
+func (r *StatusRanges) UnmarshalJSON(text []byte) error {
+	var raw string
+	if err := json.Unmarshal(text, &raw); err != nil {
+		return errors.Wrap(err, "Unable to read the status codes ranges")
+	}
+	ranges, err := parseStatusRanges(raw)
+	if err != nil {
+		return err
+	}
+	*r = ranges
+	return nil
+}
+
+// MarshalJSON marshal to json status codes ranges
+func (r StatusRanges) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
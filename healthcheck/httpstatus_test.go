@@ -0,0 +1,80 @@
+package healthcheck
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseStatusRanges(t *testing.T) {
+	ranges, err := parseStatusRanges("200-299, 401,403")
+	if err != nil {
+		t.Fatalf("Fail to parse the ranges: %v", err)
+	}
+	expected := StatusRanges{{Min: 200, Max: 299}, {Min: 401, Max: 401}, {Min: 403, Max: 403}}
+	if len(ranges) != len(expected) {
+		t.Fatalf("Invalid ranges %v", ranges)
+	}
+	for i := range expected {
+		if ranges[i] != expected[i] {
+			t.Fatalf("Invalid ranges %v", ranges)
+		}
+	}
+	if ranges.String() != "200-299,401,403" {
+		t.Fatalf("Invalid string %s", ranges.String())
+	}
+	for _, invalid := range []string{"", "200,", "299-200", "abc", "200-600", "42"} {
+		if _, err := parseStatusRanges(invalid); err == nil {
+			t.Fatalf("Was expecting an error for %s", invalid)
+		}
+	}
+}
+
+func TestStatusRangesUnmarshal(t *testing.T) {
+	var config struct {
+		Ranges StatusRanges `json:"ranges" yaml:"ranges"`
+	}
+	err := yaml.Unmarshal([]byte("ranges: 200-204,401"), &config)
+	if err != nil {
+		t.Fatalf("Fail to read the yaml ranges: %v", err)
+	}
+	if !config.Ranges.contains(204) || !config.Ranges.contains(401) || config.Ranges.contains(403) {
+		t.Fatalf("Invalid ranges %v", config.Ranges)
+	}
+	err = yaml.Unmarshal([]byte("ranges: 200-100"), &config)
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	err = json.Unmarshal([]byte(`{"ranges": "500-599"}`), &config)
+	if err != nil {
+		t.Fatalf("Fail to read the json ranges: %v", err)
+	}
+	result, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("Fail to marshal the ranges: %v", err)
+	}
+	if string(result) != `{"ranges":"500-599"}` {
+		t.Fatalf("Invalid json %s", string(result))
+	}
+}
+
+func TestIsSuccessfulRanges(t *testing.T) {
+	h := HTTPHealthcheck{
+		Config: &HTTPHealthcheckConfiguration{
+			ValidStatus:       []uint{201},
+			ValidStatusRanges: StatusRanges{{Min: 200, Max: 200}, {Min: 400, Max: 403}},
+		},
+	}
+	for _, status := range []int{200, 201, 401, 403} {
+		if !h.isSuccessful(&http.Response{StatusCode: status}) {
+			t.Fatalf("Status %d should be valid", status)
+		}
+	}
+	for _, status := range []int{204, 404, 500} {
+		if h.isSuccessful(&http.Response{StatusCode: status}) {
+			t.Fatalf("Status %d should be invalid", status)
+		}
+	}
+}
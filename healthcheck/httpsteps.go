@@ -0,0 +1,296 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// HTTPExtraction extracts a value of a step response into a variable, from a
+// header, a cookie or a JSON field
+type HTTPExtraction struct {
+	Variable string `json:"variable"`
+	Header   string `json:"header,omitempty" yaml:"header,omitempty"`
+	Cookie   string `json:"cookie,omitempty" yaml:"cookie,omitempty"`
+	// a dotted path in the JSON body, for example data.tokens.0.value
+	JSONField string `json:"json-field,omitempty" yaml:"json-field,omitempty"`
+}
+
+// HTTPStep a request executed before the healthcheck request. The steps and
+// the healthcheck request share a cookie jar, and the path, body and
+// headers of the following requests can use the extracted variables as
+// templates, for example {{ .token }}.
+type HTTPStep struct {
+	Name    string            `json:"name,omitempty" yaml:"name,omitempty"`
+	Method  string            `json:"method,omitempty" yaml:"method,omitempty"`
+	Path    string            `json:"path,omitempty" yaml:"path,omitempty"`
+	Body    string            `json:"body,omitempty" yaml:"body,omitempty"`
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	// the valid status codes, any 2xx status if empty
+	ValidStatus []uint           `json:"valid-status,omitempty" yaml:"valid-status,omitempty"`
+	Extract     []HTTPExtraction `json:"extract,omitempty" yaml:"extract,omitempty"`
+}
+
+// label returns the step name, or its position
+func (s *HTTPStep) label(i int) string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return strconv.Itoa(i)
+}
+
+// validateStepTemplate verifies a value of a request is a valid template
+func validateStepTemplate(value string) error {
+	_, err := template.New("step").Parse(value)
+	return err
+}
+
+// validateHTTPSteps validates the steps, and the templates of the healthcheck
+// request using the extracted variables
+func validateHTTPSteps(config *HTTPHealthcheckConfiguration) error {
+	if len(config.Steps) == 0 {
+		return nil
+	}
+	for i := range config.Steps {
+		step := &config.Steps[i]
+		if step.Method == "" {
+			step.Method = http.MethodGet
+		}
+		switch step.Method {
+		case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodHead, http.MethodDelete, http.MethodPatch:
+		default:
+			return fmt.Errorf("The HTTP step %s method is invalid: %s", step.label(i), step.Method)
+		}
+		values := []string{step.Path, step.Body}
+		for _, v := range step.Headers {
+			values = append(values, v)
+		}
+		for _, value := range values {
+			if err := validateStepTemplate(value); err != nil {
+				return errors.Wrapf(err, "Invalid template in the HTTP step %s", step.label(i))
+			}
+		}
+		for _, extraction := range step.Extract {
+			if extraction.Variable == "" {
+				return fmt.Errorf("The HTTP step %s has an extraction without variable", step.label(i))
+			}
+			count := 0
+			for _, source := range []string{extraction.Header, extraction.Cookie, extraction.JSONField} {
+				if source != "" {
+					count++
+				}
+			}
+			if count != 1 {
+				return fmt.Errorf("The extraction of %s in the HTTP step %s should use exactly one of header, cookie or json-field", extraction.Variable, step.label(i))
+			}
+		}
+	}
+	values := []string{config.Path, config.Body}
+	for _, v := range config.Headers {
+		values = append(values, v)
+	}
+	for _, value := range values {
+		if err := validateStepTemplate(value); err != nil {
+			return errors.Wrap(err, "Invalid template in the HTTP healthcheck request")
+		}
+	}
+	if config.GRPCWeb != nil {
+		return errors.New("The HTTP steps can't be used with the grpc-web option")
+	}
+	return nil
+}
+
+// renderStep renders a template with the extracted variables. A missing
+// variable is an error.
+func renderStep(value string, variables map[string]string) (string, error) {
+	if !strings.Contains(value, "{{") {
+		return value, nil
+	}
+	tmpl, err := template.New("step").Option("missingkey=error").Parse(value)
+	if err != nil {
+		return "", err
+	}
+	var buffer bytes.Buffer
+	if err := tmpl.Execute(&buffer, variables); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// renderHeaders renders the headers templates
+func renderHeaders(headers map[string]string, variables map[string]string) (map[string]string, error) {
+	rendered := make(map[string]string, len(headers))
+	for k, v := range headers {
+		value, err := renderStep(v, variables)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to render the header %s", k)
+		}
+		rendered[k] = value
+	}
+	return rendered, nil
+}
+
+// jsonField returns the value of a dotted path in a JSON document. The
+// numeric segments are the indexes of arrays.
+func jsonField(body []byte, path string) (string, error) {
+	var document interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return "", errors.Wrap(err, "the response is not a valid JSON document")
+	}
+	current := document
+	for _, segment := range strings.Split(path, ".") {
+		switch value := current.(type) {
+		case map[string]interface{}:
+			field, ok := value[segment]
+			if !ok {
+				return "", fmt.Errorf("the field %s is missing", path)
+			}
+			current = field
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(value) {
+				return "", fmt.Errorf("the field %s is missing", path)
+			}
+			current = value[index]
+		default:
+			return "", fmt.Errorf("the field %s is missing", path)
+		}
+	}
+	switch value := current.(type) {
+	case string:
+		return value, nil
+	case nil:
+		return "", fmt.Errorf("the field %s is null", path)
+	case float64, bool:
+		return fmt.Sprintf("%v", value), nil
+	default:
+		content, err := json.Marshal(value)
+		return string(content), err
+	}
+}
+
+// extract returns the value of an extraction from a step response
+func (e *HTTPExtraction) extract(response *http.Response, body []byte) (string, error) {
+	if e.Header != "" {
+		value := response.Header.Get(e.Header)
+		if value == "" {
+			return "", fmt.Errorf("the header %s is missing", e.Header)
+		}
+		return value, nil
+	}
+	if e.Cookie != "" {
+		for _, cookie := range response.Cookies() {
+			if cookie.Name == e.Cookie {
+				return cookie.Value, nil
+			}
+		}
+		return "", fmt.Errorf("the cookie %s is missing", e.Cookie)
+	}
+	return jsonField(body, e.JSONField)
+}
+
+// newCookieClient returns a copy of the client with a new cookie jar, shared
+// by the steps and the healthcheck request of an execution
+func newCookieClient(client *http.Client) (*http.Client, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "fail to create the cookie jar")
+	}
+	withJar := *client
+	withJar.Jar = jar
+	return &withJar, nil
+}
+
+// runStep executes a step, and adds its extracted values to the variables
+func (h *HTTPHealthcheck) runStep(ctx context.Context, client *http.Client, step *HTTPStep, variables map[string]string) error {
+	path, err := renderStep(step.Path, variables)
+	if err != nil {
+		return errors.Wrap(err, "fail to render the path")
+	}
+	body, err := renderStep(step.Body, variables)
+	if err != nil {
+		return errors.Wrap(err, "fail to render the body")
+	}
+	headers, err := renderHeaders(step.Headers, variables)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, step.Method, h.origin+path, strings.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "fail to initialize the HTTP request")
+	}
+	req.Header.Set("User-Agent", "Cabourotte")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if h.auth != nil {
+		h.auth.apply(req)
+	}
+	response, err := client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "HTTP request failed")
+	}
+	defer response.Body.Close()
+	responseBody, err := ReadBody(response.Body, h.Config.MaxBodySize)
+	if err != nil {
+		return errors.Wrap(err, "fail to read the response body")
+	}
+	valid := len(step.ValidStatus) == 0 && response.StatusCode >= 200 && response.StatusCode < 300
+	for _, status := range step.ValidStatus {
+		if uint(response.StatusCode) == status {
+			valid = true
+		}
+	}
+	if !valid {
+		return fmt.Errorf("invalid status %d", response.StatusCode)
+	}
+	for _, extraction := range step.Extract {
+		value, err := extraction.extract(response, responseBody)
+		if err != nil {
+			return errors.Wrapf(err, "fail to extract %s", extraction.Variable)
+		}
+		variables[extraction.Variable] = value
+	}
+	return nil
+}
+
+// DeepCopyInto copies the step, writing into out
+func (in *HTTPStep) DeepCopyInto(out *HTTPStep) {
+	*out = *in
+	if in.Headers != nil {
+		out.Headers = make(map[string]string, len(in.Headers))
+		for k, v := range in.Headers {
+			out.Headers[k] = v
+		}
+	}
+	if in.ValidStatus != nil {
+		out.ValidStatus = make([]uint, len(in.ValidStatus))
+		copy(out.ValidStatus, in.ValidStatus)
+	}
+	if in.Extract != nil {
+		out.Extract = make([]HTTPExtraction, len(in.Extract))
+		copy(out.Extract, in.Extract)
+	}
+}
+
+// runHTTPSteps executes the steps in order, and returns the extracted
+// variables
+func (h *HTTPHealthcheck) runHTTPSteps(ctx context.Context, client *http.Client) (map[string]string, error) {
+	variables := make(map[string]string)
+	for i := range h.Config.Steps {
+		step := &h.Config.Steps[i]
+		if err := h.runStep(ctx, client, step, variables); err != nil {
+			AddMetadata(ctx, "failed-step", step.label(i))
+			return nil, errors.Wrapf(err, "HTTP step %s failed", step.label(i))
+		}
+	}
+	return variables, nil
+}
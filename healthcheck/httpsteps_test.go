@@ -0,0 +1,140 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestJSONField(t *testing.T) {
+	body := []byte(`{"data": {"tokens": [{"value": "abc"}], "ttl": 60, "active": true}}`)
+	cases := map[string]string{
+		"data.tokens.0.value": "abc",
+		"data.ttl":            "60",
+		"data.active":         "true",
+		"data.tokens.0":       `{"value":"abc"}`,
+	}
+	for path, expected := range cases {
+		value, err := jsonField(body, path)
+		if err != nil {
+			t.Fatalf("Fail to extract %s\n%v", path, err)
+		}
+		if value != expected {
+			t.Fatalf("Invalid value %s for %s, expected %s", value, path, expected)
+		}
+	}
+	for _, path := range []string{"data.missing", "data.tokens.1.value", "data.ttl.value"} {
+		if _, err := jsonField(body, path); err == nil {
+			t.Fatalf("Was expecting an error for %s", path)
+		}
+	}
+}
+
+func TestHTTPStepsValidate(t *testing.T) {
+	base := HTTPHealthcheckConfiguration{
+		Base:        Base{Name: "foo", OneOff: true},
+		ValidStatus: []uint{200},
+		Target:      "127.0.0.1",
+		Port:        8080,
+		Timeout:     Duration(time.Second),
+	}
+	invalid := [][]HTTPStep{
+		{{Method: "CONNECT"}},
+		{{Path: "/{{ .token"}},
+		{{Extract: []HTTPExtraction{{Header: "X-Token"}}}},
+		{{Extract: []HTTPExtraction{{Variable: "token"}}}},
+		{{Extract: []HTTPExtraction{{Variable: "token", Header: "X-Token", Cookie: "session"}}}},
+	}
+	for i, steps := range invalid {
+		config := base
+		config.Steps = steps
+		if err := config.Validate(); err == nil {
+			t.Fatalf("Was expecting an error for the case %d", i)
+		}
+	}
+	config := base
+	config.Steps = []HTTPStep{{Path: "/login", Extract: []HTTPExtraction{{Variable: "token", JSONField: "token"}}}}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validation error\n%v", err)
+	}
+	if config.Steps[0].Method != http.MethodGet {
+		t.Fatalf("Invalid default method %s", config.Steps[0].Method)
+	}
+}
+
+func TestHTTPExecuteSteps(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.FormValue("user") != "cabourotte" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3cr3t", Path: "/"})
+		w.Write([]byte(`{"data": {"id": "42"}}`))
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("session")
+		if err != nil || cookie.Value != "s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("X-Token", "token-"+r.URL.Query().Get("id"))
+	})
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "token-42" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	steps := []HTTPStep{
+		{
+			Name:    "login",
+			Method:  http.MethodPost,
+			Path:    "/login",
+			Body:    "user=cabourotte",
+			Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			Extract: []HTTPExtraction{{Variable: "id", JSONField: "data.id"}},
+		},
+		{
+			Name:    "token",
+			Path:    "/token?id={{ .id }}",
+			Extract: []HTTPExtraction{{Variable: "token", Header: "X-Token"}},
+		},
+	}
+	h := NewHTTPHealthcheck(zap.NewExample(), &HTTPHealthcheckConfiguration{
+		Base:        Base{Name: "foo", OneOff: true},
+		ValidStatus: []uint{200},
+		Target:      "127.0.0.1",
+		Port:        uint(ts.Listener.Addr().(*net.TCPAddr).Port),
+		Path:        "/health",
+		Headers:     map[string]string{"X-Token": "{{ .token }}"},
+		Timeout:     Duration(time.Second * 2),
+		Steps:       steps,
+	})
+	if err := h.Config.Validate(); err != nil {
+		t.Fatalf("Validation error\n%v", err)
+	}
+	if err := h.Initialize(); err != nil {
+		t.Fatalf("Initialization error\n%v", err)
+	}
+	if err := h.Execute(context.Background()); err != nil {
+		t.Fatalf("healthcheck error\n%v", err)
+	}
+	// the login fails, the flow stops at the first step
+	h.Config.Steps[0].Body = "user=unknown"
+	ctx := WithMetadata(context.Background())
+	if err := h.Execute(ctx); err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	if GetMetadata(ctx)["failed-step"] != "login" {
+		t.Fatalf("Invalid metadata %v", GetMetadata(ctx))
+	}
+}
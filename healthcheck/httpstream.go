@@ -0,0 +1,116 @@
+package healthcheck
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultStreamMaxLines the default maximum number of lines read from a
+// streamed response
+const DefaultStreamMaxLines = 100
+
+// StreamAssertion reads a streamed response (chunked or server-sent events)
+// line by line instead of waiting for the end of the body. The connection is
+// closed once a line matches, or once max-lines lines are read.
+type StreamAssertion struct {
+	// the stream is successful when a line matches, all the lines read are
+	// accepted if not set
+	Match *Regexp `json:"match,omitempty" yaml:"match,omitempty"`
+	// the maximum number of lines read, DefaultStreamMaxLines if not set
+	MaxLines uint `json:"max-lines,omitempty" yaml:"max-lines,omitempty"`
+}
+
+// DeepCopyInto copies the receiver into out. in must be non-nil.
+func (in *StreamAssertion) DeepCopyInto(out *StreamAssertion) {
+	*out = *in
+	if in.Match != nil {
+		out.Match = in.Match.DeepCopy()
+	}
+}
+
+// maxLines returns the maximum number of lines read
+func (s *StreamAssertion) maxLines() int {
+	if s.MaxLines == 0 {
+		return DefaultStreamMaxLines
+	}
+	return int(s.MaxLines)
+}
+
+// streamResult the content read from a stream
+type streamResult struct {
+	content []byte
+	lines   int
+	matched string
+	found   bool
+}
+
+// readStream reads a stream line by line, until a line matches the assertion
+// or until the maximum number of lines is read. Without match, the end of the
+// stream or the deadline also ends the read. A BodyTooLargeError is
+// returned if more than limit bytes are read, DefaultMaxBodySize is used if
+// the limit is 0.
+func readStream(body io.Reader, assertion *StreamAssertion, limit uint) (*streamResult, error) {
+	if limit == 0 {
+		limit = DefaultMaxBodySize
+	}
+	reader := bufio.NewReader(io.LimitReader(body, int64(limit)+1))
+	var match *regexp.Regexp
+	if assertion.Match != nil {
+		r := regexp.Regexp(*assertion.Match)
+		match = &r
+	}
+	result := &streamResult{}
+	var content bytes.Buffer
+	for result.lines < assertion.maxLines() {
+		line, err := reader.ReadBytes('\n')
+		if uint(content.Len()+len(line)) > limit {
+			return nil, &BodyTooLargeError{Limit: limit}
+		}
+		content.Write(line)
+		if len(line) != 0 {
+			result.lines++
+			if match != nil {
+				text := string(bytes.TrimRight(line, "\r\n"))
+				if match.MatchString(text) {
+					result.matched = text
+					result.found = true
+					break
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		// without match, the lines read until the deadline are the stream
+		if err != nil && match == nil && (errors.Is(err, context.DeadlineExceeded) || isTimeout(err)) {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "Fail to read the stream")
+		}
+	}
+	result.content = content.Bytes()
+	return result, nil
+}
+
+// checkStream verifies that a line of the stream matched the assertion
+func checkStream(ctx context.Context, assertion *StreamAssertion, result *streamResult) error {
+	AddMetadata(ctx, "stream-lines", strconv.Itoa(result.lines))
+	if assertion.Match == nil {
+		return nil
+	}
+	if !result.found {
+		AddMetadata(ctx, "reason", "stream-no-match")
+		r := regexp.Regexp(*assertion.Match)
+		return fmt.Errorf("No line of the stream matches the regex %s after %d lines", r.String(), result.lines)
+	}
+	AddMetadata(ctx, "matched-line", result.matched)
+	return nil
+}
@@ -0,0 +1,136 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestHTTPExecuteStream(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		for i := 1; i <= 5; i++ {
+			fmt.Fprintf(w, "data: event %d\n\n", i)
+		}
+		w.(http.Flusher).Flush()
+		// the stream is never closed by the server
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("Error getting HTTP server port :\n%v", err)
+	}
+	match := Regexp(*regexp.MustCompile("^data: event 3$"))
+	cases := []struct {
+		stream  StreamAssertion
+		success bool
+		lines   string
+		reason  string
+	}{
+		{stream: StreamAssertion{Match: &match}, success: true, lines: "5"},
+		{stream: StreamAssertion{MaxLines: 2}, success: true, lines: "2"},
+		{stream: StreamAssertion{Match: &match, MaxLines: 3}, success: false, lines: "3", reason: "stream-no-match"},
+	}
+	for i, c := range cases {
+		stream := c.stream
+		h := NewHTTPHealthcheck(zap.NewExample(), &HTTPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Second * 10),
+			},
+			ValidStatus: []uint{200},
+			Target:      "127.0.0.1",
+			Port:        uint(port),
+			Protocol:    HTTP,
+			Timeout:     Duration(time.Second * 3),
+			Stream:      &stream,
+		})
+		err := h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error\n%v", err)
+		}
+		ctx := WithMetadata(context.Background())
+		start := time.Now()
+		err = h.Execute(ctx)
+		if time.Since(start) > time.Second {
+			t.Fatalf("healthcheck %d should not wait for the end of the stream", i)
+		}
+		metadata := GetMetadata(ctx)
+		if c.success && err != nil {
+			t.Fatalf("healthcheck %d error\n%v", i, err)
+		}
+		if !c.success && (err == nil || metadata["reason"] != c.reason) {
+			t.Fatalf("healthcheck %d should fail with the reason %s: %v", i, c.reason, metadata)
+		}
+		if metadata["stream-lines"] != c.lines {
+			t.Fatalf("Invalid stream lines metadata for healthcheck %d: %v", i, metadata)
+		}
+		if c.success && c.stream.Match != nil && metadata["matched-line"] != "data: event 3" {
+			t.Fatalf("Invalid matched line metadata %v", metadata)
+		}
+	}
+}
+
+func TestHTTPExecuteStreamDeadline(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "data: event 1\n")
+		w.(http.Flusher).Flush()
+		// the stream is shorter than max-lines and never closed
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("Error getting HTTP server port :\n%v", err)
+	}
+	h := NewHTTPHealthcheck(zap.NewExample(), &HTTPHealthcheckConfiguration{
+		Base: Base{
+			Name:     "foo",
+			Interval: Duration(time.Second * 10),
+		},
+		ValidStatus: []uint{200},
+		Target:      "127.0.0.1",
+		Port:        uint(port),
+		Protocol:    HTTP,
+		Timeout:     Duration(time.Millisecond * 300),
+		Stream:      &StreamAssertion{},
+	})
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error\n%v", err)
+	}
+	ctx := WithMetadata(context.Background())
+	err = h.Execute(ctx)
+	if err != nil {
+		t.Fatalf("The stream should be successful until the deadline\n%v", err)
+	}
+	if metadata := GetMetadata(ctx); metadata["stream-lines"] != "1" {
+		t.Fatalf("Invalid stream lines metadata %v", metadata)
+	}
+}
+
+func TestReadStreamTooLarge(t *testing.T) {
+	_, err := readStream(strings.NewReader("first line\nsecond line\n"), &StreamAssertion{}, 15)
+	if err == nil {
+		t.Fatalf("The stream should be too large")
+	}
+	result, err := readStream(strings.NewReader("first line\nlast"), &StreamAssertion{}, 0)
+	if err != nil {
+		t.Fatalf("Fail to read the stream\n%v", err)
+	}
+	if result.lines != 2 || string(result.content) != "first line\nlast" {
+		t.Fatalf("Invalid stream content %v", result)
+	}
+}
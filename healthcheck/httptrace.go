@@ -0,0 +1,188 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+const (
+	phaseDNS       = "dns"
+	phaseConnect   = "connect"
+	phaseTLS       = "tls"
+	phaseFirstByte = "first-byte"
+)
+
+// PhaseTimeouts the timeouts for the phases of an HTTP request
+type PhaseTimeouts struct {
+	DNS       Duration `json:"dns,omitempty" yaml:"dns,omitempty"`
+	Connect   Duration `json:"connect,omitempty" yaml:"connect,omitempty"`
+	TLS       Duration `json:"tls,omitempty" yaml:"tls,omitempty"`
+	FirstByte Duration `json:"first-byte,omitempty" yaml:"first-byte,omitempty"`
+}
+
+// max returns the highest phase timeout
+func (p PhaseTimeouts) max() Duration {
+	max := p.DNS
+	for _, timeout := range []Duration{p.Connect, p.TLS, p.FirstByte} {
+		if timeout > max {
+			max = timeout
+		}
+	}
+	return max
+}
+
+type phase struct {
+	timeout  time.Duration
+	start    time.Time
+	duration time.Duration
+	timer    *time.Timer
+}
+
+// phaseTracer measures the duration of each phase of an HTTP request, and
+// cancels the request when a phase exceeds its timeout.
+type phaseTracer struct {
+	lock       sync.Mutex
+	cancel     context.CancelFunc
+	phases     map[string]*phase
+	exceeded   string
+	remoteAddr net.Addr
+}
+
+func newPhaseTracer(timeouts PhaseTimeouts, cancel context.CancelFunc) *phaseTracer {
+	return &phaseTracer{
+		cancel: cancel,
+		phases: map[string]*phase{
+			phaseDNS:       {timeout: time.Duration(timeouts.DNS)},
+			phaseConnect:   {timeout: time.Duration(timeouts.Connect)},
+			phaseTLS:       {timeout: time.Duration(timeouts.TLS)},
+			phaseFirstByte: {timeout: time.Duration(timeouts.FirstByte)},
+		},
+	}
+}
+
+// start starts a phase. A phase can only be started once.
+func (t *phaseTracer) start(name string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	p := t.phases[name]
+	if !p.start.IsZero() {
+		return
+	}
+	p.start = time.Now()
+	if p.timeout != 0 {
+		p.timer = time.AfterFunc(p.timeout, func() {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			if t.exceeded == "" {
+				t.exceeded = name
+			}
+			t.cancel()
+		})
+	}
+}
+
+// end ends a phase
+func (t *phaseTracer) end(name string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	p := t.phases[name]
+	if p.start.IsZero() || p.duration != 0 {
+		return
+	}
+	p.duration = time.Since(p.start)
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+}
+
+// stop stops all timers
+func (t *phaseTracer) stop() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for _, p := range t.phases {
+		if p.timer != nil {
+			p.timer.Stop()
+		}
+	}
+}
+
+// clientTrace returns the httptrace hooks updating the tracer
+func (t *phaseTracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			t.start(phaseDNS)
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			t.end(phaseDNS)
+		},
+		ConnectStart: func(network, addr string) {
+			t.start(phaseConnect)
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil {
+				t.end(phaseConnect)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.start(phaseTLS)
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			t.end(phaseTLS)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			t.remoteAddr = info.Conn.RemoteAddr()
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			t.start(phaseFirstByte)
+		},
+		GotFirstResponseByte: func() {
+			t.end(phaseFirstByte)
+		},
+	}
+}
+
+// duration returns the duration of a completed phase, or 0
+func (t *phaseTracer) duration(name string) time.Duration {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.phases[name].duration
+}
+
+// getRemoteAddr returns the address of the connection used by the request
+func (t *phaseTracer) getRemoteAddr() net.Addr {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.remoteAddr
+}
+
+// exceededError returns an error if a phase exceeded its timeout
+func (t *phaseTracer) exceededError() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.exceeded == "" {
+		return nil
+	}
+	return fmt.Errorf("HTTP request failed: the %s phase exceeded its timeout of %s", t.exceeded, t.phases[t.exceeded].timeout.String())
+}
+
+// addMetadata adds the duration of the completed phases, and the phase which
+// exceeded its timeout, to the healthcheck result metadata
+func (t *phaseTracer) addMetadata(ctx context.Context) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for name, p := range t.phases {
+		if p.duration != 0 {
+			AddMetadata(ctx, fmt.Sprintf("%s-duration", name), fmt.Sprintf("%f", p.duration.Seconds()))
+		}
+	}
+	if t.exceeded != "" {
+		AddMetadata(ctx, "timeout-phase", t.exceeded)
+	}
+}
@@ -0,0 +1,86 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// validateSourceInterface verifies that the interface exists, and that no
+// source IP is configured alongside it
+func validateSourceInterface(name string, sourceIP IP) error {
+	if name == "" {
+		return nil
+	}
+	if sourceIP != nil {
+		return errors.New("The source-ip and source-interface options are mutually exclusive")
+	}
+	if _, err := net.InterfaceByName(name); err != nil {
+		return errors.Wrapf(err, "Invalid source interface %s", name)
+	}
+	return nil
+}
+
+// interfaceIP returns the current address of an interface. An IPv6 address is
+// returned if ipv6 is true, an IPv4 address otherwise.
+func interfaceIP(name string, ipv6 bool) (net.IP, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to get the interface %s", name)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to get the addresses of the interface %s", name)
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if (ipNet.IP.To4() == nil) == ipv6 {
+			return ipNet.IP, nil
+		}
+	}
+	return nil, fmt.Errorf("No address found on the interface %s", name)
+}
+
+// targetIPs returns the IPs of the target, resolving it if it is a hostname.
+// nil is returned if the resolution fails, the dial reports the error.
+func targetIPs(ctx context.Context, target string) []net.IP {
+	if ip := net.ParseIP(target); ip != nil {
+		return []net.IP{ip}
+	}
+	if ips := lookupAliased("ip", target); len(ips) != 0 {
+		return ips
+	}
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", target)
+	if err != nil {
+		return nil
+	}
+	return ips
+}
+
+// interfaceAddr returns the local TCP address to use for the interface. The
+// address family is chosen from the resolved target: the first target IP
+// having an address of the same family on the interface is used, the dialer
+// then only connects to the target IPs of this family.
+func interfaceAddr(ctx context.Context, name string, target string) (*net.TCPAddr, error) {
+	ips := targetIPs(ctx, target)
+	if len(ips) == 0 {
+		// the target can't be resolved, the dial reports the error
+		ips = []net.IP{net.IPv4zero}
+	}
+	var firstErr error
+	for _, targetIP := range ips {
+		ip, err := interfaceIP(name, targetIP.To4() == nil)
+		if err == nil {
+			return &net.TCPAddr{IP: ip}, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
@@ -0,0 +1,610 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// JSONSchemaAssertion validates the response body against a JSON schema. The
+// validation keywords of the draft 2020-12 are supported, except the formats
+// and the remote references.
+type JSONSchemaAssertion struct {
+	// the schema, as a JSON document
+	Schema string `json:"schema,omitempty" yaml:"schema,omitempty"`
+	// the path of a file containing the schema
+	File string `json:"file,omitempty" yaml:"file,omitempty"`
+}
+
+// validate verifies the JSON schema assertion options. An inline schema is
+// compiled to report its errors with the configuration.
+func (a *JSONSchemaAssertion) validate(config *HTTPHealthcheckConfiguration) error {
+	if (a.Schema == "") == (a.File == "") {
+		return errors.New("The healthcheck json-schema assertion requires either a schema or a file")
+	}
+	if config.Method == http.MethodHead {
+		return errors.New("The healthcheck json-schema assertion can't be used with the HEAD method")
+	}
+	if config.Stream != nil || config.GRPCWeb != nil {
+		return errors.New("The healthcheck json-schema assertion can't be used with the stream and grpc-web options")
+	}
+	if a.Schema != "" {
+		if _, err := compileJSONSchema([]byte(a.Schema)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compile compiles the schema of the assertion
+func (a *JSONSchemaAssertion) compile() (*jsonSchema, error) {
+	content := []byte(a.Schema)
+	if a.File != "" {
+		var err error
+		content, err = ioutil.ReadFile(a.File)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to read the JSON schema %s", a.File)
+		}
+	}
+	return compileJSONSchema(content)
+}
+
+// jsonSchema a compiled JSON schema
+type jsonSchema struct {
+	// the false schema, rejecting everything
+	rejected bool
+	ref      *jsonSchema
+
+	types    []string
+	enum     []interface{}
+	constant interface{}
+	hasConst bool
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+	multipleOf       *float64
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+
+	items       *jsonSchema
+	prefixItems []*jsonSchema
+	minItems    *int
+	maxItems    *int
+	uniqueItems bool
+
+	properties           map[string]*jsonSchema
+	patternProperties    map[*regexp.Regexp]*jsonSchema
+	additionalProperties *jsonSchema
+	required             []string
+	minProperties        *int
+	maxProperties        *int
+
+	allOf []*jsonSchema
+	anyOf []*jsonSchema
+	oneOf []*jsonSchema
+	not   *jsonSchema
+}
+
+// schemaError a validation error, at the JSON pointer path of the value
+type schemaError struct {
+	path    string
+	message string
+}
+
+func (e *schemaError) Error() string {
+	return fmt.Sprintf("%s: %s", e.path, e.message)
+}
+
+// schemaCompiler compiles a schema document, resolving the local references
+type schemaCompiler struct {
+	root interface{}
+	refs map[string]*jsonSchema
+}
+
+// compileJSONSchema compiles a JSON schema document
+func compileJSONSchema(content []byte) (*jsonSchema, error) {
+	var root interface{}
+	if err := json.Unmarshal(content, &root); err != nil {
+		return nil, errors.Wrap(err, "Invalid JSON schema")
+	}
+	compiler := &schemaCompiler{root: root, refs: make(map[string]*jsonSchema)}
+	schema, err := compiler.compile(root, "#")
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid JSON schema")
+	}
+	return schema, nil
+}
+
+// resolve returns the schema referenced by a local JSON pointer. The
+// schemas are cached to support the recursive references.
+func (c *schemaCompiler) resolve(ref string) (*jsonSchema, error) {
+	if schema, ok := c.refs[ref]; ok {
+		return schema, nil
+	}
+	if ref != "#" && !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported reference %s, only the local references are supported", ref)
+	}
+	value := c.root
+	if ref != "#" {
+		for _, token := range strings.Split(ref[2:], "/") {
+			token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+			switch v := value.(type) {
+			case map[string]interface{}:
+				value = v[token]
+			case []interface{}:
+				i, err := strconv.Atoi(token)
+				if err != nil || i < 0 || i >= len(v) {
+					return nil, fmt.Errorf("invalid reference %s", ref)
+				}
+				value = v[i]
+			default:
+				value = nil
+			}
+			if value == nil {
+				return nil, fmt.Errorf("invalid reference %s", ref)
+			}
+		}
+	}
+	schema := &jsonSchema{}
+	c.refs[ref] = schema
+	compiled, err := c.compile(value, ref)
+	if err != nil {
+		return nil, err
+	}
+	*schema = *compiled
+	return schema, nil
+}
+
+// compile compiles a schema located at the given pointer
+func (c *schemaCompiler) compile(value interface{}, pointer string) (*jsonSchema, error) {
+	if b, ok := value.(bool); ok {
+		return &jsonSchema{rejected: !b}, nil
+	}
+	raw, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the schema %s should be an object or a boolean", pointer)
+	}
+	s := &jsonSchema{}
+	invalid := func(keyword string) error {
+		return fmt.Errorf("invalid %s keyword in the schema %s", keyword, pointer)
+	}
+	number := func(keyword string) (*float64, error) {
+		v, ok := raw[keyword]
+		if !ok {
+			return nil, nil
+		}
+		n, ok := v.(float64)
+		if !ok {
+			return nil, invalid(keyword)
+		}
+		return &n, nil
+	}
+	count := func(keyword string) (*int, error) {
+		n, err := number(keyword)
+		if err != nil || n == nil {
+			return nil, err
+		}
+		if *n < 0 || math.Trunc(*n) != *n {
+			return nil, invalid(keyword)
+		}
+		i := int(*n)
+		return &i, nil
+	}
+	subschema := func(keyword string) (*jsonSchema, error) {
+		v, ok := raw[keyword]
+		if !ok {
+			return nil, nil
+		}
+		return c.compile(v, pointer+"/"+keyword)
+	}
+	list := func(keyword string) ([]*jsonSchema, error) {
+		v, ok := raw[keyword]
+		if !ok {
+			return nil, nil
+		}
+		values, ok := v.([]interface{})
+		if !ok || len(values) == 0 {
+			return nil, invalid(keyword)
+		}
+		result := make([]*jsonSchema, 0, len(values))
+		for i, value := range values {
+			schema, err := c.compile(value, fmt.Sprintf("%s/%s/%d", pointer, keyword, i))
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, schema)
+		}
+		return result, nil
+	}
+	var err error
+	if ref, ok := raw["$ref"]; ok {
+		r, ok := ref.(string)
+		if !ok {
+			return nil, invalid("$ref")
+		}
+		if s.ref, err = c.resolve(r); err != nil {
+			return nil, err
+		}
+	}
+	switch t := raw["type"].(type) {
+	case nil:
+	case string:
+		s.types = []string{t}
+	case []interface{}:
+		for _, v := range t {
+			name, ok := v.(string)
+			if !ok {
+				return nil, invalid("type")
+			}
+			s.types = append(s.types, name)
+		}
+	default:
+		return nil, invalid("type")
+	}
+	for _, t := range s.types {
+		switch t {
+		case "null", "boolean", "object", "array", "number", "string", "integer":
+		default:
+			return nil, fmt.Errorf("invalid type %s in the schema %s", t, pointer)
+		}
+	}
+	if v, ok := raw["enum"]; ok {
+		if s.enum, ok = v.([]interface{}); !ok {
+			return nil, invalid("enum")
+		}
+	}
+	s.constant, s.hasConst = raw["const"]
+	if s.minimum, err = number("minimum"); err != nil {
+		return nil, err
+	}
+	if s.maximum, err = number("maximum"); err != nil {
+		return nil, err
+	}
+	if s.exclusiveMinimum, err = number("exclusiveMinimum"); err != nil {
+		return nil, err
+	}
+	if s.exclusiveMaximum, err = number("exclusiveMaximum"); err != nil {
+		return nil, err
+	}
+	if s.multipleOf, err = number("multipleOf"); err != nil {
+		return nil, err
+	}
+	if s.multipleOf != nil && *s.multipleOf <= 0 {
+		return nil, invalid("multipleOf")
+	}
+	if s.minLength, err = count("minLength"); err != nil {
+		return nil, err
+	}
+	if s.maxLength, err = count("maxLength"); err != nil {
+		return nil, err
+	}
+	if v, ok := raw["pattern"]; ok {
+		pattern, ok := v.(string)
+		if !ok {
+			return nil, invalid("pattern")
+		}
+		if s.pattern, err = regexp.Compile(pattern); err != nil {
+			return nil, errors.Wrapf(err, "invalid pattern in the schema %s", pointer)
+		}
+	}
+	if s.items, err = subschema("items"); err != nil {
+		return nil, err
+	}
+	if s.prefixItems, err = list("prefixItems"); err != nil {
+		return nil, err
+	}
+	if s.minItems, err = count("minItems"); err != nil {
+		return nil, err
+	}
+	if s.maxItems, err = count("maxItems"); err != nil {
+		return nil, err
+	}
+	if v, ok := raw["uniqueItems"]; ok {
+		if s.uniqueItems, ok = v.(bool); !ok {
+			return nil, invalid("uniqueItems")
+		}
+	}
+	if v, ok := raw["properties"]; ok {
+		properties, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, invalid("properties")
+		}
+		s.properties = make(map[string]*jsonSchema, len(properties))
+		for name, value := range properties {
+			if s.properties[name], err = c.compile(value, pointer+"/properties/"+name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if v, ok := raw["patternProperties"]; ok {
+		properties, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, invalid("patternProperties")
+		}
+		s.patternProperties = make(map[*regexp.Regexp]*jsonSchema, len(properties))
+		for pattern, value := range properties {
+			r, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid pattern property in the schema %s", pointer)
+			}
+			if s.patternProperties[r], err = c.compile(value, pointer+"/patternProperties/"+pattern); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if s.additionalProperties, err = subschema("additionalProperties"); err != nil {
+		return nil, err
+	}
+	if v, ok := raw["required"]; ok {
+		required, ok := v.([]interface{})
+		if !ok {
+			return nil, invalid("required")
+		}
+		for _, name := range required {
+			n, ok := name.(string)
+			if !ok {
+				return nil, invalid("required")
+			}
+			s.required = append(s.required, n)
+		}
+	}
+	if s.minProperties, err = count("minProperties"); err != nil {
+		return nil, err
+	}
+	if s.maxProperties, err = count("maxProperties"); err != nil {
+		return nil, err
+	}
+	if s.allOf, err = list("allOf"); err != nil {
+		return nil, err
+	}
+	if s.anyOf, err = list("anyOf"); err != nil {
+		return nil, err
+	}
+	if s.oneOf, err = list("oneOf"); err != nil {
+		return nil, err
+	}
+	if s.not, err = subschema("not"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// jsonType returns the JSON type of a decoded value
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		if math.Trunc(v) == v {
+			return "integer"
+		}
+	}
+	return "number"
+}
+
+// hasType returns true if the value has one of the types
+func hasType(value interface{}, types []string) bool {
+	t := jsonType(value)
+	for _, expected := range types {
+		if expected == t || (expected == "number" && t == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// pointerToken escapes a JSON pointer token
+func pointerToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// validate returns the first validation error of the value, located at path
+func (s *jsonSchema) validate(value interface{}, path string) *schemaError {
+	fail := func(format string, args ...interface{}) *schemaError {
+		location := path
+		if location == "" {
+			location = "/"
+		}
+		return &schemaError{path: location, message: fmt.Sprintf(format, args...)}
+	}
+	if s.rejected {
+		return fail("no value is allowed")
+	}
+	if s.ref != nil {
+		if err := s.ref.validate(value, path); err != nil {
+			return err
+		}
+	}
+	if len(s.types) != 0 && !hasType(value, s.types) {
+		return fail("the value should be of type %s, got %s", strings.Join(s.types, " or "), jsonType(value))
+	}
+	if s.hasConst && !reflect.DeepEqual(value, s.constant) {
+		return fail("the value should be %v", s.constant)
+	}
+	if s.enum != nil {
+		found := false
+		for _, v := range s.enum {
+			if reflect.DeepEqual(value, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fail("the value should be one of %v", s.enum)
+		}
+	}
+	switch v := value.(type) {
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			return fail("the value %v should be greater than or equal to %v", v, *s.minimum)
+		}
+		if s.maximum != nil && v > *s.maximum {
+			return fail("the value %v should be lower than or equal to %v", v, *s.maximum)
+		}
+		if s.exclusiveMinimum != nil && v <= *s.exclusiveMinimum {
+			return fail("the value %v should be greater than %v", v, *s.exclusiveMinimum)
+		}
+		if s.exclusiveMaximum != nil && v >= *s.exclusiveMaximum {
+			return fail("the value %v should be lower than %v", v, *s.exclusiveMaximum)
+		}
+		if s.multipleOf != nil {
+			quotient := v / *s.multipleOf
+			if math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+				return fail("the value %v should be a multiple of %v", v, *s.multipleOf)
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.minLength != nil && length < *s.minLength {
+			return fail("the string should have at least %d characters", *s.minLength)
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			return fail("the string should have at most %d characters", *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			return fail("the string should match the pattern %s", s.pattern.String())
+		}
+	case []interface{}:
+		if s.minItems != nil && len(v) < *s.minItems {
+			return fail("the array should have at least %d items", *s.minItems)
+		}
+		if s.maxItems != nil && len(v) > *s.maxItems {
+			return fail("the array should have at most %d items", *s.maxItems)
+		}
+		if s.uniqueItems {
+			for i := range v {
+				for j := i + 1; j < len(v); j++ {
+					if reflect.DeepEqual(v[i], v[j]) {
+						return fail("the array items should be unique, the items %d and %d are equal", i, j)
+					}
+				}
+			}
+		}
+		for i, item := range v {
+			itemPath := fmt.Sprintf("%s/%d", path, i)
+			if i < len(s.prefixItems) {
+				if err := s.prefixItems[i].validate(item, itemPath); err != nil {
+					return err
+				}
+			} else if s.items != nil {
+				if err := s.items.validate(item, itemPath); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]interface{}:
+		if s.minProperties != nil && len(v) < *s.minProperties {
+			return fail("the object should have at least %d properties", *s.minProperties)
+		}
+		if s.maxProperties != nil && len(v) > *s.maxProperties {
+			return fail("the object should have at most %d properties", *s.maxProperties)
+		}
+		for _, name := range s.required {
+			if _, ok := v[name]; !ok {
+				return fail("the property %s is required", name)
+			}
+		}
+		// the properties are validated in order, for a stable first error
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			propertyPath := path + "/" + pointerToken(name)
+			matched := false
+			if schema, ok := s.properties[name]; ok {
+				matched = true
+				if err := schema.validate(v[name], propertyPath); err != nil {
+					return err
+				}
+			}
+			for pattern, schema := range s.patternProperties {
+				if pattern.MatchString(name) {
+					matched = true
+					if err := schema.validate(v[name], propertyPath); err != nil {
+						return err
+					}
+				}
+			}
+			if !matched && s.additionalProperties != nil {
+				if s.additionalProperties.rejected {
+					return fail("the property %s is not allowed", name)
+				}
+				if err := s.additionalProperties.validate(v[name], propertyPath); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, schema := range s.allOf {
+		if err := schema.validate(value, path); err != nil {
+			return err
+		}
+	}
+	if s.anyOf != nil {
+		valid := false
+		for _, schema := range s.anyOf {
+			if schema.validate(value, path) == nil {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fail("the value should match at least one of the anyOf schemas")
+		}
+	}
+	if s.oneOf != nil {
+		matches := 0
+		for _, schema := range s.oneOf {
+			if schema.validate(value, path) == nil {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return fail("the value should match exactly one of the oneOf schemas, it matches %d", matches)
+		}
+	}
+	if s.not != nil && s.not.validate(value, path) == nil {
+		return fail("the value should not match the not schema")
+	}
+	return nil
+}
+
+// checkJSONSchema validates a response body against the schema
+func checkJSONSchema(ctx context.Context, schema *jsonSchema, body []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		AddMetadata(ctx, "reason", "json-schema")
+		return errors.Wrap(err, "The response is not a valid JSON document")
+	}
+	if err := schema.validate(value, ""); err != nil {
+		AddMetadata(ctx, "reason", "json-schema")
+		AddMetadata(ctx, "json-schema-path", err.path)
+		return fmt.Errorf("The response does not match the JSON schema: %s", err.Error())
+	}
+	return nil
+}
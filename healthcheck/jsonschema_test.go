@@ -0,0 +1,180 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const testJSONSchema = `{
+  "type": "object",
+  "required": ["status", "components"],
+  "properties": {
+    "status": {"enum": ["up", "degraded"]},
+    "version": {"type": "string", "pattern": "^v[0-9]+"},
+    "components": {
+      "type": "array",
+      "minItems": 1,
+      "items": {"$ref": "#/$defs/component"}
+    }
+  },
+  "additionalProperties": false,
+  "$defs": {
+    "component": {
+      "type": "object",
+      "required": ["name", "latency"],
+      "properties": {
+        "name": {"type": "string", "minLength": 1},
+        "latency": {"type": "number", "minimum": 0, "exclusiveMaximum": 1000}
+      }
+    }
+  }
+}`
+
+func TestJSONSchemaValidate(t *testing.T) {
+	schema, err := compileJSONSchema([]byte(testJSONSchema))
+	if err != nil {
+		t.Fatalf("Fail to compile the schema\n%v", err)
+	}
+	cases := []struct {
+		document string
+		path     string
+	}{
+		{document: `{"status": "up", "version": "v2", "components": [{"name": "db", "latency": 12.5}]}`},
+		{document: `{"status": "down", "components": [{"name": "db", "latency": 1}]}`, path: "/status"},
+		{document: `{"status": "up"}`, path: "/"},
+		{document: `{"status": "up", "components": []}`, path: "/components"},
+		{document: `{"status": "up", "components": [{"name": "db", "latency": 1}, {"name": "cache", "latency": 1000}]}`, path: "/components/1/latency"},
+		{document: `{"status": "up", "components": [{"name": "", "latency": 1}]}`, path: "/components/0/name"},
+		{document: `{"status": "up", "version": "2", "components": [{"name": "db", "latency": 1}]}`, path: "/version"},
+		{document: `{"status": "up", "extra/field": true, "components": [{"name": "db", "latency": 1}]}`, path: "/"},
+		{document: `[]`, path: "/"},
+	}
+	for i, c := range cases {
+		var value interface{}
+		if err := json.Unmarshal([]byte(c.document), &value); err != nil {
+			t.Fatalf("Invalid document %d\n%v", i, err)
+		}
+		validationErr := schema.validate(value, "")
+		if c.path == "" && validationErr != nil {
+			t.Fatalf("The document %d should be valid: %v", i, validationErr)
+		}
+		if c.path != "" && (validationErr == nil || validationErr.path != c.path) {
+			t.Fatalf("The document %d should be invalid at %s: %v", i, c.path, validationErr)
+		}
+	}
+	combinators, err := compileJSONSchema([]byte(`{"oneOf": [{"type": "integer"}, {"type": "number", "multipleOf": 0.5}], "not": {"const": 3}}`))
+	if err != nil {
+		t.Fatalf("Fail to compile the schema\n%v", err)
+	}
+	for value, valid := range map[float64]bool{1.5: true, 1.25: false, 2: false, 3: false} {
+		if (combinators.validate(value, "") == nil) != valid {
+			t.Fatalf("Invalid validation of %v", value)
+		}
+	}
+	invalid := []string{
+		`{"type": "text"}`,
+		`{"minItems": -1}`,
+		`{"pattern": "("}`,
+		`{"$ref": "https://example.com/schema.json"}`,
+		`{"$ref": "#/$defs/missing"}`,
+		`{"anyOf": []}`,
+		`[]`,
+	}
+	for _, c := range invalid {
+		if _, err := compileJSONSchema([]byte(c)); err == nil {
+			t.Fatalf("The schema %s should be invalid", c)
+		}
+	}
+}
+
+func TestHTTPExecuteJSONSchema(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		if r.URL.Path == "/degraded" {
+			w.Write([]byte(`{"status": "up", "components": [{"name": "db", "latency": "slow"}]}`))
+			return
+		}
+		w.Write([]byte(`{"status": "up", "components": [{"name": "db", "latency": 3}]}`))
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("Error getting HTTP server port :\n%v", err)
+	}
+	file := filepath.Join(t.TempDir(), "schema.json")
+	err = os.WriteFile(file, []byte(testJSONSchema), 0600)
+	if err != nil {
+		t.Fatalf("Fail to write the schema\n%v", err)
+	}
+	cases := []struct {
+		path      string
+		assertion JSONSchemaAssertion
+		success   bool
+	}{
+		{path: "/", assertion: JSONSchemaAssertion{Schema: testJSONSchema}, success: true},
+		{path: "/", assertion: JSONSchemaAssertion{File: file}, success: true},
+		{path: "/degraded", assertion: JSONSchemaAssertion{File: file}, success: false},
+	}
+	for i, c := range cases {
+		assertion := c.assertion
+		config := &HTTPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Second * 10),
+			},
+			ValidStatus: []uint{200},
+			Target:      "127.0.0.1",
+			Port:        uint(port),
+			Path:        c.path,
+			Protocol:    HTTP,
+			Timeout:     Duration(time.Second * 3),
+			JSONSchema:  &assertion,
+		}
+		if err := config.Validate(); err != nil {
+			t.Fatalf("Invalid configuration %d\n%v", i, err)
+		}
+		h := NewHTTPHealthcheck(zap.NewExample(), config)
+		err := h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error\n%v", err)
+		}
+		ctx := WithMetadata(context.Background())
+		err = h.Execute(ctx)
+		metadata := GetMetadata(ctx)
+		if c.success && err != nil {
+			t.Fatalf("healthcheck %d error\n%v", i, err)
+		}
+		if !c.success && (err == nil || metadata["reason"] != "json-schema" || metadata["json-schema-path"] != "/components/0/latency") {
+			t.Fatalf("healthcheck %d should fail: %v %v", i, err, metadata)
+		}
+	}
+	invalid := []JSONSchemaAssertion{
+		{},
+		{Schema: testJSONSchema, File: file},
+		{Schema: `{"type": 1}`},
+	}
+	for i := range invalid {
+		config := &HTTPHealthcheckConfiguration{
+			Base:        Base{Name: "foo", Interval: Duration(time.Second * 10)},
+			ValidStatus: []uint{200},
+			Target:      "127.0.0.1",
+			Port:        uint(port),
+			Protocol:    HTTP,
+			Timeout:     Duration(time.Second * 3),
+			JSONSchema:  &invalid[i],
+		}
+		if err := config.Validate(); err == nil {
+			t.Fatalf("The json-schema assertion %d should be invalid", i)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package healthcheck
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+
+	"github.com/pkg/errors"
+)
+
+// validateSocketMark verifies that the socket mark can be used. A mark of 0
+// means no mark.
+func validateSocketMark(mark uint) error {
+	if mark == 0 {
+		return nil
+	}
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("The socket-mark option is only supported on Linux, not on %s", runtime.GOOS)
+	}
+	if uint64(mark) > math.MaxUint32 {
+		return errors.New("The socket mark should be lower than 2^32")
+	}
+	return nil
+}
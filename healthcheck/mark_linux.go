@@ -0,0 +1,27 @@
+//go:build linux
+
+package healthcheck
+
+import (
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// socketMarkControl returns a dialer control function setting SO_MARK on the
+// sockets
+func socketMarkControl(mark uint) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		err := c.Control(func(fd uintptr) {
+			sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_MARK, int(mark))
+		})
+		if err != nil {
+			return err
+		}
+		if sockErr != nil {
+			return errors.Wrapf(sockErr, "Fail to set the socket mark %d", mark)
+		}
+		return nil
+	}
+}
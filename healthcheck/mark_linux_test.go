@@ -0,0 +1,64 @@
+//go:build linux
+
+package healthcheck
+
+import (
+	"context"
+	"math"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestTCPExecuteSocketMark(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to listen :\n%v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	config := &TCPHealthcheckConfiguration{
+		Base: Base{
+			Name:     "foo",
+			Interval: Duration(time.Second * 5),
+		},
+		Port:       uint(listener.Addr().(*net.TCPAddr).Port),
+		Target:     "127.0.0.1",
+		Timeout:    Duration(time.Second * 2),
+		SocketMark: 42,
+	}
+	err = config.Validate()
+	if err != nil {
+		t.Fatalf("The configuration should be valid :\n%v", err)
+	}
+	h := TCPHealthcheck{
+		Logger: zap.NewExample(),
+		Config: config,
+	}
+	h.buildURL()
+	err = h.Execute(context.Background())
+	if err != nil && strings.Contains(err.Error(), syscall.EPERM.Error()) {
+		t.Skip("CAP_NET_ADMIN is needed to set socket marks")
+	}
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	if uint64(math.MaxUint) > math.MaxUint32 {
+		config.SocketMark = uint(math.MaxUint32) + 1
+		if config.Validate() == nil {
+			t.Fatalf("The socket mark should be out of range")
+		}
+	}
+}
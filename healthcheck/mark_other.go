@@ -0,0 +1,17 @@
+//go:build !linux
+
+package healthcheck
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+)
+
+// socketMarkControl returns a dialer control function failing, SO_MARK is
+// only supported on Linux
+func socketMarkControl(mark uint) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		return fmt.Errorf("socket marks are not supported on %s", runtime.GOOS)
+	}
+}
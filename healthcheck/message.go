@@ -0,0 +1,91 @@
+package healthcheck
+
+import (
+	"bytes"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// targetGetter is implemented by the healthchecks having a target
+type targetGetter interface {
+	target() string
+}
+
+// messageData the data available in the message templates
+type messageData struct {
+	Name     string
+	Target   string
+	Success  bool
+	Message  string
+	Summary  interface{}
+	Duration float64
+	Source   string
+	Labels   map[string]string
+	Metadata map[string]string
+}
+
+// parseMessageTemplate parses a message template, nil is returned if the
+// template is empty
+func parseMessageTemplate(s string) (*template.Template, error) {
+	if s == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("message").Option("missingkey=zero").Parse(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid healthcheck message template")
+	}
+	return tmpl, nil
+}
+
+// validateMessageTemplate verifies that a message template can be parsed and
+// executed
+func validateMessageTemplate(s string) error {
+	tmpl, err := parseMessageTemplate(s)
+	if err != nil || tmpl == nil {
+		return err
+	}
+	var buffer bytes.Buffer
+	if err := tmpl.Execute(&buffer, messageData{}); err != nil {
+		return errors.Wrap(err, "Invalid healthcheck message template")
+	}
+	return nil
+}
+
+// renderMessage replaces the message of the result using the template. The
+// message is not modified if the template fails.
+func renderMessage(tmpl *template.Template, healthcheck Healthcheck, result *Result) {
+	if tmpl == nil {
+		return
+	}
+	data := messageData{
+		Name:     result.Name,
+		Success:  result.Success,
+		Message:  result.Message,
+		Summary:  result.Summary,
+		Duration: result.Duration,
+		Source:   result.Source,
+		Labels:   result.Labels,
+		Metadata: result.Metadata,
+	}
+	if t, ok := healthcheck.(targetGetter); ok {
+		data.Target = t.target()
+	}
+	var buffer bytes.Buffer
+	if err := tmpl.Execute(&buffer, data); err != nil {
+		healthcheck.LogError(err, "fail to render the message template")
+		return
+	}
+	result.Message = buffer.String()
+}
+
+// ApplyMessageTemplate replaces the message of a result using the message
+// template of the healthcheck, if any
+func ApplyMessageTemplate(healthcheck Healthcheck, result *Result) {
+	tmpl, err := parseMessageTemplate(healthcheck.Base().MessageTemplate)
+	if err != nil {
+		healthcheck.LogError(err, "fail to parse the message template")
+		return
+	}
+	renderMessage(tmpl, healthcheck, result)
+}
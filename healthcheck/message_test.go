@@ -0,0 +1,46 @@
+package healthcheck
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestRenderMessage(t *testing.T) {
+	check := NewTCPHealthcheck(zap.NewExample(), &TCPHealthcheckConfiguration{
+		Base: Base{
+			Name:            "foo",
+			Interval:        Duration(10 * time.Second),
+			Labels:          map[string]string{"team": "sre"},
+			MessageTemplate: `{{ .Name }} on {{ .Target }} ({{ index .Labels "team" }}){{ if not .Success }}: {{ .Message }}, see https://wiki/{{ .Name }}{{ end }}`,
+		},
+		Target:  "127.0.0.1",
+		Port:    9000,
+		Timeout: Duration(time.Second),
+	})
+	if err := check.Config.Validate(); err != nil {
+		t.Fatalf("Invalid configuration: %v", err)
+	}
+	result := NewResult(check, 1, errors.New("connection refused"))
+	ApplyMessageTemplate(check, result)
+	expected := "foo on 127.0.0.1 (sre): connection refused, see https://wiki/foo"
+	if result.Message != expected {
+		t.Fatalf("Invalid message\nexpected: %s\nactual: %s", expected, result.Message)
+	}
+	result = NewResult(check, 1, nil)
+	ApplyMessageTemplate(check, result)
+	if result.Message != "foo on 127.0.0.1 (sre)" {
+		t.Fatalf("Invalid message %s", result.Message)
+	}
+
+	check.Config.Base.MessageTemplate = "{{ .Name "
+	if err := check.Config.Validate(); err == nil {
+		t.Fatalf("Was expecting an error for an invalid template")
+	}
+	check.Config.Base.MessageTemplate = "{{ .Unknown }}"
+	if err := check.Config.Validate(); err == nil {
+		t.Fatalf("Was expecting an error for an unknown field")
+	}
+}
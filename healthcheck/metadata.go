@@ -0,0 +1,154 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The metadata are the data observed during an execution. Keys are lower
+// case and dash separated, and are shared between the healthchecks types
+// when they have the same meaning:
+//
+//   - all: reason, warning, startup, correlation-id, adaptive-timeout
+//   - command: exit-code
+//   - dns: resolved-ips, dns-transport, resolve-duration, resolver
+//   - http: ip, status-code, response-size, tls-version, tls-cipher-suite,
+//     header-<name>, <phase>-duration, timeout-phase, metric-value, etag,
+//     body-sha256, ocsp-source, ocsp-status, ocsp-next-update, stream-lines,
+//     matched-line, grpc-status, grpc-message, grpc-serving-status,
+//     content-encoding, compressed-size, uncompressed-size, compression-ratio,
+//     security-header-<name>, missing-headers, mismatched-headers,
+//     json-schema-path, failed-step, alpn
+//   - tcp: ip, connect-duration, failed-step, tls-version, tls-cipher-suite,
+//     error-type, banner, fast-open, port-<port>, failed-ports,
+//     write-completed, unacked-bytes, peer-closed, tls-duration
+//   - tls: ip, tls-version, tls-cipher-suite, certificate-expiration,
+//     ocsp-source, ocsp-status, ocsp-next-update, tls-duration, timeout-phase
+//   - websocket: ip, status-code, tls-version, tls-cipher-suite, tls-duration
+//   - ssh: ip, host-key-type, host-key-fingerprint
+//   - ptr resolution: ip, ptr (once resolved in the background)
+//   - source IPs: failed-sources
+//   - result API: state, set to paused for the paused healthchecks
+
+const (
+	// MetadataMaxEntries is the maximum number of metadata of a result
+	MetadataMaxEntries = 32
+	// MetadataMaxValueLength is the maximum length of a metadata value
+	MetadataMaxValueLength = 512
+)
+
+type metadataKey struct{}
+
+// resultMetadata stores the metadata added by an healthcheck during its
+// execution
+type resultMetadata struct {
+	lock   sync.Mutex
+	values map[string]string
+	// the keys dropped once MetadataMaxEntries is reached
+	dropped map[string]bool
+}
+
+// WithMetadata returns a context in which healthchecks can store metadata
+// for their result
+func WithMetadata(ctx context.Context) context.Context {
+	return context.WithValue(ctx, metadataKey{}, &resultMetadata{})
+}
+
+// AddMetadata adds a metadata to the result of the healthcheck being executed.
+// It does nothing if the context was not created using WithMetadata.
+// Values are truncated to MetadataMaxValueLength, and new keys are dropped
+// once MetadataMaxEntries is reached (see logDroppedMetadata).
+func AddMetadata(ctx context.Context, key string, value string) {
+	metadata, ok := ctx.Value(metadataKey{}).(*resultMetadata)
+	if !ok {
+		return
+	}
+	metadata.lock.Lock()
+	defer metadata.lock.Unlock()
+	if metadata.values == nil {
+		metadata.values = make(map[string]string)
+	}
+	if _, ok := metadata.values[key]; !ok && len(metadata.values) >= MetadataMaxEntries {
+		if metadata.dropped == nil {
+			metadata.dropped = make(map[string]bool)
+		}
+		metadata.dropped[key] = true
+		return
+	}
+	if len(value) > MetadataMaxValueLength {
+		value = value[:MetadataMaxValueLength]
+	}
+	metadata.values[key] = value
+}
+
+// addIPMetadata adds the IP address of a network address to the metadata
+func addIPMetadata(ctx context.Context, addr net.Addr) {
+	if addr == nil {
+		return
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return
+	}
+	AddMetadata(ctx, "ip", host)
+}
+
+// tlsVersionName returns the name of a TLS version
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	}
+	return fmt.Sprintf("0x%04X", version)
+}
+
+// GetMetadata returns the metadata stored in the context
+func GetMetadata(ctx context.Context) map[string]string {
+	metadata, ok := ctx.Value(metadataKey{}).(*resultMetadata)
+	if !ok {
+		return nil
+	}
+	metadata.lock.Lock()
+	defer metadata.lock.Unlock()
+	if metadata.values == nil {
+		return nil
+	}
+	result := make(map[string]string, len(metadata.values))
+	for k, v := range metadata.values {
+		result[k] = v
+	}
+	return result
+}
+
+// logDroppedMetadata logs the metadata keys dropped during the execution
+// because MetadataMaxEntries was reached
+func logDroppedMetadata(ctx context.Context, healthcheck Healthcheck) {
+	metadata, ok := ctx.Value(metadataKey{}).(*resultMetadata)
+	if !ok {
+		return
+	}
+	metadata.lock.Lock()
+	keys := make([]string, 0, len(metadata.dropped))
+	for key := range metadata.dropped {
+		keys = append(keys, key)
+	}
+	metadata.lock.Unlock()
+	if len(keys) == 0 {
+		return
+	}
+	sort.Strings(keys)
+	healthcheck.LogError(
+		fmt.Errorf("The metadata %s are dropped, a result has at most %d metadata", strings.Join(keys, ", "), MetadataMaxEntries),
+		"too many metadata")
+}
@@ -0,0 +1,105 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestAddMetadataBounds(t *testing.T) {
+	ctx := WithMetadata(context.Background())
+	for i := 0; i < MetadataMaxEntries+10; i++ {
+		AddMetadata(ctx, fmt.Sprintf("key-%d", i), "value")
+	}
+	metadata := GetMetadata(ctx)
+	if len(metadata) != MetadataMaxEntries {
+		t.Fatalf("Invalid metadata size %d", len(metadata))
+	}
+	// existing keys can still be updated
+	AddMetadata(ctx, "key-0", strings.Repeat("a", MetadataMaxValueLength*2))
+	metadata = GetMetadata(ctx)
+	if len(metadata["key-0"]) != MetadataMaxValueLength {
+		t.Fatalf("The value should be truncated, size %d", len(metadata["key-0"]))
+	}
+	if _, ok := metadata[fmt.Sprintf("key-%d", MetadataMaxEntries)]; ok {
+		t.Fatalf("The metadata should be bounded")
+	}
+	// no-op without metadata
+	AddMetadata(context.Background(), "foo", "bar")
+
+	// the dropped keys are logged
+	var buffer bytes.Buffer
+	logger := zap.New(zapcore.NewCore(
+		zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+		zapcore.AddSync(&buffer),
+		zap.DebugLevel))
+	h := &TCPHealthcheck{Logger: logger, Config: &TCPHealthcheckConfiguration{Base: Base{Name: "foo"}}}
+	logDroppedMetadata(ctx, h)
+	expected := fmt.Sprintf("key-%d, key-%d", MetadataMaxEntries, MetadataMaxEntries+1)
+	if !strings.Contains(buffer.String(), expected) || !strings.Contains(buffer.String(), `"name":"foo"`) {
+		t.Fatalf("Invalid dropped metadata log %s", buffer.String())
+	}
+	buffer.Reset()
+	logDroppedMetadata(WithMetadata(context.Background()), h)
+	if buffer.Len() != 0 {
+		t.Fatalf("Nothing should be logged without dropped metadata: %s", buffer.String())
+	}
+}
+
+func TestHTTPExecuteMetadata(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, err := w.Write([]byte("hello"))
+		if err != nil {
+			t.Fatalf("Error writing :\n%v", err)
+		}
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	h := HTTPHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &HTTPHealthcheckConfiguration{
+			ValidStatus: []uint{201},
+			Port:        uint(port),
+			Target:      "127.0.0.1",
+			Protocol:    HTTP,
+			Path:        "/",
+			Timeout:     Duration(time.Second * 2),
+		},
+	}
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	ctx := WithMetadata(context.Background())
+	err = h.Execute(ctx)
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	metadata := GetMetadata(ctx)
+	expected := map[string]string{
+		"ip":            "127.0.0.1",
+		"status-code":   "201",
+		"response-size": "5",
+	}
+	for k, v := range expected {
+		if metadata[k] != v {
+			t.Fatalf("Invalid metadata %s: %v", k, metadata)
+		}
+	}
+	if _, ok := metadata["tls-version"]; ok {
+		t.Fatalf("The TLS version should not be set: %v", metadata)
+	}
+}
@@ -0,0 +1,117 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// MetricAssertion verifies that a Prometheus metric exists in the response
+// body with a value between the optional min and max values. Counters,
+// gauges and untyped metrics are supported.
+type MetricAssertion struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Min    *float64          `json:"min,omitempty" yaml:"min,omitempty"`
+	Max    *float64          `json:"max,omitempty" yaml:"max,omitempty"`
+}
+
+// Validate validates the metric assertion
+func (m *MetricAssertion) Validate() error {
+	if m.Name == "" {
+		return errors.New("The metric name is missing")
+	}
+	if m.Min != nil && m.Max != nil && *m.Min > *m.Max {
+		return errors.New("The metric min value should be lower than the max value")
+	}
+	return nil
+}
+
+// matches returns true if the metric has all the expected labels
+func (m *MetricAssertion) matches(metric *dto.Metric) bool {
+	for name, value := range m.Labels {
+		found := false
+		for _, label := range metric.GetLabel() {
+			if label.GetName() == name && label.GetValue() == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// metricValue returns the value of a counter, gauge or untyped metric
+func metricValue(metric *dto.Metric) (float64, bool) {
+	switch {
+	case metric.Counter != nil:
+		return metric.Counter.GetValue(), true
+	case metric.Gauge != nil:
+		return metric.Gauge.GetValue(), true
+	case metric.Untyped != nil:
+		return metric.Untyped.GetValue(), true
+	}
+	return 0, false
+}
+
+// check parses a Prometheus text format body and verifies the assertion. The
+// value found is added to the result metadata.
+func (m *MetricAssertion) check(ctx context.Context, body []byte) error {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(body))
+	if err != nil {
+		AddMetadata(ctx, "reason", "metric-parse-error")
+		return errors.Wrapf(err, "Fail to parse the metrics")
+	}
+	family, ok := families[m.Name]
+	if ok {
+		for _, metric := range family.GetMetric() {
+			if !m.matches(metric) {
+				continue
+			}
+			value, ok := metricValue(metric)
+			if !ok {
+				continue
+			}
+			AddMetadata(ctx, "metric-value", strconv.FormatFloat(value, 'f', -1, 64))
+			if m.Min != nil && value < *m.Min {
+				AddMetadata(ctx, "reason", "metric-threshold")
+				return fmt.Errorf("The metric %s value %g is lower than %g", m.Name, value, *m.Min)
+			}
+			if m.Max != nil && value > *m.Max {
+				AddMetadata(ctx, "reason", "metric-threshold")
+				return fmt.Errorf("The metric %s value %g is greater than %g", m.Name, value, *m.Max)
+			}
+			return nil
+		}
+	}
+	AddMetadata(ctx, "reason", "metric-missing")
+	return fmt.Errorf("The metric %s was not found", m.Name)
+}
+
+// DeepCopyInto copies the receiver into out. in must be non-nil.
+func (m *MetricAssertion) DeepCopyInto(out *MetricAssertion) {
+	*out = *m
+	if m.Labels != nil {
+		out.Labels = make(map[string]string, len(m.Labels))
+		for k, v := range m.Labels {
+			out.Labels[k] = v
+		}
+	}
+	if m.Min != nil {
+		min := *m.Min
+		out.Min = &min
+	}
+	if m.Max != nil {
+		max := *m.Max
+		out.Max = &max
+	}
+}
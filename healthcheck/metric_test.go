@@ -0,0 +1,53 @@
+package healthcheck
+
+import (
+	"context"
+	"testing"
+)
+
+const metricsBody = `# HELP queue_size The queue size
+# TYPE queue_size gauge
+queue_size{queue="a"} 10
+queue_size{queue="b"} 200
+# TYPE requests_total counter
+requests_total 42
+`
+
+func TestMetricAssertionCheck(t *testing.T) {
+	min := float64(5)
+	max := float64(100)
+	cases := []struct {
+		assertion MetricAssertion
+		success   bool
+		reason    string
+		value     string
+	}{
+		{assertion: MetricAssertion{Name: "requests_total", Min: &min}, success: true, value: "42"},
+		{assertion: MetricAssertion{Name: "queue_size", Labels: map[string]string{"queue": "a"}, Min: &min, Max: &max}, success: true, value: "10"},
+		{assertion: MetricAssertion{Name: "queue_size", Labels: map[string]string{"queue": "b"}, Max: &max}, success: false, reason: "metric-threshold", value: "200"},
+		{assertion: MetricAssertion{Name: "queue_size", Labels: map[string]string{"queue": "c"}}, success: false, reason: "metric-missing"},
+		{assertion: MetricAssertion{Name: "missing"}, success: false, reason: "metric-missing"},
+	}
+	for i, c := range cases {
+		ctx := WithMetadata(context.Background())
+		err := c.assertion.check(ctx, []byte(metricsBody))
+		if c.success != (err == nil) {
+			t.Fatalf("Invalid result for case %d: %v", i, err)
+		}
+		metadata := GetMetadata(ctx)
+		if metadata["reason"] != c.reason || metadata["metric-value"] != c.value {
+			t.Fatalf("Invalid metadata for case %d: %v", i, metadata)
+		}
+	}
+}
+
+func TestMetricAssertionValidate(t *testing.T) {
+	min := float64(10)
+	max := float64(5)
+	if (&MetricAssertion{}).Validate() == nil {
+		t.Fatalf("The metric name should be required")
+	}
+	if (&MetricAssertion{Name: "foo", Min: &min, Max: &max}).Validate() == nil {
+		t.Fatalf("The min value should be lower than the max value")
+	}
+}
@@ -0,0 +1,147 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// SourceIPLabel the label of the results of an healthcheck executed from one
+// of its source IPs
+const SourceIPLabel = "source-ip"
+
+// multiSourceHealthcheck is implemented by the healthchecks which can be
+// executed from several source IPs
+type multiSourceHealthcheck interface {
+	sourceChecks() []Healthcheck
+	sourceQuorum() int
+}
+
+// validateSourceIPs validates the source IPs options of an healthcheck
+func validateSourceIPs(sourceIPs []IP, quorum uint, sourceIP IP, sourceInterface string) error {
+	if len(sourceIPs) == 0 {
+		if quorum != 0 {
+			return errors.New("The healthcheck source-quorum requires source-ips")
+		}
+		return nil
+	}
+	if sourceIP != nil || sourceInterface != "" {
+		return errors.New("The healthcheck source-ips can't be used with source-ip or source-interface")
+	}
+	if quorum > uint(len(sourceIPs)) {
+		return fmt.Errorf("The healthcheck source-quorum should be lower or equal than the number of source IPs (%d)", len(sourceIPs))
+	}
+	seen := make(map[string]bool)
+	for _, ip := range sourceIPs {
+		s := net.IP(ip).String()
+		if seen[s] {
+			return fmt.Errorf("The healthcheck source IP %s is duplicated", s)
+		}
+		seen[s] = true
+	}
+	return nil
+}
+
+// sourceBase returns the base configuration of the healthcheck executed from
+// a source IP
+func sourceBase(base Base, ip IP) Base {
+	s := net.IP(ip).String()
+	base.Name = fmt.Sprintf("%s-%s", base.Name, s)
+	labels := make(map[string]string, len(base.Labels)+1)
+	for k, v := range base.Labels {
+		labels[k] = v
+	}
+	labels[SourceIPLabel] = s
+	base.Labels = labels
+	return base
+}
+
+// quorum returns the number of sources which should succeed
+func quorum(configured uint, sources int) int {
+	if configured == 0 {
+		return sources
+	}
+	return int(configured)
+}
+
+// initializeSources initializes the healthchecks executed from each source
+func initializeSources(healthcheck Healthcheck) ([]Healthcheck, error) {
+	multi, ok := healthcheck.(multiSourceHealthcheck)
+	if !ok {
+		return nil, nil
+	}
+	checks := multi.sourceChecks()
+	for _, check := range checks {
+		if err := check.Initialize(); err != nil {
+			return nil, errors.Wrapf(err, "Fail to initialize healthcheck %s", check.Base().Name)
+		}
+	}
+	return checks, nil
+}
+
+// FromSource returns true if the result is produced by the execution of an
+// healthcheck from one of its source IPs
+func (r *Result) FromSource() bool {
+	return r.Labels[SourceIPLabel] != ""
+}
+
+// executeSources executes the healthcheck from all its sources in parallel.
+// A result is produced for each source, and the returned error is not nil
+// if the quorum is not reached.
+func (c *Component) executeSources(ctx context.Context, w *Wrapper) error {
+	results := make([]*Result, len(w.sources))
+	var wg sync.WaitGroup
+	for i := range w.sources {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			check := w.sources[i]
+			sourceCtx := WithMetadata(ctx)
+			start := time.Now()
+			err := c.watch(sourceCtx, check)
+			duration := time.Since(start)
+			if errors.Is(err, ErrResultSkipped) {
+				check.LogDebug(fmt.Sprintf("no result produced: %s", err.Error()))
+				return
+			}
+			result := NewResult(check, duration.Seconds(), err)
+			result.Metadata = GetMetadata(sourceCtx)
+			logDroppedMetadata(sourceCtx, check)
+			w.tagStartup(result)
+			renderMessage(w.message, check, result)
+			results[i] = result
+		}(i)
+	}
+	wg.Wait()
+	successes := 0
+	failed := []string{}
+	for i, result := range results {
+		if result == nil {
+			continue
+		}
+		status := resultStatus(result)
+		if result.Success {
+			successes++
+		} else {
+			failed = append(failed, w.sources[i].Base().Labels[SourceIPLabel])
+		}
+		if !w.oneOff {
+			c.resultHistogram.With(prom.Labels{"name": result.Name, "status": status}).Observe(result.Duration)
+			c.emit(result)
+		}
+	}
+	if len(failed) != 0 {
+		AddMetadata(ctx, "failed-sources", strings.Join(failed, ","))
+	}
+	expected := w.healthcheck.(multiSourceHealthcheck).sourceQuorum()
+	if successes < expected {
+		return fmt.Errorf("%d sources successful out of %d, %d required", successes, len(w.sources), expected)
+	}
+	return nil
+}
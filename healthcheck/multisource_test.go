@@ -0,0 +1,92 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/prometheus"
+)
+
+func TestExecuteSources(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to start the listener: %v", err)
+	}
+	defer listener.Close()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	chanResult := make(chan *Result, 10)
+	component, err := New(zap.NewExample(), chanResult, prom)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	config := &TCPHealthcheckConfiguration{
+		Base: Base{
+			Name:     "foo",
+			Interval: Duration(10 * time.Second),
+			Labels:   map[string]string{"env": "test"},
+		},
+		Port:    uint(listener.Addr().(*net.TCPAddr).Port),
+		Target:  "127.0.0.1",
+		Timeout: Duration(time.Second * 2),
+		// 192.0.2.1 is not a local address, the dial fails
+		SourceIPs:    []IP{IP(net.ParseIP("127.0.0.1")), IP(net.ParseIP("192.0.2.1"))},
+		SourceQuorum: 1,
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Invalid configuration: %v", err)
+	}
+	check := NewTCPHealthcheck(zap.NewExample(), config)
+	wrapper := NewWrapper(check)
+	wrapper.sources, err = initializeSources(check)
+	if err != nil {
+		t.Fatalf("Fail to initialize the sources: %v", err)
+	}
+	ctx := WithMetadata(context.Background())
+	err = component.executeSources(ctx, wrapper)
+	if err != nil {
+		t.Fatalf("The quorum should be reached: %v", err)
+	}
+	if GetMetadata(ctx)["failed-sources"] != "192.0.2.1" {
+		t.Fatalf("Invalid metadata %v", GetMetadata(ctx))
+	}
+	results := map[string]*Result{}
+	for i := 0; i < 2; i++ {
+		result := <-chanResult
+		results[result.Name] = result
+	}
+	success := results["foo-127.0.0.1"]
+	failure := results["foo-192.0.2.1"]
+	if success == nil || !success.Success || success.Labels["source-ip"] != "127.0.0.1" || success.Labels["env"] != "test" {
+		t.Fatalf("Invalid result for the first source: %v", success)
+	}
+	if failure == nil || failure.Success || failure.Labels["source-ip"] != "192.0.2.1" {
+		t.Fatalf("Invalid result for the second source: %v", failure)
+	}
+	if config.Base.Labels["source-ip"] != "" {
+		t.Fatalf("The healthcheck labels should not be modified")
+	}
+
+	config.SourceQuorum = 0
+	err = component.executeSources(context.Background(), wrapper)
+	if err == nil || !strings.Contains(err.Error(), "1 sources successful out of 2, 2 required") {
+		t.Fatalf("The quorum should not be reached: %v", err)
+	}
+
+	config.SourceIP = IP(net.ParseIP("127.0.0.1"))
+	if err := config.Validate(); err == nil {
+		t.Fatalf("source-ip and source-ips should not be allowed together")
+	}
+	config.SourceIP = nil
+	config.SourceQuorum = 3
+	if err := config.Validate(); err == nil {
+		t.Fatalf("The quorum should be lower than the number of sources")
+	}
+}
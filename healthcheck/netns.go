@@ -0,0 +1,42 @@
+package healthcheck
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// netnsDirectory the directory of the network namespaces created by ip netns
+var netnsDirectory = "/var/run/netns"
+
+// networkNamespacePath returns the path of a network namespace, which is
+// either the name of a namespace created by ip netns or an absolute path (for
+// example /proc/<pid>/ns/net)
+func networkNamespacePath(namespace string) string {
+	if filepath.IsAbs(namespace) {
+		return namespace
+	}
+	return filepath.Join(netnsDirectory, namespace)
+}
+
+// validateNetworkNamespace verifies that the network namespace exists. An
+// empty namespace means the namespace of Cabourotte.
+func validateNetworkNamespace(namespace string) error {
+	if namespace == "" {
+		return nil
+	}
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("The network-namespace option is only supported on Linux, not on %s", runtime.GOOS)
+	}
+	if !filepath.IsAbs(namespace) && (strings.Contains(namespace, "/") || namespace == "." || namespace == "..") {
+		return fmt.Errorf("Invalid network namespace %s", namespace)
+	}
+	if _, err := os.Stat(networkNamespacePath(namespace)); err != nil {
+		return errors.Wrapf(err, "The network namespace %s does not exist", namespace)
+	}
+	return nil
+}
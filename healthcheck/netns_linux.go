@@ -0,0 +1,92 @@
+//go:build linux
+
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// setns moves the current thread to the network namespace of the file
+func setns(file *os.File) error {
+	return unix.Setns(int(file.Fd()), unix.CLONE_NEWNET)
+}
+
+// dialInNamespace dials from a thread moved to the network namespace. The
+// thread is restored to its namespace once the socket is created, or
+// discarded if it can't be restored.
+func dialInNamespace(ctx context.Context, path string, dial dialFunc, network, address string) (net.Conn, error) {
+	type dialResult struct {
+		conn net.Conn
+		err  error
+	}
+	done := make(chan dialResult, 1)
+	go func() {
+		runtime.LockOSThread()
+		current, err := os.Open(fmt.Sprintf("/proc/%d/task/%d/ns/net", os.Getpid(), unix.Gettid()))
+		if err != nil {
+			runtime.UnlockOSThread()
+			done <- dialResult{err: errors.Wrap(err, "Fail to open the current network namespace")}
+			return
+		}
+		defer current.Close()
+		target, err := os.Open(path)
+		if err != nil {
+			runtime.UnlockOSThread()
+			done <- dialResult{err: errors.Wrapf(err, "Fail to open the network namespace %s", path)}
+			return
+		}
+		defer target.Close()
+		if err := setns(target); err != nil {
+			runtime.UnlockOSThread()
+			done <- dialResult{err: errors.Wrapf(err, "Fail to enter the network namespace %s", path)}
+			return
+		}
+		conn, err := dial(ctx, network, address)
+		// the goroutine exits with the thread locked if it's still in the
+		// namespace, and the runtime terminates the thread
+		if setns(current) == nil {
+			runtime.UnlockOSThread()
+		}
+		done <- dialResult{conn: conn, err: err}
+	}()
+	result := <-done
+	return result.conn, result.err
+}
+
+// networkNamespaceDial returns a dial function creating the sockets in a
+// network namespace. The target is resolved in the namespace of Cabourotte
+// first, the IPs are tried in order.
+func networkNamespaceDial(namespace string, dial dialFunc) dialFunc {
+	path := networkNamespacePath(namespace)
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		ips := []string{host}
+		if net.ParseIP(host) == nil {
+			ips, err = net.DefaultResolver.LookupHost(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+		}
+		var firstErr error
+		for _, ip := range ips {
+			conn, err := dialInNamespace(ctx, path, dial, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		return nil, firstErr
+	}
+}
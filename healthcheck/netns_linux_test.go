@@ -0,0 +1,86 @@
+//go:build linux
+
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestValidateNetworkNamespace(t *testing.T) {
+	directory, err := os.MkdirTemp("", "netns")
+	if err != nil {
+		t.Fatalf("Fail to create the directory\n%v", err)
+	}
+	defer os.RemoveAll(directory)
+	netnsDirectory = directory
+	defer func() { netnsDirectory = "/var/run/netns" }()
+	if err := os.WriteFile(directory+"/cni-1234", nil, 0600); err != nil {
+		t.Fatalf("Fail to create the namespace file\n%v", err)
+	}
+	for _, namespace := range []string{"", "cni-1234", "/proc/self/ns/net"} {
+		if err := validateNetworkNamespace(namespace); err != nil {
+			t.Fatalf("The namespace %s should be valid\n%v", namespace, err)
+		}
+	}
+	for _, namespace := range []string{"unknown", "../cni-1234", "/proc/self/ns/unknown"} {
+		if err := validateNetworkNamespace(namespace); err == nil {
+			t.Fatalf("The namespace %s should be invalid", namespace)
+		}
+	}
+}
+
+func TestTCPExecuteNetworkNamespace(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to listen :\n%v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	config := &TCPHealthcheckConfiguration{
+		Base: Base{
+			Name:     "foo",
+			Interval: Duration(time.Second * 5),
+		},
+		Port:    uint(listener.Addr().(*net.TCPAddr).Port),
+		Target:  "127.0.0.1",
+		Timeout: Duration(time.Second * 2),
+		// the namespace of the test process
+		NetworkNamespace: "/proc/self/ns/net",
+	}
+	err = config.Validate()
+	if err != nil {
+		t.Fatalf("The configuration should be valid :\n%v", err)
+	}
+	h := TCPHealthcheck{
+		Logger: zap.NewExample(),
+		Config: config,
+	}
+	h.buildURL()
+	err = h.Execute(context.Background())
+	if err != nil && strings.Contains(err.Error(), syscall.EPERM.Error()) {
+		t.Skip("CAP_SYS_ADMIN is needed to enter a network namespace")
+	}
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	config.Proxy = "socks5://127.0.0.1:1080"
+	if config.Validate() == nil {
+		t.Fatalf("The network namespace can't be used with a proxy")
+	}
+}
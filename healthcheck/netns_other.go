@@ -0,0 +1,18 @@
+//go:build !linux
+
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"runtime"
+)
+
+// networkNamespaceDial returns a dial function failing, the network
+// namespaces are only supported on Linux
+func networkNamespaceDial(namespace string, dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		return nil, fmt.Errorf("network namespaces are not supported on %s", runtime.GOOS)
+	}
+}
@@ -0,0 +1,155 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ocsp"
+)
+
+// maxOCSPResponseSize the maximum size of the OCSP responses fetched from the
+// responders
+const maxOCSPResponseSize = 64 * 1024
+
+// OCSPAssertion verifies the OCSP status of the server certificate. The OCSP
+// response should indicate a good status.
+type OCSPAssertion struct {
+	// fails if the server does not staple an OCSP response
+	RequireStaple bool `json:"require-staple" yaml:"require-staple"`
+	// queries the OCSP responder of the certificate if no response is stapled
+	Fetch bool `json:"fetch"`
+	// adds a warning to the result if the OCSP response expires in less than
+	// this duration
+	WarnExpiration Duration `json:"warn-expiration,omitempty" yaml:"warn-expiration,omitempty"`
+}
+
+// Validate validates the OCSP assertion
+func (a *OCSPAssertion) Validate() error {
+	if a.RequireStaple && a.Fetch {
+		return errors.New("The OCSP require-staple and fetch options are exclusive")
+	}
+	if a.WarnExpiration < 0 {
+		return errors.New("The OCSP warn-expiration should be positive")
+	}
+	return nil
+}
+
+// ocspStatusName returns the name of an OCSP status
+func ocspStatusName(status int) string {
+	switch status {
+	case ocsp.Good:
+		return "good"
+	case ocsp.Revoked:
+		return "revoked"
+	}
+	return "unknown"
+}
+
+// ocspIssuer returns the issuer of the leaf certificate, nil if it is unknown
+func ocspIssuer(state *tls.ConnectionState) *x509.Certificate {
+	if len(state.VerifiedChains) != 0 && len(state.VerifiedChains[0]) > 1 {
+		return state.VerifiedChains[0][1]
+	}
+	if len(state.PeerCertificates) > 1 {
+		return state.PeerCertificates[1]
+	}
+	return nil
+}
+
+// fetchOCSP queries the OCSP responder of a certificate
+func fetchOCSP(ctx context.Context, leaf *x509.Certificate, issuer *x509.Certificate) ([]byte, error) {
+	if len(leaf.OCSPServer) == 0 {
+		return nil, errors.New("The certificate has no OCSP responder")
+	}
+	request, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Fail to create the OCSP request")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, leaf.OCSPServer[0], bytes.NewReader(request))
+	if err != nil {
+		return nil, errors.Wrap(err, "Fail to create the OCSP request")
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	req.Header.Set("User-Agent", "Cabourotte")
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "OCSP request to %s failed", leaf.OCSPServer[0])
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("The OCSP responder %s returned the status %d", leaf.OCSPServer[0], response.StatusCode)
+	}
+	return ReadBody(response.Body, maxOCSPResponseSize)
+}
+
+// checkOCSP verifies the OCSP response stapled by the server, or the one
+// returned by the certificate responder
+func checkOCSP(ctx context.Context, assertion *OCSPAssertion, state *tls.ConnectionState) error {
+	if assertion == nil {
+		return nil
+	}
+	if state == nil || len(state.PeerCertificates) == 0 {
+		AddMetadata(ctx, "reason", "ocsp")
+		return errors.New("The OCSP status can't be verified, no TLS connection")
+	}
+	leaf := state.PeerCertificates[0]
+	issuer := ocspIssuer(state)
+	raw := state.OCSPResponse
+	if len(raw) != 0 {
+		AddMetadata(ctx, "ocsp-source", "staple")
+		// without issuer, the signature of the response can't be verified
+		if issuer == nil {
+			AddMetadata(ctx, "reason", "ocsp")
+			return errors.New("The stapled OCSP response can't be verified, the certificate issuer is unknown")
+		}
+	} else {
+		if assertion.RequireStaple {
+			AddMetadata(ctx, "reason", "ocsp")
+			return errors.New("The server did not staple an OCSP response")
+		}
+		if !assertion.Fetch {
+			AddMetadata(ctx, "ocsp-source", "none")
+			return nil
+		}
+		if issuer == nil {
+			AddMetadata(ctx, "reason", "ocsp")
+			return errors.New("The OCSP request can't be created, the certificate issuer is unknown")
+		}
+		var err error
+		raw, err = fetchOCSP(ctx, leaf, issuer)
+		if err != nil {
+			AddMetadata(ctx, "reason", "ocsp")
+			return err
+		}
+		AddMetadata(ctx, "ocsp-source", "responder")
+	}
+	response, err := ocsp.ParseResponseForCert(raw, leaf, issuer)
+	if err != nil {
+		AddMetadata(ctx, "reason", "ocsp")
+		return errors.Wrap(err, "Invalid OCSP response")
+	}
+	AddMetadata(ctx, "ocsp-status", ocspStatusName(response.Status))
+	if !response.NextUpdate.IsZero() {
+		AddMetadata(ctx, "ocsp-next-update", response.NextUpdate.UTC().Format(time.RFC3339))
+	}
+	if response.Status != ocsp.Good {
+		AddMetadata(ctx, "reason", "ocsp")
+		return fmt.Errorf("The OCSP status of the certificate is %s", ocspStatusName(response.Status))
+	}
+	if !response.NextUpdate.IsZero() {
+		if time.Now().After(response.NextUpdate) {
+			AddMetadata(ctx, "reason", "ocsp")
+			return fmt.Errorf("The OCSP response expired at %s", response.NextUpdate.String())
+		}
+		if assertion.WarnExpiration != 0 && time.Until(response.NextUpdate) < time.Duration(assertion.WarnExpiration) {
+			AddMetadata(ctx, "warning", fmt.Sprintf("The OCSP response will expire at %s", response.NextUpdate.String()))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+type ocspFixture struct {
+	ca    *x509.Certificate
+	caKey *ecdsa.PrivateKey
+	leaf  *x509.Certificate
+}
+
+func newOCSPFixture(t *testing.T, responder string) *ocspFixture {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Fail to generate the CA key\n%v", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("Fail to create the CA certificate\n%v", err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatalf("Fail to parse the CA certificate\n%v", err)
+	}
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Fail to generate the leaf key\n%v", err)
+	}
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "leaf"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		OCSPServer:   []string{responder},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, ca, &leafKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("Fail to create the leaf certificate\n%v", err)
+	}
+	leaf, err := x509.ParseCertificate(leafDER)
+	if err != nil {
+		t.Fatalf("Fail to parse the leaf certificate\n%v", err)
+	}
+	return &ocspFixture{ca: ca, caKey: caKey, leaf: leaf}
+}
+
+func (f *ocspFixture) response(t *testing.T, status int, nextUpdate time.Time) []byte {
+	response, err := ocsp.CreateResponse(f.ca, f.ca, ocsp.Response{
+		Status:       status,
+		SerialNumber: f.leaf.SerialNumber,
+		ThisUpdate:   time.Now().Add(-time.Minute),
+		NextUpdate:   nextUpdate,
+		RevokedAt:    time.Now().Add(-time.Minute),
+	}, f.caKey)
+	if err != nil {
+		t.Fatalf("Fail to create the OCSP response\n%v", err)
+	}
+	return response
+}
+
+func (f *ocspFixture) state(staple []byte) *tls.ConnectionState {
+	return &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{f.leaf, f.ca},
+		OCSPResponse:     staple,
+	}
+}
+
+func TestCheckOCSPStaple(t *testing.T) {
+	fixture := newOCSPFixture(t, "http://127.0.0.1:1")
+	ctx := WithMetadata(context.Background())
+	staple := fixture.response(t, ocsp.Good, time.Now().Add(time.Hour))
+	err := checkOCSP(ctx, &OCSPAssertion{RequireStaple: true}, fixture.state(staple))
+	if err != nil {
+		t.Fatalf("The OCSP assertion should be successful\n%v", err)
+	}
+	metadata := GetMetadata(ctx)
+	if metadata["ocsp-status"] != "good" || metadata["ocsp-source"] != "staple" || metadata["ocsp-next-update"] == "" {
+		t.Fatalf("Invalid metadata %v", metadata)
+	}
+	if _, ok := metadata["warning"]; ok {
+		t.Fatalf("The OCSP response should not be near expiry")
+	}
+
+	ctx = WithMetadata(context.Background())
+	err = checkOCSP(ctx, &OCSPAssertion{WarnExpiration: Duration(2 * time.Hour)}, fixture.state(staple))
+	if err != nil {
+		t.Fatalf("The OCSP assertion should be successful\n%v", err)
+	}
+	if GetMetadata(ctx)["warning"] == "" {
+		t.Fatalf("A warning was expected for the OCSP response near expiry")
+	}
+
+	ctx = WithMetadata(context.Background())
+	revoked := fixture.response(t, ocsp.Revoked, time.Now().Add(time.Hour))
+	err = checkOCSP(ctx, &OCSPAssertion{}, fixture.state(revoked))
+	if err == nil {
+		t.Fatalf("The OCSP assertion should fail for a revoked certificate")
+	}
+	metadata = GetMetadata(ctx)
+	if metadata["ocsp-status"] != "revoked" || metadata["reason"] != "ocsp" {
+		t.Fatalf("Invalid metadata %v", metadata)
+	}
+
+	// the stapled response can't be verified without the issuer
+	ctx = WithMetadata(context.Background())
+	state := fixture.state(staple)
+	state.PeerCertificates = state.PeerCertificates[:1]
+	err = checkOCSP(ctx, &OCSPAssertion{}, state)
+	if err == nil {
+		t.Fatalf("The OCSP assertion should fail without issuer")
+	}
+	if GetMetadata(ctx)["reason"] != "ocsp" {
+		t.Fatalf("Invalid metadata %v", GetMetadata(ctx))
+	}
+
+	ctx = WithMetadata(context.Background())
+	err = checkOCSP(ctx, &OCSPAssertion{RequireStaple: true}, fixture.state(nil))
+	if err == nil {
+		t.Fatalf("The OCSP assertion should fail without staple")
+	}
+	ctx = WithMetadata(context.Background())
+	err = checkOCSP(ctx, &OCSPAssertion{}, fixture.state(nil))
+	if err != nil {
+		t.Fatalf("The OCSP assertion should be successful without staple\n%v", err)
+	}
+}
+
+func TestCheckOCSPFetch(t *testing.T) {
+	var fixture *ocspFixture
+	status := ocsp.Good
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/ocsp-request" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/ocsp-response")
+		// nolint
+		w.Write(fixture.response(t, status, time.Now().Add(time.Hour)))
+	}))
+	defer ts.Close()
+	fixture = newOCSPFixture(t, ts.URL)
+	ctx := WithMetadata(context.Background())
+	err := checkOCSP(ctx, &OCSPAssertion{Fetch: true}, fixture.state(nil))
+	if err != nil {
+		t.Fatalf("The OCSP assertion should be successful\n%v", err)
+	}
+	metadata := GetMetadata(ctx)
+	if metadata["ocsp-status"] != "good" || metadata["ocsp-source"] != "responder" {
+		t.Fatalf("Invalid metadata %v", metadata)
+	}
+	status = ocsp.Unknown
+	ctx = WithMetadata(context.Background())
+	err = checkOCSP(ctx, &OCSPAssertion{Fetch: true}, fixture.state(nil))
+	if err == nil {
+		t.Fatalf("The OCSP assertion should fail for an unknown status")
+	}
+}
+
+func TestOCSPAssertionValidate(t *testing.T) {
+	assertion := OCSPAssertion{RequireStaple: true, Fetch: true}
+	if err := assertion.Validate(); err == nil {
+		t.Fatalf("The require-staple and fetch options should be exclusive")
+	}
+	assertion = OCSPAssertion{WarnExpiration: Duration(-time.Second)}
+	if err := assertion.Validate(); err == nil {
+		t.Fatalf("The warn-expiration should be positive")
+	}
+}
@@ -0,0 +1,65 @@
+package healthcheck
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// PauseCheck stops the execution of an healthcheck, which stays registered. The
+// healthcheck stays paused if it is updated or reloaded, until ResumeCheck is
+// called or until it is removed.
+func (c *Component) PauseCheck(name string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	wrapper, ok := c.Healthchecks[name]
+	if !ok {
+		return fmt.Errorf("Healthcheck %s not found", name)
+	}
+	if c.paused[name] {
+		return nil
+	}
+	wrapper.healthcheck.LogInfo("Pausing healthcheck")
+	err := wrapper.Stop()
+	if err != nil {
+		return errors.Wrapf(err, "Fail to stop healthcheck %s", name)
+	}
+	c.Healthchecks[name] = wrapper.recreate()
+	c.paused[name] = true
+	c.pausedGauge.With(prom.Labels{"name": name}).Set(1)
+	return nil
+}
+
+// ResumeCheck starts again an healthcheck stopped by PauseCheck
+func (c *Component) ResumeCheck(name string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	wrapper, ok := c.Healthchecks[name]
+	if !ok {
+		return fmt.Errorf("Healthcheck %s not found", name)
+	}
+	if !c.paused[name] {
+		return nil
+	}
+	wrapper.healthcheck.LogInfo("Resuming healthcheck")
+	c.unpause(name)
+	if !c.halted() {
+		c.startWrapper(wrapper)
+	}
+	return nil
+}
+
+// IsPaused returns true if the healthcheck is paused
+func (c *Component) IsPaused(name string) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.paused[name]
+}
+
+// unpause removes the paused state of an healthcheck.
+// The function is *not* thread-safe.
+func (c *Component) unpause(name string) {
+	delete(c.paused, name)
+	c.pausedGauge.Delete(prom.Labels{"name": name})
+}
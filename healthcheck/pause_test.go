@@ -0,0 +1,101 @@
+package healthcheck
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/prometheus"
+)
+
+func TestPauseResumeCheck(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(logger, make(chan *Result, 10), prom)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	newCheck := func(port uint) Healthcheck {
+		return NewTCPHealthcheck(
+			logger,
+			&TCPHealthcheckConfiguration{
+				Base: Base{
+					Name:            "foo",
+					Interval:        Duration(time.Second * 5),
+					MessageTemplate: "{{ .Name }}",
+				},
+				Target:    "127.0.0.1",
+				Port:      port,
+				Timeout:   Duration(time.Second * 3),
+				SourceIPs: []IP{IP(net.ParseIP("127.0.0.1"))},
+			},
+		)
+	}
+	err = component.AddCheck(newCheck(9000))
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	if component.PauseCheck("bar") == nil {
+		t.Fatalf("Pausing an unknown healthcheck should fail")
+	}
+	err = component.PauseCheck("foo")
+	if err != nil {
+		t.Fatalf("Fail to pause the healthcheck\n%v", err)
+	}
+	if !component.IsPaused("foo") || component.Healthchecks["foo"].Tick != nil {
+		t.Fatalf("The healthcheck should be paused")
+	}
+	// the recreated wrapper keeps the sources and the message template
+	if len(component.Healthchecks["foo"].sources) != 1 || component.Healthchecks["foo"].message == nil {
+		t.Fatalf("The wrapper state should be kept")
+	}
+	// an update keeps the healthcheck paused
+	err = component.AddCheck(newCheck(9001))
+	if err != nil {
+		t.Fatalf("Fail to update the healthcheck\n%v", err)
+	}
+	if !component.IsPaused("foo") || component.Healthchecks["foo"].Tick != nil {
+		t.Fatalf("The healthcheck should still be paused")
+	}
+	// resuming the component does not resume paused healthchecks
+	err = component.Suspend()
+	if err != nil {
+		t.Fatalf("Fail to suspend the component\n%v", err)
+	}
+	component.Resume()
+	if component.Healthchecks["foo"].Tick != nil {
+		t.Fatalf("The healthcheck should still be paused")
+	}
+	started := component.Healthchecks["foo"].started
+	err = component.ResumeCheck("foo")
+	if err != nil {
+		t.Fatalf("Fail to resume the healthcheck\n%v", err)
+	}
+	if component.IsPaused("foo") || component.Healthchecks["foo"].Tick == nil {
+		t.Fatalf("The healthcheck should be started")
+	}
+	// the startup grace period is not restarted
+	if started.IsZero() || !component.Healthchecks["foo"].started.Equal(started) {
+		t.Fatalf("The start time of the healthcheck should be kept")
+	}
+	err = component.PauseCheck("foo")
+	if err != nil {
+		t.Fatalf("Fail to pause the healthcheck\n%v", err)
+	}
+	err = component.RemoveCheck("foo")
+	if err != nil {
+		t.Fatalf("Fail to remove the healthcheck\n%v", err)
+	}
+	if component.IsPaused("foo") {
+		t.Fatalf("The removed healthcheck should not be paused")
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
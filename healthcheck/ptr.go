@@ -0,0 +1,101 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ptrTimeout the maximum duration of a reverse DNS lookup
+	ptrTimeout = 500 * time.Millisecond
+	// ptrCacheTTL the duration during which a reverse DNS lookup is cached
+	ptrCacheTTL = 5 * time.Minute
+	// ptrCacheSize the maximum number of cached reverse DNS lookups
+	ptrCacheSize = 4096
+)
+
+type ptrEntry struct {
+	hostname string
+	expire   time.Time
+	// true while the lookup is in progress
+	resolving bool
+}
+
+var ptrCache = struct {
+	lock    sync.Mutex
+	entries map[string]ptrEntry
+}{entries: make(map[string]ptrEntry)}
+
+// lookupPTR returns the cached hostname for an IP address, or an empty string
+// if not known yet. The reverse DNS lookup is done in the background when
+// the entry is missing or expired, to not delay the healthcheck.
+func lookupPTR(ip string) string {
+	now := time.Now()
+	ptrCache.lock.Lock()
+	defer ptrCache.lock.Unlock()
+	entry, ok := ptrCache.entries[ip]
+	if (ok && now.Before(entry.expire)) || entry.resolving {
+		return entry.hostname
+	}
+	if !ok && len(ptrCache.entries) >= ptrCacheSize {
+		prunePTRCache(now)
+		if len(ptrCache.entries) >= ptrCacheSize {
+			return ""
+		}
+	}
+	entry.resolving = true
+	ptrCache.entries[ip] = entry
+	go resolvePTR(ip)
+	return entry.hostname
+}
+
+// resolvePTR executes the reverse DNS lookup of an IP address and caches it
+func resolvePTR(ip string) {
+	ctx, cancel := context.WithTimeout(context.Background(), ptrTimeout)
+	defer cancel()
+	hostname := ""
+	names, err := net.DefaultResolver.LookupAddr(ctx, ip)
+	if err == nil && len(names) > 0 {
+		hostname = strings.TrimSuffix(names[0], ".")
+	}
+	ptrCache.lock.Lock()
+	defer ptrCache.lock.Unlock()
+	ptrCache.entries[ip] = ptrEntry{hostname: hostname, expire: time.Now().Add(ptrCacheTTL)}
+}
+
+// prunePTRCache removes the expired entries.
+// The function is *not* thread-safe.
+func prunePTRCache(now time.Time) {
+	for ip, entry := range ptrCache.entries {
+		if !entry.resolving && !now.Before(entry.expire) {
+			delete(ptrCache.entries, ip)
+		}
+	}
+}
+
+// addPTRMetadata adds to the healthcheck result metadata the IP address
+// of the given network address and its hostname
+func addPTRMetadata(ctx context.Context, addr net.Addr) {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return
+	}
+	addPTRMetadataForIP(ctx, host)
+}
+
+// addPTRMetadataForIP adds to the healthcheck result metadata the given IP
+// address and its hostname, if already resolved. Nothing is done if the value
+// is not an IP.
+func addPTRMetadataForIP(ctx context.Context, ip string) {
+	if net.ParseIP(ip) == nil {
+		return
+	}
+	AddMetadata(ctx, "ip", ip)
+	hostname := lookupPTR(ip)
+	if hostname != "" {
+		AddMetadata(ctx, "ptr", hostname)
+	}
+}
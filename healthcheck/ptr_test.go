@@ -0,0 +1,41 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLookupPTR(t *testing.T) {
+	ptrCache.lock.Lock()
+	ptrCache.entries = map[string]ptrEntry{
+		"192.0.2.1": {hostname: "foo.example.com", expire: time.Now().Add(time.Minute)},
+	}
+	ptrCache.lock.Unlock()
+	ctx := WithMetadata(context.Background())
+	addPTRMetadataForIP(ctx, "192.0.2.1")
+	if GetMetadata(ctx)["ip"] != "192.0.2.1" || GetMetadata(ctx)["ptr"] != "foo.example.com" {
+		t.Fatalf("Invalid metadata %v", GetMetadata(ctx))
+	}
+	// the unknown addresses are resolved in the background
+	if lookupPTR("192.0.2.2") != "" {
+		t.Fatalf("The hostname should not be known yet")
+	}
+	ptrCache.lock.Lock()
+	if !ptrCache.entries["192.0.2.2"].resolving && ptrCache.entries["192.0.2.2"].expire.IsZero() {
+		t.Fatalf("The address should be resolved")
+	}
+	// the cache is bounded, the expired entries are pruned once full
+	expired := time.Now().Add(-time.Minute)
+	for i := len(ptrCache.entries); i < ptrCacheSize; i++ {
+		ptrCache.entries[fmt.Sprintf("10.0.%d.%d", i/256, i%256)] = ptrEntry{expire: expired}
+	}
+	ptrCache.lock.Unlock()
+	lookupPTR("192.0.2.3")
+	ptrCache.lock.Lock()
+	defer ptrCache.lock.Unlock()
+	if _, ok := ptrCache.entries["10.0.0.10"]; ok || len(ptrCache.entries) > 3 {
+		t.Fatalf("The expired entries should be pruned (%d entries)", len(ptrCache.entries))
+	}
+}
@@ -1,6 +1,7 @@
 package healthcheck
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -14,6 +15,40 @@ type Result struct {
 	Message              string            `json:"message"`
 	Duration             float64           `json:"duration"`
 	Source               string            `json:"source"`
+	Metadata             map[string]string `json:"metadata,omitempty"`
+	// the full precision execution time, HealthcheckTimestamp is kept in
+	// seconds for compatibility
+	Timestamp time.Time `json:"-"`
+	Group     string    `json:"group,omitempty"`
+	// the results of a source are numbered from 1, the epoch changes when
+	// Cabourotte restarts
+	Sequence uint64 `json:"sequence,omitempty"`
+	Epoch    string `json:"epoch,omitempty"`
+	// the runbook and the severity of the healthcheck
+	RunbookURL string   `json:"runbook-url,omitempty"`
+	Severity   Severity `json:"severity,omitempty"`
+	// the target of the healthcheck, only written in the audit logs
+	Target string `json:"-"`
+}
+
+// Time returns the result timestamp
+func (r Result) Time() time.Time {
+	if r.Timestamp.IsZero() {
+		return time.Unix(r.HealthcheckTimestamp, 0)
+	}
+	return r.Timestamp
+}
+
+// MarshalJSON marshal to json a result, using the configured timestamp format
+func (r Result) MarshalJSON() ([]byte, error) {
+	type rawResult Result
+	return json.Marshal(struct {
+		rawResult
+		HealthcheckTimestamp interface{} `json:"healthcheck-timestamp"`
+	}{
+		rawResult:            rawResult(r),
+		HealthcheckTimestamp: currentTimestampFormat().format(r.Time()),
+	})
 }
 
 // Equals implements Equals for Result
@@ -39,6 +74,18 @@ func (r Result) Equals(v Result) bool {
 	if r.Source != v.Source {
 		return false
 	}
+	if r.Group != v.Group {
+		return false
+	}
+	if r.RunbookURL != v.RunbookURL || r.Severity != v.Severity {
+		return false
+	}
+	if r.Sequence != v.Sequence || r.Epoch != v.Epoch {
+		return false
+	}
+	if !r.Timestamp.Equal(v.Timestamp) {
+		return false
+	}
 	if len(r.Labels) != len(v.Labels) {
 		return false
 	}
@@ -47,6 +94,14 @@ func (r Result) Equals(v Result) bool {
 			return false
 		}
 	}
+	if len(r.Metadata) != len(v.Metadata) {
+		return false
+	}
+	for k, value := range r.Metadata {
+		if value != v.Metadata[k] {
+			return false
+		}
+	}
 	return true
 }
 
@@ -62,8 +117,15 @@ func NewResult(healthcheck Healthcheck, duration float64, err error) *Result {
 		Summary:              healthcheck.Summary(),
 		Labels:               healthcheck.Base().Labels,
 		HealthcheckTimestamp: now.Unix(),
+		Timestamp:            now,
 		Duration:             duration,
 		Source:               source,
+		Group:                healthcheck.Base().Group,
+		RunbookURL:           healthcheck.Base().RunbookURL,
+		Severity:             healthcheck.Base().Severity,
+	}
+	if t, ok := healthcheck.(targetGetter); ok {
+		result.Target = t.target()
 	}
 	if err != nil {
 		result.Success = false
@@ -1,6 +1,7 @@
 package healthcheck
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"reflect"
@@ -25,7 +26,7 @@ type Healthcheck interface {
 	Initialize() error
 	GetConfig() interface{}
 	Summary() string
-	Execute() error
+	Execute(ctx context.Context) error
 	LogDebug(message string)
 	LogInfo(message string)
 	Base() Base
@@ -33,12 +34,33 @@ type Healthcheck interface {
 	LogError(err error, message string)
 }
 
+// dnsCacheUser is implemented by the healthchecks using the DNS cache
+type dnsCacheUser interface {
+	setDNSCache(cache *dnsCache)
+}
+
 // Component is the component which will manage healthchecks
 type Component struct {
 	Logger          *zap.Logger
 	Healthchecks    map[string]*Wrapper
 	resultHistogram *prom.HistogramVec
+	panicCounter    *prom.CounterVec
+	stuckGauge      *prom.GaugeVec
+	pausedGauge     *prom.GaugeVec
+	driftGauge      *prom.GaugeVec
+	missedCounter   *prom.CounterVec
+	timeoutGauge    *prom.GaugeVec
+	failingGauge    *prom.GaugeVec
+	dnsCache        *dnsCache
 	lock            sync.RWMutex
+	suspended       bool
+	paused          map[string]bool
+	scheduler       *scheduler
+	sequencer       *sequencer
+	// 1 if the correlation IDs are enabled
+	correlation int32
+	// true if the healthchecks are paused because the exporters are down
+	backpressure bool
 
 	ChanResult chan *Result
 }
@@ -46,26 +68,24 @@ type Component struct {
 // Start an healthcheck wrapper
 func (c *Component) startWrapper(w *Wrapper) {
 	w.healthcheck.LogInfo("Starting healthcheck")
-	w.Tick = time.NewTicker(time.Duration(w.healthcheck.Base().Interval))
+	wait := time.Duration(rand.Intn(4000)) * time.Millisecond
+	w.effective = initialInterval(w.healthcheck.Base())
+	if c.scheduler != nil {
+		c.scheduler.add(w, wait+w.effective)
+		return
+	}
+	w.Tick = time.NewTicker(w.effective)
 	w.t.Go(func() error {
-		wait := rand.Intn(4000)
-		time.Sleep(time.Duration(wait) * time.Millisecond)
+		time.Sleep(wait)
+		interval := w.effective
 		for {
 			select {
 			case <-w.Tick.C:
-				start := time.Now()
-				err := w.healthcheck.Execute()
-				duration := time.Since(start)
-				result := NewResult(
-					w.healthcheck,
-					duration.Seconds(),
-					err)
-				status := "failure"
-				if result.Success {
-					status = "success"
+				c.run(w.t.Context(context.Background()), w)
+				if w.effective != interval {
+					interval = w.effective
+					w.Tick.Reset(interval)
 				}
-				c.resultHistogram.With(prom.Labels{"name": w.healthcheck.Base().Name, "status": status}).Observe(duration.Seconds())
-				c.ChanResult <- result
 			case <-w.t.Dying():
 				return nil
 			}
@@ -73,6 +93,120 @@ func (c *Component) startWrapper(w *Wrapper) {
 	})
 }
 
+// run executes an healthcheck and sends its result
+func (c *Component) run(parent context.Context, w *Wrapper) {
+	ctx := WithMetadata(parent)
+	if timeout, ok := w.adaptiveTimeout(); ok {
+		ctx = withExecutionTimeout(ctx, timeout)
+		AddMetadata(ctx, "adaptive-timeout", timeout.String())
+		c.timeoutGauge.With(prom.Labels{"name": w.healthcheck.Base().Name}).Set(timeout.Seconds())
+	}
+	c.observeSchedule(w, time.Now())
+	result, status := c.evaluate(ctx, w)
+	if result == nil {
+		return
+	}
+	c.resultHistogram.With(prom.Labels{"name": w.healthcheck.Base().Name, "status": status}).Observe(result.Duration)
+	failing := 0.0
+	if !result.Success && status != "startup" {
+		failing = 1
+	}
+	c.failingGauge.With(prom.Labels{"name": w.healthcheck.Base().Name, "severity": string(result.Severity.OrDefault())}).Set(failing)
+	c.emitWithAliases(w.healthcheck, result)
+}
+
+// evaluate executes an healthcheck and builds its result, applying the
+// startup grace period, the thresholds and the message template. Returns the
+// result and its raw status, or a nil result if the execution produced no
+// result.
+func (c *Component) evaluate(ctx context.Context, w *Wrapper) (*Result, string) {
+	start := time.Now()
+	var err error
+	if len(w.sources) != 0 {
+		err = c.executeSources(ctx, w)
+	} else {
+		err = c.watch(ctx, w.healthcheck)
+	}
+	duration := time.Since(start)
+	if errors.Is(err, ErrResultSkipped) {
+		w.healthcheck.LogDebug(fmt.Sprintf("no result produced: %s", err.Error()))
+		return nil, ""
+	}
+	w.adapt(err == nil)
+	w.recordLatency(err == nil, duration)
+	result := NewResult(
+		w.healthcheck,
+		duration.Seconds(),
+		err)
+	result.Metadata = GetMetadata(ctx)
+	logDroppedMetadata(ctx, w.healthcheck)
+	startup := w.tagStartup(result)
+	// the histogram uses the raw outcome
+	status := resultStatus(result)
+	if startup {
+		w.healthcheck.LogDebug(fmt.Sprintf("failure during the startup grace period: %s", result.Message))
+	} else if w.threshold.apply(w.healthcheck.Base(), result) {
+		w.healthcheck.LogDebug(fmt.Sprintf("state change not reported yet (%s): %s", result.Metadata["threshold"], result.Message))
+	}
+	renderMessage(w.message, w.healthcheck, result)
+	return result, status
+}
+
+// ExecuteOneOff executes an one-off healthcheck like the periodic ones and
+// returns its result. ErrResultSkipped is returned if the execution produced
+// no result.
+func (c *Component) ExecuteOneOff(ctx context.Context, check Healthcheck) (*Result, error) {
+	wrapper, err := c.newWrapper(check)
+	if err != nil {
+		return nil, err
+	}
+	// the results of the sources are only returned through the metadata
+	wrapper.oneOff = true
+	result, _ := c.evaluate(WithMetadata(ctx), wrapper)
+	if result == nil {
+		return nil, ErrResultSkipped
+	}
+	return result, nil
+}
+
+// newWrapper initializes an healthcheck and its wrapper
+func (c *Component) newWrapper(check Healthcheck) (*Wrapper, error) {
+	if user, ok := check.(dnsCacheUser); ok {
+		user.setDNSCache(c.dnsCache)
+	}
+	wrapper := NewWrapper(check)
+	err := wrapper.healthcheck.Initialize()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to initialize healthcheck %s", wrapper.healthcheck.Base().Name)
+	}
+	wrapper.sources, err = initializeSources(wrapper.healthcheck)
+	if err != nil {
+		return nil, err
+	}
+	wrapper.message, err = parseMessageTemplate(wrapper.healthcheck.Base().MessageTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return wrapper, nil
+}
+
+// execute executes an healthcheck. A panic during the execution is converted
+// to an error, so one buggy healthcheck can't stop the others.
+func (c *Component) execute(ctx context.Context, healthcheck Healthcheck) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.panicCounter.With(prom.Labels{"name": healthcheck.Base().Name}).Inc()
+			c.Logger.Error("healthcheck panicked",
+				zap.String("name", healthcheck.Base().Name),
+				zap.String("panic", fmt.Sprintf("%v", r)),
+				zap.Stack("stack"))
+			AddMetadata(ctx, "reason", "panic")
+			err = fmt.Errorf("healthcheck panicked: %v", r)
+		}
+	}()
+	return healthcheck.Execute(ctx)
+}
+
 // New creates a new Healthcheck component
 func New(logger *zap.Logger, chanResult chan *Result, promComponent *prometheus.Prometheus) (*Component, error) {
 	buckets := []float64{
@@ -89,8 +223,98 @@ func New(logger *zap.Logger, chanResult chan *Result, promComponent *prometheus.
 	if err != nil {
 		return nil, errors.Wrapf(err, "fail to register the healthcheck result Prometheus histogram")
 	}
+	panicCounter := prom.NewCounterVec(prom.CounterOpts{
+		Name: "healthcheck_panics_total",
+		Help: "Count the number of healthchecks executions which panicked.",
+	},
+		[]string{"name"},
+	)
+	err = promComponent.Register(panicCounter)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the healthcheck panic Prometheus counter")
+	}
+	stuckGauge := prom.NewGaugeVec(prom.GaugeOpts{
+		Name: "healthcheck_stuck_executions",
+		Help: "Number of abandoned healthchecks executions which are still running.",
+	},
+		[]string{"name"},
+	)
+	err = promComponent.Register(stuckGauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the healthcheck stuck executions Prometheus gauge")
+	}
+	pausedGauge := prom.NewGaugeVec(prom.GaugeOpts{
+		Name: "healthcheck_paused",
+		Help: "1 if the healthcheck is paused.",
+	},
+		[]string{"name"},
+	)
+	err = promComponent.Register(pausedGauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the healthcheck paused Prometheus gauge")
+	}
+	driftGauge := prom.NewGaugeVec(prom.GaugeOpts{
+		Name: "healthcheck_scheduling_drift_seconds",
+		Help: "Difference between the last interval between two executions of the healthcheck and its configured interval.",
+	},
+		[]string{"name"},
+	)
+	err = promComponent.Register(driftGauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the healthcheck scheduling drift Prometheus gauge")
+	}
+	missedCounter := prom.NewCounterVec(prom.CounterOpts{
+		Name: "healthcheck_missed_ticks_total",
+		Help: "Count the number of healthchecks executions which did not start on time, because the previous one was still running or the workers were busy.",
+	},
+		[]string{"name"},
+	)
+	err = promComponent.Register(missedCounter)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the healthcheck missed ticks Prometheus counter")
+	}
+	timeoutGauge := prom.NewGaugeVec(prom.GaugeOpts{
+		Name: "healthcheck_adaptive_timeout_seconds",
+		Help: "The timeout computed from the recent latencies of the healthchecks having an adaptive timeout.",
+	},
+		[]string{"name"},
+	)
+	err = promComponent.Register(timeoutGauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the healthcheck adaptive timeout Prometheus gauge")
+	}
+	failingGauge := prom.NewGaugeVec(prom.GaugeOpts{
+		Name: "healthcheck_failing",
+		Help: "1 if the healthcheck is failing, the severity label routes the alerts.",
+	},
+		[]string{"name", "severity"},
+	)
+	err = promComponent.Register(failingGauge)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the healthcheck failing Prometheus gauge")
+	}
+	dnsCacheCounter := prom.NewCounterVec(prom.CounterOpts{
+		Name: "dns_cache_requests_total",
+		Help: "Count the number of DNS cache hits and misses.",
+	},
+		[]string{"result"},
+	)
+	err = promComponent.Register(dnsCacheCounter)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to register the DNS cache Prometheus counter")
+	}
 	component := Component{
 		resultHistogram: histo,
+		panicCounter:    panicCounter,
+		stuckGauge:      stuckGauge,
+		pausedGauge:     pausedGauge,
+		driftGauge:      driftGauge,
+		missedCounter:   missedCounter,
+		timeoutGauge:    timeoutGauge,
+		failingGauge:    failingGauge,
+		paused:          make(map[string]bool),
+		dnsCache:        newDNSCache(dnsCacheCounter),
+		sequencer:       newSequencer(),
 		Logger:          logger,
 		Healthchecks:    make(map[string]*Wrapper),
 		ChanResult:      chanResult,
@@ -120,6 +344,13 @@ func (c *Component) Stop() error {
 			return errors.Wrap(err, "Fail to stop the healthcheck component")
 		}
 	}
+	if c.scheduler != nil {
+		err := c.scheduler.stop()
+		if err != nil {
+			return errors.Wrap(err, "Fail to stop the healthchecks scheduler")
+		}
+		c.scheduler = nil
+	}
 	c.Logger.Info("All healthchecks stopped")
 	return nil
 }
@@ -131,6 +362,16 @@ func (c *Component) removeCheck(identifier string) error {
 		existingWrapper.healthcheck.LogInfo("Stopping healthcheck")
 		c.resultHistogram.Delete(prom.Labels{"name": identifier, "status": "failure"})
 		c.resultHistogram.Delete(prom.Labels{"name": identifier, "status": "success"})
+		c.resultHistogram.Delete(prom.Labels{"name": identifier, "status": "startup"})
+		c.panicCounter.Delete(prom.Labels{"name": identifier})
+		c.driftGauge.Delete(prom.Labels{"name": identifier})
+		c.missedCounter.Delete(prom.Labels{"name": identifier})
+		c.timeoutGauge.Delete(prom.Labels{"name": identifier})
+		for _, severity := range severities {
+			c.failingGauge.Delete(prom.Labels{"name": identifier, "severity": string(severity)})
+		}
+		// the stuck executions gauge is kept, abandoned executions may
+		// still be running
 		err := existingWrapper.Stop()
 		if err != nil {
 			return errors.Wrapf(err, "Fail to stop healthcheck %s", existingWrapper.healthcheck.Base().Name)
@@ -151,12 +392,12 @@ func (c *Component) AddCheck(check Healthcheck) error {
 			return nil
 		}
 	}
-	wrapper := NewWrapper(check)
-	wrapper.healthcheck.LogInfo("Adding healthcheck")
-	err := wrapper.healthcheck.Initialize()
+	check.LogInfo("Adding healthcheck")
+	wrapper, err := c.newWrapper(check)
 	if err != nil {
-		return errors.Wrapf(err, "Fail to initialize healthcheck %s", wrapper.healthcheck.Base().Name)
+		return err
 	}
+	wrapper.started = time.Now()
 
 	// verifies if the healthcheck already exists, and removes it if needed.
 	// Updating an healthcheck is removing the old one and adding the new one.
@@ -164,16 +405,129 @@ func (c *Component) AddCheck(check Healthcheck) error {
 	if err != nil {
 		return errors.Wrapf(err, "Fail to stop existing healthcheck %s", wrapper.healthcheck.Base().Name)
 	}
-	c.startWrapper(wrapper)
+	if !c.halted() && !c.paused[wrapper.healthcheck.Base().Name] {
+		c.startWrapper(wrapper)
+	}
 	c.Healthchecks[wrapper.healthcheck.Base().Name] = wrapper
 	return nil
 }
 
+// Suspend stops the execution of all healthchecks, cancelling in-flight
+// executions. The healthchecks are kept in the component and new ones can
+// still be added, but nothing is executed until Resume is called.
+func (c *Component) Suspend() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.suspended {
+		return nil
+	}
+	c.Logger.Info("Suspending healthchecks execution")
+	if !c.halted() {
+		err := c.stopAll()
+		if err != nil {
+			return err
+		}
+	}
+	c.suspended = true
+	return nil
+}
+
+// Resume starts again all healthchecks stopped by Suspend
+func (c *Component) Resume() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.suspended {
+		return
+	}
+	c.Logger.Info("Resuming healthchecks execution")
+	c.suspended = false
+	if !c.halted() {
+		c.startAll()
+	}
+}
+
+// halted returns true if the healthchecks should not be executed, because
+// they are suspended or paused by the exporters backpressure.
+// The function is *not* thread-safe.
+func (c *Component) halted() bool {
+	return c.suspended || c.backpressure
+}
+
+// stopAll stops all healthchecks, the wrappers are recreated.
+// The function is *not* thread-safe.
+func (c *Component) stopAll() error {
+	for name, wrapper := range c.Healthchecks {
+		err := wrapper.Stop()
+		if err != nil {
+			return errors.Wrapf(err, "Fail to stop healthcheck %s", name)
+		}
+		c.Healthchecks[name] = wrapper.recreate()
+	}
+	return nil
+}
+
+// startAll starts all healthchecks which are not paused.
+// The function is *not* thread-safe.
+func (c *Component) startAll() {
+	for name, wrapper := range c.Healthchecks {
+		if c.paused[name] {
+			continue
+		}
+		c.startWrapper(wrapper)
+	}
+}
+
+// SetScheduler configures the healthchecks execution. The running healthchecks
+// are restarted if the configuration changes.
+func (c *Component) SetScheduler(config SchedulerConfiguration) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if (c.scheduler == nil && config.Workers == 0) ||
+		(c.scheduler != nil && c.scheduler.workers == config.Workers) {
+		return nil
+	}
+	err := c.stopAll()
+	if err != nil {
+		return err
+	}
+	if c.scheduler != nil {
+		err := c.scheduler.stop()
+		if err != nil {
+			return errors.Wrap(err, "Fail to stop the healthchecks scheduler")
+		}
+		c.scheduler = nil
+	}
+	if config.Workers != 0 {
+		c.Logger.Info(fmt.Sprintf("Executing the healthchecks on %d workers", config.Workers))
+		c.scheduler = newScheduler(config.Workers, c.run)
+		c.scheduler.start()
+	}
+	if c.halted() {
+		return nil
+	}
+	c.startAll()
+	return nil
+}
+
+// SetDNSCache configures the DNS answers cache shared by the DNS
+// healthchecks. The cache is flushed.
+func (c *Component) SetDNSCache(config DNSCacheConfiguration) {
+	c.dnsCache.setTTL(time.Duration(config.TTL))
+}
+
+// IsSuspended returns true if the healthchecks execution is suspended
+func (c *Component) IsSuspended() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.suspended
+}
+
 // RemoveCheck Removes an healthcheck
 func (c *Component) RemoveCheck(name string) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	c.Logger.Info(fmt.Sprintf("Removing healthcheck %s", name))
+	c.unpause(name)
 	return c.removeCheck(name)
 }
 
@@ -244,7 +598,9 @@ func (c *Component) ReloadForSource(
 	dns []DNSHealthcheckConfiguration,
 	tcp []TCPHealthcheckConfiguration,
 	http []HTTPHealthcheckConfiguration,
-	tls []TLSHealthcheckConfiguration) error {
+	tls []TLSHealthcheckConfiguration,
+	websocket []WebSocketHealthcheckConfiguration,
+	sshChecks []SSHHealthcheckConfiguration) error {
 
 	oldChecks := c.SourceChecksNames(source)
 	newChecks := make(map[string]bool)
@@ -323,5 +679,35 @@ func (c *Component) ReloadForSource(
 			return errors.Wrapf(err, "Fail to add healthcheck %s", newCheck.Base().Name)
 		}
 	}
+	for i := range websocket {
+		config := &websocket[i]
+		MergeLabels(&config.Base, commonLabels)
+		config.Base.Source = source
+		newChecks[config.Base.Name] = true
+		err := config.Validate()
+		if err != nil {
+			return err
+		}
+		newCheck := NewWebSocketHealthcheck(c.Logger, config)
+		err = c.AddCheck(newCheck)
+		if err != nil {
+			return errors.Wrapf(err, "Fail to add healthcheck %s", newCheck.Base().Name)
+		}
+	}
+	for i := range sshChecks {
+		config := &sshChecks[i]
+		MergeLabels(&config.Base, commonLabels)
+		config.Base.Source = source
+		newChecks[config.Base.Name] = true
+		err := config.Validate()
+		if err != nil {
+			return err
+		}
+		newCheck := NewSSHHealthcheck(c.Logger, config)
+		err = c.AddCheck(newCheck)
+		if err != nil {
+			return errors.Wrapf(err, "Fail to add healthcheck %s", newCheck.Base().Name)
+		}
+	}
 	return c.RemoveNonConfiguredHealthchecks(oldChecks, newChecks)
 }
@@ -1,6 +1,8 @@
 package healthcheck
 
 import (
+	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -194,3 +196,218 @@ func TestMergeLabels(t *testing.T) {
 	}
 
 }
+
+func TestSuspendResume(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(logger, make(chan *Result, 10), prom)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Suspend()
+	if err != nil {
+		t.Fatalf("Fail to suspend the component\n%v", err)
+	}
+	healthcheck := NewTCPHealthcheck(
+		logger,
+		&TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Second * 5),
+			},
+			Target:  "127.0.0.1",
+			Port:    9000,
+			Timeout: Duration(time.Second * 3),
+		},
+	)
+	err = component.AddCheck(healthcheck)
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	if component.Healthchecks["foo"].Tick != nil {
+		t.Fatalf("The healthcheck should not be started")
+	}
+	component.Resume()
+	if component.IsSuspended() {
+		t.Fatalf("The component should not be suspended")
+	}
+	if component.Healthchecks["foo"].Tick == nil {
+		t.Fatalf("The healthcheck should be started")
+	}
+	err = component.Suspend()
+	if err != nil {
+		t.Fatalf("Fail to suspend the component\n%v", err)
+	}
+	if component.Healthchecks["foo"].Tick != nil {
+		t.Fatalf("The healthcheck should be stopped")
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+type panicHealthcheck struct {
+	config Base
+}
+
+func (h *panicHealthcheck) Initialize() error {
+	return nil
+}
+
+func (h *panicHealthcheck) GetConfig() interface{} {
+	return h.config
+}
+
+func (h *panicHealthcheck) Summary() string {
+	return "panic"
+}
+
+func (h *panicHealthcheck) Execute(ctx context.Context) error {
+	panic("buggy healthcheck")
+}
+
+func (h *panicHealthcheck) LogDebug(message string) {}
+
+func (h *panicHealthcheck) LogInfo(message string) {}
+
+func (h *panicHealthcheck) LogError(err error, message string) {}
+
+func (h *panicHealthcheck) Base() Base {
+	return h.config
+}
+
+func (h *panicHealthcheck) SetSource(source string) {
+	h.config.Source = source
+}
+
+func TestExecutePanic(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(zap.NewExample(), make(chan *Result, 10), prom)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	ctx := WithMetadata(context.Background())
+	err = component.execute(ctx, &panicHealthcheck{config: Base{Name: "foo"}})
+	if err == nil || !strings.Contains(err.Error(), "buggy healthcheck") {
+		t.Fatalf("The panic should be converted to an error: %v", err)
+	}
+	if GetMetadata(ctx)["reason"] != "panic" {
+		t.Fatalf("Invalid metadata %v", GetMetadata(ctx))
+	}
+	families, err := prom.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Fail to gather the metrics\n%v", err)
+	}
+	found := false
+	for _, family := range families {
+		if family.GetName() == "healthcheck_panics_total" {
+			found = family.GetMetric()[0].GetCounter().GetValue() == 1
+		}
+	}
+	if !found {
+		t.Fatalf("The panic counter was not incremented")
+	}
+}
+
+type stuckHealthcheck struct {
+	panicHealthcheck
+	release chan struct{}
+}
+
+func (h *stuckHealthcheck) Execute(ctx context.Context) error {
+	// ignores the context on purpose
+	<-h.release
+	return nil
+}
+
+// stuckGaugeValue returns the value of the stuck executions gauge
+func stuckGaugeValue(t *testing.T, prom *prometheus.Prometheus) float64 {
+	families, err := prom.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Fail to gather the metrics\n%v", err)
+	}
+	for _, family := range families {
+		if family.GetName() == "healthcheck_stuck_executions" {
+			return family.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+	return 0
+}
+
+func TestWatchStuckExecution(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(zap.NewExample(), make(chan *Result, 10), prom)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	check := &stuckHealthcheck{
+		panicHealthcheck: panicHealthcheck{config: Base{
+			Name:             "foo",
+			MaxExecutionTime: Duration(100 * time.Millisecond),
+		}},
+		release: make(chan struct{}),
+	}
+	ctx := WithMetadata(context.Background())
+	start := time.Now()
+	err = component.watch(ctx, check)
+	if err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Fatalf("The execution should be abandoned: %v", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Fatalf("The execution was not abandoned in time")
+	}
+	if GetMetadata(ctx)["reason"] != "stuck" {
+		t.Fatalf("Invalid metadata %v", GetMetadata(ctx))
+	}
+	if stuckGaugeValue(t, prom) != 1 {
+		t.Fatalf("The stuck executions gauge should be 1")
+	}
+	close(check.release)
+	for i := 0; i < 50; i++ {
+		if stuckGaugeValue(t, prom) == 0 {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if stuckGaugeValue(t, prom) != 0 {
+		t.Fatalf("The stuck executions gauge should be decremented")
+	}
+	// executions returning in time are not affected
+	err = component.watch(ctx, &panicHealthcheck{config: Base{Name: "bar", Interval: Duration(time.Second)}})
+	if err == nil || !strings.Contains(err.Error(), "buggy healthcheck") {
+		t.Fatalf("Invalid error: %v", err)
+	}
+}
+
+func TestExecuteOneOff(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	chanResult := make(chan *Result, 10)
+	component, err := New(zap.NewExample(), chanResult, prom)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	// the panic is recovered, and the message template rendered
+	result, err := component.ExecuteOneOff(context.Background(), &panicHealthcheck{config: Base{Name: "foo", MessageTemplate: "{{ .Name }} panicked"}})
+	if err != nil {
+		t.Fatalf("Fail to execute the healthcheck\n%v", err)
+	}
+	if result.Success || result.Message != "foo panicked" || result.Metadata["reason"] != "panic" {
+		t.Fatalf("Invalid result %v", result)
+	}
+	if len(chanResult) != 0 {
+		t.Fatalf("The one-off results should not be emitted")
+	}
+}
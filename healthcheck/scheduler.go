@@ -0,0 +1,214 @@
+package healthcheck
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"gopkg.in/tomb.v2"
+)
+
+// SchedulerConfiguration the configuration of the healthchecks execution
+type SchedulerConfiguration struct {
+	// the number of workers executing the healthchecks. A single scheduler
+	// dispatches the executions to the workers instead of using a goroutine
+	// and a ticker per healthcheck. Disabled if 0.
+	Workers uint
+}
+
+// scheduleQueue a min-heap of wrappers, ordered by next execution
+type scheduleQueue []*Wrapper
+
+func (q scheduleQueue) Len() int {
+	return len(q)
+}
+
+func (q scheduleQueue) Less(i, j int) bool {
+	return q[i].next.Before(q[j].next)
+}
+
+func (q scheduleQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *scheduleQueue) Push(x interface{}) {
+	w := x.(*Wrapper)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *scheduleQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	w.index = -1
+	*q = old[:n-1]
+	return w
+}
+
+// scheduler executes the healthcheck on a bounded pool of workers, at the
+// interval of each healthcheck
+type scheduler struct {
+	lock    sync.Mutex
+	queue   scheduleQueue
+	wakeup  chan struct{}
+	jobs    chan *Wrapper
+	workers uint
+	run     func(ctx context.Context, w *Wrapper)
+	t       tomb.Tomb
+}
+
+// newScheduler creates a new scheduler
+func newScheduler(workers uint, run func(ctx context.Context, w *Wrapper)) *scheduler {
+	return &scheduler{
+		wakeup:  make(chan struct{}, 1),
+		jobs:    make(chan *Wrapper),
+		workers: workers,
+		run:     run,
+	}
+}
+
+// start starts the scheduler and its workers
+func (s *scheduler) start() {
+	s.t.Go(s.loop)
+	for i := uint(0); i < s.workers; i++ {
+		s.t.Go(s.work)
+	}
+}
+
+// stop stops the scheduler, the wrappers should be removed first
+func (s *scheduler) stop() error {
+	s.t.Kill(nil)
+	return s.t.Wait()
+}
+
+// add schedules a wrapper, the first execution happens after a delay
+func (s *scheduler) add(w *Wrapper, delay time.Duration) {
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	if w.effective == 0 {
+		w.effective = initialInterval(w.healthcheck.Base())
+	}
+	w.interval = w.effective
+	w.next = time.Now().Add(delay)
+	w.scheduler = s
+	s.lock.Lock()
+	heap.Push(&s.queue, w)
+	first := w.index == 0
+	s.lock.Unlock()
+	if first {
+		select {
+		case s.wakeup <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// remove unschedules a wrapper, and waits for its running execution which is
+// cancelled
+func (s *scheduler) remove(w *Wrapper) {
+	s.lock.Lock()
+	if w.index >= 0 {
+		heap.Remove(&s.queue, w.index)
+	}
+	s.lock.Unlock()
+	w.cancel()
+	w.executions.Wait()
+}
+
+// due returns the next wrapper to execute, or the duration to wait for the
+// next execution. Like with a ticker, the executions are skipped if the
+// previous one is still running.
+func (s *scheduler) due() (*Wrapper, time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for len(s.queue) != 0 {
+		w := s.queue[0]
+		now := time.Now()
+		if w.next.After(now) {
+			return nil, w.next.Sub(now)
+		}
+		missed := now.Sub(w.next)/w.interval + 1
+		w.next = w.next.Add(missed * w.interval)
+		heap.Fix(&s.queue, 0)
+		if w.running {
+			continue
+		}
+		w.running = true
+		w.executions.Add(1)
+		return w, 0
+	}
+	return nil, time.Hour
+}
+
+// done marks the execution of a wrapper as finished. If the interval of the
+// wrapper changed, the next execution is rescheduled from now.
+func (s *scheduler) done(w *Wrapper) {
+	s.lock.Lock()
+	w.running = false
+	changed := w.effective != w.interval
+	if changed {
+		w.interval = w.effective
+		w.next = time.Now().Add(w.interval)
+		if w.index >= 0 {
+			heap.Fix(&s.queue, w.index)
+		}
+	}
+	s.lock.Unlock()
+	w.executions.Done()
+	if changed {
+		select {
+		case s.wakeup <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// loop dispatches the executions to the workers
+func (s *scheduler) loop() error {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		w, wait := s.due()
+		if w != nil {
+			select {
+			case s.jobs <- w:
+			case <-s.t.Dying():
+				s.done(w)
+				return nil
+			}
+			continue
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+		select {
+		case <-timer.C:
+		case <-s.wakeup:
+		case <-s.t.Dying():
+			return nil
+		}
+	}
+}
+
+// work executes the healthchecks dispatched by the scheduler
+func (s *scheduler) work() error {
+	for {
+		select {
+		case w := <-s.jobs:
+			if w.ctx.Err() == nil {
+				s.run(w.ctx, w)
+			}
+			s.done(w)
+		case <-s.t.Dying():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/prometheus"
+)
+
+type countingHealthcheck struct {
+	panicHealthcheck
+}
+
+func (h *countingHealthcheck) Execute(ctx context.Context) error {
+	return nil
+}
+
+func TestSchedulerInterval(t *testing.T) {
+	var lock sync.Mutex
+	count := 0
+	running := 0
+	maxRunning := 0
+	s := newScheduler(4, func(ctx context.Context, w *Wrapper) {
+		lock.Lock()
+		count++
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		lock.Unlock()
+		// slower than the interval, the executions are skipped
+		select {
+		case <-time.After(120 * time.Millisecond):
+		case <-ctx.Done():
+		}
+		lock.Lock()
+		running--
+		lock.Unlock()
+	})
+	s.start()
+	w := NewWrapper(&countingHealthcheck{panicHealthcheck{config: Base{
+		Name:     "foo",
+		Interval: Duration(50 * time.Millisecond),
+	}}})
+	s.add(w, 0)
+	time.Sleep(400 * time.Millisecond)
+	s.remove(w)
+	lock.Lock()
+	executed := count
+	lock.Unlock()
+	if executed < 2 || executed > 4 {
+		t.Fatalf("Invalid number of executions %d", executed)
+	}
+	if maxRunning != 1 {
+		t.Fatalf("The executions of an healthcheck should not overlap")
+	}
+	time.Sleep(100 * time.Millisecond)
+	lock.Lock()
+	defer lock.Unlock()
+	if count != executed || running != 0 {
+		t.Fatalf("The healthcheck should not be executed after its removal")
+	}
+	err := s.stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the scheduler\n%v", err)
+	}
+}
+
+func TestSetScheduler(t *testing.T) {
+	logger := zap.NewExample()
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	component, err := New(logger, make(chan *Result, 10), prom)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.AddCheck(&countingHealthcheck{panicHealthcheck{config: Base{
+		Name:     "foo",
+		Interval: Duration(time.Second * 5),
+	}}})
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	err = component.SetScheduler(SchedulerConfiguration{Workers: 2})
+	if err != nil {
+		t.Fatalf("Fail to set the scheduler\n%v", err)
+	}
+	wrapper := component.Healthchecks["foo"]
+	if wrapper.Tick != nil || wrapper.scheduler == nil || wrapper.index == -1 {
+		t.Fatalf("The healthcheck should be executed by the scheduler")
+	}
+	err = component.SetScheduler(SchedulerConfiguration{})
+	if err != nil {
+		t.Fatalf("Fail to set the scheduler\n%v", err)
+	}
+	wrapper = component.Healthchecks["foo"]
+	if wrapper.Tick == nil || wrapper.scheduler != nil {
+		t.Fatalf("The healthcheck should use its own ticker")
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+// benchmarkChecks starts and stops a large number of healthchecks, the number
+// of goroutines used by the healthchecks is reported
+func benchmarkChecks(b *testing.B, config SchedulerConfiguration) {
+	for i := 0; i < b.N; i++ {
+		prom, err := prometheus.New()
+		if err != nil {
+			b.Fatalf("Error creating prometheus component :\n%v", err)
+		}
+		component, err := New(zap.NewNop(), make(chan *Result, 10), prom)
+		if err != nil {
+			b.Fatalf("Fail to create the component\n%v", err)
+		}
+		err = component.SetScheduler(config)
+		if err != nil {
+			b.Fatalf("Fail to set the scheduler\n%v", err)
+		}
+		goroutines := runtime.NumGoroutine()
+		for j := 0; j < 10000; j++ {
+			err = component.AddCheck(&countingHealthcheck{panicHealthcheck{config: Base{
+				Name:     fmt.Sprintf("check-%d", j),
+				Interval: Duration(time.Minute),
+			}}})
+			if err != nil {
+				b.Fatalf("Fail to add the healthcheck\n%v", err)
+			}
+		}
+		b.ReportMetric(float64(runtime.NumGoroutine()-goroutines), "goroutines")
+		err = component.Stop()
+		if err != nil {
+			b.Fatalf("Fail to stop the component\n%v", err)
+		}
+	}
+}
+
+func BenchmarkTickerChecks(b *testing.B) {
+	benchmarkChecks(b, SchedulerConfiguration{})
+}
+
+func BenchmarkSchedulerChecks(b *testing.B) {
+	benchmarkChecks(b, SchedulerConfiguration{Workers: 16})
+}
@@ -0,0 +1,149 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// SecurityHeadersFail missing or mismatched headers are healthcheck
+	// failures (the default)
+	SecurityHeadersFail = "fail"
+	// SecurityHeadersWarn missing or mismatched headers produce a successful
+	// result with a warning
+	SecurityHeadersWarn = "warn"
+)
+
+// RequiredHeader a response header required by the security headers
+// assertion. Only its presence is verified if no value or regex is set.
+type RequiredHeader struct {
+	Name string
+	// the expected value, compared case insensitively
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+	// a regex the value should match
+	Match *Regexp `json:"match,omitempty" yaml:"match,omitempty"`
+}
+
+// SecurityHeadersAssertion verifies the response contains the required
+// security headers, for example HSTS or a content security policy
+type SecurityHeadersAssertion struct {
+	// the required headers, defaultSecurityHeaders if not set
+	Headers []RequiredHeader `json:"headers,omitempty" yaml:"headers,omitempty"`
+	// fail (the default) or warn
+	Mode string `json:"mode,omitempty" yaml:"mode,omitempty"`
+}
+
+// defaultSecurityHeaders the headers required when no header is configured
+var defaultSecurityHeaders = []RequiredHeader{
+	{Name: "Strict-Transport-Security"},
+	{Name: "X-Content-Type-Options", Value: "nosniff"},
+	{Name: "Content-Security-Policy"},
+}
+
+// DeepCopyInto copies the receiver into out. in must be non-nil.
+func (in *SecurityHeadersAssertion) DeepCopyInto(out *SecurityHeadersAssertion) {
+	*out = *in
+	if in.Headers != nil {
+		out.Headers = make([]RequiredHeader, len(in.Headers))
+		for i, header := range in.Headers {
+			out.Headers[i] = header
+			if header.Match != nil {
+				out.Headers[i].Match = header.Match.DeepCopy()
+			}
+		}
+	}
+}
+
+// Validate verifies the security headers assertion options
+func (a *SecurityHeadersAssertion) Validate() error {
+	if a.Mode != "" && a.Mode != SecurityHeadersFail && a.Mode != SecurityHeadersWarn {
+		return fmt.Errorf("Invalid security headers mode %s, fail or warn are expected", a.Mode)
+	}
+	names := make(map[string]bool)
+	for _, header := range a.Headers {
+		name := http.CanonicalHeaderKey(header.Name)
+		if name == "" {
+			return errors.New("The security headers names are required")
+		}
+		if names[name] {
+			return fmt.Errorf("The security header %s is duplicated", header.Name)
+		}
+		names[name] = true
+		if sensitiveHeaders[name] {
+			return fmt.Errorf("The header %s can not be verified by the security headers assertion", header.Name)
+		}
+		if header.Value != "" && header.Match != nil {
+			return fmt.Errorf("The security header %s value and match options are exclusive", header.Name)
+		}
+	}
+	return nil
+}
+
+// verify returns an empty string if the header value is valid, or the
+// problem found
+func (h *RequiredHeader) verify(values []string) string {
+	if len(values) == 0 {
+		return "missing"
+	}
+	value := strings.Join(values, ", ")
+	if h.Value != "" && !strings.EqualFold(strings.TrimSpace(value), h.Value) {
+		return "mismatched"
+	}
+	if h.Match != nil {
+		r := regexp.Regexp(*h.Match)
+		if !r.MatchString(value) {
+			return "mismatched"
+		}
+	}
+	return ""
+}
+
+// check verifies the response headers. The checked values are added to the
+// metadata, and the failure lists the missing and mismatched headers.
+func (a *SecurityHeadersAssertion) check(ctx context.Context, headers http.Header) error {
+	required := a.Headers
+	if len(required) == 0 {
+		required = defaultSecurityHeaders
+	}
+	var missing, mismatched []string
+	for _, header := range required {
+		name := http.CanonicalHeaderKey(header.Name)
+		values := headers.Values(name)
+		value := strings.Join(values, ", ")
+		if len(value) > maxHeaderValueSize {
+			value = value[:maxHeaderValueSize]
+		}
+		switch header.verify(values) {
+		case "missing":
+			missing = append(missing, name)
+			continue
+		case "mismatched":
+			mismatched = append(mismatched, name)
+		}
+		AddMetadata(ctx, "security-header-"+strings.ToLower(name), value)
+	}
+	if len(missing) == 0 && len(mismatched) == 0 {
+		return nil
+	}
+	var problems []string
+	if len(missing) != 0 {
+		AddMetadata(ctx, "missing-headers", strings.Join(missing, ","))
+		problems = append(problems, fmt.Sprintf("missing headers %s", strings.Join(missing, ", ")))
+	}
+	if len(mismatched) != 0 {
+		AddMetadata(ctx, "mismatched-headers", strings.Join(mismatched, ","))
+		problems = append(problems, fmt.Sprintf("mismatched headers %s", strings.Join(mismatched, ", ")))
+	}
+	message := "Invalid security headers: " + strings.Join(problems, ", ")
+	if a.Mode == SecurityHeadersWarn {
+		AddMetadata(ctx, "warning", message)
+		return nil
+	}
+	AddMetadata(ctx, "reason", "security-headers")
+	return errors.New(message)
+}
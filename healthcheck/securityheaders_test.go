@@ -0,0 +1,93 @@
+package healthcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestHTTPExecuteSecurityHeaders(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("Error getting HTTP server port :\n%v", err)
+	}
+	hsts := Regexp(*regexp.MustCompile(`max-age=\d{9,}`))
+	cases := []struct {
+		assertion  SecurityHeadersAssertion
+		success    bool
+		missing    string
+		mismatched string
+		warning    bool
+	}{
+		{assertion: SecurityHeadersAssertion{Headers: []RequiredHeader{{Name: "strict-transport-security"}, {Name: "X-Content-Type-Options", Value: "NoSniff"}}}, success: true},
+		{assertion: SecurityHeadersAssertion{}, success: false, missing: "Content-Security-Policy"},
+		{assertion: SecurityHeadersAssertion{Headers: []RequiredHeader{{Name: "Strict-Transport-Security", Match: &hsts}, {Name: "X-Frame-Options"}}}, success: false, missing: "X-Frame-Options", mismatched: "Strict-Transport-Security"},
+		{assertion: SecurityHeadersAssertion{Mode: SecurityHeadersWarn}, success: true, missing: "Content-Security-Policy", warning: true},
+	}
+	for i, c := range cases {
+		assertion := c.assertion
+		h := NewHTTPHealthcheck(zap.NewExample(), &HTTPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Second * 10),
+			},
+			ValidStatus:     []uint{200},
+			Target:          "127.0.0.1",
+			Port:            uint(port),
+			Protocol:        HTTP,
+			Timeout:         Duration(time.Second * 3),
+			SecurityHeaders: &assertion,
+		})
+		err := h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error\n%v", err)
+		}
+		ctx := WithMetadata(context.Background())
+		err = h.Execute(ctx)
+		metadata := GetMetadata(ctx)
+		if c.success && err != nil {
+			t.Fatalf("healthcheck %d error\n%v", i, err)
+		}
+		if !c.success && (err == nil || metadata["reason"] != "security-headers") {
+			t.Fatalf("healthcheck %d should fail: %v", i, metadata)
+		}
+		if metadata["missing-headers"] != c.missing || metadata["mismatched-headers"] != c.mismatched {
+			t.Fatalf("Invalid headers metadata for healthcheck %d: %v", i, metadata)
+		}
+		if c.warning != (metadata["warning"] != "") {
+			t.Fatalf("Invalid warning for healthcheck %d: %v", i, metadata)
+		}
+		if metadata["security-header-strict-transport-security"] != "max-age=63072000; includeSubDomains" {
+			t.Fatalf("Invalid header value metadata for healthcheck %d: %v", i, metadata)
+		}
+	}
+}
+
+func TestSecurityHeadersValidate(t *testing.T) {
+	hsts := Regexp(*regexp.MustCompile("max-age"))
+	invalid := []SecurityHeadersAssertion{
+		{Mode: "skip"},
+		{Headers: []RequiredHeader{{Name: ""}}},
+		{Headers: []RequiredHeader{{Name: "X-Frame-Options"}, {Name: "x-frame-options"}}},
+		{Headers: []RequiredHeader{{Name: "Set-Cookie"}}},
+		{Headers: []RequiredHeader{{Name: "Strict-Transport-Security", Value: "max-age=1", Match: &hsts}}},
+	}
+	for i, c := range invalid {
+		if err := c.Validate(); err == nil {
+			t.Fatalf("Was expecting an error for the assertion %d", i)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package healthcheck
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// processEpoch identifies the process emitting the results, the sequences
+// restart from 1 with a new epoch
+var processEpoch = strconv.FormatInt(time.Now().UnixNano(), 36)
+
+// sequencer numbers the results emitted for each source
+type sequencer struct {
+	lock      sync.Mutex
+	sequences map[string]uint64
+}
+
+// newSequencer creates a new sequencer
+func newSequencer() *sequencer {
+	return &sequencer{
+		sequences: make(map[string]uint64),
+	}
+}
+
+// number sets the epoch and the next sequence number of the result source
+func (s *sequencer) number(result *Result) {
+	s.lock.Lock()
+	s.sequences[result.Source]++
+	result.Sequence = s.sequences[result.Source]
+	s.lock.Unlock()
+	result.Epoch = processEpoch
+}
+
+// emit numbers a result and sends it to the exporters
+func (c *Component) emit(result *Result) {
+	c.sequencer.number(result)
+	c.correlate(result)
+	c.ChanResult <- result
+}
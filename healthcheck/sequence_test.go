@@ -0,0 +1,38 @@
+package healthcheck
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSequencer(t *testing.T) {
+	s := newSequencer()
+	var wg sync.WaitGroup
+	results := make([]*Result, 100)
+	for i := range results {
+		source := "configuration"
+		if i%2 == 0 {
+			source = "api"
+		}
+		results[i] = &Result{Source: source}
+		wg.Add(1)
+		go func(result *Result) {
+			defer wg.Done()
+			s.number(result)
+		}(results[i])
+	}
+	wg.Wait()
+	sequences := map[string]map[uint64]bool{
+		"api":           {},
+		"configuration": {},
+	}
+	for _, result := range results {
+		if result.Epoch != processEpoch || result.Sequence == 0 || result.Sequence > 50 {
+			t.Fatalf("Invalid result %v", result)
+		}
+		if sequences[result.Source][result.Sequence] {
+			t.Fatalf("Duplicated sequence %d for the source %s", result.Sequence, result.Source)
+		}
+		sequences[result.Source][result.Sequence] = true
+	}
+}
@@ -0,0 +1,70 @@
+package healthcheck
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Severity the severity of the failures of an healthcheck, used to route the
+// alerts
+type Severity string
+
+const (
+	// SeverityCritical the failures are critical (the default)
+	SeverityCritical Severity = "critical"
+	// SeverityWarning the failures are warnings
+	SeverityWarning Severity = "warning"
+	// SeverityInfo the failures are informative
+	SeverityInfo Severity = "info"
+)
+
+// severities the valid severities
+var severities = []Severity{SeverityCritical, SeverityWarning, SeverityInfo}
+
+// Validate validates the severity
+func (s Severity) Validate() error {
+	if s == "" {
+		return nil
+	}
+	for _, severity := range severities {
+		if s == severity {
+			return nil
+		}
+	}
+	return fmt.Errorf("Invalid healthcheck severity %s, should be critical, warning or info", s)
+}
+
+// OrDefault returns the severity, critical if not set
+func (s Severity) OrDefault() Severity {
+	if s == "" {
+		return SeverityCritical
+	}
+	return s
+}
+
+// validateAlerting validates the runbook URL and the severity of an
+// healthcheck
+func validateAlerting(base *Base) error {
+	if err := base.Severity.Validate(); err != nil {
+		return err
+	}
+	if base.RunbookURL != "" {
+		u, err := url.Parse(base.RunbookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("Invalid healthcheck runbook-url %s, should be an http or https URL", base.RunbookURL)
+		}
+	}
+	return nil
+}
+
+// State returns the tri-state of the result: ok if successful, critical for
+// the failures of the critical healthchecks, and warn for the others
+func (r *Result) State() string {
+	if r.Success {
+		return "ok"
+	}
+	if r.Severity.OrDefault() == SeverityCritical {
+		return "critical"
+	}
+	return "warn"
+}
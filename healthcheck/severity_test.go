@@ -0,0 +1,47 @@
+package healthcheck
+
+import (
+	"testing"
+)
+
+func TestValidateAlerting(t *testing.T) {
+	valid := []Base{
+		{},
+		{Severity: SeverityInfo, RunbookURL: "https://wiki.example.com/runbooks/db"},
+		{Severity: SeverityCritical, RunbookURL: "http://wiki/db"},
+	}
+	for _, base := range valid {
+		if err := validateAlerting(&base); err != nil {
+			t.Fatalf("The configuration %+v should be valid\n%v", base, err)
+		}
+	}
+	invalid := []Base{
+		{Severity: "page"},
+		{RunbookURL: "wiki.example.com/runbooks/db"},
+		{RunbookURL: "ftp://wiki.example.com/runbooks/db"},
+		{RunbookURL: "https://"},
+	}
+	for _, base := range invalid {
+		if err := validateAlerting(&base); err == nil {
+			t.Fatalf("The configuration %+v should be invalid", base)
+		}
+	}
+}
+
+func TestResultState(t *testing.T) {
+	cases := []struct {
+		result   Result
+		expected string
+	}{
+		{Result{Success: true, Severity: SeverityCritical}, "ok"},
+		{Result{Success: false}, "critical"},
+		{Result{Success: false, Severity: SeverityCritical}, "critical"},
+		{Result{Success: false, Severity: SeverityWarning}, "warn"},
+		{Result{Success: false, Severity: SeverityInfo}, "warn"},
+	}
+	for _, c := range cases {
+		if state := c.result.State(); state != c.expected {
+			t.Fatalf("Invalid state %s for %+v, expected %s", state, c.result, c.expected)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package healthcheck
+
+import (
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// the limits of the socket options values, the Linux ones
+const (
+	maxKeepAliveTime  = 32767 * time.Second
+	maxKeepAliveCount = 127
+	minSocketBuffer   = 1024
+	maxSocketBuffer   = 64 * 1024 * 1024
+)
+
+// SocketOptions the options of the TCP healthchecks sockets, to emulate a
+// specific client. The keepalive and buffer options are only supported on
+// Linux, they are ignored with a warning on the other platforms.
+type SocketOptions struct {
+	// TCP_NODELAY, enabled by default by Go
+	NoDelay *bool `json:"no-delay,omitempty" yaml:"no-delay,omitempty"`
+	// the keepalive probes parameters, keepalive is enabled when one is set
+	KeepAliveIdle     Duration `json:"keepalive-idle,omitempty" yaml:"keepalive-idle,omitempty"`
+	KeepAliveInterval Duration `json:"keepalive-interval,omitempty" yaml:"keepalive-interval,omitempty"`
+	KeepAliveCount    uint     `json:"keepalive-count,omitempty" yaml:"keepalive-count,omitempty"`
+	// the SO_SNDBUF and SO_RCVBUF sizes in bytes
+	SendBuffer    uint `json:"send-buffer,omitempty" yaml:"send-buffer,omitempty"`
+	ReceiveBuffer uint `json:"receive-buffer,omitempty" yaml:"receive-buffer,omitempty"`
+}
+
+// DeepCopyInto copies the receiver into out. in must be non-nil.
+func (in *SocketOptions) DeepCopyInto(out *SocketOptions) {
+	*out = *in
+	if in.NoDelay != nil {
+		noDelay := *in.NoDelay
+		out.NoDelay = &noDelay
+	}
+}
+
+// validateKeepAliveTime verifies a keepalive duration, set in seconds
+func validateKeepAliveTime(d Duration) bool {
+	duration := time.Duration(d)
+	return d == 0 || (duration >= time.Second && duration <= maxKeepAliveTime && duration%time.Second == 0)
+}
+
+// validateSocketBuffer verifies a socket buffer size
+func validateSocketBuffer(size uint) bool {
+	return size == 0 || (size >= minSocketBuffer && size <= maxSocketBuffer)
+}
+
+// Validate verifies the socket options ranges
+func (o *SocketOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+	if !validateKeepAliveTime(o.KeepAliveIdle) || !validateKeepAliveTime(o.KeepAliveInterval) {
+		return errors.New("The socket-options keepalive-idle and keepalive-interval should be a number of seconds between 1s and 32767s")
+	}
+	if o.KeepAliveCount > maxKeepAliveCount {
+		return errors.New("The socket-options keepalive-count should be lower than 128")
+	}
+	if !validateSocketBuffer(o.SendBuffer) || !validateSocketBuffer(o.ReceiveBuffer) {
+		return errors.New("The socket-options send-buffer and receive-buffer should be between 1KiB and 64MiB")
+	}
+	return nil
+}
+
+// keepAlive returns true if the keepalive is configured
+func (o *SocketOptions) keepAlive() bool {
+	return o.KeepAliveIdle != 0 || o.KeepAliveInterval != 0 || o.KeepAliveCount != 0
+}
+
+// platformSpecific returns true if options only supported on Linux are set
+func (o *SocketOptions) platformSpecific() bool {
+	return o.keepAlive() || o.SendBuffer != 0 || o.ReceiveBuffer != 0
+}
+
+// applyDialer configures the dialer for the options. The Go keepalive is
+// disabled when the keepalive is configured, it would override the options.
+func (o *SocketOptions) applyDialer(dialer *net.Dialer, unsupported func(err error)) {
+	if o == nil || !o.platformSpecific() {
+		return
+	}
+	if o.keepAlive() {
+		dialer.KeepAlive = -1
+	}
+	dialer.Control = chainControl(dialer.Control, socketOptionsControl(o, unsupported))
+}
+
+// applyConn sets the options of an established connection. TCP_NODELAY is
+// set by Go once connected, so it's overridden here.
+func (o *SocketOptions) applyConn(conn net.Conn) error {
+	if o == nil || o.NoDelay == nil {
+		return nil
+	}
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return nil
+	}
+	if err := tcpConn.SetNoDelay(*o.NoDelay); err != nil {
+		return errors.Wrap(err, "Fail to set the TCP_NODELAY option")
+	}
+	return nil
+}
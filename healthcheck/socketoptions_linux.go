@@ -0,0 +1,62 @@
+//go:build linux
+
+package healthcheck
+
+import (
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// setSocketOptions sets the socket options on a file descriptor
+func setSocketOptions(fd int, options *SocketOptions) error {
+	if options.keepAlive() {
+		if err := syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_KEEPALIVE, 1); err != nil {
+			return errors.Wrap(err, "Fail to enable the keepalive")
+		}
+	}
+	if options.KeepAliveIdle != 0 {
+		idle := int(time.Duration(options.KeepAliveIdle) / time.Second)
+		if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE, idle); err != nil {
+			return errors.Wrap(err, "Fail to set the keepalive idle time")
+		}
+	}
+	if options.KeepAliveInterval != 0 {
+		interval := int(time.Duration(options.KeepAliveInterval) / time.Second)
+		if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, syscall.TCP_KEEPINTVL, interval); err != nil {
+			return errors.Wrap(err, "Fail to set the keepalive interval")
+		}
+	}
+	if options.KeepAliveCount != 0 {
+		if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_TCP, syscall.TCP_KEEPCNT, int(options.KeepAliveCount)); err != nil {
+			return errors.Wrap(err, "Fail to set the keepalive count")
+		}
+	}
+	if options.SendBuffer != 0 {
+		if err := syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_SNDBUF, int(options.SendBuffer)); err != nil {
+			return errors.Wrap(err, "Fail to set the send buffer size")
+		}
+	}
+	if options.ReceiveBuffer != 0 {
+		if err := syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_RCVBUF, int(options.ReceiveBuffer)); err != nil {
+			return errors.Wrap(err, "Fail to set the receive buffer size")
+		}
+	}
+	return nil
+}
+
+// socketOptionsControl returns a dialer control function setting the socket
+// options before the connection is established
+func socketOptionsControl(options *SocketOptions, unsupported func(err error)) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		err := c.Control(func(fd uintptr) {
+			sockErr = setSocketOptions(int(fd), options)
+		})
+		if err != nil {
+			return err
+		}
+		return sockErr
+	}
+}
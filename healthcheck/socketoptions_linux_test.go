@@ -0,0 +1,117 @@
+//go:build linux
+
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestSocketOptionsControl(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to listen :\n%v", err)
+	}
+	defer listener.Close()
+	noDelay := false
+	options := &SocketOptions{
+		NoDelay:           &noDelay,
+		KeepAliveIdle:     Duration(30 * time.Second),
+		KeepAliveInterval: Duration(5 * time.Second),
+		KeepAliveCount:    3,
+		ReceiveBuffer:     65536,
+	}
+	if err := options.Validate(); err != nil {
+		t.Fatalf("The socket options should be valid :\n%v", err)
+	}
+	dialer := net.Dialer{}
+	options.applyDialer(&dialer, func(err error) {
+		t.Fatalf("The socket options should be supported :\n%v", err)
+	})
+	conn, err := dialer.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Fail to connect :\n%v", err)
+	}
+	defer conn.Close()
+	if err := options.applyConn(conn); err != nil {
+		t.Fatalf("Fail to set the socket options :\n%v", err)
+	}
+	raw, err := conn.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatalf("Fail to get the raw connection :\n%v", err)
+	}
+	expected := []struct {
+		level  int
+		option int
+		value  int
+	}{
+		{level: syscall.SOL_SOCKET, option: syscall.SO_KEEPALIVE, value: 1},
+		{level: syscall.IPPROTO_TCP, option: syscall.TCP_KEEPIDLE, value: 30},
+		{level: syscall.IPPROTO_TCP, option: syscall.TCP_KEEPINTVL, value: 5},
+		{level: syscall.IPPROTO_TCP, option: syscall.TCP_KEEPCNT, value: 3},
+		{level: syscall.IPPROTO_TCP, option: syscall.TCP_NODELAY, value: 0},
+	}
+	err = raw.Control(func(fd uintptr) {
+		for _, e := range expected {
+			value, err := syscall.GetsockoptInt(int(fd), e.level, e.option)
+			if err != nil || value != e.value {
+				t.Fatalf("Invalid socket option %d: %d %v", e.option, value, err)
+			}
+		}
+		// the kernel doubles the requested size
+		value, err := syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_RCVBUF)
+		if err != nil || value < 65536 {
+			t.Fatalf("Invalid receive buffer size %d %v", value, err)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Fail to read the socket options :\n%v", err)
+	}
+}
+
+func TestTCPExecuteSocketOptions(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to listen :\n%v", err)
+	}
+	defer listener.Close()
+	config := &TCPHealthcheckConfiguration{
+		Base: Base{
+			Name:     "foo",
+			Interval: Duration(time.Second * 5),
+		},
+		Port:    uint(listener.Addr().(*net.TCPAddr).Port),
+		Target:  "127.0.0.1",
+		Timeout: Duration(time.Second * 2),
+		SocketOptions: &SocketOptions{
+			KeepAliveIdle: Duration(10 * time.Second),
+			SendBuffer:    4096,
+		},
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("The configuration should be valid :\n%v", err)
+	}
+	h := NewTCPHealthcheck(zap.NewExample(), config)
+	h.buildURL()
+	if err := h.Execute(context.Background()); err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	invalid := []SocketOptions{
+		{KeepAliveIdle: Duration(500 * time.Millisecond)},
+		{KeepAliveInterval: Duration(1500 * time.Millisecond)},
+		{KeepAliveCount: 200},
+		{SendBuffer: 10},
+	}
+	for _, options := range invalid {
+		options := options
+		config.SocketOptions = &options
+		if config.Validate() == nil {
+			t.Fatalf("The socket options %v should be invalid", options)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package healthcheck
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+)
+
+// socketOptionsControl returns a dialer control function calling
+// unsupported, the keepalive and buffer options are only supported on Linux
+func socketOptionsControl(options *SocketOptions, unsupported func(err error)) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		unsupported(fmt.Errorf("The keepalive and buffer socket options are not supported on %s", runtime.GOOS))
+		return nil
+	}
+}
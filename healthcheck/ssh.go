@@ -0,0 +1,277 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultSSHPort the default port of the SSH healthchecks
+const DefaultSSHPort = 22
+
+// errSSHHostKeyReceived aborts the SSH handshake once the host key is
+// received, before the authentication
+var errSSHHostKeyReceived = errors.New("SSH host key received")
+
+// SSHHealthcheckConfiguration defines a SSH healthcheck configuration. The
+// healthcheck only executes the key exchange and never authenticates.
+type SSHHealthcheckConfiguration struct {
+	Base `json:",inline" yaml:",inline"`
+	// can be an IP or a domain
+	Target   string   `json:"target"`
+	Port     uint     `json:"port"`
+	SourceIP IP       `json:"source-ip,omitempty" yaml:"source-ip,omitempty"`
+	Timeout  Duration `json:"timeout"`
+	// the accepted SHA256 fingerprints of the host key, in the ssh-keygen
+	// format (SHA256:...). Several fingerprints can be configured during a
+	// key rotation.
+	Fingerprints []string `json:"fingerprints"`
+	// how resolution errors of the target are reported
+	DNSFailurePolicy DNSFailurePolicy `json:"dns-failure-policy,omitempty" yaml:"dns-failure-policy,omitempty"`
+}
+
+// SSHHealthcheck defines a SSH healthcheck
+type SSHHealthcheck struct {
+	Logger *zap.Logger
+	Config *SSHHealthcheckConfiguration
+	URL    string
+}
+
+// Validate validates the healthcheck configuration
+func (config *SSHHealthcheckConfiguration) Validate() error {
+	if config.Base.Name == "" {
+		return errors.New("The healthcheck name is missing")
+	}
+	if err := validateMessageTemplate(config.Base.MessageTemplate); err != nil {
+		return err
+	}
+	if err := validateAlerting(&config.Base); err != nil {
+		return err
+	}
+	if config.Base.StartupGracePeriod < 0 {
+		return errors.New("The healthcheck startup-grace-period should be positive")
+	}
+	if config.Target == "" {
+		return errors.New("The healthcheck target is missing")
+	}
+	if config.Port == 0 {
+		config.Port = DefaultSSHPort
+	}
+	if config.Timeout == 0 {
+		return errors.New("The healthcheck timeout is missing")
+	}
+	if len(config.Fingerprints) == 0 {
+		return errors.New("The healthcheck fingerprints are missing")
+	}
+	for _, fingerprint := range config.Fingerprints {
+		if !strings.HasPrefix(fingerprint, "SHA256:") || len(fingerprint) == len("SHA256:") {
+			return fmt.Errorf("Invalid fingerprint %s, a SHA256:... fingerprint is expected", fingerprint)
+		}
+	}
+	if err := config.DNSFailurePolicy.Validate(); err != nil {
+		return err
+	}
+	if !config.Base.OneOff {
+		if config.Base.Interval < Duration(2*time.Second) {
+			return errors.New("The healthcheck interval should be greater than 2 second")
+		}
+		if config.Base.Interval < config.Timeout {
+			return errors.New("The healthcheck interval should be greater than the timeout")
+		}
+		if err := config.Base.AdaptiveInterval.Validate(config.Base.Interval, config.Timeout); err != nil {
+			return err
+		}
+		if err := config.Base.AdaptiveTimeout.Validate(config.Base.Interval); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Base get the base configuration
+func (h *SSHHealthcheck) Base() Base {
+	return h.Config.Base
+}
+
+// timeout returns the healthcheck timeout
+func (h *SSHHealthcheck) timeout() time.Duration {
+	return time.Duration(h.Config.Timeout)
+}
+
+// target returns the healthcheck target
+func (h *SSHHealthcheck) target() string {
+	return h.Config.Target
+}
+
+// SetSource set the healthcheck source
+func (h *SSHHealthcheck) SetSource(source string) {
+	h.Config.Base.Source = source
+}
+
+// Summary returns an healthcheck summary
+func (h *SSHHealthcheck) Summary() string {
+	summary := ""
+	if h.Config.Base.Description != "" {
+		summary = fmt.Sprintf("%s on %s:%d", h.Config.Base.Description, h.Config.Target, h.Config.Port)
+
+	} else {
+		summary = fmt.Sprintf("on %s:%d", h.Config.Target, h.Config.Port)
+	}
+
+	return summary
+}
+
+// Initialize the healthcheck.
+func (h *SSHHealthcheck) Initialize() error {
+	port := h.Config.Port
+	if port == 0 {
+		port = DefaultSSHPort
+	}
+	h.URL = net.JoinHostPort(h.Config.Target, fmt.Sprintf("%d", port))
+	return nil
+}
+
+// GetConfig get the config
+func (h *SSHHealthcheck) GetConfig() interface{} {
+	return h.Config
+}
+
+// LogError logs an error with context
+func (h *SSHHealthcheck) LogError(err error, message string) {
+	logError(h.Logger, h.Config.Base.Name, err, message,
+		zap.String("target", h.Config.Target),
+		zap.Uint("port", h.Config.Port))
+}
+
+// LogDebug logs a message with context
+func (h *SSHHealthcheck) LogDebug(message string) {
+	h.Logger.Debug(message,
+		zap.String("target", h.Config.Target),
+		zap.Uint("port", h.Config.Port),
+		zap.String("name", h.Config.Base.Name))
+}
+
+// LogInfo logs a message with context
+func (h *SSHHealthcheck) LogInfo(message string) {
+	h.Logger.Info(message,
+		zap.String("target", h.Config.Target),
+		zap.Uint("port", h.Config.Port),
+		zap.String("name", h.Config.Base.Name))
+}
+
+// hostKey executes the SSH key exchange on the connection and returns the
+// host key of the server. The handshake is aborted before the authentication.
+func hostKey(conn net.Conn, address string) (ssh.PublicKey, error) {
+	var key ssh.PublicKey
+	config := &ssh.ClientConfig{
+		User: "cabourotte",
+		HostKeyCallback: func(hostname string, remote net.Addr, k ssh.PublicKey) error {
+			key = k
+			return errSSHHostKeyReceived
+		},
+	}
+	client, _, _, err := ssh.NewClientConn(conn, address, config)
+	if err == nil {
+		// nolint
+		client.Close()
+		return nil, errors.New("The SSH server accepted the connection without authentication")
+	}
+	if key == nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Execute executes an healthcheck on the given target
+func (h *SSHHealthcheck) Execute(ctx context.Context) error {
+	h.LogDebug("start executing healthcheck")
+	timeout := executionTimeout(ctx, time.Duration(h.Config.Timeout))
+	dialer := net.Dialer{}
+	if h.Config.SourceIP != nil {
+		srcIP := net.IP(h.Config.SourceIP).String()
+		addr, err := net.ResolveTCPAddr("tcp", fmt.Sprintf("%s:0", srcIP))
+		if err != nil {
+			return errors.Wrapf(err, "Fail to set the source IP %s", srcIP)
+		}
+		dialer = net.Dialer{
+			LocalAddr: addr,
+			Timeout:   timeout,
+		}
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	conn, err := dialAliased(timeoutCtx, dialer.DialContext, "tcp", h.URL)
+	if handled, policyErr := applyDNSFailurePolicy(ctx, h.Config.DNSFailurePolicy, err); handled {
+		return policyErr
+	}
+	if err != nil {
+		return errors.Wrapf(err, "SSH connection failed on %s", h.URL)
+	}
+	defer conn.Close()
+	addIPMetadata(ctx, conn.RemoteAddr())
+	deadline, _ := timeoutCtx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return errors.Wrap(err, "Fail to set the connection deadline")
+	}
+	key, err := hostKey(conn, h.URL)
+	if err != nil {
+		AddMetadata(ctx, "reason", "ssh-handshake")
+		return errors.Wrapf(err, "SSH handshake failed on %s", h.URL)
+	}
+	fingerprint := ssh.FingerprintSHA256(key)
+	AddMetadata(ctx, "host-key-type", key.Type())
+	AddMetadata(ctx, "host-key-fingerprint", fingerprint)
+	for _, expected := range h.Config.Fingerprints {
+		if fingerprint == expected {
+			return nil
+		}
+	}
+	AddMetadata(ctx, "reason", "host-key-mismatch")
+	return fmt.Errorf("The SSH host key %s of %s does not match the configured fingerprints", fingerprint, h.URL)
+}
+
+// NewSSHHealthcheck creates a SSH healthcheck from a logger and a configuration
+func NewSSHHealthcheck(logger *zap.Logger, config *SSHHealthcheckConfiguration) *SSHHealthcheck {
+	return &SSHHealthcheck{
+		Logger: logger,
+		Config: config,
+	}
+}
+
+// MarshalJSON marshal to json a SSH healthcheck
+func (h *SSHHealthcheck) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.Config)
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SSHHealthcheckConfiguration) DeepCopyInto(out *SSHHealthcheckConfiguration) {
+	*out = *in
+	in.Base.DeepCopyInto(&out.Base)
+	if in.SourceIP != nil {
+		in, out := &in.SourceIP, &out.SourceIP
+		*out = make(IP, len(*in))
+		copy(*out, *in)
+	}
+	if in.Fingerprints != nil {
+		in, out := &in.Fingerprints, &out.Fingerprints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SSHHealthcheckConfiguration.
+func (in *SSHHealthcheckConfiguration) DeepCopy() *SSHHealthcheckConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(SSHHealthcheckConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
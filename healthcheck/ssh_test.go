@@ -0,0 +1,161 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+)
+
+// sshServer starts a SSH server rejecting all authentications and returns
+// its listener and its host key fingerprint
+func sshServer(t *testing.T) (net.Listener, string) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Fail to generate the host key\n%v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatalf("Fail to create the signer\n%v", err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			return nil, errors.New("invalid password")
+		},
+	}
+	config.AddHostKey(signer)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to listen\n%v", err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				// nolint
+				ssh.NewServerConn(conn, config)
+			}()
+		}
+	}()
+	return listener, ssh.FingerprintSHA256(signer.PublicKey())
+}
+
+func TestSSHExecute(t *testing.T) {
+	listener, fingerprint := sshServer(t)
+	defer listener.Close()
+	port := uint(listener.Addr().(*net.TCPAddr).Port)
+	cases := []struct {
+		fingerprints []string
+		success      bool
+	}{
+		{fingerprints: []string{fingerprint}, success: true},
+		{fingerprints: []string{"SHA256:old", fingerprint}, success: true},
+		{fingerprints: []string{"SHA256:invalid"}, success: false},
+	}
+	for i, c := range cases {
+		h := NewSSHHealthcheck(zap.NewExample(), &SSHHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Second * 10),
+			},
+			Target:       "127.0.0.1",
+			Port:         port,
+			Timeout:      Duration(time.Second * 3),
+			Fingerprints: c.fingerprints,
+		})
+		err := h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error\n%v", err)
+		}
+		ctx := WithMetadata(context.Background())
+		err = h.Execute(ctx)
+		metadata := GetMetadata(ctx)
+		if c.success && err != nil {
+			t.Fatalf("healthcheck %d error\n%v", i, err)
+		}
+		if !c.success && (err == nil || metadata["reason"] != "host-key-mismatch") {
+			t.Fatalf("healthcheck %d should fail with a host key mismatch: %v", i, metadata)
+		}
+		if metadata["host-key-fingerprint"] != fingerprint || metadata["host-key-type"] != ssh.KeyAlgoED25519 {
+			t.Fatalf("Invalid host key metadata %v", metadata)
+		}
+	}
+}
+
+func TestSSHExecuteNotSSH(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to listen\n%v", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// nolint
+		conn.Write([]byte("HTTP/1.1 400 Bad Request\r\n\r\n"))
+		time.Sleep(5 * time.Second)
+	}()
+	h := NewSSHHealthcheck(zap.NewExample(), &SSHHealthcheckConfiguration{
+		Base: Base{
+			Name:     "foo",
+			Interval: Duration(time.Second * 10),
+		},
+		Target:       "127.0.0.1",
+		Port:         uint(listener.Addr().(*net.TCPAddr).Port),
+		Timeout:      Duration(time.Second),
+		Fingerprints: []string{"SHA256:foo"},
+	})
+	err = h.Initialize()
+	if err != nil {
+		t.Fatalf("Initialization error\n%v", err)
+	}
+	ctx := WithMetadata(context.Background())
+	start := time.Now()
+	err = h.Execute(ctx)
+	if err == nil || GetMetadata(ctx)["reason"] != "ssh-handshake" {
+		t.Fatalf("The healthcheck should fail on the handshake: %v", err)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Fatalf("The handshake should be bounded by the timeout")
+	}
+}
+
+func TestSSHValidate(t *testing.T) {
+	config := SSHHealthcheckConfiguration{
+		Base: Base{
+			Name:     "foo",
+			Interval: Duration(time.Second * 10),
+		},
+		Target:       "127.0.0.1",
+		Timeout:      Duration(time.Second * 3),
+		Fingerprints: []string{"SHA256:foo"},
+	}
+	err := config.Validate()
+	if err != nil {
+		t.Fatalf("Validation error\n%v", err)
+	}
+	if config.Port != DefaultSSHPort {
+		t.Fatalf("Invalid default port %d", config.Port)
+	}
+	config.Fingerprints = []string{"aa:bb:cc"}
+	if config.Validate() == nil {
+		t.Fatalf("Was expecting an error for the fingerprint")
+	}
+	config.Fingerprints = nil
+	if config.Validate() == nil {
+		t.Fatalf("Was expecting an error for the missing fingerprints")
+	}
+}
@@ -2,15 +2,16 @@ package healthcheck
 
 import (
 	"context"
+	cryptotls "crypto/tls"
 	"encoding/json"
 	"fmt"
 	"net"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
-
-	"gopkg.in/tomb.v2"
 )
 
 // TCPHealthcheckConfiguration defines a TCP healthcheck configuration
@@ -22,6 +23,47 @@ type TCPHealthcheckConfiguration struct {
 	SourceIP   IP       `json:"source-ip,omitempty" yaml:"source-ip,omitempty"`
 	Timeout    Duration `json:"timeout"`
 	ShouldFail bool     `json:"should-fail" yaml:"should-fail"`
+	ResolvePTR bool     `json:"resolve-ptr,omitempty" yaml:"resolve-ptr,omitempty"`
+	// how resolution errors of the target are reported
+	DNSFailurePolicy DNSFailurePolicy `json:"dns-failure-policy,omitempty" yaml:"dns-failure-policy,omitempty"`
+	// optional SOCKS5 proxy URL
+	Proxy string `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	// the interface to bind to, its address is resolved on each execution
+	SourceInterface string `json:"source-interface,omitempty" yaml:"source-interface,omitempty"`
+	// the SO_MARK value set on the socket, Linux only
+	SocketMark uint `json:"socket-mark,omitempty" yaml:"socket-mark,omitempty"`
+	// the network namespace the connection originates from, Linux only
+	NetworkNamespace string `json:"network-namespace,omitempty" yaml:"network-namespace,omitempty"`
+	// the send/expect dialogue executed once connected
+	Steps []TCPStep `json:"steps,omitempty" yaml:"steps,omitempty"`
+	// the connection establishment durations above which the result has a
+	// warning, or is failed
+	WarnConnectTime     Duration `json:"warn-connect-time,omitempty" yaml:"warn-connect-time,omitempty"`
+	CriticalConnectTime Duration `json:"critical-connect-time,omitempty" yaml:"critical-connect-time,omitempty"`
+	// execute the healthcheck from each source IP, the healthcheck is
+	// successful if at least source-quorum sources are
+	SourceIPs    []IP `json:"source-ips,omitempty" yaml:"source-ips,omitempty"`
+	SourceQuorum uint `json:"source-quorum,omitempty" yaml:"source-quorum,omitempty"`
+	// the connection is wrapped in TLS if set, the steps are executed
+	// over the TLS connection
+	TLS *TCPTLSConfiguration `json:"tls,omitempty" yaml:"tls,omitempty"`
+	// the connection error expected by a should-fail healthcheck
+	ExpectError TCPExpectedError `json:"expect-error,omitempty" yaml:"expect-error,omitempty"`
+	// the greeting the server should send once connected, read for at most
+	// banner-timeout
+	ExpectBanner  *Regexp  `json:"expect-banner,omitempty" yaml:"expect-banner,omitempty"`
+	BannerTimeout Duration `json:"banner-timeout,omitempty" yaml:"banner-timeout,omitempty"`
+	// enables TCP Fast Open, the send payload of the first step is sent in
+	// the SYN. The connection is established normally if not supported.
+	TCPFastOpen bool `json:"tcp-fast-open,omitempty" yaml:"tcp-fast-open,omitempty"`
+	// the options of the socket: nodelay, keepalive and buffer sizes
+	SocketOptions *SocketOptions `json:"socket-options,omitempty" yaml:"socket-options,omitempty"`
+	// probes several ports instead of port, the healthcheck is successful if
+	// at least ports-quorum ports are, all of them by default
+	Ports       []uint `json:"ports,omitempty" yaml:"ports,omitempty"`
+	PortsQuorum uint   `json:"ports-quorum,omitempty" yaml:"ports-quorum,omitempty"`
+	// verifies the peer drains the data sent by the steps
+	WriteCompletion *TCPWriteCompletion `json:"write-completion,omitempty" yaml:"write-completion,omitempty"`
 }
 
 // Validate validates the healthcheck configuration
@@ -29,15 +71,99 @@ func (config *TCPHealthcheckConfiguration) Validate() error {
 	if config.Base.Name == "" {
 		return errors.New("The healthcheck name is missing")
 	}
+	if err := validateMessageTemplate(config.Base.MessageTemplate); err != nil {
+		return err
+	}
+	if err := validateAlerting(&config.Base); err != nil {
+		return err
+	}
+	if config.Base.StartupGracePeriod < 0 {
+		return errors.New("The healthcheck startup-grace-period should be positive")
+	}
 	if config.Target == "" {
 		return errors.New("The healthcheck target is missing")
 	}
-	if config.Port == 0 {
-		return errors.New("The healthcheck port is missing")
+	if strings.Contains(config.Target, "/") {
+		return errors.New("The healthcheck target should be an IP or a domain, CIDR targets are only supported in the configuration file")
+	}
+	if err := validateTCPPorts(config); err != nil {
+		return err
 	}
 	if config.Timeout == 0 {
 		return errors.New("The healthcheck timeout is missing")
 	}
+	if err := config.DNSFailurePolicy.Validate(); err != nil {
+		return err
+	}
+	if err := validateSourceInterface(config.SourceInterface, config.SourceIP); err != nil {
+		return err
+	}
+	if err := validateSocketMark(config.SocketMark); err != nil {
+		return err
+	}
+	if err := validateNetworkNamespace(config.NetworkNamespace); err != nil {
+		return err
+	}
+	if config.NetworkNamespace != "" && (config.Proxy != "" || config.SourceInterface != "") {
+		return errors.New("The TCP network-namespace option can't be used with proxy or source-interface")
+	}
+	if err := validateSourceIPs(config.SourceIPs, config.SourceQuorum, config.SourceIP, config.SourceInterface); err != nil {
+		return err
+	}
+	if err := validateTCPSteps(config.Steps); err != nil {
+		return err
+	}
+	if err := config.ExpectError.Validate(); err != nil {
+		return err
+	}
+	if err := validateTCPBanner(config); err != nil {
+		return err
+	}
+	if err := validateTCPFastOpen(config); err != nil {
+		return err
+	}
+	if err := config.SocketOptions.Validate(); err != nil {
+		return err
+	}
+	if config.ExpectError != "" && !config.ShouldFail {
+		return errors.New("The TCP expect-error option requires should-fail")
+	}
+	if config.ShouldFail && len(config.Steps) != 0 {
+		return errors.New("The TCP steps can't be used with should-fail")
+	}
+	if config.WriteCompletion != nil {
+		if err := config.WriteCompletion.validate(config); err != nil {
+			return err
+		}
+	}
+	if config.TLS != nil {
+		if config.ShouldFail {
+			return errors.New("The TCP TLS options can't be used with should-fail")
+		}
+		if err := config.TLS.Validate(); err != nil {
+			return err
+		}
+		if err := config.TLS.TLSHandshakeTimeouts.Validate(config.Timeout); err != nil {
+			return err
+		}
+	}
+	if config.WarnConnectTime < 0 || config.WarnConnectTime >= config.Timeout {
+		return errors.New("The healthcheck warn-connect-time should be positive and lower than the timeout")
+	}
+	if config.CriticalConnectTime < 0 || config.CriticalConnectTime >= config.Timeout {
+		return errors.New("The healthcheck critical-connect-time should be positive and lower than the timeout")
+	}
+	if config.WarnConnectTime != 0 && config.CriticalConnectTime != 0 && config.WarnConnectTime >= config.CriticalConnectTime {
+		return errors.New("The healthcheck warn-connect-time should be lower than critical-connect-time")
+	}
+	if config.ShouldFail && (config.WarnConnectTime != 0 || config.CriticalConnectTime != 0) {
+		return errors.New("The connect time thresholds can't be used with should-fail")
+	}
+	if config.Proxy != "" {
+		if _, err := ParseProxy(config.Proxy, "socks5"); err != nil {
+			return err
+		}
+	}
 	if !config.Base.OneOff {
 		if config.Base.Interval < Duration(2*time.Second) {
 			return errors.New("The healthcheck interval should be greater than 2 second")
@@ -45,6 +171,12 @@ func (config *TCPHealthcheckConfiguration) Validate() error {
 		if config.Base.Interval < config.Timeout {
 			return errors.New("The healthcheck interval should be greater than the timeout")
 		}
+		if err := config.Base.AdaptiveInterval.Validate(config.Base.Interval, config.Timeout); err != nil {
+			return err
+		}
+		if err := config.Base.AdaptiveTimeout.Validate(config.Base.Interval); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -55,24 +187,43 @@ type TCPHealthcheck struct {
 	Config *TCPHealthcheckConfiguration
 	URL    string
 
-	Tick *time.Ticker
-	t    tomb.Tomb
+	Tick      *time.Ticker
+	tlsConfig *cryptotls.Config
+	// the TCP Fast Open fallback is only logged once
+	fastOpenFallback sync.Once
+	// the unsupported socket options are only logged once
+	socketOptionsWarning sync.Once
+	// the healthchecks of each port, for the multi-port healthchecks
+	ports []*TCPHealthcheck
 }
 
 // buildURL build the target URL for the TCP healthcheck, depending of its
 // configuration
 func (h *TCPHealthcheck) buildURL() {
-	h.URL = net.JoinHostPort(h.Config.Target, fmt.Sprintf("%d", h.Config.Port))
+	h.URL = net.JoinHostPort(h.Config.Target, h.port())
+}
+
+// port returns the port of the healthcheck, the ports separated by a comma
+// for the multi-port healthchecks
+func (h *TCPHealthcheck) port() string {
+	if len(h.Config.Ports) == 0 {
+		return fmt.Sprintf("%d", h.Config.Port)
+	}
+	ports := make([]string, 0, len(h.Config.Ports))
+	for _, port := range h.Config.Ports {
+		ports = append(ports, fmt.Sprintf("%d", port))
+	}
+	return strings.Join(ports, ",")
 }
 
 // Summary returns an healthcheck summary
 func (h *TCPHealthcheck) Summary() string {
 	summary := ""
 	if h.Config.Base.Description != "" {
-		summary = fmt.Sprintf("%s on %s:%d", h.Config.Base.Description, h.Config.Target, h.Config.Port)
+		summary = fmt.Sprintf("%s on %s:%s", h.Config.Base.Description, h.Config.Target, h.port())
 
 	} else {
-		summary = fmt.Sprintf("on %s:%d", h.Config.Target, h.Config.Port)
+		summary = fmt.Sprintf("on %s:%s", h.Config.Target, h.port())
 	}
 
 	if h.Config.ShouldFail {
@@ -85,6 +236,20 @@ func (h *TCPHealthcheck) Summary() string {
 // Initialize the healthcheck.
 func (h *TCPHealthcheck) Initialize() error {
 	h.buildURL()
+	if h.Config.TLS != nil {
+		tlsConfig, err := newTCPTLSConfig(h.Config.TLS, h.Config.Target)
+		if err != nil {
+			return err
+		}
+		h.tlsConfig = tlsConfig
+	}
+	if len(h.Config.Ports) != 0 {
+		checks, err := h.portChecks()
+		if err != nil {
+			return err
+		}
+		h.ports = checks
+	}
 	return nil
 }
 
@@ -98,6 +263,35 @@ func (h *TCPHealthcheck) Base() Base {
 	return h.Config.Base
 }
 
+// timeout returns the healthcheck timeout
+func (h *TCPHealthcheck) timeout() time.Duration {
+	return time.Duration(h.Config.Timeout)
+}
+
+// sourceChecks returns an healthcheck for each source IP
+func (h *TCPHealthcheck) sourceChecks() []Healthcheck {
+	checks := make([]Healthcheck, 0, len(h.Config.SourceIPs))
+	for _, ip := range h.Config.SourceIPs {
+		config := h.Config.DeepCopy()
+		config.Base = sourceBase(config.Base, ip)
+		config.SourceIP = ip
+		config.SourceIPs = nil
+		config.SourceQuorum = 0
+		checks = append(checks, NewTCPHealthcheck(h.Logger, config))
+	}
+	return checks
+}
+
+// sourceQuorum returns the number of sources which should succeed
+func (h *TCPHealthcheck) sourceQuorum() int {
+	return quorum(h.Config.SourceQuorum, len(h.Config.SourceIPs))
+}
+
+// target returns the healthcheck target
+func (h *TCPHealthcheck) target() string {
+	return h.Config.Target
+}
+
 // SetSource set the healthcheck source
 func (h *TCPHealthcheck) SetSource(source string) {
 	h.Config.Base.Source = source
@@ -105,11 +299,9 @@ func (h *TCPHealthcheck) SetSource(source string) {
 
 // LogError logs an error with context
 func (h *TCPHealthcheck) LogError(err error, message string) {
-	h.Logger.Error(err.Error(),
-		zap.String("extra", message),
+	logError(h.Logger, h.Config.Base.Name, err, message,
 		zap.String("target", h.Config.Target),
-		zap.Uint("port", h.Config.Port),
-		zap.String("name", h.Config.Base.Name))
+		zap.Uint("port", h.Config.Port))
 }
 
 // LogDebug logs a message with context
@@ -129,9 +321,13 @@ func (h *TCPHealthcheck) LogInfo(message string) {
 }
 
 // Execute executes an healthcheck on the given target
-func (h *TCPHealthcheck) Execute() error {
+func (h *TCPHealthcheck) Execute(ctx context.Context) error {
 	h.LogDebug("start executing healthcheck")
-	ctx := h.t.Context(context.TODO())
+	if len(h.Config.Ports) != 0 {
+		return h.executePorts(ctx)
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, executionTimeout(ctx, time.Duration(h.Config.Timeout)))
+	defer cancel()
 	dialer := net.Dialer{}
 	if h.Config.SourceIP != nil {
 		srcIP := net.IP(h.Config.SourceIP).String()
@@ -143,19 +339,116 @@ func (h *TCPHealthcheck) Execute() error {
 			LocalAddr: addr,
 		}
 	}
-	timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(h.Config.Timeout))
-	defer cancel()
-	conn, err := dialer.DialContext(timeoutCtx, "tcp", h.URL)
+	if h.Config.SourceInterface != "" {
+		addr, err := interfaceAddr(timeoutCtx, h.Config.SourceInterface, h.Config.Target)
+		if err != nil {
+			return errors.Wrapf(err, "Fail to set the source interface %s", h.Config.SourceInterface)
+		}
+		dialer = net.Dialer{
+			LocalAddr: addr,
+		}
+	}
+	if h.Config.SocketMark != 0 {
+		dialer.Control = socketMarkControl(h.Config.SocketMark)
+	}
+	fastOpen := "enabled"
+	if h.Config.TCPFastOpen {
+		dialer.Control = chainControl(dialer.Control, fastOpenControl(func(err error) {
+			fastOpen = "unsupported"
+			h.fastOpenFallback.Do(func() {
+				h.LogInfo(fmt.Sprintf("TCP Fast Open disabled, falling back to a regular connection: %s", err.Error()))
+			})
+		}))
+	}
+	h.Config.SocketOptions.applyDialer(&dialer, func(err error) {
+		h.socketOptionsWarning.Do(func() {
+			h.Logger.Warn(fmt.Sprintf("Socket options ignored: %s", err.Error()), zap.String("name", h.Config.Base.Name))
+		})
+	})
+	var conn net.Conn
+	var err error
+	start := time.Now()
+	if h.Config.Proxy != "" {
+		conn, err = socks5Dial(timeoutCtx, h.Config.Proxy, &dialer, h.URL)
+	} else {
+		dial := dialer.DialContext
+		if h.Config.NetworkNamespace != "" {
+			dial = networkNamespaceDial(h.Config.NetworkNamespace, dial)
+		}
+		conn, err = dialAliased(timeoutCtx, dial, "tcp", h.URL)
+	}
+	connectTime := time.Since(start)
+	if h.Config.ResolvePTR {
+		if err == nil {
+			addPTRMetadata(ctx, conn.RemoteAddr())
+		} else {
+			addPTRMetadataForIP(ctx, h.Config.Target)
+		}
+	}
+	if err == nil {
+		addIPMetadata(ctx, conn.RemoteAddr())
+	}
+	if handled, policyErr := applyDNSFailurePolicy(ctx, h.Config.DNSFailurePolicy, err); handled {
+		return policyErr
+	}
 	if h.Config.ShouldFail {
 		if err == nil {
 			defer conn.Close()
 			return fmt.Errorf("TCP check is successful on %s but an error was expected", h.URL)
 		}
+		return h.Config.ExpectError.checkError(ctx, err, h.URL)
 	} else {
 		if err != nil {
 			return errors.Wrapf(err, "TCP connection failed on %s", h.URL)
 		}
 		defer conn.Close()
+		if err := h.Config.SocketOptions.applyConn(conn); err != nil {
+			return err
+		}
+		raw := conn
+		AddMetadata(ctx, "connect-duration", fmt.Sprintf("%f", connectTime.Seconds()))
+		if h.Config.TCPFastOpen {
+			AddMetadata(ctx, "fast-open", fastOpen)
+		}
+		if err := h.checkConnectTime(ctx, connectTime); err != nil {
+			return err
+		}
+		if h.tlsConfig != nil {
+			conn, err = tlsHandshake(timeoutCtx, conn, h.tlsConfig, &h.Config.TLS.TLSHandshakeTimeouts)
+			if err != nil {
+				return errors.Wrapf(err, "TCP healthcheck failed on %s", h.URL)
+			}
+			defer conn.Close()
+		}
+		if h.Config.ExpectBanner != nil {
+			err = checkBanner(timeoutCtx, conn, h.Config.ExpectBanner, time.Duration(h.Config.BannerTimeout))
+			if err != nil {
+				return err
+			}
+		}
+		if len(h.Config.Steps) != 0 {
+			if err := runTCPSteps(timeoutCtx, conn, h.Config.Steps, h.Config.WriteCompletion); err != nil {
+				return err
+			}
+		}
+		if h.Config.WriteCompletion != nil {
+			return h.Config.WriteCompletion.finish(timeoutCtx, raw, conn)
+		}
+	}
+	return nil
+}
+
+// checkConnectTime compares the connection establishment duration to the
+// thresholds
+func (h *TCPHealthcheck) checkConnectTime(ctx context.Context, connectTime time.Duration) error {
+	critical := time.Duration(h.Config.CriticalConnectTime)
+	warn := time.Duration(h.Config.WarnConnectTime)
+	if critical != 0 && connectTime >= critical {
+		AddMetadata(ctx, "reason", "slow-connect")
+		return fmt.Errorf("TCP connection to %s established in %s, above the critical threshold %s", h.URL, connectTime, critical)
+	}
+	if warn != 0 && connectTime >= warn {
+		AddMetadata(ctx, "warning", fmt.Sprintf("TCP connection established in %s, above the warning threshold %s", connectTime, warn))
 	}
 	return nil
 }
@@ -182,6 +475,44 @@ func (in *TCPHealthcheckConfiguration) DeepCopyInto(out *TCPHealthcheckConfigura
 		*out = make(IP, len(*in))
 		copy(*out, *in)
 	}
+	if in.SourceIPs != nil {
+		in, out := &in.SourceIPs, &out.SourceIPs
+		*out = make([]IP, len(*in))
+		for i := range *in {
+			(*out)[i] = make(IP, len((*in)[i]))
+			copy((*out)[i], (*in)[i])
+		}
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(TCPTLSConfiguration)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExpectBanner != nil {
+		out.ExpectBanner = in.ExpectBanner.DeepCopy()
+	}
+	if in.Steps != nil {
+		in, out := &in.Steps, &out.Steps
+		*out = make([]TCPStep, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SocketOptions != nil {
+		in, out := &in.SocketOptions, &out.SocketOptions
+		*out = new(SocketOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]uint, len(*in))
+		copy(*out, *in)
+	}
+	if in.WriteCompletion != nil {
+		in, out := &in.WriteCompletion, &out.WriteCompletion
+		*out = new(TCPWriteCompletion)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TCPHealthcheckConfiguration.
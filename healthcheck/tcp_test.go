@@ -1,6 +1,8 @@
 package healthcheck
 
 import (
+	"context"
+	"errors"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -47,7 +49,7 @@ func TestTCPExecuteSuccess(t *testing.T) {
 		},
 	}
 	h.buildURL()
-	err = h.Execute()
+	err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -73,7 +75,7 @@ func TestTCPExecuteSuccessSourceIP(t *testing.T) {
 		},
 	}
 	h.buildURL()
-	err = h.Execute()
+	err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -106,7 +108,7 @@ func TestTCPv6ExecuteSuccess(t *testing.T) {
 		},
 	}
 	h.buildURL()
-	err = h.Execute()
+	err = h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
@@ -155,8 +157,202 @@ func TestTCPExecuteSuccessShoulddFail(t *testing.T) {
 		},
 	}
 	h.buildURL()
-	err := h.Execute()
+	err := h.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("healthcheck error :\n%v", err)
 	}
 }
+
+func TestTCPExecuteResolvePTR(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	h := TCPHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &TCPHealthcheckConfiguration{
+			Port:       uint(port),
+			Target:     "127.0.0.1",
+			Timeout:    Duration(time.Second * 2),
+			ResolvePTR: true,
+		},
+	}
+	h.buildURL()
+	ctx := WithMetadata(context.Background())
+	err = h.Execute(ctx)
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	metadata := GetMetadata(ctx)
+	if metadata["ip"] != "127.0.0.1" {
+		t.Fatalf("Invalid metadata %v", metadata)
+	}
+}
+
+func TestTCPExecuteDNSFailurePolicy(t *testing.T) {
+	cases := []struct {
+		policy DNSFailurePolicy
+		check  func(err error) bool
+	}{
+		{policy: "", check: func(err error) bool { return err != nil && !errors.Is(err, ErrResultSkipped) }},
+		{policy: DNSFailurePolicyFail, check: func(err error) bool { return err != nil && !errors.Is(err, ErrResultSkipped) }},
+		{policy: DNSFailurePolicySkip, check: func(err error) bool { return errors.Is(err, ErrResultSkipped) }},
+		{policy: DNSFailurePolicyWarn, check: func(err error) bool { return err == nil }},
+	}
+	for _, c := range cases {
+		h := TCPHealthcheck{
+			Logger: zap.NewExample(),
+			Config: &TCPHealthcheckConfiguration{
+				Port:             9000,
+				Target:           "cabourotte.invalid",
+				Timeout:          Duration(time.Second * 2),
+				DNSFailurePolicy: c.policy,
+			},
+		}
+		h.buildURL()
+		ctx := WithMetadata(context.Background())
+		err := h.Execute(ctx)
+		if !c.check(err) {
+			t.Fatalf("Invalid result for policy %s: %v", c.policy, err)
+		}
+		if GetMetadata(ctx)["reason"] != "dns-resolution-failure" {
+			t.Fatalf("Invalid metadata for policy %s: %v", c.policy, GetMetadata(ctx))
+		}
+	}
+}
+
+func TestTCPExecuteSourceInterface(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	config := &TCPHealthcheckConfiguration{
+		Base: Base{
+			Name:     "foo",
+			Interval: Duration(time.Second * 5),
+		},
+		Port:            uint(port),
+		Target:          "127.0.0.1",
+		Timeout:         Duration(time.Second * 2),
+		SourceInterface: "lo",
+	}
+	err = config.Validate()
+	if err != nil {
+		t.Fatalf("The configuration should be valid :\n%v", err)
+	}
+	h := TCPHealthcheck{
+		Logger: zap.NewExample(),
+		Config: config,
+	}
+	h.buildURL()
+	err = h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	config.SourceInterface = "doesnotexist0"
+	if config.Validate() == nil {
+		t.Fatalf("The configuration should be invalid")
+	}
+	config.SourceInterface = "lo"
+	config.SourceIP = IP(net.ParseIP("127.0.0.1"))
+	if config.Validate() == nil {
+		t.Fatalf("The source IP and the source interface should be mutually exclusive")
+	}
+}
+
+func TestTCPExecuteSourceInterfaceIPv6Hostname(t *testing.T) {
+	listener, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 is needed on the loopback interface")
+	}
+	defer listener.Close()
+	// the hostname only resolves to an IPv6 address
+	SetHostAliases(HostAliases{"cabourotte-ipv6.invalid": {"::1"}})
+	defer SetHostAliases(nil)
+	h := TCPHealthcheck{
+		Logger: zap.NewExample(),
+		Config: &TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Second * 5),
+			},
+			Port:            uint(listener.Addr().(*net.TCPAddr).Port),
+			Target:          "cabourotte-ipv6.invalid",
+			Timeout:         Duration(time.Second * 2),
+			SourceInterface: "lo",
+		},
+	}
+	h.buildURL()
+	err = h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+}
+
+func TestTCPExecuteConnectTime(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to start the listener: %v", err)
+	}
+	defer listener.Close()
+	cases := []struct {
+		warn     Duration
+		critical Duration
+		warning  bool
+		failure  bool
+	}{
+		{warn: Duration(time.Second), critical: Duration(time.Second + 1)},
+		{warn: Duration(1), warning: true},
+		{warn: Duration(1), critical: Duration(2), failure: true},
+	}
+	for _, c := range cases {
+		h := NewTCPHealthcheck(zap.NewExample(), &TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(10 * time.Second),
+			},
+			Port:                uint(listener.Addr().(*net.TCPAddr).Port),
+			Target:              "127.0.0.1",
+			Timeout:             Duration(time.Second * 2),
+			WarnConnectTime:     c.warn,
+			CriticalConnectTime: c.critical,
+		})
+		if err := h.Config.Validate(); err != nil {
+			t.Fatalf("Invalid configuration: %v", err)
+		}
+		h.buildURL()
+		ctx := WithMetadata(context.Background())
+		err = h.Execute(ctx)
+		metadata := GetMetadata(ctx)
+		if metadata["connect-duration"] == "" {
+			t.Fatalf("The connect duration is missing: %v", metadata)
+		}
+		if c.failure != (err != nil) || (c.failure && metadata["reason"] != "slow-connect") {
+			t.Fatalf("Invalid result for %v: %v %v", c, err, metadata)
+		}
+		if c.warning != (metadata["warning"] != "") {
+			t.Fatalf("Invalid warning for %v: %v", c, metadata)
+		}
+	}
+	config := TCPHealthcheckConfiguration{
+		Base:                Base{Name: "foo", Interval: Duration(10 * time.Second)},
+		Port:                1,
+		Target:              "127.0.0.1",
+		Timeout:             Duration(time.Second * 2),
+		WarnConnectTime:     Duration(time.Second),
+		CriticalConnectTime: Duration(time.Millisecond),
+	}
+	if err := config.Validate(); err == nil {
+		t.Fatalf("The warn threshold should be lower than the critical one")
+	}
+}
@@ -0,0 +1,65 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// validateTCPBanner validates the banner options of a TCP healthcheck
+func validateTCPBanner(config *TCPHealthcheckConfiguration) error {
+	if config.BannerTimeout != 0 && config.ExpectBanner == nil {
+		return errors.New("The TCP banner-timeout option requires expect-banner")
+	}
+	if config.BannerTimeout < 0 || config.BannerTimeout >= config.Timeout {
+		return errors.New("The healthcheck banner-timeout should be positive and lower than the timeout")
+	}
+	if config.ExpectBanner != nil && config.ShouldFail {
+		return errors.New("The TCP expect-banner option can't be used with should-fail")
+	}
+	return nil
+}
+
+// checkBanner reads the greeting sent by the server once connected, until it
+// matches the expected banner or until the banner timeout expires. The
+// healthcheck timeout is used if the banner timeout is not set.
+func checkBanner(ctx context.Context, conn net.Conn, expected *Regexp, timeout time.Duration) error {
+	deadline, ok := ctx.Deadline()
+	if timeout != 0 {
+		bannerDeadline := time.Now().Add(timeout)
+		if !ok || bannerDeadline.Before(deadline) {
+			deadline = bannerDeadline
+			ok = true
+		}
+	}
+	if ok {
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			return errors.Wrap(err, "Fail to set the connection deadline")
+		}
+	}
+	received, err := expect(conn, expected)
+	banner := strings.TrimRight(string(received), "\r\n")
+	if banner != "" {
+		AddMetadata(ctx, "banner", banner)
+	}
+	if err != nil {
+		// a timeout is a mismatch if a banner was received
+		var netErr net.Error
+		if banner == "" && errors.As(err, &netErr) && netErr.Timeout() {
+			AddMetadata(ctx, "reason", "banner-timeout")
+		} else {
+			AddMetadata(ctx, "reason", "banner-mismatch")
+		}
+		r := regexp.Regexp(*expected)
+		return fmt.Errorf("The banner does not match %s: %q (%s)", r.String(), banner, err.Error())
+	}
+	// the steps use the healthcheck deadline
+	// nolint
+	conn.SetReadDeadline(time.Time{})
+	return nil
+}
@@ -0,0 +1,85 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+)
+
+func TestTCPExecuteBanner(t *testing.T) {
+	listener := scriptedListener(t, "SSH-2.0-OpenSSH_8.9\r\n", map[string]string{})
+	defer listener.Close()
+	silent := scriptedListener(t, "", map[string]string{})
+	defer silent.Close()
+	configYAML := `
+name: foo
+target: 127.0.0.1
+timeout: 3s
+interval: 10s
+expect-banner: "^SSH-2\\.0-"
+banner-timeout: 200ms
+`
+	var config TCPHealthcheckConfiguration
+	err := yaml.UnmarshalStrict([]byte(configYAML), &config)
+	if err != nil {
+		t.Fatalf("Fail to parse the configuration: %v", err)
+	}
+	config.Port = uint(listener.Addr().(*net.TCPAddr).Port)
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Invalid configuration: %v", err)
+	}
+	h := NewTCPHealthcheck(zap.NewExample(), &config)
+	h.buildURL()
+	ctx := WithMetadata(context.Background())
+	err = h.Execute(ctx)
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	if GetMetadata(ctx)["banner"] != "SSH-2.0-OpenSSH_8.9" {
+		t.Fatalf("Invalid metadata %v", GetMetadata(ctx))
+	}
+
+	config.ExpectBanner = mustRegexp(t, "^220 ")
+	ctx = WithMetadata(context.Background())
+	err = h.Execute(ctx)
+	if err == nil {
+		t.Fatalf("The banner should not match")
+	}
+	metadata := GetMetadata(ctx)
+	if metadata["reason"] != "banner-mismatch" || metadata["banner"] != "SSH-2.0-OpenSSH_8.9" {
+		t.Fatalf("Invalid metadata %v", metadata)
+	}
+
+	config.Port = uint(silent.Addr().(*net.TCPAddr).Port)
+	h.buildURL()
+	ctx = WithMetadata(context.Background())
+	start := time.Now()
+	err = h.Execute(ctx)
+	if err == nil {
+		t.Fatalf("The banner should not be received")
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("The banner timeout should be used instead of the healthcheck timeout")
+	}
+	if GetMetadata(ctx)["reason"] != "banner-timeout" {
+		t.Fatalf("Invalid metadata %v", GetMetadata(ctx))
+	}
+}
+
+func TestValidateTCPBanner(t *testing.T) {
+	cases := []TCPHealthcheckConfiguration{
+		{Timeout: Duration(time.Second), BannerTimeout: Duration(100 * time.Millisecond)},
+		{Timeout: Duration(time.Second), ExpectBanner: mustRegexp(t, "^220"), BannerTimeout: Duration(time.Second)},
+		{Timeout: Duration(time.Second), ExpectBanner: mustRegexp(t, "^220"), ShouldFail: true},
+	}
+	for i, c := range cases {
+		c := c
+		if err := validateTCPBanner(&c); err == nil {
+			t.Fatalf("Was expecting an error for the case %d", i)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// TCPExpectedError the connection error expected by a should-fail TCP
+// healthcheck
+type TCPExpectedError string
+
+const (
+	// TCPExpectAny any connection error is expected (the default)
+	TCPExpectAny TCPExpectedError = "any"
+	// TCPExpectRefused the connection should be refused or reset, the port
+	// is closed
+	TCPExpectRefused TCPExpectedError = "refused"
+	// TCPExpectTimeout the connection should time out, the packets are
+	// dropped
+	TCPExpectTimeout TCPExpectedError = "timeout"
+)
+
+// Validate validates the expected error
+func (e TCPExpectedError) Validate() error {
+	if e != "" && e != TCPExpectAny && e != TCPExpectRefused && e != TCPExpectTimeout {
+		return fmt.Errorf("Invalid TCP expected error %s, should be any, refused or timeout", e)
+	}
+	return nil
+}
+
+// tcpErrorType classifies a connection error
+func tcpErrorType(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "refused"
+	case errors.Is(err, syscall.ECONNRESET):
+		return "reset"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return "unreachable"
+	case isDNSError(err):
+		return "dns"
+	}
+	return "other"
+}
+
+// checkError verifies that a connection error is the expected one. The error
+// type is added to the result metadata.
+func (e TCPExpectedError) checkError(ctx context.Context, err error, url string) error {
+	errorType := tcpErrorType(err)
+	AddMetadata(ctx, "error-type", errorType)
+	switch e {
+	case TCPExpectRefused:
+		if errorType != "refused" && errorType != "reset" {
+			return errors.Wrapf(err, "TCP connection on %s failed with a %s error but was expected to be refused", url, errorType)
+		}
+	case TCPExpectTimeout:
+		if errorType != "timeout" {
+			return errors.Wrapf(err, "TCP connection on %s failed with a %s error but was expected to time out", url, errorType)
+		}
+	}
+	return nil
+}
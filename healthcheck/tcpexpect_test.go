@@ -0,0 +1,103 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestTCPErrorType(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected string
+	}{
+		{err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, expected: "refused"},
+		{err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNRESET)}, expected: "reset"},
+		{err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.EHOSTUNREACH)}, expected: "unreachable"},
+		{err: &net.OpError{Op: "dial", Err: os.ErrDeadlineExceeded}, expected: "timeout"},
+		{err: context.DeadlineExceeded, expected: "timeout"},
+		{err: &net.DNSError{Err: "no such host", Name: "foo"}, expected: "dns"},
+		{err: os.ErrPermission, expected: "other"},
+	}
+	for _, c := range cases {
+		if tcpErrorType(c.err) != c.expected {
+			t.Fatalf("Invalid error type %s for %v", tcpErrorType(c.err), c.err)
+		}
+	}
+}
+
+func TestTCPExecuteExpectError(t *testing.T) {
+	// a closed port
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to listen:\n%v", err)
+	}
+	port, err := strconv.ParseUint(strings.Split(listener.Addr().String(), ":")[1], 10, 16)
+	if err != nil {
+		t.Fatalf("Fail to get the port:\n%v", err)
+	}
+	listener.Close()
+	cases := []struct {
+		expect  TCPExpectedError
+		success bool
+	}{
+		{expect: "", success: true},
+		{expect: TCPExpectAny, success: true},
+		{expect: TCPExpectRefused, success: true},
+		{expect: TCPExpectTimeout, success: false},
+	}
+	for _, c := range cases {
+		h := TCPHealthcheck{
+			Logger: zap.NewExample(),
+			Config: &TCPHealthcheckConfiguration{
+				ShouldFail:  true,
+				ExpectError: c.expect,
+				Port:        uint(port),
+				Target:      "127.0.0.1",
+				Timeout:     Duration(time.Second * 2),
+			},
+		}
+		h.buildURL()
+		ctx := WithMetadata(context.Background())
+		err := h.Execute(ctx)
+		if c.success != (err == nil) {
+			t.Fatalf("Invalid result for %s: %v", c.expect, err)
+		}
+		if GetMetadata(ctx)["error-type"] != "refused" {
+			t.Fatalf("Invalid metadata %v", GetMetadata(ctx))
+		}
+	}
+}
+
+func TestValidateTCPExpectError(t *testing.T) {
+	config := TCPHealthcheckConfiguration{
+		Base: Base{
+			Name:     "foo",
+			Interval: Duration(10 * time.Second),
+		},
+		Port:        9000,
+		Target:      "127.0.0.1",
+		Timeout:     Duration(time.Second * 2),
+		ShouldFail:  true,
+		ExpectError: TCPExpectRefused,
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Invalid configuration:\n%v", err)
+	}
+	config.ExpectError = "closed"
+	if config.Validate() == nil {
+		t.Fatalf("The expected error should be invalid")
+	}
+	config.ExpectError = TCPExpectTimeout
+	config.ShouldFail = false
+	if config.Validate() == nil {
+		t.Fatalf("expect-error should require should-fail")
+	}
+}
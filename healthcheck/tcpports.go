@@ -0,0 +1,102 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// validateTCPPorts verifies the ports of a multi-port TCP healthcheck
+func validateTCPPorts(config *TCPHealthcheckConfiguration) error {
+	if len(config.Ports) == 0 {
+		if config.PortsQuorum != 0 {
+			return errors.New("The healthcheck ports-quorum requires ports")
+		}
+		if config.Port == 0 {
+			return errors.New("The healthcheck port is missing")
+		}
+		return nil
+	}
+	if config.Port != 0 {
+		return errors.New("The healthcheck port and ports options can't be used together")
+	}
+	if config.PortsQuorum > uint(len(config.Ports)) {
+		return fmt.Errorf("The healthcheck ports-quorum should be lower or equal than the number of ports (%d)", len(config.Ports))
+	}
+	seen := make(map[uint]bool)
+	for _, port := range config.Ports {
+		if port == 0 || port > 65535 {
+			return fmt.Errorf("Invalid healthcheck port %d", port)
+		}
+		if seen[port] {
+			return fmt.Errorf("The healthcheck port %d is duplicated", port)
+		}
+		seen[port] = true
+	}
+	return nil
+}
+
+// portChecks returns an healthcheck for each port
+func (h *TCPHealthcheck) portChecks() ([]*TCPHealthcheck, error) {
+	checks := make([]*TCPHealthcheck, 0, len(h.Config.Ports))
+	for _, port := range h.Config.Ports {
+		config := h.Config.DeepCopy()
+		config.Port = port
+		config.Ports = nil
+		config.PortsQuorum = 0
+		check := NewTCPHealthcheck(h.Logger, config)
+		if err := check.Initialize(); err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// executePorts executes the healthcheck on all ports in parallel. The status
+// of each port is added to the metadata, and the returned error is not nil
+// if the quorum is not reached.
+func (h *TCPHealthcheck) executePorts(ctx context.Context) error {
+	if h.ports == nil {
+		checks, err := h.portChecks()
+		if err != nil {
+			return err
+		}
+		h.ports = checks
+	}
+	errs := make([]error, len(h.ports))
+	var wg sync.WaitGroup
+	for i := range h.ports {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = h.ports[i].Execute(WithMetadata(ctx))
+		}(i)
+	}
+	wg.Wait()
+	successes := 0
+	failed := []string{}
+	for i, err := range errs {
+		port := strconv.FormatUint(uint64(h.ports[i].Config.Port), 10)
+		if err != nil {
+			failed = append(failed, port)
+			AddMetadata(ctx, "port-"+port, err.Error())
+			continue
+		}
+		successes++
+		AddMetadata(ctx, "port-"+port, "success")
+	}
+	if len(failed) != 0 {
+		AddMetadata(ctx, "failed-ports", strings.Join(failed, ","))
+	}
+	expected := quorum(h.Config.PortsQuorum, len(h.ports))
+	if successes < expected {
+		AddMetadata(ctx, "reason", "ports-quorum")
+		return fmt.Errorf("%d ports successful out of %d on %s, %d required", successes, len(h.ports), h.Config.Target, expected)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestTCPExecutePorts(t *testing.T) {
+	ports := []uint{}
+	for i := 0; i < 2; i++ {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Fail to listen :\n%v", err)
+		}
+		defer listener.Close()
+		ports = append(ports, uint(listener.Addr().(*net.TCPAddr).Port))
+	}
+	// a closed port
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to listen :\n%v", err)
+	}
+	closed := uint(listener.Addr().(*net.TCPAddr).Port)
+	listener.Close()
+	cases := []struct {
+		ports   []uint
+		quorum  uint
+		success bool
+	}{
+		{ports: ports, success: true},
+		{ports: append([]uint{closed}, ports...), success: false},
+		{ports: append([]uint{closed}, ports...), quorum: 2, success: true},
+	}
+	for i, c := range cases {
+		config := &TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(time.Second * 5),
+			},
+			Target:      "127.0.0.1",
+			Timeout:     Duration(time.Second * 2),
+			Ports:       c.ports,
+			PortsQuorum: c.quorum,
+		}
+		if err := config.Validate(); err != nil {
+			t.Fatalf("The configuration should be valid :\n%v", err)
+		}
+		h := NewTCPHealthcheck(zap.NewExample(), config)
+		if err := h.Initialize(); err != nil {
+			t.Fatalf("Initialization error\n%v", err)
+		}
+		ctx := WithMetadata(context.Background())
+		err := h.Execute(ctx)
+		metadata := GetMetadata(ctx)
+		if c.success != (err == nil) {
+			t.Fatalf("Invalid result for case %d: %v %v", i, err, metadata)
+		}
+		for _, port := range ports {
+			if metadata[fmt.Sprintf("port-%d", port)] != "success" {
+				t.Fatalf("Invalid port metadata for case %d: %v", i, metadata)
+			}
+		}
+		if len(c.ports) == 3 && (metadata["failed-ports"] != fmt.Sprintf("%d", closed) ||
+			metadata[fmt.Sprintf("port-%d", closed)] == "") {
+			t.Fatalf("Invalid failed ports metadata for case %d: %v", i, metadata)
+		}
+	}
+	invalid := []TCPHealthcheckConfiguration{
+		{Port: 22, Ports: []uint{22}},
+		{Ports: []uint{22, 22}},
+		{Ports: []uint{22, 70000}},
+		{Ports: []uint{22}, PortsQuorum: 2},
+		{Port: 22, PortsQuorum: 1},
+	}
+	for _, config := range invalid {
+		config.Base = Base{Name: "foo", Interval: Duration(time.Second * 5)}
+		config.Target = "127.0.0.1"
+		config.Timeout = Duration(time.Second * 2)
+		if config.Validate() == nil {
+			t.Fatalf("The configuration should be invalid %v", config)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+// tcpStepMaxRead the maximum amount of data read while waiting for an
+// expected response
+const tcpStepMaxRead = 64 * 1024
+
+// TCPStep a step of a TCP dialogue: the send bytes are written, then the
+// data read from the connection should match the expect regexp
+type TCPStep struct {
+	Send   string  `json:"send,omitempty" yaml:"send,omitempty"`
+	Expect *Regexp `json:"expect,omitempty" yaml:"expect,omitempty"`
+}
+
+// validateTCPSteps validates a list of TCP steps
+func validateTCPSteps(steps []TCPStep) error {
+	for i, step := range steps {
+		if step.Send == "" && step.Expect == nil {
+			return fmt.Errorf("The TCP step %d should have a send or an expect value", i)
+		}
+	}
+	return nil
+}
+
+// expect reads from the connection until the data matches the regexp
+func expect(conn net.Conn, expected *Regexp) ([]byte, error) {
+	r := regexp.Regexp(*expected)
+	buffer := make([]byte, 0, 512)
+	chunk := make([]byte, 512)
+	for {
+		n, err := conn.Read(chunk)
+		buffer = append(buffer, chunk[:n]...)
+		if r.Match(buffer) {
+			return buffer, nil
+		}
+		if err != nil {
+			if err == io.EOF {
+				return buffer, fmt.Errorf("connection closed before receiving %s", r.String())
+			}
+			return buffer, err
+		}
+		if len(buffer) >= tcpStepMaxRead {
+			return buffer, fmt.Errorf("no match for %s in the first %d bytes", r.String(), tcpStepMaxRead)
+		}
+	}
+}
+
+// runTCPSteps executes the steps in order on the connection, within the
+// context deadline. The writes are bounded by the write completion deadline
+// if set.
+func runTCPSteps(ctx context.Context, conn net.Conn, steps []TCPStep, completion *TCPWriteCompletion) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return errors.Wrap(err, "Fail to set the connection deadline")
+		}
+	}
+	for i, step := range steps {
+		if step.Send != "" {
+			var err error
+			if completion != nil {
+				err = completion.write(ctx, conn, []byte(step.Send))
+			} else {
+				_, err = conn.Write([]byte(step.Send))
+			}
+			if err != nil {
+				AddMetadata(ctx, "failed-step", fmt.Sprintf("%d", i))
+				return errors.Wrapf(err, "TCP step %d: fail to send data", i)
+			}
+		}
+		if step.Expect != nil {
+			received, err := expect(conn, step.Expect)
+			if err != nil {
+				AddMetadata(ctx, "failed-step", fmt.Sprintf("%d", i))
+				return errors.Wrapf(err, "TCP step %d: unexpected response %q", i, string(received))
+			}
+		}
+	}
+	return nil
+}
+
+// DeepCopyInto copies the step into out
+func (in *TCPStep) DeepCopyInto(out *TCPStep) {
+	*out = *in
+	if in.Expect != nil {
+		out.Expect = new(Regexp)
+		in.Expect.DeepCopyInto(out.Expect)
+	}
+}
@@ -0,0 +1,116 @@
+package healthcheck
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+)
+
+// scriptedListener greets the clients then answers each line using the
+// responses
+func scriptedListener(t *testing.T, greeting string, responses map[string]string) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to start the listener: %v", err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				_, _ = conn.Write([]byte(greeting))
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					_, _ = conn.Write([]byte(responses[strings.TrimSpace(line)]))
+				}
+			}(conn)
+		}
+	}()
+	return listener
+}
+
+func TestTCPExecuteSteps(t *testing.T) {
+	listener := scriptedListener(t, "220 ready\r\n", map[string]string{
+		"HELO cabourotte": "250 hello\r\n",
+		"QUIT":            "221 bye\r\n",
+	})
+	defer listener.Close()
+	addr := listener.Addr().(*net.TCPAddr)
+	configYAML := `
+name: foo
+target: 127.0.0.1
+timeout: 2s
+interval: 10s
+steps:
+  - expect: "^220 "
+  - send: "HELO cabourotte\r\n"
+    expect: "250 hello"
+  - send: "QUIT\r\n"
+    expect: "221"
+`
+	var config TCPHealthcheckConfiguration
+	err := yaml.UnmarshalStrict([]byte(configYAML), &config)
+	if err != nil {
+		t.Fatalf("Fail to parse the configuration: %v", err)
+	}
+	config.Port = uint(addr.Port)
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Invalid configuration: %v", err)
+	}
+	h := NewTCPHealthcheck(zap.NewExample(), &config)
+	h.buildURL()
+	err = h.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+
+	copied := config.DeepCopy()
+	copied.Steps[1].Expect = nil
+	if config.Steps[1].Expect == nil {
+		t.Fatalf("The steps should be copied")
+	}
+
+	config.Timeout = Duration(200 * time.Millisecond)
+	config.Steps = []TCPStep{
+		{Expect: mustRegexp(t, "^220 ")},
+		{Send: "HELO cabourotte\r\n", Expect: mustRegexp(t, "^220 ")},
+	}
+	ctx := WithMetadata(context.Background())
+	err = h.Execute(ctx)
+	if err == nil || !strings.Contains(err.Error(), "TCP step 1") {
+		t.Fatalf("Was expecting an error on the step 1: %v", err)
+	}
+	if GetMetadata(ctx)["failed-step"] != "1" {
+		t.Fatalf("Invalid metadata %v", GetMetadata(ctx))
+	}
+}
+
+func mustRegexp(t *testing.T, s string) *Regexp {
+	r, err := regexp.Compile(s)
+	if err != nil {
+		t.Fatalf("Invalid regexp %s: %v", s, err)
+	}
+	result := Regexp(*r)
+	return &result
+}
+
+func TestValidateTCPSteps(t *testing.T) {
+	err := validateTCPSteps([]TCPStep{{Send: "a"}, {}})
+	if err == nil || !strings.Contains(err.Error(), "step 1") {
+		t.Fatalf("Was expecting an error: %v", err)
+	}
+}
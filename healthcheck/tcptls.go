@@ -0,0 +1,48 @@
+package healthcheck
+
+import (
+	cryptotls "crypto/tls"
+
+	"github.com/pkg/errors"
+
+	"github.com/mcorbin/cabourotte/tls"
+)
+
+// TCPTLSConfiguration the TLS options of a TCP healthcheck
+type TCPTLSConfiguration struct {
+	Key    string `json:"key,omitempty"`
+	Cert   string `json:"cert,omitempty"`
+	Cacert string `json:"cacert,omitempty"`
+	// the SNI, the target is used if not set
+	ServerName string `json:"server-name,omitempty" yaml:"server-name,omitempty"`
+	Insecure   bool   `json:"insecure"`
+	// the handshake timeout and duration thresholds
+	TLSHandshakeTimeouts `json:",inline" yaml:",inline"`
+}
+
+// Validate validates the TLS options of a TCP healthcheck
+func (config *TCPTLSConfiguration) Validate() error {
+	if !((config.Key != "" && config.Cert != "") ||
+		(config.Key == "" && config.Cert == "")) {
+		return errors.New("Invalid certificates")
+	}
+	return nil
+}
+
+// newTCPTLSConfig builds the TLS configuration of a TCP healthcheck
+func newTCPTLSConfig(config *TCPTLSConfiguration, target string) (*cryptotls.Config, error) {
+	tlsConfig, err := tls.GetTLSConfig(config.Key, config.Cert, config.Cacert, config.Insecure)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.ServerName = config.ServerName
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = target
+	}
+	return tlsConfig, nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TCPTLSConfiguration) DeepCopyInto(out *TCPTLSConfiguration) {
+	*out = *in
+}
@@ -0,0 +1,92 @@
+package healthcheck
+
+import (
+	"bufio"
+	"context"
+	cryptotls "crypto/tls"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestTCPExecuteTLS(t *testing.T) {
+	server := httptest.NewUnstartedServer(nil)
+	server.StartTLS()
+	certificates := server.TLS.Certificates
+	server.Close()
+	listener, err := cryptotls.Listen("tcp", "127.0.0.1:0", &cryptotls.Config{
+		Certificates: certificates,
+		MaxVersion:   cryptotls.VersionTLS12,
+	})
+	if err != nil {
+		t.Fatalf("Fail to start the listener: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				line, err := bufio.NewReader(conn).ReadString('\n')
+				if err != nil {
+					return
+				}
+				_, _ = conn.Write([]byte("pong " + line))
+			}(conn)
+		}
+	}()
+	addr := listener.Addr().(*net.TCPAddr)
+	config := TCPHealthcheckConfiguration{
+		Base: Base{
+			Name:     "foo",
+			Interval: Duration(10 * time.Second),
+		},
+		Target:  "127.0.0.1",
+		Port:    uint(addr.Port),
+		Timeout: Duration(2 * time.Second),
+		Steps: []TCPStep{
+			{Send: "ping\n"},
+			{Expect: mustRegexp(t, "^pong ping")},
+		},
+		TLS: &TCPTLSConfiguration{
+			ServerName: "example.com",
+		},
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Invalid configuration: %v", err)
+	}
+	h := NewTCPHealthcheck(zap.NewExample(), &config)
+	if err := h.Initialize(); err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	ctx := WithMetadata(context.Background())
+	if err := h.Execute(ctx); err == nil {
+		t.Fatalf("Was expecting an error, the certificate is not trusted")
+	}
+	if GetMetadata(ctx)["reason"] != "tls-handshake" {
+		t.Fatalf("Invalid metadata %v", GetMetadata(ctx))
+	}
+	config.TLS.Insecure = true
+	h = NewTCPHealthcheck(zap.NewExample(), &config)
+	if err := h.Initialize(); err != nil {
+		t.Fatalf("Initialization error :\n%v", err)
+	}
+	ctx = WithMetadata(context.Background())
+	if err := h.Execute(ctx); err != nil {
+		t.Fatalf("healthcheck error :\n%v", err)
+	}
+	if GetMetadata(ctx)["tls-version"] != "TLS 1.2" {
+		t.Fatalf("Invalid metadata %v", GetMetadata(ctx))
+	}
+	config.ShouldFail = true
+	config.Steps = nil
+	if err := config.Validate(); err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
@@ -0,0 +1,136 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// sendQueuePollInterval the interval between two reads of the send queue
+const sendQueuePollInterval = 10 * time.Millisecond
+
+// TCPWriteCompletion verifies that the peer drains the data sent by the
+// steps, to detect the servers accepting the connections without processing
+// them. The writes are bounded by a deadline, and on Linux the healthcheck
+// waits for the peer to acknowledge all the data sent.
+type TCPWriteCompletion struct {
+	// the maximum duration of the writes and of their acknowledgement, the
+	// healthcheck timeout if not set
+	WriteTimeout Duration `json:"write-timeout,omitempty" yaml:"write-timeout,omitempty"`
+	// once the steps are executed, the write side of the connection is
+	// closed and the peer should close the connection in drain-timeout,
+	// the healthcheck timeout if not set
+	HalfClose    bool     `json:"half-close,omitempty" yaml:"half-close,omitempty"`
+	DrainTimeout Duration `json:"drain-timeout,omitempty" yaml:"drain-timeout,omitempty"`
+}
+
+// validate verifies the write completion options
+func (w *TCPWriteCompletion) validate(config *TCPHealthcheckConfiguration) error {
+	if config.ShouldFail {
+		return errors.New("The healthcheck write-completion option can't be used with should-fail")
+	}
+	send := false
+	for _, step := range config.Steps {
+		send = send || step.Send != ""
+	}
+	if !send {
+		return errors.New("The healthcheck write-completion option requires a step sending data")
+	}
+	if w.WriteTimeout < 0 || w.WriteTimeout >= config.Timeout {
+		return errors.New("The healthcheck write-timeout should be positive and lower than the timeout")
+	}
+	if w.DrainTimeout < 0 || w.DrainTimeout >= config.Timeout {
+		return errors.New("The healthcheck drain-timeout should be positive and lower than the timeout")
+	}
+	if w.DrainTimeout != 0 && !w.HalfClose {
+		return errors.New("The healthcheck drain-timeout option requires half-close")
+	}
+	return nil
+}
+
+// operationDeadline returns the deadline of an operation starting now, bounded by the
+// context deadline
+func operationDeadline(ctx context.Context, timeout Duration) time.Time {
+	deadline, ok := ctx.Deadline()
+	if timeout != 0 {
+		limit := time.Now().Add(time.Duration(timeout))
+		if !ok || limit.Before(deadline) {
+			return limit
+		}
+	}
+	return deadline
+}
+
+// isTimeout returns true if the error is a deadline error
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// write writes the payload before the write deadline. A write which does not
+// complete in time means the peer is not reading.
+func (w *TCPWriteCompletion) write(ctx context.Context, conn net.Conn, payload []byte) error {
+	if err := conn.SetWriteDeadline(operationDeadline(ctx, w.WriteTimeout)); err != nil {
+		return errors.Wrap(err, "Fail to set the write deadline")
+	}
+	n, err := conn.Write(payload)
+	if err != nil && isTimeout(err) {
+		AddMetadata(ctx, "write-completed", "false")
+		AddMetadata(ctx, "reason", "stuck-write")
+		return fmt.Errorf("write stuck, %d bytes of %d were written before the deadline", n, len(payload))
+	}
+	return err
+}
+
+// finish waits for the peer to acknowledge the data sent, then half-closes
+// the connection and waits for the peer to close it if enabled. raw is the
+// TCP connection below conn.
+func (w *TCPWriteCompletion) finish(ctx context.Context, raw net.Conn, conn net.Conn) error {
+	deadline := operationDeadline(ctx, w.WriteTimeout)
+	for {
+		queued, supported, err := sendQueue(raw)
+		if err != nil {
+			return err
+		}
+		if !supported || queued == 0 {
+			break
+		}
+		if time.Now().Add(sendQueuePollInterval).After(deadline) {
+			AddMetadata(ctx, "write-completed", "false")
+			AddMetadata(ctx, "unacked-bytes", strconv.Itoa(queued))
+			AddMetadata(ctx, "reason", "stuck-write")
+			return fmt.Errorf("write stuck, %d bytes were not acknowledged by the peer before the deadline", queued)
+		}
+		time.Sleep(sendQueuePollInterval)
+	}
+	AddMetadata(ctx, "write-completed", "true")
+	if !w.HalfClose {
+		return nil
+	}
+	closer, ok := conn.(interface{ CloseWrite() error })
+	if !ok {
+		return errors.New("The connection does not support half-close")
+	}
+	if err := closer.CloseWrite(); err != nil {
+		return errors.Wrap(err, "Fail to half-close the connection")
+	}
+	if err := conn.SetReadDeadline(operationDeadline(ctx, w.DrainTimeout)); err != nil {
+		return errors.Wrap(err, "Fail to set the read deadline")
+	}
+	// the data sent by the peer is discarded until it closes the connection
+	_, err := io.Copy(io.Discard, conn)
+	if err != nil {
+		if isTimeout(err) {
+			AddMetadata(ctx, "reason", "peer-not-reading")
+			return errors.New("the peer did not close the connection after the half-close, it is not reading")
+		}
+		return errors.Wrap(err, "Fail to wait for the peer to close the connection")
+	}
+	AddMetadata(ctx, "peer-closed", "true")
+	return nil
+}
@@ -0,0 +1,36 @@
+//go:build linux
+
+package healthcheck
+
+import (
+	"net"
+	"syscall"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+// sendQueue returns the number of bytes sent on the connection and not yet
+// acknowledged by the peer
+func sendQueue(conn net.Conn) (int, bool, error) {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return 0, false, nil
+	}
+	raw, err := tcpConn.SyscallConn()
+	if err != nil {
+		return 0, false, errors.Wrap(err, "Fail to get the raw connection")
+	}
+	var queued int32
+	var ioctlErr syscall.Errno
+	err = raw.Control(func(fd uintptr) {
+		_, _, ioctlErr = syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCOUTQ, uintptr(unsafe.Pointer(&queued)))
+	})
+	if err != nil {
+		return 0, false, errors.Wrap(err, "Fail to get the raw connection")
+	}
+	if ioctlErr != 0 {
+		return 0, false, errors.Wrap(ioctlErr, "Fail to read the send queue")
+	}
+	return int(queued), true, nil
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package healthcheck
+
+import (
+	"net"
+)
+
+// sendQueue returns false, the send queue can only be read on Linux
+func sendQueue(conn net.Conn) (int, bool, error) {
+	return 0, false, nil
+}
@@ -0,0 +1,112 @@
+package healthcheck
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// drainListener accepts the connections, and reads them until EOF if read is
+// true. The connections are never read otherwise.
+func drainListener(t *testing.T, read bool) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to start the listener: %v", err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				if !read {
+					time.Sleep(3 * time.Second)
+					return
+				}
+				_, _ = io.Copy(io.Discard, conn)
+			}(conn)
+		}
+	}()
+	return listener
+}
+
+func TestTCPExecuteWriteCompletion(t *testing.T) {
+	reading := drainListener(t, true)
+	defer reading.Close()
+	stuck := drainListener(t, false)
+	defer stuck.Close()
+	cases := []struct {
+		listener   net.Listener
+		payload    string
+		completion TCPWriteCompletion
+		reason     string
+		completed  string
+	}{
+		{listener: reading, payload: "ping\r\n", completion: TCPWriteCompletion{HalfClose: true}, completed: "true"},
+		{listener: reading, payload: strings.Repeat("a", 4*1024*1024), completion: TCPWriteCompletion{WriteTimeout: Duration(time.Second)}, completed: "true"},
+		{listener: stuck, payload: "ping\r\n", completion: TCPWriteCompletion{HalfClose: true, DrainTimeout: Duration(200 * time.Millisecond)}, completed: "true", reason: "peer-not-reading"},
+		{listener: stuck, payload: strings.Repeat("a", 64*1024*1024), completion: TCPWriteCompletion{WriteTimeout: Duration(200 * time.Millisecond)}, completed: "false", reason: "stuck-write"},
+	}
+	for i, c := range cases {
+		completion := c.completion
+		config := &TCPHealthcheckConfiguration{
+			Base: Base{
+				Name:     "foo",
+				Interval: Duration(10 * time.Second),
+			},
+			Target:          "127.0.0.1",
+			Port:            uint(c.listener.Addr().(*net.TCPAddr).Port),
+			Timeout:         Duration(2 * time.Second),
+			Steps:           []TCPStep{{Send: c.payload}},
+			WriteCompletion: &completion,
+		}
+		if err := config.Validate(); err != nil {
+			t.Fatalf("Invalid configuration %d\n%v", i, err)
+		}
+		h := NewTCPHealthcheck(zap.NewExample(), config)
+		if err := h.Initialize(); err != nil {
+			t.Fatalf("Initialization error\n%v", err)
+		}
+		ctx := WithMetadata(context.Background())
+		err := h.Execute(ctx)
+		metadata := GetMetadata(ctx)
+		if c.reason == "" && err != nil {
+			t.Fatalf("healthcheck %d error\n%v", i, err)
+		}
+		if c.reason != "" && (err == nil || metadata["reason"] != c.reason) {
+			t.Fatalf("healthcheck %d should fail with the reason %s: %v %v", i, c.reason, err, metadata)
+		}
+		if metadata["write-completed"] != c.completed {
+			t.Fatalf("Invalid write-completed metadata for healthcheck %d: %v", i, metadata)
+		}
+		if c.completion.HalfClose && c.reason == "" && metadata["peer-closed"] != "true" {
+			t.Fatalf("The peer should have closed the connection: %v", metadata)
+		}
+	}
+}
+
+func TestTCPWriteCompletionValidate(t *testing.T) {
+	invalid := []TCPHealthcheckConfiguration{
+		{Steps: nil, WriteCompletion: &TCPWriteCompletion{}},
+		{Steps: []TCPStep{{Send: "ping"}}, WriteCompletion: &TCPWriteCompletion{WriteTimeout: Duration(5 * time.Second)}},
+		{Steps: []TCPStep{{Send: "ping"}}, WriteCompletion: &TCPWriteCompletion{DrainTimeout: Duration(time.Second)}},
+		{Steps: []TCPStep{{Send: "ping"}}, WriteCompletion: &TCPWriteCompletion{HalfClose: true, DrainTimeout: Duration(-time.Second)}},
+	}
+	for i := range invalid {
+		config := invalid[i]
+		config.Base = Base{Name: "foo", Interval: Duration(10 * time.Second)}
+		config.Target = "127.0.0.1"
+		config.Port = 9000
+		config.Timeout = Duration(2 * time.Second)
+		if err := config.Validate(); err == nil {
+			t.Fatalf("The write completion %d should be invalid", i)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package healthcheck
+
+import (
+	"fmt"
+)
+
+// thresholdState tracks the consecutive results of an healthcheck, in order
+// to only report a state change once the configured threshold is reached.
+type thresholdState struct {
+	initialized bool
+	reported    bool
+	consecutive uint
+}
+
+// threshold returns the number of consecutive results needed to report the state
+func threshold(base Base, success bool) uint {
+	t := base.FailureThreshold
+	if success {
+		t = base.SuccessThreshold
+	}
+	if t == 0 {
+		return 1
+	}
+	return t
+}
+
+// apply updates the state with a new result. The first result is reported as
+// is. If the result changes the state but the threshold is not reached yet,
+// the result is modified to report the previous state, and the raw outcome is
+// added to its metadata. Returns true if the result was modified.
+func (s *thresholdState) apply(base Base, result *Result) bool {
+	if !s.initialized {
+		s.initialized = true
+		s.reported = result.Success
+		return false
+	}
+	if result.Success == s.reported {
+		s.consecutive = 0
+		return false
+	}
+	s.consecutive++
+	expected := threshold(base, result.Success)
+	if s.consecutive >= expected {
+		s.reported = result.Success
+		s.consecutive = 0
+		return false
+	}
+	if result.Metadata == nil {
+		result.Metadata = make(map[string]string)
+	}
+	result.Metadata["raw-success"] = fmt.Sprintf("%t", result.Success)
+	result.Metadata["threshold"] = fmt.Sprintf("%d/%d", s.consecutive, expected)
+	result.Success = s.reported
+	return true
+}
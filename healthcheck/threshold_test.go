@@ -0,0 +1,51 @@
+package healthcheck
+
+import (
+	"testing"
+)
+
+func TestThresholdApply(t *testing.T) {
+	base := Base{
+		FailureThreshold: 3,
+		SuccessThreshold: 2,
+	}
+	cases := []struct {
+		raw      bool
+		reported bool
+	}{
+		{raw: true, reported: true},
+		{raw: false, reported: true},
+		{raw: false, reported: true},
+		{raw: true, reported: true},
+		{raw: false, reported: true},
+		{raw: false, reported: true},
+		{raw: false, reported: false},
+		{raw: false, reported: false},
+		{raw: true, reported: false},
+		{raw: true, reported: true},
+	}
+	state := thresholdState{}
+	for i, c := range cases {
+		result := &Result{Success: c.raw}
+		modified := state.apply(base, result)
+		if result.Success != c.reported {
+			t.Fatalf("Invalid reported state for result %d: %t", i, result.Success)
+		}
+		if modified != (c.raw != c.reported) {
+			t.Fatalf("Invalid modified flag for result %d", i)
+		}
+		if modified && result.Metadata["raw-success"] != "false" && result.Metadata["raw-success"] != "true" {
+			t.Fatalf("Invalid metadata for result %d: %v", i, result.Metadata)
+		}
+	}
+}
+
+func TestThresholdDefault(t *testing.T) {
+	state := thresholdState{}
+	for _, success := range []bool{true, false, true} {
+		result := &Result{Success: success}
+		if state.apply(Base{}, result) {
+			t.Fatalf("The result should not be modified without thresholds")
+		}
+	}
+}
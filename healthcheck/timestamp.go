@@ -0,0 +1,64 @@
+package healthcheck
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// TimestampFormat the format of the results timestamps in the API and in the
+// exporters
+type TimestampFormat string
+
+const (
+	// TimestampSeconds a Unix timestamp in seconds, the default
+	TimestampSeconds TimestampFormat = "seconds"
+	// TimestampMilliseconds a Unix timestamp in milliseconds
+	TimestampMilliseconds TimestampFormat = "milliseconds"
+	// TimestampRFC3339Nano a RFC3339 string with a nanosecond precision
+	TimestampRFC3339Nano TimestampFormat = "rfc3339nano"
+)
+
+var timestampFormat atomic.Value
+
+// Validate validates the timestamp format
+func (f TimestampFormat) Validate() error {
+	switch f {
+	case "", TimestampSeconds, TimestampMilliseconds, TimestampRFC3339Nano:
+		return nil
+	}
+	return fmt.Errorf("Invalid timestamp format %s", f)
+}
+
+// SetTimestampFormat sets the format used to marshal the results timestamps
+func SetTimestampFormat(format TimestampFormat) {
+	if format == "" {
+		format = TimestampSeconds
+	}
+	timestampFormat.Store(format)
+}
+
+// currentTimestampFormat returns the configured timestamp format
+func currentTimestampFormat() TimestampFormat {
+	format, ok := timestampFormat.Load().(TimestampFormat)
+	if !ok {
+		return TimestampSeconds
+	}
+	return format
+}
+
+// format formats a timestamp
+func (f TimestampFormat) format(t time.Time) interface{} {
+	switch f {
+	case TimestampMilliseconds:
+		return t.UnixNano() / int64(time.Millisecond)
+	case TimestampRFC3339Nano:
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+	return t.Unix()
+}
+
+// FormatTimestamp formats a timestamp using the configured timestamp format
+func FormatTimestamp(t time.Time) interface{} {
+	return currentTimestampFormat().format(t)
+}
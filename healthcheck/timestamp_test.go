@@ -0,0 +1,67 @@
+package healthcheck
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestResultMarshalJSONTimestamp(t *testing.T) {
+	defer SetTimestampFormat(TimestampSeconds)
+	now := time.Date(2021, 3, 4, 10, 20, 30, 123456789, time.UTC)
+	result := Result{
+		Name:                 "foo",
+		HealthcheckTimestamp: now.Unix(),
+		Timestamp:            now,
+	}
+	cases := []struct {
+		format   TimestampFormat
+		expected interface{}
+	}{
+		{format: "", expected: float64(now.Unix())},
+		{format: TimestampSeconds, expected: float64(now.Unix())},
+		{format: TimestampMilliseconds, expected: float64(1614853230123)},
+		{format: TimestampRFC3339Nano, expected: "2021-03-04T10:20:30.123456789Z"},
+	}
+	for _, c := range cases {
+		SetTimestampFormat(c.format)
+		payload, err := json.Marshal(&result)
+		if err != nil {
+			t.Fatalf("Fail to marshal the result: %v", err)
+		}
+		var decoded map[string]interface{}
+		err = json.Unmarshal(payload, &decoded)
+		if err != nil {
+			t.Fatalf("Fail to unmarshal the result: %v", err)
+		}
+		if decoded["healthcheck-timestamp"] != c.expected {
+			t.Fatalf("Invalid timestamp for format %s: %v", c.format, decoded["healthcheck-timestamp"])
+		}
+		if decoded["name"] != "foo" {
+			t.Fatalf("Invalid name %v", decoded["name"])
+		}
+	}
+	// results built without the full precision timestamp
+	SetTimestampFormat(TimestampMilliseconds)
+	payload, err := json.Marshal(Result{HealthcheckTimestamp: now.Unix()})
+	if err != nil {
+		t.Fatalf("Fail to marshal the result: %v", err)
+	}
+	var decoded map[string]interface{}
+	err = json.Unmarshal(payload, &decoded)
+	if err != nil {
+		t.Fatalf("Fail to unmarshal the result: %v", err)
+	}
+	if decoded["healthcheck-timestamp"] != float64(now.Unix()*1000) {
+		t.Fatalf("Invalid timestamp %v", decoded["healthcheck-timestamp"])
+	}
+}
+
+func TestTimestampFormatValidate(t *testing.T) {
+	if err := TimestampFormat("minutes").Validate(); err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	if err := TimestampRFC3339Nano.Validate(); err != nil {
+		t.Fatalf("Valid format rejected: %v", err)
+	}
+}
@@ -12,8 +12,6 @@ import (
 
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
-
-	"gopkg.in/tomb.v2"
 )
 
 // TLSHealthcheckConfiguration defines a TLS healthcheck configuration
@@ -30,6 +28,12 @@ type TLSHealthcheckConfiguration struct {
 	ServerName      string   `json:"server-name,omitempty" yaml:"server-name"`
 	Insecure        bool     `json:"insecure"`
 	ExpirationDelay Duration `json:"expiration-delay" yaml:"expiration-delay"`
+	// how resolution errors of the target are reported
+	DNSFailurePolicy DNSFailurePolicy `json:"dns-failure-policy,omitempty" yaml:"dns-failure-policy,omitempty"`
+	// verifies the OCSP status of the server certificate
+	OCSP *OCSPAssertion `json:"ocsp,omitempty" yaml:"ocsp,omitempty"`
+	// the handshake timeout and duration thresholds
+	TLSHandshakeTimeouts `json:",inline" yaml:",inline"`
 }
 
 // TLSHealthcheck defines a TLS healthcheck
@@ -40,7 +44,6 @@ type TLSHealthcheck struct {
 	TLSConfig *tls.Config
 
 	Tick *time.Ticker
-	t    tomb.Tomb
 }
 
 // Validate validates the healthcheck configuration
@@ -48,6 +51,15 @@ func (config *TLSHealthcheckConfiguration) Validate() error {
 	if config.Base.Name == "" {
 		return errors.New("The healthcheck name is missing")
 	}
+	if err := validateMessageTemplate(config.Base.MessageTemplate); err != nil {
+		return err
+	}
+	if err := validateAlerting(&config.Base); err != nil {
+		return err
+	}
+	if config.Base.StartupGracePeriod < 0 {
+		return errors.New("The healthcheck startup-grace-period should be positive")
+	}
 	if config.Target == "" {
 		return errors.New("The healthcheck target is missing")
 	}
@@ -57,6 +69,17 @@ func (config *TLSHealthcheckConfiguration) Validate() error {
 	if config.Timeout == 0 {
 		return errors.New("The healthcheck timeout is missing")
 	}
+	if err := config.DNSFailurePolicy.Validate(); err != nil {
+		return err
+	}
+	if err := config.TLSHandshakeTimeouts.Validate(config.Timeout); err != nil {
+		return err
+	}
+	if config.OCSP != nil {
+		if err := config.OCSP.Validate(); err != nil {
+			return err
+		}
+	}
 	if !config.Base.OneOff {
 		if config.Base.Interval < Duration(2*time.Second) {
 			return errors.New("The healthcheck interval should be greater than 2 second")
@@ -64,6 +87,12 @@ func (config *TLSHealthcheckConfiguration) Validate() error {
 		if config.Base.Interval < config.Timeout {
 			return errors.New("The healthcheck interval should be greater than the timeout")
 		}
+		if err := config.Base.AdaptiveInterval.Validate(config.Base.Interval, config.Timeout); err != nil {
+			return err
+		}
+		if err := config.Base.AdaptiveTimeout.Validate(config.Base.Interval); err != nil {
+			return err
+		}
 	}
 	if !((config.Key != "" && config.Cert != "") ||
 		(config.Key == "" && config.Cert == "")) {
@@ -77,6 +106,16 @@ func (h *TLSHealthcheck) Base() Base {
 	return h.Config.Base
 }
 
+// timeout returns the healthcheck timeout
+func (h *TLSHealthcheck) timeout() time.Duration {
+	return time.Duration(h.Config.Timeout)
+}
+
+// target returns the healthcheck target
+func (h *TLSHealthcheck) target() string {
+	return h.Config.Target
+}
+
 // SetSource set the healthcheck source
 func (h *TLSHealthcheck) SetSource(source string) {
 	h.Config.Base.Source = source
@@ -142,11 +181,9 @@ func (h *TLSHealthcheck) GetConfig() interface{} {
 
 // LogError logs an error with context
 func (h *TLSHealthcheck) LogError(err error, message string) {
-	h.Logger.Error(err.Error(),
-		zap.String("extra", message),
+	logError(h.Logger, h.Config.Base.Name, err, message,
 		zap.String("target", h.Config.Target),
-		zap.Uint("port", h.Config.Port),
-		zap.String("name", h.Config.Base.Name))
+		zap.Uint("port", h.Config.Port))
 }
 
 // LogDebug logs a message with context
@@ -166,10 +203,10 @@ func (h *TLSHealthcheck) LogInfo(message string) {
 }
 
 // Execute executes an healthcheck on the given target
-func (h *TLSHealthcheck) Execute() error {
+func (h *TLSHealthcheck) Execute(ctx context.Context) error {
 	h.LogDebug("start executing healthcheck")
+	timeout := executionTimeout(ctx, time.Duration(h.Config.Timeout))
 	dialer := net.Dialer{}
-	ctx := h.t.Context(context.TODO())
 	if h.Config.SourceIP != nil {
 		srcIP := net.IP(h.Config.SourceIP).String()
 		addr, err := net.ResolveTCPAddr("tcp", fmt.Sprintf("%s:0", srcIP))
@@ -178,36 +215,45 @@ func (h *TLSHealthcheck) Execute() error {
 		}
 		dialer = net.Dialer{
 			LocalAddr: addr,
-			Timeout:   time.Duration(h.Config.Timeout),
+			Timeout:   timeout,
 		}
 	}
 
-	timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(h.Config.Timeout))
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
-	conn, err := dialer.DialContext(timeoutCtx, "tcp", h.URL)
+	conn, err := dialAliased(timeoutCtx, dialer.DialContext, "tcp", h.URL)
+	if handled, policyErr := applyDNSFailurePolicy(ctx, h.Config.DNSFailurePolicy, err); handled {
+		return policyErr
+	}
 	if err != nil {
 		return errors.Wrapf(err, "TLS connection failed on %s", h.URL)
 	}
 	defer conn.Close()
-	tlsConn := tls.Client(conn, h.TLSConfig)
-	defer tlsConn.Close()
-	err = tlsConn.Handshake()
+	addIPMetadata(ctx, conn.RemoteAddr())
+	tlsConn, err := tlsHandshake(timeoutCtx, conn, h.TLSConfig, &h.Config.TLSHandshakeTimeouts)
 	if err != nil {
-		return errors.Wrapf(err, "TLS handshake failed on %s", h.URL)
+		return errors.Wrapf(err, "TLS healthcheck failed on %s", h.URL)
 	}
-	if h.Config.ExpirationDelay != 0 {
-		state := tlsConn.ConnectionState()
-		expirationTime := time.Time{}
-		for _, cert := range state.PeerCertificates {
-			if (expirationTime.IsZero() || cert.NotAfter.Before(expirationTime)) && !cert.NotAfter.IsZero() {
-				expirationTime = cert.NotAfter
-			}
+	defer tlsConn.Close()
+	state := tlsConn.ConnectionState()
+	expirationTime := time.Time{}
+	for _, cert := range state.PeerCertificates {
+		if (expirationTime.IsZero() || cert.NotAfter.Before(expirationTime)) && !cert.NotAfter.IsZero() {
+			expirationTime = cert.NotAfter
 		}
+	}
+	if !expirationTime.IsZero() {
+		AddMetadata(ctx, "certificate-expiration", expirationTime.UTC().Format(time.RFC3339))
+	}
+	if h.Config.ExpirationDelay != 0 {
 		expirationTimeLimit := time.Now().Add(time.Duration(h.Config.ExpirationDelay))
 		if expirationTime.Before(expirationTimeLimit) {
 			return fmt.Errorf("The certificate for %s will expire at %s", h.URL, expirationTime.String())
 		}
 	}
+	if err := checkOCSP(timeoutCtx, h.Config.OCSP, &state); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -234,6 +280,11 @@ func (in *TLSHealthcheckConfiguration) DeepCopyInto(out *TLSHealthcheckConfigura
 		*out = make(IP, len(*in))
 		copy(*out, *in)
 	}
+	if in.OCSP != nil {
+		in, out := &in.OCSP, &out.OCSP
+		*out = new(OCSPAssertion)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSHealthcheckConfiguration.
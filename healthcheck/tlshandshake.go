@@ -0,0 +1,90 @@
+package healthcheck
+
+import (
+	"context"
+	cryptotls "crypto/tls"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TLSHandshakeTimeouts the timeout of the TLS handshakes, and the handshake
+// durations above which the result has a warning, or is failed. The
+// handshake duration is reported in the tls-duration metadata.
+type TLSHandshakeTimeouts struct {
+	// the healthcheck timeout is used if not set
+	HandshakeTimeout      Duration `json:"handshake-timeout,omitempty" yaml:"handshake-timeout,omitempty"`
+	WarnHandshakeTime     Duration `json:"warn-handshake-time,omitempty" yaml:"warn-handshake-time,omitempty"`
+	CriticalHandshakeTime Duration `json:"critical-handshake-time,omitempty" yaml:"critical-handshake-time,omitempty"`
+}
+
+// Validate validates the handshake timeouts against the healthcheck timeout
+func (t *TLSHandshakeTimeouts) Validate(timeout Duration) error {
+	if t.HandshakeTimeout < 0 || (timeout != 0 && t.HandshakeTimeout > timeout) {
+		return errors.New("The healthcheck handshake-timeout should be positive and lower than the timeout")
+	}
+	if t.WarnHandshakeTime < 0 || t.CriticalHandshakeTime < 0 {
+		return errors.New("The healthcheck handshake time thresholds should be positive")
+	}
+	limit := timeout
+	if t.HandshakeTimeout != 0 {
+		limit = t.HandshakeTimeout
+	}
+	if limit != 0 && (t.WarnHandshakeTime >= limit || t.CriticalHandshakeTime >= limit) {
+		return errors.New("The healthcheck handshake time thresholds should be lower than the handshake timeout")
+	}
+	if t.WarnHandshakeTime != 0 && t.CriticalHandshakeTime != 0 && t.WarnHandshakeTime >= t.CriticalHandshakeTime {
+		return errors.New("The healthcheck warn-handshake-time should be lower than critical-handshake-time")
+	}
+	return nil
+}
+
+// check compares the handshake duration to the thresholds
+func (t *TLSHandshakeTimeouts) check(ctx context.Context, duration time.Duration) error {
+	critical := time.Duration(t.CriticalHandshakeTime)
+	warn := time.Duration(t.WarnHandshakeTime)
+	if critical != 0 && duration >= critical {
+		AddMetadata(ctx, "reason", "slow-tls-handshake")
+		return fmt.Errorf("TLS handshake completed in %s, above the critical threshold %s", duration, critical)
+	}
+	if warn != 0 && duration >= warn {
+		AddMetadata(ctx, "warning", fmt.Sprintf("TLS handshake completed in %s, above the warning threshold %s", duration, warn))
+	}
+	return nil
+}
+
+// tlsHandshake wraps a TCP connection in TLS, the negotiated version and the
+// handshake duration are added to the metadata. The handshake is bounded by
+// the handshake timeout if set.
+func tlsHandshake(ctx context.Context, conn net.Conn, tlsConfig *cryptotls.Config, timeouts *TLSHandshakeTimeouts) (*cryptotls.Conn, error) {
+	handshakeCtx := ctx
+	if timeouts != nil && timeouts.HandshakeTimeout != 0 {
+		var cancel context.CancelFunc
+		handshakeCtx, cancel = context.WithTimeout(ctx, time.Duration(timeouts.HandshakeTimeout))
+		defer cancel()
+	}
+	tlsConn := cryptotls.Client(conn, tlsConfig)
+	start := time.Now()
+	err := tlsConn.HandshakeContext(handshakeCtx)
+	duration := time.Since(start)
+	if err != nil {
+		AddMetadata(ctx, "reason", "tls-handshake")
+		if handshakeCtx.Err() != nil && ctx.Err() == nil {
+			AddMetadata(ctx, "timeout-phase", phaseTLS)
+			return nil, fmt.Errorf("TLS handshake exceeded its timeout of %s", time.Duration(timeouts.HandshakeTimeout))
+		}
+		return nil, errors.Wrap(err, "TLS handshake failed")
+	}
+	AddMetadata(ctx, fmt.Sprintf("%s-duration", phaseTLS), fmt.Sprintf("%f", duration.Seconds()))
+	state := tlsConn.ConnectionState()
+	AddMetadata(ctx, "tls-version", tlsVersionName(state.Version))
+	AddMetadata(ctx, "tls-cipher-suite", cryptotls.CipherSuiteName(state.CipherSuite))
+	if timeouts != nil {
+		if err := timeouts.check(ctx, duration); err != nil {
+			return nil, err
+		}
+	}
+	return tlsConn, nil
+}
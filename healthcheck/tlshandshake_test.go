@@ -0,0 +1,150 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestTLSHandshakeTimeoutsValidate(t *testing.T) {
+	valid := []TLSHandshakeTimeouts{
+		{},
+		{HandshakeTimeout: Duration(time.Second)},
+		{HandshakeTimeout: Duration(time.Second), WarnHandshakeTime: Duration(100 * time.Millisecond), CriticalHandshakeTime: Duration(500 * time.Millisecond)},
+	}
+	for i, c := range valid {
+		if err := c.Validate(Duration(3 * time.Second)); err != nil {
+			t.Fatalf("Validation error for the case %d\n%v", i, err)
+		}
+	}
+	invalid := []TLSHandshakeTimeouts{
+		{HandshakeTimeout: Duration(5 * time.Second)},
+		{HandshakeTimeout: Duration(-time.Second)},
+		{HandshakeTimeout: Duration(time.Second), CriticalHandshakeTime: Duration(2 * time.Second)},
+		{WarnHandshakeTime: Duration(time.Second), CriticalHandshakeTime: Duration(500 * time.Millisecond)},
+	}
+	for i, c := range invalid {
+		if err := c.Validate(Duration(3 * time.Second)); err == nil {
+			t.Fatalf("Was expecting an error for the case %d", i)
+		}
+	}
+}
+
+func TestTLSExecuteHandshakeTimeout(t *testing.T) {
+	// the server never answers the client hello
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to listen\n%v", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		time.Sleep(3 * time.Second)
+	}()
+	h := NewTLSHealthcheck(zap.NewExample(), &TLSHealthcheckConfiguration{
+		Base:    Base{Name: "foo", OneOff: true},
+		Port:    uint(listener.Addr().(*net.TCPAddr).Port),
+		Target:  "127.0.0.1",
+		Timeout: Duration(time.Second * 2),
+		TLSHandshakeTimeouts: TLSHandshakeTimeouts{
+			HandshakeTimeout: Duration(200 * time.Millisecond),
+		},
+	})
+	if err := h.Initialize(); err != nil {
+		t.Fatalf("Initialization error\n%v", err)
+	}
+	ctx := WithMetadata(context.Background())
+	start := time.Now()
+	err = h.Execute(ctx)
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("The handshake should be bounded by the handshake timeout")
+	}
+	if GetMetadata(ctx)["timeout-phase"] != "tls" {
+		t.Fatalf("Invalid metadata %v", GetMetadata(ctx))
+	}
+}
+
+func TestTLSExecuteHandshakeThresholds(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	port := uint(ts.Listener.Addr().(*net.TCPAddr).Port)
+	cases := []struct {
+		timeouts TLSHandshakeTimeouts
+		success  bool
+		warning  bool
+	}{
+		{timeouts: TLSHandshakeTimeouts{}, success: true},
+		{timeouts: TLSHandshakeTimeouts{WarnHandshakeTime: Duration(time.Nanosecond)}, success: true, warning: true},
+		{timeouts: TLSHandshakeTimeouts{CriticalHandshakeTime: Duration(time.Nanosecond)}, success: false},
+	}
+	for i, c := range cases {
+		h := NewTLSHealthcheck(zap.NewExample(), &TLSHealthcheckConfiguration{
+			Base:                 Base{Name: "foo", OneOff: true},
+			Port:                 port,
+			Target:               "127.0.0.1",
+			Timeout:              Duration(time.Second * 2),
+			Insecure:             true,
+			TLSHandshakeTimeouts: c.timeouts,
+		})
+		if err := h.Initialize(); err != nil {
+			t.Fatalf("Initialization error\n%v", err)
+		}
+		ctx := WithMetadata(context.Background())
+		err := h.Execute(ctx)
+		metadata := GetMetadata(ctx)
+		if c.success && err != nil {
+			t.Fatalf("healthcheck %d error\n%v", i, err)
+		}
+		if !c.success && (err == nil || metadata["reason"] != "slow-tls-handshake") {
+			t.Fatalf("healthcheck %d should fail with a slow handshake: %v", i, metadata)
+		}
+		if _, ok := metadata["tls-duration"]; !ok {
+			t.Fatalf("The handshake duration is missing from the metadata %v", metadata)
+		}
+		if _, ok := metadata["warning"]; ok != c.warning {
+			t.Fatalf("Invalid warning for the healthcheck %d: %v", i, metadata)
+		}
+	}
+}
+
+func TestHTTPExecuteHandshakeThreshold(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	h := NewHTTPHealthcheck(zap.NewExample(), &HTTPHealthcheckConfiguration{
+		Base:        Base{Name: "foo", OneOff: true},
+		ValidStatus: []uint{200},
+		Target:      "127.0.0.1",
+		Port:        uint(ts.Listener.Addr().(*net.TCPAddr).Port),
+		Protocol:    HTTPS,
+		Insecure:    true,
+		Timeout:     Duration(time.Second * 2),
+		TLSHandshakeTimeouts: TLSHandshakeTimeouts{
+			HandshakeTimeout:      Duration(time.Second),
+			CriticalHandshakeTime: Duration(time.Nanosecond),
+		},
+	})
+	if err := h.Initialize(); err != nil {
+		t.Fatalf("Initialization error\n%v", err)
+	}
+	ctx := WithMetadata(context.Background())
+	err := h.Execute(ctx)
+	if err == nil || GetMetadata(ctx)["reason"] != "slow-tls-handshake" {
+		t.Fatalf("The healthcheck should fail with a slow handshake: %v", GetMetadata(ctx))
+	}
+}
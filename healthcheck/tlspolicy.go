@@ -0,0 +1,81 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// TLSVersion a TLS version, for example "1.2"
+type TLSVersion uint16
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// UnmarshalText unmarshal a TLS version
+func (v *TLSVersion) UnmarshalText(text []byte) error {
+	s := strings.TrimPrefix(string(text), "TLS ")
+	version, ok := tlsVersions[s]
+	if !ok {
+		return fmt.Errorf("Invalid TLS version %s, valid versions are 1.0, 1.1, 1.2 and 1.3", string(text))
+	}
+	*v = TLSVersion(version)
+	return nil
+}
+
+// MarshalText marshal a TLS version
+func (v TLSVersion) MarshalText() ([]byte, error) {
+	if v == 0 {
+		return []byte{}, nil
+	}
+	for name, version := range tlsVersions {
+		if uint16(v) == version {
+			return []byte(name), nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown TLS version %d", v)
+}
+
+// validateForbiddenCiphers verifies that the cipher suites names are known
+func validateForbiddenCiphers(ciphers []string) error {
+	known := make(map[string]bool)
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		known[suite.Name] = true
+	}
+	for _, cipher := range ciphers {
+		if !known[cipher] {
+			return fmt.Errorf("Unknown cipher suite %s", cipher)
+		}
+	}
+	return nil
+}
+
+// checkTLSPolicy verifies the negotiated TLS parameters
+func checkTLSPolicy(ctx context.Context, minVersion TLSVersion, forbiddenCiphers []string, state *tls.ConnectionState) error {
+	if minVersion == 0 && len(forbiddenCiphers) == 0 {
+		return nil
+	}
+	if state == nil {
+		AddMetadata(ctx, "reason", "tls-policy")
+		return errors.New("The TLS policy can't be verified, no TLS connection")
+	}
+	if state.Version < uint16(minVersion) {
+		AddMetadata(ctx, "reason", "tls-policy")
+		return fmt.Errorf("Negotiated TLS version %s is lower than %s", tlsVersionName(state.Version), tlsVersionName(uint16(minVersion)))
+	}
+	cipher := tls.CipherSuiteName(state.CipherSuite)
+	for _, forbidden := range forbiddenCiphers {
+		if cipher == forbidden {
+			AddMetadata(ctx, "reason", "tls-policy")
+			return fmt.Errorf("Negotiated cipher suite %s is forbidden", cipher)
+		}
+	}
+	return nil
+}
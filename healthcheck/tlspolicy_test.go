@@ -0,0 +1,89 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+)
+
+func TestHTTPExecuteTLSPolicy(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts.TLS = &tls.Config{
+		MaxVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+	}
+	ts.StartTLS()
+	defer ts.Close()
+	port, err := strconv.ParseUint(strings.Split(ts.URL, ":")[2], 10, 16)
+	if err != nil {
+		t.Fatalf("error getting HTTP server port :\n%v", err)
+	}
+	cases := []struct {
+		minVersion TLSVersion
+		forbidden  []string
+		expected   string
+	}{
+		{minVersion: TLSVersion(tls.VersionTLS12)},
+		{minVersion: TLSVersion(tls.VersionTLS13), expected: "Negotiated TLS version TLS 1.2 is lower than TLS 1.3"},
+		{forbidden: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}, expected: "is forbidden"},
+	}
+	for _, c := range cases {
+		h := NewHTTPHealthcheck(zap.NewExample(), &HTTPHealthcheckConfiguration{
+			ValidStatus:      []uint{200},
+			Port:             uint(port),
+			Target:           "127.0.0.1",
+			Protocol:         HTTPS,
+			Insecure:         true,
+			Path:             "/",
+			Timeout:          Duration(time.Second * 2),
+			MinTLSVersion:    c.minVersion,
+			ForbiddenCiphers: c.forbidden,
+		})
+		err = h.Initialize()
+		if err != nil {
+			t.Fatalf("Initialization error :\n%v", err)
+		}
+		ctx := WithMetadata(context.Background())
+		err = h.Execute(ctx)
+		metadata := GetMetadata(ctx)
+		if metadata["tls-version"] != "TLS 1.2" || metadata["tls-cipher-suite"] != "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256" {
+			t.Fatalf("Invalid metadata %v", metadata)
+		}
+		if c.expected == "" && err != nil {
+			t.Fatalf("healthcheck error :\n%v", err)
+		}
+		if c.expected != "" && (err == nil || !strings.Contains(err.Error(), c.expected) || metadata["reason"] != "tls-policy") {
+			t.Fatalf("Was expecting an error containing %s: %v %v", c.expected, err, metadata)
+		}
+	}
+}
+
+func TestTLSPolicyConfiguration(t *testing.T) {
+	var config struct {
+		Version TLSVersion `yaml:"version"`
+	}
+	err := yaml.Unmarshal([]byte("version: \"1.3\""), &config)
+	if err != nil || config.Version != TLSVersion(tls.VersionTLS13) {
+		t.Fatalf("Invalid TLS version %v: %v", config.Version, err)
+	}
+	err = yaml.Unmarshal([]byte("version: \"1.4\""), &config)
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	if err := validateForbiddenCiphers([]string{"TLS_RSA_WITH_RC4_128_SHA"}); err != nil {
+		t.Fatalf("The cipher should be known: %v", err)
+	}
+	if err := validateForbiddenCiphers([]string{"RC4"}); err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
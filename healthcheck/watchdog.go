@@ -0,0 +1,77 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// watchdogGrace is added to the healthcheck timeout to compute the maximum
+// lifetime of an execution
+const watchdogGrace = 5 * time.Second
+
+// timeoutGetter is implemented by the healthchecks having a timeout
+type timeoutGetter interface {
+	timeout() time.Duration
+}
+
+// maxExecutionTime returns the duration after which an execution is
+// considered stuck
+func maxExecutionTime(healthcheck Healthcheck) time.Duration {
+	base := healthcheck.Base()
+	if base.MaxExecutionTime != 0 {
+		return time.Duration(base.MaxExecutionTime)
+	}
+	if t, ok := healthcheck.(timeoutGetter); ok && t.timeout() != 0 {
+		timeout := t.timeout()
+		if base.AdaptiveTimeout != nil && time.Duration(base.AdaptiveTimeout.MaxTimeout) > timeout {
+			timeout = time.Duration(base.AdaptiveTimeout.MaxTimeout)
+		}
+		return timeout + watchdogGrace
+	}
+	if base.Interval != 0 {
+		return time.Duration(base.Interval) + watchdogGrace
+	}
+	return 0
+}
+
+// watch executes an healthcheck, abandoning the execution if it does not
+// return before its maximum lifetime. The abandoned goroutine is tracked by
+// the stuck executions gauge until it returns.
+func (c *Component) watch(ctx context.Context, healthcheck Healthcheck) error {
+	limit := maxExecutionTime(healthcheck)
+	if limit == 0 {
+		return c.execute(ctx, healthcheck)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.execute(ctx, healthcheck)
+	}()
+	timer := time.NewTimer(limit)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		cancel()
+		return err
+	case <-timer.C:
+	}
+	name := healthcheck.Base().Name
+	gauge := c.stuckGauge.With(prom.Labels{"name": name})
+	gauge.Inc()
+	c.Logger.Error("healthcheck execution stuck, abandoning it",
+		zap.String("name", name),
+		zap.Duration("max-execution-time", limit))
+	AddMetadata(ctx, "reason", "stuck")
+	cancel()
+	go func() {
+		<-done
+		gauge.Dec()
+		c.Logger.Info("stuck healthcheck execution returned",
+			zap.String("name", name))
+	}()
+	return fmt.Errorf("healthcheck execution timeout: still running after %s", limit)
+}
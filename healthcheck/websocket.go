@@ -0,0 +1,370 @@
+package healthcheck
+
+import (
+	"bufio"
+	"context"
+	cryptotls "crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/tls"
+)
+
+// WebSocketHealthcheckConfiguration defines a WebSocket healthcheck configuration
+type WebSocketHealthcheckConfiguration struct {
+	Base `json:",inline" yaml:",inline"`
+	// the ws:// or wss:// URL of the endpoint
+	URL string `json:"url"`
+	// the subprotocol requested to the server, which should select it
+	Subprotocol string            `json:"subprotocol,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	// a text message sent once the connection is established
+	Send string `json:"send,omitempty"`
+	// a message matching this regexp should be received
+	Expect *Regexp `json:"expect,omitempty"`
+	// sends a ping frame and waits for the pong frame
+	Ping       bool     `json:"ping"`
+	Timeout    Duration `json:"timeout"`
+	Key        string   `json:"key,omitempty"`
+	Cert       string   `json:"cert,omitempty"`
+	Cacert     string   `json:"cacert,omitempty"`
+	Insecure   bool     `json:"insecure"`
+	ShouldFail bool     `json:"should-fail" yaml:"should-fail"`
+	// the TLS handshake timeout and duration thresholds of the wss urls
+	TLSHandshakeTimeouts `json:",inline" yaml:",inline"`
+}
+
+// WebSocketHealthcheck defines a WebSocket healthcheck
+type WebSocketHealthcheck struct {
+	Logger    *zap.Logger
+	Config    *WebSocketHealthcheckConfiguration
+	URL       *url.URL
+	TLSConfig *cryptotls.Config
+}
+
+// Validate validates the healthcheck configuration
+func (config *WebSocketHealthcheckConfiguration) Validate() error {
+	if config.Base.Name == "" {
+		return errors.New("The healthcheck name is missing")
+	}
+	if err := validateMessageTemplate(config.Base.MessageTemplate); err != nil {
+		return err
+	}
+	if err := validateAlerting(&config.Base); err != nil {
+		return err
+	}
+	if config.Base.StartupGracePeriod < 0 {
+		return errors.New("The healthcheck startup-grace-period should be positive")
+	}
+	if config.URL == "" {
+		return errors.New("The healthcheck url is missing")
+	}
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid healthcheck url %s", config.URL)
+	}
+	if u.Scheme != "ws" && u.Scheme != "wss" {
+		return fmt.Errorf("The healthcheck url %s should use the ws or wss scheme", config.URL)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("The healthcheck url %s has no host", config.URL)
+	}
+	if config.Timeout == 0 {
+		return errors.New("The healthcheck timeout is missing")
+	}
+	if !config.Base.OneOff {
+		if config.Base.Interval < Duration(2*time.Second) {
+			return errors.New("The healthcheck interval should be greater than 2 second")
+		}
+		if config.Base.Interval < config.Timeout {
+			return errors.New("The healthcheck interval should be greater than the timeout")
+		}
+		if err := config.Base.AdaptiveInterval.Validate(config.Base.Interval, config.Timeout); err != nil {
+			return err
+		}
+		if err := config.Base.AdaptiveTimeout.Validate(config.Base.Interval); err != nil {
+			return err
+		}
+	}
+	if !((config.Key != "" && config.Cert != "") ||
+		(config.Key == "" && config.Cert == "")) {
+		return errors.New("Invalid certificates")
+	}
+	if u.Scheme != "wss" && (config.Key != "" || config.Cacert != "" || config.Insecure || config.TLSHandshakeTimeouts != (TLSHandshakeTimeouts{})) {
+		return errors.New("The TLS options require a wss url")
+	}
+	if err := config.TLSHandshakeTimeouts.Validate(config.Timeout); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Base get the base configuration
+func (h *WebSocketHealthcheck) Base() Base {
+	return h.Config.Base
+}
+
+// timeout returns the healthcheck timeout
+func (h *WebSocketHealthcheck) timeout() time.Duration {
+	return time.Duration(h.Config.Timeout)
+}
+
+// target returns the healthcheck target
+func (h *WebSocketHealthcheck) target() string {
+	return h.URL.Hostname()
+}
+
+// SetSource set the healthcheck source
+func (h *WebSocketHealthcheck) SetSource(source string) {
+	h.Config.Base.Source = source
+}
+
+// Summary returns an healthcheck summary
+func (h *WebSocketHealthcheck) Summary() string {
+	summary := ""
+	if h.Config.Base.Description != "" {
+		summary = fmt.Sprintf("%s on %s", h.Config.Base.Description, h.Config.URL)
+
+	} else {
+		summary = fmt.Sprintf("on %s", h.Config.URL)
+	}
+
+	return summary
+}
+
+// Initialize the healthcheck.
+func (h *WebSocketHealthcheck) Initialize() error {
+	u, err := url.Parse(h.Config.URL)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid healthcheck url %s", h.Config.URL)
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	h.URL = u
+	if u.Scheme == "wss" {
+		tlsConfig, err := tls.GetTLSConfig(h.Config.Key, h.Config.Cert, h.Config.Cacert, h.Config.Insecure)
+		if err != nil {
+			return err
+		}
+		tlsConfig.ServerName = u.Hostname()
+		h.TLSConfig = tlsConfig
+	}
+	return nil
+}
+
+// GetConfig get the config
+func (h *WebSocketHealthcheck) GetConfig() interface{} {
+	return h.Config
+}
+
+// LogError logs an error with context
+func (h *WebSocketHealthcheck) LogError(err error, message string) {
+	logError(h.Logger, h.Config.Base.Name, err, message,
+		zap.String("url", h.Config.URL))
+}
+
+// LogDebug logs a message with context
+func (h *WebSocketHealthcheck) LogDebug(message string) {
+	h.Logger.Debug(message,
+		zap.String("url", h.Config.URL),
+		zap.String("name", h.Config.Base.Name))
+}
+
+// LogInfo logs a message with context
+func (h *WebSocketHealthcheck) LogInfo(message string) {
+	h.Logger.Info(message,
+		zap.String("url", h.Config.URL),
+		zap.String("name", h.Config.Base.Name))
+}
+
+// address returns the host:port of the endpoint
+func (h *WebSocketHealthcheck) address() string {
+	port := h.URL.Port()
+	if port == "" {
+		port = "80"
+		if h.URL.Scheme == "wss" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(h.URL.Hostname(), port)
+}
+
+// handshake performs the opening handshake on the connection
+func (h *WebSocketHealthcheck) handshake(ctx context.Context, conn net.Conn) (*wsConn, error) {
+	key, err := wsKey()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("GET", h.URL.String(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Fail to build the upgrade request")
+	}
+	for k, v := range h.Config.Headers {
+		req.Header.Set(k, v)
+		if strings.EqualFold(k, "host") {
+			req.Host = v
+		}
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if h.Config.Subprotocol != "" {
+		req.Header.Set("Sec-WebSocket-Protocol", h.Config.Subprotocol)
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, errors.Wrap(err, "Fail to send the upgrade request")
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Fail to read the upgrade response")
+	}
+	resp.Body.Close()
+	AddMetadata(ctx, "status-code", strconv.Itoa(resp.StatusCode))
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, fmt.Errorf("The server returned the status %d instead of upgrading the connection", resp.StatusCode)
+	}
+	if !strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") {
+		return nil, errors.New("Invalid Upgrade header in the server response")
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != wsAccept(key) {
+		return nil, errors.New("Invalid Sec-WebSocket-Accept header in the server response")
+	}
+	if h.Config.Subprotocol != "" && resp.Header.Get("Sec-WebSocket-Protocol") != h.Config.Subprotocol {
+		return nil, fmt.Errorf("The server did not select the subprotocol %s", h.Config.Subprotocol)
+	}
+	return &wsConn{conn: conn, reader: reader}, nil
+}
+
+// exchange sends the ping frame and the message, and waits for the replies
+func (h *WebSocketHealthcheck) exchange(ws *wsConn) error {
+	if h.Config.Ping {
+		if err := ws.writeFrame(wsPing, []byte("cabourotte")); err != nil {
+			return err
+		}
+		for {
+			opcode, _, err := ws.read()
+			if err != nil {
+				return err
+			}
+			if opcode == wsPong {
+				break
+			}
+		}
+	}
+	if h.Config.Send != "" {
+		if err := ws.writeFrame(wsText, []byte(h.Config.Send)); err != nil {
+			return err
+		}
+	}
+	if h.Config.Expect != nil {
+		r := regexp.Regexp(*h.Config.Expect)
+		for {
+			opcode, message, err := ws.read()
+			if err != nil {
+				return errors.Wrap(err, "The expected message was not received")
+			}
+			if opcode != wsPong && r.Match(message) {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// check connects to the endpoint and executes the exchange
+func (h *WebSocketHealthcheck) check(ctx context.Context) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, executionTimeout(ctx, h.timeout()))
+	defer cancel()
+	dialer := net.Dialer{}
+	conn, err := dialAliased(timeoutCtx, dialer.DialContext, "tcp", h.address())
+	if err != nil {
+		return errors.Wrapf(err, "WebSocket connection failed on %s", h.Config.URL)
+	}
+	defer conn.Close()
+	addIPMetadata(ctx, conn.RemoteAddr())
+	deadline, _ := timeoutCtx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return errors.Wrap(err, "Fail to set the connection deadline")
+	}
+	if h.TLSConfig != nil {
+		conn, err = tlsHandshake(timeoutCtx, conn, h.TLSConfig, &h.Config.TLSHandshakeTimeouts)
+		if err != nil {
+			return errors.Wrapf(err, "WebSocket healthcheck failed on %s", h.Config.URL)
+		}
+	}
+	ws, err := h.handshake(ctx, conn)
+	if err != nil {
+		AddMetadata(ctx, "reason", "websocket-handshake")
+		return errors.Wrapf(err, "WebSocket handshake failed on %s", h.Config.URL)
+	}
+	defer ws.close()
+	if err := h.exchange(ws); err != nil {
+		return errors.Wrapf(err, "WebSocket healthcheck failed on %s", h.Config.URL)
+	}
+	return nil
+}
+
+// Execute executes an healthcheck on the given target
+func (h *WebSocketHealthcheck) Execute(ctx context.Context) error {
+	h.LogDebug("start executing healthcheck")
+	err := h.check(ctx)
+	if h.Config.ShouldFail {
+		if err == nil {
+			return fmt.Errorf("WebSocket check is successful on %s but an error was expected", h.Config.URL)
+		}
+		return nil
+	}
+	return err
+}
+
+// NewWebSocketHealthcheck creates a WebSocket healthcheck from a logger and a configuration
+func NewWebSocketHealthcheck(logger *zap.Logger, config *WebSocketHealthcheckConfiguration) *WebSocketHealthcheck {
+	return &WebSocketHealthcheck{
+		Logger: logger,
+		Config: config,
+	}
+}
+
+// MarshalJSON marshal to json a WebSocket healthcheck
+func (h *WebSocketHealthcheck) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.Config)
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebSocketHealthcheckConfiguration) DeepCopyInto(out *WebSocketHealthcheckConfiguration) {
+	*out = *in
+	in.Base.DeepCopyInto(&out.Base)
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Expect != nil {
+		out.Expect = in.Expect.DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebSocketHealthcheckConfiguration.
+func (in *WebSocketHealthcheckConfiguration) DeepCopy() *WebSocketHealthcheckConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(WebSocketHealthcheckConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
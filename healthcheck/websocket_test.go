@@ -0,0 +1,121 @@
+package healthcheck
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// wsServerFrame writes an unmasked frame
+func wsServerFrame(w *bufio.Writer, opcode byte, payload []byte) {
+	w.WriteByte(0x80 | opcode)
+	w.WriteByte(byte(len(payload)))
+	w.Write(payload)
+	w.Flush()
+}
+
+// wsEchoHandler upgrades the connection and echoes the text messages
+func wsEchoHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/ws" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	hijacker := w.(http.Hijacker)
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + wsAccept(key) + "\r\n")
+	if r.Header.Get("Sec-WebSocket-Protocol") == "chat" {
+		rw.WriteString("Sec-WebSocket-Protocol: chat\r\n")
+	}
+	rw.WriteString("\r\n")
+	rw.Flush()
+	ws := &wsConn{conn: conn, reader: rw.Reader}
+	for {
+		_, opcode, payload, err := ws.readFrame()
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case wsPing:
+			wsServerFrame(rw.Writer, wsPong, payload)
+		case wsText:
+			wsServerFrame(rw.Writer, wsText, []byte("echo: "+string(payload)))
+		case wsClose:
+			return
+		}
+	}
+}
+
+func TestWebSocketExecute(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(wsEchoHandler))
+	defer ts.Close()
+	url := "ws" + strings.TrimPrefix(ts.URL, "http")
+	expect := Regexp(*regexp.MustCompile("echo: hello"))
+	wrong := Regexp(*regexp.MustCompile("goodbye"))
+	cases := []struct {
+		config  WebSocketHealthcheckConfiguration
+		success bool
+	}{
+		{config: WebSocketHealthcheckConfiguration{URL: url + "/ws"}, success: true},
+		{config: WebSocketHealthcheckConfiguration{URL: url + "/ws", Ping: true}, success: true},
+		{config: WebSocketHealthcheckConfiguration{URL: url + "/ws", Subprotocol: "chat", Send: "hello", Expect: &expect}, success: true},
+		{config: WebSocketHealthcheckConfiguration{URL: url + "/ws", Subprotocol: "mqtt"}, success: false},
+		{config: WebSocketHealthcheckConfiguration{URL: url + "/ws", Send: "hello", Expect: &wrong}, success: false},
+		{config: WebSocketHealthcheckConfiguration{URL: url + "/notfound"}, success: false},
+		{config: WebSocketHealthcheckConfiguration{URL: url + "/notfound", ShouldFail: true}, success: true},
+		{config: WebSocketHealthcheckConfiguration{URL: url + "/ws", ShouldFail: true}, success: false},
+	}
+	for i, c := range cases {
+		config := c.config
+		config.Base = Base{Name: "foo", Interval: Duration(time.Second * 5)}
+		config.Timeout = Duration(time.Second)
+		h := NewWebSocketHealthcheck(zap.NewExample(), &config)
+		if err := h.Initialize(); err != nil {
+			t.Fatalf("Initialization error\n%v", err)
+		}
+		ctx := WithMetadata(context.Background())
+		err := h.Execute(ctx)
+		if c.success && err != nil {
+			t.Fatalf("healthcheck %d error\n%v", i, err)
+		}
+		if !c.success && err == nil {
+			t.Fatalf("healthcheck %d was expected to fail", i)
+		}
+		if c.config.URL == url+"/notfound" && GetMetadata(ctx)["status-code"] != "404" {
+			t.Fatalf("Invalid status code metadata %v", GetMetadata(ctx))
+		}
+	}
+}
+
+func TestWebSocketValidate(t *testing.T) {
+	base := Base{Name: "foo", Interval: Duration(time.Second * 5)}
+	cases := []WebSocketHealthcheckConfiguration{
+		{Base: base, Timeout: Duration(time.Second)},
+		{Base: base, URL: "http://localhost/ws", Timeout: Duration(time.Second)},
+		{Base: base, URL: "ws:///ws", Timeout: Duration(time.Second)},
+		{Base: base, URL: "ws://localhost/ws"},
+		{Base: base, URL: "ws://localhost/ws", Timeout: Duration(time.Second), Insecure: true},
+		{Base: base, URL: "wss://localhost/ws", Timeout: Duration(time.Second), Key: "key.pem"},
+	}
+	for i := range cases {
+		if err := cases[i].Validate(); err == nil {
+			t.Fatalf("The configuration %d should be invalid", i)
+		}
+	}
+	valid := WebSocketHealthcheckConfiguration{Base: base, URL: "wss://localhost/ws", Timeout: Duration(time.Second), Insecure: true}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("The configuration should be valid\n%v", err)
+	}
+}
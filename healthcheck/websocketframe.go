@@ -0,0 +1,177 @@
+package healthcheck
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// the WebSocket frames opcodes
+const (
+	wsContinuation byte = 0x0
+	wsText         byte = 0x1
+	wsBinary       byte = 0x2
+	wsClose        byte = 0x8
+	wsPing         byte = 0x9
+	wsPong         byte = 0xa
+)
+
+// wsMaxMessageSize the maximum size of a message read by the healthcheck
+const wsMaxMessageSize = 1024 * 1024
+
+// wsGUID the GUID used to compute the Sec-WebSocket-Accept header
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// wsKey returns a random Sec-WebSocket-Key value
+func wsKey() (string, error) {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return "", errors.Wrap(err, "Fail to generate the WebSocket key")
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// wsAccept returns the expected Sec-WebSocket-Accept value of a key
+func wsAccept(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// wsConn a client WebSocket connection
+type wsConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// writeFrame writes a masked frame
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	frame := []byte{0x80 | opcode}
+	length := len(payload)
+	switch {
+	case length < 126:
+		frame = append(frame, 0x80|byte(length))
+	case length <= 0xffff:
+		frame = append(frame, 0x80|126, byte(length>>8), byte(length))
+	default:
+		frame = append(frame, 0x80|127)
+		size := make([]byte, 8)
+		binary.BigEndian.PutUint64(size, uint64(length))
+		frame = append(frame, size...)
+	}
+	mask := make([]byte, 4)
+	if _, err := rand.Read(mask); err != nil {
+		return errors.Wrap(err, "Fail to generate the WebSocket mask")
+	}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		return errors.Wrap(err, "Fail to write the WebSocket frame")
+	}
+	return nil
+}
+
+// readFrame reads a frame, the fin bit, the opcode and the payload are returned
+func (c *wsConn) readFrame() (bool, byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(c.reader, header); err != nil {
+		return false, 0, nil, err
+	}
+	fin := header[0]&0x80 != 0
+	opcode := header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		size := make([]byte, 2)
+		if _, err := io.ReadFull(c.reader, size); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(size))
+	case 127:
+		size := make([]byte, 8)
+		if _, err := io.ReadFull(c.reader, size); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(size)
+	}
+	if length > wsMaxMessageSize {
+		return false, 0, nil, fmt.Errorf("The WebSocket frame size %d is too large", length)
+	}
+	mask := make([]byte, 4)
+	if masked {
+		if _, err := io.ReadFull(c.reader, mask); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
+
+// read reads the next data message or pong frame. The ping frames are
+// answered, and an error is returned if the server closes the connection.
+func (c *wsConn) read() (byte, []byte, error) {
+	var message []byte
+	var messageType byte
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, errors.Wrap(err, "Fail to read the WebSocket frame")
+		}
+		switch opcode {
+		case wsPing:
+			if err := c.writeFrame(wsPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case wsPong:
+			return wsPong, payload, nil
+		case wsClose:
+			code := 1005
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			return 0, nil, fmt.Errorf("The WebSocket connection was closed by the server (code %d)", code)
+		case wsText, wsBinary:
+			messageType = opcode
+			message = payload
+		case wsContinuation:
+			if messageType == 0 {
+				return 0, nil, errors.New("Unexpected WebSocket continuation frame")
+			}
+			message = append(message, payload...)
+			if len(message) > wsMaxMessageSize {
+				return 0, nil, fmt.Errorf("The WebSocket message size %d is too large", len(message))
+			}
+		default:
+			return 0, nil, fmt.Errorf("Unknown WebSocket opcode %d", opcode)
+		}
+		if fin {
+			return messageType, message, nil
+		}
+	}
+}
+
+// close sends a normal closure frame and closes the connection
+func (c *wsConn) close() {
+	// nolint
+	c.writeFrame(wsClose, []byte{0x03, 0xe8})
+	c.conn.Close()
+}
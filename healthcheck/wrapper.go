@@ -1,6 +1,9 @@
 package healthcheck
 
 import (
+	"context"
+	"sync"
+	"text/template"
 	"time"
 
 	"gopkg.in/tomb.v2"
@@ -11,17 +14,98 @@ type Wrapper struct {
 	healthcheck Healthcheck
 	Tick        *time.Ticker
 	t           tomb.Tomb
+	threshold   thresholdState
+	// the healthchecks executed from each source IP
+	sources []Healthcheck
+	message *template.Template
+	// the time the healthcheck was added, the startup grace period is not
+	// restarted when the wrapper is recreated
+	started time.Time
+	// true if the healthcheck is executed once, through the API
+	oneOff bool
+	// the current interval, which changes if the interval is adaptive
+	effective time.Duration
+
+	// the execution state when the healthcheck is executed by a scheduler,
+	// the running field is protected by the scheduler lock
+	scheduler  *scheduler
+	ctx        context.Context
+	cancel     context.CancelFunc
+	interval   time.Duration
+	next       time.Time
+	index      int
+	running    bool
+	executions sync.WaitGroup
+
+	// the start of the last execution, to compute the scheduling drift
+	lastRun time.Time
+	// the recent successful latencies, if the timeout is adaptive
+	latencies latencyWindow
 }
 
 // NewWrapper creates a new wrapper struct
 func NewWrapper(healthcheck Healthcheck) *Wrapper {
 	return &Wrapper{
 		healthcheck: healthcheck,
+		index:       -1,
+	}
+}
+
+// recreate returns a new wrapper for the healthcheck, keeping the state of
+// the current one. A tomb can't be reused, the wrapper is recreated once
+// stopped.
+func (w *Wrapper) recreate() *Wrapper {
+	wrapper := NewWrapper(w.healthcheck)
+	wrapper.threshold = w.threshold
+	wrapper.sources = w.sources
+	wrapper.message = w.message
+	wrapper.started = w.started
+	wrapper.latencies = w.latencies
+	return wrapper
+}
+
+// inStartupGrace returns true if a failure happens during the startup grace
+// period of the healthcheck
+func (w *Wrapper) inStartupGrace(success bool) bool {
+	grace := time.Duration(w.healthcheck.Base().StartupGracePeriod)
+	return !success && grace != 0 && time.Since(w.started) < grace
+}
+
+// tagStartup tags the result if it's a failure during the startup grace
+// period. Returns true if the result was tagged.
+func (w *Wrapper) tagStartup(result *Result) bool {
+	if !w.inStartupGrace(result.Success) {
+		return false
+	}
+	if result.Metadata == nil {
+		result.Metadata = make(map[string]string)
+	}
+	result.Metadata["startup"] = "true"
+	return true
+}
+
+// resultStatus returns the status of a result for the metrics
+func resultStatus(result *Result) string {
+	if result.Success {
+		return "success"
+	}
+	if result.Metadata["startup"] == "true" {
+		return "startup"
 	}
+	return "failure"
 }
 
 // Stop an Healthcheck wrapper
 func (w *Wrapper) Stop() error {
+	if w.scheduler != nil {
+		w.scheduler.remove(w)
+		w.scheduler = nil
+		return nil
+	}
+	// the wrapper was never started
+	if w.Tick == nil {
+		return nil
+	}
 	w.Tick.Stop()
 	w.t.Kill(nil)
 	err := w.t.Wait()
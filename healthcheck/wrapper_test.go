@@ -0,0 +1,46 @@
+package healthcheck
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStartupGrace(t *testing.T) {
+	w := NewWrapper(&panicHealthcheck{config: Base{Name: "foo", StartupGracePeriod: Duration(time.Hour)}})
+	w.started = time.Now()
+	result := NewResult(w.healthcheck, 1, errors.New("connection refused"))
+	if !w.tagStartup(result) {
+		t.Fatalf("The failure should be tagged")
+	}
+	if result.Metadata["startup"] != "true" || resultStatus(result) != "startup" {
+		t.Fatalf("Invalid result %v", result)
+	}
+	success := NewResult(w.healthcheck, 1, nil)
+	if w.tagStartup(success) || resultStatus(success) != "success" {
+		t.Fatalf("The success should not be tagged")
+	}
+	w.started = time.Now().Add(-2 * time.Hour)
+	result = NewResult(w.healthcheck, 1, errors.New("connection refused"))
+	if w.tagStartup(result) || resultStatus(result) != "failure" {
+		t.Fatalf("The failure should not be tagged after the grace period")
+	}
+	w = NewWrapper(&panicHealthcheck{config: Base{Name: "foo"}})
+	w.started = time.Now()
+	if w.tagStartup(NewResult(w.healthcheck, 1, errors.New("connection refused"))) {
+		t.Fatalf("The failure should not be tagged without grace period")
+	}
+	config := TCPHealthcheckConfiguration{
+		Base: Base{
+			Name:               "foo",
+			Interval:           Duration(10 * time.Second),
+			StartupGracePeriod: Duration(-time.Second),
+		},
+		Target:  "127.0.0.1",
+		Port:    9000,
+		Timeout: Duration(time.Second),
+	}
+	if err := config.Validate(); err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
@@ -64,6 +64,10 @@ type BulkPayload struct {
 	TCPChecks     []healthcheck.TCPHealthcheckConfiguration     `json:"tcp-checks"`
 	HTTPChecks    []healthcheck.HTTPHealthcheckConfiguration    `json:"http-checks"`
 	TLSChecks     []healthcheck.TLSHealthcheckConfiguration     `json:"tls-checks"`
+	// the healthchecks of WebSocket endpoints
+	WebSocketChecks []healthcheck.WebSocketHealthcheckConfiguration `json:"websocket-checks"`
+	// the healthchecks of SSH servers host keys
+	SSHChecks []healthcheck.SSHHealthcheckConfiguration `json:"ssh-checks"`
 }
 
 // Validate validates the payload for bulk requests
@@ -109,6 +113,26 @@ func (p *BulkPayload) Validate() error {
 			return errors.New(msg)
 		}
 	}
+	for _, config := range p.WebSocketChecks {
+		err := config.Validate()
+		if config.Base.OneOff {
+			return errors.New(oneOffErrorMsg)
+		}
+		if err != nil {
+			msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+			return errors.New(msg)
+		}
+	}
+	for _, config := range p.SSHChecks {
+		err := config.Validate()
+		if config.Base.OneOff {
+			return errors.New(oneOffErrorMsg)
+		}
+		if err != nil {
+			msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+			return errors.New(msg)
+		}
+	}
 	for _, config := range p.CommandChecks {
 		err := config.Validate()
 		if config.Base.OneOff {
@@ -8,6 +8,7 @@ import (
 	"io/fs"
 	"net/http"
 	"reflect"
+	"strconv"
 	"strings"
 	"sync"
 	"text/template"
@@ -15,8 +16,10 @@ import (
 
 	"github.com/labstack/echo"
 	"github.com/labstack/echo/middleware"
+	"github.com/pkg/errors"
 
 	"github.com/mcorbin/cabourotte/healthcheck"
+	"github.com/mcorbin/cabourotte/memorystore"
 	"github.com/mcorbin/corbierror"
 )
 
@@ -44,21 +47,26 @@ func (c *Component) addCheck(ec echo.Context, check healthcheck.Healthcheck) err
 //go:embed assets
 var embededFiles embed.FS
 
-// oneOff executes an one-off healthcheck and returns its result
-func (c *Component) oneOff(ec echo.Context, healthcheck healthcheck.Healthcheck) error {
-	c.Logger.Info(fmt.Sprintf("Executing one-off healthcheck %s", healthcheck.Base().Name))
-	err := healthcheck.Initialize()
+// oneOff executes an one-off healthcheck and returns its result. The
+// healthcheck is executed like the periodic ones.
+func (c *Component) oneOff(ec echo.Context, check healthcheck.Healthcheck) error {
+	c.Logger.Info(fmt.Sprintf("Executing one-off healthcheck %s", check.Base().Name))
+	result, err := c.healthcheck.ExecuteOneOff(ec.Request().Context(), check)
+	if errors.Is(err, healthcheck.ErrResultSkipped) {
+		msg := fmt.Sprintf("One-off healthcheck %s executed, no result produced", check.Base().Name)
+		c.Logger.Info(msg)
+		return ec.JSON(http.StatusOK, newResponse(msg))
+	}
 	if err != nil {
-		msg := fmt.Sprintf("Fail to initialize one off healthcheck %s: %s", healthcheck.Base().Name, err.Error())
+		msg := fmt.Sprintf("Fail to initialize one off healthcheck %s: %s", check.Base().Name, err.Error())
 		return corbierror.New(msg, corbierror.Internal, true)
 	}
-	err = healthcheck.Execute()
-	if err != nil {
-		msg := fmt.Sprintf("Execution of one off healthcheck %s failed: %s", healthcheck.Base().Name, err.Error())
+	if !result.Success {
+		msg := fmt.Sprintf("Execution of one off healthcheck %s failed: %s", check.Base().Name, result.Message)
 		c.Logger.Error(msg)
 		return corbierror.New(msg, corbierror.Internal, true)
 	}
-	msg := fmt.Sprintf("One-off healthcheck %s successfully executed", healthcheck.Base().Name)
+	msg := fmt.Sprintf("One-off healthcheck %s successfully executed", check.Base().Name)
 	c.Logger.Info(msg)
 	return ec.JSON(http.StatusCreated, newResponse(msg))
 }
@@ -80,6 +88,21 @@ func (c *Component) handleCheck(ec echo.Context, healthcheck healthcheck.Healthc
 	return ec.JSON(http.StatusCreated, newResponse("Healthcheck successfully added"))
 }
 
+// withState adds the state of the healthcheck to the metadata of a result if
+// the healthcheck is paused
+func (c *Component) withState(result healthcheck.Result) healthcheck.Result {
+	if !c.healthcheck.IsPaused(result.Name) {
+		return result
+	}
+	metadata := make(map[string]string, len(result.Metadata)+1)
+	for k, v := range result.Metadata {
+		metadata[k] = v
+	}
+	metadata["state"] = "paused"
+	result.Metadata = metadata
+	return result
+}
+
 // handlers configures the handlers for the http server component
 func (c *Component) handlers() {
 	c.Server.HTTPErrorHandler = errorHandler(c.Logger)
@@ -147,6 +170,36 @@ func (c *Component) handlers() {
 			return c.handleCheck(ec, healthcheck)
 		})
 
+		c.Server.POST("/healthcheck/websocket", func(ec echo.Context) error {
+			var config healthcheck.WebSocketHealthcheckConfiguration
+			if err := ec.Bind(&config); err != nil {
+				msg := fmt.Sprintf("Fail to create the WebSocket healthcheck. Invalid JSON: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			err := config.Validate()
+			if err != nil {
+				msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			healthcheck := healthcheck.NewWebSocketHealthcheck(c.Logger, &config)
+			return c.handleCheck(ec, healthcheck)
+		})
+
+		c.Server.POST("/healthcheck/ssh", func(ec echo.Context) error {
+			var config healthcheck.SSHHealthcheckConfiguration
+			if err := ec.Bind(&config); err != nil {
+				msg := fmt.Sprintf("Fail to create the SSH healthcheck. Invalid JSON: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			err := config.Validate()
+			if err != nil {
+				msg := fmt.Sprintf("Invalid healthcheck configuration: %s", err.Error())
+				return corbierror.New(msg, corbierror.BadRequest, true)
+			}
+			healthcheck := healthcheck.NewSSHHealthcheck(c.Logger, &config)
+			return c.handleCheck(ec, healthcheck)
+		})
+
 		c.Server.POST("/healthcheck/http", func(ec echo.Context) error {
 			var config healthcheck.HTTPHealthcheckConfiguration
 			if err := ec.Bind(&config); err != nil {
@@ -228,6 +281,24 @@ func (c *Component) handlers() {
 				}
 				newChecks[config.Base.Name] = true
 			}
+			for i := range payload.WebSocketChecks {
+				config := payload.WebSocketChecks[i]
+				healthcheck := healthcheck.NewWebSocketHealthcheck(c.Logger, &config)
+				err := c.addCheck(ec, healthcheck)
+				if err != nil {
+					return c.addCheckError(ec, healthcheck, err)
+				}
+				newChecks[config.Base.Name] = true
+			}
+			for i := range payload.SSHChecks {
+				config := payload.SSHChecks[i]
+				healthcheck := healthcheck.NewSSHHealthcheck(c.Logger, &config)
+				err := c.addCheck(ec, healthcheck)
+				if err != nil {
+					return c.addCheckError(ec, healthcheck, err)
+				}
+				newChecks[config.Base.Name] = true
+			}
 			for i := range payload.CommandChecks {
 				config := payload.CommandChecks[i]
 				healthcheck := healthcheck.NewCommandHealthcheck(c.Logger, &config)
@@ -266,10 +337,42 @@ func (c *Component) handlers() {
 			}
 			return ec.JSON(http.StatusOK, newResponse(fmt.Sprintf("Successfully deleted healthcheck %s", name)))
 		})
+
+		c.Server.POST("/healthcheck/:name/pause", func(ec echo.Context) error {
+			name := ec.Param("name")
+			c.Logger.Info(fmt.Sprintf("Pausing healthcheck %s", name))
+			if c.healthcheck.GetCheck(name) == nil {
+				return corbierror.New("Healthcheck not found", corbierror.NotFound, true)
+			}
+			err := c.healthcheck.PauseCheck(name)
+			if err != nil {
+				msg := fmt.Sprintf("Fail to pause the healthcheck: %s", err.Error())
+				return corbierror.New(msg, corbierror.Internal, true)
+			}
+			return ec.JSON(http.StatusOK, newResponse(fmt.Sprintf("Successfully paused healthcheck %s", name)))
+		})
+
+		c.Server.POST("/healthcheck/:name/resume", func(ec echo.Context) error {
+			name := ec.Param("name")
+			c.Logger.Info(fmt.Sprintf("Resuming healthcheck %s", name))
+			if c.healthcheck.GetCheck(name) == nil {
+				return corbierror.New("Healthcheck not found", corbierror.NotFound, true)
+			}
+			err := c.healthcheck.ResumeCheck(name)
+			if err != nil {
+				msg := fmt.Sprintf("Fail to resume the healthcheck: %s", err.Error())
+				return corbierror.New(msg, corbierror.Internal, true)
+			}
+			return ec.JSON(http.StatusOK, newResponse(fmt.Sprintf("Successfully resumed healthcheck %s", name)))
+		})
 	}
 	if !c.Config.DisableResultAPI {
 		c.Server.GET("/result", func(ec echo.Context) error {
-			return ec.JSON(http.StatusOK, c.MemoryStore.List())
+			results := c.MemoryStore.List()
+			for i := range results {
+				results[i] = c.withState(results[i])
+			}
+			return ec.JSON(http.StatusOK, results)
 		})
 		c.Server.GET("/result/:name", func(ec echo.Context) error {
 			name := ec.Param("name")
@@ -277,9 +380,53 @@ func (c *Component) handlers() {
 			if err != nil {
 				return corbierror.New(err.Error(), corbierror.NotFound, true)
 			}
-			return ec.JSON(http.StatusOK, result)
+			return ec.JSON(http.StatusOK, c.withState(result))
 
 		})
+		c.Server.GET("/group", func(ec echo.Context) error {
+			return ec.JSON(http.StatusOK, c.MemoryStore.Groups())
+		})
+		c.Server.GET("/group/:name", func(ec echo.Context) error {
+			group, err := c.MemoryStore.Group(ec.Param("name"))
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.NotFound, true)
+			}
+			return ec.JSON(http.StatusOK, group)
+		})
+		c.Server.GET("/summary", func(ec echo.Context) error {
+			top := memorystore.DefaultSummaryTop
+			if param := ec.QueryParam("top"); param != "" {
+				value, err := strconv.Atoi(param)
+				if err != nil || value <= 0 {
+					return corbierror.New("Invalid top parameter, a positive integer is expected", corbierror.BadRequest, true)
+				}
+				top = value
+			}
+			return ec.JSON(http.StatusOK, c.MemoryStore.Summary(top, c.healthcheck.IsPaused, time.Now()))
+		})
+		c.Server.GET("/exporter/recording/:name", func(ec echo.Context) error {
+			recorder := c.getRecorder()
+			if recorder == nil {
+				return corbierror.New("No recording exporter configured", corbierror.NotFound, true)
+			}
+			results, err := recorder.Recorded(ec.Param("name"))
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.NotFound, true)
+			}
+			return ec.JSON(http.StatusOK, results)
+		})
+		c.Server.DELETE("/exporter/recording/:name", func(ec echo.Context) error {
+			recorder := c.getRecorder()
+			if recorder == nil {
+				return corbierror.New("No recording exporter configured", corbierror.NotFound, true)
+			}
+			name := ec.Param("name")
+			err := recorder.ResetRecorded(name)
+			if err != nil {
+				return corbierror.New(err.Error(), corbierror.NotFound, true)
+			}
+			return ec.JSON(http.StatusOK, newResponse(fmt.Sprintf("Successfully reset the results of the exporter %s", name)))
+		})
 		c.Server.GET("/frontend", func(ec echo.Context) error {
 			err := ec.Redirect(http.StatusFound, "/frontend/index.html")
 			return err
@@ -344,5 +491,15 @@ func (c *Component) handlers() {
 		return ec.JSON(http.StatusOK, "ok")
 	})
 
+	c.Server.GET("/live", func(ec echo.Context) error {
+		return ec.JSON(http.StatusOK, "ok")
+	})
+	c.Server.GET("/ready", func(ec echo.Context) error {
+		err := c.ready()
+		if err != nil {
+			return ec.JSON(http.StatusServiceUnavailable, newResponse(err.Error()))
+		}
+		return ec.JSON(http.StatusOK, "ok")
+	})
 	c.Server.GET("/metrics", echo.WrapHandler(c.Prometheus.Handler()))
 }
@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -220,6 +221,21 @@ func TestOneOffCheck(t *testing.T) {
 	if !strings.Contains(body, "One-off healthcheck baz successfully executed") {
 		t.Fatalf("Invalid body %s", body)
 	}
+	// a resolution failure is skipped with the skip DNS failure policy
+	reqBody = `{"name":"skipped","interval":"10m","one-off":true,"target":"cabourotte.invalid","port":9999,"timeout":"2s","dns-failure-policy":"skip"}`
+	req, err = http.NewRequest("POST", "http://127.0.0.1:2001/healthcheck/tcp", bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		t.Fatalf("Fail to build the HTTP request\n%v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	skippedResp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("HTTP request failed\n%v", err)
+	}
+	defer skippedResp.Body.Close()
+	if skippedResp.StatusCode != http.StatusOK {
+		t.Fatalf("The skipped result should not be an error, status %d", skippedResp.StatusCode)
+	}
 	err = component.Stop()
 	if err != nil {
 		t.Fatalf("Fail to stop the component\n%v", err)
@@ -341,3 +357,189 @@ func TestBasicAuth(t *testing.T) {
 		t.Fatalf("Expected 200, got status %d", resp.StatusCode)
 	}
 }
+
+func TestReadiness(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	memstore := memorystore.NewMemoryStore(logger)
+	healthcheck, err := healthcheck.New(zap.NewExample(), make(chan *healthcheck.Result, 10), prom)
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	component, err := New(logger, memstore, prom, &Configuration{Host: "127.0.0.1", Port: 2004}, healthcheck)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	ready := fmt.Errorf("not ready")
+	component.SetReadiness(func() error {
+		return ready
+	})
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	cases := []struct {
+		endpoint string
+		status   int
+		ready    error
+		shutdown bool
+	}{
+		{endpoint: "/live", status: 200, ready: fmt.Errorf("not ready")},
+		{endpoint: "/ready", status: 503, ready: fmt.Errorf("not ready")},
+		{endpoint: "/ready", status: 200, ready: nil},
+		{endpoint: "/ready", status: 503, ready: nil, shutdown: true},
+		{endpoint: "/live", status: 200, ready: nil, shutdown: true},
+	}
+	for _, c := range cases {
+		ready = c.ready
+		if c.shutdown {
+			component.SetShuttingDown()
+		}
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:2004%s", c.endpoint))
+		if err != nil {
+			t.Fatalf("HTTP request failed\n%v", err)
+		}
+		if resp.StatusCode != c.status {
+			t.Fatalf("Expected %d for %s, got status %d", c.status, c.endpoint, resp.StatusCode)
+		}
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
+
+func TestPauseResumeHandlers(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	memstore := memorystore.NewMemoryStore(logger)
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom)
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	component, err := New(logger, memstore, prom, &Configuration{Host: "127.0.0.1", Port: 2006}, checkComponent)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	check := healthcheck.NewTCPHealthcheck(logger, &healthcheck.TCPHealthcheckConfiguration{
+		Base: healthcheck.Base{
+			Name:     "foo",
+			Interval: healthcheck.Duration(time.Second * 10),
+		},
+		Target:  "127.0.0.1",
+		Port:    9999,
+		Timeout: healthcheck.Duration(time.Second * 2),
+	})
+	err = checkComponent.AddCheck(check)
+	if err != nil {
+		t.Fatalf("Fail to add the healthcheck\n%v", err)
+	}
+	memstore.Add(&healthcheck.Result{Name: "foo", Success: true})
+	client := &http.Client{}
+	post := func(path string) int {
+		resp, err := client.Post(fmt.Sprintf("http://127.0.0.1:2006%s", path), "application/json", nil)
+		if err != nil {
+			t.Fatalf("HTTP request failed\n%v", err)
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+	getResult := func() string {
+		resp, err := client.Get("http://127.0.0.1:2006/result/foo")
+		if err != nil {
+			t.Fatalf("HTTP request failed\n%v", err)
+		}
+		defer resp.Body.Close()
+		bodyBytes, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("Fail to read the body\n%v", err)
+		}
+		return string(bodyBytes)
+	}
+	if status := post("/healthcheck/bar/pause"); status != http.StatusNotFound {
+		t.Fatalf("Invalid status %d", status)
+	}
+	if status := post("/healthcheck/foo/pause"); status != http.StatusOK {
+		t.Fatalf("Invalid status %d", status)
+	}
+	if !checkComponent.IsPaused("foo") {
+		t.Fatalf("The healthcheck should be paused")
+	}
+	if body := getResult(); !strings.Contains(body, `"state":"paused"`) {
+		t.Fatalf("The result should be reported as paused: %s", body)
+	}
+	if status := post("/healthcheck/foo/resume"); status != http.StatusOK {
+		t.Fatalf("Invalid status %d", status)
+	}
+	if checkComponent.IsPaused("foo") {
+		t.Fatalf("The healthcheck should be resumed")
+	}
+	if body := getResult(); strings.Contains(body, `paused`) {
+		t.Fatalf("The result should not be reported as paused: %s", body)
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+	err = checkComponent.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the healthcheck component\n%v", err)
+	}
+}
+
+func TestSummaryHandler(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	memstore := memorystore.NewMemoryStore(logger)
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom)
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	component, err := New(logger, memstore, prom, &Configuration{Host: "127.0.0.1", Port: 2008}, checkComponent)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	defer component.Stop()
+	memstore.Add(&healthcheck.Result{Name: "foo", Success: true, Timestamp: time.Now()})
+	memstore.Add(&healthcheck.Result{Name: "bar", Success: false, Timestamp: time.Now(), Message: "connection refused"})
+	resp, err := http.Get("http://127.0.0.1:2008/summary?top=1")
+	if err != nil {
+		t.Fatalf("HTTP request failed\n%v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Fail to read the body\n%v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Invalid status %d", resp.StatusCode)
+	}
+	if !strings.Contains(string(body), `"total":2,"ok":1,"warn":0,"critical":1`) || !strings.Contains(string(body), `"name":"bar","message":"connection refused"`) {
+		t.Fatalf("Invalid summary %s", string(body))
+	}
+	resp, err = http.Get("http://127.0.0.1:2008/summary?top=foo")
+	if err != nil {
+		t.Fatalf("HTTP request failed\n%v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Invalid status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,26 @@
+package http
+
+import (
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// Recorder gives access to the results of the recording exporters
+type Recorder interface {
+	Recorded(name string) ([]*healthcheck.Result, error)
+	ResetRecorded(name string) error
+}
+
+// SetRecorder configures the recorder used by the recording exporters
+// endpoints. The endpoints return an error if no recorder is set.
+func (c *Component) SetRecorder(recorder Recorder) {
+	c.recorderLock.Lock()
+	defer c.recorderLock.Unlock()
+	c.recorder = recorder
+}
+
+// getRecorder returns the configured recorder
+func (c *Component) getRecorder() Recorder {
+	c.recorderLock.RLock()
+	defer c.recorderLock.RUnlock()
+	return c.recorder
+}
@@ -0,0 +1,99 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+	"github.com/mcorbin/cabourotte/memorystore"
+	"github.com/mcorbin/cabourotte/prometheus"
+)
+
+type testRecorder struct {
+	results []*healthcheck.Result
+}
+
+func (r *testRecorder) Recorded(name string) ([]*healthcheck.Result, error) {
+	if name != "recording" {
+		return nil, fmt.Errorf("The exporter %s does not exist", name)
+	}
+	return r.results, nil
+}
+
+func (r *testRecorder) ResetRecorded(name string) error {
+	if name != "recording" {
+		return fmt.Errorf("The exporter %s does not exist", name)
+	}
+	r.results = nil
+	return nil
+}
+
+func TestRecordingHandlers(t *testing.T) {
+	prom, err := prometheus.New()
+	if err != nil {
+		t.Fatalf("Error creating prometheus component :\n%v", err)
+	}
+	logger := zap.NewExample()
+	checkComponent, err := healthcheck.New(logger, make(chan *healthcheck.Result, 10), prom)
+	if err != nil {
+		t.Fatalf("Fail to create the healthcheck component\n%v", err)
+	}
+	component, err := New(logger, memorystore.NewMemoryStore(logger), prom, &Configuration{Host: "127.0.0.1", Port: 2007}, checkComponent)
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component\n%v", err)
+	}
+	resp, err := http.Get("http://127.0.0.1:2007/exporter/recording/recording")
+	if err != nil {
+		t.Fatalf("HTTP request failed\n%v", err)
+	}
+	if resp.StatusCode != 404 {
+		t.Fatalf("Expected a 404 without recorder, got %d", resp.StatusCode)
+	}
+	component.SetRecorder(&testRecorder{results: []*healthcheck.Result{{Name: "foo", Success: true}}})
+	resp, err = http.Get("http://127.0.0.1:2007/exporter/recording/recording")
+	if err != nil {
+		t.Fatalf("HTTP request failed\n%v", err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Fail to read the body\n%v", err)
+	}
+	var results []healthcheck.Result
+	if err := json.Unmarshal(body, &results); err != nil {
+		t.Fatalf("Fail to parse the results\n%v", err)
+	}
+	if len(results) != 1 || results[0].Name != "foo" {
+		t.Fatalf("Invalid recorded results %s", string(body))
+	}
+	req, err := http.NewRequest("DELETE", "http://127.0.0.1:2007/exporter/recording/recording", nil)
+	if err != nil {
+		t.Fatalf("Fail to build the request\n%v", err)
+	}
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("HTTP request failed\n%v", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("Fail to reset the results, status %d", resp.StatusCode)
+	}
+	resp, err = http.Get("http://127.0.0.1:2007/exporter/recording/unknown")
+	if err != nil {
+		t.Fatalf("HTTP request failed\n%v", err)
+	}
+	if resp.StatusCode != 404 {
+		t.Fatalf("Expected a 404 for an unknown exporter, got %d", resp.StatusCode)
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component\n%v", err)
+	}
+}
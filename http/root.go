@@ -32,6 +32,11 @@ type Component struct {
 	requestHistogram *prom.HistogramVec
 	responseCounter  *prom.CounterVec
 	wg               sync.WaitGroup
+	readinessLock    sync.RWMutex
+	readiness        func() error
+	shuttingDown     bool
+	recorderLock     sync.RWMutex
+	recorder         Recorder
 }
 
 // New creates a new HTTP component
@@ -106,6 +111,35 @@ func New(logger *zap.Logger, memstore *memorystore.MemoryStore, promComponent *p
 	return &component, nil
 }
 
+// SetReadiness configures the function used by the /ready endpoint to
+// verify if Cabourotte is ready. A nil function means always ready.
+func (c *Component) SetReadiness(readiness func() error) {
+	c.readinessLock.Lock()
+	defer c.readinessLock.Unlock()
+	c.readiness = readiness
+}
+
+// SetShuttingDown marks Cabourotte as shutting down: the /ready endpoint
+// will now return an error.
+func (c *Component) SetShuttingDown() {
+	c.readinessLock.Lock()
+	defer c.readinessLock.Unlock()
+	c.shuttingDown = true
+}
+
+// ready returns an error if Cabourotte is not ready
+func (c *Component) ready() error {
+	c.readinessLock.RLock()
+	defer c.readinessLock.RUnlock()
+	if c.shuttingDown {
+		return errors.New("Cabourotte is shutting down")
+	}
+	if c.readiness != nil {
+		return c.readiness()
+	}
+	return nil
+}
+
 // Start starts the http server
 func (c *Component) Start() error {
 	address := fmt.Sprintf("%s:%d", c.Config.Host, c.Config.Port)
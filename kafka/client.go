@@ -0,0 +1,499 @@
+package kafka
+
+import (
+	"bufio"
+	cryptotls "crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mcorbin/cabourotte/tls"
+)
+
+// the Kafka APIs keys and the versions used by the client
+const (
+	apiProduce         int16 = 0
+	apiMetadata        int16 = 3
+	produceVersion     int16 = 3
+	metadataVersion    int16 = 4
+	recordBatchVersion int8  = 2
+)
+
+// maxResponseSize the maximum size of a broker response
+const maxResponseSize = 16 * 1024 * 1024
+
+// castagnoli the CRC table of the record batches checksums
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+var errorCodes = map[int16]string{
+	2:  "corrupt message",
+	3:  "unknown topic or partition",
+	5:  "leader not available",
+	6:  "not leader for the partition",
+	7:  "request timed out",
+	10: "message too large",
+	19: "not enough replicas",
+	29: "topic authorization failed",
+}
+
+// brokerError returns the error of a Kafka error code
+func brokerError(code int16) error {
+	msg, ok := errorCodes[code]
+	if !ok {
+		msg = fmt.Sprintf("error code %d", code)
+	}
+	return fmt.Errorf("Kafka broker error: %s", msg)
+}
+
+// Options the options of a Kafka client
+type Options struct {
+	// the bootstrap brokers addresses
+	Brokers  []string
+	ClientID string
+	// TLS is used if not nil
+	TLSConfig *cryptotls.Config
+	// the maximum duration of the TLS handshake, the timeout is used if
+	// not set
+	HandshakeTimeout time.Duration
+	// the maximum duration of the connections and of the requests
+	Timeout time.Duration
+	// the acknowledgements required by the producer: -1 for all the in-sync
+	// replicas, 1 for the leader only
+	Acks int16
+}
+
+// Header a record header
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+// broker a connection to a broker
+type broker struct {
+	lock   sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// Client a minimal Kafka client, only producing records to the topics it
+// was created for. The records of a key are always produced to the same
+// partition.
+type Client struct {
+	options Options
+
+	lock          sync.Mutex
+	correlationID int32
+	// the brokers addresses by node ID
+	addresses map[int32]string
+	brokers   map[int32]*broker
+	// the partitions leaders, by topic
+	leaders map[string][]int32
+	closed  bool
+}
+
+// Connect connects to the cluster and fetches the partitions leaders of the
+// topics. An error is returned if a topic does not exist.
+func Connect(options Options, topics []string) (*Client, error) {
+	if len(options.Brokers) == 0 {
+		return nil, errors.New("No Kafka broker configured")
+	}
+	client := &Client{
+		options:   options,
+		addresses: make(map[int32]string),
+		brokers:   make(map[int32]*broker),
+		leaders:   make(map[string][]int32),
+	}
+	var lastErr error
+	for _, address := range options.Brokers {
+		b, err := client.dial(address)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		err = client.metadata(b, topics)
+		b.close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return client, nil
+	}
+	return nil, lastErr
+}
+
+// dial connects to a broker
+func (c *Client) dial(address string) (*broker, error) {
+	dialer := &net.Dialer{Timeout: c.options.Timeout}
+	var conn net.Conn
+	var err error
+	if c.options.TLSConfig != nil {
+		conn, err = tls.Dial(dialer, address, c.options.TLSConfig, c.options.HandshakeTimeout)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to connect to the Kafka broker %s", address)
+	}
+	return &broker{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+// close closes the connection to a broker
+func (b *broker) close() {
+	// nolint
+	b.conn.Close()
+}
+
+// metadata fetches the brokers and the partitions leaders of the topics
+func (c *Client) metadata(b *broker, topics []string) error {
+	body := appendInt32(nil, int32(len(topics)))
+	for _, topic := range topics {
+		body = appendString(body, topic)
+	}
+	// the topics are not created automatically
+	body = append(body, 0)
+	response, err := c.request(b, apiMetadata, metadataVersion, body)
+	if err != nil {
+		return err
+	}
+	r := &reader{b: response}
+	r.int32() // throttle time
+	addresses := make(map[int32]string)
+	for i := r.int32(); i > 0 && r.err == nil; i-- {
+		id := r.int32()
+		host := r.string()
+		port := r.int32()
+		r.string() // rack
+		addresses[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	r.string() // cluster ID
+	r.int32()  // controller ID
+	leaders := make(map[string][]int32)
+	for i := r.int32(); i > 0 && r.err == nil; i-- {
+		code := r.int16()
+		name := r.string()
+		r.int8() // internal
+		var partitions []int32
+		for j := r.int32(); j > 0 && r.err == nil; j-- {
+			r.int16() // partition error code
+			index := r.int32()
+			leader := r.int32()
+			r.int32Array() // replicas
+			r.int32Array() // in-sync replicas
+			if index < 0 || index > 1<<16 {
+				return fmt.Errorf("Invalid partition %d for the Kafka topic %s", index, name)
+			}
+			for int(index) >= len(partitions) {
+				partitions = append(partitions, -1)
+			}
+			partitions[index] = leader
+		}
+		if code != 0 {
+			return errors.Wrapf(brokerError(code), "Invalid Kafka topic %s", name)
+		}
+		leaders[name] = partitions
+	}
+	if r.err != nil {
+		return errors.Wrap(r.err, "Invalid Kafka metadata response")
+	}
+	for _, topic := range topics {
+		if len(leaders[topic]) == 0 {
+			return fmt.Errorf("The Kafka topic %s has no partition", topic)
+		}
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.addresses = addresses
+	c.leaders = leaders
+	return nil
+}
+
+// request sends a request to a broker and returns the response body
+func (c *Client) request(b *broker, key int16, version int16, body []byte) ([]byte, error) {
+	c.lock.Lock()
+	c.correlationID++
+	correlationID := c.correlationID
+	c.lock.Unlock()
+	request := appendInt16(make([]byte, 4, 64+len(body)), key)
+	request = appendInt16(request, version)
+	request = appendInt32(request, correlationID)
+	request = appendString(request, c.options.ClientID)
+	request = append(request, body...)
+	binary.BigEndian.PutUint32(request, uint32(len(request)-4))
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if c.options.Timeout != 0 {
+		// nolint
+		b.conn.SetDeadline(time.Now().Add(c.options.Timeout))
+	}
+	if _, err := b.conn.Write(request); err != nil {
+		return nil, errors.Wrap(err, "Fail to write to the Kafka broker")
+	}
+	var size [4]byte
+	if _, err := io.ReadFull(b.reader, size[:]); err != nil {
+		return nil, errors.Wrap(err, "Fail to read the Kafka response")
+	}
+	length := binary.BigEndian.Uint32(size[:])
+	if length < 4 || length > maxResponseSize {
+		return nil, fmt.Errorf("Invalid Kafka response size %d", length)
+	}
+	response := make([]byte, length)
+	if _, err := io.ReadFull(b.reader, response); err != nil {
+		return nil, errors.Wrap(err, "Fail to read the Kafka response")
+	}
+	if int32(binary.BigEndian.Uint32(response)) != correlationID {
+		return nil, errors.New("Invalid Kafka response correlation ID")
+	}
+	return response[4:], nil
+}
+
+// leader returns the partition of a key and the connection to its leader
+func (c *Client) leader(topic string, key []byte) (int32, *broker, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed {
+		return 0, nil, errors.New("The Kafka client is closed")
+	}
+	partitions, ok := c.leaders[topic]
+	if !ok {
+		return 0, nil, fmt.Errorf("Unknown Kafka topic %s", topic)
+	}
+	hash := fnv.New32a()
+	// nolint
+	hash.Write(key)
+	partition := int32(hash.Sum32() % uint32(len(partitions)))
+	id := partitions[partition]
+	if b, ok := c.brokers[id]; ok {
+		return partition, b, nil
+	}
+	address, ok := c.addresses[id]
+	if !ok {
+		return 0, nil, fmt.Errorf("No leader for the partition %d of the Kafka topic %s", partition, topic)
+	}
+	b, err := c.dial(address)
+	if err != nil {
+		return 0, nil, err
+	}
+	c.brokers[id] = b
+	return partition, b, nil
+}
+
+// Produce produces a record and waits for the broker acknowledgement
+func (c *Client) Produce(topic string, key []byte, value []byte, headers []Header) error {
+	partition, b, err := c.leader(topic, key)
+	if err != nil {
+		return err
+	}
+	records := recordBatch(time.Now(), key, value, headers)
+	body := appendInt16(nil, -1) // transactional ID
+	body = appendInt16(body, c.options.Acks)
+	body = appendInt32(body, int32(c.options.Timeout/time.Millisecond))
+	body = appendInt32(body, 1)
+	body = appendString(body, topic)
+	body = appendInt32(body, 1)
+	body = appendInt32(body, partition)
+	body = appendInt32(body, int32(len(records)))
+	body = append(body, records...)
+	response, err := c.request(b, apiProduce, produceVersion, body)
+	if err != nil {
+		c.drop(b)
+		return err
+	}
+	r := &reader{b: response}
+	for i := r.int32(); i > 0 && r.err == nil; i-- {
+		r.string() // topic
+		for j := r.int32(); j > 0 && r.err == nil; j-- {
+			r.int32() // partition
+			code := r.int16()
+			r.int64() // base offset
+			r.int64() // log append time
+			if code != 0 && r.err == nil {
+				return errors.Wrapf(brokerError(code), "Fail to produce to the Kafka topic %s", topic)
+			}
+		}
+	}
+	if r.err != nil {
+		return errors.Wrap(r.err, "Invalid Kafka produce response")
+	}
+	return nil
+}
+
+// drop closes a broker connection after an error, it is reopened by the
+// next request
+func (c *Client) drop(b *broker) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for id, current := range c.brokers {
+		if current == b {
+			delete(c.brokers, id)
+		}
+	}
+	b.close()
+}
+
+// Close closes the connections to the brokers
+func (c *Client) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.closed = true
+	for id, b := range c.brokers {
+		b.close()
+		delete(c.brokers, id)
+	}
+	return nil
+}
+
+// recordBatch encodes a v2 record batch containing one record
+func recordBatch(timestamp time.Time, key []byte, value []byte, headers []Header) []byte {
+	record := []byte{0}              // attributes
+	record = appendVarint(record, 0) // timestamp delta
+	record = appendVarint(record, 0) // offset delta
+	record = appendVarBytes(record, key)
+	record = appendVarBytes(record, value)
+	record = appendVarint(record, int64(len(headers)))
+	for _, header := range headers {
+		record = appendVarBytes(record, []byte(header.Key))
+		record = appendVarBytes(record, header.Value)
+	}
+	millis := timestamp.UnixNano() / int64(time.Millisecond)
+	// the checksummed part, from the attributes to the end of the batch
+	batch := appendInt16(nil, 0)  // attributes
+	batch = appendInt32(batch, 0) // last offset delta
+	batch = appendInt64(batch, millis)
+	batch = appendInt64(batch, millis)
+	batch = appendInt64(batch, -1) // producer ID
+	batch = appendInt16(batch, -1) // producer epoch
+	batch = appendInt32(batch, -1) // base sequence
+	batch = appendInt32(batch, 1)
+	batch = appendVarint(batch, int64(len(record)))
+	batch = append(batch, record...)
+	result := appendInt64(nil, 0) // base offset
+	// the length of the batch after the length field
+	result = appendInt32(result, int32(4+1+4+len(batch)))
+	result = appendInt32(result, -1) // partition leader epoch
+	result = append(result, byte(recordBatchVersion))
+	result = appendInt32(result, int32(crc32.Checksum(batch, castagnoli)))
+	return append(result, batch...)
+}
+
+// appendInt16 appends a big endian int16
+func appendInt16(b []byte, v int16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+// appendInt32 appends a big endian int32
+func appendInt32(b []byte, v int32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// appendInt64 appends a big endian int64
+func appendInt64(b []byte, v int64) []byte {
+	return appendInt32(appendInt32(b, int32(v>>32)), int32(v))
+}
+
+// appendString appends an int16 length-prefixed string
+func appendString(b []byte, s string) []byte {
+	b = appendInt16(b, int16(len(s)))
+	return append(b, s...)
+}
+
+// appendVarint appends a zigzag encoded varint
+func appendVarint(b []byte, v int64) []byte {
+	var buffer [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(buffer[:], v)
+	return append(b, buffer[:n]...)
+}
+
+// appendVarBytes appends varint length-prefixed bytes, a nil slice is encoded
+// as a null value
+func appendVarBytes(b []byte, v []byte) []byte {
+	if v == nil {
+		return appendVarint(b, -1)
+	}
+	b = appendVarint(b, int64(len(v)))
+	return append(b, v...)
+}
+
+// reader decodes a response, the first error is kept and the next reads
+// return zero values
+type reader struct {
+	b   []byte
+	err error
+}
+
+// next returns the next n bytes
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.b) < n {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	result := r.b[:n]
+	r.b = r.b[n:]
+	return result
+}
+
+func (r *reader) int8() int8 {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	return int8(b[0])
+}
+
+func (r *reader) int16() int16 {
+	b := r.next(2)
+	if b == nil {
+		return 0
+	}
+	return int16(binary.BigEndian.Uint16(b))
+}
+
+func (r *reader) int32() int32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return int32(binary.BigEndian.Uint32(b))
+}
+
+func (r *reader) int64() int64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(b))
+}
+
+// string reads a nullable string
+func (r *reader) string() string {
+	length := r.int16()
+	if length < 0 {
+		return ""
+	}
+	return string(r.next(int(length)))
+}
+
+func (r *reader) int32Array() []int32 {
+	count := r.int32()
+	if count < 0 || int(count) > len(r.b)/4 {
+		if count > 0 {
+			r.err = io.ErrUnexpectedEOF
+		}
+		return nil
+	}
+	result := make([]int32, 0, count)
+	for i := int32(0); i < count; i++ {
+		result = append(result, r.int32())
+	}
+	return result
+}
@@ -0,0 +1,223 @@
+package kafka
+
+import (
+	"bufio"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// record a record received by the test broker
+type record struct {
+	topic     string
+	partition int32
+	key       string
+	value     string
+	headers   map[string]string
+}
+
+// testBroker a single node cluster, the topics have two partitions
+func testBroker(t *testing.T, topics []string) (net.Listener, chan record) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to start the listener: %v", err)
+	}
+	records := make(chan record, 10)
+	existing := make(map[string]bool)
+	for _, topic := range topics {
+		existing[topic] = true
+	}
+	addr := listener.Addr().(*net.TCPAddr)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn, addr, existing, records)
+		}
+	}()
+	return listener, records
+}
+
+// serve answers the metadata and produce requests of a connection
+func serve(conn net.Conn, addr *net.TCPAddr, topics map[string]bool, records chan record) {
+	defer conn.Close()
+	buffered := bufio.NewReader(conn)
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(buffered, size[:]); err != nil {
+			return
+		}
+		request := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(buffered, request); err != nil {
+			return
+		}
+		r := &reader{b: request}
+		key := r.int16()
+		r.int16() // version
+		correlationID := r.int32()
+		r.string() // client ID
+		response := appendInt32(nil, correlationID)
+		switch key {
+		case apiMetadata:
+			response = appendInt32(response, 0)
+			response = appendInt32(response, 1)
+			response = appendInt32(response, 1)
+			response = appendString(response, addr.IP.String())
+			response = appendInt32(response, int32(addr.Port))
+			response = appendInt16(response, -1)
+			response = appendInt16(response, -1)
+			response = appendInt32(response, 1)
+			count := r.int32()
+			response = appendInt32(response, count)
+			for i := int32(0); i < count; i++ {
+				name := r.string()
+				if !topics[name] {
+					response = appendInt16(response, 3)
+					response = appendString(response, name)
+					response = append(response, 0)
+					response = appendInt32(response, 0)
+					continue
+				}
+				response = appendInt16(response, 0)
+				response = appendString(response, name)
+				response = append(response, 0)
+				response = appendInt32(response, 2)
+				for p := int32(0); p < 2; p++ {
+					response = appendInt16(response, 0)
+					response = appendInt32(response, p)
+					response = appendInt32(response, 1)
+					response = appendInt32(response, 1)
+					response = appendInt32(response, 1)
+					response = appendInt32(response, 1)
+					response = appendInt32(response, 1)
+				}
+			}
+		case apiProduce:
+			r.string() // transactional ID
+			r.int16()  // acks
+			r.int32()  // timeout
+			r.int32()
+			topic := r.string()
+			r.int32()
+			partition := r.int32()
+			batch := r.next(int(r.int32()))
+			code := int16(0)
+			rec, ok := decodeBatch(batch)
+			if ok {
+				rec.topic = topic
+				rec.partition = partition
+				records <- rec
+			} else {
+				code = 2
+			}
+			response = appendInt32(response, 1)
+			response = appendString(response, topic)
+			response = appendInt32(response, 1)
+			response = appendInt32(response, partition)
+			response = appendInt16(response, code)
+			response = appendInt64(response, 0)
+			response = appendInt64(response, -1)
+			response = appendInt32(response, 0)
+		default:
+			return
+		}
+		frame := appendInt32(nil, int32(len(response)))
+		if _, err := conn.Write(append(frame, response...)); err != nil {
+			return
+		}
+	}
+}
+
+// decodeBatch decodes a record batch of one record and verifies its checksum
+func decodeBatch(batch []byte) (record, bool) {
+	r := &reader{b: batch}
+	r.int64() // base offset
+	length := r.int32()
+	r.int32() // partition leader epoch
+	magic := r.int8()
+	crc := uint32(r.int32())
+	if r.err != nil || magic != 2 || int(length) != len(batch)-12 {
+		return record{}, false
+	}
+	if crc32.Checksum(r.b, crc32.MakeTable(crc32.Castagnoli)) != crc {
+		return record{}, false
+	}
+	r.next(2 + 4 + 8 + 8 + 8 + 2 + 4)
+	if r.int32() != 1 {
+		return record{}, false
+	}
+	varint := func() int64 {
+		v, n := binary.Varint(r.b)
+		r.next(n)
+		return v
+	}
+	varint() // record length
+	r.int8()
+	varint()
+	varint()
+	result := record{headers: make(map[string]string)}
+	result.key = string(r.next(int(varint())))
+	result.value = string(r.next(int(varint())))
+	for i := varint(); i > 0; i-- {
+		k := string(r.next(int(varint())))
+		result.headers[k] = string(r.next(int(varint())))
+	}
+	return result, r.err == nil
+}
+
+func TestProduce(t *testing.T) {
+	listener, records := testBroker(t, []string{"results"})
+	defer listener.Close()
+	client, err := Connect(Options{
+		Brokers:  []string{"127.0.0.1:1", listener.Addr().String()},
+		ClientID: "cabourotte",
+		Timeout:  3 * time.Second,
+		Acks:     -1,
+	}, []string{"results"})
+	if err != nil {
+		t.Fatalf("Fail to connect: %v", err)
+	}
+	defer client.Close()
+	partitions := make(map[string]int32)
+	for i := 0; i < 4; i++ {
+		key := "check-" + strconv.Itoa(i%2)
+		err = client.Produce("results", []byte(key), []byte("payload"), []Header{{Key: "source", Value: []byte("config")}})
+		if err != nil {
+			t.Fatalf("Fail to produce: %v", err)
+		}
+		select {
+		case rec := <-records:
+			if rec.topic != "results" || rec.key != key || rec.value != "payload" || rec.headers["source"] != "config" {
+				t.Fatalf("Invalid record %v", rec)
+			}
+			if partition, ok := partitions[key]; ok && partition != rec.partition {
+				t.Fatalf("The records of a key should be produced to the same partition")
+			}
+			partitions[key] = rec.partition
+		case <-time.After(3 * time.Second):
+			t.Fatalf("The record was not received")
+		}
+	}
+	err = client.Produce("unknown", []byte("key"), []byte("payload"), nil)
+	if err == nil {
+		t.Fatalf("Was expecting an error for an unknown topic")
+	}
+}
+
+func TestConnectUnknownTopic(t *testing.T) {
+	listener, _ := testBroker(t, []string{"results"})
+	defer listener.Close()
+	_, err := Connect(Options{
+		Brokers: []string{listener.Addr().String()},
+		Timeout: 3 * time.Second,
+	}, []string{"results", "missing"})
+	if err == nil {
+		t.Fatalf("Was expecting an error for a missing topic")
+	}
+}
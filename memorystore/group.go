@@ -0,0 +1,162 @@
+package memorystore
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// GroupHealthy the group status when enough healthchecks are successful
+	GroupHealthy string = "healthy"
+	// GroupUnhealthy the group status when too many healthchecks are failing
+	GroupUnhealthy string = "unhealthy"
+)
+
+// GroupConfiguration the configuration of a group of healthchecks
+type GroupConfiguration struct {
+	Name string
+	// the minimum ratio of successful healthchecks for the group to be
+	// healthy, between 0 and 1. All healthchecks should be successful if
+	// not set.
+	Threshold float64 `json:"threshold,omitempty" yaml:"threshold,omitempty"`
+}
+
+// UnmarshalYAML parses the configuration of a group from YAML.
+func (c *GroupConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration GroupConfiguration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the group configuration")
+	}
+	if raw.Name == "" {
+		return errors.New("Invalid name for the group configuration")
+	}
+	if raw.Threshold < 0 || raw.Threshold > 1 {
+		return fmt.Errorf("The threshold of the group %s should be between 0 and 1", raw.Name)
+	}
+	*c = GroupConfiguration(raw)
+	return nil
+}
+
+// GroupResult the aggregated results of a group of healthchecks
+type GroupResult struct {
+	Name      string `json:"name"`
+	Total     int    `json:"total"`
+	Successes int    `json:"successes"`
+	Failures  int    `json:"failures"`
+	Status    string `json:"status"`
+}
+
+// groupStatus computes the status of a group from its results
+func groupStatus(threshold float64, successes int, total int) string {
+	if threshold == 0 {
+		threshold = 1
+	}
+	if total != 0 && float64(successes)/float64(total) >= threshold {
+		return GroupHealthy
+	}
+	return GroupUnhealthy
+}
+
+// SetGroups configures the thresholds of the groups
+func (m *MemoryStore) SetGroups(groups []GroupConfiguration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.groups = make(map[string]GroupConfiguration, len(groups))
+	for _, group := range groups {
+		m.groups[group.Name] = group
+	}
+}
+
+// Groups returns the aggregated results of all groups
+func (m *MemoryStore) Groups() []GroupResult {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	groups := make(map[string]*GroupResult)
+	for name := range m.groups {
+		groups[name] = &GroupResult{Name: name}
+	}
+	for _, result := range m.Results {
+		// the results of the sources of an healthcheck are only counted
+		// once, through the healthcheck result
+		if result.Group == "" || result.FromSource() {
+			continue
+		}
+		group, ok := groups[result.Group]
+		if !ok {
+			group = &GroupResult{Name: result.Group}
+			groups[result.Group] = group
+		}
+		group.Total++
+		if result.Success {
+			group.Successes++
+		} else {
+			group.Failures++
+		}
+	}
+	results := make([]GroupResult, 0, len(groups))
+	for _, group := range groups {
+		group.Status = groupStatus(m.groups[group.Name].Threshold, group.Successes, group.Total)
+		results = append(results, *group)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+	return results
+}
+
+// Group returns the aggregated results of a group
+func (m *MemoryStore) Group(name string) (GroupResult, error) {
+	for _, group := range m.Groups() {
+		if group.Name == name {
+			return group, nil
+		}
+	}
+	return GroupResult{}, fmt.Errorf("Group %s not found", name)
+}
+
+// groupCollector exposes the groups results as Prometheus metrics
+type groupCollector struct {
+	store   *MemoryStore
+	results *prom.Desc
+	healthy *prom.Desc
+}
+
+// GroupCollector returns a Prometheus collector for the groups results
+func (m *MemoryStore) GroupCollector() prom.Collector {
+	return &groupCollector{
+		store: m,
+		results: prom.NewDesc(
+			"healthcheck_group_results",
+			"The number of healthchecks results in a group, by status",
+			[]string{"group", "status"},
+			nil),
+		healthy: prom.NewDesc(
+			"healthcheck_group_healthy",
+			"1 if the group is healthy, 0 otherwise",
+			[]string{"group"},
+			nil),
+	}
+}
+
+// Describe implements prom.Collector
+func (c *groupCollector) Describe(ch chan<- *prom.Desc) {
+	ch <- c.results
+	ch <- c.healthy
+}
+
+// Collect implements prom.Collector
+func (c *groupCollector) Collect(ch chan<- prom.Metric) {
+	for _, group := range c.store.Groups() {
+		ch <- prom.MustNewConstMetric(c.results, prom.GaugeValue, float64(group.Successes), group.Name, "success")
+		ch <- prom.MustNewConstMetric(c.results, prom.GaugeValue, float64(group.Failures), group.Name, "failure")
+		healthy := 0.0
+		if group.Status == GroupHealthy {
+			healthy = 1
+		}
+		ch <- prom.MustNewConstMetric(c.healthy, prom.GaugeValue, healthy, group.Name)
+	}
+}
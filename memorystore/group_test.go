@@ -0,0 +1,64 @@
+package memorystore
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+func TestGroups(t *testing.T) {
+	store := NewMemoryStore(zap.NewExample())
+	store.SetGroups([]GroupConfiguration{
+		{Name: "web", Threshold: 0.5},
+		{Name: "empty"},
+	})
+	now := time.Now().Unix()
+	store.Add(&healthcheck.Result{Name: "web-1", Group: "web", Success: true, HealthcheckTimestamp: now})
+	store.Add(&healthcheck.Result{Name: "web-2", Group: "web", Success: false, HealthcheckTimestamp: now})
+	store.Add(&healthcheck.Result{Name: "db-1", Group: "db", Success: true, HealthcheckTimestamp: now})
+	store.Add(&healthcheck.Result{Name: "db-2", Group: "db", Success: false, HealthcheckTimestamp: now})
+	store.Add(&healthcheck.Result{Name: "other", Success: false, HealthcheckTimestamp: now})
+	groups := store.Groups()
+	expected := []GroupResult{
+		{Name: "db", Total: 2, Successes: 1, Failures: 1, Status: GroupUnhealthy},
+		{Name: "empty", Status: GroupUnhealthy},
+		{Name: "web", Total: 2, Successes: 1, Failures: 1, Status: GroupHealthy},
+	}
+	if len(groups) != len(expected) {
+		t.Fatalf("Invalid groups %v", groups)
+	}
+	for i := range expected {
+		if groups[i] != expected[i] {
+			t.Fatalf("Invalid group, expected %v, got %v", expected[i], groups[i])
+		}
+	}
+	group, err := store.Group("web")
+	if err != nil {
+		t.Fatalf("Fail to get the group: %v", err)
+	}
+	if group != expected[2] {
+		t.Fatalf("Invalid group %v", group)
+	}
+	_, err = store.Group("unknown")
+	if err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
+
+func TestGroupsMultiSource(t *testing.T) {
+	store := NewMemoryStore(zap.NewExample())
+	now := time.Now().Unix()
+	store.Add(&healthcheck.Result{Name: "web", Group: "web", Success: true, HealthcheckTimestamp: now})
+	store.Add(&healthcheck.Result{Name: "web-10.0.0.1", Group: "web", Success: true, HealthcheckTimestamp: now, Labels: map[string]string{healthcheck.SourceIPLabel: "10.0.0.1"}})
+	store.Add(&healthcheck.Result{Name: "web-10.0.0.2", Group: "web", Success: false, HealthcheckTimestamp: now, Labels: map[string]string{healthcheck.SourceIPLabel: "10.0.0.2"}})
+	group, err := store.Group("web")
+	if err != nil {
+		t.Fatalf("Fail to get the group: %v", err)
+	}
+	if group.Total != 1 || group.Successes != 1 || group.Status != GroupHealthy {
+		t.Fatalf("The multi-source healthcheck should be counted once %v", group)
+	}
+}
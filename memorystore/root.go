@@ -19,16 +19,23 @@ type MemoryStore struct {
 	Results map[string]*healthcheck.Result
 	Tick    *time.Ticker
 
-	t    tomb.Tomb
-	lock sync.RWMutex
+	t      tomb.Tomb
+	lock   sync.RWMutex
+	groups map[string]GroupConfiguration
+	// the last successful result time of each healthcheck, and the
+	// first failure time of the healthchecks never successful
+	lastSuccess  map[string]time.Time
+	firstFailure map[string]time.Time
 }
 
 // NewMemoryStore creates a new memory store
 func NewMemoryStore(logger *zap.Logger) *MemoryStore {
 	return &MemoryStore{
-		Logger:  logger,
-		TTL:     time.Second * 120,
-		Results: make(map[string]*healthcheck.Result),
+		Logger:       logger,
+		TTL:          time.Second * 120,
+		Results:      make(map[string]*healthcheck.Result),
+		lastSuccess:  make(map[string]time.Time),
+		firstFailure: make(map[string]time.Time),
 	}
 }
 
@@ -65,6 +72,14 @@ func (m *MemoryStore) Add(result *healthcheck.Result) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 	m.Results[result.Name] = result
+	if result.Success {
+		m.lastSuccess[result.Name] = result.Time()
+		delete(m.firstFailure, result.Name)
+	} else if _, ok := m.lastSuccess[result.Name]; !ok {
+		if _, ok := m.firstFailure[result.Name]; !ok {
+			m.firstFailure[result.Name] = result.Time()
+		}
+	}
 }
 
 // Purge the expired results
@@ -74,11 +89,13 @@ func (m *MemoryStore) Purge() {
 	now := time.Now()
 	for i := range m.Results {
 		result := m.Results[i]
-		checkTimestamp := time.Unix(result.HealthcheckTimestamp, 0)
+		checkTimestamp := result.Time()
 		if now.After(checkTimestamp.Add(m.TTL)) {
 			m.Logger.Info("expire healthcheck",
 				zap.String("name", result.Name))
 			delete(m.Results, result.Name)
+			delete(m.lastSuccess, result.Name)
+			delete(m.firstFailure, result.Name)
 		}
 	}
 }
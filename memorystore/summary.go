@@ -0,0 +1,103 @@
+package memorystore
+
+import (
+	"sort"
+	"time"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+// DefaultSummaryTop the default number of failing healthchecks in the summary
+const DefaultSummaryTop = 10
+
+// FailingCheck a failing healthcheck of the summary
+type FailingCheck struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	// the time of the last successful result, or of the first failure if
+	// the healthcheck was never successful
+	FailingSince interface{} `json:"failing-since"`
+	// the failure duration in seconds
+	FailingDuration float64 `json:"failing-duration"`
+}
+
+// Summary the aggregated status of the healthchecks. Every healthcheck is
+// counted in one of the ok, warn, critical, muted or paused counters, the
+// failures of the healthchecks with the warning or info severity are warn.
+type Summary struct {
+	Total    int `json:"total"`
+	OK       int `json:"ok"`
+	Warn     int `json:"warn"`
+	Critical int `json:"critical"`
+	// the failures not reported yet, because of the startup grace period
+	// or of the thresholds
+	Muted  int `json:"muted"`
+	Paused int `json:"paused"`
+	// the longest failing healthchecks first
+	Failing []FailingCheck `json:"failing"`
+}
+
+// muted returns true if the failure of a result is not reported
+func muted(result *healthcheck.Result) bool {
+	if result.Metadata["startup"] == "true" {
+		return true
+	}
+	raw, ok := result.Metadata["raw-success"]
+	return ok && raw == "false" && result.Success
+}
+
+// Summary aggregates the latest results. The top longest failing healthchecks
+// are returned, paused returns true for the paused healthchecks.
+func (m *MemoryStore) Summary(top int, paused func(name string) bool, now time.Time) Summary {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	summary := Summary{Failing: []FailingCheck{}}
+	var failing []FailingCheck
+	var since []time.Time
+	for name, result := range m.Results {
+		summary.Total++
+		switch {
+		case paused != nil && paused(name):
+			summary.Paused++
+		case muted(result):
+			summary.Muted++
+		case !result.Success:
+			if result.State() == "critical" {
+				summary.Critical++
+			} else {
+				summary.Warn++
+			}
+			start, ok := m.lastSuccess[name]
+			if !ok {
+				start = m.firstFailure[name]
+			}
+			failing = append(failing, FailingCheck{
+				Name:            name,
+				Message:         result.Message,
+				FailingDuration: now.Sub(start).Seconds(),
+			})
+			since = append(since, start)
+		case result.Metadata["warning"] != "":
+			summary.Warn++
+		default:
+			summary.OK++
+		}
+	}
+	for i := range failing {
+		failing[i].FailingSince = healthcheck.FormatTimestamp(since[i])
+	}
+	sort.SliceStable(failing, func(i, j int) bool {
+		if failing[i].FailingDuration == failing[j].FailingDuration {
+			return failing[i].Name < failing[j].Name
+		}
+		return failing[i].FailingDuration > failing[j].FailingDuration
+	})
+	if top <= 0 {
+		top = DefaultSummaryTop
+	}
+	if len(failing) > top {
+		failing = failing[:top]
+	}
+	summary.Failing = append(summary.Failing, failing...)
+	return summary
+}
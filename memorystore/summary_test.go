@@ -0,0 +1,55 @@
+package memorystore
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mcorbin/cabourotte/healthcheck"
+)
+
+func TestSummary(t *testing.T) {
+	store := NewMemoryStore(zap.NewExample())
+	now := time.Now()
+	add := func(name string, success bool, at time.Time, metadata map[string]string) {
+		store.Add(&healthcheck.Result{Name: name, Success: success, Timestamp: at, Metadata: metadata, Message: name})
+	}
+	add("ok", true, now, nil)
+	add("warn", true, now, map[string]string{"warning": "slow"})
+	add("paused", false, now, nil)
+	add("startup", false, now, map[string]string{"startup": "true"})
+	add("threshold", true, now, map[string]string{"raw-success": "false", "threshold": "1/3"})
+	// failing since its last success
+	add("old", true, now.Add(-time.Hour), nil)
+	add("old", false, now.Add(-50*time.Minute), nil)
+	add("old", false, now, nil)
+	// never successful, failing since its first failure
+	add("recent", false, now.Add(-time.Minute), nil)
+	add("recent", false, now, nil)
+	add("new", false, now, nil)
+	// the failures of the non critical healthchecks are warnings
+	store.Add(&healthcheck.Result{Name: "degraded", Success: false, Timestamp: now, Severity: healthcheck.SeverityWarning})
+	summary := store.Summary(2, func(name string) bool { return name == "paused" }, now)
+	if summary.Total != 9 || summary.OK != 1 || summary.Warn != 2 || summary.Critical != 3 || summary.Muted != 2 || summary.Paused != 1 {
+		t.Fatalf("Invalid summary %+v", summary)
+	}
+	if len(summary.Failing) != 2 {
+		t.Fatalf("Invalid failing healthchecks %+v", summary.Failing)
+	}
+	if summary.Failing[0].Name != "old" || summary.Failing[0].FailingDuration != time.Hour.Seconds() {
+		t.Fatalf("Invalid failing healthcheck %+v", summary.Failing[0])
+	}
+	if summary.Failing[0].FailingSince != now.Add(-time.Hour).Unix() {
+		t.Fatalf("Invalid failing since %v", summary.Failing[0].FailingSince)
+	}
+	if summary.Failing[1].Name != "recent" || summary.Failing[1].FailingDuration != time.Minute.Seconds() {
+		t.Fatalf("Invalid failing healthcheck %+v", summary.Failing[1])
+	}
+	// a success resets the failure duration
+	add("old", true, now, nil)
+	summary = store.Summary(0, nil, now)
+	if summary.Critical != 3 || summary.Paused != 0 || summary.Failing[0].Name != "recent" {
+		t.Fatalf("Invalid summary %+v", summary)
+	}
+}
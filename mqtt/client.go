@@ -0,0 +1,382 @@
+package mqtt
+
+import (
+	"bufio"
+	cryptotls "crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mcorbin/cabourotte/tls"
+)
+
+// the MQTT 3.1.1 control packets types
+const (
+	packetConnect    byte = 1
+	packetConnack    byte = 2
+	packetPublish    byte = 3
+	packetPuback     byte = 4
+	packetPubrec     byte = 5
+	packetPubrel     byte = 6
+	packetPubcomp    byte = 7
+	packetPingreq    byte = 12
+	packetDisconnect byte = 14
+)
+
+// maxRemainingLength the maximum size of a packet, without the fixed header
+const maxRemainingLength = 268435455
+
+// ErrClosed is returned when the connection to the broker is closed
+var ErrClosed = errors.New("the MQTT connection is closed")
+
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// Options the options of an MQTT client
+type Options struct {
+	Address  string
+	ClientID string
+	Username string
+	Password string
+	// TLS is used if not nil
+	TLSConfig *cryptotls.Config
+	// the maximum duration of the TLS handshake, the timeout is used if
+	// not set
+	HandshakeTimeout time.Duration
+	KeepAlive        time.Duration
+	// the maximum duration of the connection and of the acknowledgements
+	Timeout time.Duration
+}
+
+// Client a minimal MQTT 3.1.1 client, only publishing messages
+type Client struct {
+	options Options
+	conn    net.Conn
+
+	writeLock sync.Mutex
+	lock      sync.Mutex
+	nextID    uint16
+	pending   map[uint16]chan struct{}
+	closed    bool
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Connect connects to the broker
+func Connect(options Options) (*Client, error) {
+	dialer := &net.Dialer{Timeout: options.Timeout}
+	var conn net.Conn
+	var err error
+	if options.TLSConfig != nil {
+		conn, err = tls.Dial(dialer, options.Address, options.TLSConfig, options.HandshakeTimeout)
+	} else {
+		conn, err = dialer.Dial("tcp", options.Address)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to connect to the MQTT broker %s", options.Address)
+	}
+	client := &Client{
+		options: options,
+		conn:    conn,
+		pending: make(map[uint16]chan struct{}),
+		done:    make(chan struct{}),
+	}
+	reader := bufio.NewReader(conn)
+	if err := client.handshake(reader); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	client.wg.Add(1)
+	go client.read(reader)
+	if options.KeepAlive != 0 {
+		client.wg.Add(1)
+		go client.keepAlive()
+	}
+	return client, nil
+}
+
+// handshake sends the CONNECT packet and waits for the CONNACK
+func (c *Client) handshake(reader *bufio.Reader) error {
+	var flags byte = 0x02 // clean session
+	payload := appendString(nil, c.options.ClientID)
+	if c.options.Username != "" {
+		flags |= 0x80
+		payload = appendString(payload, c.options.Username)
+	}
+	if c.options.Password != "" {
+		flags |= 0x40
+		payload = appendString(payload, c.options.Password)
+	}
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags)
+	body = appendUint16(body, uint16(c.options.KeepAlive/time.Second))
+	body = append(body, payload...)
+	if c.options.Timeout != 0 {
+		// nolint
+		c.conn.SetDeadline(time.Now().Add(c.options.Timeout))
+		// nolint
+		defer c.conn.SetDeadline(time.Time{})
+	}
+	if err := c.write(packetConnect<<4, body); err != nil {
+		return err
+	}
+	packetType, response, err := readPacket(reader)
+	if err != nil {
+		return errors.Wrap(err, "Fail to read the MQTT CONNACK")
+	}
+	if packetType != packetConnack || len(response) != 2 {
+		return fmt.Errorf("Invalid MQTT CONNACK packet (type %d)", packetType)
+	}
+	if response[1] != 0 {
+		msg, ok := connackErrors[response[1]]
+		if !ok {
+			msg = fmt.Sprintf("return code %d", response[1])
+		}
+		return fmt.Errorf("MQTT connection refused: %s", msg)
+	}
+	return nil
+}
+
+// Publish publishes a message. The function waits for the broker
+// acknowledgement if the QoS is 1 or 2.
+func (c *Client) Publish(topic string, payload []byte, qos byte, retain bool) error {
+	if qos > 2 {
+		return fmt.Errorf("Invalid MQTT QoS %d", qos)
+	}
+	header := packetPublish<<4 | qos<<1
+	if retain {
+		header |= 0x01
+	}
+	body := appendString(nil, topic)
+	var ack chan struct{}
+	var id uint16
+	if qos != 0 {
+		id, ack = c.register()
+		if ack == nil {
+			return ErrClosed
+		}
+		defer c.unregister(id)
+		body = appendUint16(body, id)
+	}
+	body = append(body, payload...)
+	if err := c.write(header, body); err != nil {
+		return err
+	}
+	if ack == nil {
+		return nil
+	}
+	var timeout <-chan time.Time
+	if c.options.Timeout != 0 {
+		timer := time.NewTimer(c.options.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-ack:
+		return nil
+	case <-c.done:
+		return ErrClosed
+	case <-timeout:
+		return fmt.Errorf("No acknowledgement received from the MQTT broker after %s", c.options.Timeout)
+	}
+}
+
+// Connected returns true if the connection to the broker is open
+func (c *Client) Connected() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return !c.closed
+}
+
+// Disconnect sends the DISCONNECT packet and closes the connection
+func (c *Client) Disconnect() error {
+	var err error
+	if c.Connected() {
+		err = c.write(packetDisconnect<<4, nil)
+	}
+	c.close()
+	closeErr := c.conn.Close()
+	c.wg.Wait()
+	if err != nil {
+		return err
+	}
+	if closeErr != nil && !errors.Is(closeErr, net.ErrClosed) {
+		return closeErr
+	}
+	return nil
+}
+
+// register allocates a packet identifier, nil is returned if the client is
+// closed
+func (c *Client) register() (uint16, chan struct{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed {
+		return 0, nil
+	}
+	for {
+		c.nextID++
+		if c.nextID == 0 {
+			continue
+		}
+		if _, ok := c.pending[c.nextID]; !ok {
+			break
+		}
+	}
+	ack := make(chan struct{})
+	c.pending[c.nextID] = ack
+	return c.nextID, ack
+}
+
+// unregister releases a packet identifier
+func (c *Client) unregister(id uint16) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.pending, id)
+}
+
+// acknowledge notifies the publisher waiting for a packet identifier
+func (c *Client) acknowledge(id uint16) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if ack, ok := c.pending[id]; ok {
+		close(ack)
+		delete(c.pending, id)
+	}
+}
+
+// close marks the client as closed
+func (c *Client) close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.done)
+	}
+}
+
+// read reads the packets sent by the broker until the connection is closed
+func (c *Client) read(reader *bufio.Reader) {
+	defer c.wg.Done()
+	defer c.close()
+	for {
+		packetType, body, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+		switch packetType {
+		case packetPuback, packetPubcomp:
+			if len(body) == 2 {
+				c.acknowledge(binary.BigEndian.Uint16(body))
+			}
+		case packetPubrec:
+			if len(body) == 2 {
+				if err := c.write(packetPubrel<<4|0x02, body); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// keepAlive sends a PINGREQ packet periodically
+func (c *Client) keepAlive() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(c.options.KeepAlive / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.write(packetPingreq<<4, nil); err != nil {
+				c.close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// write writes a packet
+func (c *Client) write(header byte, body []byte) error {
+	if len(body) > maxRemainingLength {
+		return errors.New("The MQTT packet is too large")
+	}
+	packet := []byte{header}
+	packet = appendLength(packet, len(body))
+	packet = append(packet, body...)
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	if c.options.Timeout != 0 {
+		// nolint
+		c.conn.SetWriteDeadline(time.Now().Add(c.options.Timeout))
+	}
+	_, err := c.conn.Write(packet)
+	if err != nil {
+		return errors.Wrap(err, "Fail to write to the MQTT broker")
+	}
+	return nil
+}
+
+// readPacket reads a packet, the packet type and the packet body are returned
+func readPacket(reader *bufio.Reader) (byte, []byte, error) {
+	header, err := reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length := 0
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("Invalid MQTT remaining length")
+		}
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length |= int(b&0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return 0, nil, err
+	}
+	return header >> 4, body, nil
+}
+
+// appendLength appends the remaining length of a packet
+func appendLength(b []byte, length int) []byte {
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if length == 0 {
+			return b
+		}
+	}
+}
+
+// appendString appends a length-prefixed string
+func appendString(b []byte, s string) []byte {
+	b = appendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// appendUint16 appends a big endian uint16
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
@@ -0,0 +1,130 @@
+package mqtt
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+)
+
+// message a message received by the test broker
+type message struct {
+	header  byte
+	topic   string
+	payload string
+}
+
+// broker accepts one client, and acknowledges its messages
+func broker(t *testing.T, returnCode byte) (net.Listener, chan message) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to start the listener: %v", err)
+	}
+	messages := make(chan message, 10)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		packetType, _, err := readPacket(reader)
+		if err != nil || packetType != packetConnect {
+			return
+		}
+		_, _ = conn.Write([]byte{packetConnack << 4, 2, 0, returnCode})
+		for {
+			header, err := reader.ReadByte()
+			if err != nil {
+				return
+			}
+			if err := reader.UnreadByte(); err != nil {
+				return
+			}
+			packetType, body, err := readPacket(reader)
+			if err != nil {
+				return
+			}
+			switch packetType {
+			case packetPublish:
+				qos := (header >> 1) & 0x03
+				length := int(body[0])<<8 | int(body[1])
+				topic := string(body[2 : 2+length])
+				rest := body[2+length:]
+				if qos != 0 {
+					id := rest[:2]
+					rest = rest[2:]
+					if qos == 1 {
+						_, _ = conn.Write([]byte{packetPuback << 4, 2, id[0], id[1]})
+					} else {
+						_, _ = conn.Write([]byte{packetPubrec << 4, 2, id[0], id[1]})
+					}
+				}
+				messages <- message{header: header, topic: topic, payload: string(rest)}
+			case packetPubrel:
+				_, _ = conn.Write([]byte{packetPubcomp << 4, 2, body[0], body[1]})
+			case packetDisconnect:
+				close(messages)
+				return
+			}
+		}
+	}()
+	return listener, messages
+}
+
+func TestPublish(t *testing.T) {
+	listener, messages := broker(t, 0)
+	defer listener.Close()
+	client, err := Connect(Options{
+		Address:   listener.Addr().String(),
+		ClientID:  "test",
+		Username:  "user",
+		Password:  "password",
+		KeepAlive: 10 * time.Second,
+		Timeout:   2 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Fail to connect: %v", err)
+	}
+	for qos := byte(0); qos <= 2; qos++ {
+		err = client.Publish("cabourotte/foo", []byte("payload"), qos, qos == 1)
+		if err != nil {
+			t.Fatalf("Fail to publish with QoS %d: %v", qos, err)
+		}
+		m := <-messages
+		if m.topic != "cabourotte/foo" || m.payload != "payload" {
+			t.Fatalf("Invalid message %v", m)
+		}
+		if (m.header>>1)&0x03 != qos || (m.header&0x01 == 1) != (qos == 1) {
+			t.Fatalf("Invalid header %d for QoS %d", m.header, qos)
+		}
+	}
+	if err := client.Publish("cabourotte/foo", []byte("payload"), 3, false); err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+	if err := client.Disconnect(); err != nil {
+		t.Fatalf("Fail to disconnect: %v", err)
+	}
+	if _, ok := <-messages; ok {
+		t.Fatalf("The broker should have received the disconnection")
+	}
+	if client.Connected() {
+		t.Fatalf("The client should be disconnected")
+	}
+	if err := client.Publish("cabourotte/foo", []byte("payload"), 1, false); err == nil {
+		t.Fatalf("Was expecting an error")
+	}
+}
+
+func TestConnectRefused(t *testing.T) {
+	listener, _ := broker(t, 5)
+	defer listener.Close()
+	_, err := Connect(Options{
+		Address:  listener.Addr().String(),
+		ClientID: "test",
+		Timeout:  2 * time.Second,
+	})
+	if err == nil || err.Error() != "MQTT connection refused: not authorized" {
+		t.Fatalf("Invalid error %v", err)
+	}
+}
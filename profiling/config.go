@@ -0,0 +1,56 @@
+package profiling
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultHost the default address of the profiling server
+const DefaultHost = "127.0.0.1"
+
+// BasicAuth the credentials of the profiling server
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+// Configuration the profiling server configuration
+type Configuration struct {
+	Enabled bool
+	Host    string `json:"host,omitempty" yaml:"host,omitempty"`
+	Port    uint32
+	// required if the server is not bound to a loopback address
+	BasicAuth BasicAuth `yaml:"basic-auth"`
+}
+
+// UnmarshalYAML parses the profiling server configuration from YAML.
+func (c *Configuration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawConfiguration Configuration
+	raw := rawConfiguration{}
+	if err := unmarshal(&raw); err != nil {
+		return errors.Wrap(err, "Unable to read the pprof configuration")
+	}
+	if !raw.Enabled {
+		*c = Configuration(raw)
+		return nil
+	}
+	if raw.Host == "" {
+		raw.Host = DefaultHost
+	}
+	ip := net.ParseIP(raw.Host)
+	if ip == nil {
+		return errors.New("Invalid IP address for the pprof server")
+	}
+	if raw.Port == 0 {
+		return errors.New("Invalid port for the pprof server")
+	}
+	if (raw.BasicAuth.Username == "") != (raw.BasicAuth.Password == "") {
+		return errors.New("Invalid Basic Auth configuration for the pprof server")
+	}
+	if !ip.IsLoopback() && raw.BasicAuth.Username == "" {
+		return errors.New("The pprof server requires basic auth when it is not bound to a loopback address")
+	}
+	*c = Configuration(raw)
+	return nil
+}
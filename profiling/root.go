@@ -0,0 +1,92 @@
+package profiling
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Component the profiling server, exposing the pprof endpoints on a
+// dedicated listener
+type Component struct {
+	Config *Configuration
+	Logger *zap.Logger
+	server *http.Server
+	wg     sync.WaitGroup
+}
+
+// New creates a new profiling component
+func New(logger *zap.Logger, config *Configuration) *Component {
+	// the handlers are not registered on the default mux
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	component := &Component{
+		Config: config,
+		Logger: logger,
+	}
+	component.server = &http.Server{
+		Handler: component.authenticate(mux),
+	}
+	return component
+}
+
+// authenticate verifies the basic auth credentials if configured
+func (c *Component) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c.Config.BasicAuth.Username != "" {
+			username, password, ok := r.BasicAuth()
+			if !ok ||
+				subtle.ConstantTimeCompare([]byte(username), []byte(c.Config.BasicAuth.Username)) != 1 ||
+				subtle.ConstantTimeCompare([]byte(password), []byte(c.Config.BasicAuth.Password)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Basic realm="pprof"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Start starts the profiling server
+func (c *Component) Start() error {
+	address := net.JoinHostPort(c.Config.Host, fmt.Sprintf("%d", c.Config.Port))
+	c.Logger.Info(fmt.Sprintf("Starting the pprof server on %s", address))
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return errors.Wrapf(err, "Fail to start the pprof server on %s", address)
+	}
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		err := c.server.Serve(listener)
+		if err != http.ErrServerClosed {
+			c.Logger.Error(fmt.Sprintf("pprof server error: %s", err.Error()))
+		}
+	}()
+	return nil
+}
+
+// Stop stops the profiling server
+func (c *Component) Stop() error {
+	c.Logger.Info("Stopping the pprof server")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := c.server.Shutdown(ctx)
+	c.wg.Wait()
+	if err != nil {
+		return errors.Wrapf(err, "Fail to stop the pprof server")
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package profiling
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+)
+
+func TestStartStop(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Fail to get a port:\n%v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	component := New(zap.NewExample(), &Configuration{
+		Enabled:   true,
+		Host:      "127.0.0.1",
+		Port:      uint32(port),
+		BasicAuth: BasicAuth{Username: "foo", Password: "bar"},
+	})
+	err = component.Start()
+	if err != nil {
+		t.Fatalf("Fail to start the component:\n%v", err)
+	}
+	url := fmt.Sprintf("http://127.0.0.1:%d/debug/pprof/goroutine?debug=1", port)
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("HTTP error:\n%v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Invalid status %d without credentials", resp.StatusCode)
+	}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatalf("Fail to build the request:\n%v", err)
+	}
+	req.SetBasicAuth("foo", "bar")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("HTTP error:\n%v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Invalid status %d", resp.StatusCode)
+	}
+	err = component.Stop()
+	if err != nil {
+		t.Fatalf("Fail to stop the component:\n%v", err)
+	}
+}
+
+func TestUnmarshalConfiguration(t *testing.T) {
+	var config Configuration
+	err := yaml.Unmarshal([]byte("enabled: true\nport: 6060"), &config)
+	if err != nil {
+		t.Fatalf("Fail to parse the configuration:\n%v", err)
+	}
+	if config.Host != DefaultHost {
+		t.Fatalf("Invalid host %s", config.Host)
+	}
+	cases := []string{
+		"enabled: true",
+		"enabled: true\nport: 6060\nhost: foo",
+		"enabled: true\nport: 6060\nhost: 0.0.0.0",
+		"enabled: true\nport: 6060\nbasic-auth:\n  username: foo",
+	}
+	for _, c := range cases {
+		var config Configuration
+		err := yaml.Unmarshal([]byte(c), &config)
+		if err == nil {
+			t.Fatalf("Was expecting an error for:\n%s", c)
+		}
+	}
+}
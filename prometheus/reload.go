@@ -0,0 +1,70 @@
+package prometheus
+
+import (
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// reloadMetrics the metrics tracking the configuration reloads
+type reloadMetrics struct {
+	success   prom.Gauge
+	timestamp prom.Gauge
+	attempts  *prom.CounterVec
+	skipped   *prom.GaugeVec
+}
+
+// newReloadMetrics creates the reload metrics
+func newReloadMetrics() *reloadMetrics {
+	return &reloadMetrics{
+		success: prom.NewGauge(prom.GaugeOpts{
+			Name: "cabourotte_config_last_reload_success",
+			Help: "1 if the last configuration reload succeeded, 0 otherwise.",
+		}),
+		timestamp: prom.NewGauge(prom.GaugeOpts{
+			Name: "cabourotte_config_last_reload_timestamp_seconds",
+			Help: "Timestamp of the last configuration reload attempt.",
+		}),
+		attempts: prom.NewCounterVec(prom.CounterOpts{
+			Name: "cabourotte_config_reloads_total",
+			Help: "Count the configuration reload attempts.",
+		},
+			[]string{"status"}),
+		skipped: prom.NewGaugeVec(prom.GaugeOpts{
+			Name: "cabourotte_config_skipped_entries",
+			Help: "Number of invalid configuration entries skipped by the best-effort loading, by section.",
+		},
+			[]string{"section"}),
+	}
+}
+
+// register registers the reload metrics in the registry
+func (m *reloadMetrics) register(registry *prom.Registry) error {
+	for _, collector := range []prom.Collector{m.success, m.timestamp, m.attempts, m.skipped} {
+		if err := registry.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordReload updates the reload metrics from the result of a reload
+func (p *Prometheus) RecordReload(err error) {
+	p.reload.timestamp.Set(float64(time.Now().Unix()))
+	if err != nil {
+		p.reload.success.Set(0)
+		p.reload.attempts.With(prom.Labels{"status": "failure"}).Inc()
+		return
+	}
+	p.reload.success.Set(1)
+	p.reload.attempts.With(prom.Labels{"status": "success"}).Inc()
+}
+
+// RecordSkippedEntries sets the number of configuration entries skipped by
+// the best-effort loading for each section
+func (p *Prometheus) RecordSkippedEntries(sections map[string]int) {
+	p.reload.skipped.Reset()
+	for section, count := range sections {
+		p.reload.skipped.With(prom.Labels{"section": section}).Set(float64(count))
+	}
+}
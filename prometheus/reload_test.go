@@ -0,0 +1,55 @@
+package prometheus
+
+import (
+	"errors"
+	"testing"
+)
+
+// gaugeValue returns the value of a metric without labels
+func gaugeValue(t *testing.T, p *Prometheus, name string) float64 {
+	families, err := p.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Fail to gather the metrics\n%v", err)
+	}
+	for _, family := range families {
+		if family.GetName() == name {
+			return family.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+	t.Fatalf("Metric %s not found", name)
+	return 0
+}
+
+func TestRecordReload(t *testing.T) {
+	p, err := New()
+	if err != nil {
+		t.Fatalf("Fail to create the component\n%v", err)
+	}
+	p.RecordReload(nil)
+	if gaugeValue(t, p, "cabourotte_config_last_reload_success") != 1 {
+		t.Fatalf("The reload should be successful")
+	}
+	if gaugeValue(t, p, "cabourotte_config_last_reload_timestamp_seconds") == 0 {
+		t.Fatalf("The reload timestamp should be set")
+	}
+	p.RecordReload(errors.New("invalid configuration"))
+	if gaugeValue(t, p, "cabourotte_config_last_reload_success") != 0 {
+		t.Fatalf("The reload should be failed")
+	}
+	families, err := p.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Fail to gather the metrics\n%v", err)
+	}
+	for _, family := range families {
+		if family.GetName() == "cabourotte_config_reloads_total" {
+			for _, metric := range family.GetMetric() {
+				if metric.GetCounter().GetValue() != 1 {
+					t.Fatalf("Invalid reload attempts counter %v", metric)
+				}
+			}
+			if len(family.GetMetric()) != 2 {
+				t.Fatalf("Was expecting a success and a failure")
+			}
+		}
+	}
+}
@@ -14,6 +14,7 @@ type Prometheus struct {
 	Config   *Configuration
 	Logger   *zap.Logger
 	Registry *prom.Registry
+	reload   *reloadMetrics
 }
 
 // New creates a new Prometheus component
@@ -21,11 +22,16 @@ func New() (*Prometheus, error) {
 	reg := prom.NewRegistry()
 	p := &Prometheus{
 		Registry: reg,
+		reload:   newReloadMetrics(),
 	}
 	err := p.Register(collectors.NewGoCollector())
 	if err != nil {
 		return nil, err
 	}
+	err = p.reload.register(reg)
+	if err != nil {
+		return nil, err
+	}
 	return p, nil
 }
 
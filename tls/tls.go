@@ -1,10 +1,13 @@
 package tls
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"io/ioutil"
+	"net"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -35,3 +38,40 @@ func GetTLSConfig(keyPath string, certPath string, cacertPath string, insecure b
 	tlsConfig.InsecureSkipVerify = insecure
 	return tlsConfig, nil
 }
+
+// Dial connects to an address and executes the TLS handshake. The handshake
+// is bounded by the handshake timeout if set, and by the dialer timeout
+// otherwise.
+func Dial(dialer *net.Dialer, address string, tlsConfig *tls.Config, handshakeTimeout time.Duration) (*tls.Conn, error) {
+	conn, err := dialer.Dial("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	config := tlsConfig.Clone()
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			host = address
+		}
+		config.ServerName = host
+	}
+	timeout := handshakeTimeout
+	if timeout == 0 {
+		timeout = dialer.Timeout
+	}
+	ctx := context.Background()
+	if timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		if handshakeTimeout != 0 && ctx.Err() != nil {
+			return nil, fmt.Errorf("TLS handshake with %s exceeded its timeout of %s", address, handshakeTimeout)
+		}
+		return nil, errors.Wrapf(err, "TLS handshake with %s failed", address)
+	}
+	return tlsConn, nil
+}